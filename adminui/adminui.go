@@ -0,0 +1,58 @@
+// Package adminui serves the admin web UI: static pages, embedded in the
+// binary, that sign an admin in and call the API for them. Small
+// deployments get a way to browse books, manage users, read the audit log
+// and export the catalog without a separate frontend.
+package adminui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Path is where the UI is served; its pages route by URL fragment, so
+// every file is under it.
+const Path = "/admin/ui"
+
+//go:embed static
+var static embed.FS
+
+var (
+	root  = mustSub(static, "static")
+	files = http.FileServer(http.FS(root))
+)
+
+func mustSub(fsys fs.FS, dir string) fs.FS {
+	sub, err := fs.Sub(fsys, dir)
+	if err != nil {
+		panic(err)
+	}
+	return sub
+}
+
+// GET /admin/ui/*filepath
+func Serve(c *gin.Context) {
+	if name := strings.TrimPrefix(c.Param("filepath"), "/"); name != "" {
+		if _, err := fs.Stat(root, name); err != nil {
+			// Answered as any unknown route is.
+			apierrors.Abort(c, apierrors.NotFound("No route matches %s").WithArgs(c.Request.URL.Path))
+			return
+		}
+	}
+	// The pages only load their own scripts and styles, and only talk to
+	// the API they are served by.
+	c.Header("Content-Security-Policy", "default-src 'self'; img-src 'self' data: blob:; frame-ancestors 'none'; base-uri 'none'; form-action 'self'")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Referrer-Policy", "no-referrer")
+	// Embedded files have no modification time to revalidate against, and
+	// change with every release.
+	c.Header("Cache-Control", "no-cache")
+
+	r := c.Request.Clone(c.Request.Context())
+	r.URL.Path = c.Param("filepath")
+	files.ServeHTTP(c.Writer, r)
+}
@@ -0,0 +1,350 @@
+// The admin UI: plain scripts calling the API as the signed-in admin. The
+// tokens are kept in session storage, so closing the tab signs out.
+"use strict";
+
+const API = "/api/v1";
+const PAGE_SIZE = 20;
+
+const session = {
+  get token() { return sessionStorage.getItem("token"); },
+  get refreshToken() { return sessionStorage.getItem("refresh_token"); },
+  save(token) {
+    sessionStorage.setItem("token", token.token);
+    sessionStorage.setItem("refresh_token", token.refresh_token);
+  },
+  clear() { sessionStorage.clear(); },
+};
+
+class ProblemError extends Error {
+  constructor(status, problem) {
+    super(problem.detail || problem.title || `Request failed with status ${status}`);
+    this.status = status;
+    this.problem = problem;
+  }
+}
+
+// request calls the API, exchanging the refresh token for a new access
+// token once when the current one has expired. It resolves to the parsed
+// JSON body, or to the response itself with raw.
+async function request(method, path, { body, raw = false, retry = true } = {}) {
+  const headers = { Accept: "application/json" };
+  if (session.token) headers.Authorization = `Bearer ${session.token}`;
+  if (body !== undefined) headers["Content-Type"] = "application/json";
+  const response = await fetch(API + path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  if (response.status === 401 && retry && session.refreshToken) {
+    if (await refresh()) return request(method, path, { body, raw, retry: false });
+  }
+  if (!response.ok) {
+    let problem = {};
+    try { problem = await response.json(); } catch { /* not a problem document */ }
+    if (response.status === 401 && path !== "/auth/login") signOut("Your session expired; sign in again.");
+    throw new ProblemError(response.status, problem);
+  }
+  if (raw) return response;
+  return response.status === 204 ? null : response.json();
+}
+
+async function refresh() {
+  const response = await fetch(API + "/auth/refresh", {
+    method: "POST",
+    headers: { "Content-Type": "application/json", Accept: "application/json" },
+    body: JSON.stringify({ refresh_token: session.refreshToken }),
+  });
+  if (!response.ok) return false;
+  session.save((await response.json()).data);
+  return true;
+}
+
+// Rendering helpers.
+
+function $(selector, root = document) { return root.querySelector(selector); }
+
+function element(tag, attributes = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attributes)) {
+    if (name.startsWith("on")) node.addEventListener(name.slice(2), value);
+    else if (value !== undefined && value !== null && value !== false) node.setAttribute(name, value === true ? "" : value);
+  }
+  node.append(...children.filter((child) => child !== null && child !== undefined));
+  return node;
+}
+
+function button(label, onclick, className) {
+  return element("button", { type: "button", class: className, onclick }, label);
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+function show(message, isError = true) {
+  const node = $("#message");
+  node.textContent = message;
+  node.className = isError ? "error" : "info";
+  node.hidden = !message;
+}
+
+async function attempt(fn) {
+  show("");
+  try {
+    await fn();
+  } catch (error) {
+    // Expired sessions were signed out with their own message.
+    if (error.status !== 401 || session.token) show(error.message);
+  }
+}
+
+function filters(form) {
+  const params = new URLSearchParams();
+  for (const [name, value] of new FormData(form)) {
+    if (value !== "") params.set(name, value);
+  }
+  return params;
+}
+
+// Pages, each a section shown for its URL fragment.
+
+const pages = {
+  books: { load: loadBooks, page: 1 },
+  users: { load: loadUsers, page: 1 },
+  audit: { load: loadAudit, cursors: [""] },
+  exports: { load: renderExports },
+};
+
+function pager(section, { page, totalPages, hasPrevious, hasNext }, go) {
+  const node = $(".pager", section);
+  node.replaceChildren(
+    button("Previous", () => go(-1), null),
+    element("span", {}, totalPages !== undefined ? `Page ${page} of ${Math.max(totalPages, 1)}` : `Page ${page}`),
+    button("Next", () => go(1), null),
+  );
+  node.children[0].disabled = !hasPrevious;
+  node.children[2].disabled = !hasNext;
+}
+
+async function loadBooks() {
+  const section = $("#books");
+  const state = pages.books;
+  const params = filters($("form", section));
+  params.set("page", state.page);
+  params.set("page_size", PAGE_SIZE);
+  params.set("preload", "author");
+  const { data, meta } = await request("GET", `/books?${params}`);
+
+  $("tbody", section).replaceChildren(...data.map((book) => element("tr", { class: book.deleted_at ? "deleted" : null },
+    element("td", {}, book.title),
+    element("td", {}, book.author ? book.author.name : ""),
+    element("td", {}, book.isbn),
+    element("td", {}, book.year || ""),
+    element("td", {}, `${book.available_copies} of ${book.quantity}`),
+    element("td", { class: "actions" }, book.deleted_at
+      ? button("Restore", () => attempt(async () => {
+        await request("POST", `/books/${book.id}/restore`);
+        await loadBooks();
+      }))
+      : button("Delete", () => attempt(async () => {
+        if (!confirm(`Delete "${book.title}"? It can be restored until it is purged.`)) return;
+        await request("DELETE", `/books/${book.id}`);
+        await loadBooks();
+      }), "danger")),
+  )));
+  pager(section, { page: meta.page, totalPages: meta.total_pages, hasPrevious: meta.page > 1, hasNext: meta.page < meta.total_pages }, (step) => {
+    state.page += step;
+    attempt(loadBooks);
+  });
+}
+
+async function loadUsers() {
+  const section = $("#users");
+  const state = pages.users;
+  const params = filters($("form", section));
+  params.set("page", state.page);
+  params.set("page_size", PAGE_SIZE);
+  const { data, meta } = await request("GET", `/admin/users?${params}`);
+
+  const act = (method, path, body) => attempt(async () => {
+    await request(method, path, { body });
+    await loadUsers();
+  });
+  $("tbody", section).replaceChildren(...data.map((user) => {
+    const locked = user.locked_until && new Date(user.locked_until) > new Date();
+    const status = user.disabled_at ? "Disabled" : locked ? `Locked until ${formatTime(user.locked_until)}` : "Active";
+    const otherRole = user.role === "admin" ? "reader" : "admin";
+    return element("tr", { class: user.disabled_at ? "deleted" : null },
+      element("td", {}, user.email),
+      element("td", {}, user.role),
+      element("td", {}, user.email_verified_at ? "Yes" : "No"),
+      element("td", {}, user.totp_enabled_at ? "On" : "Off"),
+      element("td", {}, status),
+      element("td", { class: "actions" },
+        button(`Make ${otherRole}`, () => act("PUT", `/admin/users/${user.id}/role`, { role: otherRole })),
+        locked ? button("Unlock", () => act("POST", `/users/${user.id}/unlock`)) : null,
+        user.disabled_at
+          ? button("Enable", () => act("POST", `/admin/users/${user.id}/enable`))
+          : button("Disable", () => act("POST", `/admin/users/${user.id}/disable`)),
+        button("Delete", () => {
+          if (confirm(`Delete ${user.email}? This can't be undone.`)) act("DELETE", `/admin/users/${user.id}`);
+        }, "danger")),
+    );
+  }));
+  pager(section, { page: meta.page, totalPages: meta.total_pages, hasPrevious: meta.page > 1, hasNext: meta.page < meta.total_pages }, (step) => {
+    state.page += step;
+    attempt(loadUsers);
+  });
+}
+
+// The audit log is paged by cursor; the cursors of the pages seen so far
+// are kept to go back.
+async function loadAudit() {
+  const section = $("#audit");
+  const state = pages.audit;
+  const params = filters($("form", section));
+  params.set("page_size", PAGE_SIZE);
+  params.set("cursor", state.cursors[state.cursors.length - 1]);
+  const { data, meta } = await request("GET", `/audit?${params}`);
+
+  $("tbody", section).replaceChildren(...data.map((entry) => element("tr", {},
+    element("td", {}, formatTime(entry.created_at)),
+    element("td", {}, entry.user_id ?? ""),
+    element("td", {}, entry.action),
+    element("td", {}, entry.entity),
+    element("td", { class: "mono" }, entry.entity_id),
+    element("td", {}, entry.changes
+      ? element("details", {}, element("summary", {}, `${Object.keys(entry.changes).length} columns`),
+        element("pre", {}, JSON.stringify(entry.changes, null, 2)))
+      : ""),
+  )));
+  pager(section, { page: state.cursors.length, hasPrevious: state.cursors.length > 1, hasNext: Boolean(meta.next_cursor) }, (step) => {
+    if (step > 0) state.cursors.push(meta.next_cursor);
+    else state.cursors.pop();
+    attempt(loadAudit);
+  });
+}
+
+// The exports started in this session, most recent first.
+function startedExports() {
+  return JSON.parse(sessionStorage.getItem("exports") || "[]");
+}
+
+async function renderExports() {
+  const section = $("#exports");
+  const exports = await Promise.all(startedExports().map(async (id) => {
+    try {
+      return (await request("GET", `/exports/${id}`)).data;
+    } catch (error) {
+      // Expired exports are gone.
+      if (error.status === 404) return null;
+      throw error;
+    }
+  }));
+  const current = exports.filter(Boolean);
+  sessionStorage.setItem("exports", JSON.stringify(current.map((e) => e.id)));
+
+  $("tbody", section).replaceChildren(...current.map((e) => element("tr", {},
+    element("td", {}, formatTime(e.created_at)),
+    element("td", {}, e.format.toUpperCase()),
+    element("td", { title: e.error || null }, e.status),
+    element("td", {}, e.status === "succeeded" ? e.rows : ""),
+    element("td", { class: "actions" }, e.download_url ? button("Download", () => attempt(() => download(e))) : null),
+  )));
+
+  // Poll while exports are still being built.
+  clearTimeout(renderExports.timer);
+  if (current.some((e) => e.status === "queued" || e.status === "running") && location.hash === "#exports") {
+    renderExports.timer = setTimeout(() => attempt(renderExports), 2000);
+  }
+}
+
+// download fetches the file with the admin's token, which a plain link
+// can't send, and saves it.
+async function download(e) {
+  const response = await request("GET", e.download_url.replace(API, ""), { raw: true });
+  const url = URL.createObjectURL(await response.blob());
+  const link = element("a", { href: url, download: e.filename || `export.${e.format}` });
+  document.body.append(link);
+  link.click();
+  link.remove();
+  URL.revokeObjectURL(url);
+}
+
+// Navigation and signing in.
+
+function route() {
+  const signedIn = Boolean(session.token);
+  $("#nav").hidden = !signedIn;
+  const name = signedIn ? (location.hash.slice(1) in pages ? location.hash.slice(1) : "books") : "sign-in";
+  for (const section of document.querySelectorAll("main > section")) section.hidden = section.id !== name;
+  for (const link of document.querySelectorAll("#nav a")) link.classList.toggle("current", link.hash === `#${name}`);
+  if (signedIn) attempt(pages[name].load);
+}
+
+function signOut(message) {
+  const refreshToken = session.refreshToken;
+  if (session.token && !message) {
+    // Revoked server side; signing out here doesn't wait for it.
+    request("POST", "/auth/logout", { body: { refresh_token: refreshToken }, retry: false }).catch(() => {});
+  }
+  session.clear();
+  route();
+  if (message) show(message);
+}
+
+async function signIn(event) {
+  event.preventDefault();
+  const form = event.target;
+  const input = Object.fromEntries(new FormData(form));
+  try {
+    const { data } = await request("POST", "/auth/login", { body: input, retry: false });
+    session.save(data);
+    // Only admins get anything out of the UI.
+    await request("GET", "/admin/users?page_size=1", { retry: false });
+  } catch (error) {
+    if (error.problem && error.problem.second_factor_required) {
+      $("#code-field").hidden = false;
+      $("input[name=code]", form).focus();
+      show(error.message, false);
+      return;
+    }
+    session.clear();
+    show(error.status === 403 ? "This account isn't an admin." : error.message);
+    return;
+  }
+  form.reset();
+  $("#code-field").hidden = true;
+  show("");
+  route();
+}
+
+document.addEventListener("DOMContentLoaded", () => {
+  $("#sign-in-form").addEventListener("submit", signIn);
+  $("#sign-out").addEventListener("click", () => signOut());
+
+  for (const form of document.querySelectorAll("form.filters")) {
+    form.addEventListener("submit", (event) => {
+      event.preventDefault();
+      const state = pages[form.dataset.list];
+      if ("page" in state) state.page = 1;
+      if ("cursors" in state) state.cursors = [""];
+      attempt(state.load);
+    });
+  }
+
+  $("#export-form").addEventListener("submit", (event) => {
+    event.preventDefault();
+    const { format, ...filter } = Object.fromEntries(new FormData(event.target));
+    for (const name of Object.keys(filter)) if (filter[name] === "") delete filter[name];
+    attempt(async () => {
+      const { data } = await request("POST", "/exports", { body: { format, filter } });
+      sessionStorage.setItem("exports", JSON.stringify([data.id, ...startedExports()]));
+      await renderExports();
+    });
+  });
+
+  window.addEventListener("hashchange", route);
+  route();
+});
@@ -0,0 +1,42 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/go-playground/validator/v10"
+)
+
+// From maps an arbitrary error returned by a controller, service or
+// repository to a problem. Unknown errors become a 500.
+func From(err error) *Problem {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+
+	var validationErrs validator.ValidationErrors
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var tooLarge *http.MaxBytesError
+	var includeErr *render.IncludeError
+	switch {
+	case errors.As(err, &validationErrs), errors.As(err, &syntaxErr), errors.As(err, &typeErr), errors.As(err, &tooLarge):
+		return Binding(err)
+	case errors.As(err, &includeErr):
+		return BadRequest("There is no relationship %s to include.").WithArgs(includeErr.Path)
+	case errors.Is(err, repositories.ErrNotFound):
+		return NotFound("Record not found!")
+	case errors.Is(err, repositories.ErrDuplicate):
+		return Conflict("Record already exists!")
+	case errors.Is(err, repositories.ErrStaleVersion):
+		return Conflict("Record has been modified by someone else; fetch it again and retry.")
+	case errors.Is(err, repositories.ErrUnavailable):
+		return ServiceUnavailable("The database is unavailable; try again later.")
+	}
+
+	return Internal()
+}
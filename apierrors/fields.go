@@ -0,0 +1,120 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/i18n"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/validation"
+	"github.com/go-playground/validator/v10"
+	"golang.org/x/text/language"
+)
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+
+	// The message before it was filled in, to translate.
+	format string
+	args   []interface{}
+}
+
+func newFieldError(field, format string, args ...interface{}) FieldError {
+	return FieldError{Field: field, Message: fmt.Sprintf(format, args...), format: format, args: args}
+}
+
+func (f FieldError) localize(lang language.Tag) FieldError {
+	if f.format == "" {
+		f.Message = i18n.T(lang, f.Message)
+	} else {
+		f.Message = i18n.T(lang, f.format, f.args...)
+	}
+	return f
+}
+
+// Binding turns an error from c.ShouldBind* into a validation problem with
+// an "errors" member listing every offending field.
+func Binding(err error) *Problem {
+	var validationErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		return PayloadTooLarge("Request body exceeds %d bytes.").WithArgs(tooLarge.Limit)
+	case errors.As(err, &validationErrs):
+		return Validation("One or more fields are invalid.").With("errors", FieldErrors(validationErrs))
+	case errors.As(err, &typeErr):
+		field := newFieldError(typeErr.Field, "must be "+jsonKind(typeErr.Type))
+		return Validation("One or more fields are invalid.").With("errors", []FieldError{field})
+	case errors.As(err, &syntaxErr):
+		return Validation("Request body is not valid JSON.")
+	case err.Error() == "EOF":
+		return Validation("Request body is required.")
+	}
+	return Validation(err.Error())
+}
+
+func FieldErrors(errs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(errs))
+	for _, fe := range errs {
+		fields = append(fields, fieldError(fe))
+	}
+	return fields
+}
+
+func fieldError(fe validator.FieldError) FieldError {
+	field := fe.Field()
+	switch fe.Tag() {
+	case "required":
+		return newFieldError(field, "required")
+	case "email":
+		return newFieldError(field, "must be a valid email address")
+	case "isbn", "isbn10", "isbn13":
+		return newFieldError(field, "must be a valid ISBN")
+	case "currency":
+		return newFieldError(field, "must be an ISO 4217 currency code, e.g. EUR")
+	case "publication_year":
+		return newFieldError(field, "must be between %d and %d", validation.MinPublicationYear, validation.MaxPublicationYear())
+	case "min":
+		if isString(fe) {
+			return newFieldError(field, "must be at least %s characters", fe.Param())
+		}
+		return newFieldError(field, "must be at least %s", fe.Param())
+	case "max":
+		if isString(fe) {
+			return newFieldError(field, "must be at most %s characters", fe.Param())
+		}
+		return newFieldError(field, "must be at most %s", fe.Param())
+	case "http_url":
+		return newFieldError(field, "must be an http or https URL")
+	case "oneof":
+		return newFieldError(field, "must be one of %s", fe.Param())
+	}
+	return newFieldError(field, "failed the %s check", fe.Tag())
+}
+
+func isString(fe validator.FieldError) bool {
+	return fe.Kind().String() == "string"
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a string"
+}
@@ -0,0 +1,171 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/i18n"
+	"golang.org/x/text/language"
+)
+
+const ContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Extensions are serialized
+// as additional top-level members.
+type Problem struct {
+	Type       string                 `json:"type"`
+	Title      string                 `json:"title"`
+	Status     int                    `json:"status"`
+	Detail     string                 `json:"detail,omitempty"`
+	Instance   string                 `json:"instance,omitempty"`
+	Extensions map[string]interface{} `json:"-"`
+
+	// Set by WithArgs: the detail before it was filled in, which is what
+	// gets translated.
+	format string
+	args   []interface{}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// With returns a copy of p carrying an extra extension member.
+func (p *Problem) With(key string, value interface{}) *Problem {
+	clone := *p
+	clone.Extensions = make(map[string]interface{}, len(p.Extensions)+1)
+	for k, v := range p.Extensions {
+		clone.Extensions[k] = v
+	}
+	clone.Extensions[key] = value
+	return &clone
+}
+
+// WithArgs returns a copy of p whose detail, read as a format string, is
+// filled in with args. The format is translated for the client rather than
+// the detail, so messages carrying values are translated too:
+//
+//	apierrors.NotFound("No route matches %s").WithArgs(path)
+func (p *Problem) WithArgs(args ...interface{}) *Problem {
+	clone := *p
+	clone.format, clone.args = p.Detail, args
+	clone.Detail = fmt.Sprintf(p.Detail, args...)
+	return &clone
+}
+
+// Localize returns a copy of p with its title, detail and field errors
+// translated into lang.
+func (p *Problem) Localize(lang language.Tag) *Problem {
+	clone := *p
+	clone.Title = i18n.T(lang, p.Title)
+	if p.format != "" {
+		clone.Detail = i18n.T(lang, p.format, p.args...)
+	} else if p.Detail != "" {
+		clone.Detail = i18n.T(lang, p.Detail)
+	}
+	if fields, ok := p.Extensions["errors"].([]FieldError); ok {
+		translated := make([]FieldError, len(fields))
+		for i, field := range fields {
+			translated[i] = field.localize(lang)
+		}
+		clone = *clone.With("errors", translated)
+	}
+	return &clone
+}
+
+func (p *Problem) withInstance(instance string) *Problem {
+	clone := *p
+	clone.Instance = instance
+	return &clone
+}
+
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	body := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		body[k] = v
+	}
+	body["type"] = p.Type
+	body["title"] = p.Title
+	body["status"] = p.Status
+	if p.Detail != "" {
+		body["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		body["instance"] = p.Instance
+	}
+	return json.Marshal(body)
+}
+
+// New builds a problem whose type is "about:blank" and whose title is the
+// standard reason phrase for status, as RFC 7807 recommends.
+func New(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func newTyped(slug string, status int, title, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + slug,
+		Title:  title,
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func BadRequest(detail string) *Problem {
+	return New(http.StatusBadRequest, detail)
+}
+
+func Validation(detail string) *Problem {
+	return newTyped("validation-error", http.StatusBadRequest, "Your request parameters didn't validate.", detail)
+}
+
+func Unauthorized(detail string) *Problem {
+	return New(http.StatusUnauthorized, detail)
+}
+
+func Forbidden(detail string) *Problem {
+	return New(http.StatusForbidden, detail)
+}
+
+func NotFound(detail string) *Problem {
+	return newTyped("not-found", http.StatusNotFound, "Resource not found.", detail)
+}
+
+func MethodNotAllowed(detail string) *Problem {
+	return New(http.StatusMethodNotAllowed, detail)
+}
+
+func Conflict(detail string) *Problem {
+	return New(http.StatusConflict, detail)
+}
+
+func RequestTimeout(detail string) *Problem {
+	return New(http.StatusRequestTimeout, detail)
+}
+
+func PayloadTooLarge(detail string) *Problem {
+	return New(http.StatusRequestEntityTooLarge, detail)
+}
+
+func TooManyRequests(detail string) *Problem {
+	return New(http.StatusTooManyRequests, detail)
+}
+
+func ServiceUnavailable(detail string) *Problem {
+	return newTyped("service-unavailable", http.StatusServiceUnavailable, "Service Unavailable", detail)
+}
+
+// Internal hides the underlying error from the client; it is still recorded
+// on the gin context by the middleware so it shows up in the logs.
+func Internal() *Problem {
+	return newTyped("internal-error", http.StatusInternalServerError, "Internal Server Error", "An unexpected error occurred.")
+}
@@ -0,0 +1,118 @@
+package apierrors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/i18n"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/requestid"
+	"github.com/gin-gonic/gin"
+)
+
+// Abort writes problem as application/problem+json, in the language the
+// client's Accept-Language prefers, and stops the chain. Clients asking for
+// JSON:API get a document of error objects instead.
+func Abort(c *gin.Context, problem *Problem) {
+	lang := i18n.Match(c.GetHeader("Accept-Language"))
+	problem = problem.Localize(lang)
+	c.Header("Content-Language", lang.String())
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	c.Writer.Header().Add("Vary", "Accept")
+	if problem.Instance == "" {
+		problem = problem.withInstance(c.Request.URL.Path)
+	}
+	if id := c.GetString(requestid.Key); id != "" {
+		problem = problem.With(requestid.Key, id)
+	}
+	if render.Negotiate(c.GetHeader("Accept")) == render.JSONAPI {
+		c.Render(problem.Status, problemRender{body: jsonAPIErrors(problem), contentType: render.JSONAPI})
+	} else {
+		c.Render(problem.Status, problemRender{body: problem, contentType: ContentType})
+	}
+	c.Abort()
+}
+
+// Middleware renders the last error attached with c.Error as a problem
+// response, provided the handler hasn't written a response itself.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		Abort(c, From(c.Errors.Last().Err))
+	}
+}
+
+// problemRender is a gin render.Render that keeps the problem+json, or
+// JSON:API, content type instead of gin's application/json default.
+type problemRender struct {
+	body        interface{}
+	contentType string
+}
+
+func (r problemRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.body)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (r problemRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", r.contentType)
+}
+
+// jsonAPIErrors is problem as a JSON:API errors document: one error object
+// per invalid field, pointing at it, or else a single one. The request ID
+// is the id of each, the problem type its type link, and the other
+// extensions their meta.
+func jsonAPIErrors(problem *Problem) gin.H {
+	base := gin.H{"status": strconv.Itoa(problem.Status), "title": problem.Title}
+	if problem.Type != "about:blank" {
+		base["links"] = gin.H{"type": problem.Type}
+	}
+	meta := gin.H{}
+	for key, value := range problem.Extensions {
+		switch key {
+		case "errors":
+		case requestid.Key:
+			base["id"] = value
+		default:
+			meta[key] = value
+		}
+	}
+	if len(meta) > 0 {
+		base["meta"] = meta
+	}
+	with := func(members gin.H) gin.H {
+		object := gin.H{}
+		for key, value := range base {
+			object[key] = value
+		}
+		for key, value := range members {
+			object[key] = value
+		}
+		return object
+	}
+
+	fields, _ := problem.Extensions["errors"].([]FieldError)
+	if len(fields) == 0 {
+		object := with(nil)
+		if problem.Detail != "" {
+			object["detail"] = problem.Detail
+		}
+		return gin.H{"errors": []gin.H{object}, "jsonapi": gin.H{"version": render.JSONAPIVersion}}
+	}
+	objects := make([]gin.H, len(fields))
+	for i, field := range fields {
+		objects[i] = with(gin.H{"detail": field.Message, "source": gin.H{"pointer": "/data/attributes/" + strings.ReplaceAll(field.Field, ".", "/")}})
+	}
+	return gin.H{"errors": objects, "jsonapi": gin.H{"version": render.JSONAPIVersion}}
+}
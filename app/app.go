@@ -0,0 +1,582 @@
+// Package app wires the API together, from the configuration down to the
+// routes, so that the server and the integration tests start the same
+// thing. The database is the one models.DB holds, so there is one App per
+// process at a time.
+package app
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/graph"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/grpcserver"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpcache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/idempotency"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/jobs"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/live"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/logging"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/mailer"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/metrics"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/oauth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/outbox"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/quota"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/ratelimit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/reporting"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/requestid"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/router"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/search"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/storage"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tracing"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/validation"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/webhooks"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"github.com/uptrace/opentelemetry-go-extra/otelgorm"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+	"google.golang.org/grpc"
+)
+
+// App is the API ready to serve: the HTTP server and, when configured, the
+// gRPC server and the HTTPS redirect, with the background jobs and
+// dispatchers behind them.
+type App struct {
+	Config *config.Config
+	// Handler serves the HTTP API, for tests to call in-process; Run
+	// serves it on Config.Port.
+	Handler http.Handler
+
+	server   *http.Server
+	redirect *http.Server
+	grpc     *grpc.Server
+	runner   *jobs.Runner
+	// Run by Shutdown, last added first.
+	stops []func(context.Context) error
+	// Run by Reload, and the configuration they last applied.
+	reloads []func(*config.Config) error
+	applied *config.Config
+}
+
+// Bootstrap connects to the database, migrating it if need be, see
+// CheckMigrations, and builds the repositories, services, middleware and
+// routes cfg calls for. The dispatchers and job workers start working
+// right away, though nothing is scheduled or listened on until Run. If it
+// fails, whatever it started is stopped again.
+func Bootstrap(cfg *config.Config) (_ *App, err error) {
+	if err := Connect(cfg); err != nil {
+		return nil, err
+	}
+	a := &App{Config: cfg, applied: cfg}
+	a.onShutdown(func(context.Context) error { return models.Close() })
+	defer func() {
+		if err != nil {
+			err = errors.Join(err, a.Shutdown(context.Background()))
+		}
+	}()
+
+	flushTraces, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		return nil, err
+	}
+	a.onShutdown(flushTraces)
+	if err := models.DB.Use(metrics.GormPlugin{}); err != nil {
+		return nil, err
+	}
+	if err := models.DB.Use(otelgorm.NewPlugin(otelgorm.WithoutQueryVariables())); err != nil {
+		return nil, err
+	}
+	if cfg.Database.BreakerThreshold > 0 {
+		if err := models.DB.Use(repositories.NewCircuitBreaker(cfg.Database.BreakerThreshold, cfg.Database.BreakerCooldown)); err != nil {
+			return nil, err
+		}
+	}
+	if err := Prepare(cfg); err != nil {
+		return nil, err
+	}
+
+	if err := validation.Setup(); err != nil {
+		return nil, err
+	}
+
+	checks := map[string]controllers.HealthCheck{
+		"database": models.Ping,
+	}
+
+	var redisClient *redis.Client
+	if cfg.Redis.URL != "" {
+		opts, err := redis.ParseURL(cfg.Redis.URL)
+		if err != nil {
+			return nil, err
+		}
+		redisClient = redis.NewClient(opts)
+		a.onShutdown(func(context.Context) error { return redisClient.Close() })
+		checks["redis"] = func(ctx context.Context) error { return redisClient.Ping(ctx).Err() }
+	}
+
+	a.onReload(func(cfg *config.Config) error {
+		logging.SetLevel(cfg.LogLevel)
+		auth.RotateSecret(a.Config.Auth, cfg.Auth.JWTSecret)
+		return nil
+	})
+
+	reporter, err := reporting.New(cfg.ErrorReporting)
+	if err != nil {
+		return nil, err
+	}
+	if reporter != nil {
+		a.onShutdown(reporter.Flush)
+	}
+	// The admin channel follows the loans, errors and metrics of this
+	// instance on feed.
+	feed := events.NewBroadcaster()
+	reporter = reporting.Tee(reporter, live.NewReporter(feed))
+
+	r := gin.New()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		return nil, err
+	}
+	r.Use(router.Methods(), requestid.Middleware(), otelgin.Middleware(cfg.Tracing.ServiceName), logging.Middleware(slog.Default()), metrics.Middleware(), reporting.Recovery(reporter), apierrors.Middleware())
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		r.Use(middlewares.CORS(cfg.CORS))
+	}
+	r.Use(middlewares.Timeout(cfg.RequestTimeout, "/api/v1/books/events", router.DebugProfilePath, router.AdminChannelPath), middlewares.BodyLimit(int64(cfg.MaxBodySize)))
+	if len(cfg.Database.Replicas) > 0 && cfg.Database.ReadYourWrites {
+		r.Use(middlewares.ReadYourWrites())
+	}
+	if cfg.TLS.Enabled() && cfg.TLS.HSTSMaxAge > 0 {
+		r.Use(middlewares.HSTS(cfg.TLS))
+	}
+	if cfg.Compression.Level > 0 {
+		r.Use(middlewares.Compress(cfg.Compression))
+	}
+	if cfg.RequestAudit.Enabled {
+		sink := io.Writer(os.Stdout)
+		if cfg.RequestAudit.File != "" {
+			file, err := os.OpenFile(cfg.RequestAudit.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+			if err != nil {
+				return nil, err
+			}
+			a.onShutdown(func(context.Context) error { return file.Close() })
+			sink = file
+		}
+		r.Use(middlewares.RequestAudit(cfg.RequestAudit, logging.NewSink(sink)))
+	}
+	// Installed even when disabled, as a reload may enable them.
+	limit := ratelimit.NewDefaultLimit(ratelimit.Limit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst})
+	addressLimit := ratelimit.NewDefaultLimit(ratelimit.Limit{Rate: cfg.RateLimit.AddressRate, Burst: cfg.RateLimit.AddressBurst})
+	a.onReload(func(cfg *config.Config) error {
+		limit.Set(ratelimit.Limit{Rate: cfg.RateLimit.Rate, Burst: cfg.RateLimit.Burst})
+		addressLimit.Set(ratelimit.Limit{Rate: cfg.RateLimit.AddressRate, Burst: cfg.RateLimit.AddressBurst})
+		return nil
+	})
+	var limits ratelimit.Store = ratelimit.NewMemoryStore()
+	if redisClient != nil {
+		limits = ratelimit.NewRedisStore(redisClient)
+	}
+	// Before anything authenticates callers, or fails to.
+	r.Use(ratelimit.PerAddress(limits, addressLimit))
+	// Counts the requests of whoever the middlewares that follow, and the
+	// routes, authenticate.
+	usageRepository := repositories.NewUsageRepository(models.DB)
+	meter := quota.NewMeter(usageRepository, cfg.Quotas)
+	a.onShutdown(meter.Stop)
+	r.Use(quota.Middleware(meter, cfg.Quotas))
+	// Before the rate limiter, so it counts API key requests per key.
+	apiKeyService := services.NewAPIKeyService(repositories.NewAPIKeyRepository(models.DB))
+	r.Use(middlewares.APIKeyAuth(apiKeyService))
+	var nonces auth.NonceCache = auth.NewMemoryNonces()
+	if redisClient != nil {
+		nonces = auth.NewRedisNonces(redisClient)
+	}
+	r.Use(middlewares.SignatureAuth(apiKeyService, nonces, cfg.Auth.SignatureClockSkew, int64(cfg.MaxBodySize), controllers.MaxImportSize))
+	tenantService := services.NewTenantService(repositories.NewTenantRepository(models.DB))
+	r.Use(middlewares.Tenant(cfg.Tenancy, tenantService))
+	r.Use(ratelimit.Middleware(limits, limit))
+	r.NoRoute(func(c *gin.Context) {
+		apierrors.Abort(c, apierrors.NotFound("No route matches %s").WithArgs(c.Request.URL.Path))
+	})
+
+	files, err := storage.New(cfg.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	var revoked auth.RevocationList = auth.NewMemoryRevocations()
+	if redisClient != nil {
+		revoked = auth.NewRedisRevocations(redisClient)
+	}
+	var flagStore features.Store = features.NewMemoryStore()
+	if redisClient != nil {
+		flagStore = features.NewRedisStore(redisClient)
+	}
+	flags, err := features.New(configuredFlags(cfg), flagStore, cfg.Features.Refresh)
+	if err != nil {
+		return nil, err
+	}
+	a.onReload(func(cfg *config.Config) error { return flags.Configure(configuredFlags(cfg)) })
+	// Logins stay open so admins can sign in to turn maintenance off.
+	r.Use(middlewares.Maintenance(flags, cfg.Auth, revoked, cfg.Maintenance.RetryAfter, "/healthz", "/readyz", "/metrics", "/api/v1/auth/login", "/api/v1/auth/refresh", router.AdminChannelPath))
+	var loginFailures auth.FailureCounter = auth.NewMemoryFailureCounter()
+	if redisClient != nil {
+		loginFailures = auth.NewRedisFailureCounter(redisClient)
+	}
+	var modifications httpcache.Store = httpcache.NewMemoryStore()
+	if redisClient != nil {
+		modifications = httpcache.NewRedisStore(redisClient)
+	}
+	vary := []string{"Authorization", "X-API-Key"}
+	if cfg.Tenancy.Header != "" {
+		vary = append(vary, cfg.Tenancy.Header)
+	}
+	httpCache := httpcache.New(modifications, cfg.HTTPCache, vary...)
+	if err := models.DB.Use(httpCache.GormPlugin()); err != nil {
+		return nil, err
+	}
+
+	bookRepository := repositories.NewBookRepository(models.DB)
+	authorRepository := repositories.NewAuthorRepository(models.DB)
+	publisherRepository := repositories.NewPublisherRepository(models.DB)
+	categoryRepository := repositories.NewCategoryRepository(models.DB)
+	userRepository := repositories.NewUserRepository(models.DB)
+	auditRepository := repositories.NewAuditRepository(models.DB)
+	reviewRepository := repositories.NewReviewRepository(models.DB)
+	memberRepository := repositories.NewMemberRepository(models.DB)
+	loanRepository := repositories.NewLoanRepository(models.DB)
+	stockRepository := repositories.NewStockRepository(models.DB)
+	branchRepository := repositories.NewBranchRepository(models.DB)
+	webhookRepository := repositories.NewWebhookRepository(models.DB)
+	outboxRepository := repositories.NewOutboxRepository(models.DB)
+	refreshTokenRepository := repositories.NewRefreshTokenRepository(models.DB)
+	userIdentityRepository := repositories.NewUserIdentityRepository(models.DB)
+	userTokenRepository := repositories.NewUserTokenRepository(models.DB)
+	recoveryCodeRepository := repositories.NewRecoveryCodeRepository(models.DB)
+
+	bookService := services.NewBookService(bookRepository, authorRepository, publisherRepository, categoryRepository)
+	authorService := services.NewAuthorService(authorRepository)
+	publisherService := services.NewPublisherService(publisherRepository)
+	categoryService := services.NewCategoryService(categoryRepository)
+	twoFactorService := services.NewTwoFactorService(userRepository, recoveryCodeRepository, cfg.Auth)
+	authService := services.NewAuthService(userRepository, userIdentityRepository, refreshTokenRepository, revoked, oauth.New(cfg.OAuth), twoFactorService, loginFailures, cfg.Auth)
+	userService := services.NewUserService(userRepository, refreshTokenRepository, repositories.NewTenantRepository(models.DB), revoked, cfg.Auth)
+	mail := mailer.New(cfg.Mail)
+	accountService := services.NewAccountService(userRepository, userTokenRepository, refreshTokenRepository, mail, cfg.Auth, cfg.Mail)
+	coverService := services.NewCoverService(bookRepository, files, cfg.Storage.MaxCoverPixels)
+	auditService := services.NewAuditService(auditRepository, bookRepository)
+	changeService := services.NewChangeService(auditRepository)
+	reviewService := services.NewReviewService(reviewRepository, bookRepository)
+	memberService := services.NewMemberService(memberRepository)
+	fineRepository := repositories.NewFineRepository(models.DB)
+	holdRepository := repositories.NewHoldRepository(models.DB)
+	runner := jobs.NewRunner(cfg.Jobs.Workers)
+	a.onShutdown(runner.Stop)
+	processHolds := func() {
+		// Already queued or running, it will get to the new ready hold too.
+		runner.Trigger("process-holds")
+	}
+	loanService := services.NewLoanService(loanRepository, memberRepository, fineRepository, branchRepository, cfg.Lending, processHolds)
+	loanService = services.NewPublishingLoanService(loanService, feed)
+	notificationService := services.NewNotificationService(repositories.NewNotificationRepository(models.DB), memberRepository, loanRepository, holdRepository, newNotifiers(mail, cfg.Lending.Notifications, cfg.HTTPClient, cfg.Webhooks), cfg.Lending.Notifications)
+	holdService := services.NewHoldService(holdRepository, bookRepository, memberRepository, notificationService, cfg.Lending.Holds, processHolds)
+	stockService := services.NewStockService(stockRepository, bookRepository, branchRepository)
+	lookupService := services.NewLookupService(newLookupProvider(cfg.Lookup, cfg.HTTPClient, redisClient))
+	webhookService := services.NewWebhookService(webhookRepository, cfg.Webhooks)
+	maintenanceService := services.NewMaintenanceService(bookRepository, reviewRepository, cfg.Jobs)
+	recommendationService := services.NewRecommendationService(repositories.NewRecommendationRepository(models.DB), bookRepository)
+	tagService := services.NewTagService(repositories.NewTagRepository(models.DB), bookRepository)
+
+	bus := events.NewBus()
+	dispatcher := webhooks.NewDispatcher(webhookRepository, cfg.Webhooks, cfg.HTTPClient)
+	a.onShutdown(dispatcher.Stop)
+	bus.Subscribe(dispatcher.Publish)
+	// Event streams see the changes made through every instance when they
+	// share Redis, and only this one's otherwise.
+	broadcaster := events.NewBroadcaster()
+	relay := events.NewRedisRelay(redisClient, "bookstore:events")
+	a.onShutdown(relay.Close)
+	if redisClient != nil {
+		bus.Subscribe(relay.Publish)
+		relay.Forward(broadcaster)
+	} else {
+		bus.Subscribe(broadcaster.Publish)
+	}
+	searchService := services.NewDatabaseSearchService(bookService)
+	var indexer *search.Indexer
+	if cfg.Search.URL != "" {
+		client := search.NewClient(cfg.Search)
+		indexer = search.NewIndexer(client, bookRepository)
+		bus.Subscribe(indexer.Publish)
+		a.onShutdown(indexer.Stop)
+		searchService = services.NewIndexSearchService(client, bookRepository)
+	}
+
+	// Book changes reach the bus through the outbox, once the broker has
+	// them.
+	broker, err := outbox.NewBroker(cfg.Outbox)
+	if err != nil {
+		return nil, err
+	}
+	publisher := outbox.NewDispatcher(outboxRepository, broker, bus, cfg.Outbox)
+	a.onShutdown(publisher.Stop)
+
+	// What the admin channel's invalidate-cache command drops.
+	caches := map[string]*cache.Cache{}
+	if redisClient != nil && cfg.Cache.TTL > 0 {
+		books := cache.New(redisClient, "books", cfg.Cache.TTL)
+		caches["books"] = books
+		a.onReload(func(cfg *config.Config) error {
+			books.SetTTL(cfg.Cache.TTL)
+			return nil
+		})
+		bookService = services.NewCachedBookService(bookService, books)
+		authorService = services.NewCacheInvalidatingAuthorService(authorService, books)
+		publisherService = services.NewCacheInvalidatingPublisherService(publisherService, books)
+		categoryService = services.NewCacheInvalidatingCategoryService(categoryService, books)
+		coverService = services.NewCacheInvalidatingCoverService(coverService, books)
+		reviewService = services.NewCacheInvalidatingReviewService(reviewService, books)
+		loanService = services.NewCacheInvalidatingLoanService(loanService, books)
+		holdService = services.NewCacheInvalidatingHoldService(holdService, books)
+		stockService = services.NewCacheInvalidatingStockService(stockService, books)
+		maintenanceService = services.NewCacheInvalidatingMaintenanceService(maintenanceService, books)
+		tagService = services.NewCacheInvalidatingTagService(tagService, books)
+	}
+	statsService := services.NewStatsService(repositories.NewStatsRepository(models.DB))
+	if redisClient != nil && cfg.Cache.StatsTTL > 0 {
+		stats := cache.New(redisClient, "stats", cfg.Cache.StatsTTL)
+		caches["stats"] = stats
+		a.onReload(func(cfg *config.Config) error {
+			stats.SetTTL(cfg.Cache.StatsTTL)
+			return nil
+		})
+		statsService = services.NewCachedStatsService(statsService, stats)
+	}
+
+	exportService := services.NewExportService(repositories.NewExportRepository(models.DB), files, controllers.WriteExport(bookService, coverService, categoryService), cfg.Jobs.ExportsExpireAfter, func() {
+		// Already queued or running, it will get to the new export too.
+		runner.Trigger("process-exports")
+	})
+	for _, job := range []jobs.Job{
+		{Name: "purge-deleted-books", Schedule: cfg.Jobs.PurgeSchedule, Run: maintenanceService.PurgeDeletedBooks},
+		{Name: "refresh-ratings", Schedule: cfg.Jobs.RatingsSchedule, Run: maintenanceService.RefreshRatings},
+		{Name: "overdue-loan-reminders", Schedule: cfg.Jobs.RemindersSchedule, Run: notificationService.NotifyOverdueLoans},
+		{Name: "refresh-recommendations", Schedule: cfg.Jobs.RecommendationsSchedule, Run: recommendationService.Refresh},
+		{Name: "process-holds", Schedule: cfg.Jobs.HoldsSchedule, Run: holdService.Process},
+		{Name: "process-exports", Schedule: cfg.Jobs.ExportsSchedule, Run: exportService.Process},
+	} {
+		if err := runner.Register(job); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Database.Partitioned {
+		partitionService := services.NewPartitionService(repositories.NewPartitionRepository(models.DB), cfg.Database)
+		if err := runner.Register(jobs.Job{Name: "maintain-partitions", Schedule: cfg.Jobs.PartitionsSchedule, Run: partitionService.Maintain}); err != nil {
+			return nil, err
+		}
+	}
+	if indexer != nil {
+		if err := runner.Register(jobs.Job{Name: "reindex-search", Run: indexer.Reindex}); err != nil {
+			return nil, err
+		}
+	}
+	a.runner = runner
+
+	var idempotent gin.HandlerFunc
+	if cfg.Idempotency.TTL > 0 {
+		var store idempotency.Store = idempotency.NewMemoryStore(cfg.Idempotency.TTL)
+		if redisClient != nil {
+			store = idempotency.NewRedisStore(redisClient, cfg.Idempotency.TTL)
+		}
+		idempotent = idempotency.Middleware(store)
+	}
+
+	router.Register(r, cfg.Auth, router.Controllers{
+		Batch:           controllers.NewBatchController(r, models.InTransaction),
+		Books:           controllers.NewBookController(bookService, services.NewCurrencyService(newRateProvider(cfg.Currency, cfg.HTTPClient, redisClient)), searchService),
+		BookVersions:    controllers.NewBookVersionController(services.NewBookVersionService(repositories.NewBookVersionRepository(models.DB), bookRepository, bookService)),
+		Authors:         controllers.NewAuthorController(authorService),
+		Categories:      controllers.NewCategoryController(categoryService),
+		Covers:          controllers.NewCoverController(coverService),
+		Barcodes:        controllers.NewBarcodeController(bookService),
+		Authentication:  controllers.NewAuthController(authService, accountService),
+		Health:          controllers.NewHealthController(checks),
+		Audit:           controllers.NewAuditController(auditService),
+		Changes:         controllers.NewChangeController(changeService),
+		Usage:           controllers.NewUsageController(services.NewUsageService(usageRepository)),
+		Reviews:         controllers.NewReviewController(reviewService),
+		Members:         controllers.NewMemberController(memberService),
+		Loans:           controllers.NewLoanController(loanService),
+		Notifications:   controllers.NewNotificationController(notificationService),
+		Holds:           controllers.NewHoldController(holdService),
+		Fines:           controllers.NewFineController(services.NewFineService(fineRepository)),
+		Stock:           controllers.NewStockController(stockService),
+		Lookup:          controllers.NewLookupController(lookupService),
+		Webhooks:        controllers.NewWebhookController(webhookService),
+		BookEvents:      controllers.NewBookEventController(broadcaster, cfg.Events.Heartbeat),
+		AdminChannel:    controllers.NewAdminChannelController(broadcaster, feed, live.NewCommands(flags, caches), cfg.Events, cfg.CORS.AllowedOrigins),
+		Jobs:            controllers.NewJobController(runner),
+		APIKeys:         controllers.NewAPIKeyController(apiKeyService),
+		TwoFactor:       controllers.NewTwoFactorController(twoFactorService),
+		Users:           controllers.NewUserController(userService),
+		Tenants:         controllers.NewTenantController(tenantService),
+		Stats:           controllers.NewStatsController(statsService),
+		QueryStats:      controllers.NewQueryStatsController(),
+		Publishers:      controllers.NewPublisherController(publisherService),
+		Branches:        controllers.NewBranchController(services.NewBranchService(branchRepository)),
+		Recommendations: controllers.NewRecommendationController(recommendationService),
+		ReadingLists:    controllers.NewReadingListController(services.NewReadingListService(repositories.NewReadingListRepository(models.DB), bookRepository)),
+		Series:          controllers.NewSeriesController(services.NewSeriesService(repositories.NewSeriesRepository(models.DB), bookRepository)),
+		Tags:            controllers.NewTagController(tagService),
+		Exports:         controllers.NewExportController(exportService),
+		Reports:         controllers.NewReportController(exportService, categoryService),
+		Features:        controllers.NewFeatureController(flags),
+		Flags:           flags,
+		GraphQL:         graph.NewHandler(bookService, authorService, categoryService),
+		Revoked:         revoked,
+		Idempotent:      idempotent,
+		HTTPCache:       httpCache,
+		Debug:           cfg.Debug.Enabled,
+		AdminUI:         cfg.AdminUI.Enabled,
+	})
+
+	a.Handler = r
+	a.server = &http.Server{
+		Addr:    ":" + cfg.Port,
+		Handler: r,
+		// Slow clients don't get to hold connections open indefinitely
+		// before their request even starts.
+		ReadHeaderTimeout: cfg.RequestTimeout,
+	}
+	if a.redirect, err = configureTLS(a.server, cfg.TLS, cfg.Port); err != nil {
+		return nil, err
+	}
+	// Event streams never go idle, so they are ended as shutdown starts.
+	a.server.RegisterOnShutdown(broadcaster.Close)
+	a.server.RegisterOnShutdown(feed.Close)
+	if cfg.GRPCPort != "" {
+		a.grpc = grpcserver.New(cfg.Auth, revoked, cfg.Tenancy, tenantService, bookService)
+	}
+	return a, nil
+}
+
+// onShutdown has Shutdown run fn, before whatever was added earlier.
+func (a *App) onShutdown(fn func(context.Context) error) {
+	a.stops = append(a.stops, fn)
+}
+
+// Run starts the job schedule and serves the HTTP API, over TLS if
+// configured, the HTTPS redirect and the gRPC API when there are, until ctx
+// is done or any fails, whose error it returns. Shutdown must be called
+// afterwards either way.
+func (a *App) Run(ctx context.Context) error {
+	a.runner.Start()
+
+	errs := make(chan error, 3)
+	if a.grpc != nil {
+		addr := ":" + a.Config.GRPCPort
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			slog.Info("listening for grpc", "addr", addr)
+			if err := a.grpc.Serve(lis); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	go func() {
+		slog.Info("listening", "addr", a.server.Addr, "tls", a.server.TLSConfig != nil)
+		var err error
+		if a.server.TLSConfig != nil {
+			// The certificates come from the TLS config.
+			err = a.server.ListenAndServeTLS("", "")
+		} else {
+			err = a.server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+	if a.redirect != nil {
+		go func() {
+			slog.Info("redirecting to https", "addr", a.redirect.Addr)
+			if err := a.redirect.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		}()
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+		return nil
+	}
+}
+
+// Shutdown stops accepting connections and gives in-flight requests and
+// calls until ctx is done to finish. It then stops the jobs and
+// dispatchers, flushes pending spans and closes the database, within the
+// same deadline.
+func (a *App) Shutdown(ctx context.Context) error {
+	var err error
+	if a.server != nil {
+		slog.Info("shutting down, waiting for in-flight requests")
+		// All at once, so that slow calls to one don't use up the
+		// deadline of the others.
+		var wg sync.WaitGroup
+		var serverErr, redirectErr error
+		if a.grpc != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				stopGRPC(ctx, a.grpc)
+			}()
+		}
+		if a.redirect != nil {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				redirectErr = a.redirect.Shutdown(ctx)
+			}()
+		}
+		serverErr = a.server.Shutdown(ctx)
+		wg.Wait()
+		err = errors.Join(serverErr, redirectErr)
+	}
+	for i := len(a.stops) - 1; i >= 0; i-- {
+		err = errors.Join(err, a.stops[i](ctx))
+	}
+	a.stops = nil
+	return err
+}
+
+// stopGRPC lets in-flight calls finish, then cancels whatever is still
+// running once ctx is done.
+func stopGRPC(ctx context.Context, srv *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		srv.Stop()
+	}
+}
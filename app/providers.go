@@ -0,0 +1,103 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpclient"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/lookup"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/mailer"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/notify"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/webhooks"
+	"github.com/redis/go-redis/v9"
+	"github.com/shopspring/decimal"
+)
+
+// newHTTPClient makes the requests of a service each bounded by timeout and
+// retried up to retries times.
+func newHTTPClient(cfg config.HTTPClientConfig, timeout time.Duration, retries int) *httpclient.Client {
+	return httpclient.New(httpclient.Options{
+		Timeout:          timeout,
+		Retries:          retries,
+		Backoff:          cfg.RetryBackoff,
+		BreakerThreshold: cfg.BreakerThreshold,
+		BreakerCooldown:  cfg.BreakerCooldown,
+	})
+}
+
+// newLookupProvider chains the configured catalogs behind a cache shared
+// through Redis when there is one.
+func newLookupProvider(cfg config.LookupConfig, outbound config.HTTPClientConfig, redisClient *redis.Client) lookup.Provider {
+	client := lookup.NewClient(newHTTPClient(outbound, cfg.Timeout, cfg.Retries))
+	var providers []lookup.Provider
+	if cfg.OpenLibraryURL != "" {
+		providers = append(providers, lookup.NewOpenLibrary(client, cfg.OpenLibraryURL))
+	}
+	if cfg.GoogleBooksURL != "" {
+		providers = append(providers, lookup.NewGoogleBooks(client, cfg.GoogleBooksURL, cfg.GoogleBooksAPIKey))
+	}
+
+	provider := lookup.Chain(providers...)
+	switch {
+	case cfg.CacheTTL == 0:
+		return provider
+	case redisClient != nil:
+		return lookup.Cached(provider, lookup.NewRedisStore(cache.New(redisClient, "isbn", cfg.CacheTTL)))
+	default:
+		return lookup.Cached(provider, lookup.NewMemoryStore(cfg.CacheTTL, 10000))
+	}
+}
+
+// newRateProvider fetches exchange rates from the configured API, or serves
+// the fixed ones without one, behind a cache shared through Redis when
+// there is one.
+func newRateProvider(cfg config.CurrencyConfig, outbound config.HTTPClientConfig, redisClient *redis.Client) money.Provider {
+	var provider money.Provider
+	if cfg.URL != "" {
+		provider = money.NewFrankfurter(newHTTPClient(outbound, cfg.Timeout, cfg.Retries), cfg.URL)
+	} else {
+		// Validated with the configuration.
+		rates := make(money.Rates, len(cfg.Rates))
+		for code, rate := range cfg.Rates {
+			rates[code] = decimal.RequireFromString(rate)
+		}
+		provider = money.Static(cfg.Base, rates)
+	}
+
+	switch {
+	case cfg.URL == "" || cfg.CacheTTL == 0:
+		return provider
+	case redisClient != nil:
+		return money.Cached(provider, money.NewRedisStore(cache.New(redisClient, "rates", cfg.CacheTTL)))
+	default:
+		return money.Cached(provider, money.NewMemoryStore(cfg.CacheTTL))
+	}
+}
+
+// newNotifiers returns the notifier of each channel members may choose.
+// Members' webhooks are held to the same targets as the others.
+func newNotifiers(mail mailer.Mailer, cfg config.NotificationsConfig, outbound config.HTTPClientConfig, hooks config.WebhookConfig) map[string]notify.Notifier {
+	var transport http.RoundTripper
+	if !hooks.AllowPrivateTargets {
+		transport = webhooks.Transport()
+	}
+	client := httpclient.New(httpclient.Options{
+		Timeout:          cfg.WebhookTimeout,
+		Retries:          cfg.WebhookRetries,
+		Backoff:          outbound.RetryBackoff,
+		BreakerThreshold: outbound.BreakerThreshold,
+		BreakerCooldown:  outbound.BreakerCooldown,
+		// As for webhook deliveries, a redirect fails the notification
+		// rather than being followed.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		Transport:     transport,
+	})
+	return map[string]notify.Notifier{
+		notify.ChannelEmail:   notify.Email{Mailer: mail},
+		notify.ChannelWebhook: notify.NewWebhook(client, cfg.WebhookSecret),
+		notify.ChannelConsole: notify.Console{},
+	}
+}
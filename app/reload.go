@@ -0,0 +1,86 @@
+package app
+
+import (
+	"log/slog"
+	"maps"
+	"reflect"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+)
+
+// Reload applies the settings of cfg that can change while the server runs:
+// the log level, the JWT secret, see auth.RotateSecret, the rate limit, the
+// feature flags and maintenance mode, and the cache TTLs, though turning
+// the caches on or off takes a restart. Other changes, rotated secrets
+// among them, are logged and left for the next restart: the database is
+// not reconnected to, so rotated database credentials take effect then.
+//
+// cfg must be valid, as config.Load returns it. Should applying it fail,
+// the settings applied before are applied again, and the error returned.
+func (a *App) Reload(cfg *config.Config) error {
+	if reflect.DeepEqual(cfg, a.applied) {
+		return nil
+	}
+	for i, apply := range a.reloads {
+		if err := apply(cfg); err != nil {
+			for _, undo := range a.reloads[:i] {
+				if err := undo(a.applied); err != nil {
+					slog.Error("restoring the configuration failed", "error", err)
+				}
+			}
+			return err
+		}
+	}
+	if pending := restartRequired(a.applied, cfg); len(pending) > 0 {
+		slog.Warn("configuration changes that take a restart were not applied", "settings", pending)
+	}
+	a.applied = cfg
+	return nil
+}
+
+// onReload has Reload run fn.
+func (a *App) onReload(fn func(*config.Config) error) {
+	a.reloads = append(a.reloads, fn)
+}
+
+// restartRequired returns the settings differing between cfg and next that
+// Reload doesn't apply, by their name in the configuration file.
+func restartRequired(cfg, next *config.Config) []string {
+	reloaded := *next
+	reloaded.LogLevel = cfg.LogLevel
+	reloaded.Auth.JWTSecret = cfg.Auth.JWTSecret
+	reloaded.RateLimit = cfg.RateLimit
+	reloaded.Features.Flags = cfg.Features.Flags
+	reloaded.Maintenance.Enabled = cfg.Maintenance.Enabled
+	if (cfg.Cache.TTL > 0) == (next.Cache.TTL > 0) {
+		reloaded.Cache.TTL = cfg.Cache.TTL
+	}
+	if (cfg.Cache.StatsTTL > 0) == (next.Cache.StatsTTL > 0) {
+		reloaded.Cache.StatsTTL = cfg.Cache.StatsTTL
+	}
+
+	var changed []string
+	before, after := reflect.ValueOf(*cfg), reflect.ValueOf(reloaded)
+	for i := 0; i < before.NumField(); i++ {
+		if !reflect.DeepEqual(before.Field(i).Interface(), after.Field(i).Interface()) {
+			name, _, _ := strings.Cut(before.Type().Field(i).Tag.Get("yaml"), ",")
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// configuredFlags are the feature flags cfg sets, maintenance mode among
+// them.
+func configuredFlags(cfg *config.Config) map[string]bool {
+	flags := maps.Clone(cfg.Features.Flags)
+	if cfg.Maintenance.Enabled {
+		if flags == nil {
+			flags = map[string]bool{}
+		}
+		flags[features.Maintenance] = true
+	}
+	return flags
+}
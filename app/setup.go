@@ -0,0 +1,67 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/audit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/fieldcrypt"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/logging"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/migrations"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/outbox"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/querystats"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Connect sets up logging and field encryption as cfg says and connects
+// to the database, which every command needs.
+func Connect(cfg *config.Config) error {
+	gin.SetMode(cfg.GinMode)
+	logging.New(cfg.LogLevel)
+
+	// Before anything reads or writes encrypted fields, migrations included.
+	if err := fieldcrypt.Setup(cfg.Encryption); err != nil {
+		return err
+	}
+	if !fieldcrypt.Enabled() {
+		slog.Warn("no encryption key configured, sensitive fields are stored in plaintext")
+	}
+
+	querystats.Setup(cfg.Database)
+	return models.ConnectDatabase(cfg.Database)
+}
+
+// Prepare gets the schema up to date, see CheckMigrations, and then
+// installs the plugins recording changes in the audit log and the outbox,
+// for the commands that write data.
+func Prepare(cfg *config.Config) error {
+	if err := CheckMigrations(models.DB, cfg.GinMode); err != nil {
+		return err
+	}
+	// Only once migrated: the plugin writes to audit_logs, which the
+	// migrations themselves must not do.
+	if err := models.DB.Use(audit.GormPlugin{}); err != nil {
+		return err
+	}
+	return models.DB.Use(outbox.GormPlugin{})
+}
+
+// CheckMigrations applies pending migrations in development, but refuses to
+// boot in release mode so a deploy can't silently run against an old schema.
+func CheckMigrations(db *gorm.DB, mode string) error {
+	pending, err := migrations.Pending(db)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if mode == gin.ReleaseMode {
+		return fmt.Errorf("pending migrations %v; run `migrate up` before starting the server", pending)
+	}
+	slog.Info("applying pending migrations", "migrations", pending)
+	return migrations.Up(db)
+}
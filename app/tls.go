@@ -0,0 +1,66 @@
+package app
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// configureTLS makes srv serve HTTPS as cfg says, and returns the server
+// for cfg.RedirectPort, or nil when there is none. port is the one srv
+// listens on, which redirects point to.
+func configureTLS(srv *http.Server, cfg config.TLSConfig, port string) (*http.Server, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+
+	redirect := redirectToHTTPS(port)
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		srv.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	} else {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.AutocertDomains...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		// Answers the TLS-ALPN challenges on port itself.
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		// Answers the HTTP challenges and redirects everything else.
+		redirect = manager.HTTPHandler(redirect)
+	}
+
+	if cfg.RedirectPort == "" {
+		return nil, nil
+	}
+	return &http.Server{
+		Addr:              ":" + cfg.RedirectPort,
+		Handler:           redirect,
+		ReadHeaderTimeout: 10 * time.Second,
+	}, nil
+}
+
+// redirectToHTTPS sends requests to the same URL over HTTPS on port. 308
+// rather than 301, so that clients repeat other methods than GET as they
+// were.
+func redirectToHTTPS(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			host = r.Host
+		}
+		if port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusPermanentRedirect)
+	})
+}
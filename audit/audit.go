@@ -0,0 +1,219 @@
+// Package audit records every row GORM creates, updates or deletes in the
+// audit_logs table, along with the user and request that caused it.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/requestid"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Tables whose writes are never recorded.
+var skippedTables = map[string]bool{
+	"audit_logs":         true,
+	"book_versions":      true,
+	"branch_stocks":      true,
+	"daily_usages":       true,
+	"migrations":         true,
+	"outbox_events":      true,
+	"recovery_codes":     true,
+	"refresh_tokens":     true,
+	"sent_notifications": true,
+	"stock_transfers":    true,
+	"user_tokens":        true,
+	"webhook_deliveries": true,
+}
+
+// Written in place of the values of fields tagged `audit:"-"`.
+const redacted = "[redacted]"
+
+// GormPlugin writes the audit entries in the same transaction as the change
+// they describe, so a change can't be committed without its entry. Reads and
+// raw SQL are not recorded; see Record for the latter.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string { return "audit" }
+
+// GORM drops the SET clause once an UPDATE has run, so the builder keeps a
+// copy under this key for the after-update callback.
+const setKey = "audit:set"
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	build := db.ClauseBuilders["SET"]
+	db.ClauseBuilders["SET"] = func(c clause.Clause, builder clause.Builder) {
+		if stmt, ok := builder.(*gorm.Statement); ok {
+			stmt.DB.InstanceSet(setKey, c.Expression)
+		}
+		if build != nil {
+			build(c, builder)
+		} else {
+			c.Build(builder)
+		}
+	}
+
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("audit:create", record(models.AuditCreate)); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("audit:update", record(models.AuditUpdate)); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("audit:delete", record(models.AuditDelete))
+}
+
+func record(action string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		stmt := db.Statement
+		if db.Error != nil || db.RowsAffected == 0 || stmt.Schema == nil || skippedTables[stmt.Table] {
+			return
+		}
+
+		var changes models.JSONObject
+		if action == models.AuditUpdate {
+			changes = assignments(db)
+			if changes != nil && len(changes) == 0 {
+				// Only updated_at was touched, e.g. by saving associations.
+				return
+			}
+		}
+
+		var entries []models.AuditLog
+		eachRow(stmt.ReflectValue, func(row reflect.Value) {
+			entry := newEntry(stmt.Context, action, stmt.Table)
+			entry.EntityID = primaryKey(stmt.Context, stmt.Schema, row)
+			if tenantID, ok := tenant(stmt.Context, stmt.Schema, row); ok {
+				entry.TenantID = &tenantID
+			}
+			entry.Changes = changes
+			if action == models.AuditCreate {
+				entry.Changes = columns(stmt.Context, stmt.Schema, row)
+			}
+			entries = append(entries, entry)
+		})
+		if len(entries) == 0 {
+			// A statement on a bare model, e.g. Where(...).Delete(&Book{}).
+			entries = append(entries, newEntry(stmt.Context, action, stmt.Table))
+		}
+
+		if err := db.Session(&gorm.Session{NewDB: true}).Create(&entries).Error; err != nil {
+			db.AddError(err)
+		}
+	}
+}
+
+// Record writes the entries of a change made with raw SQL, which GormPlugin
+// doesn't see: one for each of the rows of table, by primary key, that
+// action changed, with the column values written.
+func Record(db *gorm.DB, action, table string, ids []string, changes models.JSONObject) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	entries := make([]models.AuditLog, len(ids))
+	for i, id := range ids {
+		entries[i] = newEntry(db.Statement.Context, action, table)
+		entries[i].EntityID = id
+		entries[i].Changes = changes
+	}
+	return db.Session(&gorm.Session{NewDB: true}).Create(&entries).Error
+}
+
+func newEntry(ctx context.Context, action, table string) models.AuditLog {
+	entry := models.AuditLog{Action: action, Entity: table, RequestID: requestid.FromContext(ctx)}
+	if identity, ok := auth.FromContext(ctx); ok {
+		entry.UserID = &identity.UserID
+	}
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		entry.TenantID = &tenantID
+	}
+	return entry
+}
+
+// tenant returns the tenant row belongs to, if its table has one: a job
+// acting for no tenant in particular still changes some tenant's rows.
+func tenant(ctx context.Context, s *schema.Schema, row reflect.Value) (uint, bool) {
+	field := s.LookUpField("tenant_id")
+	if field == nil {
+		return 0, false
+	}
+	value, zero := field.ValueOf(ctx, row)
+	id, ok := value.(uint)
+	return id, ok && !zero
+}
+
+func eachRow(value reflect.Value, fn func(reflect.Value)) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			fn(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		fn(value)
+	}
+}
+
+// primaryKey returns "" unless every primary key column of row is set.
+func primaryKey(ctx context.Context, s *schema.Schema, row reflect.Value) string {
+	if len(s.PrimaryFields) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(s.PrimaryFields))
+	for _, field := range s.PrimaryFields {
+		value, zero := field.ValueOf(ctx, row)
+		if zero {
+			return ""
+		}
+		parts = append(parts, fmt.Sprint(value))
+	}
+	return strings.Join(parts, ",")
+}
+
+func columns(ctx context.Context, s *schema.Schema, row reflect.Value) models.JSONObject {
+	values := models.JSONObject{}
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.PrimaryKey {
+			continue
+		}
+		value, _ := field.ValueOf(ctx, row)
+		values[field.DBName] = redact(field, value)
+	}
+	return values
+}
+
+// assignments returns the columns an UPDATE set, minus updated_at which
+// every update touches.
+func assignments(db *gorm.DB) models.JSONObject {
+	expr, _ := db.InstanceGet(setKey)
+	set, ok := expr.(clause.Set)
+	if !ok {
+		return nil
+	}
+	values := models.JSONObject{}
+	for _, assignment := range set {
+		if assignment.Column.Name == "updated_at" {
+			continue
+		}
+		value := assignment.Value
+		if expr, ok := value.(clause.Expr); ok {
+			value = expr.SQL
+		}
+		values[assignment.Column.Name] = redact(db.Statement.Schema.LookUpField(assignment.Column.Name), value)
+	}
+	return values
+}
+
+func redact(field *schema.Field, value interface{}) interface{} {
+	if field != nil && field.Tag.Get("audit") == "-" {
+		return redacted
+	}
+	return value
+}
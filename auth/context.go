@@ -0,0 +1,17 @@
+package auth
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the caller's identity, so code
+// below the HTTP layer (e.g. the audit log) can tell who made a change.
+func NewContext(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity stored by NewContext, if any.
+func FromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(contextKey{}).(Identity)
+	return identity, ok
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// FailureCounter counts failed logins per key, such as a client IP
+// address, within a window starting at the first failure.
+// MemoryFailureCounter counts per process; RedisFailureCounter shares the
+// counts between instances.
+type FailureCounter interface {
+	// Add counts a failure under key, starting a window of the given length
+	// if none is running.
+	Add(ctx context.Context, key string, window time.Duration) error
+	// Count returns the failures under key in the running window and when
+	// it ends.
+	Count(ctx context.Context, key string) (int, time.Time, error)
+}
+
+type failureWindow struct {
+	count int
+	ends  time.Time
+}
+
+type MemoryFailureCounter struct {
+	mu        sync.Mutex
+	windows   map[string]*failureWindow
+	lastSweep time.Time
+}
+
+func NewMemoryFailureCounter() *MemoryFailureCounter {
+	return &MemoryFailureCounter{windows: map[string]*failureWindow{}, lastSweep: time.Now()}
+}
+
+func (c *MemoryFailureCounter) Add(_ context.Context, key string, window time.Duration) error {
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sweep(now)
+	w, ok := c.windows[key]
+	if !ok || !now.Before(w.ends) {
+		w = &failureWindow{ends: now.Add(window)}
+		c.windows[key] = w
+	}
+	w.count++
+	return nil
+}
+
+func (c *MemoryFailureCounter) Count(_ context.Context, key string) (int, time.Time, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w, ok := c.windows[key]
+	if !ok || !time.Now().Before(w.ends) {
+		return 0, time.Time{}, nil
+	}
+	return w.count, w.ends, nil
+}
+
+// sweep drops ended windows. It runs at most once a minute.
+func (c *MemoryFailureCounter) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < time.Minute {
+		return
+	}
+	c.lastSweep = now
+	for key, w := range c.windows {
+		if !now.Before(w.ends) {
+			delete(c.windows, key)
+		}
+	}
+}
+
+type RedisFailureCounter struct {
+	client *redis.Client
+}
+
+func NewRedisFailureCounter(client *redis.Client) *RedisFailureCounter {
+	return &RedisFailureCounter{client: client}
+}
+
+func (c *RedisFailureCounter) Add(ctx context.Context, key string, window time.Duration) error {
+	count, err := c.client.Incr(ctx, failuresKey(key)).Result()
+	if err != nil || count > 1 {
+		return err
+	}
+	return c.client.PExpire(ctx, failuresKey(key), window).Err()
+}
+
+func (c *RedisFailureCounter) Count(ctx context.Context, key string) (int, time.Time, error) {
+	pipe := c.client.Pipeline()
+	count := pipe.Get(ctx, failuresKey(key))
+	ttl := pipe.PTTL(ctx, failuresKey(key))
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return 0, time.Time{}, err
+	}
+	n, err := count.Int()
+	if errors.Is(err, redis.Nil) || ttl.Val() <= 0 {
+		return 0, time.Time{}, nil
+	}
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return n, time.Now().Add(ttl.Val()), nil
+}
+
+func failuresKey(key string) string {
+	return "login_failures:" + key
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// NonceCache remembers the nonces of signed requests for as long as their
+// timestamps are accepted, so a request can't be replayed meanwhile.
+// MemoryNonces remembers them per process; RedisNonces shares them
+// between instances.
+type NonceCache interface {
+	// Use records nonce for ttl and reports whether it was new.
+	Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error)
+}
+
+type MemoryNonces struct {
+	mu        sync.Mutex
+	used      map[string]time.Time
+	lastSweep time.Time
+}
+
+func NewMemoryNonces() *MemoryNonces {
+	return &MemoryNonces{used: map[string]time.Time{}, lastSweep: time.Now()}
+}
+
+func (n *MemoryNonces) Use(_ context.Context, nonce string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.sweep(now)
+	if until, ok := n.used[nonce]; ok && now.Before(until) {
+		return false, nil
+	}
+	n.used[nonce] = now.Add(ttl)
+	return true, nil
+}
+
+// sweep drops the nonces that are no longer needed. It runs at most once a
+// minute.
+func (n *MemoryNonces) sweep(now time.Time) {
+	if now.Sub(n.lastSweep) < time.Minute {
+		return
+	}
+	n.lastSweep = now
+	for nonce, until := range n.used {
+		if !now.Before(until) {
+			delete(n.used, nonce)
+		}
+	}
+}
+
+type RedisNonces struct {
+	client *redis.Client
+}
+
+func NewRedisNonces(client *redis.Client) *RedisNonces {
+	return &RedisNonces{client: client}
+}
+
+func (n *RedisNonces) Use(ctx context.Context, nonce string, ttl time.Duration) (bool, error) {
+	return n.client.SetNX(ctx, "signature_nonce:"+nonce, 1, ttl).Result()
+}
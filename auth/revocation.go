@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RevocationList holds the IDs of access tokens revoked before they
+// expired, such as those of logged out sessions, and the users all of whose
+// tokens issued so far were, such as demoted or disabled ones. Either only
+// needs to be kept until the tokens expire. MemoryRevocations keeps them
+// per process; RedisRevocations shares them between instances.
+type RevocationList interface {
+	Revoke(ctx context.Context, tokenID string, until time.Time) error
+	// RevokeUser revokes the tokens issued to the user up to now. Token
+	// times are whole seconds, so those issued later in the same second
+	// are revoked too.
+	RevokeUser(ctx context.Context, userID uint, until time.Time) error
+	Revoked(ctx context.Context, identity Identity) (bool, error)
+}
+
+// Authenticate is ParseToken for tokens that may have been revoked: those on
+// revoked, which may be nil, are invalid too. If revoked can't be checked
+// the token is accepted rather than locking everyone out.
+func Authenticate(ctx context.Context, cfg config.AuthConfig, revoked RevocationList, token string) (Identity, error) {
+	identity, err := ParseToken(cfg, token)
+	if err != nil || revoked == nil || identity.TokenID == "" {
+		return identity, err
+	}
+	isRevoked, err := revoked.Revoked(ctx, identity)
+	if err != nil {
+		slog.WarnContext(ctx, "token revocation list unavailable", "error", err)
+		return identity, nil
+	}
+	if isRevoked {
+		return Identity{}, ErrInvalidToken
+	}
+	return identity, nil
+}
+
+// userRevocation revokes the tokens of a user issued up to before.
+type userRevocation struct {
+	before, until time.Time
+}
+
+type MemoryRevocations struct {
+	mu        sync.Mutex
+	revoked   map[string]time.Time
+	users     map[uint]userRevocation
+	lastSweep time.Time
+}
+
+func NewMemoryRevocations() *MemoryRevocations {
+	return &MemoryRevocations{revoked: map[string]time.Time{}, users: map[uint]userRevocation{}, lastSweep: time.Now()}
+}
+
+func (l *MemoryRevocations) Revoke(_ context.Context, tokenID string, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(time.Now())
+	l.revoked[tokenID] = until
+	return nil
+}
+
+func (l *MemoryRevocations) RevokeUser(_ context.Context, userID uint, until time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+	l.users[userID] = userRevocation{before: now, until: until}
+	return nil
+}
+
+func (l *MemoryRevocations) Revoked(_ context.Context, identity Identity) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if until, ok := l.revoked[identity.TokenID]; ok && now.Before(until) {
+		return true, nil
+	}
+	user, ok := l.users[identity.UserID]
+	return ok && now.Before(user.until) && issuedBy(identity, user.before), nil
+}
+
+// issuedBy reports whether the token of identity was issued in the second
+// of t or before.
+func issuedBy(identity Identity, t time.Time) bool {
+	return !identity.IssuedAt.After(t.Truncate(time.Second))
+}
+
+// sweep drops the IDs of expired tokens. It runs at most once a minute.
+func (l *MemoryRevocations) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for id, until := range l.revoked {
+		if !now.Before(until) {
+			delete(l.revoked, id)
+		}
+	}
+	for id, user := range l.users {
+		if !now.Before(user.until) {
+			delete(l.users, id)
+		}
+	}
+}
+
+type RedisRevocations struct {
+	client *redis.Client
+}
+
+func NewRedisRevocations(client *redis.Client) *RedisRevocations {
+	return &RedisRevocations{client: client}
+}
+
+func (l *RedisRevocations) Revoke(ctx context.Context, tokenID string, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return l.client.Set(ctx, redisKey(tokenID), 1, ttl).Err()
+}
+
+// RevokeUser stores the time tokens are revoked up to, in Unix seconds.
+func (l *RedisRevocations) RevokeUser(ctx context.Context, userID uint, until time.Time) error {
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+	return l.client.Set(ctx, redisUserKey(userID), time.Now().Unix(), ttl).Err()
+}
+
+// Revoked looks both the token and its user up in one round trip.
+func (l *RedisRevocations) Revoked(ctx context.Context, identity Identity) (bool, error) {
+	values, err := l.client.MGet(ctx, redisKey(identity.TokenID), redisUserKey(identity.UserID)).Result()
+	if err != nil {
+		return false, err
+	}
+	if values[0] != nil {
+		return true, nil
+	}
+	before, ok := values[1].(string)
+	if !ok {
+		return false, nil
+	}
+	seconds, err := strconv.ParseInt(before, 10, 64)
+	if err != nil {
+		return false, err
+	}
+	return issuedBy(identity, time.Unix(seconds, 0)), nil
+}
+
+func redisKey(tokenID string) string {
+	return "revoked_token:" + tokenID
+}
+
+func redisUserKey(userID uint) string {
+	return "revoked_user_tokens:" + strconv.FormatUint(uint64(userID), 10)
+}
@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// The headers of a signed request. The signature is the hex HMAC-SHA256,
+// keyed with the API key, of StringToSign.
+const (
+	SignatureKeyHeader       = "X-Signature-Key-Id"
+	SignatureTimestampHeader = "X-Signature-Timestamp"
+	SignatureNonceHeader     = "X-Signature-Nonce"
+	SignatureHeader          = "X-Signature"
+	// The hex SHA-256 of the body, that of no bytes when there is none.
+	ContentDigestHeader = "X-Content-SHA256"
+)
+
+// Digest returns the hex SHA-256 of body, as ContentDigestHeader has it.
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// StringToSign is what a request signature covers: the method, the path
+// with its query string as sent, the Unix timestamp in seconds, the nonce
+// and the body digest, one per line.
+func StringToSign(method, uri, timestamp, nonce, digest string) string {
+	return strings.Join([]string{strings.ToUpper(method), uri, timestamp, nonce, digest}, "\n")
+}
+
+// Sign returns the signature of a request with key.
+func Sign(key, method, uri, timestamp, nonce, digest string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(StringToSign(method, uri, timestamp, nonce, digest)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature tells, in constant time, whether signature is that of
+// the request with key.
+func VerifySignature(key, signature, method, uri, timestamp, nonce, digest string) bool {
+	expected := Sign(key, method, uri, timestamp, nonce, digest)
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signature)))
+}
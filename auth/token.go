@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	Role string `json:"role"`
+	// The tenant the user is a member of, if only one.
+	Tenant *uint `json:"tenant,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Identity is what a valid token tells us about the caller.
+type Identity struct {
+	UserID uint
+	Role   string
+	// The tenant the caller is a member of, or nil if they are a member of
+	// every tenant.
+	TenantID *uint
+	// The ID, issue time and expiry of the token, for revoking it; unset
+	// for callers not authenticated by a token.
+	TokenID   string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+// rotated holds the JWT secret once it was rotated at runtime, replacing the
+// configured one, and the secret it replaced, until the tokens signed with
+// that have expired.
+var rotated struct {
+	sync.RWMutex
+	current, previous string
+	at                time.Time
+}
+
+// RotateSecret has tokens signed with secret from now on, rather than with
+// the configured secret or the one set before. Tokens signed with the
+// secret it replaces stay valid until they expire, at most a TokenTTL from
+// now; rotating again within that cuts them short.
+func RotateSecret(cfg config.AuthConfig, secret string) {
+	rotated.Lock()
+	defer rotated.Unlock()
+	current := rotated.current
+	if current == "" {
+		current = cfg.JWTSecret
+	}
+	if secret != current {
+		rotated.current, rotated.previous, rotated.at = secret, current, time.Now()
+	}
+}
+
+// MemberOf reports whether the caller may act for the tenant.
+func (identity Identity) MemberOf(tenantID uint) bool {
+	return identity.TenantID == nil || *identity.TenantID == tenantID
+}
+
+// secrets returns the secret tokens are signed with, then the one they
+// may still be signed with, if any, and when it was replaced. The previous
+// secret is forgotten once the tokens it signed have expired.
+func secrets(cfg config.AuthConfig) (string, string, time.Time) {
+	rotated.RLock()
+	current, previous, at := rotated.current, rotated.previous, rotated.at
+	rotated.RUnlock()
+	if current == "" {
+		return cfg.JWTSecret, "", time.Time{}
+	}
+	if previous != "" && time.Since(at) >= cfg.TokenTTL {
+		rotated.Lock()
+		if rotated.at.Equal(at) {
+			rotated.previous = ""
+		}
+		rotated.Unlock()
+		previous = ""
+	}
+	return current, previous, at
+}
+
+// GenerateToken issues a signed HS256 token whose subject is the user ID,
+// with a random ID to revoke it by. tenantID is the tenant the user is a
+// member of, nil for every tenant.
+func GenerateToken(cfg config.AuthConfig, userID uint, role string, tenantID *uint) (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	claims := Claims{
+		Role:   role,
+		Tenant: tenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(id),
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(cfg.TokenTTL)),
+		},
+	}
+	secret, _, _ := secrets(cfg)
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseToken validates the signature and expiry and returns the caller's identity.
+func ParseToken(cfg config.AuthConfig, tokenString string) (Identity, error) {
+	var claims Claims
+	current, previous, rotatedAt := secrets(cfg)
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+		return []byte(current), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if errors.Is(err, jwt.ErrTokenSignatureInvalid) && previous != "" {
+		claims = Claims{}
+		_, err = jwt.ParseWithClaims(tokenString, &claims, func(*jwt.Token) (interface{}, error) {
+			return []byte(previous), nil
+		}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		// Only tokens issued before the rotation, to expire when they
+		// would have then, were signed with it.
+		if err == nil && (claims.IssuedAt == nil || claims.IssuedAt.After(rotatedAt) ||
+			claims.ExpiresAt == nil || claims.ExpiresAt.After(rotatedAt.Add(cfg.TokenTTL))) {
+			err = ErrInvalidToken
+		}
+	}
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		return Identity{}, ErrInvalidToken
+	}
+	identity := Identity{UserID: uint(userID), Role: claims.Role, TenantID: claims.Tenant, TokenID: claims.ID}
+	if claims.IssuedAt != nil {
+		identity.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		identity.ExpiresAt = claims.ExpiresAt.Time
+	}
+	return identity, nil
+}
@@ -0,0 +1,177 @@
+// Package barcodes draws the codes stuck on books for inventory: an EAN-13
+// of the ISBN, which any scanner reads, or a QR code of the book's ID for
+// books without one.
+package barcodes
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/ean"
+	"github.com/boombuler/barcode/qr"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+// What a code encodes.
+const (
+	EncodeISBN = "isbn"
+	EncodeID   = "id"
+)
+
+// The image formats a code is written in.
+const (
+	PNG = "png"
+	SVG = "svg"
+)
+
+var ErrNoISBN = errors.New("book has no valid ISBN to encode")
+
+// Blank modules around the code, which scanners need to find it.
+const (
+	linearQuietZone = 10
+	matrixQuietZone = 4
+)
+
+// Bars of linear codes are drawn this many times as tall as a module is
+// wide, about the proportions of a printed EAN-13.
+const barHeight = 60
+
+// Code is a barcode of a book, one module per unit.
+type Code struct {
+	code barcode.Barcode
+	// Text is what the code encodes, printed under it on labels.
+	Text string
+}
+
+// For returns the code encoding the book's ISBN or ID. An empty encode
+// means the ISBN when the book has a valid one, the ID otherwise; EncodeISBN
+// fails with ErrNoISBN when it hasn't.
+func For(book *models.Book, encode string) (*Code, error) {
+	number := isbn.To13(book.ISBN)
+	switch {
+	case encode == EncodeISBN && number == "":
+		return nil, ErrNoISBN
+	case encode == EncodeISBN, encode == "" && number != "":
+		code, err := ean.Encode(number)
+		if err != nil {
+			return nil, err
+		}
+		return &Code{code: code, Text: number}, nil
+	case encode == EncodeID, encode == "":
+		text := book.ID.String()
+		code, err := qr.Encode(text, qr.M, qr.Auto)
+		if err != nil {
+			return nil, err
+		}
+		return &Code{code: code, Text: text}, nil
+	}
+	return nil, fmt.Errorf("unknown barcode encoding %q", encode)
+}
+
+// Linear reports whether the code is a row of bars rather than a matrix.
+func (c *Code) Linear() bool {
+	return c.code.Metadata().Dimensions == 1
+}
+
+// Size returns how many modules wide and tall the code is, without its
+// quiet zone. Linear codes are barHeight tall.
+func (c *Code) Size() (width, height int) {
+	bounds := c.code.Bounds()
+	if c.Linear() {
+		return bounds.Dx(), barHeight
+	}
+	return bounds.Dx(), bounds.Dy()
+}
+
+// QuietZone returns how many blank modules should surround the code.
+func (c *Code) QuietZone() int {
+	if c.Linear() {
+		return linearQuietZone
+	}
+	return matrixQuietZone
+}
+
+// Run is a horizontal stretch of dark modules.
+type Run struct {
+	X, Y, Width int
+	// Height is 1 in matrices, barHeight in linear codes.
+	Height int
+}
+
+// Runs returns the dark parts of the code, row by row, merging adjacent
+// modules so they are drawn without seams.
+func (c *Code) Runs() []Run {
+	bounds := c.code.Bounds()
+	rows, height := bounds.Dy(), 1
+	if c.Linear() {
+		rows, height = 1, barHeight
+	}
+	var runs []Run
+	for y := 0; y < rows; y++ {
+		start := -1
+		for x := 0; x <= bounds.Dx(); x++ {
+			dark := x < bounds.Dx() && c.dark(x, y)
+			switch {
+			case dark && start < 0:
+				start = x
+			case !dark && start >= 0:
+				runs = append(runs, Run{X: start, Y: y * height, Width: x - start, Height: height})
+				start = -1
+			}
+		}
+	}
+	return runs
+}
+
+func (c *Code) dark(x, y int) bool {
+	bounds := c.code.Bounds()
+	gray := color.GrayModel.Convert(c.code.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.Gray)
+	return gray.Y < 128
+}
+
+// WritePNG draws the code, with its quiet zone, scale pixels per module.
+func (c *Code) WritePNG(w io.Writer, scale int) error {
+	width, height := c.Size()
+	quiet := c.QuietZone()
+	img := image.NewGray(image.Rect(0, 0, (width+2*quiet)*scale, (height+2*quiet)*scale))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for _, run := range c.Runs() {
+		for y := (quiet + run.Y) * scale; y < (quiet+run.Y+run.Height)*scale; y++ {
+			row := img.Pix[y*img.Stride:]
+			for x := (quiet + run.X) * scale; x < (quiet+run.X+run.Width)*scale; x++ {
+				row[x] = 0
+			}
+		}
+	}
+	return png.Encode(w, img)
+}
+
+// WriteSVG draws the code, with its quiet zone, one user unit per module,
+// scale pixels per module by default.
+func (c *Code) WriteSVG(w io.Writer, scale int) error {
+	width, height := c.Size()
+	quiet := c.QuietZone()
+	fullWidth, fullHeight := width+2*quiet, height+2*quiet
+	if _, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`+"\n",
+		fullWidth*scale, fullHeight*scale, fullWidth, fullHeight); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, `<rect width="%d" height="%d" fill="#fff"/>`+"\n"+`<path fill="#000" d="`, fullWidth, fullHeight); err != nil {
+		return err
+	}
+	for _, run := range c.Runs() {
+		if _, err := fmt.Fprintf(w, "M%d %dh%dv%dh-%dz", quiet+run.X, quiet+run.Y, run.Width, run.Height, run.Width); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "\"/>\n</svg>\n")
+	return err
+}
@@ -0,0 +1,90 @@
+// Package cache is a small Redis-backed cache for read endpoints.
+//
+// Entries are never deleted one by one. Every key embeds the namespace's
+// current generation, and invalidating the namespace just increments it:
+// older entries become unreachable and expire on their own.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/metrics"
+	"github.com/redis/go-redis/v9"
+)
+
+type Cache struct {
+	client    *redis.Client
+	namespace string
+	// A time.Duration, which SetTTL changes.
+	ttl atomic.Int64
+}
+
+func New(client *redis.Client, namespace string, ttl time.Duration) *Cache {
+	c := &Cache{client: client, namespace: namespace}
+	c.SetTTL(ttl)
+	return c
+}
+
+// SetTTL changes how long the entries cached from now on are kept.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.ttl.Store(int64(ttl))
+}
+
+// Fetch returns the value cached under key, or calls load, caches its result
+// and returns it. Redis errors are logged and fall back to load, so an
+// outage slows reads down instead of failing them.
+func Fetch[T any](ctx context.Context, c *Cache, key string, load func() (T, error)) (T, error) {
+	fullKey, err := c.key(ctx, key)
+	if err != nil {
+		slog.WarnContext(ctx, "cache unavailable", "cache", c.namespace, "error", err)
+		return load()
+	}
+
+	var value T
+	data, err := c.client.Get(ctx, fullKey).Bytes()
+	if err == nil && json.Unmarshal(data, &value) == nil {
+		metrics.CacheHit(c.namespace)
+		return value, nil
+	}
+	if err != nil && !errors.Is(err, redis.Nil) {
+		slog.WarnContext(ctx, "cache read failed", "cache", c.namespace, "error", err)
+	}
+	metrics.CacheMiss(c.namespace)
+
+	value, err = load()
+	if err != nil {
+		return value, err
+	}
+	if data, err := json.Marshal(value); err == nil {
+		if err := c.client.Set(ctx, fullKey, data, time.Duration(c.ttl.Load())).Err(); err != nil {
+			slog.WarnContext(ctx, "cache write failed", "cache", c.namespace, "error", err)
+		}
+	}
+	return value, nil
+}
+
+// Invalidate drops every entry in the namespace. It must be called after the
+// write it accounts for has been committed.
+func (c *Cache) Invalidate(ctx context.Context) {
+	if err := c.client.Incr(ctx, c.generationKey()).Err(); err != nil {
+		slog.WarnContext(ctx, "cache invalidation failed", "cache", c.namespace, "error", err)
+	}
+}
+
+func (c *Cache) key(ctx context.Context, key string) (string, error) {
+	generation, err := c.client.Get(ctx, c.generationKey()).Int64()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "", err
+	}
+	return "cache:" + c.namespace + ":" + strconv.FormatInt(generation, 10) + ":" + key, nil
+}
+
+func (c *Cache) generationKey() string {
+	return "cache:" + c.namespace + ":generation"
+}
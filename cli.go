@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/spf13/cobra"
+)
+
+// newRootCommand builds the CLI. Run without a subcommand it serves the
+// API, as `serve` does.
+func newRootCommand() *cobra.Command {
+	var configFile string
+	root := &cobra.Command{
+		Use:   "bookstore",
+		Short: "The bookstore API and its operational tasks",
+		Args:  cobra.NoArgs,
+		// Failures at run time aren't usage errors.
+		SilenceUsage: true,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if configFile != "" {
+				os.Setenv("CONFIG_FILE", configFile)
+			}
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runServe()
+		},
+	}
+	root.PersistentFlags().StringVar(&configFile, "config", "", "configuration file, overriding CONFIG_FILE")
+	root.AddCommand(
+		&cobra.Command{
+			Use:   "serve",
+			Short: "Serve the API until SIGINT or SIGTERM",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return runServe()
+			},
+		},
+		newMigrateCommand(),
+		newSeedCommand(),
+		newCreateAdminCommand(),
+		newExportCommand(),
+		newReindexCommand(),
+		newPartitionCommand(),
+	)
+	return root
+}
+
+// bootstrap loads the configuration and connects to the database, see
+// app.Connect, which every command needs.
+func bootstrap() (*config.Config, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	if err := app.Connect(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
@@ -0,0 +1,1434 @@
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/gin-gonic/gin"
+	"github.com/robfig/cron/v3"
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "config.yaml"
+
+type Config struct {
+	Port            string               `yaml:"port"`
+	GRPCPort        string               `yaml:"grpc_port"` // empty disables the gRPC API
+	ShutdownTimeout time.Duration        `yaml:"shutdown_timeout"`
+	RequestTimeout  time.Duration        `yaml:"request_timeout"` // headers included; 0 disables it
+	MaxBodySize     int                  `yaml:"max_body_size"`   // bytes; uploads have their own limits
+	TrustedProxies  []string             `yaml:"trusted_proxies"` // addresses or CIDRs which may set X-Forwarded-For; none by default
+	LogLevel        string               `yaml:"log_level"`
+	GinMode         string               `yaml:"gin_mode"`
+	Database        DatabaseConfig       `yaml:"database"`
+	Auth            AuthConfig           `yaml:"auth"`
+	Tracing         TracingConfig        `yaml:"tracing"`
+	ErrorReporting  ErrorReportingConfig `yaml:"error_reporting"`
+	Redis           RedisConfig          `yaml:"redis"`
+	RateLimit       RateLimitConfig      `yaml:"rate_limit"`
+	Quotas          QuotaConfig          `yaml:"quotas"`
+	Storage         StorageConfig        `yaml:"storage"`
+	Cache           CacheConfig          `yaml:"cache"`
+	HTTPCache       HTTPCacheConfig      `yaml:"http_cache"`
+	HTTPClient      HTTPClientConfig     `yaml:"http_client"`
+	Lending         LendingConfig        `yaml:"lending"`
+	Lookup          LookupConfig         `yaml:"lookup"`
+	Currency        CurrencyConfig       `yaml:"currency"`
+	Search          SearchConfig         `yaml:"search"`
+	Webhooks        WebhookConfig        `yaml:"webhooks"`
+	Events          EventsConfig         `yaml:"events"`
+	Outbox          OutboxConfig         `yaml:"outbox"`
+	Jobs            JobsConfig           `yaml:"jobs"`
+	Idempotency     IdempotencyConfig    `yaml:"idempotency"`
+	CORS            CORSConfig           `yaml:"cors"`
+	Compression     CompressionConfig    `yaml:"compression"`
+	OAuth           OAuthConfig          `yaml:"oauth"`
+	Mail            MailConfig           `yaml:"mail"`
+	Tenancy         TenancyConfig        `yaml:"tenancy"`
+	Encryption      EncryptionConfig     `yaml:"encryption"`
+	Secrets         SecretsConfig        `yaml:"secrets"`
+	Debug           DebugConfig          `yaml:"debug"`
+	AdminUI         AdminUIConfig        `yaml:"admin_ui"`
+	TLS             TLSConfig            `yaml:"tls"`
+	RequestAudit    RequestAuditConfig   `yaml:"request_audit"`
+	Features        FeaturesConfig       `yaml:"features"`
+	Maintenance     MaintenanceConfig    `yaml:"maintenance"`
+}
+
+// FeaturesConfig turns features on or off in this environment; see the
+// features package for their names. Admins override them at runtime
+// through /admin/features.
+type FeaturesConfig struct {
+	// Features not listed keep their default, which is on for all but
+	// maintenance.
+	Flags map[string]bool `yaml:"flags"`
+	// How often instances reread the overrides, which are shared through
+	// Redis when it is configured.
+	Refresh time.Duration `yaml:"refresh"`
+}
+
+// MaintenanceConfig is for the maintenance flag, which answers every
+// request with 503 but those of admins, the probes and logins.
+type MaintenanceConfig struct {
+	// Starts the server in maintenance mode.
+	Enabled bool `yaml:"enabled"`
+	// Sent in Retry-After to the requests turned away.
+	RetryAfter time.Duration `yaml:"retry_after"`
+}
+
+// TLSConfig makes the server speak HTTPS on Port itself, with the
+// certificate in CertFile and KeyFile or with ones obtained from Let's
+// Encrypt for AutocertDomains. Without either it speaks plain HTTP.
+type TLSConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// Certificates for these host names are obtained and renewed
+	// automatically, which means agreeing to the Let's Encrypt terms of
+	// service. They are kept in AutocertCacheDir across restarts.
+	AutocertDomains  []string `yaml:"autocert_domains"`
+	AutocertCacheDir string   `yaml:"autocert_cache_dir"`
+	// Where Let's Encrypt sends notices about the certificates; optional.
+	AutocertEmail string `yaml:"autocert_email"`
+	// A plain HTTP port redirecting to HTTPS, and answering the challenges
+	// of Let's Encrypt, usually 80; empty serves none.
+	RedirectPort string `yaml:"redirect_port"`
+	// How long browsers keep to HTTPS for the host after a response over
+	// it (Strict-Transport-Security); 0 sends no such header.
+	HSTSMaxAge            time.Duration `yaml:"hsts_max_age"`
+	HSTSIncludeSubdomains bool          `yaml:"hsts_include_subdomains"`
+}
+
+// Enabled tells whether the server speaks HTTPS.
+func (cfg TLSConfig) Enabled() bool {
+	return cfg.CertFile != "" || len(cfg.AutocertDomains) > 0
+}
+
+// RequestAuditConfig has every request that may change something logged
+// with its response, bodies included, apart from the application log.
+type RequestAuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Appended to, one JSON object per request; empty writes to stdout.
+	File string `yaml:"file"`
+	// Members of JSON bodies whose values are never logged, at any depth
+	// and whatever their case.
+	RedactFields []string `yaml:"redact_fields"`
+	// Longer bodies are described by their size only.
+	MaxBodySize int `yaml:"max_body_size"`
+}
+
+type DebugConfig struct {
+	// Serves pprof profiles and runtime stats under /debug, to admins.
+	// They reveal a lot about the process, so they are off by default.
+	Enabled bool `yaml:"enabled"`
+}
+
+type AdminUIConfig struct {
+	// Serves the admin web UI at /admin/ui/. The page holds no data: it
+	// calls the API, which only answers admins.
+	Enabled bool `yaml:"enabled"`
+}
+
+type DatabaseConfig struct {
+	Driver          string        `yaml:"driver"`
+	DSN             string        `yaml:"dsn"`
+	MaxOpenConns    int           `yaml:"max_open_conns"`
+	MaxIdleConns    int           `yaml:"max_idle_conns"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"`
+	// DSNs of read replicas of the primary, of the same driver. Reads of
+	// the catalog are spread over them; writes, transactions and every
+	// other table stay on the primary.
+	Replicas []string `yaml:"replicas"`
+	// Whether a request reads from the primary once it has written, so it
+	// sees its own writes however far the replicas lag.
+	ReadYourWrites bool `yaml:"read_your_writes"`
+	// How long startup keeps retrying to reach the database, backing off
+	// between attempts, before giving up.
+	ConnectTimeout time.Duration `yaml:"connect_timeout"`
+	// After this many consecutive statements fail to reach the database,
+	// the next ones fail fast with 503 for BreakerCooldown, after which one
+	// is let through to check whether it is back. 0 disables the breaker.
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+	// Queries taking at least this long are logged and counted as slow. 0
+	// counts none.
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
+	// How far back the query summary of GET /admin/queries goes.
+	QueryStatsWindow time.Duration `yaml:"query_stats_window"`
+	// On Postgres, whether the audit log and loans, once converted by the
+	// partition command, are kept in monthly partitions: the
+	// maintain-partitions job creates those of the next PartitionsAhead
+	// months, and drops those of the audit log that ended more than
+	// AuditRetention ago (0 keeps them all).
+	Partitioned     bool          `yaml:"partitioned"`
+	PartitionsAhead int           `yaml:"partitions_ahead"`
+	AuditRetention  time.Duration `yaml:"audit_retention"`
+}
+
+type AuthConfig struct {
+	JWTSecret string        `yaml:"jwt_secret"`
+	TokenTTL  time.Duration `yaml:"token_ttl"`
+	// How long a refresh token can be exchanged for a new access token.
+	RefreshTokenTTL time.Duration `yaml:"refresh_token_ttl"`
+	// How long the links in email verification and password reset emails
+	// work.
+	VerificationTokenTTL time.Duration `yaml:"verification_token_ttl"`
+	ResetTokenTTL        time.Duration `yaml:"reset_token_ttl"`
+	// The name authenticator apps list the account under.
+	TOTPIssuer string `yaml:"totp_issuer"`
+	// An account is locked after MaxFailedLogins failed logins in a row, for
+	// LockoutDuration, doubled with every further failure up to
+	// MaxLockoutDuration. 0 never locks accounts.
+	MaxFailedLogins    int           `yaml:"max_failed_logins"`
+	LockoutDuration    time.Duration `yaml:"lockout_duration"`
+	MaxLockoutDuration time.Duration `yaml:"max_lockout_duration"`
+	// An IP address failing MaxIPFailedLogins logins, to any accounts,
+	// within IPFailureWindow can't log in until the window ends. 0 allows
+	// any number.
+	MaxIPFailedLogins int           `yaml:"max_ip_failed_logins"`
+	IPFailureWindow   time.Duration `yaml:"ip_failure_window"`
+	// How far the timestamp of a signed request may be from the server
+	// clock, either way.
+	SignatureClockSkew time.Duration `yaml:"signature_clock_skew"`
+}
+
+type OAuthConfig struct {
+	// The public URL of the API. Providers send users back to
+	// <redirect base URL>/api/v1/auth/<provider>/callback, which must be
+	// registered with them.
+	RedirectBaseURL string              `yaml:"redirect_base_url"`
+	Google          OAuthProviderConfig `yaml:"google"`
+	GitHub          OAuthProviderConfig `yaml:"github"`
+}
+
+// A provider can be signed in with when both are set.
+type OAuthProviderConfig struct {
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+}
+
+// Keys are 32 bytes, base64-encoded. See the fieldcrypt package.
+type EncryptionConfig struct {
+	// Encrypts sensitive fields, such as member emails; they are stored in
+	// plaintext without one.
+	Key string `yaml:"key"`
+	// Keys rotated out, still used to read what they encrypted.
+	PreviousKeys []string `yaml:"previous_keys"`
+	// Keys the blind indexes encrypted fields are looked up by, required
+	// with Key. Unlike Key it can't be changed once there is data.
+	IndexKey string `yaml:"index_key"`
+}
+
+type TenancyConfig struct {
+	// The header naming the tenant a request is for, by slug.
+	Header string `yaml:"header"`
+	// When set, requests to <slug>.<base domain> are for that tenant.
+	BaseDomain string `yaml:"base_domain"`
+}
+
+type MailConfig struct {
+	From string `yaml:"from"`
+	// Where the pages the emails link to live: <link base URL>/verify-email
+	// and /reset-password, given the token in a token query parameter.
+	LinkBaseURL string `yaml:"link_base_url"`
+	// Emails are only logged when there's no host.
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+type SMTPConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type TracingConfig struct {
+	// OTLP/HTTP collector URL, e.g. http://localhost:4318. Spans are only
+	// exported when it is set.
+	Endpoint    string  `yaml:"endpoint"`
+	ServiceName string  `yaml:"service_name"`
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+// ErrorReportingConfig sends panics, and the errors 5xx responses are
+// made of, to an error tracker. They are logged either way.
+type ErrorReportingConfig struct {
+	// sentry, or empty to only log them.
+	Provider string       `yaml:"provider"`
+	Sentry   SentryConfig `yaml:"sentry"`
+}
+
+type SentryConfig struct {
+	DSN string `yaml:"dsn"`
+	// Tag the events, e.g. production and the version deployed.
+	Environment string `yaml:"environment"`
+	Release     string `yaml:"release"`
+	// Fraction of the events sent, above 0 and up to 1.
+	SampleRate float64 `yaml:"sample_rate"`
+}
+
+type RedisConfig struct {
+	// redis://[user:password@]host:port/db. Optional; features that can share
+	// state through Redis fall back to in-process state without it.
+	URL string `yaml:"url"`
+}
+
+type RateLimitConfig struct {
+	// Sustained requests per second per client; 0 disables rate limiting.
+	Rate  float64 `yaml:"rate"`
+	Burst int     `yaml:"burst"`
+	// Sustained requests per second per IP address, counted before callers
+	// are authenticated so that failing to authenticate is limited too;
+	// 0 disables it. It must leave room for every client behind an
+	// address, API keys with limits of their own included.
+	AddressRate  float64 `yaml:"address_rate"`
+	AddressBurst int     `yaml:"address_burst"`
+}
+
+// QuotaConfig caps the requests clients make in a calendar month (UTC).
+// Requests made with API keys count against the key, those made with
+// access tokens against the user; anonymous ones aren't counted.
+type QuotaConfig struct {
+	// Monthly requests per API key and per user; 0 leaves them uncapped.
+	// API keys issued with a quota of their own get that instead.
+	APIKey int `yaml:"api_key"`
+	User   int `yaml:"user"`
+	// How often the counts are written to the database, and those of other
+	// instances read back: a client may overrun its quota by what it makes
+	// on other instances meanwhile.
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+type CacheConfig struct {
+	// How long book reads stay cached in Redis; 0 disables the cache. It is
+	// also disabled when no Redis URL is configured.
+	TTL time.Duration `yaml:"ttl"`
+	// How long the admin statistics stay cached in Redis. Writes don't
+	// invalidate them, so they may be this old; 0 computes them on every
+	// request.
+	StatsTTL time.Duration `yaml:"stats_ttl"`
+}
+
+// HTTPCacheConfig is how long clients and shared caches may reuse what the
+// public read endpoints answer before asking again. Either way responses
+// carry a Last-Modified date, so asking again with If-Modified-Since is
+// answered with a bodyless 304 until the data changes.
+type HTTPCacheConfig struct {
+	// For lists, such as books or a category's books; 0 has clients
+	// revalidate every time.
+	ListMaxAge time.Duration `yaml:"list_max_age"`
+	// For single records, such as a book.
+	DetailMaxAge time.Duration `yaml:"detail_max_age"`
+}
+
+// HTTPClientConfig is shared by the requests made to other services: the
+// ISBN catalogs, the exchange rates API, webhooks and member notifications.
+// How long each may take, and how many times it is retried, is configured
+// with the service.
+type HTTPClientConfig struct {
+	// Waited before the first retry of a failed request, and twice as long
+	// before each next one. Webhook deliveries have their own.
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	// After BreakerThreshold requests in a row to the same host fail, the
+	// next ones fail fast for BreakerCooldown, after which one is let
+	// through to check whether it is back. 0 disables the breakers.
+	BreakerThreshold int           `yaml:"breaker_threshold"`
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown"`
+}
+
+type LendingConfig struct {
+	// How long a member may keep a checked-out book before it is overdue.
+	LoanDuration  time.Duration       `yaml:"loan_duration"`
+	Fines         FinesConfig         `yaml:"fines"`
+	Holds         HoldsConfig         `yaml:"holds"`
+	Notifications NotificationsConfig `yaml:"notifications"`
+}
+
+// FinesConfig is what members are charged for returning books late.
+// Amounts are decimals, e.g. "0.25", in Currency.
+type FinesConfig struct {
+	// Charged per day, or part of one, a loan is returned late, up to Cap
+	// per loan. 0 charges nothing, and 0 caps nothing.
+	DailyRate string `yaml:"daily_rate"`
+	Cap       string `yaml:"cap"`
+	Currency  string `yaml:"currency"`
+	// Members whose unpaid fines add up to more than this can't check books
+	// out; empty never stops them.
+	BlockAbove string `yaml:"block_above"`
+}
+
+// HoldsConfig is how members queue for books with no copies available.
+type HoldsConfig struct {
+	// How long the copy set aside for a member whose turn came is kept for
+	// them before going to the next in line.
+	PickupWindow time.Duration `yaml:"pickup_window"`
+	// How many books a member may hold at once; 0 for no limit.
+	MaxPerMember int `yaml:"max_per_member"`
+}
+
+// NotificationsConfig is how members are told about their loans, such as
+// overdue ones.
+type NotificationsConfig struct {
+	// The channels (email, webhook, console) of the members who haven't
+	// chosen theirs.
+	DefaultChannels []string `yaml:"default_channels"`
+	// Bounds each POST to a member's webhook; failures (timeouts, 429s,
+	// 5xx) are retried WebhookRetries times.
+	WebhookTimeout time.Duration `yaml:"webhook_timeout"`
+	WebhookRetries int           `yaml:"webhook_retries"`
+	// Signs the webhook notifications like webhook deliveries, when set.
+	WebhookSecret string `yaml:"webhook_secret"`
+}
+
+type LookupConfig struct {
+	// Base URLs of the catalogs ISBNs are looked up in, tried in this order.
+	// An empty URL disables that catalog.
+	OpenLibraryURL    string `yaml:"openlibrary_url"`
+	GoogleBooksURL    string `yaml:"google_books_url"`
+	GoogleBooksAPIKey string `yaml:"google_books_api_key"`
+	// Bounds each request to a catalog; failed requests are retried up to
+	// Retries times.
+	Timeout time.Duration `yaml:"timeout"`
+	Retries int           `yaml:"retries"`
+	// How long results are remembered, in Redis when configured and in
+	// process otherwise. 0 disables caching.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+}
+
+type CurrencyConfig struct {
+	// Base URL of a Frankfurter-compatible API the rates prices are
+	// converted at are fetched from. When empty, the fixed Rates are used.
+	URL     string        `yaml:"url"`
+	Timeout time.Duration `yaml:"timeout"`
+	Retries int           `yaml:"retries"`
+	// How long fetched rates are used before being fetched again, in Redis
+	// when configured and in process otherwise. 0 disables caching.
+	CacheTTL time.Duration `yaml:"cache_ttl"`
+	// Fixed rates, each how much of the currency one unit of Base buys,
+	// e.g. base EUR and USD "1.08".
+	Base  string            `yaml:"base"`
+	Rates map[string]string `yaml:"rates"`
+}
+
+// SearchConfig points GET /books/search at an Elasticsearch or OpenSearch
+// index kept in step with the catalog, instead of the database.
+type SearchConfig struct {
+	// Base URL of the cluster; empty searches the database.
+	URL string `yaml:"url"`
+	// The alias the books are indexed and searched through.
+	Index   string        `yaml:"index"`
+	Timeout time.Duration `yaml:"timeout"`
+	// Basic auth credentials, if the cluster requires them.
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+type WebhookConfig struct {
+	// Bounds each delivery attempt.
+	Timeout time.Duration `yaml:"timeout"`
+	// Failed deliveries are retried until MaxAttempts attempts have been
+	// made, waiting RetryBackoff before the first retry and twice as long
+	// before each next one.
+	MaxAttempts  int           `yaml:"max_attempts"`
+	RetryBackoff time.Duration `yaml:"retry_backoff"`
+	// How many deliveries are made concurrently.
+	Workers int `yaml:"workers"`
+	// Lets webhooks, and members' notification webhooks, point to
+	// loopback, private and link-local addresses, which they otherwise
+	// can't, so as not to reach internal services. For development only.
+	AllowPrivateTargets bool `yaml:"allow_private_targets"`
+}
+
+type EventsConfig struct {
+	// How often idle event streams get a comment line, and the admin
+	// channel a ping, so proxies don't close them.
+	Heartbeat time.Duration `yaml:"heartbeat"`
+	// How often the admin channel pushes a snapshot of the metrics.
+	MetricsInterval time.Duration `yaml:"metrics_interval"`
+}
+
+type OutboxConfig struct {
+	// Where book events are published besides webhooks and event streams:
+	// nats, or empty for nowhere else.
+	Broker string     `yaml:"broker"`
+	NATS   NATSConfig `yaml:"nats"`
+	// How often the outbox is checked for events to publish, and how many
+	// are published per check.
+	PollInterval time.Duration `yaml:"poll_interval"`
+	BatchSize    int           `yaml:"batch_size"`
+	// How long published events are kept; 0 keeps them forever.
+	Retention time.Duration `yaml:"retention"`
+}
+
+type NATSConfig struct {
+	URL string `yaml:"url"`
+	// The JetStream stream events are stored in; it is created if missing.
+	Stream string `yaml:"stream"`
+	// Events are published under "<subject_prefix>.<event type>", e.g.
+	// bookstore.book.created.
+	SubjectPrefix string `yaml:"subject_prefix"`
+}
+
+type JobsConfig struct {
+	// How many jobs can run at the same time.
+	Workers int `yaml:"workers"`
+	// Cron expressions (minute hour day month weekday, or descriptors such
+	// as @daily) for each job; empty runs it only when triggered.
+	PurgeSchedule           string `yaml:"purge_schedule"`
+	RatingsSchedule         string `yaml:"ratings_schedule"`
+	RemindersSchedule       string `yaml:"reminders_schedule"`
+	RecommendationsSchedule string `yaml:"recommendations_schedule"`
+	// Holds are also processed as soon as a copy is set aside for one; the
+	// schedule expires those not picked up in time.
+	HoldsSchedule string `yaml:"holds_schedule"`
+	// Exports are also processed as soon as they are queued; the schedule
+	// picks up those queued while the job was busy.
+	ExportsSchedule string `yaml:"exports_schedule"`
+	// Only run with partitioned tables, see DatabaseConfig.Partitioned.
+	PartitionsSchedule string `yaml:"partitions_schedule"`
+	// How long soft-deleted books are kept before being purged.
+	PurgeAfter time.Duration `yaml:"purge_after"`
+	// How long finished exports are kept for download.
+	ExportsExpireAfter time.Duration `yaml:"exports_expire_after"`
+}
+
+type IdempotencyConfig struct {
+	// How long the responses of requests sent with an Idempotency-Key are
+	// kept for retries to get them back, in Redis when configured and in
+	// process otherwise. 0 ignores the header.
+	TTL time.Duration `yaml:"ttl"`
+}
+
+type CORSConfig struct {
+	// Origins (scheme://host[:port]) browsers may call the API from, with *
+	// matching any part of one, e.g. https://*.example.com, or alone any
+	// origin. Empty disables CORS.
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	AllowedMethods []string `yaml:"allowed_methods"`
+	// Request headers scripts may send, and response headers they may read.
+	AllowedHeaders []string `yaml:"allowed_headers"`
+	ExposedHeaders []string `yaml:"exposed_headers"`
+	// Whether cookies and Authorization headers are sent along; not
+	// allowed with the * origin.
+	AllowCredentials bool `yaml:"allow_credentials"`
+	// How long browsers may cache a preflight response.
+	MaxAge time.Duration `yaml:"max_age"`
+}
+
+type CompressionConfig struct {
+	// gzip/deflate level, 1 (fastest) to 9 (smallest); 0 disables
+	// compression.
+	Level int `yaml:"level"`
+	// Smaller bodies are sent as is.
+	MinSize int `yaml:"min_size"`
+	// Content types worth compressing.
+	Types []string `yaml:"types"`
+}
+
+type StorageConfig struct {
+	// local or s3.
+	Driver   string   `yaml:"driver"`
+	LocalDir string   `yaml:"local_dir"`
+	S3       S3Config `yaml:"s3"`
+	// Covers of more pixels (width × height) are rejected before they are
+	// decoded, which takes 4 bytes a pixel whatever the file's size.
+	MaxCoverPixels int `yaml:"max_cover_pixels"`
+}
+
+type S3Config struct {
+	// host[:port] without scheme, e.g. s3.amazonaws.com or localhost:9000.
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl"`
+
+	// Clients download covers and exports straight from the bucket through
+	// URLs signed for this long; 0 serves them through the API instead.
+	PresignExpiry time.Duration `yaml:"presign_expiry"`
+	// The host[:port] clients reach the bucket at, when it isn't Endpoint
+	// (e.g. MinIO behind a proxy); presigned URLs point there.
+	PublicEndpoint string `yaml:"public_endpoint"`
+}
+
+// Load builds the configuration from defaults, an optional YAML file and
+// environment variables, in that order of precedence (env wins).
+//
+// The file is read from CONFIG_FILE when set, otherwise from config.yaml in
+// the working directory if it exists.
+func Load() (*Config, error) {
+	cfg := &Config{
+		Port:            "8080",
+		GRPCPort:        "9090",
+		ShutdownTimeout: 10 * time.Second,
+		RequestTimeout:  30 * time.Second,
+		MaxBodySize:     1 << 20,
+		LogLevel:        "info",
+		GinMode:         gin.DebugMode,
+		Database: DatabaseConfig{
+			Driver:          "sqlite",
+			DSN:             "test.db",
+			MaxOpenConns:    10,
+			MaxIdleConns:    5,
+			ConnMaxLifetime: time.Hour,
+			ReadYourWrites:  true,
+			ConnectTimeout:  30 * time.Second,
+			// Enough not to trip on a dropped connection or two.
+			BreakerThreshold:   5,
+			BreakerCooldown:    10 * time.Second,
+			SlowQueryThreshold: 200 * time.Millisecond,
+			QueryStatsWindow:   15 * time.Minute,
+			PartitionsAhead:    3,
+		},
+		Auth: AuthConfig{
+			TokenTTL:             15 * time.Minute,
+			RefreshTokenTTL:      30 * 24 * time.Hour,
+			VerificationTokenTTL: 48 * time.Hour,
+			ResetTokenTTL:        time.Hour,
+			TOTPIssuer:           "Bookstore",
+			MaxFailedLogins:      5,
+			LockoutDuration:      time.Minute,
+			MaxLockoutDuration:   time.Hour,
+			MaxIPFailedLogins:    20,
+			IPFailureWindow:      15 * time.Minute,
+			SignatureClockSkew:   5 * time.Minute,
+		},
+		Tracing: TracingConfig{
+			ServiceName: "bookstore-api",
+			SampleRatio: 1,
+		},
+		ErrorReporting: ErrorReportingConfig{
+			Sentry: SentryConfig{SampleRate: 1},
+		},
+		RateLimit: RateLimitConfig{Rate: 10, Burst: 20, AddressRate: 50, AddressBurst: 100},
+		Quotas:    QuotaConfig{FlushInterval: 10 * time.Second},
+		Cache:     CacheConfig{TTL: time.Minute, StatsTTL: time.Minute},
+		HTTPClient: HTTPClientConfig{
+			RetryBackoff:     200 * time.Millisecond,
+			BreakerThreshold: 5,
+			BreakerCooldown:  30 * time.Second,
+		},
+		Lending: LendingConfig{
+			LoanDuration: 14 * 24 * time.Hour,
+			Fines: FinesConfig{
+				DailyRate:  "0.25",
+				Cap:        "10.00",
+				Currency:   "USD",
+				BlockAbove: "5.00",
+			},
+			Holds: HoldsConfig{
+				PickupWindow: 3 * 24 * time.Hour,
+				MaxPerMember: 5,
+			},
+			Notifications: NotificationsConfig{
+				DefaultChannels: []string{"email"},
+				WebhookTimeout:  10 * time.Second,
+				WebhookRetries:  2,
+			},
+		},
+		Lookup: LookupConfig{
+			OpenLibraryURL: "https://openlibrary.org",
+			GoogleBooksURL: "https://www.googleapis.com/books/v1",
+			Timeout:        5 * time.Second,
+			Retries:        2,
+			CacheTTL:       24 * time.Hour,
+		},
+		Currency: CurrencyConfig{
+			URL:      "https://api.frankfurter.app",
+			Timeout:  5 * time.Second,
+			Retries:  2,
+			CacheTTL: time.Hour,
+		},
+		Search: SearchConfig{Index: "books", Timeout: 5 * time.Second},
+		Webhooks: WebhookConfig{
+			Timeout:      10 * time.Second,
+			MaxAttempts:  5,
+			RetryBackoff: 30 * time.Second,
+			Workers:      4,
+		},
+		Events: EventsConfig{Heartbeat: 15 * time.Second, MetricsInterval: 10 * time.Second},
+		Outbox: OutboxConfig{
+			NATS:         NATSConfig{URL: "nats://localhost:4222", Stream: "BOOKSTORE", SubjectPrefix: "bookstore"},
+			PollInterval: time.Second,
+			BatchSize:    100,
+			Retention:    7 * 24 * time.Hour,
+		},
+		Jobs: JobsConfig{
+			Workers:                 2,
+			PurgeSchedule:           "0 3 * * *",
+			RatingsSchedule:         "30 3 * * *",
+			RemindersSchedule:       "0 9 * * *",
+			RecommendationsSchedule: "0 4 * * *",
+			HoldsSchedule:           "@every 5m",
+			ExportsSchedule:         "@every 1m",
+			PartitionsSchedule:      "0 1 * * *",
+			PurgeAfter:              30 * 24 * time.Hour,
+			ExportsExpireAfter:      24 * time.Hour,
+		},
+		Idempotency: IdempotencyConfig{TTL: 24 * time.Hour},
+		CORS: CORSConfig{
+			AllowedMethods: []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"},
+			AllowedHeaders: []string{"Authorization", "Content-Type", "Accept", "If-Match", "If-None-Match", "Idempotency-Key", "X-API-Key", "API-Version", "X-Request-ID", "X-Tenant"},
+			ExposedHeaders: []string{"ETag", "Link", "Location", "Retry-After", "API-Version", "Deprecation", "Idempotent-Replayed", "X-Request-ID", "X-Total", "X-Total-Pages", "X-RateLimit-Limit", "X-RateLimit-Remaining", "X-Quota-Limit", "X-Quota-Remaining", "X-Quota-Reset"},
+			MaxAge:         12 * time.Hour,
+		},
+		Compression: CompressionConfig{
+			Level:   5,
+			MinSize: 1024,
+			Types:   []string{"application/json", "application/problem+json", "application/xml", "text/csv", "text/plain", "text/html"},
+		},
+		OAuth: OAuthConfig{RedirectBaseURL: "http://localhost:8080"},
+		Mail: MailConfig{
+			From:        "Bookstore <no-reply@localhost>",
+			LinkBaseURL: "http://localhost:8080",
+			SMTP:        SMTPConfig{Port: 587},
+		},
+		Tenancy: TenancyConfig{Header: "X-Tenant"},
+		AdminUI: AdminUIConfig{Enabled: true},
+		RequestAudit: RequestAuditConfig{
+			RedactFields: []string{"password", "token", "access_token", "refresh_token", "secret", "code", "recovery_codes", "qr_code", "otpauth_url", "key", "email", "phone"},
+			MaxBodySize:  64 << 10,
+		},
+		Secrets:     SecretsConfig{Vault: VaultConfig{Mount: "secret"}, Timeout: 10 * time.Second},
+		Features:    FeaturesConfig{Refresh: 10 * time.Second},
+		Maintenance: MaintenanceConfig{RetryAfter: 5 * time.Minute},
+		TLS: TLSConfig{
+			AutocertCacheDir: "certs",
+			HSTSMaxAge:       365 * 24 * time.Hour,
+		},
+		Storage: StorageConfig{
+			Driver:   "local",
+			LocalDir: "uploads",
+			S3:       S3Config{Endpoint: "s3.amazonaws.com", UseSSL: true, PresignExpiry: 15 * time.Minute},
+			// 50 megapixels, 200 MB decoded.
+			MaxCoverPixels: 50_000_000,
+		},
+	}
+
+	if err := cfg.loadFile(); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	if err := cfg.loadSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func (cfg *Config) loadFile() error {
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return nil
+		}
+		return fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (cfg *Config) loadEnv() error {
+	setFromEnv(&cfg.Port, "PORT")
+	setFromEnv(&cfg.GRPCPort, "GRPC_PORT")
+	setFromEnv(&cfg.LogLevel, "LOG_LEVEL")
+	setFromEnv(&cfg.GinMode, "GIN_MODE")
+	setFromEnv(&cfg.Database.Driver, "DB_DRIVER")
+	setFromEnv(&cfg.Database.DSN, "DB_DSN")
+	listFromEnv(&cfg.Database.Replicas, "DB_REPLICAS")
+	setFromEnv(&cfg.Auth.JWTSecret, "JWT_SECRET")
+	setFromEnv(&cfg.Auth.TOTPIssuer, "TOTP_ISSUER")
+	setFromEnv(&cfg.Tracing.Endpoint, "OTEL_EXPORTER_OTLP_ENDPOINT")
+	setFromEnv(&cfg.Tracing.ServiceName, "OTEL_SERVICE_NAME")
+	setFromEnv(&cfg.ErrorReporting.Provider, "ERROR_REPORTING_PROVIDER")
+	setFromEnv(&cfg.ErrorReporting.Sentry.DSN, "SENTRY_DSN")
+	setFromEnv(&cfg.ErrorReporting.Sentry.Environment, "SENTRY_ENVIRONMENT")
+	setFromEnv(&cfg.ErrorReporting.Sentry.Release, "SENTRY_RELEASE")
+	setFromEnv(&cfg.Redis.URL, "REDIS_URL")
+	setFromEnv(&cfg.Storage.Driver, "STORAGE_DRIVER")
+	setFromEnv(&cfg.Storage.LocalDir, "STORAGE_LOCAL_DIR")
+	setFromEnv(&cfg.Storage.S3.Endpoint, "S3_ENDPOINT")
+	setFromEnv(&cfg.Storage.S3.Region, "S3_REGION")
+	setFromEnv(&cfg.Storage.S3.Bucket, "S3_BUCKET")
+	setFromEnv(&cfg.Storage.S3.AccessKey, "S3_ACCESS_KEY")
+	setFromEnv(&cfg.Storage.S3.SecretKey, "S3_SECRET_KEY")
+	setFromEnv(&cfg.Storage.S3.PublicEndpoint, "S3_PUBLIC_ENDPOINT")
+	setFromEnv(&cfg.Lookup.OpenLibraryURL, "OPENLIBRARY_URL")
+	setFromEnv(&cfg.Lookup.GoogleBooksURL, "GOOGLE_BOOKS_URL")
+	setFromEnv(&cfg.Lookup.GoogleBooksAPIKey, "GOOGLE_BOOKS_API_KEY")
+	setFromEnv(&cfg.Currency.URL, "EXCHANGE_RATES_URL")
+	setFromEnv(&cfg.Search.URL, "SEARCH_URL")
+	setFromEnv(&cfg.Search.Index, "SEARCH_INDEX")
+	setFromEnv(&cfg.Search.Username, "SEARCH_USERNAME")
+	setFromEnv(&cfg.Search.Password, "SEARCH_PASSWORD")
+	setFromEnv(&cfg.Outbox.Broker, "OUTBOX_BROKER")
+	setFromEnv(&cfg.Outbox.NATS.URL, "NATS_URL")
+	setFromEnv(&cfg.Outbox.NATS.Stream, "NATS_STREAM")
+	setFromEnv(&cfg.Outbox.NATS.SubjectPrefix, "NATS_SUBJECT_PREFIX")
+	setFromEnv(&cfg.Jobs.PurgeSchedule, "JOBS_PURGE_SCHEDULE")
+	setFromEnv(&cfg.Jobs.RatingsSchedule, "JOBS_RATINGS_SCHEDULE")
+	setFromEnv(&cfg.Jobs.RemindersSchedule, "JOBS_REMINDERS_SCHEDULE")
+	setFromEnv(&cfg.Jobs.RecommendationsSchedule, "JOBS_RECOMMENDATIONS_SCHEDULE")
+	setFromEnv(&cfg.Jobs.HoldsSchedule, "JOBS_HOLDS_SCHEDULE")
+	setFromEnv(&cfg.Jobs.ExportsSchedule, "JOBS_EXPORTS_SCHEDULE")
+	setFromEnv(&cfg.Jobs.PartitionsSchedule, "JOBS_PARTITIONS_SCHEDULE")
+	setFromEnv(&cfg.Mail.From, "MAIL_FROM")
+	setFromEnv(&cfg.Mail.LinkBaseURL, "MAIL_LINK_BASE_URL")
+	setFromEnv(&cfg.Mail.SMTP.Host, "SMTP_HOST")
+	setFromEnv(&cfg.Mail.SMTP.Username, "SMTP_USERNAME")
+	setFromEnv(&cfg.Mail.SMTP.Password, "SMTP_PASSWORD")
+	setFromEnv(&cfg.Encryption.Key, "ENCRYPTION_KEY")
+	listFromEnv(&cfg.Encryption.PreviousKeys, "ENCRYPTION_PREVIOUS_KEYS")
+	setFromEnv(&cfg.Encryption.IndexKey, "ENCRYPTION_INDEX_KEY")
+	setFromEnv(&cfg.Secrets.Provider, "SECRETS_PROVIDER")
+	setFromEnv(&cfg.Secrets.Vault.Address, "VAULT_ADDR")
+	setFromEnv(&cfg.Secrets.Vault.Token, "VAULT_TOKEN")
+	setFromEnv(&cfg.Secrets.Vault.Namespace, "VAULT_NAMESPACE")
+	setFromEnv(&cfg.Secrets.AWS.Region, "SECRETS_AWS_REGION")
+	setFromEnv(&cfg.TLS.CertFile, "TLS_CERT_FILE")
+	setFromEnv(&cfg.TLS.KeyFile, "TLS_KEY_FILE")
+	listFromEnv(&cfg.TLS.AutocertDomains, "TLS_AUTOCERT_DOMAINS")
+	setFromEnv(&cfg.TLS.AutocertCacheDir, "TLS_AUTOCERT_CACHE_DIR")
+	setFromEnv(&cfg.TLS.AutocertEmail, "TLS_AUTOCERT_EMAIL")
+	setFromEnv(&cfg.TLS.RedirectPort, "TLS_REDIRECT_PORT")
+	setFromEnv(&cfg.RequestAudit.File, "REQUEST_AUDIT_FILE")
+	listFromEnv(&cfg.RequestAudit.RedactFields, "REQUEST_AUDIT_REDACT_FIELDS")
+	setFromEnv(&cfg.Tenancy.Header, "TENANT_HEADER")
+	setFromEnv(&cfg.Tenancy.BaseDomain, "TENANT_BASE_DOMAIN")
+	setFromEnv(&cfg.OAuth.RedirectBaseURL, "OAUTH_REDIRECT_BASE_URL")
+	setFromEnv(&cfg.OAuth.Google.ClientID, "GOOGLE_CLIENT_ID")
+	setFromEnv(&cfg.OAuth.Google.ClientSecret, "GOOGLE_CLIENT_SECRET")
+	setFromEnv(&cfg.OAuth.GitHub.ClientID, "GITHUB_CLIENT_ID")
+	setFromEnv(&cfg.OAuth.GitHub.ClientSecret, "GITHUB_CLIENT_SECRET")
+	listFromEnv(&cfg.TrustedProxies, "TRUSTED_PROXIES")
+	listFromEnv(&cfg.CORS.AllowedOrigins, "CORS_ALLOWED_ORIGINS")
+	listFromEnv(&cfg.CORS.AllowedMethods, "CORS_ALLOWED_METHODS")
+	listFromEnv(&cfg.CORS.AllowedHeaders, "CORS_ALLOWED_HEADERS")
+	listFromEnv(&cfg.CORS.ExposedHeaders, "CORS_EXPOSED_HEADERS")
+	listFromEnv(&cfg.Compression.Types, "COMPRESSION_TYPES")
+	setFromEnv(&cfg.Lending.Fines.DailyRate, "FINES_DAILY_RATE")
+	setFromEnv(&cfg.Lending.Fines.Cap, "FINES_CAP")
+	setFromEnv(&cfg.Lending.Fines.Currency, "FINES_CURRENCY")
+	setFromEnv(&cfg.Lending.Fines.BlockAbove, "FINES_BLOCK_ABOVE")
+	listFromEnv(&cfg.Lending.Notifications.DefaultChannels, "NOTIFICATIONS_DEFAULT_CHANNELS")
+	setFromEnv(&cfg.Lending.Notifications.WebhookSecret, "NOTIFICATIONS_WEBHOOK_SECRET")
+
+	return errors.Join(
+		intFromEnv(&cfg.Database.MaxOpenConns, "DB_MAX_OPEN_CONNS"),
+		intFromEnv(&cfg.Database.MaxIdleConns, "DB_MAX_IDLE_CONNS"),
+		boolFromEnv(&cfg.Database.ReadYourWrites, "DB_READ_YOUR_WRITES"),
+		durationFromEnv(&cfg.ShutdownTimeout, "SHUTDOWN_TIMEOUT"),
+		durationFromEnv(&cfg.RequestTimeout, "REQUEST_TIMEOUT"),
+		intFromEnv(&cfg.MaxBodySize, "MAX_BODY_SIZE"),
+		durationFromEnv(&cfg.Database.ConnMaxLifetime, "DB_CONN_MAX_LIFETIME"),
+		durationFromEnv(&cfg.Database.ConnectTimeout, "DB_CONNECT_TIMEOUT"),
+		intFromEnv(&cfg.Database.BreakerThreshold, "DB_BREAKER_THRESHOLD"),
+		durationFromEnv(&cfg.Database.BreakerCooldown, "DB_BREAKER_COOLDOWN"),
+		durationFromEnv(&cfg.Database.SlowQueryThreshold, "DB_SLOW_QUERY_THRESHOLD"),
+		durationFromEnv(&cfg.Database.QueryStatsWindow, "DB_QUERY_STATS_WINDOW"),
+		boolFromEnv(&cfg.Database.Partitioned, "DB_PARTITIONED"),
+		intFromEnv(&cfg.Database.PartitionsAhead, "DB_PARTITIONS_AHEAD"),
+		durationFromEnv(&cfg.Database.AuditRetention, "DB_AUDIT_RETENTION"),
+		durationFromEnv(&cfg.Auth.TokenTTL, "JWT_TOKEN_TTL"),
+		durationFromEnv(&cfg.Auth.RefreshTokenTTL, "REFRESH_TOKEN_TTL"),
+		durationFromEnv(&cfg.Auth.VerificationTokenTTL, "VERIFICATION_TOKEN_TTL"),
+		durationFromEnv(&cfg.Auth.ResetTokenTTL, "RESET_TOKEN_TTL"),
+		intFromEnv(&cfg.Auth.MaxFailedLogins, "MAX_FAILED_LOGINS"),
+		durationFromEnv(&cfg.Auth.LockoutDuration, "LOCKOUT_DURATION"),
+		durationFromEnv(&cfg.Auth.MaxLockoutDuration, "MAX_LOCKOUT_DURATION"),
+		intFromEnv(&cfg.Auth.MaxIPFailedLogins, "MAX_IP_FAILED_LOGINS"),
+		durationFromEnv(&cfg.Auth.IPFailureWindow, "IP_FAILURE_WINDOW"),
+		durationFromEnv(&cfg.Auth.SignatureClockSkew, "SIGNATURE_CLOCK_SKEW"),
+		intFromEnv(&cfg.Mail.SMTP.Port, "SMTP_PORT"),
+		durationFromEnv(&cfg.Cache.TTL, "CACHE_TTL"),
+		durationFromEnv(&cfg.Cache.StatsTTL, "CACHE_STATS_TTL"),
+		durationFromEnv(&cfg.HTTPCache.ListMaxAge, "HTTP_CACHE_LIST_MAX_AGE"),
+		durationFromEnv(&cfg.HTTPCache.DetailMaxAge, "HTTP_CACHE_DETAIL_MAX_AGE"),
+		durationFromEnv(&cfg.Secrets.Timeout, "SECRETS_TIMEOUT"),
+		durationFromEnv(&cfg.Secrets.Refresh, "SECRETS_REFRESH"),
+		durationFromEnv(&cfg.HTTPClient.RetryBackoff, "HTTP_CLIENT_RETRY_BACKOFF"),
+		intFromEnv(&cfg.HTTPClient.BreakerThreshold, "HTTP_CLIENT_BREAKER_THRESHOLD"),
+		durationFromEnv(&cfg.HTTPClient.BreakerCooldown, "HTTP_CLIENT_BREAKER_COOLDOWN"),
+		durationFromEnv(&cfg.Lending.LoanDuration, "LOAN_DURATION"),
+		durationFromEnv(&cfg.Lending.Holds.PickupWindow, "HOLDS_PICKUP_WINDOW"),
+		intFromEnv(&cfg.Lending.Holds.MaxPerMember, "HOLDS_MAX_PER_MEMBER"),
+		durationFromEnv(&cfg.Lending.Notifications.WebhookTimeout, "NOTIFICATIONS_WEBHOOK_TIMEOUT"),
+		intFromEnv(&cfg.Lending.Notifications.WebhookRetries, "NOTIFICATIONS_WEBHOOK_RETRIES"),
+		durationFromEnv(&cfg.Lookup.Timeout, "LOOKUP_TIMEOUT"),
+		intFromEnv(&cfg.Lookup.Retries, "LOOKUP_RETRIES"),
+		durationFromEnv(&cfg.Lookup.CacheTTL, "LOOKUP_CACHE_TTL"),
+		durationFromEnv(&cfg.Currency.Timeout, "EXCHANGE_RATES_TIMEOUT"),
+		intFromEnv(&cfg.Currency.Retries, "EXCHANGE_RATES_RETRIES"),
+		durationFromEnv(&cfg.Currency.CacheTTL, "EXCHANGE_RATES_CACHE_TTL"),
+		durationFromEnv(&cfg.Search.Timeout, "SEARCH_TIMEOUT"),
+		durationFromEnv(&cfg.Webhooks.Timeout, "WEBHOOK_TIMEOUT"),
+		intFromEnv(&cfg.Webhooks.MaxAttempts, "WEBHOOK_MAX_ATTEMPTS"),
+		durationFromEnv(&cfg.Webhooks.RetryBackoff, "WEBHOOK_RETRY_BACKOFF"),
+		intFromEnv(&cfg.Webhooks.Workers, "WEBHOOK_WORKERS"),
+		boolFromEnv(&cfg.Webhooks.AllowPrivateTargets, "WEBHOOK_ALLOW_PRIVATE_TARGETS"),
+		durationFromEnv(&cfg.Events.Heartbeat, "EVENTS_HEARTBEAT"),
+		durationFromEnv(&cfg.Events.MetricsInterval, "EVENTS_METRICS_INTERVAL"),
+		durationFromEnv(&cfg.Outbox.PollInterval, "OUTBOX_POLL_INTERVAL"),
+		intFromEnv(&cfg.Outbox.BatchSize, "OUTBOX_BATCH_SIZE"),
+		durationFromEnv(&cfg.Outbox.Retention, "OUTBOX_RETENTION"),
+		intFromEnv(&cfg.Jobs.Workers, "JOBS_WORKERS"),
+		durationFromEnv(&cfg.Jobs.PurgeAfter, "JOBS_PURGE_AFTER"),
+		durationFromEnv(&cfg.Jobs.ExportsExpireAfter, "JOBS_EXPORTS_EXPIRE_AFTER"),
+		durationFromEnv(&cfg.Idempotency.TTL, "IDEMPOTENCY_TTL"),
+		floatFromEnv(&cfg.Tracing.SampleRatio, "OTEL_TRACES_SAMPLE_RATIO"),
+		floatFromEnv(&cfg.ErrorReporting.Sentry.SampleRate, "SENTRY_SAMPLE_RATE"),
+		floatFromEnv(&cfg.RateLimit.Rate, "RATE_LIMIT_RATE"),
+		intFromEnv(&cfg.RateLimit.Burst, "RATE_LIMIT_BURST"),
+		floatFromEnv(&cfg.RateLimit.AddressRate, "RATE_LIMIT_ADDRESS_RATE"),
+		intFromEnv(&cfg.RateLimit.AddressBurst, "RATE_LIMIT_ADDRESS_BURST"),
+		intFromEnv(&cfg.Quotas.APIKey, "QUOTA_API_KEY"),
+		intFromEnv(&cfg.Quotas.User, "QUOTA_USER"),
+		durationFromEnv(&cfg.Quotas.FlushInterval, "QUOTA_FLUSH_INTERVAL"),
+		intFromEnv(&cfg.Storage.MaxCoverPixels, "STORAGE_MAX_COVER_PIXELS"),
+		boolFromEnv(&cfg.Storage.S3.UseSSL, "S3_USE_SSL"),
+		durationFromEnv(&cfg.Storage.S3.PresignExpiry, "S3_PRESIGN_EXPIRY"),
+		boolFromEnv(&cfg.CORS.AllowCredentials, "CORS_ALLOW_CREDENTIALS"),
+		boolFromEnv(&cfg.Debug.Enabled, "DEBUG_ENABLED"),
+		boolFromEnv(&cfg.AdminUI.Enabled, "ADMIN_UI_ENABLED"),
+		durationFromEnv(&cfg.TLS.HSTSMaxAge, "TLS_HSTS_MAX_AGE"),
+		boolFromEnv(&cfg.TLS.HSTSIncludeSubdomains, "TLS_HSTS_INCLUDE_SUBDOMAINS"),
+		boolFromEnv(&cfg.RequestAudit.Enabled, "REQUEST_AUDIT_ENABLED"),
+		intFromEnv(&cfg.RequestAudit.MaxBodySize, "REQUEST_AUDIT_MAX_BODY_SIZE"),
+		durationFromEnv(&cfg.CORS.MaxAge, "CORS_MAX_AGE"),
+		intFromEnv(&cfg.Compression.Level, "COMPRESSION_LEVEL"),
+		intFromEnv(&cfg.Compression.MinSize, "COMPRESSION_MIN_SIZE"),
+		flagsFromEnv(&cfg.Features.Flags, "FEATURES"),
+		durationFromEnv(&cfg.Features.Refresh, "FEATURES_REFRESH"),
+		boolFromEnv(&cfg.Maintenance.Enabled, "MAINTENANCE_ENABLED"),
+		durationFromEnv(&cfg.Maintenance.RetryAfter, "MAINTENANCE_RETRY_AFTER"),
+	)
+}
+
+func setFromEnv(target *string, key string) {
+	if value, ok := os.LookupEnv(key); ok {
+		*target = value
+	}
+}
+
+// listFromEnv reads a comma-separated list.
+func listFromEnv(target *[]string, key string) {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return
+	}
+	list := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	*target = list
+}
+
+func intFromEnv(target *int, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*target = n
+	return nil
+}
+
+func boolFromEnv(target *bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*target = b
+	return nil
+}
+
+func floatFromEnv(target *float64, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*target = f
+	return nil
+}
+
+// flagsFromEnv reads a comma-separated list of name=true or name=false,
+// a bare name being on, into the flags from the file.
+func flagsFromEnv(target *map[string]bool, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	if *target == nil {
+		*target = map[string]bool{}
+	}
+	for _, item := range strings.Split(value, ",") {
+		name, enabled, found := strings.Cut(strings.TrimSpace(item), "=")
+		if name == "" {
+			continue
+		}
+		on := true
+		if found {
+			var err error
+			if on, err = strconv.ParseBool(enabled); err != nil {
+				return fmt.Errorf("invalid %s: %s: %w", key, name, err)
+			}
+		}
+		(*target)[name] = on
+	}
+	return nil
+}
+
+func durationFromEnv(target *time.Duration, key string) error {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	*target = d
+	return nil
+}
+
+// Validate reports every missing or invalid setting at once.
+func (cfg *Config) Validate() error {
+	var problems []string
+
+	if cfg.Port == "" {
+		problems = append(problems, "port is required (PORT)")
+	}
+	if cfg.GRPCPort != "" && cfg.GRPCPort == cfg.Port {
+		problems = append(problems, "grpc port must differ from the http port (GRPC_PORT)")
+	}
+	if (cfg.TLS.CertFile == "") != (cfg.TLS.KeyFile == "") {
+		problems = append(problems, "tls needs both a certificate and a key file (TLS_CERT_FILE, TLS_KEY_FILE)")
+	}
+	if cfg.TLS.CertFile != "" && len(cfg.TLS.AutocertDomains) > 0 {
+		problems = append(problems, "tls takes either certificate files or autocert domains, not both (TLS_AUTOCERT_DOMAINS)")
+	}
+	if len(cfg.TLS.AutocertDomains) > 0 && cfg.TLS.AutocertCacheDir == "" {
+		problems = append(problems, "autocert needs a directory to keep certificates in (TLS_AUTOCERT_CACHE_DIR)")
+	}
+	if cfg.TLS.RedirectPort != "" && (!cfg.TLS.Enabled() || cfg.TLS.RedirectPort == cfg.Port || cfg.TLS.RedirectPort == cfg.GRPCPort) {
+		problems = append(problems, "tls redirect port needs tls and a port of its own (TLS_REDIRECT_PORT)")
+	}
+	if cfg.RequestAudit.Enabled && cfg.RequestAudit.MaxBodySize <= 0 {
+		problems = append(problems, "request audit max body size must be positive (REQUEST_AUDIT_MAX_BODY_SIZE)")
+	}
+	if cfg.Features.Refresh < 0 {
+		problems = append(problems, "features refresh must not be negative (FEATURES_REFRESH)")
+	}
+	if cfg.Maintenance.RetryAfter <= 0 {
+		problems = append(problems, "maintenance retry after must be positive (MAINTENANCE_RETRY_AFTER)")
+	}
+	if cfg.TLS.HSTSMaxAge < 0 {
+		problems = append(problems, "hsts max age must not be negative (TLS_HSTS_MAX_AGE)")
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		problems = append(problems, "shutdown timeout must be positive (SHUTDOWN_TIMEOUT)")
+	}
+	if cfg.RequestTimeout < 0 {
+		problems = append(problems, "request timeout must not be negative (REQUEST_TIMEOUT)")
+	}
+	if cfg.MaxBodySize <= 0 {
+		problems = append(problems, "max body size must be positive (MAX_BODY_SIZE)")
+	}
+	for _, proxy := range cfg.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				problems = append(problems, fmt.Sprintf("trusted proxy %q is neither an address nor a CIDR range (TRUSTED_PROXIES)", proxy))
+			}
+		}
+	}
+	switch cfg.Database.Driver {
+	case "sqlite", "postgres", "mysql":
+	default:
+		problems = append(problems, fmt.Sprintf("database driver must be one of sqlite, postgres, mysql, got %q (DB_DRIVER)", cfg.Database.Driver))
+	}
+	if cfg.Database.DSN == "" {
+		problems = append(problems, "database dsn is required (DB_DSN)")
+	}
+	for _, dsn := range cfg.Database.Replicas {
+		if dsn == "" {
+			problems = append(problems, "database replica dsns must not be empty (DB_REPLICAS)")
+			break
+		}
+	}
+	if cfg.Database.MaxOpenConns < 0 || cfg.Database.MaxIdleConns < 0 || cfg.Database.ConnMaxLifetime < 0 {
+		problems = append(problems, "database pool settings must not be negative")
+	}
+	if cfg.Database.ConnectTimeout < 0 {
+		problems = append(problems, "database connect timeout must not be negative (DB_CONNECT_TIMEOUT)")
+	}
+	if cfg.Database.BreakerThreshold < 0 {
+		problems = append(problems, "database breaker threshold must not be negative (DB_BREAKER_THRESHOLD)")
+	}
+	if cfg.Database.BreakerThreshold > 0 && cfg.Database.BreakerCooldown <= 0 {
+		problems = append(problems, "database breaker cooldown must be positive (DB_BREAKER_COOLDOWN)")
+	}
+	if cfg.Database.SlowQueryThreshold < 0 {
+		problems = append(problems, "slow query threshold must not be negative (DB_SLOW_QUERY_THRESHOLD)")
+	}
+	if cfg.Database.QueryStatsWindow <= 0 {
+		problems = append(problems, "query stats window must be positive (DB_QUERY_STATS_WINDOW)")
+	}
+	if cfg.Database.Partitioned && cfg.Database.Driver != "postgres" {
+		problems = append(problems, "tables can only be partitioned on postgres (DB_PARTITIONED)")
+	}
+	if cfg.Database.PartitionsAhead < 1 {
+		problems = append(problems, "partitions ahead must be at least 1 (DB_PARTITIONS_AHEAD)")
+	}
+	if cfg.Database.AuditRetention < 0 {
+		problems = append(problems, "audit retention must not be negative (DB_AUDIT_RETENTION)")
+	}
+	if cfg.Auth.JWTSecret == "" {
+		problems = append(problems, "jwt secret is required (JWT_SECRET)")
+	}
+	if cfg.Auth.TokenTTL <= 0 {
+		problems = append(problems, "jwt token ttl must be positive (JWT_TOKEN_TTL)")
+	}
+	if cfg.Auth.RefreshTokenTTL <= 0 {
+		problems = append(problems, "refresh token ttl must be positive (REFRESH_TOKEN_TTL)")
+	}
+	if cfg.Auth.VerificationTokenTTL <= 0 {
+		problems = append(problems, "verification token ttl must be positive (VERIFICATION_TOKEN_TTL)")
+	}
+	if cfg.Auth.ResetTokenTTL <= 0 {
+		problems = append(problems, "reset token ttl must be positive (RESET_TOKEN_TTL)")
+	}
+	if cfg.Auth.MaxFailedLogins < 0 {
+		problems = append(problems, "max failed logins must not be negative (MAX_FAILED_LOGINS)")
+	}
+	if cfg.Auth.MaxFailedLogins > 0 && (cfg.Auth.LockoutDuration <= 0 || cfg.Auth.MaxLockoutDuration < cfg.Auth.LockoutDuration) {
+		problems = append(problems, "lockout duration must be positive and at most the max lockout duration (LOCKOUT_DURATION, MAX_LOCKOUT_DURATION)")
+	}
+	if cfg.Auth.MaxIPFailedLogins < 0 {
+		problems = append(problems, "max ip failed logins must not be negative (MAX_IP_FAILED_LOGINS)")
+	}
+	if cfg.Auth.MaxIPFailedLogins > 0 && cfg.Auth.IPFailureWindow <= 0 {
+		problems = append(problems, "ip failure window must be positive (IP_FAILURE_WINDOW)")
+	}
+	if cfg.Auth.SignatureClockSkew <= 0 {
+		problems = append(problems, "signature clock skew must be positive (SIGNATURE_CLOCK_SKEW)")
+	}
+	if _, err := mail.ParseAddress(cfg.Mail.From); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid mail sender %q: %v (MAIL_FROM)", cfg.Mail.From, err))
+	}
+	if cfg.Mail.SMTP.Host != "" && (cfg.Mail.SMTP.Port < 1 || cfg.Mail.SMTP.Port > 65535) {
+		problems = append(problems, "smtp port must be between 1 and 65535 (SMTP_PORT)")
+	}
+	if cfg.Tracing.Endpoint != "" && cfg.Tracing.ServiceName == "" {
+		problems = append(problems, "tracing service name is required when an endpoint is set (OTEL_SERVICE_NAME)")
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing sample ratio must be between 0 and 1 (OTEL_TRACES_SAMPLE_RATIO)")
+	}
+	switch cfg.ErrorReporting.Provider {
+	case "":
+	case "sentry":
+		if cfg.ErrorReporting.Sentry.DSN == "" {
+			problems = append(problems, "sentry dsn is required to report errors to sentry (SENTRY_DSN)")
+		}
+		if cfg.ErrorReporting.Sentry.SampleRate <= 0 || cfg.ErrorReporting.Sentry.SampleRate > 1 {
+			problems = append(problems, "sentry sample rate must be above 0 and at most 1 (SENTRY_SAMPLE_RATE)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("error reporting provider must be sentry or empty, got %q (ERROR_REPORTING_PROVIDER)", cfg.ErrorReporting.Provider))
+	}
+	if cfg.RateLimit.Rate < 0 {
+		problems = append(problems, "rate limit must not be negative (RATE_LIMIT_RATE)")
+	}
+	if cfg.RateLimit.Rate > 0 && cfg.RateLimit.Burst < 1 {
+		problems = append(problems, "rate limit burst must be at least 1 (RATE_LIMIT_BURST)")
+	}
+	if cfg.RateLimit.AddressRate < 0 {
+		problems = append(problems, "address rate limit must not be negative (RATE_LIMIT_ADDRESS_RATE)")
+	}
+	if cfg.RateLimit.AddressRate > 0 && cfg.RateLimit.AddressBurst < 1 {
+		problems = append(problems, "address rate limit burst must be at least 1 (RATE_LIMIT_ADDRESS_BURST)")
+	}
+	if cfg.Quotas.APIKey < 0 {
+		problems = append(problems, "api key quota must not be negative (QUOTA_API_KEY)")
+	}
+	if cfg.Quotas.User < 0 {
+		problems = append(problems, "user quota must not be negative (QUOTA_USER)")
+	}
+	if cfg.Quotas.FlushInterval <= 0 {
+		problems = append(problems, "quota flush interval must be positive (QUOTA_FLUSH_INTERVAL)")
+	}
+	if cfg.Cache.TTL < 0 {
+		problems = append(problems, "cache ttl must not be negative (CACHE_TTL)")
+	}
+	if cfg.Cache.StatsTTL < 0 {
+		problems = append(problems, "cache stats ttl must not be negative (CACHE_STATS_TTL)")
+	}
+	if cfg.HTTPCache.ListMaxAge < 0 {
+		problems = append(problems, "http cache list max age must not be negative (HTTP_CACHE_LIST_MAX_AGE)")
+	}
+	if cfg.HTTPCache.DetailMaxAge < 0 {
+		problems = append(problems, "http cache detail max age must not be negative (HTTP_CACHE_DETAIL_MAX_AGE)")
+	}
+	if cfg.HTTPClient.RetryBackoff < 0 {
+		problems = append(problems, "http client retry backoff must not be negative (HTTP_CLIENT_RETRY_BACKOFF)")
+	}
+	if cfg.HTTPClient.BreakerThreshold < 0 {
+		problems = append(problems, "http client breaker threshold must not be negative (HTTP_CLIENT_BREAKER_THRESHOLD)")
+	}
+	if cfg.HTTPClient.BreakerThreshold > 0 && cfg.HTTPClient.BreakerCooldown <= 0 {
+		problems = append(problems, "http client breaker cooldown must be positive (HTTP_CLIENT_BREAKER_COOLDOWN)")
+	}
+	if cfg.Lending.LoanDuration <= 0 {
+		problems = append(problems, "loan duration must be positive (LOAN_DURATION)")
+	}
+	fineAmounts := []struct{ value, env string }{
+		{cfg.Lending.Fines.DailyRate, "FINES_DAILY_RATE"},
+		{cfg.Lending.Fines.Cap, "FINES_CAP"},
+	}
+	if cfg.Lending.Fines.BlockAbove != "" {
+		fineAmounts = append(fineAmounts, struct{ value, env string }{cfg.Lending.Fines.BlockAbove, "FINES_BLOCK_ABOVE"})
+	}
+	for _, amount := range fineAmounts {
+		if parsed, err := decimal.NewFromString(amount.value); err != nil || parsed.IsNegative() {
+			problems = append(problems, fmt.Sprintf("fine amounts must be decimals of at least 0, got %q (%s)", amount.value, amount.env))
+		}
+	}
+	if !money.Valid(cfg.Lending.Fines.Currency) {
+		problems = append(problems, fmt.Sprintf("fines currency must be an ISO 4217 currency code, got %q (FINES_CURRENCY)", cfg.Lending.Fines.Currency))
+	}
+	if cfg.Lending.Holds.PickupWindow <= 0 {
+		problems = append(problems, "hold pickup window must be positive (HOLDS_PICKUP_WINDOW)")
+	}
+	if cfg.Lending.Holds.MaxPerMember < 0 {
+		problems = append(problems, "holds per member must not be negative (HOLDS_MAX_PER_MEMBER)")
+	}
+	for _, channel := range cfg.Lending.Notifications.DefaultChannels {
+		if channel != "email" && channel != "webhook" && channel != "console" {
+			problems = append(problems, fmt.Sprintf("notification channels must be among email, webhook, console, got %q (NOTIFICATIONS_DEFAULT_CHANNELS)", channel))
+		}
+	}
+	if cfg.Lending.Notifications.WebhookTimeout <= 0 {
+		problems = append(problems, "notification webhook timeout must be positive (NOTIFICATIONS_WEBHOOK_TIMEOUT)")
+	}
+	if cfg.Lending.Notifications.WebhookRetries < 0 {
+		problems = append(problems, "notification webhook retries must not be negative (NOTIFICATIONS_WEBHOOK_RETRIES)")
+	}
+	if cfg.Lookup.Timeout <= 0 {
+		problems = append(problems, "lookup timeout must be positive (LOOKUP_TIMEOUT)")
+	}
+	if cfg.Lookup.Retries < 0 {
+		problems = append(problems, "lookup retries must not be negative (LOOKUP_RETRIES)")
+	}
+	if cfg.Lookup.CacheTTL < 0 {
+		problems = append(problems, "lookup cache ttl must not be negative (LOOKUP_CACHE_TTL)")
+	}
+	if cfg.Currency.URL != "" && cfg.Currency.Timeout <= 0 {
+		problems = append(problems, "exchange rates timeout must be positive (EXCHANGE_RATES_TIMEOUT)")
+	}
+	if cfg.Currency.Retries < 0 {
+		problems = append(problems, "exchange rates retries must not be negative (EXCHANGE_RATES_RETRIES)")
+	}
+	if cfg.Currency.CacheTTL < 0 {
+		problems = append(problems, "exchange rates cache ttl must not be negative (EXCHANGE_RATES_CACHE_TTL)")
+	}
+	if len(cfg.Currency.Rates) > 0 && !money.Valid(cfg.Currency.Base) {
+		problems = append(problems, fmt.Sprintf("exchange rates base must be an ISO 4217 currency code, got %q", cfg.Currency.Base))
+	}
+	for code, rate := range cfg.Currency.Rates {
+		if parsed, err := decimal.NewFromString(rate); !money.Valid(code) || err != nil || !parsed.IsPositive() {
+			problems = append(problems, fmt.Sprintf("exchange rate %s: %q must be a positive number for an ISO 4217 currency code", code, rate))
+		}
+	}
+	if cfg.Search.URL != "" {
+		if cfg.Search.Index == "" {
+			problems = append(problems, "search index must not be empty (SEARCH_INDEX)")
+		}
+		if cfg.Search.Timeout <= 0 {
+			problems = append(problems, "search timeout must be positive (SEARCH_TIMEOUT)")
+		}
+	}
+	if cfg.Webhooks.Timeout <= 0 {
+		problems = append(problems, "webhook timeout must be positive (WEBHOOK_TIMEOUT)")
+	}
+	if cfg.Webhooks.MaxAttempts < 1 {
+		problems = append(problems, "webhook max attempts must be at least 1 (WEBHOOK_MAX_ATTEMPTS)")
+	}
+	if cfg.Webhooks.RetryBackoff <= 0 {
+		problems = append(problems, "webhook retry backoff must be positive (WEBHOOK_RETRY_BACKOFF)")
+	}
+	if cfg.Webhooks.Workers < 1 {
+		problems = append(problems, "webhook workers must be at least 1 (WEBHOOK_WORKERS)")
+	}
+	if cfg.Events.Heartbeat <= 0 {
+		problems = append(problems, "event stream heartbeat must be positive (EVENTS_HEARTBEAT)")
+	}
+	if cfg.Events.MetricsInterval <= 0 {
+		problems = append(problems, "admin channel metrics interval must be positive (EVENTS_METRICS_INTERVAL)")
+	}
+	switch cfg.Outbox.Broker {
+	case "":
+	case "nats":
+		if cfg.Outbox.NATS.URL == "" || cfg.Outbox.NATS.Stream == "" || cfg.Outbox.NATS.SubjectPrefix == "" {
+			problems = append(problems, "nats url, stream and subject prefix are required for the nats broker (NATS_URL, NATS_STREAM, NATS_SUBJECT_PREFIX)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("outbox broker must be nats or empty, got %q (OUTBOX_BROKER)", cfg.Outbox.Broker))
+	}
+	if cfg.Outbox.PollInterval <= 0 {
+		problems = append(problems, "outbox poll interval must be positive (OUTBOX_POLL_INTERVAL)")
+	}
+	if cfg.Outbox.BatchSize < 1 {
+		problems = append(problems, "outbox batch size must be at least 1 (OUTBOX_BATCH_SIZE)")
+	}
+	if cfg.Outbox.Retention < 0 {
+		problems = append(problems, "outbox retention must not be negative (OUTBOX_RETENTION)")
+	}
+	if cfg.Jobs.Workers < 1 {
+		problems = append(problems, "job workers must be at least 1 (JOBS_WORKERS)")
+	}
+	for _, schedule := range []struct{ value, env string }{
+		{cfg.Jobs.PurgeSchedule, "JOBS_PURGE_SCHEDULE"},
+		{cfg.Jobs.RatingsSchedule, "JOBS_RATINGS_SCHEDULE"},
+		{cfg.Jobs.RemindersSchedule, "JOBS_REMINDERS_SCHEDULE"},
+		{cfg.Jobs.RecommendationsSchedule, "JOBS_RECOMMENDATIONS_SCHEDULE"},
+		{cfg.Jobs.HoldsSchedule, "JOBS_HOLDS_SCHEDULE"},
+		{cfg.Jobs.ExportsSchedule, "JOBS_EXPORTS_SCHEDULE"},
+		{cfg.Jobs.PartitionsSchedule, "JOBS_PARTITIONS_SCHEDULE"},
+	} {
+		if _, err := cron.ParseStandard(schedule.value); schedule.value != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("invalid job schedule %q: %v (%s)", schedule.value, err, schedule.env))
+		}
+	}
+	if cfg.Jobs.PurgeAfter <= 0 {
+		problems = append(problems, "purge retention must be positive (JOBS_PURGE_AFTER)")
+	}
+	if cfg.Jobs.ExportsExpireAfter <= 0 {
+		problems = append(problems, "export retention must be positive (JOBS_EXPORTS_EXPIRE_AFTER)")
+	}
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		switch {
+		case origin == "*":
+			if len(cfg.CORS.AllowedOrigins) > 1 {
+				problems = append(problems, "cors origin * can't be combined with other origins (CORS_ALLOWED_ORIGINS)")
+			}
+			if cfg.CORS.AllowCredentials {
+				problems = append(problems, "cors credentials can't be allowed for every origin (CORS_ALLOW_CREDENTIALS)")
+			}
+		case !strings.HasPrefix(origin, "http://") && !strings.HasPrefix(origin, "https://"):
+			problems = append(problems, fmt.Sprintf("cors origin %q must start with http:// or https:// (CORS_ALLOWED_ORIGINS)", origin))
+		}
+	}
+	for _, provider := range []struct {
+		name string
+		cfg  OAuthProviderConfig
+	}{{"google", cfg.OAuth.Google}, {"github", cfg.OAuth.GitHub}} {
+		if (provider.cfg.ClientID == "") != (provider.cfg.ClientSecret == "") {
+			problems = append(problems, fmt.Sprintf("%s oauth needs both a client id and a client secret (%s_CLIENT_ID, %s_CLIENT_SECRET)", provider.name, strings.ToUpper(provider.name), strings.ToUpper(provider.name)))
+		}
+		redirect := cfg.OAuth.RedirectBaseURL
+		if provider.cfg.ClientID != "" && !strings.HasPrefix(redirect, "http://") && !strings.HasPrefix(redirect, "https://") {
+			problems = append(problems, fmt.Sprintf("%s oauth needs the http:// or https:// URL of the API to redirect back to (OAUTH_REDIRECT_BASE_URL)", provider.name))
+		}
+	}
+	for _, key := range append([]string{cfg.Encryption.Key, cfg.Encryption.IndexKey}, cfg.Encryption.PreviousKeys...) {
+		if decoded, err := base64.StdEncoding.DecodeString(key); key != "" && (err != nil || len(decoded) != 32) {
+			problems = append(problems, "encryption keys must be 32 bytes, base64-encoded (ENCRYPTION_KEY, ENCRYPTION_PREVIOUS_KEYS, ENCRYPTION_INDEX_KEY)")
+			break
+		}
+	}
+	if cfg.Encryption.Key == "" && len(cfg.Encryption.PreviousKeys) > 0 {
+		problems = append(problems, "previous encryption keys need a current one (ENCRYPTION_KEY)")
+	}
+	if cfg.Encryption.Key != "" && cfg.Encryption.IndexKey == "" {
+		problems = append(problems, "field encryption needs an index key too (ENCRYPTION_INDEX_KEY)")
+	}
+	if cfg.Secrets.Provider != "" {
+		problems = append(problems, cfg.Secrets.problems()...)
+	}
+	if cfg.Secrets.Refresh < 0 {
+		problems = append(problems, "secrets refresh must not be negative (SECRETS_REFRESH)")
+	}
+	if cfg.Tenancy.Header == "" {
+		problems = append(problems, "tenant header is required (TENANT_HEADER)")
+	}
+	if cfg.Compression.Level < 0 || cfg.Compression.Level > 9 {
+		problems = append(problems, "compression level must be between 0 and 9 (COMPRESSION_LEVEL)")
+	}
+	if cfg.Compression.MinSize < 0 {
+		problems = append(problems, "compression min size must not be negative (COMPRESSION_MIN_SIZE)")
+	}
+	if cfg.Idempotency.TTL < 0 {
+		problems = append(problems, "idempotency ttl must not be negative (IDEMPOTENCY_TTL)")
+	}
+	switch cfg.Storage.Driver {
+	case "local":
+		if cfg.Storage.LocalDir == "" {
+			problems = append(problems, "storage directory is required for the local driver (STORAGE_LOCAL_DIR)")
+		}
+	case "s3":
+		if cfg.Storage.S3.Endpoint == "" || cfg.Storage.S3.Bucket == "" {
+			problems = append(problems, "s3 endpoint and bucket are required for the s3 driver (S3_ENDPOINT, S3_BUCKET)")
+		}
+		// Presigned URLs are valid for at most a week.
+		if cfg.Storage.S3.PresignExpiry < 0 || cfg.Storage.S3.PresignExpiry > 7*24*time.Hour {
+			problems = append(problems, "s3 presign expiry must be between 0 and 168h (S3_PRESIGN_EXPIRY)")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("storage driver must be one of local, s3, got %q (STORAGE_DRIVER)", cfg.Storage.Driver))
+	}
+	if cfg.Storage.MaxCoverPixels < 1 {
+		problems = append(problems, "max cover pixels must be at least 1 (STORAGE_MAX_COVER_PIXELS)")
+	}
+
+	switch cfg.LogLevel {
+	case "debug", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Sprintf("log level must be one of debug, info, warn, error, got %q (LOG_LEVEL)", cfg.LogLevel))
+	}
+
+	switch cfg.GinMode {
+	case gin.DebugMode, gin.ReleaseMode, gin.TestMode:
+	default:
+		problems = append(problems, fmt.Sprintf("gin mode must be one of debug, release, test, got %q (GIN_MODE)", cfg.GinMode))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
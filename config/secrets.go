@@ -0,0 +1,170 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SecretRefPrefix starts the settings that refer to a secret rather than
+// holding a value: secret:<name>#<key> is the value under key in the named
+// secret, and secret:<name> the whole of a secret that is a single value.
+const SecretRefPrefix = "secret:"
+
+// SecretsConfig is for the secrets manager the settings referring to a
+// secret, such as the database DSN or the JWT secret, are fetched from.
+type SecretsConfig struct {
+	// vault or aws; empty has references to secrets rejected.
+	Provider string           `yaml:"provider"`
+	Vault    VaultConfig      `yaml:"vault"`
+	AWS      AWSSecretsConfig `yaml:"aws"`
+	// Bounds fetching the secrets.
+	Timeout time.Duration `yaml:"timeout"`
+	// How often the secrets are fetched again, so rotated ones are picked
+	// up as a reload would; 0 fetches them at startup only. Reloads don't
+	// reconnect to the database, so rotated database credentials take a
+	// restart.
+	Refresh time.Duration `yaml:"refresh"`
+}
+
+// VaultConfig points at the KV version 2 secrets engine of a HashiCorp
+// Vault server, under which secrets are named by their path.
+type VaultConfig struct {
+	Address string `yaml:"address"`
+	Token   string `yaml:"token"`
+	// Where the engine is mounted.
+	Mount string `yaml:"mount"`
+	// Vault Enterprise namespace; optional.
+	Namespace string `yaml:"namespace"`
+}
+
+// AWSSecretsConfig is for AWS Secrets Manager, whose secrets are named by
+// name or ARN. Credentials come from where the AWS SDK looks by default:
+// the environment, shared files, or the instance or task role.
+type AWSSecretsConfig struct {
+	// Empty takes the region the SDK finds by default too.
+	Region string `yaml:"region"`
+}
+
+// SecretsProvider fetches secrets from a secrets manager.
+type SecretsProvider interface {
+	// Secret returns the values of the named secret by key, a secret that
+	// is a single value having it under the empty key.
+	Secret(ctx context.Context, name string) (map[string]string, error)
+}
+
+// NewSecretsProvider returns the provider cfg configures, which must be
+// valid.
+func NewSecretsProvider(ctx context.Context, cfg SecretsConfig) (SecretsProvider, error) {
+	if cfg.Provider == "aws" {
+		return NewAWSSecrets(ctx, cfg.AWS)
+	}
+	return NewVaultSecrets(cfg.Vault, cfg.Timeout), nil
+}
+
+// ResolveSecrets replaces the settings referring to a secret with its value
+// from provider, fetching each secret once. The secrets settings themselves
+// can't refer to one.
+func (cfg *Config) ResolveSecrets(ctx context.Context, provider SecretsProvider) error {
+	fetched := map[string]map[string]string{}
+	return cfg.eachSecretRef(func(setting string, value *string) error {
+		name, key, _ := strings.Cut(strings.TrimPrefix(*value, SecretRefPrefix), "#")
+		secret, ok := fetched[name]
+		if !ok {
+			var err error
+			if secret, err = provider.Secret(ctx, name); err != nil {
+				return fmt.Errorf("%s: fetching secret %s: %w", setting, name, err)
+			}
+			fetched[name] = secret
+		}
+		resolved, ok := secret[key]
+		if !ok {
+			return fmt.Errorf("%s: secret %s has no key %q", setting, name, key)
+		}
+		*value = resolved
+		return nil
+	})
+}
+
+// loadSecrets resolves the references to secrets, if there are any. It
+// comes before Validate, which checks the values.
+func (cfg *Config) loadSecrets() error {
+	refs := false
+	cfg.eachSecretRef(func(string, *string) error {
+		refs = true
+		return nil
+	})
+	if !refs {
+		return nil
+	}
+	if problems := cfg.Secrets.problems(); len(problems) > 0 {
+		return fmt.Errorf("invalid configuration: %s", strings.Join(problems, "; "))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Secrets.Timeout)
+	defer cancel()
+	provider, err := NewSecretsProvider(ctx, cfg.Secrets)
+	if err != nil {
+		return err
+	}
+	return cfg.ResolveSecrets(ctx, provider)
+}
+
+// problems are those of the secrets settings, which need checking before
+// secrets are fetched.
+func (cfg SecretsConfig) problems() []string {
+	var problems []string
+	switch cfg.Provider {
+	case "aws":
+	case "vault":
+		if cfg.Vault.Address == "" || cfg.Vault.Token == "" || cfg.Vault.Mount == "" {
+			problems = append(problems, "vault address, token and mount are required for the vault secrets provider (VAULT_ADDR, VAULT_TOKEN)")
+		}
+	case "":
+		problems = append(problems, "settings refer to secrets but no secrets provider is configured (SECRETS_PROVIDER)")
+	default:
+		problems = append(problems, fmt.Sprintf("secrets provider must be one of vault, aws, got %q (SECRETS_PROVIDER)", cfg.Provider))
+	}
+	if cfg.Timeout <= 0 {
+		problems = append(problems, "secrets timeout must be positive (SECRETS_TIMEOUT)")
+	}
+	return problems
+}
+
+// eachSecretRef calls fn with every string setting, in a list or not,
+// referring to a secret, named by its path in the configuration file.
+func (cfg *Config) eachSecretRef(fn func(setting string, value *string) error) error {
+	var walk func(v reflect.Value, path string) error
+	walk = func(v reflect.Value, path string) error {
+		switch v.Kind() {
+		case reflect.String:
+			if strings.HasPrefix(v.String(), SecretRefPrefix) {
+				return fn(path, v.Addr().Interface().(*string))
+			}
+		case reflect.Slice:
+			for i := 0; i < v.Len(); i++ {
+				if err := walk(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		case reflect.Struct:
+			for i := 0; i < v.NumField(); i++ {
+				field := v.Type().Field(i)
+				if !field.IsExported() || field.Type == reflect.TypeOf(SecretsConfig{}) {
+					continue
+				}
+				name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+				if path != "" {
+					name = path + "." + name
+				}
+				if err := walk(v.Field(i), name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(reflect.ValueOf(cfg).Elem(), "")
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// AWSSecrets reads secrets from AWS Secrets Manager. Those whose value is a
+// JSON object, as the console stores key/value pairs, have its members as
+// keys; others are a single value.
+type AWSSecrets struct {
+	client *secretsmanager.Client
+}
+
+func NewAWSSecrets(ctx context.Context, cfg AWSSecretsConfig) (*AWSSecrets, error) {
+	var opts []func(*awsconfig.LoadOptions) error
+	if cfg.Region != "" {
+		opts = append(opts, awsconfig.WithRegion(cfg.Region))
+	}
+	sdkConfig, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &AWSSecrets{client: secretsmanager.NewFromConfig(sdkConfig)}, nil
+}
+
+func (s *AWSSecrets) Secret(ctx context.Context, name string) (map[string]string, error) {
+	out, err := s.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{SecretId: aws.String(name)})
+	if err != nil {
+		return nil, err
+	}
+	value := aws.ToString(out.SecretString)
+	if out.SecretString == nil {
+		value = string(out.SecretBinary)
+	}
+
+	var members map[string]interface{}
+	if json.Unmarshal([]byte(value), &members) != nil {
+		return map[string]string{"": value}, nil
+	}
+	values := make(map[string]string, len(members))
+	for key, member := range members {
+		if s, ok := member.(string); ok {
+			values[key] = s
+		} else {
+			encoded, _ := json.Marshal(member)
+			values[key] = string(encoded)
+		}
+	}
+	return values, nil
+}
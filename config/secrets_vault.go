@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// VaultSecrets reads secrets from the KV version 2 engine of a Vault server,
+// authenticating with a token.
+type VaultSecrets struct {
+	http *http.Client
+	cfg  VaultConfig
+}
+
+func NewVaultSecrets(cfg VaultConfig, timeout time.Duration) *VaultSecrets {
+	return &VaultSecrets{http: &http.Client{Timeout: timeout}, cfg: cfg}
+}
+
+type vaultSecret struct {
+	Data struct {
+		Data map[string]interface{} `json:"data"`
+	} `json:"data"`
+}
+
+func (v *VaultSecrets) Secret(ctx context.Context, name string) (map[string]string, error) {
+	endpoint := strings.TrimSuffix(v.cfg.Address, "/") + "/v1/" + url.PathEscape(v.cfg.Mount) + "/data/" + strings.TrimPrefix(name, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("X-Vault-Token", v.cfg.Token)
+	if v.cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.cfg.Namespace)
+	}
+
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("vault answered %d", resp.StatusCode)
+	}
+
+	var body vaultSecret
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(body.Data.Data))
+	for key, value := range body.Data.Data {
+		if s, ok := value.(string); ok {
+			values[key] = s
+		} else {
+			encoded, _ := json.Marshal(value)
+			values[key] = string(encoded)
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,173 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/live"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/metrics"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// Commands are small JSON objects.
+const maxCommandSize = 4 << 10
+
+// How long writing a message to an admin may take before they are
+// disconnected.
+const adminWriteWait = 10 * time.Second
+
+// CommandRunner runs what admins send over the admin channel;
+// *live.Commands implements it.
+type CommandRunner interface {
+	Run(ctx context.Context, userID uint, command live.Command) live.Result
+}
+
+type AdminChannelController struct {
+	catalog  EventSource
+	feed     EventSource
+	commands CommandRunner
+	cfg      config.EventsConfig
+	upgrader websocket.Upgrader
+}
+
+// NewAdminChannelController pushes the events of catalog and feed. Browsers
+// may connect from the API's own pages and those of origins, which are
+// matched as CORS origins are.
+func NewAdminChannelController(catalog, feed EventSource, commands CommandRunner, cfg config.EventsConfig, origins []string) *AdminChannelController {
+	return &AdminChannelController{
+		catalog:  catalog,
+		feed:     feed,
+		commands: commands,
+		cfg:      cfg,
+		upgrader: websocket.Upgrader{CheckOrigin: checkOrigin(origins)},
+	}
+}
+
+func checkOrigin(origins []string) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		// Not a browser.
+		if origin == "" {
+			return true
+		}
+		if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, allowed := range origins {
+			prefix, suffix, wildcard := strings.Cut(allowed, "*")
+			if origin == allowed || wildcard && strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// GET /ws
+//
+// @Summary Follow the instance live
+// @Description WebSocket for admin dashboards. It pushes JSON messages shaped like catalog events ({id, type, occurred_at, data}): the book.created, book.updated and book.deleted of the catalog, then the loan.created, loan.returned and error.reported (panics and 5xx errors) of this instance, and a metrics.snapshot of it on connecting and every EVENTS_METRICS_INTERVAL.
+// @Description It runs the commands sent as JSON: {"id": "1", "command": "maintenance", "enabled": true} turns maintenance mode on or off, {"id": "2", "command": "invalidate-cache", "cache": "books"} drops the cached reads of books or stats, or of both without a cache. Each is answered with a command.result event. Browsers, which can't set headers on WebSockets, pass their bearer token as access_token.
+// @Tags admin
+// @Security BearerAuth
+// @Param access_token query string false "Bearer token, for browsers"
+// @Success 101 "Switching Protocols"
+// @Failure 400 "Not a WebSocket handshake"
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /ws [get]
+func (ctrl *AdminChannelController) Connect(c *gin.Context) {
+	conn, err := ctrl.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrade answered the request already.
+		return
+	}
+	defer conn.Close()
+
+	catalog, cancelCatalog := ctrl.catalog.Subscribe(eventStreamBuffer)
+	defer cancelCatalog()
+	feed, cancelFeed := ctrl.feed.Subscribe(eventStreamBuffer)
+	defer cancelFeed()
+
+	// Connections take one writer at a time, so results are written here.
+	results, done := make(chan live.Result), make(chan struct{})
+	defer close(done)
+	go ctrl.readCommands(c.Request.Context(), conn, c.GetUint(middlewares.UserIDKey), results, done)
+
+	heartbeat := time.NewTicker(ctrl.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	snapshots := time.NewTicker(ctrl.cfg.MetricsInterval)
+	defer snapshots.Stop()
+
+	send := func(event events.Event) bool {
+		conn.SetWriteDeadline(time.Now().Add(adminWriteWait))
+		return conn.WriteJSON(event) == nil
+	}
+	ok := ctrl.sendSnapshot(c.Request.Context(), send)
+	for ok {
+		select {
+		case event, open := <-catalog:
+			ok = open && send(event)
+		case event, open := <-feed:
+			ok = open && send(event)
+		case result, open := <-results:
+			ok = open && send(events.New(live.CommandResult, result))
+		case <-snapshots.C:
+			ok = ctrl.sendSnapshot(c.Request.Context(), send)
+		case <-heartbeat.C:
+			ok = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(adminWriteWait)) == nil
+		}
+	}
+	// The subscription ended, with the server shutting down or the admin
+	// falling behind, or the connection is gone and this fails.
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(adminWriteWait))
+}
+
+func (ctrl *AdminChannelController) sendSnapshot(ctx context.Context, send func(events.Event) bool) bool {
+	snapshot, err := metrics.TakeSnapshot()
+	if err != nil {
+		slog.WarnContext(ctx, "taking metrics snapshot failed", "error", err)
+		return true
+	}
+	return send(events.New(live.MetricsSnapshot, snapshot))
+}
+
+// readCommands runs the commands of the admin and hands their results over,
+// until the connection fails or falls silent for two heartbeats, or done is
+// closed. It closes results then.
+func (ctrl *AdminChannelController) readCommands(ctx context.Context, conn *websocket.Conn, userID uint, results chan<- live.Result, done <-chan struct{}) {
+	defer close(results)
+	conn.SetReadLimit(maxCommandSize)
+	extend := func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(2 * ctrl.cfg.Heartbeat))
+	}
+	extend("")
+	conn.SetPongHandler(extend)
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		extend("")
+		var command live.Command
+		result := live.Result{Error: "commands must be JSON objects"}
+		if json.Unmarshal(message, &command) == nil {
+			result = ctrl.commands.Run(ctx, userID, command)
+		}
+		select {
+		case results <- result:
+		case <-done:
+			return
+		}
+	}
+}
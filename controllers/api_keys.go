@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAPIKeyInput struct {
+	Name   string   `json:"name" binding:"required,max=255"`
+	Scopes []string `json:"scopes" binding:"required,min=1,dive,oneof=read write"`
+	// Requests per second and burst allowed to the key; the default rate
+	// limit when left out.
+	RateLimit float64 `json:"rate_limit" binding:"omitempty,gt=0,required_with=RateBurst"`
+	RateBurst int     `json:"rate_burst" binding:"omitempty,gt=0,required_with=RateLimit"`
+	// Requests allowed to the key per calendar month; the default quota
+	// when left out.
+	MonthlyQuota int        `json:"monthly_quota" binding:"omitempty,gt=0"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	// Signed keys sign their requests instead of being sent with them.
+	Signed bool `json:"signed"`
+}
+
+// CreatedAPIKey is the only representation of an API key that includes the
+// key itself.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+type APIKeyController struct {
+	keys services.APIKeyService
+}
+
+func NewAPIKeyController(keys services.APIKeyService) *APIKeyController {
+	return &APIKeyController{keys: keys}
+}
+
+// @Summary List API keys
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.APIKey,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/api-keys [get]
+func (ctrl *APIKeyController) FindAPIKeys(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	keys, total, err := ctrl.keys.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": keys, "meta": pagination})
+}
+
+// @Summary Get an API key
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} object{data=models.APIKey}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/api-keys/{id} [get]
+func (ctrl *APIKeyController) FindAPIKey(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	key, err := ctrl.keys.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": key})
+}
+
+// @Summary Issue an API key
+// @Description The key is issued to the caller and authenticates as them when sent in the X-API-Key header. Keys with only the read scope can make GET, HEAD and OPTIONS requests.
+// @Description Signed keys are never sent: requests carry X-Signature-Key-Id, the key's ID; X-Signature-Timestamp, the Unix time in seconds; X-Signature-Nonce, 8 to 128 characters never used before; X-Content-SHA256, the hex SHA-256 of the body, empty or not; and X-Signature, the hex HMAC-SHA256 with the key of the method, path with query string, timestamp, nonce and body hash, joined by newlines. The timestamp must be within the configured clock skew of the server's.
+// @Description The response is the only time the key is shown.
+// @Tags api-keys
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateAPIKeyInput true "API key"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=controllers.CreatedAPIKey}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/api-keys [post]
+func (ctrl *APIKeyController) CreateAPIKey(c *gin.Context) {
+	var input CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		c.Error(apierrors.Validation("expires_at must be in the future"))
+		return
+	}
+
+	key := models.APIKey{
+		Name:         input.Name,
+		Scopes:       input.Scopes,
+		UserID:       c.GetUint(middlewares.UserIDKey),
+		RateLimit:    input.RateLimit,
+		RateBurst:    input.RateBurst,
+		MonthlyQuota: input.MonthlyQuota,
+		ExpiresAt:    input.ExpiresAt,
+		Signed:       input.Signed,
+	}
+	secret, err := ctrl.keys.Issue(c.Request.Context(), &key)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": CreatedAPIKey{APIKey: key, Key: secret}})
+}
+
+// @Summary Revoke an API key
+// @Description Requests made with the key are rejected from then on. The key is kept, for the record; revoking it again does nothing.
+// @Tags api-keys
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "API key ID"
+// @Success 200 {object} object{data=models.APIKey}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/api-keys/{id} [delete]
+func (ctrl *APIKeyController) RevokeAPIKey(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	key, err := ctrl.keys.Revoke(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": key})
+}
@@ -0,0 +1,174 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+const invalidAuditCursor = "Invalid cursor; it must come from next_cursor of an audit log list."
+
+type AuditController struct {
+	audit services.AuditService
+}
+
+func NewAuditController(audit services.AuditService) *AuditController {
+	return &AuditController{audit: audit}
+}
+
+// GET audit?user_id=&entity=&entity_id=&from=&to=&page=&page_size=&cursor=
+//
+// @Summary List audit log entries
+// @Description Newest first. from and to take an RFC 3339 time or a date; a date in to includes that whole day.
+// @Description With cursor, pages follow each other by position rather than offset, so they cost the same however deep and entries written meanwhile don't shift them; page is ignored and meta is a controllers.CursorPagination, without totals. Prefer it for large audit logs.
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param user_id query int false "User who made the change"
+// @Param entity query string false "Table name, e.g. books"
+// @Param entity_id query string false "Primary key of the row"
+// @Param from query string false "Earliest time (inclusive)"
+// @Param to query string false "Latest time"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param cursor query string false "Page by cursor instead: empty for the first page, then the previous page's next_cursor"
+// @Success 200 {object} object{data=[]models.AuditLog,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/audit [get]
+func (ctrl *AuditController) FindAuditLogs(c *gin.Context) {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	pagination := paginationFromQuery(c)
+	if raw, ok := c.GetQuery("cursor"); ok {
+		ctrl.listAuditLogsAfter(c, filter, pagination.PageSize, raw)
+		return
+	}
+
+	entries, total, err := ctrl.audit.List(c.Request.Context(), filter, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": entries, "meta": pagination})
+}
+
+// listAuditLogsAfter serves ?cursor=, empty for the first page.
+func (ctrl *AuditController) listAuditLogsAfter(c *gin.Context, filter repositories.AuditFilter, limit int, raw string) {
+	var after *repositories.AuditCursor
+	if raw != "" {
+		var cursor repositories.AuditCursor
+		if err := decodeCursor(raw, &cursor); err != nil || cursor.ID == 0 {
+			c.Error(apierrors.Validation(invalidAuditCursor))
+			return
+		}
+		after = &cursor
+	}
+
+	entries, next, err := ctrl.audit.ListAfter(c.Request.Context(), filter, after, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	meta := CursorPagination{PageSize: limit}
+	if next != nil {
+		encoded, err := encodeCursor(next)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		meta.NextCursor = &encoded
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": entries, "meta": meta})
+}
+
+// GET books/:id/history?page=&page_size=
+//
+// @Summary List the changes made to a book
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.AuditLog,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/history [get]
+func (ctrl *AuditController) FindBookHistory(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	entries, total, err := ctrl.audit.BookHistory(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": entries, "meta": pagination})
+}
+
+func auditFilterFromQuery(c *gin.Context) (repositories.AuditFilter, error) {
+	filter := repositories.AuditFilter{
+		Entity:   c.Query("entity"),
+		EntityID: c.Query("entity_id"),
+	}
+
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid user_id: %q", raw)
+		}
+		filter.UserID = uint(id)
+	}
+
+	var err error
+	if filter.From, err = timeFromQuery(c, "from", false); err != nil {
+		return filter, err
+	}
+	if filter.To, err = timeFromQuery(c, "to", true); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+// timeFromQuery parses an RFC 3339 time or a YYYY-MM-DD date (midnight UTC).
+// With endOfDay a date means the following midnight, so that an exclusive
+// bound still covers the whole day.
+func timeFromQuery(c *gin.Context, param string, endOfDay bool) (time.Time, error) {
+	raw := c.Query(param)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	day, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: %q is neither an RFC 3339 time nor a date", param, raw)
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, nil
+}
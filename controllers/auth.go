@@ -0,0 +1,422 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/oauth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// The state a provider login was started with, to check that the callback
+// comes from the same browser.
+const oauthStateCookie = "oauth_state"
+
+type RegisterInput struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type LoginInput struct {
+	Email    string `json:"email" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	// An authenticator or recovery code, for users with two-factor
+	// authentication enabled.
+	Code string `json:"code"`
+}
+
+type RefreshInput struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutInput struct {
+	// Revoked along with the access token, so it can't be refreshed.
+	RefreshToken string `json:"refresh_token"`
+	// Revokes every refresh token of the caller instead.
+	Everywhere bool `json:"everywhere"`
+}
+
+type VerifyEmailInput struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type ForgotPasswordInput struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordInput struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=8,max=72"`
+}
+
+type AuthController struct {
+	auth     services.AuthService
+	accounts services.AccountService
+}
+
+func NewAuthController(auth services.AuthService, accounts services.AccountService) *AuthController {
+	return &AuthController{auth: auth, accounts: accounts}
+}
+
+// POST /auth/register
+//
+// @Summary Register a user account
+// @Description A link to verify the email address is mailed to it.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.RegisterInput true "Credentials"
+// @Success 201 {object} object{data=models.User}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/auth/register [post]
+func (ctrl *AuthController) Register(c *gin.Context) {
+	var input RegisterInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	user, err := ctrl.auth.Register(c.Request.Context(), input.Email, input.Password)
+	if errors.Is(err, services.ErrEmailTaken) {
+		c.Error(apierrors.Conflict("Email already registered!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// The account is usable without it; the user can ask for another.
+	if err := ctrl.accounts.SendVerification(c.Request.Context(), user.ID); err != nil {
+		slog.WarnContext(c.Request.Context(), "sending verification email", "user_id", user.ID, "error", err)
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": user})
+}
+
+// POST /auth/login
+//
+// @Summary Log in and obtain a JWT
+// @Description The response includes a refresh token, to exchange for a new JWT at /auth/refresh when it expires.
+// @Description Users with two-factor authentication enabled also give a code; without one the 401 response has second_factor_required set.
+// @Description Repeated failed logins lock the account, or block the client's address, for a while: the 429 response has locked_until and a Retry-After header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.LoginInput true "Credentials"
+// @Success 200 {object} object{data=services.Token}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 429 {object} apierrors.Problem
+// @Router /api/v1/auth/login [post]
+func (ctrl *AuthController) Login(c *gin.Context) {
+	var input LoginInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	token, err := ctrl.auth.Login(c.Request.Context(), services.LoginAttempt{
+		Email:    input.Email,
+		Password: input.Password,
+		Code:     input.Code,
+		IP:       c.ClientIP(),
+	})
+	var locked *services.LockedOutError
+	switch {
+	case errors.As(err, &locked):
+		retryAfter := max(int(math.Ceil(time.Until(locked.Until).Seconds())), 1)
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		problem := apierrors.TooManyRequests("Too many failed logins, retry in %ds.").WithArgs(retryAfter)
+		c.Error(problem.With("locked_until", locked.Until))
+		return
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.Error(apierrors.Unauthorized("Invalid email or password!"))
+		return
+	case errors.Is(err, services.ErrSecondFactorRequired):
+		problem := apierrors.Unauthorized("An authenticator or recovery code is required!")
+		c.Error(problem.With("second_factor_required", true))
+		return
+	case errors.Is(err, services.ErrInvalidSecondFactor):
+		c.Error(apierrors.Unauthorized("Invalid authenticator or recovery code!"))
+		return
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.Error(apierrors.Forbidden("This account is disabled!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": token})
+}
+
+// POST /auth/refresh
+//
+// @Summary Exchange a refresh token for a new JWT
+// @Description The refresh token is used up: the response has the next one. Using a refresh token twice revokes every refresh token issued since the login it came from.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.RefreshInput true "Refresh token"
+// @Success 200 {object} object{data=services.Token}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/auth/refresh [post]
+func (ctrl *AuthController) Refresh(c *gin.Context) {
+	var input RefreshInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	token, err := ctrl.auth.Refresh(c.Request.Context(), input.RefreshToken)
+	switch {
+	case errors.Is(err, services.ErrInvalidRefreshToken):
+		c.Error(apierrors.Unauthorized("Invalid, expired or revoked refresh token!"))
+		return
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.Error(apierrors.Forbidden("This account is disabled!"))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": token})
+}
+
+// POST /auth/logout
+//
+// @Summary Log out
+// @Description Revokes the JWT the request is made with and the refresh token given, or with everywhere, all the caller's refresh tokens.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body controllers.LogoutInput false "Refresh token to revoke"
+// @Success 200 {object} object{data=bool}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/auth/logout [post]
+func (ctrl *AuthController) Logout(c *gin.Context) {
+	var input LogoutInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.Error(apierrors.Binding(err))
+			return
+		}
+	}
+
+	identity, _ := auth.FromContext(c.Request.Context())
+	if err := ctrl.auth.Logout(c.Request.Context(), identity, input.RefreshToken, input.Everywhere); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// GET /auth/:provider/login
+//
+// @Summary Sign in with an OAuth provider
+// @Description Redirects to the provider (google or github, if configured), which sends the user back to /auth/{provider}/callback.
+// @Tags auth
+// @Param provider path string true "Provider" Enums(google, github)
+// @Success 302
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/auth/{provider}/login [get]
+func (ctrl *AuthController) ProviderLogin(c *gin.Context) {
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		c.Error(err)
+		return
+	}
+	state := base64.RawURLEncoding.EncodeToString(random)
+
+	url, err := ctrl.auth.ProviderLoginURL(c.Param("provider"), state)
+	if errors.Is(err, services.ErrUnknownProvider) {
+		c.Error(apierrors.NotFound("Unknown login provider!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, 600, strings.TrimSuffix(c.Request.URL.Path, "/login"), "", c.Request.TLS != nil, true)
+	c.Redirect(http.StatusFound, url)
+}
+
+// GET /auth/:provider/callback?code=&state=
+//
+// @Summary Complete signing in with an OAuth provider
+// @Description Where the provider sends the user back to. Users are matched by their provider account, then by its verified email address; anyone else gets a new account, without a password.
+// @Tags auth
+// @Produce json
+// @Param provider path string true "Provider" Enums(google, github)
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} object{data=services.Token}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/auth/{provider}/callback [get]
+func (ctrl *AuthController) ProviderCallback(c *gin.Context) {
+	if reason := c.Query("error"); reason != "" {
+		c.Error(apierrors.Unauthorized("Sign-in was not completed: %s").WithArgs(reason))
+		return
+	}
+	state, err := c.Cookie(oauthStateCookie)
+	if err != nil || state == "" || c.Query("state") != state {
+		c.Error(apierrors.BadRequest("The sign-in state is missing or doesn't match, start again from the login URL."))
+		return
+	}
+	c.SetCookie(oauthStateCookie, "", -1, strings.TrimSuffix(c.Request.URL.Path, "/callback"), "", c.Request.TLS != nil, true)
+
+	token, err := ctrl.auth.ProviderLogin(c.Request.Context(), c.Param("provider"), c.Query("code"))
+	switch {
+	case errors.Is(err, services.ErrUnknownProvider):
+		c.Error(apierrors.NotFound("Unknown login provider!"))
+		return
+	case errors.Is(err, oauth.ErrNoVerifiedEmail):
+		c.Error(apierrors.Unauthorized("The provider account has no verified email address!"))
+		return
+	case errors.Is(err, services.ErrProviderLogin):
+		c.Error(apierrors.Unauthorized("Signing in with the provider failed!"))
+		return
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.Error(apierrors.Forbidden("This account is disabled!"))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": token})
+}
+
+// POST /auth/verify
+//
+// @Summary Verify an email address
+// @Description With the token from the link mailed at registration. Each token works once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.VerifyEmailInput true "Token"
+// @Success 200 {object} object{data=models.User}
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/auth/verify [post]
+func (ctrl *AuthController) VerifyEmail(c *gin.Context) {
+	var input VerifyEmailInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	user, err := ctrl.accounts.VerifyEmail(c.Request.Context(), input.Token)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		c.Error(apierrors.BadRequest("Invalid, expired or already used token!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// POST /auth/verify/resend
+//
+// @Summary Mail another email verification link
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/auth/verify/resend [post]
+func (ctrl *AuthController) ResendVerification(c *gin.Context) {
+	err := ctrl.accounts.SendVerification(c.Request.Context(), c.GetUint(middlewares.UserIDKey))
+	if errors.Is(err, services.ErrEmailVerified) {
+		c.Error(apierrors.Conflict("Email address already verified!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusAccepted, gin.H{"data": true})
+}
+
+// POST /auth/forgot
+//
+// @Summary Ask for a password reset link
+// @Description Mails a link to reset the password to the address if it has an account. The response is the same either way.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.ForgotPasswordInput true "Email address"
+// @Success 202 {object} object{data=bool}
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/auth/forgot [post]
+func (ctrl *AuthController) ForgotPassword(c *gin.Context) {
+	var input ForgotPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	if err := ctrl.accounts.ForgotPassword(c.Request.Context(), input.Email); err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusAccepted, gin.H{"data": true})
+}
+
+// POST /auth/reset
+//
+// @Summary Reset a password
+// @Description With the token from the link mailed by /auth/forgot. Each token works once, and using one logs the user out of their other sessions.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param input body controllers.ResetPasswordInput true "Token and new password"
+// @Success 200 {object} object{data=bool}
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/auth/reset [post]
+func (ctrl *AuthController) ResetPassword(c *gin.Context) {
+	var input ResetPasswordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	err := ctrl.accounts.ResetPassword(c.Request.Context(), input.Token, input.Password)
+	if errors.Is(err, services.ErrInvalidUserToken) {
+		c.Error(apierrors.BadRequest("Invalid, expired or already used token!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
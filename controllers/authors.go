@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAuthorInput struct {
+	Name string `json:"name" binding:"required,min=2,max=255"`
+	Bio  string `json:"bio" binding:"max=2000"`
+}
+
+type UpdateAuthorInput struct {
+	Name string `json:"name" binding:"omitempty,min=2,max=255"`
+	Bio  string `json:"bio" binding:"max=2000"`
+}
+
+// AuthorController serves authors through a CRUDController. Its endpoints are
+// those of the CRUDController, declared again only to be documented.
+type AuthorController struct {
+	*CRUDController[models.Author, CreateAuthorInput, UpdateAuthorInput]
+}
+
+func NewAuthorController(authors services.AuthorService) *AuthorController {
+	return &AuthorController{NewCRUDController[models.Author](authors, CRUDResource[models.Author, CreateAuthorInput, UpdateAuthorInput]{
+		New:     func(input CreateAuthorInput) models.Author { return models.Author{Name: input.Name, Bio: input.Bio} },
+		Changes: func(input UpdateAuthorInput) models.Author { return models.Author{Name: input.Name, Bio: input.Bio} },
+		Error: func(err error) error {
+			if errors.Is(err, services.ErrAuthorHasBooks) {
+				return apierrors.Conflict("Author still has books; delete or reassign them first!")
+			}
+			return err
+		},
+	})}
+}
+
+// GET authors?page=&page_size=
+//
+// @Summary List authors
+// @Tags authors
+// @Produce json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Author,meta=controllers.Pagination}
+// @Router /api/v1/authors [get]
+func (ctrl *AuthorController) List(c *gin.Context) { ctrl.CRUDController.List(c) }
+
+// @Summary Get an author
+// @Tags authors
+// @Produce json
+// @Param id path int true "Author ID"
+// @Success 200 {object} object{data=models.Author}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/authors/{id} [get]
+func (ctrl *AuthorController) Get(c *gin.Context) { ctrl.CRUDController.Get(c) }
+
+// @Summary Create an author
+// @Tags authors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateAuthorInput true "Author"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 200 {object} object{data=models.Author}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/authors [post]
+func (ctrl *AuthorController) Create(c *gin.Context) { ctrl.CRUDController.Create(c) }
+
+// @Summary Update an author
+// @Tags authors
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Author ID"
+// @Param input body controllers.UpdateAuthorInput true "Author"
+// @Success 200 {object} object{data=models.Author}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/authors/{id} [put]
+func (ctrl *AuthorController) Update(c *gin.Context) { ctrl.CRUDController.Update(c) }
+
+// @Summary Delete an author
+// @Tags authors
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Author ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/authors/{id} [delete]
+func (ctrl *AuthorController) Delete(c *gin.Context) { ctrl.CRUDController.Delete(c) }
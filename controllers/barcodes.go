@@ -0,0 +1,168 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/barcodes"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/reports"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Pixels per module of the barcode images served.
+const barcodeScale = 4
+
+// maxLabels bounds the labels of a single sheet request, copies included.
+const maxLabels = 1000
+
+type LabelsInput struct {
+	BookIDs []string `json:"book_ids" binding:"required,min=1,max=200,dive,uuid"`
+	// isbn or id; by default the ISBN of books with a valid one, the ID of
+	// the others.
+	Encode string `json:"encode" binding:"omitempty,oneof=isbn id"`
+	// Print a label for every copy the library owns instead of one per book.
+	PerCopy bool `json:"per_copy"`
+	// Labels left blank at the start of the first sheet, to print on a
+	// partly used one.
+	Skip int `json:"skip" binding:"min=0,max=23"`
+}
+
+type BarcodeController struct {
+	books services.BookService
+}
+
+func NewBarcodeController(books services.BookService) *BarcodeController {
+	return &BarcodeController{books: books}
+}
+
+// GET books/:id/barcode?format=&encode=
+//
+// @Summary Get a book's barcode
+// @Description An EAN-13 of the ISBN, or a QR code of the book ID for books without a valid ISBN. The encoded text isn't printed under the code.
+// @Tags books
+// @Produce image/png
+// @Produce image/svg+xml
+// @Param id path string true "Book ID"
+// @Param format query string false "png (default) or svg"
+// @Param encode query string false "isbn or id (default isbn when the book has a valid one)"
+// @Success 200 {file} file
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/barcode [get]
+func (ctrl *BarcodeController) FindBarcode(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	format := c.DefaultQuery("format", barcodes.PNG)
+	if format != barcodes.PNG && format != barcodes.SVG {
+		c.Error(apierrors.Validation("format must be png or svg"))
+		return
+	}
+	encode := c.Query("encode")
+	if encode != "" && encode != barcodes.EncodeISBN && encode != barcodes.EncodeID {
+		c.Error(apierrors.Validation("encode must be isbn or id"))
+		return
+	}
+
+	book, err := ctrl.books.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	code, err := barcodes.For(book, encode)
+	if errors.Is(err, barcodes.ErrNoISBN) {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	var buf bytes.Buffer
+	contentType := "image/png"
+	if format == barcodes.SVG {
+		contentType = "image/svg+xml"
+		err = code.WriteSVG(&buf, barcodeScale)
+	} else {
+		err = code.WritePNG(&buf, barcodeScale)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	// The ISBN may be corrected, so keep caching short.
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Data(http.StatusOK, contentType, buf.Bytes())
+}
+
+// POST books/labels
+//
+// @Summary Print barcode labels
+// @Description A PDF of A4 sheets of 24 labels (3 by 8, 63.5 by 33.9 mm), each with the title, author and barcode of a book, in the order given.
+// @Tags books
+// @Accept json
+// @Produce application/pdf
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.LabelsInput true "Books (at most 200)"
+// @Success 200 {file} file
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/books/labels [post]
+func (ctrl *BarcodeController) PrintLabels(c *gin.Context) {
+	var input LabelsInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	sheet := reports.LabelSheet{GeneratedAt: time.Now(), Skip: input.Skip}
+	for _, raw := range input.BookIDs {
+		book, err := ctrl.books.Get(c.Request.Context(), uuid.MustParse(raw), "Author")
+		if errors.Is(err, repositories.ErrNotFound) {
+			c.Error(apierrors.Validation("Book %s does not exist!").WithArgs(raw))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		code, err := barcodes.For(book, input.Encode)
+		if errors.Is(err, barcodes.ErrNoISBN) {
+			c.Error(apierrors.Validation("Book %s has no valid ISBN to encode!").WithArgs(raw))
+			return
+		}
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		copies := 1
+		if input.PerCopy {
+			copies = book.Quantity
+		}
+		if len(sheet.Labels)+copies > maxLabels {
+			c.Error(apierrors.Validation("Labels are printed at most %d at a time.").WithArgs(maxLabels))
+			return
+		}
+		for i := 0; i < copies; i++ {
+			sheet.Labels = append(sheet.Labels, reports.Label{Book: book, Code: code})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := reports.WriteLabels(&buf, sheet); err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="labels.pdf"`)
+	c.Data(http.StatusOK, reports.ContentType, buf.Bytes())
+}
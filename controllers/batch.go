@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxBatchOperations is how many operations a batch may hold.
+const MaxBatchOperations = 20
+
+type BatchOperation struct {
+	Method string `json:"method" binding:"required,oneof=GET POST PUT PATCH DELETE"`
+	// The path of the endpoint, with its query string, e.g. /api/v1/books?page=2.
+	Path string `json:"path" binding:"required,startswith=/api/"`
+	// Sent along with the headers of the batch request, overriding them.
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body" swaggertype:"object"`
+}
+
+type BatchInput struct {
+	// Atomic runs the operations in one transaction: the first one failing
+	// undoes those before it and the rest are skipped.
+	Atomic     bool             `json:"atomic"`
+	Operations []BatchOperation `json:"operations" binding:"required,min=1,max=20,dive"`
+}
+
+type BatchResult struct {
+	Status int `json:"status"`
+	// The Content-Type, ETag and Location headers of the response, if set.
+	Headers map[string]string `json:"headers,omitempty"`
+	// JSON bodies as they are, others as a string.
+	Body json.RawMessage `json:"body,omitempty" swaggertype:"object"`
+}
+
+type BatchMeta struct {
+	// Whether an atomic batch was undone because an operation failed.
+	RolledBack bool `json:"rolled_back"`
+}
+
+// Endpoints that can't be reached through a batch: streams never finish,
+// and batches don't nest.
+var unbatchable = map[string]bool{
+	"/api/v1/batch":        true,
+	"/api/v1/books/events": true,
+}
+
+// Headers of the batch request not passed on to its operations, since they
+// describe the batch itself. A signature only covers the batch request; its
+// operations are made with the API key it was verified with.
+var batchOnlyHeaders = []string{
+	"Content-Length", "Content-Type", "Content-Encoding", "Accept-Encoding", "Idempotency-Key", "If-Match", "If-None-Match", "X-Request-Id",
+	auth.SignatureKeyHeader, auth.SignatureTimestampHeader, auth.SignatureNonceHeader, auth.SignatureHeader, auth.ContentDigestHeader,
+}
+
+var errBatchFailed = errors.New("batch operation failed")
+
+type BatchController struct {
+	handler  http.Handler
+	transact func(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// NewBatchController runs operations through handler, the router they
+// would otherwise reach directly, and atomic batches in a transaction from
+// transact.
+func NewBatchController(handler http.Handler, transact func(ctx context.Context, fn func(ctx context.Context) error) error) *BatchController {
+	return &BatchController{handler: handler, transact: transact}
+}
+
+// POST batch
+//
+// @Summary Run several operations in one request
+// @Description Runs up to 20 requests to other endpoints in order, each authenticated by the headers of the batch request unless it overrides them, or by the API key of a signed batch request, and returns their responses. Operations run one after the other whatever their outcome, unless atomic is set: then they share a transaction, and the first one failing with a 4xx or 5xx status undoes those before it and skips the rest. Event streams can't be batched.
+// @Tags batch
+// @Accept json
+// @Produce json
+// @Param input body controllers.BatchInput true "Operations"
+// @Success 200 {object} object{data=[]controllers.BatchResult,meta=controllers.BatchMeta}
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/batch [post]
+func (ctrl *BatchController) RunBatch(c *gin.Context) {
+	var input BatchInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+	for i, op := range input.Operations {
+		target, err := url.Parse(op.Path)
+		if err != nil || unbatchable[path.Clean(target.Path)] {
+			c.Error(apierrors.Validation("Operation %d can't be batched: %s").WithArgs(i, op.Path))
+			return
+		}
+	}
+
+	results := make([]BatchResult, 0, len(input.Operations))
+	if !input.Atomic {
+		for _, op := range input.Operations {
+			results = append(results, ctrl.run(c, c.Request.Context(), op))
+		}
+		render.Respond(c, http.StatusOK, gin.H{"data": results, "meta": BatchMeta{}})
+		return
+	}
+
+	err := ctrl.transact(c.Request.Context(), func(ctx context.Context) error {
+		for _, op := range input.Operations {
+			result := ctrl.run(c, ctx, op)
+			results = append(results, result)
+			if result.Status >= http.StatusBadRequest {
+				return errBatchFailed
+			}
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBatchFailed) {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": results, "meta": BatchMeta{RolledBack: err != nil}})
+}
+
+// run sends op through the router as a request of its own, made with ctx.
+func (ctrl *BatchController) run(c *gin.Context, ctx context.Context, op BatchOperation) BatchResult {
+	body := bytes.NewReader(nil)
+	if len(op.Body) > 0 && !bytes.Equal(op.Body, []byte("null")) {
+		body = bytes.NewReader(op.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, op.Method, op.Path, body)
+	if err != nil {
+		return problemResult(apierrors.Validation("Invalid operation: %s").WithArgs(err.Error()))
+	}
+	req.RemoteAddr = c.Request.RemoteAddr
+	req.Header = c.Request.Header.Clone()
+	for _, header := range batchOnlyHeaders {
+		req.Header.Del(header)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body.Len() > 0 {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range op.Headers {
+		req.Header.Set(name, value)
+	}
+
+	w := &batchRecorder{header: http.Header{}, status: http.StatusOK}
+	ctrl.handler.ServeHTTP(w, req)
+
+	result := BatchResult{Status: w.status}
+	for _, name := range []string{"Content-Type", "ETag", "Location"} {
+		if value := w.header.Get(name); value != "" {
+			if result.Headers == nil {
+				result.Headers = map[string]string{}
+			}
+			result.Headers[name] = value
+		}
+	}
+	if w.body.Len() > 0 {
+		mediaType, _, _ := mime.ParseMediaType(w.header.Get("Content-Type"))
+		if (mediaType == "application/json" || mediaType == "application/problem+json") && json.Valid(w.body.Bytes()) {
+			result.Body = w.body.Bytes()
+		} else {
+			result.Body, _ = json.Marshal(w.body.String())
+		}
+	}
+	return result
+}
+
+func problemResult(problem *apierrors.Problem) BatchResult {
+	body, _ := json.Marshal(problem)
+	return BatchResult{Status: problem.Status, Headers: map[string]string{"Content-Type": "application/problem+json"}, Body: body}
+}
+
+// batchRecorder keeps the response to an operation.
+type batchRecorder struct {
+	header      http.Header
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (w *batchRecorder) Header() http.Header { return w.header }
+
+func (w *batchRecorder) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status, w.wroteHeader = status, true
+	}
+}
+
+func (w *batchRecorder) Write(data []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.body.Write(data)
+}
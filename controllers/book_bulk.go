@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+)
+
+// Upper bound on the number of items in a single bulk request.
+const maxBulkItems = 100
+
+type BulkCreateResult struct {
+	// Position of the item in the request array.
+	Index  int                `json:"index"`
+	Status int                `json:"status"`
+	Data   *models.Book       `json:"data,omitempty"`
+	Error  *apierrors.Problem `json:"error,omitempty"`
+}
+
+type BulkDeleteInput struct {
+	IDs []string `json:"ids" binding:"required,min=1,max=100,dive,uuid"`
+}
+
+type BulkDeleteResult struct {
+	ID     uuid.UUID          `json:"id" swaggertype:"string" format:"uuid"`
+	Status int                `json:"status"`
+	Error  *apierrors.Problem `json:"error,omitempty"`
+}
+
+// POST books/bulk
+// Every item is validated on its own; the valid ones are inserted in a
+// single transaction and the invalid ones reported. The response is 200
+// when every item was created, 207 otherwise.
+//
+// @Summary Create several books
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body []controllers.CreateBookInput true "Books (at most 100)"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 200 {object} object{data=[]controllers.BulkCreateResult}
+// @Success 207 {object} object{data=[]controllers.BulkCreateResult}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/bulk [post]
+func (ctrl *BookController) CreateBooks(c *gin.Context) {
+	var items []json.RawMessage
+	if err := c.ShouldBindJSON(&items); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+	if len(items) == 0 || len(items) > maxBulkItems {
+		c.Error(apierrors.Validation("Request body must be an array of 1 to 100 books."))
+		return
+	}
+
+	results := make([]BulkCreateResult, len(items))
+	books := make([]*models.Book, 0, len(items))
+	positions := make([]int, 0, len(items))
+	for i, item := range items {
+		results[i].Index = i
+
+		var input CreateBookInput
+		err := json.Unmarshal(item, &input)
+		if err == nil {
+			err = binding.Validator.ValidateStruct(&input)
+		}
+		if err != nil {
+			results[i].Status, results[i].Error = http.StatusBadRequest, apierrors.Binding(err)
+			continue
+		}
+
+		books = append(books, &models.Book{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, PublisherID: input.PublisherID, Year: input.Year, ISBN: input.ISBN, Quantity: input.Quantity, Price: input.Price, Currency: input.Currency})
+		positions = append(positions, i)
+	}
+
+	rejected, err := ctrl.books.CreateMany(c.Request.Context(), books)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for j, book := range books {
+		result := &results[positions[j]]
+		if rejected[j] != nil {
+			result.Status, result.Error = http.StatusBadRequest, apierrors.From(bookError(rejected[j]))
+			continue
+		}
+		result.Status, result.Data = http.StatusOK, book
+	}
+
+	render.Respond(c, bulkStatus(len(positions) == len(items) && !anyError(rejected)), gin.H{"data": results})
+}
+
+// DELETE books/bulk
+//
+// @Summary Soft-delete several books
+// @Description Deletes in a single transaction. IDs that don't match a book are reported with status 404; the response is 207 when any ID failed.
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.BulkDeleteInput true "Book IDs (at most 100)"
+// @Success 200 {object} object{data=[]controllers.BulkDeleteResult}
+// @Success 207 {object} object{data=[]controllers.BulkDeleteResult}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/books/bulk [delete]
+func (ctrl *BookController) DeleteBooks(c *gin.Context) {
+	var input BulkDeleteInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(input.IDs))
+	seen := make(map[uuid.UUID]bool, len(input.IDs))
+	for _, raw := range input.IDs {
+		// Already validated by the uuid binding rule.
+		id := uuid.MustParse(raw)
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	deleted, err := ctrl.books.DeleteMany(c.Request.Context(), ids)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	results := make([]BulkDeleteResult, len(ids))
+	allDeleted := true
+	for i, id := range ids {
+		results[i] = BulkDeleteResult{ID: id, Status: http.StatusOK}
+		if !deleted[id] {
+			allDeleted = false
+			results[i].Status, results[i].Error = http.StatusNotFound, apierrors.NotFound("Record not found!")
+		}
+	}
+
+	render.Respond(c, bulkStatus(allDeleted), gin.H{"data": results})
+}
+
+func bulkStatus(allSucceeded bool) int {
+	if allSucceeded {
+		return http.StatusOK
+	}
+	return http.StatusMultiStatus
+}
+
+func anyError(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
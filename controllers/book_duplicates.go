@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type MergeBookInput struct {
+	// The book merged into the one in the path, then deleted.
+	DuplicateID string `json:"duplicate_id" binding:"required,uuid" format:"uuid"`
+}
+
+// GET books/duplicates
+//
+// @Summary Report likely duplicate books
+// @Description Groups the books that likely duplicate one another, by ISBN, or by author and title, oldest first. Books that duplicate nothing are left out.
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} object{data=[]services.DuplicateGroup}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/books/duplicates [get]
+func (ctrl *BookController) FindDuplicateBooks(c *gin.Context) {
+	groups, err := ctrl.books.DuplicateReport(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": groups})
+}
+
+// POST books/:id/merge
+//
+// @Summary Merge a duplicate into a book
+// @Description Moves the loans, reviews, categories, tags, series volumes, favorites, reading list entries and stock transfers of the duplicate to the book, adds its copies to the book's, branch by branch, fills in the details the book lacks, then deletes the duplicate permanently, in one transaction. Refused while members are in line for the duplicate.
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.MergeBookInput true "Duplicate to merge"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/merge [post]
+func (ctrl *BookController) MergeBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input MergeBookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	book, err := ctrl.books.Merge(c.Request.Context(), id, uuid.MustParse(input.DuplicateID))
+	if err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/gin-gonic/gin"
+)
+
+// How many events a stream may fall behind by before it is dropped.
+const eventStreamBuffer = 64
+
+// EventSource is what event streams read from; *events.Broadcaster
+// implements it.
+type EventSource interface {
+	Subscribe(buffer int) (<-chan events.Event, func())
+}
+
+type BookEventController struct {
+	source    EventSource
+	heartbeat time.Duration
+}
+
+func NewBookEventController(source EventSource, heartbeat time.Duration) *BookEventController {
+	return &BookEventController{source: source, heartbeat: heartbeat}
+}
+
+// GET books/events?category_id=&types=
+//
+// @Summary Stream catalog changes
+// @Description Server-Sent Events: one event per book created, updated or deleted, named after its type, with the event's ID and its JSON as data. Comment lines are sent every heartbeat interval to keep idle connections open.
+// @Description Only changes made after connecting are sent. A client that falls too far behind is disconnected; EventSource reconnects by itself.
+// @Tags books
+// @Produce text/event-stream
+// @Param category_id query int false "Only books in this category"
+// @Param types query string false "Comma-separated event types, e.g. book.created,book.deleted (all by default)"
+// @Success 200 {object} events.Event
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/books/events [get]
+func (ctrl *BookEventController) StreamBookEvents(c *gin.Context) {
+	match, err := eventFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+
+	stream, cancel := ctrl.source.Subscribe(eventStreamBuffer)
+	defer cancel()
+	heartbeat := time.NewTicker(ctrl.heartbeat)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	// Keeps nginx from buffering the stream.
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keepalive\n\n")
+			return true
+		case event, ok := <-stream:
+			if !ok {
+				return false
+			}
+			if !match(event) {
+				return true
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				c.Error(err)
+				return false
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			return true
+		}
+	})
+}
+
+// Reads the ?category_id= and ?types= filters of an event stream.
+func eventFilterFromQuery(c *gin.Context) (func(events.Event) bool, error) {
+	var categoryID uint
+	if raw := c.Query("category_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid category_id: %q", raw)
+		}
+		categoryID = uint(id)
+	}
+
+	var types []string
+	if raw := c.Query("types"); raw != "" {
+		for _, eventType := range strings.Split(raw, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !slices.Contains(events.Types, eventType) {
+				return nil, fmt.Errorf("invalid event type: %q", eventType)
+			}
+			types = append(types, eventType)
+		}
+	}
+
+	return func(event events.Event) bool {
+		if types != nil && !slices.Contains(types, event.Type) {
+			return false
+		}
+		return categoryID == 0 || event.InCategory(categoryID)
+	}, nil
+}
@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/search"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type BookSearchResult struct {
+	Book models.Book `json:"book"`
+	Rank float64     `json:"rank"`
+	// Title, description and author name with matched terms wrapped in
+	// <mark></mark>, HTML-escaped otherwise.
+	Highlights map[string]string `json:"highlights"`
+}
+
+type SearchPagination struct {
+	Pagination
+	// How many of the matching books have each author, publisher,
+	// category, tag and decade; only counted by the search index.
+	Facets *search.Facets `json:"facets,omitempty"`
+}
+
+// GET books/search?q=&page=&page_size=&author=&author_id=&publisher_id=&category_id=&tag=&title_contains=&year_gte=&year_lte=
+//
+// @Summary Search books
+// @Description Full-text search over title, author name and description, best matches first. With a search index configured, matching tolerates typos, the results can be filtered as book lists are, and meta carries facets; without one, filters are rejected.
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param q query string true "Search terms"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Phrase in the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Success 200 {object} object{data=[]controllers.BookSearchResult,meta=controllers.SearchPagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/books/search [get]
+func (ctrl *BookController) SearchBooks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.Error(apierrors.Validation("q is required"))
+		return
+	}
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	found, err := ctrl.search.Search(c.Request.Context(), search.Query{
+		Text:   query,
+		Filter: filter,
+		Offset: pagination.Offset(),
+		Limit:  pagination.PageSize,
+	})
+	if errors.Is(err, services.ErrSearchFilters) {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if errors.Is(err, services.ErrSearchUnavailable) {
+		slog.WarnContext(c.Request.Context(), "book search failed", "error", err)
+		c.Error(apierrors.New(http.StatusBadGateway, "The search index could not be queried; try again later."))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(found.Total)
+
+	results := make([]BookSearchResult, len(found.Hits))
+	for i, hit := range found.Hits {
+		results[i] = BookSearchResult{Book: hit.Book, Rank: hit.Rank, Highlights: hit.Highlights}
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": results, "meta": SearchPagination{Pagination: pagination, Facets: found.Facets}})
+}
@@ -0,0 +1,405 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tabular"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/shopspring/decimal"
+)
+
+// MaxImportSize is the largest upload an import accepts; the route must
+// allow bodies that large.
+const MaxImportSize = 10 << 20
+
+const maxImportRows = 10000
+
+// BookExportColumns heads the columns of a catalog export, whose rows
+// BookExportRow makes; the export command writes the same file.
+var BookExportColumns = []string{"id", "slug", "title", "description", "author_id", "author", "year", "isbn", "price", "currency", "created_at", "updated_at"}
+
+// Columns an import understands; by default they are matched to header
+// cells by name, case-insensitively.
+var importColumns = []string{"title", "description", "author_id", "year", "isbn", "price", "currency"}
+
+type ImportReport struct {
+	DryRun bool `json:"dry_run"`
+	// Rows that passed validation (and were inserted unless dry_run).
+	Valid    int `json:"valid"`
+	Imported int `json:"imported"`
+}
+
+type ImportRowError struct {
+	// Spreadsheet row number, the header being row 1.
+	Row    int                    `json:"row"`
+	Errors []apierrors.FieldError `json:"errors"`
+}
+
+// ImportProblem documents the problem invalid imports are answered with.
+type ImportProblem struct {
+	apierrors.Problem
+	Rows []ImportRowError `json:"rows"`
+}
+
+// GET books/export?format=csv|xlsx accepts the same filters as GET books.
+//
+// @Summary Export the catalog
+// @Tags books
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param format query string false "csv (default) or xlsx"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Success 200 {file} file
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/books/export [get]
+func (ctrl *BookController) ExportBooks(c *gin.Context) {
+	format := c.DefaultQuery("format", tabular.CSV)
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if format != tabular.CSV && format != tabular.XLSX {
+		c.Error(apierrors.Validation(tabular.ErrUnknownFormat.Error()))
+		return
+	}
+
+	c.Header("Content-Type", tabular.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="books.`+format+`"`)
+
+	if _, err := WriteBookExport(ctrl.books)(c.Request.Context(), format, filter, c.Writer); err != nil {
+		// Once rows have been streamed the status can't change any more;
+		// the error is still logged.
+		c.Writer.Header().Del("Content-Disposition")
+		c.Error(err)
+	}
+}
+
+// WriteBookExport writes the file GET books/export serves, for the export
+// jobs and the export command to build it too.
+func WriteBookExport(books services.BookService) services.ExportWriter {
+	return func(ctx context.Context, format string, filter repositories.BookFilter, out io.Writer) (int, error) {
+		w, err := tabular.NewWriter(format, out)
+		if err != nil {
+			return 0, err
+		}
+		if err := w.Write(BookExportColumns); err != nil {
+			return 0, err
+		}
+
+		rows := 0
+		err = books.Export(ctx, filter, func(page []models.Book) error {
+			for _, book := range page {
+				if err := w.Write(BookExportRow(book)); err != nil {
+					return err
+				}
+			}
+			rows += len(page)
+			return nil
+		})
+		if err != nil {
+			return rows, err
+		}
+		return rows, w.Close()
+	}
+}
+
+func BookExportRow(book models.Book) []string {
+	author := ""
+	if book.Author != nil {
+		author = book.Author.Name
+	}
+	year := ""
+	if book.Year != 0 {
+		year = strconv.Itoa(book.Year)
+	}
+	price := ""
+	if book.Price != nil {
+		price = book.Price.StringFixed(money.MinorUnits(book.Currency))
+	}
+	return []string{
+		book.ID.String(),
+		book.Slug,
+		book.Title,
+		book.Description,
+		strconv.FormatUint(uint64(book.AuthorID), 10),
+		author,
+		year,
+		book.ISBN,
+		price,
+		book.Currency,
+		book.CreatedAt.Format(time.RFC3339),
+		book.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// POST books/import
+// The first row must be a header. Every row is validated and the books are
+// inserted in a single transaction only if all rows are valid.
+//
+// @Summary Import books from CSV or XLSX
+// @Tags books
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param file formData file true "CSV or XLSX file (max 10 MB, 10000 rows)"
+// @Param format formData string false "csv or xlsx; defaults to the file extension"
+// @Param mapping formData string false "JSON object mapping columns (title, description, author_id, year, isbn, price, currency) to header names, e.g. {\"title\":\"Book Title\"}"
+// @Param dry_run formData bool false "Validate without importing"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 200 {object} object{data=controllers.ImportReport}
+// @Failure 400 {object} controllers.ImportProblem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/import [post]
+func (ctrl *BookController) ImportBooks(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.Error(apierrors.PayloadTooLarge("The upload exceeds 10 MB."))
+			return
+		}
+		c.Error(apierrors.Validation("file is required"))
+		return
+	}
+
+	format := c.PostForm("format")
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(header.Filename)), ".")
+	}
+
+	mapping := map[string]string{}
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.Error(apierrors.Validation("mapping must be a JSON object of column to header name"))
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+
+	rows, err := tabular.ReadAll(format, file)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if len(rows) == 0 {
+		c.Error(apierrors.Validation("file is empty"))
+		return
+	}
+	if len(rows)-1 > maxImportRows {
+		c.Error(apierrors.Validation("file has more than 10000 rows"))
+		return
+	}
+
+	columns, err := importColumnIndexes(rows[0], mapping)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+
+	var books []*models.Book
+	var lines []int
+	var rowErrors []ImportRowError
+	for i, row := range rows[1:] {
+		line := i + 2
+		if blank(row) {
+			continue
+		}
+		book, fieldErrors := parseImportRow(row, columns)
+		if len(fieldErrors) > 0 {
+			rowErrors = append(rowErrors, ImportRowError{Row: line, Errors: fieldErrors})
+			continue
+		}
+		books = append(books, book)
+		lines = append(lines, line)
+	}
+
+	dryRun := c.PostForm("dry_run") == "true"
+	// Invalid rows mean nothing is imported, but authors are still checked
+	// so the report covers every problem at once.
+	rejected, err := ctrl.books.Import(c.Request.Context(), books, dryRun || len(rowErrors) > 0)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	for i, reason := range rejected {
+		if reason != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: lines[i], Errors: []apierrors.FieldError{importRejection(reason)}})
+		}
+	}
+
+	if len(rowErrors) > 0 {
+		sortRowErrors(rowErrors)
+		c.Error(apierrors.Validation("%d row(s) are invalid; nothing was imported.").WithArgs(len(rowErrors)).With("rows", rowErrors))
+		return
+	}
+
+	report := ImportReport{DryRun: dryRun, Valid: len(books)}
+	if !dryRun {
+		report.Imported = len(books)
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": report})
+}
+
+// importColumnIndexes finds, for each import column, the index of the
+// header cell it is read from.
+func importColumnIndexes(header []string, mapping map[string]string) (map[string]int, error) {
+	for column := range mapping {
+		if !contains(importColumns, column) {
+			return nil, errors.New("mapping: unknown column " + strconv.Quote(column) + ", expected one of " + strings.Join(importColumns, ", "))
+		}
+	}
+
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	indexes := map[string]int{}
+	for _, column := range importColumns {
+		name := column
+		if mapped, ok := mapping[column]; ok {
+			name = mapped
+		}
+		if i, ok := positions[strings.ToLower(strings.TrimSpace(name))]; ok {
+			indexes[column] = i
+		} else if _, ok := mapping[column]; ok {
+			return nil, errors.New("mapping: header " + strconv.Quote(name) + " not found")
+		}
+	}
+	for _, required := range []string{"title", "author_id"} {
+		if _, ok := indexes[required]; !ok {
+			return nil, errors.New("no " + required + " column in the header")
+		}
+	}
+	return indexes, nil
+}
+
+// parseImportRow converts a row into a book and validates it with the same
+// rules as POST books.
+func parseImportRow(row []string, columns map[string]int) (*models.Book, []apierrors.FieldError) {
+	cell := func(column string) string {
+		i, ok := columns[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+
+	input := CreateBookInput{Title: cell("title"), Description: cell("description"), ISBN: cell("isbn"), Currency: cell("currency")}
+	var fieldErrors []apierrors.FieldError
+	if raw := cell("author_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			fieldErrors = append(fieldErrors, apierrors.FieldError{Field: "author_id", Message: "must be an integer"})
+		}
+		input.AuthorID = uint(id)
+	}
+	if raw := cell("year"); raw != "" {
+		year, err := strconv.Atoi(raw)
+		if err != nil {
+			fieldErrors = append(fieldErrors, apierrors.FieldError{Field: "year", Message: "must be an integer"})
+		}
+		input.Year = year
+	}
+	if raw := cell("price"); raw != "" {
+		price, err := decimal.NewFromString(raw)
+		if err != nil {
+			fieldErrors = append(fieldErrors, apierrors.FieldError{Field: "price", Message: "must be a number"})
+		}
+		input.Price = &price
+	}
+
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		var validationErrs validator.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			return nil, append(fieldErrors, apierrors.FieldError{Message: err.Error()})
+		}
+		for _, fe := range apierrors.FieldErrors(validationErrs) {
+			if !hasField(fieldErrors, fe.Field) {
+				fieldErrors = append(fieldErrors, fe)
+			}
+		}
+	}
+	if len(fieldErrors) > 0 {
+		return nil, fieldErrors
+	}
+
+	return &models.Book{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, Year: input.Year, ISBN: input.ISBN, Price: input.Price, Currency: input.Currency}, nil
+}
+
+// importRejection describes why the service rejected a row's book.
+func importRejection(reason error) apierrors.FieldError {
+	switch {
+	case errors.Is(reason, services.ErrPriceCurrency):
+		return apierrors.FieldError{Field: "currency", Message: "required"}
+	case errors.Is(reason, services.ErrInvalidPrice):
+		return apierrors.FieldError{Field: "price", Message: "must not be negative nor have more decimals than its currency allows"}
+	}
+	return apierrors.FieldError{Field: "author_id", Message: "does not reference an existing author"}
+}
+
+func sortRowErrors(rows []ImportRowError) {
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Row < rows[j].Row })
+}
+
+func blank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func hasField(errs []apierrors.FieldError, field string) bool {
+	for _, fe := range errs {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type BookVersionController struct {
+	versions services.BookVersionService
+}
+
+func NewBookVersionController(versions services.BookVersionService) *BookVersionController {
+	return &BookVersionController{versions: versions}
+}
+
+// GET books/:id/versions?page=&page_size=
+//
+// @Summary List the versions of a book
+// @Description Snapshots of the book's edited fields as each version left them, newest first, with who wrote them when known.
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.BookVersion,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/versions [get]
+func (ctrl *BookVersionController) FindBookVersions(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	versions, total, err := ctrl.versions.List(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": versions, "meta": pagination})
+}
+
+// GET books/:id/versions/:v/diff?against=
+//
+// @Summary Compare a version of a book with another
+// @Description The fields that differ between version v and the version given by against, or the book as it is now.
+// @Tags books
+// @Produce json,application/xml
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param v path int true "Version"
+// @Param against query int false "Version to compare with (default the current one)"
+// @Success 200 {object} object{data=services.BookDiff}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/versions/{v}/diff [get]
+func (ctrl *BookVersionController) FindBookVersionDiff(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	version, ok := pathID(c, "v")
+	if !ok {
+		return
+	}
+	var against uint
+	if raw := c.Query("against"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil || parsed == 0 {
+			c.Error(apierrors.Validation("against must be a version number."))
+			return
+		}
+		against = uint(parsed)
+	}
+
+	diff, err := ctrl.versions.Diff(c.Request.Context(), id, version, against)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": diff})
+}
+
+// POST books/:id/revert/:v
+//
+// @Summary Revert a book to one of its versions
+// @Description Writes the title, description, author, publisher, year and ISBN of version v back to the book, as a new version. The quantity is left alone: copies are only added and removed through stock adjustments.
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param v path int true "Version"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/revert/{v} [post]
+func (ctrl *BookVersionController) RevertBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	version, ok := pathID(c, "v")
+	if !ok {
+		return
+	}
+
+	book, err := ctrl.versions.Revert(c.Request.Context(), id, version)
+	if err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
@@ -1,77 +1,747 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
+	"slices"
+	"strconv"
 
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
 	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
 )
 
 type CreateBookInput struct {
-	Title  string `json:"title" binding:"required"`
-	Author string `json:"author" binding:"required"`
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=10000"`
+	AuthorID    uint   `json:"author_id" binding:"required"`
+	PublisherID *uint  `json:"publisher_id" binding:"omitempty,min=1"`
+	Year        int    `json:"year" binding:"omitempty,publication_year"`
+	ISBN        string `json:"isbn" binding:"omitempty,isbn"`
+	// Copies owned; defaults to 1.
+	Quantity int `json:"quantity" binding:"omitempty,min=1,max=10000"`
+	// A string or a number, in currency, which a price requires.
+	Price    *decimal.Decimal `json:"price" swaggertype:"string" example:"19.90"`
+	Currency string           `json:"currency" binding:"omitempty,currency" example:"EUR"`
 }
 
 type UpdateBookInput struct {
-	Title  string `json:"title"`
-	Author string `json:"author"`
+	// Version of the book the change is based on; stale versions get a 409.
+	Version     uint             `json:"version"`
+	Title       string           `json:"title" binding:"max=255"`
+	Description string           `json:"description" binding:"max=10000"`
+	AuthorID    uint             `json:"author_id"`
+	PublisherID *uint            `json:"publisher_id" binding:"omitempty,min=1"`
+	Year        int              `json:"year" binding:"omitempty,publication_year"`
+	ISBN        string           `json:"isbn" binding:"omitempty,isbn"`
+	Price       *decimal.Decimal `json:"price" swaggertype:"string" example:"19.90"`
+	Currency    string           `json:"currency" binding:"omitempty,currency" example:"EUR"`
 }
 
-// GET books
-func FindBooks(c *gin.Context) {
-	var books []models.Book
-	models.DB.Find(&books)
+// PatchBookInput uses pointers so an omitted field can be told apart from
+// one explicitly set to its zero value.
+type PatchBookInput struct {
+	Version     uint             `json:"version"`
+	Title       *string          `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string          `json:"description" binding:"omitempty,max=10000"`
+	AuthorID    *uint            `json:"author_id" binding:"omitempty,min=1"`
+	PublisherID *uint            `json:"publisher_id" binding:"omitempty,min=1"`
+	Year        *int             `json:"year" binding:"omitempty,publication_year"`
+	ISBN        *string          `json:"isbn" binding:"omitempty,isbn"`
+	Price       *decimal.Decimal `json:"price" swaggertype:"string" example:"19.90"`
+	Currency    *string          `json:"currency" binding:"omitempty,currency" example:"EUR"`
+}
+
+type BookController struct {
+	books      services.BookService
+	currencies services.CurrencyService
+	search     services.SearchService
+}
 
-	c.JSON(http.StatusOK, gin.H{"data": books})
+func NewBookController(books services.BookService, currencies services.CurrencyService, search services.SearchService) *BookController {
+	return &BookController{books: books, currencies: currencies, search: search}
 }
 
-func FindBook(c *gin.Context) {
-	var book models.Book
+// GET books?page=&page_size=&author=&author_id=&publisher_id=&category_id=&tag=&title_contains=&year_gte=&year_lte=&sort=&preload=&include_deleted=&cursor=&fields=&convert_to=
+//
+// @Summary List books
+// @Description With cursor, pages follow each other by position rather than offset, so books added meanwhile don't shift them; page is ignored and meta is a controllers.CursorPagination, without totals.
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (id, title, author_id, year, created_at, updated_at)"
+// @Param preload query string false "Associations to embed (author, publisher, categories, tags)"
+// @Param include_deleted query bool false "Include soft-deleted books (admins only)"
+// @Param cursor query string false "Page by cursor instead: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. title,author (all by default); author, publisher, categories and tags embed the association"
+// @Param convert_to query string false "ISO 4217 currency to convert prices to, returned as converted_price"
+// @Success 200 {object} object{data=[]models.Book,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/books [get]
+func (ctrl *BookController) FindBooks(c *gin.Context) {
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+
+	ctrl.listBooks(c, filter)
+}
+
+// GET categories/:id/books accepts the same query parameters as GET books.
+//
+// @Summary List the books in a category
+// @Tags categories
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path int true "Category ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (id, title, author_id, year, created_at, updated_at)"
+// @Param preload query string false "Associations to embed (author, publisher, categories, tags)"
+// @Param cursor query string false "Page by cursor instead: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. title,author (all by default); author, publisher, categories and tags embed the association"
+// @Param convert_to query string false "ISO 4217 currency to convert prices to, returned as converted_price"
+// @Success 200 {object} object{data=[]models.Book,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/categories/{id}/books [get]
+func (ctrl *BookController) FindCategoryBooks(c *gin.Context) {
+	categoryID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	filter.CategoryID = categoryID
+
+	ctrl.listBooks(c, filter)
+}
+
+// GET publishers/:id/books accepts the same query parameters as GET books.
+//
+// @Summary List the books of a publisher
+// @Tags publishers
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path int true "Publisher ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (id, title, author_id, year, created_at, updated_at)"
+// @Param preload query string false "Associations to embed (author, publisher, categories, tags)"
+// @Param cursor query string false "Page by cursor instead: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. title,author (all by default); author, publisher, categories and tags embed the association"
+// @Param convert_to query string false "ISO 4217 currency to convert prices to, returned as converted_price"
+// @Success 200 {object} object{data=[]models.Book,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/publishers/{id}/books [get]
+func (ctrl *BookController) FindPublisherBooks(c *gin.Context) {
+	publisherID, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	filter.PublisherID = publisherID
+
+	ctrl.listBooks(c, filter)
+}
 
-	if err := models.DB.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Record not found!"})
+// GET tags/:name/books accepts the same query parameters as GET books.
+//
+// @Summary List the books with a tag
+// @Tags tags
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param name path string true "Tag name"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Param sort query string false "Comma separated sort fields, prefix with - for descending (id, title, author_id, year, created_at, updated_at)"
+// @Param preload query string false "Associations to embed (author, publisher, categories, tags)"
+// @Param cursor query string false "Page by cursor instead: empty for the first page, then the previous page's next_cursor"
+// @Param fields query string false "Comma-separated fields to return, e.g. title,author (all by default); author, publisher, categories and tags embed the association"
+// @Param convert_to query string false "ISO 4217 currency to convert prices to, returned as converted_price"
+// @Success 200 {object} object{data=[]models.Book,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/tags/{name}/books [get]
+func (ctrl *BookController) FindTagBooks(c *gin.Context) {
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
 		return
 	}
+	filter.Tag = services.NormalizeTag(c.Param("name"))
 
-	c.JSON(http.StatusOK, gin.H{"data": &book})
+	ctrl.listBooks(c, filter)
 }
 
-func CreateBook(c *gin.Context) {
+func (ctrl *BookController) listBooks(c *gin.Context, filter repositories.BookFilter) {
+	pagination := paginationFromQuery(c)
+
+	sort, err := bookSortFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+
+	preloads, err := preloadsFromQuery(c, bookPreloads)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+
+	sel, err := bookSelectionFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	currency, ok := convertToFromQuery(c, &sel)
+	if !ok {
+		return
+	}
+
+	includeDeleted := c.Query("include_deleted") == "true"
+	if includeDeleted && c.GetString(middlewares.UserRoleKey) != models.RoleAdmin {
+		c.Error(apierrors.Forbidden("Only admins can list deleted books!"))
+		return
+	}
+
+	opts := repositories.BookListOptions{
+		Filter:         filter,
+		Sort:           sort,
+		Offset:         pagination.Offset(),
+		Limit:          pagination.PageSize,
+		Preloads:       sel.withPreloads(preloads),
+		Columns:        sel.columns,
+		IncludeDeleted: includeDeleted,
+	}
+	if raw, ok := c.GetQuery("cursor"); ok {
+		ctrl.listBooksAfter(c, opts, sel, currency, raw)
+		return
+	}
+
+	books, total, err := ctrl.books.List(c.Request.Context(), opts)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+	if !ctrl.convertPrices(c, books, currency) {
+		return
+	}
+
+	data, err := sel.apply(books)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": data, "meta": pagination})
+}
+
+const invalidCursor = "Invalid cursor; it must come from next_cursor of a list with the same sort."
+
+// listBooksAfter serves ?cursor=, empty for the first page. The cursor
+// carries its sort; ?sort= may be left out of later requests but must not
+// differ.
+func (ctrl *BookController) listBooksAfter(c *gin.Context, opts repositories.BookListOptions, sel bookSelection, currency, raw string) {
+	var after *repositories.BookCursor
+	if raw != "" {
+		var cursor repositories.BookCursor
+		err := decodeCursor(raw, &cursor)
+		if err != nil || (c.Query("sort") != "" && !slices.Equal(cursor.Sort, opts.Sort)) {
+			c.Error(apierrors.Validation(invalidCursor))
+			return
+		}
+		after = &cursor
+		opts.Sort = cursor.Sort
+	}
+
+	books, next, err := ctrl.books.ListAfter(c.Request.Context(), opts, after)
+	if errors.Is(err, repositories.ErrInvalidCursor) {
+		c.Error(apierrors.Validation(invalidCursor))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	meta := CursorPagination{PageSize: opts.Limit}
+	if next != nil {
+		encoded, err := encodeCursor(next)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		meta.NextCursor = &encoded
+	}
+	if !ctrl.convertPrices(c, books, currency) {
+		return
+	}
+	data, err := sel.apply(books)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": data, "meta": meta})
+}
+
+// GET books/:id?preload=&fields=&convert_to=
+// :id is either the book's UUID or its slug.
+//
+// @Summary Get a book
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path string true "Book ID or slug"
+// @Param preload query string false "Associations to embed (author, publisher, categories, tags)"
+// @Param fields query string false "Comma-separated fields to return, e.g. title,author (all by default); author, publisher, categories and tags embed the association"
+// @Param convert_to query string false "ISO 4217 currency to convert the price to, returned as converted_price"
+// @Param If-None-Match header string false "ETag of a cached copy"
+// @Success 200 {object} object{data=models.Book}
+// @Header 200 {string} ETag "Version of the book"
+// @Success 304
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/books/{id} [get]
+func (ctrl *BookController) FindBook(c *gin.Context) {
+	preloads, err := preloadsFromQuery(c, bookPreloads)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	sel, err := bookSelectionFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	currency, ok := convertToFromQuery(c, &sel)
+	if !ok {
+		return
+	}
+	preloads = sel.withPreloads(preloads)
+	columns := sel.columns
+	if len(columns) > 0 {
+		// The ETag is made of these.
+		columns = append(columns, "version", "review_count", "available_copies")
+	}
+
+	var book *models.Book
+	if id, parseErr := uuid.Parse(c.Param("id")); parseErr == nil {
+		book, err = ctrl.books.GetColumns(c.Request.Context(), id, columns, preloads...)
+	} else {
+		book, err = ctrl.books.GetBySlugColumns(c.Request.Context(), c.Param("id"), columns, preloads...)
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if notModified(c, book.ETag()) {
+		return
+	}
+	if currency != "" {
+		converted := []models.Book{*book}
+		if !ctrl.convertPrices(c, converted, currency) {
+			return
+		}
+		book = &converted[0]
+	}
+
+	data, err := sel.apply(book)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": data})
+}
+
+// @Summary Create a book
+// @Description Fails with 409, listing them under duplicates, if the book likely duplicates others: same ISBN, or same author and a similar title. Pass force=true to create it anyway.
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateBookInput true "Book"
+// @Param force query bool false "Create the book even if it likely duplicates others"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books [post]
+func (ctrl *BookController) CreateBook(c *gin.Context) {
 	var input CreateBookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apierrors.Binding(err))
 		return
 	}
 
-	book := models.Book{Title: input.Title, Author: input.Author}
-	models.DB.Create(&book)
-	c.JSON(http.StatusOK, gin.H{"data": book})
+	book := models.Book{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, PublisherID: input.PublisherID, Year: input.Year, ISBN: input.ISBN, Quantity: input.Quantity, Price: input.Price, Currency: input.Currency}
+	if c.Query("force") != "true" {
+		duplicates, err := ctrl.books.FindDuplicates(c.Request.Context(), &book)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		if len(duplicates) > 0 {
+			c.Error(apierrors.Conflict("The book likely duplicates existing ones; merge them, or pass force=true to create it anyway.").With("duplicates", duplicates))
+			return
+		}
+	}
+	if err := ctrl.books.Create(c.Request.Context(), &book); err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
 }
 
-func UpdateBook(c *gin.Context) {
-	var book models.Book
-	if err := models.DB.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"data": "Record not found!"})
+// @Summary Update a book
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the version being changed, or *"
+// @Param input body controllers.UpdateBookInput true "Book"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 412 {object} apierrors.Problem
+// @Failure 428 {object} apierrors.Problem
+// @Router /api/v1/books/{id} [put]
+func (ctrl *BookController) UpdateBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
 		return
 	}
 
 	var input UpdateBookInput
 	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	book, err := ctrl.books.Update(c.Request.Context(), id, models.Book{Version: input.Version, Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, PublisherID: input.PublisherID, Year: input.Year, ISBN: input.ISBN, Price: input.Price, Currency: input.Currency}, ifMatch)
+	if err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// PATCH books/:id
+// Accepts application/json or application/merge-patch+json; only the fields
+// present in the body are changed.
+//
+// @Summary Partially update a book
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the version being changed, or *"
+// @Param input body controllers.PatchBookInput true "Fields to change"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 412 {object} apierrors.Problem
+// @Failure 428 {object} apierrors.Problem
+// @Router /api/v1/books/{id} [patch]
+func (ctrl *BookController) PatchBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	var input PatchBookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	book, err := ctrl.books.Patch(c.Request.Context(), id, services.BookPatch{Version: input.Version, Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, PublisherID: input.PublisherID, Year: input.Year, ISBN: input.ISBN, Price: input.Price, Currency: input.Currency}, ifMatch)
+	if err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// @Summary Soft-delete a book
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param If-Match header string true "ETag of the version being changed, or *"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 412 {object} apierrors.Problem
+// @Failure 428 {object} apierrors.Problem
+// @Router /api/v1/books/{id} [delete]
+func (ctrl *BookController) DeleteBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.books.Delete(c.Request.Context(), id, ifMatch); err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// POST books/:id/restore
+//
+// @Summary Restore a soft-deleted book
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/restore [post]
+func (ctrl *BookController) RestoreBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	book, err := ctrl.books.Restore(c.Request.Context(), id)
+	if errors.Is(err, services.ErrNotDeleted) {
+		c.Error(apierrors.Conflict("Book is not deleted!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// DELETE books/:id/permanent
+//
+// @Summary Permanently delete a book
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/permanent [delete]
+func (ctrl *BookController) DeleteBookPermanently(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	if err := ctrl.books.DeletePermanently(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+type AttachCategoriesInput struct {
+	CategoryIDs []uint `json:"category_ids" binding:"required,min=1"`
+}
+
+// POST books/:id/categories
+//
+// @Summary Attach categories to a book
+// @Tags books
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.AttachCategoriesInput true "Categories"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/categories [post]
+func (ctrl *BookController) AttachCategories(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input AttachCategoriesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
 		return
 	}
 
-	models.DB.Model(&book).Updates(input)
-	c.JSON(http.StatusOK, gin.H{"data": book})
+	book, err := ctrl.books.AttachCategories(c.Request.Context(), id, input.CategoryIDs)
+	if err != nil {
+		c.Error(bookError(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
 }
 
-func DeleteBook(c *gin.Context) {
-	var book models.Book
-	if err := models.DB.Where("id = ?", c.Param("id")).First(&book).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"data": "Record not found!"})
+// DELETE books/:id/categories/:category_id
+//
+// @Summary Detach a category from a book
+// @Tags books
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param category_id path int true "Category ID"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/categories/{category_id} [delete]
+func (ctrl *BookController) DetachCategory(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	categoryID, ok := pathID(c, "category_id")
+	if !ok {
+		return
+	}
+
+	book, err := ctrl.books.DetachCategory(c.Request.Context(), id, categoryID)
+	if err != nil {
+		c.Error(err)
 		return
 	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// Parses the :id path parameter as a book UUID.
+func bookID(c *gin.Context) (uuid.UUID, bool) {
+	return pathUUID(c, "id")
+}
+
+// pathUUID parses a UUID path parameter; a malformed one can't match any
+// record, so it answers 404.
+func pathUUID(c *gin.Context, param string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.Error(apierrors.NotFound("Record not found!"))
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// Same as pathUUID for the integer IDs of authors and categories.
+func pathID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 64)
+	if err != nil {
+		c.Error(apierrors.NotFound("Record not found!"))
+		return 0, false
+	}
+	return uint(id), true
+}
 
-	models.DB.Delete(&book)
-	c.JSON(http.StatusOK, gin.H{"data": true})
+func bookError(err error) error {
+	if errors.Is(err, services.ErrUnknownAuthor) || errors.Is(err, services.ErrUnknownPublisher) || errors.Is(err, services.ErrUnknownCategory) {
+		return apierrors.Validation(err.Error())
+	}
+	if errors.Is(err, services.ErrUnknownBook) || errors.Is(err, services.ErrSameBook) {
+		return apierrors.Validation(err.Error())
+	}
+	if errors.Is(err, services.ErrPriceCurrency) || errors.Is(err, services.ErrInvalidPrice) {
+		return apierrors.Validation(err.Error())
+	}
+	if errors.Is(err, repositories.ErrBookHeld) {
+		return apierrors.Conflict("Members are in line for the duplicate; cancel their holds before merging it!")
+	}
+	if errors.Is(err, services.ErrPreconditionFailed) {
+		return apierrors.New(http.StatusPreconditionFailed, "The book has been modified since you fetched it; get it again and retry.")
+	}
+	return err
 }
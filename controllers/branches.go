@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateBranchInput struct {
+	// Short name of the branch, unique in the library.
+	Code    string `json:"code" binding:"required,max=32" example:"downtown"`
+	Name    string `json:"name" binding:"required,min=2,max=255"`
+	Address string `json:"address" binding:"max=500"`
+}
+
+type UpdateBranchInput struct {
+	Code    string `json:"code" binding:"omitempty,max=32" example:"downtown"`
+	Name    string `json:"name" binding:"omitempty,min=2,max=255"`
+	Address string `json:"address" binding:"max=500"`
+}
+
+// BranchController serves branches through a CRUDController. Its endpoints are
+// those of the CRUDController, declared again only to be documented.
+type BranchController struct {
+	*CRUDController[models.Branch, CreateBranchInput, UpdateBranchInput]
+}
+
+func NewBranchController(branches services.BranchService) *BranchController {
+	return &BranchController{NewCRUDController[models.Branch](branches, CRUDResource[models.Branch, CreateBranchInput, UpdateBranchInput]{
+		New: func(input CreateBranchInput) models.Branch {
+			return models.Branch{Code: input.Code, Name: input.Name, Address: input.Address}
+		},
+		Changes: func(input UpdateBranchInput) models.Branch {
+			return models.Branch{Code: input.Code, Name: input.Name, Address: input.Address}
+		},
+		CreatedStatus: http.StatusCreated,
+		Error: func(err error) error {
+			if errors.Is(err, repositories.ErrDuplicate) {
+				return apierrors.Conflict("A branch with this code already exists!")
+			}
+			if errors.Is(err, services.ErrBranchHasCopies) {
+				return apierrors.Conflict("Branch still holds copies; transfer them to another branch first.")
+			}
+			return err
+		},
+	})}
+}
+
+// GET branches?page=&page_size=
+//
+// @Summary List branches
+// @Tags branches
+// @Produce json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Branch,meta=controllers.Pagination}
+// @Router /api/v1/branches [get]
+func (ctrl *BranchController) List(c *gin.Context) { ctrl.CRUDController.List(c) }
+
+// @Summary Get a branch
+// @Tags branches
+// @Produce json
+// @Param id path int true "Branch ID"
+// @Success 200 {object} object{data=models.Branch}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/branches/{id} [get]
+func (ctrl *BranchController) Get(c *gin.Context) { ctrl.CRUDController.Get(c) }
+
+// @Summary Create a branch
+// @Tags branches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateBranchInput true "Branch"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Branch}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/branches [post]
+func (ctrl *BranchController) Create(c *gin.Context) { ctrl.CRUDController.Create(c) }
+
+// @Summary Update a branch
+// @Tags branches
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Branch ID"
+// @Param input body controllers.UpdateBranchInput true "Branch"
+// @Success 200 {object} object{data=models.Branch}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/branches/{id} [put]
+func (ctrl *BranchController) Update(c *gin.Context) { ctrl.CRUDController.Update(c) }
+
+// @Summary Delete a branch
+// @Description Only once the copies it holds have been transferred elsewhere.
+// @Tags branches
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Branch ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/branches/{id} [delete]
+func (ctrl *BranchController) Delete(c *gin.Context) { ctrl.CRUDController.Delete(c) }
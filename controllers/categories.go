@@ -0,0 +1,91 @@
+package controllers
+
+import (
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CategoryInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// CategoryController serves categories through a CRUDController. Its endpoints are
+// those of the CRUDController, declared again only to be documented.
+type CategoryController struct {
+	*CRUDController[models.Category, CategoryInput, CategoryInput]
+}
+
+func NewCategoryController(categories services.CategoryService) *CategoryController {
+	return &CategoryController{NewCRUDController[models.Category](categories, CRUDResource[models.Category, CategoryInput, CategoryInput]{
+		New:     func(input CategoryInput) models.Category { return models.Category{Name: input.Name} },
+		Changes: func(input CategoryInput) models.Category { return models.Category{Name: input.Name} },
+	})}
+}
+
+// GET categories?page=&page_size=
+//
+// @Summary List categories
+// @Tags categories
+// @Produce json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Category,meta=controllers.Pagination}
+// @Router /api/v1/categories [get]
+func (ctrl *CategoryController) List(c *gin.Context) { ctrl.CRUDController.List(c) }
+
+// @Summary Get a category
+// @Tags categories
+// @Produce json
+// @Param id path int true "Category ID"
+// @Success 200 {object} object{data=models.Category}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/categories/{id} [get]
+func (ctrl *CategoryController) Get(c *gin.Context) { ctrl.CRUDController.Get(c) }
+
+// @Summary Create a category
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CategoryInput true "Category"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 200 {object} object{data=models.Category}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/categories [post]
+func (ctrl *CategoryController) Create(c *gin.Context) { ctrl.CRUDController.Create(c) }
+
+// @Summary Update a category
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Category ID"
+// @Param input body controllers.CategoryInput true "Category"
+// @Success 200 {object} object{data=models.Category}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/categories/{id} [put]
+func (ctrl *CategoryController) Update(c *gin.Context) { ctrl.CRUDController.Update(c) }
+
+// @Summary Delete a category
+// @Tags categories
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Category ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/categories/{id} [delete]
+func (ctrl *CategoryController) Delete(c *gin.Context) { ctrl.CRUDController.Delete(c) }
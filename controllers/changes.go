@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type ChangeController struct {
+	changes services.ChangeService
+}
+
+func NewChangeController(changes services.ChangeService) *ChangeController {
+	return &ChangeController{changes: changes}
+}
+
+// ChangesMeta is the meta of the changes feed. NextSince is always set:
+// with nothing new, it is the since asked with, to be asked again later.
+type ChangesMeta struct {
+	NextSince string `json:"next_since"`
+	HasMore   bool   `json:"has_more"`
+}
+
+const invalidSince = "Invalid since; it must come from next_since of the changes feed."
+
+// GET changes?since=&types=&limit=
+//
+// @Summary List the changes made to the catalog
+// @Description Creates, updates and deletes of books, authors, publishers, categories, tags, series and the links between them, oldest first, for downstream systems to sync from. Start without since, then pass the next_since of each response; has_more tells whether to ask again right away. Changes are listed once a few seconds old.
+// @Tags audit
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param since query string false "next_since of the previous response"
+// @Param types query string false "Comma-separated tables, e.g. books,authors"
+// @Param limit query int false "Maximum number of changes (default 100, max 1000)"
+// @Success 200 {object} object{data=[]services.Change,meta=controllers.ChangesMeta}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/changes [get]
+func (ctrl *ChangeController) FindChanges(c *gin.Context) {
+	var since uint
+	if raw := c.Query("since"); raw != "" {
+		var ok bool
+		if since, ok = decodeSince(raw); !ok {
+			c.Error(apierrors.Validation(invalidSince))
+			return
+		}
+	}
+	types, ok := changeTypesFromQuery(c)
+	if !ok {
+		return
+	}
+	limit, ok := limitFromQuery(c, 100, 1000)
+	if !ok {
+		return
+	}
+
+	changes, more, err := ctrl.changes.Feed(c.Request.Context(), since, types, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	if len(changes) > 0 {
+		since = changes[len(changes)-1].Sequence
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": changes, "meta": ChangesMeta{NextSince: encodeSince(since), HasMore: more}})
+}
+
+func changeTypesFromQuery(c *gin.Context) ([]string, bool) {
+	var types []string
+	if raw := c.Query("types"); raw != "" {
+		for _, changeType := range strings.Split(raw, ",") {
+			changeType = strings.TrimSpace(changeType)
+			if !slices.Contains(services.ChangeTypes, changeType) {
+				c.Error(apierrors.Validation("Invalid change type %q; it must be one of %s.").WithArgs(changeType, strings.Join(services.ChangeTypes, ", ")))
+				return nil, false
+			}
+			types = append(types, changeType)
+		}
+	}
+	return types, true
+}
+
+// Like list cursors, since is opaque to clients: the sequence of the last
+// change sent, base64url-encoded.
+func encodeSince(sequence uint) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(uint64(sequence), 10)))
+}
+
+func decodeSince(raw string) (uint, bool) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return 0, false
+	}
+	sequence, err := strconv.ParseUint(string(data), 10, 64)
+	return uint(sequence), err == nil
+}
@@ -0,0 +1,52 @@
+package controllers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+// requireIfMatch reads the If-Match header that writes to a book must carry,
+// answering 428 when it is missing. "*" accepts any version and comes back
+// as a nil list, which skips the check.
+func requireIfMatch(c *gin.Context) ([]string, bool) {
+	header := c.GetHeader("If-Match")
+	if header == "" {
+		c.Error(apierrors.New(http.StatusPreconditionRequired, "If-Match header is required; send the ETag of the book you are changing."))
+		return nil, false
+	}
+	if strings.TrimSpace(header) == "*" {
+		return nil, true
+	}
+	return splitETags(header), true
+}
+
+// notModified sets the ETag header and, when If-None-Match holds the same
+// ETag, answers 304 and returns true.
+func notModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range splitETags(header) {
+		// If-None-Match uses the weak comparison.
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+func splitETags(header string) []string {
+	var etags []string
+	for _, part := range strings.Split(header, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			etags = append(etags, part)
+		}
+	}
+	return etags
+}
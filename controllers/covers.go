@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/images"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// MaxCoverSize is the largest cover accepted. The route must allow bodies
+// somewhat larger, for the multipart framing around the file.
+const MaxCoverSize = 5 << 20
+
+type CoverController struct {
+	covers services.CoverService
+}
+
+func NewCoverController(covers services.CoverService) *CoverController {
+	return &CoverController{covers: covers}
+}
+
+// POST books/:id/cover
+//
+// @Summary Upload a book cover
+// @Description Replaces the current cover. A thumbnail of at most 256x256 pixels is generated. Images of more pixels than the configured maximum (50 megapixels by default) are rejected.
+// @Tags books
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param file formData file true "JPEG, PNG, GIF or WebP image (max 5 MB)"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 413 {object} apierrors.Problem
+// @Failure 415 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/cover [post]
+func (ctrl *CoverController) UploadCover(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	header, err := c.FormFile("file")
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge), err == nil && header.Size > MaxCoverSize:
+		c.Error(apierrors.PayloadTooLarge("The cover exceeds 5 MB."))
+		return
+	case err != nil:
+		c.Error(apierrors.Validation("file is required"))
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	book, err := ctrl.covers.Upload(c.Request.Context(), id, data)
+	if errors.Is(err, images.ErrUnsupported) {
+		c.Error(apierrors.New(http.StatusUnsupportedMediaType, err.Error()))
+		return
+	}
+	if errors.Is(err, images.ErrTooManyPixels) {
+		c.Error(apierrors.PayloadTooLarge("The cover has too many pixels."))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// GET books/:id/cover?size=thumbnail
+//
+// @Summary Get a book cover
+// @Tags books
+// @Produce image/jpeg
+// @Produce image/png
+// @Produce image/gif
+// @Produce image/webp
+// @Param id path string true "Book ID"
+// @Param size query string false "original (default) or thumbnail"
+// @Success 200 {file} file
+// @Success 302 "Redirect to the cover in object storage, when it supports direct downloads"
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/cover [get]
+func (ctrl *CoverController) FindCover(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	size := c.DefaultQuery("size", "original")
+	if size != "original" && size != "thumbnail" {
+		c.Error(apierrors.Validation("size must be original or thumbnail"))
+		return
+	}
+
+	link, err := ctrl.covers.DownloadURL(c.Request.Context(), id, size == "thumbnail")
+	if err != nil {
+		c.Error(coverError(err))
+		return
+	}
+	if link != "" {
+		// The link expires, so the redirect is only cached briefly.
+		c.Header("Cache-Control", "private, max-age=60")
+		c.Redirect(http.StatusFound, link)
+		return
+	}
+
+	obj, err := ctrl.covers.Open(c.Request.Context(), id, size == "thumbnail")
+	if err != nil {
+		c.Error(coverError(err))
+		return
+	}
+	defer obj.Close()
+
+	// The URL stays the same when the cover is replaced, so keep caching short.
+	c.DataFromReader(http.StatusOK, obj.Size, obj.ContentType, obj, map[string]string{
+		"Cache-Control": "public, max-age=300",
+	})
+}
+
+func coverError(err error) error {
+	if errors.Is(err, services.ErrNoCover) {
+		return apierrors.NotFound("Book has no cover!")
+	}
+	return err
+}
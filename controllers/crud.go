@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// CRUDResource describes a resource to a CRUDController: how the bodies of
+// creates (C) and updates (U) become records (T), and what it answers
+// differently from other resources.
+type CRUDResource[T, C, U any] struct {
+	// New is the record a create stores.
+	New func(input C) T
+	// Changes is what an update applies; its zero fields are left as they
+	// are.
+	Changes func(input U) T
+	// CreatedStatus answers creates; http.StatusOK if zero.
+	CreatedStatus int
+	// Error, if set, maps the errors particular to the resource to
+	// problems, returning the others as they are.
+	Error func(err error) error
+}
+
+// CRUDController lists, gets, creates, updates and deletes the records of a
+// resource with integer IDs. Serving a resource takes little more than
+// registering its endpoints; controllers embed it to override some, or to
+// add others.
+type CRUDController[T, C, U any] struct {
+	records  services.CRUDService[T]
+	resource CRUDResource[T, C, U]
+}
+
+func NewCRUDController[T, C, U any](records services.CRUDService[T], resource CRUDResource[T, C, U]) *CRUDController[T, C, U] {
+	if resource.CreatedStatus == 0 {
+		resource.CreatedStatus = http.StatusOK
+	}
+	return &CRUDController[T, C, U]{records: records, resource: resource}
+}
+
+// GET <resource>?page=&page_size=
+func (ctrl *CRUDController[T, C, U]) List(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	records, total, err := ctrl.records.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(ctrl.error(err))
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": records, "meta": pagination})
+}
+
+// GET <resource>/:id
+func (ctrl *CRUDController[T, C, U]) Get(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	record, err := ctrl.records.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(ctrl.error(err))
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": record})
+}
+
+// POST <resource>
+func (ctrl *CRUDController[T, C, U]) Create(c *gin.Context) {
+	var input C
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	record := ctrl.resource.New(input)
+	if err := ctrl.records.Create(c.Request.Context(), &record); err != nil {
+		c.Error(ctrl.error(err))
+		return
+	}
+	render.Respond(c, ctrl.resource.CreatedStatus, gin.H{"data": record})
+}
+
+// PUT <resource>/:id
+func (ctrl *CRUDController[T, C, U]) Update(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input U
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	record, err := ctrl.records.Update(c.Request.Context(), id, ctrl.resource.Changes(input))
+	if err != nil {
+		c.Error(ctrl.error(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": record})
+}
+
+// DELETE <resource>/:id
+func (ctrl *CRUDController[T, C, U]) Delete(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.records.Delete(c.Request.Context(), id); err != nil {
+		c.Error(ctrl.error(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+func (ctrl *CRUDController[T, C, U]) error(err error) error {
+	if ctrl.resource.Error == nil {
+		return err
+	}
+	return ctrl.resource.Error(err)
+}
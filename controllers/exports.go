@@ -0,0 +1,184 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tabular"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateExportInput struct {
+	// csv (default), xlsx, or pdf for a printable catalog report.
+	Format string            `json:"format" binding:"omitempty,oneof=csv xlsx pdf" example:"csv"`
+	Filter ExportFilterInput `json:"filter"`
+}
+
+// ExportFilterInput narrows an export down as the query parameters of the
+// same names narrow GET books down; no filter exports the whole catalog.
+type ExportFilterInput struct {
+	Author        string `json:"author"`
+	AuthorID      uint   `json:"author_id"`
+	PublisherID   uint   `json:"publisher_id"`
+	CategoryID    uint   `json:"category_id"`
+	Tag           string `json:"tag"`
+	TitleContains string `json:"title_contains"`
+	YearGte       *int   `json:"year_gte"`
+	YearLte       *int   `json:"year_lte"`
+}
+
+// ExportStatus is an export with, once it has succeeded, the URL its file
+// is downloaded from.
+type ExportStatus struct {
+	models.Export
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+type ExportController struct {
+	exports services.ExportService
+}
+
+func NewExportController(exports services.ExportService) *ExportController {
+	return &ExportController{exports: exports}
+}
+
+// POST exports
+//
+// @Summary Export the catalog in the background
+// @Description Queues an export of the books matching the filter; poll GET /exports/{id} until its status is succeeded or failed, then download the file from its download_url.
+// @Tags exports
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateExportInput true "Format and filter"
+// @Success 202 {object} object{data=controllers.ExportStatus}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/exports [post]
+func (ctrl *ExportController) CreateExport(c *gin.Context) {
+	var input CreateExportInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+	if input.Format == "" {
+		input.Format = tabular.CSV
+	}
+
+	f := input.Filter
+	filter := repositories.BookFilter{
+		Author:        f.Author,
+		AuthorID:      f.AuthorID,
+		PublisherID:   f.PublisherID,
+		CategoryID:    f.CategoryID,
+		Tag:           services.NormalizeTag(f.Tag),
+		TitleContains: f.TitleContains,
+		YearGte:       f.YearGte,
+		YearLte:       f.YearLte,
+	}
+	export, err := ctrl.exports.Create(c.Request.Context(), c.GetUint(middlewares.UserIDKey), input.Format, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Location", exportPath(export))
+	render.Respond(c, http.StatusAccepted, gin.H{"data": exportStatus(export)})
+}
+
+// GET exports/:id
+//
+// @Summary Get the status of one of my exports
+// @Tags exports
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Export ID"
+// @Success 200 {object} object{data=controllers.ExportStatus}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/exports/{id} [get]
+func (ctrl *ExportController) FindExport(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	export, err := ctrl.exports.Get(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": exportStatus(export)})
+}
+
+// GET exports/:id/download
+//
+// @Summary Download the file of one of my exports
+// @Tags exports
+// @Produce text/csv
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Produce application/pdf
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Export ID"
+// @Success 200 {file} file
+// @Success 302 "Redirect to the file in object storage, when it supports direct downloads"
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/exports/{id}/download [get]
+func (ctrl *ExportController) DownloadExport(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+	userID := c.GetUint(middlewares.UserIDKey)
+
+	link, err := ctrl.exports.DownloadURL(c.Request.Context(), userID, id)
+	if err != nil {
+		c.Error(exportError(err))
+		return
+	}
+	if link != "" {
+		c.Header("Cache-Control", "no-store")
+		c.Redirect(http.StatusFound, link)
+		return
+	}
+
+	export, obj, err := ctrl.exports.Open(c.Request.Context(), userID, id)
+	if err != nil {
+		c.Error(exportError(err))
+		return
+	}
+	defer obj.Close()
+	c.DataFromReader(http.StatusOK, obj.Size, services.ExportContentType(export.Format), obj, map[string]string{
+		"Content-Disposition": `attachment; filename="` + export.Filename + `"`,
+		"Cache-Control":       "no-store",
+	})
+}
+
+func exportPath(export *models.Export) string {
+	return "/api/v1/exports/" + export.ID.String()
+}
+
+func exportStatus(export *models.Export) ExportStatus {
+	status := ExportStatus{Export: *export}
+	if export.Status == models.ExportSucceeded {
+		status.DownloadURL = exportPath(export) + "/download"
+	}
+	return status
+}
+
+func exportError(err error) error {
+	if errors.Is(err, services.ErrExportNotReady) {
+		return apierrors.Conflict("The export has not succeeded; check its status.")
+	}
+	return err
+}
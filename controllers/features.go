@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureToggler is what the feature flag endpoints read and change;
+// *features.Flags implements it.
+type FeatureToggler interface {
+	List(ctx context.Context) ([]features.Flag, error)
+	Set(ctx context.Context, name string, enabled bool) (features.Flag, error)
+	Reset(ctx context.Context, name string) (features.Flag, error)
+}
+
+type SetFeatureInput struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+type FeatureController struct {
+	flags FeatureToggler
+}
+
+func NewFeatureController(flags FeatureToggler) *FeatureController {
+	return &FeatureController{flags: flags}
+}
+
+// @Summary List feature flags
+// @Tags features
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} object{data=[]features.Flag}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/admin/features [get]
+func (ctrl *FeatureController) FindFeatures(c *gin.Context) {
+	flags, err := ctrl.flags.List(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": flags})
+}
+
+// @Summary Turn a feature on or off
+// @Description Overrides the configured value until the override is deleted. Turning maintenance on answers everyone but admins with 503. Other instances pick the change up within the refresh interval when flags are shared through Redis; without Redis it applies to the instance answering only.
+// @Tags features
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Flag name"
+// @Param input body controllers.SetFeatureInput true "Whether the feature is on"
+// @Success 200 {object} object{data=features.Flag}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/admin/features/{name} [put]
+func (ctrl *FeatureController) SetFeature(c *gin.Context) {
+	var input SetFeatureInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	flag, err := ctrl.flags.Set(c.Request.Context(), c.Param("name"), *input.Enabled)
+	if err != nil {
+		c.Error(featureError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "feature flag set", "flag", flag.Name, "enabled", flag.Enabled, "user_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": flag})
+}
+
+// @Summary Put a feature back to its configured value
+// @Tags features
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Flag name"
+// @Success 200 {object} object{data=features.Flag}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/admin/features/{name} [delete]
+func (ctrl *FeatureController) ResetFeature(c *gin.Context) {
+	flag, err := ctrl.flags.Reset(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		c.Error(featureError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "feature flag reset", "flag", flag.Name, "enabled", flag.Enabled, "user_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": flag})
+}
+
+func featureError(err error) error {
+	if errors.Is(err, features.ErrUnknownFlag) {
+		return apierrors.NotFound("No feature flag is named that!")
+	}
+	return err
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// Columns that may be used in ?sort=. Keys are the public names accepted in
+// the query string, values are the database columns they map to.
+var bookSortColumns = map[string]string{
+	"id":         "id",
+	"title":      "title",
+	"author_id":  "author_id",
+	"year":       "year",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+// Associations that may be embedded with ?preload=. Keys are the public
+// names, values the GORM association names.
+var bookPreloads = map[string]string{
+	"author":     "Author",
+	"publisher":  "Publisher",
+	"categories": "Categories",
+	"tags":       "Tags",
+}
+
+// Fields that may be requested with ?fields=. Keys are the public names,
+// values the columns they are loaded from, or the association they embed.
+var bookFields = map[string]struct{ column, association string }{
+	"id":               {column: "id"},
+	"slug":             {column: "slug"},
+	"title":            {column: "title"},
+	"description":      {column: "description"},
+	"author_id":        {column: "author_id"},
+	"author":           {association: "Author"},
+	"publisher_id":     {column: "publisher_id"},
+	"publisher":        {association: "Publisher"},
+	"categories":       {association: "Categories"},
+	"tags":             {association: "Tags"},
+	"year":             {column: "year"},
+	"isbn":             {column: "isbn"},
+	"quantity":         {column: "quantity"},
+	"available_copies": {column: "available_copies"},
+	"rating_average":   {column: "rating_average"},
+	"review_count":     {column: "review_count"},
+	"price":            {column: "price"},
+	"currency":         {column: "currency"},
+	"converted_price":  {column: "price"},
+	"version":          {column: "version"},
+	"created_at":       {column: "created_at"},
+	"updated_at":       {column: "updated_at"},
+	"deleted_at":       {column: "deleted_at"},
+}
+
+// bookSelection is what ?fields= asks for: the members kept in the
+// response, the columns loaded and the associations embedded. Empty when
+// the parameter is absent, meaning everything.
+type bookSelection struct {
+	fields   []string
+	columns  []string
+	preloads []string
+}
+
+// Translates ?fields=title,author into a selection, rejecting fields not in
+// the whitelist. Requesting an association embeds it, as ?preload= does.
+func bookSelectionFromQuery(c *gin.Context) (bookSelection, error) {
+	var sel bookSelection
+	raw := c.Query("fields")
+	if raw == "" {
+		return sel, nil
+	}
+
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		field, ok := bookFields[name]
+		if !ok {
+			return sel, fmt.Errorf("invalid field: %q", name)
+		}
+		sel.fields = append(sel.fields, name)
+		if field.association != "" {
+			sel.preloads = append(sel.preloads, field.association)
+		} else {
+			sel.columns = append(sel.columns, field.column)
+		}
+	}
+	if len(sel.columns) == 0 {
+		// Only associations: the book's own columns beyond its ID aren't
+		// needed.
+		sel.columns = []string{"id"}
+	}
+	return sel, nil
+}
+
+// apply returns data, a book or books, trimmed to the selected fields.
+func (sel bookSelection) apply(data interface{}) (interface{}, error) {
+	if len(sel.fields) == 0 {
+		return data, nil
+	}
+	return render.Pick(data, sel.fields)
+}
+
+// withPreloads adds the selection's associations to those from ?preload=.
+func (sel bookSelection) withPreloads(preloads []string) []string {
+	for _, association := range sel.preloads {
+		if !slices.Contains(preloads, association) {
+			preloads = append(preloads, association)
+		}
+	}
+	return preloads
+}
+
+// Reads the ?author=, ?author_id=, ?publisher_id=, ?category_id=, ?tag=,
+// ?title_contains=, ?year_gte= and ?year_lte= filters.
+func bookFilterFromQuery(c *gin.Context) (repositories.BookFilter, error) {
+	filter := repositories.BookFilter{
+		Author:        c.Query("author"),
+		Tag:           services.NormalizeTag(c.Query("tag")),
+		TitleContains: c.Query("title_contains"),
+	}
+
+	for param, target := range map[string]*uint{"author_id": &filter.AuthorID, "publisher_id": &filter.PublisherID, "category_id": &filter.CategoryID} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %q", param, raw)
+		}
+		*target = uint(id)
+	}
+
+	for param, target := range map[string]**int{"year_gte": &filter.YearGte, "year_lte": &filter.YearLte} {
+		raw := c.Query(param)
+		if raw == "" {
+			continue
+		}
+		year, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid %s: %q", param, raw)
+		}
+		*target = &year
+	}
+
+	return filter, nil
+}
+
+// Translates ?sort=title,-created_at into sort entries. Only columns in the
+// whitelist are accepted; a leading "-" sorts descending.
+func bookSortFromQuery(c *gin.Context) ([]repositories.Sort, error) {
+	raw := c.Query("sort")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var sorts []repositories.Sort
+	for _, field := range strings.Split(raw, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(field, "-")
+
+		column, ok := bookSortColumns[field]
+		if !ok {
+			return nil, fmt.Errorf("invalid sort field: %q", field)
+		}
+		sorts = append(sorts, repositories.Sort{Column: column, Desc: desc})
+	}
+	return sorts, nil
+}
+
+// Translates ?preload=author into association names, rejecting anything
+// not in allowed. JSON:API's ?include= is read as ?preload= when there's
+// none, so what's included is loaded.
+func preloadsFromQuery(c *gin.Context, allowed map[string]string) ([]string, error) {
+	raw := c.Query("preload")
+	if raw == "" {
+		raw = c.Query("include")
+	}
+	if raw == "" {
+		return nil, nil
+	}
+
+	var preloads []string
+	for _, name := range strings.Split(raw, ",") {
+		name = strings.TrimSpace(name)
+		association, ok := allowed[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid preload: %q", name)
+		}
+		preloads = append(preloads, association)
+	}
+	return preloads, nil
+}
@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type SettleFineInput struct {
+	// paid when the member paid it, waived when they were let off.
+	Status string `json:"status" binding:"required,oneof=paid waived" enums:"paid,waived"`
+	// E.g. a receipt number, or why the fine was waived.
+	Note string `json:"note" binding:"max=500"`
+}
+
+type FineController struct {
+	fines services.FineService
+}
+
+func NewFineController(fines services.FineService) *FineController {
+	return &FineController{fines: fines}
+}
+
+// GET fines?member_id=&status=&page=&page_size=
+//
+// @Summary List the fines charged for late returns
+// @Description Newest first. A loan returned late is fined the configured daily rate per day, or part of one, up to the cap (FINES_DAILY_RATE, FINES_CAP).
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param member_id query string false "Only this member's fines"
+// @Param status query string false "Only fines in this state" Enums(unpaid, paid, waived)
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Fine,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/fines [get]
+func (ctrl *FineController) FindFines(c *gin.Context) {
+	filter := repositories.FineFilter{Status: c.Query("status")}
+	switch filter.Status {
+	case "", models.FineUnpaid, models.FinePaid, models.FineWaived:
+	default:
+		c.Error(apierrors.Validation("Invalid status %q; it must be unpaid, paid or waived.").WithArgs(filter.Status))
+		return
+	}
+	if raw := c.Query("member_id"); raw != "" {
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.Error(apierrors.Validation("Invalid member_id %q; it must be a member ID.").WithArgs(raw))
+			return
+		}
+		filter.MemberID = id
+	}
+	pagination := paginationFromQuery(c)
+
+	fines, total, err := ctrl.fines.List(c.Request.Context(), filter, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": fines, "meta": pagination})
+}
+
+// @Summary Get a fine
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Fine ID"
+// @Success 200 {object} object{data=models.Fine}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/fines/{id} [get]
+func (ctrl *FineController) FindFine(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	fine, err := ctrl.fines.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": fine})
+}
+
+// POST fines/:id/settle
+//
+// @Summary Settle a fine
+// @Description Marks an unpaid fine paid or waived. Members owing more than the configured limit in unpaid fines (FINES_BLOCK_ABOVE) can't check books out.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Fine ID"
+// @Param input body controllers.SettleFineInput true "Settlement"
+// @Success 200 {object} object{data=models.Fine}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/fines/{id}/settle [post]
+func (ctrl *FineController) SettleFine(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+	var input SettleFineInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	fine, err := ctrl.fines.Settle(c.Request.Context(), id, input.Status, c.GetUint(middlewares.UserIDKey), input.Note)
+	if errors.Is(err, repositories.ErrFineSettled) {
+		c.Error(apierrors.Conflict("This fine has already been settled!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": fine})
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const readinessTimeout = 2 * time.Second
+
+// HealthCheck reports whether a dependency is usable.
+type HealthCheck func(ctx context.Context) error
+
+type DependencyStatus struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type HealthController struct {
+	checks map[string]HealthCheck
+}
+
+// NewHealthController takes the readiness checks keyed by dependency name.
+func NewHealthController(checks map[string]HealthCheck) *HealthController {
+	return &HealthController{checks: checks}
+}
+
+// GET /healthz
+// Liveness only says the process is serving requests; it never touches
+// dependencies so a slow database doesn't get the pod restarted.
+//
+// @Summary Liveness probe
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string}
+// @Router /healthz [get]
+func (ctrl *HealthController) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// GET /readyz
+//
+// @Summary Readiness probe
+// @Description Runs every dependency check and returns 503 if any of them fails.
+// @Tags health
+// @Produce json
+// @Success 200 {object} object{status=string,checks=map[string]controllers.DependencyStatus}
+// @Failure 503 {object} object{status=string,checks=map[string]controllers.DependencyStatus}
+// @Router /readyz [get]
+func (ctrl *HealthController) Readiness(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(ctrl.checks))
+	for name := range ctrl.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	status, code := "ok", http.StatusOK
+	results := make(map[string]DependencyStatus, len(names))
+	for _, name := range names {
+		start := time.Now()
+		err := ctrl.checks[name](ctx)
+		result := DependencyStatus{Status: "up", LatencyMS: time.Since(start).Milliseconds()}
+		if err != nil {
+			result.Status = "down"
+			result.Error = err.Error()
+			status, code = "unavailable", http.StatusServiceUnavailable
+		}
+		results[name] = result
+	}
+
+	c.JSON(code, gin.H{"status": status, "checks": results})
+}
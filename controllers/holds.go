@@ -0,0 +1,207 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type PlaceHoldInput struct {
+	MemberID string `json:"member_id" binding:"required,uuid" format:"uuid"`
+}
+
+type HoldController struct {
+	holds services.HoldService
+}
+
+func NewHoldController(holds services.HoldService) *HoldController {
+	return &HoldController{holds: holds}
+}
+
+// POST books/:id/holds
+//
+// @Summary Put a member in line for a book
+// @Description Only books with no copies available can be held. The first copy returned is set aside for the first member in line, who is notified and has the configured pickup window (HOLDS_PICKUP_WINDOW) to borrow it before it goes to the next.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.PlaceHoldInput true "Member"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Hold}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/holds [post]
+func (ctrl *HoldController) PlaceHold(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	var input PlaceHoldInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	hold, err := ctrl.holds.Place(c.Request.Context(), id, uuid.MustParse(input.MemberID))
+	if err != nil {
+		c.Error(holdError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": hold})
+}
+
+// GET books/:id/holds?page=&page_size=
+//
+// @Summary List the line for a book
+// @Description The holds a copy was set aside for first, then the waiting ones in the order they were placed.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Hold,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/holds [get]
+func (ctrl *HoldController) FindBookHolds(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	holds, total, err := ctrl.holds.BookHolds(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": holds, "meta": pagination})
+}
+
+// GET members/:id/holds?page=&page_size=
+//
+// @Summary List a member's holds
+// @Description The books the member is in line for, ready holds first.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Member ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Hold,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/members/{id}/holds [get]
+func (ctrl *HoldController) FindMemberHolds(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	holds, total, err := ctrl.holds.MemberHolds(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": holds, "meta": pagination})
+}
+
+// GET holds/:id
+//
+// @Summary Get a hold
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Hold ID"
+// @Success 200 {object} object{data=models.Hold}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/holds/{id} [get]
+func (ctrl *HoldController) FindHold(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	hold, err := ctrl.holds.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": hold})
+}
+
+// DELETE holds/:id
+//
+// @Summary Cancel a hold
+// @Description A copy set aside for the hold goes to the next member in line.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Hold ID"
+// @Success 200 {object} object{data=models.Hold}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/holds/{id} [delete]
+func (ctrl *HoldController) CancelHold(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	hold, err := ctrl.holds.Cancel(c.Request.Context(), id)
+	if err != nil {
+		c.Error(holdError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": hold})
+}
+
+func holdError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUnknownMember):
+		return apierrors.Validation(err.Error())
+	case errors.Is(err, repositories.ErrCopiesAvailable):
+		return apierrors.Conflict("Copies of this book are available; borrow one instead!")
+	case errors.Is(err, repositories.ErrAlreadyBorrowed):
+		return apierrors.Conflict("The member already has this book on loan!")
+	case errors.Is(err, repositories.ErrAlreadyHeld):
+		return apierrors.Conflict("The member is already in line for this book!")
+	case errors.Is(err, repositories.ErrTooManyHolds):
+		return apierrors.Conflict("The member holds as many books as allowed!")
+	case errors.Is(err, repositories.ErrHoldClosed):
+		return apierrors.Conflict("This hold is no longer active!")
+	}
+	return err
+}
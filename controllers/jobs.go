@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/jobs"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+)
+
+// JobRunner is what the job endpoints report on; *jobs.Runner implements
+// it.
+type JobRunner interface {
+	Statuses() []jobs.Status
+	Status(name string) (jobs.Status, error)
+	Trigger(name string) error
+}
+
+type JobController struct {
+	runner JobRunner
+}
+
+func NewJobController(runner JobRunner) *JobController {
+	return &JobController{runner: runner}
+}
+
+// @Summary List background jobs
+// @Description Statuses are those of the instance answering, since the last restart.
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} object{data=[]jobs.Status}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/jobs [get]
+func (ctrl *JobController) FindJobs(c *gin.Context) {
+	render.Respond(c, http.StatusOK, gin.H{"data": ctrl.runner.Statuses()})
+}
+
+// @Summary Get a background job
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Job name"
+// @Success 200 {object} object{data=jobs.Status}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/jobs/{name} [get]
+func (ctrl *JobController) FindJob(c *gin.Context) {
+	status, err := ctrl.runner.Status(c.Param("name"))
+	if err != nil {
+		c.Error(jobError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": status})
+}
+
+// @Summary Run a background job now
+// @Description The job is queued and runs in the background; poll GET /jobs/{name} for its outcome.
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Job name"
+// @Success 202 {object} object{data=jobs.Status}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/jobs/{name}/run [post]
+func (ctrl *JobController) RunJob(c *gin.Context) {
+	name := c.Param("name")
+	if err := ctrl.runner.Trigger(name); err != nil {
+		c.Error(jobError(err))
+		return
+	}
+	status, err := ctrl.runner.Status(name)
+	if err != nil {
+		c.Error(jobError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusAccepted, gin.H{"data": status})
+}
+
+func jobError(err error) error {
+	switch {
+	case errors.Is(err, jobs.ErrUnknownJob):
+		return apierrors.NotFound("No job is named that!")
+	case errors.Is(err, jobs.ErrAlreadyQueued):
+		return apierrors.Conflict("This job is already queued or running!")
+	}
+	return err
+}
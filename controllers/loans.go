@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CheckoutInput struct {
+	BookID   string `json:"book_id" binding:"required,uuid" format:"uuid"`
+	MemberID string `json:"member_id" binding:"required,uuid" format:"uuid"`
+	// The branch lending the copy; without one an unassigned copy is lent.
+	BranchID *uint `json:"branch_id"`
+}
+
+type LoanController struct {
+	loans services.LoanService
+}
+
+func NewLoanController(loans services.LoanService) *LoanController {
+	return &LoanController{loans: loans}
+}
+
+// POST loans
+// The loan is due after the configured loan duration (LOAN_DURATION).
+//
+// @Summary Check out a book to a member
+// @Description The copy is taken from the shelves of branch_id, or from the unassigned copies without one, unless one was set aside for the member's hold on the book. Members owing more than the configured limit in unpaid fines (FINES_BLOCK_ABOVE) are refused with a 409.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CheckoutInput true "Book and member"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Loan}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/loans [post]
+func (ctrl *LoanController) CreateLoan(c *gin.Context) {
+	var input CheckoutInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	loan, err := ctrl.loans.Checkout(c.Request.Context(), uuid.MustParse(input.BookID), uuid.MustParse(input.MemberID), input.BranchID)
+	if err != nil {
+		c.Error(loanError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": loan})
+}
+
+// POST loans/:id/return
+//
+// @Summary Return a borrowed book
+// @Description A late return fines the member, as the returned loan's fine shows. The copy is set aside for the first member in line for the book, if any.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Loan ID"
+// @Success 200 {object} object{data=models.Loan}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/loans/{id}/return [post]
+func (ctrl *LoanController) ReturnLoan(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	loan, err := ctrl.loans.Return(c.Request.Context(), id)
+	if err != nil {
+		c.Error(loanError(err))
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": loan})
+}
+
+// GET members/:id/loans?page=&page_size=
+//
+// @Summary List a member's active loans
+// @Description Books the member currently has out, soonest due first.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Member ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Loan,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/members/{id}/loans [get]
+func (ctrl *LoanController) FindMemberLoans(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	loans, total, err := ctrl.loans.ActiveLoans(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": loans, "meta": pagination})
+}
+
+// GET loans/overdue?page=&page_size=
+//
+// @Summary List overdue loans
+// @Description Loans still out past their due date, most overdue first.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Loan,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/loans/overdue [get]
+func (ctrl *LoanController) FindOverdueLoans(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	loans, total, err := ctrl.loans.Overdue(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": loans, "meta": pagination})
+}
+
+func loanError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrUnknownBook), errors.Is(err, services.ErrUnknownMember), errors.Is(err, services.ErrUnknownBranch):
+		return apierrors.Validation(err.Error())
+	case errors.Is(err, repositories.ErrNoCopiesAvailable):
+		return apierrors.Conflict("No copies of this book are available right now!")
+	case errors.Is(err, repositories.ErrAlreadyBorrowed):
+		return apierrors.Conflict("The member already has this book on loan!")
+	case errors.Is(err, repositories.ErrAlreadyReturned):
+		return apierrors.Conflict("This loan has already been returned!")
+	case errors.Is(err, services.ErrUnpaidFines):
+		return apierrors.Conflict("The member owes too much in unpaid fines to borrow until they are settled!")
+	}
+	return err
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/lookup"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type LookupInput struct {
+	ISBN string `json:"isbn" binding:"required,isbn"`
+}
+
+type LookupController struct {
+	lookups services.LookupService
+}
+
+func NewLookupController(lookups services.LookupService) *LookupController {
+	return &LookupController{lookups: lookups}
+}
+
+// POST books/lookup
+// Fetches an edition's metadata from OpenLibrary, then Google Books, to
+// prefill a new book. Nothing is stored.
+//
+// @Summary Look up book metadata by ISBN
+// @Tags books
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.LookupInput true "ISBN-10 or ISBN-13"
+// @Success 200 {object} object{data=lookup.Metadata}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 502 {object} apierrors.Problem
+// @Router /api/v1/books/lookup [post]
+func (ctrl *LookupController) LookupBook(c *gin.Context) {
+	var input LookupInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	metadata, err := ctrl.lookups.Lookup(c.Request.Context(), input.ISBN)
+	if errors.Is(err, lookup.ErrNotFound) {
+		c.Error(apierrors.NotFound("No book found for this ISBN."))
+		return
+	}
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "isbn lookup failed", "isbn", input.ISBN, "error", err)
+		c.Error(apierrors.New(http.StatusBadGateway, "The book catalogs could not be reached; try again later."))
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": metadata})
+}
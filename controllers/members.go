@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateMemberInput struct {
+	Name  string `json:"name" binding:"required,min=2,max=255"`
+	Email string `json:"email" binding:"required,email,max=255"`
+	// In E.164 format, e.g. +14155550123.
+	Phone string `json:"phone" binding:"omitempty,e164"`
+}
+
+type MemberController struct {
+	members services.MemberService
+}
+
+func NewMemberController(members services.MemberService) *MemberController {
+	return &MemberController{members: members}
+}
+
+// GET members?page=&page_size=
+//
+// @Summary List library members
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Member,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/members [get]
+func (ctrl *MemberController) FindMembers(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	members, total, err := ctrl.members.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": members, "meta": pagination})
+}
+
+// @Summary Get a library member
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Member ID"
+// @Success 200 {object} object{data=models.Member}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/members/{id} [get]
+func (ctrl *MemberController) FindMember(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	member, err := ctrl.members.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": member})
+}
+
+// @Summary Register a library member
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateMemberInput true "Member"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Member}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/members [post]
+func (ctrl *MemberController) CreateMember(c *gin.Context) {
+	var input CreateMemberInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	member := models.Member{Name: input.Name, Email: input.Email, Phone: input.Phone}
+	err := ctrl.members.Create(c.Request.Context(), &member)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		c.Error(apierrors.Conflict("A member with this email already exists!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": member})
+}
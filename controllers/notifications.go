@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type NotificationPreferenceInput struct {
+	// Where the member is notified; empty to stop notifying them.
+	Channels []string `json:"channels" binding:"required,max=3,unique,dive,oneof=email webhook console"`
+	// Needed by the webhook channel, which POSTs notifications to it.
+	WebhookURL string `json:"webhook_url" binding:"omitempty,http_url,max=2048"`
+}
+
+type NotificationController struct {
+	notifications services.NotificationService
+}
+
+func NewNotificationController(notifications services.NotificationService) *NotificationController {
+	return &NotificationController{notifications: notifications}
+}
+
+// GET members/:id/notification-preferences
+//
+// @Summary Get how a member is notified
+// @Description Members who haven't chosen get the configured default channels (NOTIFICATIONS_DEFAULT_CHANNELS).
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Member ID"
+// @Success 200 {object} object{data=models.NotificationPreference}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/members/{id}/notification-preferences [get]
+func (ctrl *NotificationController) FindPreference(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	preference, err := ctrl.notifications.Preference(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": preference})
+}
+
+// PUT members/:id/notification-preferences
+//
+// @Summary Choose how a member is notified
+// @Description Overdue loans are notified once a day, and ready holds once, through each channel: email, webhook (POSTed as JSON to webhook_url) or console (logged for staff).
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Member ID"
+// @Param input body controllers.NotificationPreferenceInput true "Preferences"
+// @Success 200 {object} object{data=models.NotificationPreference}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/members/{id}/notification-preferences [put]
+func (ctrl *NotificationController) UpdatePreference(c *gin.Context) {
+	id, ok := pathUUID(c, "id")
+	if !ok {
+		return
+	}
+	var input NotificationPreferenceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	preference := models.NotificationPreference{MemberID: id, Channels: models.StringList(input.Channels), WebhookURL: input.WebhookURL}
+	err := ctrl.notifications.SetPreference(c.Request.Context(), &preference)
+	if errors.Is(err, services.ErrWebhookURLRequired) {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": preference})
+}
+
+// GET notifications?member_id=&loan_id=&hold_id=&kind=&page=&page_size=
+//
+// @Summary List the notifications sent to members
+// @Description Newest first, one per channel, including those that failed to be sent.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param member_id query string false "Only those sent to this member"
+// @Param loan_id query string false "Only those about this loan"
+// @Param hold_id query string false "Only those about this hold"
+// @Param kind query string false "Only those of this kind: loan.overdue or hold.ready"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.SentNotification,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/notifications [get]
+func (ctrl *NotificationController) FindNotifications(c *gin.Context) {
+	filter := repositories.NotificationFilter{Kind: c.Query("kind")}
+	for _, param := range []struct {
+		name, invalid string
+		id            *uuid.UUID
+	}{
+		{"member_id", "Invalid member_id %q; it must be a member ID.", &filter.MemberID},
+		{"loan_id", "Invalid loan_id %q; it must be a loan ID.", &filter.LoanID},
+		{"hold_id", "Invalid hold_id %q; it must be a hold ID.", &filter.HoldID},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		id, err := uuid.Parse(raw)
+		if err != nil {
+			c.Error(apierrors.Validation(param.invalid).WithArgs(raw))
+			return
+		}
+		*param.id = id
+	}
+	pagination := paginationFromQuery(c)
+
+	notifications, total, err := ctrl.notifications.List(c.Request.Context(), filter, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": notifications, "meta": pagination})
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// Reads page and page_size from the query string, falling back to defaults
+// and capping page_size at maxPageSize.
+func paginationFromQuery(c *gin.Context) Pagination {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	pageSize, err := strconv.Atoi(c.Query("page_size"))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	return Pagination{Page: page, PageSize: pageSize}
+}
+
+// Reads ?limit= for lists that aren't paginated, defaulting to def. Values
+// outside 1 to max are a validation error, reported on c.
+func limitFromQuery(c *gin.Context, def, max int) (int, bool) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 || n > max {
+		c.Error(apierrors.Validation("limit must be between 1 and %d").WithArgs(max))
+		return 0, false
+	}
+	return n, true
+}
+
+func (p Pagination) Offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+func (p *Pagination) SetTotal(total int64) {
+	p.Total = total
+	p.TotalPages = int((total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+// CursorPagination is the meta of cursor-paginated lists. NextCursor is
+// null on the last page.
+type CursorPagination struct {
+	PageSize   int     `json:"page_size"`
+	NextCursor *string `json:"next_cursor"`
+}
+
+// Cursors are opaque to clients: the JSON of the repository's cursor,
+// base64url-encoded so it fits in a query string as is.
+func encodeCursor(cursor any) (string, error) {
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(raw string, cursor any) error {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, cursor)
+}
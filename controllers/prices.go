@@ -0,0 +1,48 @@
+package controllers
+
+import (
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/gin-gonic/gin"
+)
+
+// convertToFromQuery reads ?convert_to=, "" when absent. A selection of
+// fields gets the columns the conversion is made from.
+func convertToFromQuery(c *gin.Context, sel *bookSelection) (string, bool) {
+	raw := c.Query("convert_to")
+	if raw == "" {
+		return "", true
+	}
+	currency := money.Normalize(raw)
+	if !money.Valid(currency) {
+		c.Error(apierrors.Validation("convert_to must be an ISO 4217 currency code, e.g. EUR."))
+		return "", false
+	}
+	if len(sel.columns) > 0 {
+		for _, column := range []string{"price", "currency"} {
+			if !slices.Contains(sel.columns, column) {
+				sel.columns = append(sel.columns, column)
+			}
+		}
+	}
+	return currency, true
+}
+
+// convertPrices converts the prices of books to currency, unless it's "".
+// It reports whether it succeeded; if not, the error is already set on c.
+func (ctrl *BookController) convertPrices(c *gin.Context, books []models.Book, currency string) bool {
+	if currency == "" {
+		return true
+	}
+	if err := ctrl.currencies.ConvertPrices(c.Request.Context(), books, currency); err != nil {
+		slog.WarnContext(c.Request.Context(), "price conversion failed", "currency", currency, "error", err)
+		c.Error(apierrors.New(http.StatusBadGateway, "The exchange rates could not be fetched; try again later."))
+		return false
+	}
+	return true
+}
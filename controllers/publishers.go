@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreatePublisherInput struct {
+	Name    string `json:"name" binding:"required,min=2,max=255"`
+	Website string `json:"website" binding:"omitempty,url,max=255"`
+}
+
+type UpdatePublisherInput struct {
+	Name    string `json:"name" binding:"omitempty,min=2,max=255"`
+	Website string `json:"website" binding:"omitempty,url,max=255"`
+}
+
+// PublisherController serves publishers through a CRUDController. Its endpoints are
+// those of the CRUDController, declared again only to be documented.
+type PublisherController struct {
+	*CRUDController[models.Publisher, CreatePublisherInput, UpdatePublisherInput]
+}
+
+func NewPublisherController(publishers services.PublisherService) *PublisherController {
+	return &PublisherController{NewCRUDController[models.Publisher](publishers, CRUDResource[models.Publisher, CreatePublisherInput, UpdatePublisherInput]{
+		New: func(input CreatePublisherInput) models.Publisher {
+			return models.Publisher{Name: input.Name, Website: input.Website}
+		},
+		Changes: func(input UpdatePublisherInput) models.Publisher {
+			return models.Publisher{Name: input.Name, Website: input.Website}
+		},
+		CreatedStatus: http.StatusCreated,
+		Error: func(err error) error {
+			if errors.Is(err, services.ErrPublisherHasBooks) {
+				return apierrors.Conflict("Publisher still has books; delete them or give them another publisher first.")
+			}
+			return err
+		},
+	})}
+}
+
+// GET publishers?page=&page_size=
+//
+// @Summary List publishers
+// @Tags publishers
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Publisher,meta=controllers.Pagination}
+// @Router /api/v1/publishers [get]
+func (ctrl *PublisherController) List(c *gin.Context) { ctrl.CRUDController.List(c) }
+
+// @Summary Get a publisher
+// @Tags publishers
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path int true "Publisher ID"
+// @Success 200 {object} object{data=models.Publisher}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/publishers/{id} [get]
+func (ctrl *PublisherController) Get(c *gin.Context) { ctrl.CRUDController.Get(c) }
+
+// @Summary Create a publisher
+// @Tags publishers
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreatePublisherInput true "Publisher"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Publisher}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/publishers [post]
+func (ctrl *PublisherController) Create(c *gin.Context) { ctrl.CRUDController.Create(c) }
+
+// @Summary Update a publisher
+// @Tags publishers
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Publisher ID"
+// @Param input body controllers.UpdatePublisherInput true "Publisher"
+// @Success 200 {object} object{data=models.Publisher}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/publishers/{id} [put]
+func (ctrl *PublisherController) Update(c *gin.Context) { ctrl.CRUDController.Update(c) }
+
+// @Summary Delete a publisher
+// @Tags publishers
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Publisher ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/publishers/{id} [delete]
+func (ctrl *PublisherController) Delete(c *gin.Context) { ctrl.CRUDController.Delete(c) }
@@ -0,0 +1,36 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/querystats"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+)
+
+type QueryStatsController struct{}
+
+func NewQueryStatsController() *QueryStatsController {
+	return &QueryStatsController{}
+}
+
+// @Summary Get a summary of recent database queries
+// @Description The queries run over the configured window, grouped by shape with their literals replaced by ?, the costliest by total time first, and the latest queries at least as slow as the configured threshold. Each instance keeps its own summary.
+// @Tags stats
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param limit query int false "How many queries to list (default 20, max 100)"
+// @Success 200 {object} object{data=querystats.Summary}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/admin/queries [get]
+func (ctrl *QueryStatsController) GetQueryStats(c *gin.Context) {
+	limit, ok := limitFromQuery(c, 20, 100)
+	if !ok {
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": querystats.Snapshot(limit)})
+}
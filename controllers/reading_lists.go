@@ -0,0 +1,421 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateReadingListInput struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+type UpdateReadingListInput struct {
+	Name        string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+// SharedReadingList is a shared list as anyone holding its token sees it.
+type SharedReadingList struct {
+	models.ReadingList
+	Books []models.ReadingListBook `json:"books"`
+}
+
+type ReadingListController struct {
+	lists services.ReadingListService
+}
+
+func NewReadingListController(lists services.ReadingListService) *ReadingListController {
+	return &ReadingListController{lists: lists}
+}
+
+// GET me/lists?page=&page_size=
+//
+// @Summary List my reading lists
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.ReadingList,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/me/lists [get]
+func (ctrl *ReadingListController) FindReadingLists(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	lists, total, err := ctrl.lists.List(c.Request.Context(), c.GetUint(middlewares.UserIDKey), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": lists, "meta": pagination})
+}
+
+// @Summary Get one of my reading lists
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Success 200 {object} object{data=models.ReadingList}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id} [get]
+func (ctrl *ReadingListController) FindReadingList(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := ctrl.lists.Get(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": list})
+}
+
+// @Summary Create a reading list
+// @Tags reading lists
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateReadingListInput true "Reading list"
+// @Success 201 {object} object{data=models.ReadingList}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/me/lists [post]
+func (ctrl *ReadingListController) CreateReadingList(c *gin.Context) {
+	var input CreateReadingListInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	list := models.ReadingList{UserID: c.GetUint(middlewares.UserIDKey), Name: input.Name, Description: input.Description}
+	if err := ctrl.lists.Create(c.Request.Context(), &list); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusCreated, gin.H{"data": list})
+}
+
+// @Summary Update a reading list
+// @Tags reading lists
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Param input body controllers.UpdateReadingListInput true "Reading list"
+// @Success 200 {object} object{data=models.ReadingList}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id} [put]
+func (ctrl *ReadingListController) UpdateReadingList(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input UpdateReadingListInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	list, err := ctrl.lists.Update(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id, models.ReadingList{Name: input.Name, Description: input.Description})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": list})
+}
+
+// Deleting a list leaves its books alone.
+//
+// @Summary Delete a reading list
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id} [delete]
+func (ctrl *ReadingListController) DeleteReadingList(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.lists.Delete(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// GET me/lists/:id/books
+//
+// @Summary List the books on one of my reading lists
+// @Description In the order they were added, with their authors. Deleted books are left out.
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Success 200 {object} object{data=[]models.ReadingListBook}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id}/books [get]
+func (ctrl *ReadingListController) FindReadingListBooks(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	books, err := ctrl.lists.Books(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": books})
+}
+
+// POST me/lists/:id/books/:book_id
+//
+// @Summary Add a book to one of my reading lists
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Param book_id path string true "Book ID"
+// @Success 201 {object} object{data=models.ReadingListBook}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id}/books/{book_id} [post]
+func (ctrl *ReadingListController) AddReadingListBook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	bookID, ok := pathUUID(c, "book_id")
+	if !ok {
+		return
+	}
+
+	entry, err := ctrl.lists.AddBook(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id, bookID)
+	switch {
+	case errors.Is(err, services.ErrUnknownBook):
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	case errors.Is(err, services.ErrBookInList):
+		c.Error(apierrors.Conflict("The book is already on this list."))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusCreated, gin.H{"data": entry})
+}
+
+// DELETE me/lists/:id/books/:book_id
+//
+// @Summary Remove a book from one of my reading lists
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id}/books/{book_id} [delete]
+func (ctrl *ReadingListController) RemoveReadingListBook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	bookID, ok := pathUUID(c, "book_id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.lists.RemoveBook(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id, bookID); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// POST me/lists/:id/share
+//
+// @Summary Share one of my reading lists
+// @Description Gives the list a new share_token, with which anyone can read it at GET /api/v1/shared/lists/{token}. Sharing again replaces the token, so links handed out before stop working.
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Success 200 {object} object{data=models.ReadingList}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id}/share [post]
+func (ctrl *ReadingListController) ShareReadingList(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := ctrl.lists.Share(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": list})
+}
+
+// DELETE me/lists/:id/share
+//
+// @Summary Stop sharing one of my reading lists
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Reading list ID"
+// @Success 200 {object} object{data=models.ReadingList}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/lists/{id}/share [delete]
+func (ctrl *ReadingListController) UnshareReadingList(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	list, err := ctrl.lists.Unshare(c.Request.Context(), c.GetUint(middlewares.UserIDKey), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": list})
+}
+
+// GET shared/lists/:token
+//
+// @Summary Read a shared reading list
+// @Description The list and its books, for anyone holding the token it was shared with.
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param token path string true "Share token"
+// @Success 200 {object} object{data=controllers.SharedReadingList}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/shared/lists/{token} [get]
+func (ctrl *ReadingListController) FindSharedReadingList(c *gin.Context) {
+	list, books, err := ctrl.lists.Shared(c.Request.Context(), c.Param("token"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": SharedReadingList{ReadingList: *list, Books: books}})
+}
+
+// GET me/favorites?page=&page_size=
+//
+// @Summary List my favorite books
+// @Description Latest first. Deleted books are left out.
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Favorite,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/me/favorites [get]
+func (ctrl *ReadingListController) FindFavorites(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	favorites, total, err := ctrl.lists.Favorites(c.Request.Context(), c.GetUint(middlewares.UserIDKey), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": favorites, "meta": pagination})
+}
+
+// PUT me/favorites/:book_id
+//
+// @Summary Mark a book as a favorite
+// @Description Marking a favorite again changes nothing.
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} object{data=models.Favorite}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/favorites/{book_id} [put]
+func (ctrl *ReadingListController) AddFavorite(c *gin.Context) {
+	bookID, ok := pathUUID(c, "book_id")
+	if !ok {
+		return
+	}
+
+	favorite, err := ctrl.lists.AddFavorite(c.Request.Context(), c.GetUint(middlewares.UserIDKey), bookID)
+	if errors.Is(err, services.ErrUnknownBook) {
+		c.Error(apierrors.NotFound("Record not found!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": favorite})
+}
+
+// DELETE me/favorites/:book_id
+//
+// @Summary Unmark a favorite book
+// @Tags reading lists
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/me/favorites/{book_id} [delete]
+func (ctrl *ReadingListController) RemoveFavorite(c *gin.Context) {
+	bookID, ok := pathUUID(c, "book_id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.lists.RemoveFavorite(c.Request.Context(), c.GetUint(middlewares.UserIDKey), bookID); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type RecommendationController struct {
+	recommendations services.RecommendationService
+}
+
+func NewRecommendationController(recommendations services.RecommendationService) *RecommendationController {
+	return &RecommendationController{recommendations: recommendations}
+}
+
+// GET books/:id/similar?limit=
+//
+// @Summary List books similar to a book
+// @Description Scored by the categories, tags and author the books share, and by how many members borrowed both. Scores are recomputed by the refresh-recommendations job, so a book added since its last run has none.
+// @Tags recommendations
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path string true "Book ID"
+// @Param limit query int false "How many books to return (default 10, max 50)"
+// @Success 200 {object} object{data=[]repositories.ScoredBook}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/similar [get]
+func (ctrl *RecommendationController) FindSimilarBooks(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	limit, ok := limitFromQuery(c, 10, services.MaxSimilarBooks)
+	if !ok {
+		return
+	}
+
+	books, err := ctrl.recommendations.Similar(c.Request.Context(), id, limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": books})
+}
+
+// GET me/recommendations?limit=
+//
+// @Summary Recommend books to me
+// @Description The books most similar to my favorites, the books on my reading lists and those I rated 4 or more, leaving out the books I have already come across.
+// @Tags recommendations
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param limit query int false "How many books to return (default 10, max 50)"
+// @Success 200 {object} object{data=[]repositories.ScoredBook}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/me/recommendations [get]
+func (ctrl *RecommendationController) FindRecommendations(c *gin.Context) {
+	limit, ok := limitFromQuery(c, 10, services.MaxSimilarBooks)
+	if !ok {
+		return
+	}
+
+	books, err := ctrl.recommendations.ForUser(c.Request.Context(), c.GetUint(middlewares.UserIDKey), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": books})
+}
@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/reports"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// ReportController queues catalog reports as PDF exports, which are polled
+// and downloaded like any other export.
+type ReportController struct {
+	exports    services.ExportService
+	categories services.CategoryService
+}
+
+func NewReportController(exports services.ExportService, categories services.CategoryService) *ReportController {
+	return &ReportController{exports: exports, categories: categories}
+}
+
+// GET reports/catalog.pdf accepts the same filters as GET books.
+//
+// @Summary Generate a printable catalog report
+// @Description Queues a PDF of the books matching the filters, with their covers, a page of counts and the start of each description; poll the export the Location header points at, then download the file from its download_url. Every request queues a new report.
+// @Tags reports
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param author query string false "Exact author name"
+// @Param author_id query int false "Author ID"
+// @Param publisher_id query int false "Publisher ID"
+// @Param category_id query int false "Category ID"
+// @Param tag query string false "Tag name"
+// @Param title_contains query string false "Substring of the title"
+// @Param year_gte query int false "Minimum publication year"
+// @Param year_lte query int false "Maximum publication year"
+// @Success 202 {object} object{data=controllers.ExportStatus}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/reports/catalog.pdf [get]
+func (ctrl *ReportController) CatalogReport(c *gin.Context) {
+	filter, err := bookFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	ctrl.queue(c, filter)
+}
+
+// GET reports/categories/:id/catalog.pdf
+//
+// @Summary Generate a printable report of a category
+// @Description Queues a PDF of the books in the category, as GET /reports/catalog.pdf does.
+// @Tags reports
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Category ID"
+// @Success 202 {object} object{data=controllers.ExportStatus}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/reports/categories/{id}/catalog.pdf [get]
+func (ctrl *ReportController) CategoryReport(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	if _, err := ctrl.categories.Get(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+	ctrl.queue(c, repositories.BookFilter{CategoryID: id})
+}
+
+func (ctrl *ReportController) queue(c *gin.Context, filter repositories.BookFilter) {
+	export, err := ctrl.exports.Create(c.Request.Context(), c.GetUint(middlewares.UserIDKey), reports.PDF, filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	c.Header("Location", exportPath(export))
+	render.Respond(c, http.StatusAccepted, gin.H{"data": exportStatus(export)})
+}
+
+// WriteExport writes the files of the export jobs: spreadsheets as GET
+// books/export serves them and PDF catalog reports.
+func WriteExport(books services.BookService, covers services.CoverService, categories services.CategoryService) services.ExportWriter {
+	sheet := WriteBookExport(books)
+	return func(ctx context.Context, format string, filter repositories.BookFilter, out io.Writer) (int, error) {
+		if format == reports.PDF {
+			return writeCatalogReport(ctx, books, covers, categories, filter, out)
+		}
+		return sheet(ctx, format, filter, out)
+	}
+}
+
+// writeCatalogReport lays out the books matching filter by title, titled
+// after the category when the filter is by one.
+func writeCatalogReport(ctx context.Context, books services.BookService, covers services.CoverService, categories services.CategoryService, filter repositories.BookFilter, out io.Writer) (int, error) {
+	catalog := reports.Catalog{Title: "Catalog", GeneratedAt: time.Now()}
+	if filter.CategoryID != 0 {
+		category, err := categories.Get(ctx, filter.CategoryID)
+		if err != nil {
+			return 0, err
+		}
+		catalog.Title = "Catalog: " + category.Name
+	}
+
+	err := books.Export(ctx, filter, func(page []models.Book) error {
+		catalog.Books = append(catalog.Books, page...)
+		return nil
+	}, "Author", "Publisher", "Categories")
+	if err != nil {
+		return 0, err
+	}
+	sort.SliceStable(catalog.Books, func(i, j int) bool {
+		return strings.ToLower(catalog.Books[i].Title) < strings.ToLower(catalog.Books[j].Title)
+	})
+
+	catalog.Cover = func(book *models.Book) (*reports.Cover, error) {
+		if book.CoverThumbnailKey == "" {
+			return nil, nil
+		}
+		obj, err := covers.Open(ctx, book.ID, true)
+		if errors.Is(err, services.ErrNoCover) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer obj.Close()
+		data, err := io.ReadAll(obj)
+		if err != nil {
+			return nil, err
+		}
+		return &reports.Cover{Data: data, ContentType: obj.ContentType}, nil
+	}
+	return len(catalog.Books), reports.WriteCatalog(out, catalog)
+}
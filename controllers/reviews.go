@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateReviewInput struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=5000"`
+}
+
+type ReviewController struct {
+	reviews services.ReviewService
+}
+
+func NewReviewController(reviews services.ReviewService) *ReviewController {
+	return &ReviewController{reviews: reviews}
+}
+
+// GET books/:id/reviews?page=&page_size=
+//
+// @Summary List a book's reviews
+// @Tags reviews
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Review,meta=controllers.Pagination}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/reviews [get]
+func (ctrl *ReviewController) FindReviews(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	reviews, total, err := ctrl.reviews.List(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": reviews, "meta": pagination})
+}
+
+// POST books/:id/reviews
+// Any signed-in user may review a book, once.
+//
+// @Summary Review a book
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.CreateReviewInput true "Review"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Review}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/reviews [post]
+func (ctrl *ReviewController) CreateReview(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input CreateReviewInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	review := models.Review{BookID: id, UserID: c.GetUint(middlewares.UserIDKey), Rating: input.Rating, Comment: input.Comment}
+	err := ctrl.reviews.Create(c.Request.Context(), &review)
+	if errors.Is(err, services.ErrAlreadyReviewed) {
+		c.Error(apierrors.Conflict("You have already reviewed this book!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": review})
+}
@@ -0,0 +1,272 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+type CreateSeriesInput struct {
+	Name        string `json:"name" binding:"required,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+type UpdateSeriesInput struct {
+	Name        string `json:"name" binding:"omitempty,min=1,max=255"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+type AddSeriesBookInput struct {
+	BookID string `json:"book_id" binding:"required,uuid" format:"uuid"`
+	Volume int    `json:"volume" binding:"required,min=1"`
+}
+
+type SeriesController struct {
+	series services.SeriesService
+}
+
+func NewSeriesController(series services.SeriesService) *SeriesController {
+	return &SeriesController{series: series}
+}
+
+// GET series?page=&page_size=
+//
+// @Summary List series
+// @Tags series
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Series,meta=controllers.Pagination}
+// @Router /api/v1/series [get]
+func (ctrl *SeriesController) FindSeries(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	series, total, err := ctrl.series.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": series, "meta": pagination})
+}
+
+// @Summary Get a series
+// @Tags series
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path int true "Series ID"
+// @Success 200 {object} object{data=models.Series}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/series/{id} [get]
+func (ctrl *SeriesController) FindOneSeries(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	series, err := ctrl.series.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": series})
+}
+
+// @Summary Create a series
+// @Tags series
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateSeriesInput true "Series"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Series}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/series [post]
+func (ctrl *SeriesController) CreateSeries(c *gin.Context) {
+	var input CreateSeriesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	series := models.Series{Name: input.Name, Description: input.Description}
+	if err := ctrl.series.Create(c.Request.Context(), &series); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusCreated, gin.H{"data": series})
+}
+
+// @Summary Update a series
+// @Tags series
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Series ID"
+// @Param input body controllers.UpdateSeriesInput true "Series"
+// @Success 200 {object} object{data=models.Series}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/series/{id} [put]
+func (ctrl *SeriesController) UpdateSeries(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input UpdateSeriesInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	series, err := ctrl.series.Update(c.Request.Context(), id, models.Series{Name: input.Name, Description: input.Description})
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": series})
+}
+
+// Deleting a series leaves its books alone.
+//
+// @Summary Delete a series
+// @Tags series
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Series ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/series/{id} [delete]
+func (ctrl *SeriesController) DeleteSeries(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.series.Delete(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// GET series/:id/books
+//
+// @Summary List the books of a series in volume order
+// @Description Every volume of the series, first to last, with its book and the book's author. Deleted books are left out.
+// @Tags series
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param id path int true "Series ID"
+// @Success 200 {object} object{data=[]models.SeriesVolume}
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/series/{id}/books [get]
+func (ctrl *SeriesController) FindSeriesBooks(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	volumes, err := ctrl.series.Volumes(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": volumes})
+}
+
+// POST series/:id/books
+//
+// @Summary Add a book to a series
+// @Description Makes the book the given volume of the series. A book is in a series once, and each volume number is taken by one book.
+// @Tags series
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Series ID"
+// @Param input body controllers.AddSeriesBookInput true "Book and volume number"
+// @Success 201 {object} object{data=models.SeriesVolume}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/series/{id}/books [post]
+func (ctrl *SeriesController) AddSeriesBook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input AddSeriesBookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	volume, err := ctrl.series.AddBook(c.Request.Context(), id, uuid.MustParse(input.BookID), input.Volume)
+	switch {
+	case errors.Is(err, services.ErrUnknownBook):
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	case errors.Is(err, services.ErrBookInSeries):
+		c.Error(apierrors.Conflict("The book is already in this series; remove it first to change its volume."))
+		return
+	case errors.Is(err, services.ErrVolumeTaken):
+		c.Error(apierrors.Conflict("Another book of this series is already that volume."))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusCreated, gin.H{"data": volume})
+}
+
+// DELETE series/:id/books/:book_id
+//
+// @Summary Remove a book from a series
+// @Tags series
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Series ID"
+// @Param book_id path string true "Book ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/series/{id}/books/{book_id} [delete]
+func (ctrl *SeriesController) RemoveSeriesBook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	bookID, ok := pathUUID(c, "book_id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.series.RemoveBook(c.Request.Context(), id, bookID); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type StatsController struct {
+	stats services.StatsService
+}
+
+func NewStatsController(stats services.StatsService) *StatsController {
+	return &StatsController{stats: stats}
+}
+
+// @Summary Get catalog and lending statistics
+// @Description Counts of books by category and by month added over the last year, the ten top-rated and most-borrowed books, and totals of books, authors and active and overdue loans. May be cached for up to the configured stats TTL.
+// @Tags stats
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Success 200 {object} object{data=services.Stats}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/admin/stats [get]
+func (ctrl *StatsController) GetStats(c *gin.Context) {
+	stats, err := ctrl.stats.Get(c.Request.Context())
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": stats})
+}
@@ -0,0 +1,253 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type AdjustStockInput struct {
+	// Copies added (positive) or written off (negative).
+	Delta int `json:"delta" binding:"required,min=-10000,max=10000"`
+	// The branch whose copies change; without one the unassigned copies do.
+	BranchID *uint `json:"branch_id"`
+}
+
+type TransferStockInput struct {
+	// Where the copies are taken from and moved to; null for the unassigned
+	// copies.
+	FromBranchID *uint  `json:"from_branch_id"`
+	ToBranchID   *uint  `json:"to_branch_id"`
+	Copies       int    `json:"copies" binding:"required,min=1,max=10000"`
+	Note         string `json:"note" binding:"max=500"`
+}
+
+type StockController struct {
+	stock services.StockService
+}
+
+func NewStockController(stock services.StockService) *StockController {
+	return &StockController{stock: stock}
+}
+
+// POST books/:id/stock/adjust
+// Copies on loan can't be written off until they are returned.
+//
+// @Summary Adjust a book's stock
+// @Description Changes the copies of branch_id, or the unassigned copies without one.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.AdjustStockInput true "Change in copies owned"
+// @Success 200 {object} object{data=models.Book}
+// @Header 200 {string} ETag "Version of the book"
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/stock/adjust [post]
+func (ctrl *StockController) AdjustStock(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input AdjustStockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	book, err := ctrl.stock.Adjust(c.Request.Context(), id, input.BranchID, input.Delta)
+	if errors.Is(err, repositories.ErrCopiesOnLoan) {
+		c.Error(apierrors.Conflict("Not enough copies on the shelf; copies on loan can't be removed."))
+		return
+	}
+	if errors.Is(err, services.ErrUnknownBranch) {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	c.Header("ETag", book.ETag())
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// GET books/:id/availability?branch=
+//
+// @Summary Get how many copies of a book can be borrowed
+// @Description Without a branch, the book's numbers are broken down by branch; copies at none are unassigned.
+// @Tags lending
+// @Produce json
+// @Param id path string true "Book ID"
+// @Param branch query int false "Only the copies of this branch"
+// @Success 200 {object} object{data=services.Availability}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/availability [get]
+func (ctrl *StockController) FindAvailability(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	var branchID *uint
+	if raw := c.Query("branch"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.Error(apierrors.Validation("Invalid branch %q; it must be a branch ID.").WithArgs(raw))
+			return
+		}
+		branch := uint(parsed)
+		branchID = &branch
+	}
+
+	availability, err := ctrl.stock.Availability(c.Request.Context(), id, branchID)
+	if errors.Is(err, services.ErrUnknownBranch) {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": availability})
+}
+
+// POST books/:id/transfers
+//
+// @Summary Transfer copies of a book between branches
+// @Description Only copies on the shelves can be moved; null branches stand for the unassigned copies.
+// @Tags lending
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.TransferStockInput true "Copies moved"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.StockTransfer}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/transfers [post]
+func (ctrl *StockController) TransferStock(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input TransferStockInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	transfer := models.StockTransfer{BookID: id, FromBranchID: input.FromBranchID, ToBranchID: input.ToBranchID, Copies: input.Copies, Note: input.Note}
+	if userID := c.GetUint(middlewares.UserIDKey); userID != 0 {
+		transfer.UserID = &userID
+	}
+	err := ctrl.stock.Transfer(c.Request.Context(), &transfer)
+	switch {
+	case errors.Is(err, services.ErrSameBranch):
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	case errors.Is(err, services.ErrUnknownBranch):
+		c.Error(apierrors.Validation("from_branch_id and to_branch_id must reference existing branches"))
+		return
+	case errors.Is(err, repositories.ErrNotEnoughCopies):
+		c.Error(apierrors.Conflict("Not enough copies on the shelves of the branch to transfer!"))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": transfer})
+}
+
+// GET books/:id/transfers?page=&page_size=
+//
+// @Summary List the transfers of a book's copies
+// @Description Newest first.
+// @Tags lending
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.StockTransfer,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/transfers [get]
+func (ctrl *StockController) FindBookTransfers(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	transfers, total, err := ctrl.stock.BookTransfers(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": transfers, "meta": pagination})
+}
+
+// GET branches/:id/transfers?page=&page_size=
+//
+// @Summary List the transfers from or to a branch
+// @Description Newest first.
+// @Tags branches
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Branch ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.StockTransfer,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/branches/{id}/transfers [get]
+func (ctrl *StockController) FindBranchTransfers(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	transfers, total, err := ctrl.stock.BranchTransfers(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": transfers, "meta": pagination})
+}
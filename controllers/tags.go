@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type TagBookInput struct {
+	Tags []string `json:"tags" binding:"required,min=1,max=20"`
+}
+
+type RenameTagInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type MergeTagInput struct {
+	// The tag the books move to; it must exist.
+	Into string `json:"into" binding:"required"`
+}
+
+type TagController struct {
+	tags services.TagService
+}
+
+func NewTagController(tags services.TagService) *TagController {
+	return &TagController{tags: tags}
+}
+
+// GET tags?starts_with=&limit=
+//
+// @Summary Autocomplete tags
+// @Description The tags starting with the given prefix, whatever its case, the most used first, with how many books carry them.
+// @Tags tags
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Param starts_with query string false "Prefix of the tag names (all tags when empty)"
+// @Param limit query int false "How many tags to return (default 10, max 50)"
+// @Success 200 {object} object{data=[]repositories.TagCount}
+// @Failure 400 {object} apierrors.Problem
+// @Router /api/v1/tags [get]
+func (ctrl *TagController) FindTags(c *gin.Context) {
+	limit, ok := limitFromQuery(c, 10, 50)
+	if !ok {
+		return
+	}
+
+	tags, err := ctrl.tags.Autocomplete(c.Request.Context(), c.Query("starts_with"), limit)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": tags})
+}
+
+// POST books/:id/tags
+//
+// @Summary Tag a book
+// @Description Adds the tags to the book, creating those that don't exist yet. Names are lowercased and their whitespace collapsed.
+// @Tags tags
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param input body controllers.TagBookInput true "Tags"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/tags [post]
+func (ctrl *TagController) TagBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	var input TagBookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	book, err := ctrl.tags.TagBook(c.Request.Context(), id, input.Tags)
+	if err != nil {
+		c.Error(tagError(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// DELETE books/:id/tags/:name
+//
+// @Summary Untag a book
+// @Tags tags
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path string true "Book ID"
+// @Param name path string true "Tag name"
+// @Success 200 {object} object{data=models.Book}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/books/{id}/tags/{name} [delete]
+func (ctrl *TagController) UntagBook(c *gin.Context) {
+	id, ok := bookID(c)
+	if !ok {
+		return
+	}
+
+	book, err := ctrl.tags.UntagBook(c.Request.Context(), id, c.Param("name"))
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": book})
+}
+
+// PUT tags/:name
+//
+// @Summary Rename a tag
+// @Description Renames the tag on every book carrying it. Fails if a tag with the new name exists; merge the two instead.
+// @Tags tags
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Tag name"
+// @Param input body controllers.RenameTagInput true "New name"
+// @Success 200 {object} object{data=models.Tag}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/tags/{name} [put]
+func (ctrl *TagController) RenameTag(c *gin.Context) {
+	var input RenameTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	tag, err := ctrl.tags.Rename(c.Request.Context(), c.Param("name"), input.Name)
+	if err != nil {
+		c.Error(tagError(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": tag})
+}
+
+// POST tags/:name/merge
+//
+// @Summary Merge a tag into another
+// @Description Moves every book carrying the tag to the one named by into, then deletes the tag, in one transaction.
+// @Tags tags
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param name path string true "Tag name"
+// @Param input body controllers.MergeTagInput true "Tag to merge into"
+// @Success 200 {object} object{data=models.Tag}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/tags/{name}/merge [post]
+func (ctrl *TagController) MergeTag(c *gin.Context) {
+	var input MergeTagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	tag, err := ctrl.tags.Merge(c.Request.Context(), c.Param("name"), input.Into)
+	if err != nil {
+		c.Error(tagError(err))
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": tag})
+}
+
+func tagError(err error) error {
+	switch {
+	case errors.Is(err, services.ErrInvalidTag), errors.Is(err, services.ErrSameTag):
+		return apierrors.Validation(err.Error())
+	case errors.Is(err, services.ErrTagExists):
+		return apierrors.Conflict("A tag with that name already exists; merge the two instead.")
+	}
+	return err
+}
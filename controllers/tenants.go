@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateTenantInput struct {
+	// Names the tenant in the X-Tenant header and as a subdomain: lowercase
+	// letters, digits and hyphens.
+	Slug string `json:"slug" binding:"required,subdomain"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type TenantController struct {
+	tenants services.TenantService
+}
+
+func NewTenantController(tenants services.TenantService) *TenantController {
+	return &TenantController{tenants: tenants}
+}
+
+// @Summary List tenants
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Tenant,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/tenants [get]
+func (ctrl *TenantController) FindTenants(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	tenants, total, err := ctrl.tenants.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": tenants, "meta": pagination})
+}
+
+// @Summary Get a tenant
+// @Tags tenants
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Tenant ID"
+// @Success 200 {object} object{data=models.Tenant}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/tenants/{id} [get]
+func (ctrl *TenantController) FindTenant(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	tenant, err := ctrl.tenants.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": tenant})
+}
+
+// @Summary Provision a tenant
+// @Description Adds a library with no books, authors or loans yet. Requests for it name its slug in the X-Tenant header, or use it as the subdomain when a base domain is configured.
+// @Tags tenants
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateTenantInput true "Tenant"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=models.Tenant}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/tenants [post]
+func (ctrl *TenantController) CreateTenant(c *gin.Context) {
+	var input CreateTenantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	tenant := models.Tenant{Slug: input.Slug, Name: input.Name}
+	err := ctrl.tenants.Create(c.Request.Context(), &tenant)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		c.Error(apierrors.Conflict("A tenant with this slug already exists!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": tenant})
+}
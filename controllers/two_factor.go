@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type TwoFactorCodeInput struct {
+	// An authenticator code, or for disabling, a recovery code.
+	Code string `json:"code" binding:"required"`
+}
+
+// RecoveryCodes are shown once, when two-factor authentication is enabled.
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type TwoFactorController struct {
+	twoFactor services.TwoFactorService
+}
+
+func NewTwoFactorController(twoFactor services.TwoFactorService) *TwoFactorController {
+	return &TwoFactorController{twoFactor: twoFactor}
+}
+
+// POST /auth/2fa/enable
+//
+// @Summary Start enrolling an authenticator app
+// @Description Returns the TOTP secret, as an otpauth URL and a QR code of it to scan. Two-factor authentication is enabled once /auth/2fa/verify is given a code from the app; enabling again before then starts over with a new secret.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{data=services.TOTPEnrollment}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/auth/2fa/enable [post]
+func (ctrl *TwoFactorController) EnableTwoFactor(c *gin.Context) {
+	enrollment, err := ctrl.twoFactor.Enable(c.Request.Context(), c.GetUint(middlewares.UserIDKey))
+	if errors.Is(err, services.ErrTwoFactorEnabled) {
+		c.Error(apierrors.Conflict("Two-factor authentication is already enabled!"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": enrollment})
+}
+
+// POST /auth/2fa/verify
+//
+// @Summary Confirm the authenticator app and enable two-factor authentication
+// @Description Logging in takes a code from the app from then on. The response has the recovery codes, each usable once in place of a code; they are not shown again.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body controllers.TwoFactorCodeInput true "Code from the app"
+// @Success 200 {object} object{data=controllers.RecoveryCodes}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/auth/2fa/verify [post]
+func (ctrl *TwoFactorController) VerifyTwoFactor(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	codes, err := ctrl.twoFactor.Verify(c.Request.Context(), c.GetUint(middlewares.UserIDKey), input.Code)
+	switch {
+	case errors.Is(err, services.ErrTwoFactorEnabled):
+		c.Error(apierrors.Conflict("Two-factor authentication is already enabled!"))
+		return
+	case errors.Is(err, services.ErrTwoFactorNotEnrolled):
+		c.Error(apierrors.Conflict("No authenticator is being enrolled, start at /auth/2fa/enable."))
+		return
+	case errors.Is(err, services.ErrInvalidSecondFactor):
+		c.Error(apierrors.BadRequest("Invalid authenticator code!"))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": RecoveryCodes{RecoveryCodes: codes}})
+}
+
+// POST /auth/2fa/disable
+//
+// @Summary Disable two-factor authentication
+// @Description Removes the authenticator app and the recovery codes.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param input body controllers.TwoFactorCodeInput true "Authenticator or recovery code"
+// @Success 200 {object} object{data=bool}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/auth/2fa/disable [post]
+func (ctrl *TwoFactorController) DisableTwoFactor(c *gin.Context) {
+	var input TwoFactorCodeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	err := ctrl.twoFactor.Disable(c.Request.Context(), c.GetUint(middlewares.UserIDKey), input.Code)
+	switch {
+	case errors.Is(err, services.ErrTwoFactorDisabled):
+		c.Error(apierrors.Conflict("Two-factor authentication is not enabled!"))
+		return
+	case errors.Is(err, services.ErrInvalidSecondFactor):
+		c.Error(apierrors.BadRequest("Invalid authenticator or recovery code!"))
+		return
+	case err != nil:
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type UsageController struct {
+	usage services.UsageService
+}
+
+func NewUsageController(usage services.UsageService) *UsageController {
+	return &UsageController{usage: usage}
+}
+
+// UsageMeta is the meta of a user's usage: the days it covers and the
+// requests made over them.
+type UsageMeta struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Requests int64  `json:"requests"`
+}
+
+// GET me/usage?from=&to=
+//
+// @Summary List the requests the current user made per day
+// @Description Per day and API key; api_key_id 0 counts the requests made with access tokens. from and to are dates (UTC), both included, and default to the current month. Counts are saved every few seconds, so the latest requests may be missing. Under a quota, responses tell what is left of it in X-Quota-Limit, X-Quota-Remaining and X-Quota-Reset.
+// @Tags usage
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} object{data=[]models.DailyUsage,meta=controllers.UsageMeta}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Router /api/v1/me/usage [get]
+func (ctrl *UsageController) FindMyUsage(c *gin.Context) {
+	filter, ok := usageFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	filter.UserID = c.GetUint(middlewares.UserIDKey)
+
+	usage, err := ctrl.usage.List(c.Request.Context(), filter)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	meta := UsageMeta{From: filter.From, To: filter.To}
+	for _, day := range usage {
+		meta.Requests += day.Requests
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": usage, "meta": meta})
+}
+
+// GET usage?user_id=&from=&to=&page=&page_size=
+//
+// @Summary Report the requests made per user and API key
+// @Description The busiest first; api_key_id 0 counts the requests made with access tokens. from and to are dates (UTC), both included, and default to the current month.
+// @Tags usage
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param user_id query int false "Only this user's requests"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]repositories.UsageTotal,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/usage [get]
+func (ctrl *UsageController) FindUsageReport(c *gin.Context) {
+	filter, ok := usageFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("user_id"); raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.Error(apierrors.Validation("Invalid user_id %q; it must be a user ID.").WithArgs(raw))
+			return
+		}
+		filter.UserID = uint(id)
+	}
+	pagination := paginationFromQuery(c)
+
+	totals, total, err := ctrl.usage.Report(c.Request.Context(), filter, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": totals, "meta": pagination})
+}
+
+// Reads the ?from= and ?to= days of a usage read, which default to the
+// first and last days of the current month.
+func usageFilterFromQuery(c *gin.Context) (repositories.UsageFilter, bool) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	filter := repositories.UsageFilter{
+		From: start.Format(time.DateOnly),
+		To:   start.AddDate(0, 1, -1).Format(time.DateOnly),
+	}
+	for _, bound := range []struct {
+		param, invalid string
+		day            *string
+	}{
+		{"from", "Invalid from %q; it must be a date (YYYY-MM-DD).", &filter.From},
+		{"to", "Invalid to %q; it must be a date (YYYY-MM-DD).", &filter.To},
+	} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		if _, err := time.Parse(time.DateOnly, raw); err != nil {
+			c.Error(apierrors.Validation(bound.invalid).WithArgs(raw))
+			return filter, false
+		}
+		*bound.day = raw
+	}
+	if filter.From > filter.To {
+		c.Error(apierrors.Validation("from must not be after to"))
+		return filter, false
+	}
+	return filter, true
+}
@@ -0,0 +1,300 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+type SetRoleInput struct {
+	Role string `json:"role" binding:"required,oneof=admin reader"`
+}
+
+type SetTenantInput struct {
+	// The tenant to make the user a member of, or null for every tenant.
+	TenantID *uint `json:"tenant_id"`
+}
+
+type UserController struct {
+	users services.UserService
+}
+
+func NewUserController(users services.UserService) *UserController {
+	return &UserController{users: users}
+}
+
+// @Summary Unlock a user account
+// @Description Lifts the lock repeated failed logins put on the account and resets its failed login count. Unlocking an account that isn't locked does nothing.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} object{data=models.User}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/users/{id}/unlock [post]
+func (ctrl *UserController) UnlockUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.users.Unlock(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// GET admin/users?q=&role=&disabled=&page=&page_size=
+//
+// @Summary List users
+// @Description By email address.
+// @Tags users
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param q query string false "Part of the email address"
+// @Param role query string false "Role" Enums(admin, reader)
+// @Param disabled query bool false "Only disabled, or only enabled, accounts"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.User,meta=controllers.Pagination}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/admin/users [get]
+func (ctrl *UserController) FindUsers(c *gin.Context) {
+	filter, err := userFilterFromQuery(c)
+	if err != nil {
+		c.Error(apierrors.Validation(err.Error()))
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	users, total, err := ctrl.users.List(c.Request.Context(), filter, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": users, "meta": pagination})
+}
+
+// @Summary Get a user
+// @Tags users
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} object{data=models.User}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id} [get]
+func (ctrl *UserController) FindUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.users.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// @Summary Change a user's role
+// @Description Demoting an admin revokes the access tokens already issued, which carry the admin role; refreshing them picks up the new one. The last admin of every tenant who can log in can't be demoted.
+// @Tags users
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Param input body controllers.SetRoleInput true "New role"
+// @Success 200 {object} object{data=models.User}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id}/role [put]
+func (ctrl *UserController) SetUserRole(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var input SetRoleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	user, err := ctrl.users.SetRole(c.Request.Context(), id, input.Role)
+	if err != nil {
+		c.Error(userError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "user role changed", "user_id", user.ID, "role", user.Role, "admin_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// @Summary Change the tenant a user is a member of
+// @Description Members of a tenant can only act for it; members of every tenant, with a null tenant_id, can act for any, and only they can manage users and tenants and the rest of the deployment. The access tokens already issued are revoked; refreshing them picks up the new tenant. The last admin of every tenant who can log in can't be made a member of one.
+// @Tags users
+// @Accept json
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Param input body controllers.SetTenantInput true "New tenant"
+// @Success 200 {object} object{data=models.User}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id}/tenant [put]
+func (ctrl *UserController) SetUserTenant(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	var input SetTenantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	user, err := ctrl.users.SetTenant(c.Request.Context(), id, input.TenantID)
+	if err != nil {
+		c.Error(userError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "user tenant changed", "user_id", user.ID, "tenant_id", user.TenantID, "admin_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// @Summary Disable a user account
+// @Description The account can't log in, refresh its tokens or use its API keys; its access and refresh tokens are revoked, so it is logged out everywhere. The last admin of every tenant who can log in can't be disabled. Disabling a disabled account does nothing.
+// @Tags users
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} object{data=models.User}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id}/disable [post]
+func (ctrl *UserController) DisableUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.users.Disable(c.Request.Context(), id)
+	if err != nil {
+		c.Error(userError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "user disabled", "user_id", user.ID, "admin_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// @Summary Enable a disabled user account
+// @Description Enabling an account that isn't disabled does nothing.
+// @Tags users
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} object{data=models.User}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id}/enable [post]
+func (ctrl *UserController) EnableUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	user, err := ctrl.users.Enable(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "user enabled", "user_id", user.ID, "admin_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": user})
+}
+
+// @Summary Delete a user account
+// @Description Deletes the account with its tokens, API keys, sign-in identities, reviews, reading lists and favorites. The audit log keeps the changes the user made. The last admin of every tenant who can log in can't be deleted.
+// @Tags users
+// @Produce json,application/xml,text/csv,application/vnd.api+json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "User ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Router /api/v1/admin/users/{id} [delete]
+func (ctrl *UserController) DeleteUser(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.users.Delete(c.Request.Context(), id); err != nil {
+		c.Error(userError(err))
+		return
+	}
+	slog.InfoContext(c.Request.Context(), "user deleted", "user_id", id, "admin_id", c.GetUint(middlewares.UserIDKey))
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+func userFilterFromQuery(c *gin.Context) (repositories.UserFilter, error) {
+	filter := repositories.UserFilter{Query: c.Query("q"), Role: c.Query("role")}
+	if filter.Role != "" && filter.Role != models.RoleAdmin && filter.Role != models.RoleReader {
+		return filter, fmt.Errorf("invalid role: %q", filter.Role)
+	}
+	if raw := c.Query("disabled"); raw != "" {
+		disabled, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("invalid disabled: %q", raw)
+		}
+		filter.Disabled = &disabled
+	}
+	return filter, nil
+}
+
+func userError(err error) error {
+	if errors.Is(err, services.ErrLastAdmin) {
+		return apierrors.Conflict("This is the last admin of every tenant who can log in; make someone else one first.")
+	}
+	if errors.Is(err, services.ErrUnknownTenant) {
+		return apierrors.Validation("tenant_id must be the ID of a tenant")
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type CreateWebhookInput struct {
+	URL string `json:"url" binding:"required,http_url,max=2048"`
+	// Event types to deliver; all of them when empty.
+	Events []string `json:"events" binding:"omitempty,max=10,dive,oneof=book.created book.updated book.deleted"`
+}
+
+// CreatedWebhook is the only representation of a webhook that includes its
+// secret.
+type CreatedWebhook struct {
+	models.Webhook
+	Secret string `json:"secret"`
+}
+
+type WebhookController struct {
+	webhooks services.WebhookService
+}
+
+func NewWebhookController(webhooks services.WebhookService) *WebhookController {
+	return &WebhookController{webhooks: webhooks}
+}
+
+// @Summary List webhooks
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.Webhook,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Router /api/v1/webhooks [get]
+func (ctrl *WebhookController) FindWebhooks(c *gin.Context) {
+	pagination := paginationFromQuery(c)
+
+	webhooks, total, err := ctrl.webhooks.List(c.Request.Context(), pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": webhooks, "meta": pagination})
+}
+
+// @Summary Get a webhook
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} object{data=models.Webhook}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/webhooks/{id} [get]
+func (ctrl *WebhookController) FindWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	webhook, err := ctrl.webhooks.Get(c.Request.Context(), id)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusOK, gin.H{"data": webhook})
+}
+
+// @Summary Register a webhook
+// @Description Events are POSTed as JSON ({id, type, occurred_at, data}) with the headers X-Webhook-Event, X-Webhook-Delivery (the event ID) and X-Webhook-Signature: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed with the secret>".
+// @Description The response is the only time the secret is shown.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param input body controllers.CreateWebhookInput true "Webhook"
+// @Param Idempotency-Key header string false "Unique key making retries of the request return its first response instead of running it again"
+// @Success 201 {object} object{data=controllers.CreatedWebhook}
+// @Failure 400 {object} apierrors.Problem
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 409 {object} apierrors.Problem
+// @Failure 422 {object} apierrors.Problem
+// @Router /api/v1/webhooks [post]
+func (ctrl *WebhookController) CreateWebhook(c *gin.Context) {
+	var input CreateWebhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.Error(apierrors.Binding(err))
+		return
+	}
+
+	webhook := models.Webhook{URL: input.URL, Events: input.Events}
+	if webhook.Events == nil {
+		webhook.Events = models.StringList{}
+	}
+	err := ctrl.webhooks.Create(c.Request.Context(), &webhook)
+	if errors.Is(err, webhooks.ErrPrivateTarget) {
+		c.Error(apierrors.Validation("url must not point to a loopback, private or link-local address"))
+		return
+	}
+	if err != nil {
+		c.Error(err)
+		return
+	}
+
+	render.Respond(c, http.StatusCreated, gin.H{"data": CreatedWebhook{Webhook: webhook, Secret: webhook.Secret}})
+}
+
+// @Summary Delete a webhook
+// @Description Deleting a webhook also deletes its delivery log.
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} object{data=bool}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/webhooks/{id} [delete]
+func (ctrl *WebhookController) DeleteWebhook(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := ctrl.webhooks.Delete(c.Request.Context(), id); err != nil {
+		c.Error(err)
+		return
+	}
+	render.Respond(c, http.StatusOK, gin.H{"data": true})
+}
+
+// GET webhooks/:id/deliveries?page=&page_size=
+//
+// @Summary List a webhook's delivery attempts
+// @Description Newest first; every attempt, retries included, is listed.
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Security APIKeyAuth
+// @Param id path int true "Webhook ID"
+// @Param page query int false "Page number (default 1)"
+// @Param page_size query int false "Page size (default 20, max 100)"
+// @Success 200 {object} object{data=[]models.WebhookDelivery,meta=controllers.Pagination}
+// @Failure 401 {object} apierrors.Problem
+// @Failure 403 {object} apierrors.Problem
+// @Failure 404 {object} apierrors.Problem
+// @Router /api/v1/webhooks/{id}/deliveries [get]
+func (ctrl *WebhookController) FindWebhookDeliveries(c *gin.Context) {
+	id, ok := pathID(c, "id")
+	if !ok {
+		return
+	}
+	pagination := paginationFromQuery(c)
+
+	deliveries, total, err := ctrl.webhooks.Deliveries(c.Request.Context(), id, pagination.Offset(), pagination.PageSize)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	pagination.SetTotal(total)
+
+	render.Respond(c, http.StatusOK, gin.H{"data": deliveries, "meta": pagination})
+}
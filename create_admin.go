@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/spf13/cobra"
+)
+
+// newCreateAdminCommand builds `create-admin --email E [--password P]`.
+// Without a password, a new account gets a random one, printed once; an
+// existing account keeps its own and is only promoted.
+func newCreateAdminCommand() *cobra.Command {
+	var email, password string
+	cmd := &cobra.Command{
+		Use:   "create-admin",
+		Short: "Create an admin account, or make an existing account an admin",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if n := len(password); n > 0 && (n < 8 || n > 72) {
+				return errors.New("the password must be 8 to 72 characters long")
+			}
+			cfg, err := bootstrap()
+			if err != nil {
+				return err
+			}
+			if err := app.Prepare(cfg); err != nil {
+				return err
+			}
+
+			users := services.NewUserService(repositories.NewUserRepository(models.DB), repositories.NewRefreshTokenRepository(models.DB), repositories.NewTenantRepository(models.DB), nil, cfg.Auth)
+			generated := ""
+			user, created, err := users.CreateAdmin(cmd.Context(), email, password)
+			if errors.Is(err, services.ErrPasswordRequired) {
+				if generated, err = randomPassword(); err != nil {
+					return err
+				}
+				user, created, err = users.CreateAdmin(cmd.Context(), email, generated)
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case generated != "":
+				fmt.Fprintf(cmd.OutOrStdout(), "created admin %s with password %s\n", user.Email, generated)
+			case created:
+				fmt.Fprintf(cmd.OutOrStdout(), "created admin %s\n", user.Email)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "made %s an admin\n", user.Email)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&email, "email", "", "email address of the account (required)")
+	cmd.Flags().StringVar(&password, "password", "", "password to set; a new account gets a random one if empty")
+	cmd.MarkFlagRequired("email")
+	return cmd
+}
+
+func randomPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,42 @@
+// Package docs serves the OpenAPI 3.1 spec generated from the swag
+// annotations on main and the controllers.
+package docs
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:generate go run github.com/swaggo/swag/v2/cmd/swag@v2.0.0-rc6 init --v3.1 --dir ../ --output . --outputTypes json,yaml --parseDependency=false
+
+//go:embed swagger.json
+var spec []byte
+
+// GET /openapi.json
+func Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", spec)
+}
+
+// GET /docs
+func UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(uiPage))
+}
+
+const uiPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Bookstore API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
@@ -0,0 +1,171 @@
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+func TestBookCover(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Voices", AuthorID: author.ID})
+	path := "/api/v1/books/" + book.ID.String() + "/cover"
+	client := srv.Client(t)
+
+	client.Get(path).ExpectProblem(http.StatusNotFound)
+	client.Get(path + "?size=huge").ExpectProblem(http.StatusBadRequest)
+
+	cover := pngImage(t, 600, 800)
+	admin.Upload(path, "file", "cover.png", cover, nil).Expect(http.StatusOK)
+
+	original := client.Get(path).Expect(http.StatusOK)
+	if original.Header.Get("Content-Type") != "image/png" || !bytes.Equal(original.Body, cover) {
+		t.Fatalf("got %d bytes of %s, want the uploaded cover", len(original.Body), original.Header.Get("Content-Type"))
+	}
+	thumbnail, _, err := image.Decode(bytes.NewReader(client.Get(path + "?size=thumbnail").Expect(http.StatusOK).Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bounds := thumbnail.Bounds(); bounds.Dx() > 256 || bounds.Dy() > 256 {
+		t.Fatalf("got a %dx%d thumbnail, want at most 256x256", bounds.Dx(), bounds.Dy())
+	}
+
+	admin.Upload(path, "file", "cover.txt", []byte("not an image"), nil).ExpectProblem(http.StatusUnsupportedMediaType)
+	admin.Upload(path, "other", "cover.png", cover, nil).ExpectProblem(http.StatusBadRequest)
+	admin.Upload(path, "file", "cover.png", make([]byte, controllers.MaxCoverSize+1), nil).ExpectProblem(http.StatusRequestEntityTooLarge)
+	admin.Upload("/api/v1/books/"+missingBook+"/cover", "file", "cover.png", cover, nil).ExpectProblem(http.StatusNotFound)
+	srv.Reader(t).Upload(path, "file", "cover.png", cover, nil).ExpectProblem(http.StatusForbidden)
+	client.Get("/api/v1/books/" + missingBook + "/cover").ExpectProblem(http.StatusNotFound)
+}
+
+func TestBookHistoryAndVersions(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "City of Illusions", AuthorID: author.ID, Year: 1967})
+	path := "/api/v1/books/" + book.ID.String()
+	admin.WithHeader("If-Match", book.ETag()).Patch(path, map[string]any{"title": "City of Illusions (2nd ed.)", "year": 1978}).Expect(http.StatusOK)
+	reader := srv.Reader(t)
+
+	t.Run("history", func(t *testing.T) {
+		var entries []models.AuditLog
+		admin.Get(path + "/history").Expect(http.StatusOK).Data(&entries)
+		if len(entries) < 2 {
+			t.Fatalf("got %d audit entries, want the creation and the update", len(entries))
+		}
+		reader.Get(path + "/history").ExpectProblem(http.StatusForbidden)
+		srv.Client(t).Get(path + "/history").ExpectProblem(http.StatusUnauthorized)
+	})
+
+	t.Run("versions", func(t *testing.T) {
+		var versions []models.BookVersion
+		admin.Get(path + "/versions").Expect(http.StatusOK).Data(&versions)
+		if len(versions) != 2 || versions[0].Version != 2 || versions[1].Version != 1 {
+			t.Fatalf("got %+v, want versions 2 and 1, newest first", versions)
+		}
+		admin.Get("/api/v1/books/" + missingBook + "/versions").ExpectProblem(http.StatusNotFound)
+		reader.Get(path + "/versions").ExpectProblem(http.StatusForbidden)
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		var diff struct {
+			From    uint `json:"from"`
+			To      uint `json:"to"`
+			Changes map[string]struct {
+				From any `json:"from"`
+				To   any `json:"to"`
+			} `json:"changes"`
+		}
+		admin.Get(path + "/versions/1/diff?against=2").Expect(http.StatusOK).Data(&diff)
+		if diff.From != 1 || diff.To != 2 || diff.Changes["title"].From != "City of Illusions" || diff.Changes["title"].To != "City of Illusions (2nd ed.)" {
+			t.Fatalf("got %+v, want the title change", diff)
+		}
+		if _, ok := diff.Changes["description"]; ok {
+			t.Errorf("got %+v, want unchanged fields left out", diff.Changes)
+		}
+		admin.Get(path + "/versions/1/diff").Expect(http.StatusOK)
+		admin.Get(path + "/versions/1/diff?against=nope").ExpectProblem(http.StatusBadRequest)
+		admin.Get(path + "/versions/9/diff").ExpectProblem(http.StatusNotFound)
+		admin.Get(path + "/versions/nope/diff").ExpectProblem(http.StatusNotFound)
+		reader.Get(path + "/versions/1/diff").ExpectProblem(http.StatusForbidden)
+	})
+
+	t.Run("revert", func(t *testing.T) {
+		var reverted models.Book
+		resp := admin.Post(path+"/revert/1", nil).Expect(http.StatusOK)
+		resp.Data(&reverted)
+		if reverted.Title != "City of Illusions" || reverted.Year != 1967 || reverted.Version != 3 {
+			t.Fatalf("got %+v, want version 1's fields as version 3", reverted)
+		}
+		if resp.Header.Get("ETag") != reverted.ETag() {
+			t.Errorf("got ETag %s, want %s", resp.Header.Get("ETag"), reverted.ETag())
+		}
+		admin.Post(path+"/revert/9", nil).ExpectProblem(http.StatusNotFound)
+		admin.Post("/api/v1/books/"+missingBook+"/revert/1", nil).ExpectProblem(http.StatusNotFound)
+		reader.Post(path+"/revert/1", nil).ExpectProblem(http.StatusForbidden)
+	})
+}
+
+func TestBookEvents(t *testing.T) {
+	srv, admin, author := catalog(t)
+	fiction := admin.CreateCategory("Fiction")
+	client := srv.Client(t)
+
+	client.Get("/api/v1/books/events?types=book.exploded").ExpectProblem(http.StatusBadRequest)
+	client.Get("/api/v1/books/events?category_id=nope").ExpectProblem(http.StatusBadRequest)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	resp, err := http.DefaultClient.Do(client.Request(http.MethodGet, "/api/v1/books/events?types="+events.BookCreated+","+events.BookDeleted, nil).WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("got status %d and %s, want an event stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// Only changes made once connected are sent, hence the books are only
+	// created now.
+	lines := bufio.NewScanner(resp.Body)
+	if !lines.Scan() || lines.Text() != "retry: 3000" {
+		t.Fatalf("got %q, want the retry interval first", lines.Text())
+	}
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Four Ways to Forgiveness", AuthorID: author.ID})
+	admin.Post("/api/v1/books/"+book.ID.String()+"/categories", controllers.AttachCategoriesInput{CategoryIDs: []uint{fiction.ID}}).Expect(http.StatusOK)
+	admin.WithHeader("If-Match", "*").Delete("/api/v1/books/" + book.ID.String()).Expect(http.StatusOK)
+
+	var received []string
+	for len(received) < 2 && lines.Scan() {
+		if name, ok := strings.CutPrefix(lines.Text(), "event: "); ok {
+			received = append(received, name)
+		}
+	}
+	if strings.Join(received, ",") != events.BookCreated+","+events.BookDeleted {
+		t.Fatalf("got events %v (%v), want the book created then deleted, without the update", received, lines.Err())
+	}
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
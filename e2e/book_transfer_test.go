@@ -0,0 +1,231 @@
+package e2e
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/lookup"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/testsupport"
+)
+
+func TestBulkBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+
+	var created []controllers.BulkCreateResult
+	admin.Post("/api/v1/books/bulk", []any{
+		controllers.CreateBookInput{Title: "Tehanu", AuthorID: author.ID},
+		map[string]any{"author_id": author.ID},
+		controllers.CreateBookInput{Title: "Orphan", AuthorID: 999},
+	}).Expect(http.StatusMultiStatus).Data(&created)
+	if len(created) != 3 || created[0].Status != http.StatusOK || created[0].Data == nil || created[1].Status != http.StatusBadRequest || created[2].Status != http.StatusBadRequest {
+		t.Fatalf("got %+v, want the first created and the others rejected", created)
+	}
+	tehanu := *created[0].Data
+
+	var more []controllers.BulkCreateResult
+	admin.Post("/api/v1/books/bulk", []any{
+		controllers.CreateBookInput{Title: "Tales from Earthsea", AuthorID: author.ID},
+		controllers.CreateBookInput{Title: "The Other Wind", AuthorID: author.ID},
+	}).Expect(http.StatusOK).Data(&more)
+	if len(more) != 2 || more[0].Status != http.StatusOK || more[1].Status != http.StatusOK {
+		t.Fatalf("got %+v, want both created", more)
+	}
+
+	admin.Post("/api/v1/books/bulk", []any{}).ExpectProblem(http.StatusBadRequest)
+	admin.Post("/api/v1/books/bulk", map[string]any{"title": "Not an array"}).ExpectProblem(http.StatusBadRequest)
+	srv.Reader(t).Post("/api/v1/books/bulk", []any{controllers.CreateBookInput{Title: "Mine", AuthorID: author.ID}}).ExpectProblem(http.StatusForbidden)
+
+	var deleted []controllers.BulkDeleteResult
+	admin.Do(http.MethodDelete, "/api/v1/books/bulk", controllers.BulkDeleteInput{IDs: []string{tehanu.ID.String(), missingBook}}).Expect(http.StatusMultiStatus).Data(&deleted)
+	if len(deleted) != 2 || deleted[0].Status != http.StatusOK || deleted[1].Status != http.StatusNotFound {
+		t.Fatalf("got %+v, want the first deleted and the second missing", deleted)
+	}
+	srv.Client(t).Get("/api/v1/books/" + tehanu.ID.String()).ExpectProblem(http.StatusNotFound)
+
+	admin.Do(http.MethodDelete, "/api/v1/books/bulk", controllers.BulkDeleteInput{IDs: []string{more[0].Data.ID.String()}}).Expect(http.StatusOK)
+	admin.Do(http.MethodDelete, "/api/v1/books/bulk", controllers.BulkDeleteInput{IDs: []string{"not-a-uuid"}}).ExpectProblem(http.StatusBadRequest)
+	admin.Do(http.MethodDelete, "/api/v1/books/bulk", controllers.BulkDeleteInput{}).ExpectProblem(http.StatusBadRequest)
+	srv.Reader(t).Do(http.MethodDelete, "/api/v1/books/bulk", controllers.BulkDeleteInput{IDs: []string{missingBook}}).ExpectProblem(http.StatusForbidden)
+}
+
+func TestImportBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	id := itoa(author.ID)
+
+	t.Run("dry run", func(t *testing.T) {
+		var report controllers.ImportReport
+		admin.Upload("/api/v1/books/import", "file", "books.csv", []byte("title,author_id,year\nThe Beginning Place,"+id+",1980\n"), map[string]string{"dry_run": "true"}).Expect(http.StatusOK).Data(&report)
+		if !report.DryRun || report.Valid != 1 || report.Imported != 0 {
+			t.Fatalf("got %+v, want one valid row and nothing imported", report)
+		}
+	})
+
+	t.Run("imported", func(t *testing.T) {
+		var report controllers.ImportReport
+		admin.Upload("/api/v1/books/import", "file", "books.csv", []byte("Name,Writer,Year\nThe Beginning Place,"+id+",1980\n\n"+"Malafrena,"+id+",1979\n"), map[string]string{"mapping": `{"title":"Name","author_id":"Writer"}`}).Expect(http.StatusOK).Data(&report)
+		if report.Valid != 2 || report.Imported != 2 {
+			t.Fatalf("got %+v, want two rows imported", report)
+		}
+		var listed []models.Book
+		srv.Client(t).Get("/api/v1/books?sort=title").Expect(http.StatusOK).Data(&listed)
+		if len(listed) != 2 || listed[0].Title != "Malafrena" || listed[0].Year != 1979 {
+			t.Fatalf("got %v, want the imported books", titles(listed))
+		}
+	})
+
+	t.Run("invalid rows", func(t *testing.T) {
+		problem := admin.Upload("/api/v1/books/import", "file", "books.csv", []byte("title,author_id,year\n,"+id+",1980\nGood,"+id+",1990\nOrphan,999,nope\n"), nil).ExpectProblem(http.StatusBadRequest)
+		var rows []controllers.ImportRowError
+		if err := json.Unmarshal(problem.Extensions["rows"], &rows); err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 2 || rows[0].Row != 2 || rows[1].Row != 4 {
+			t.Fatalf("got %+v, want rows 2 and 4 reported", rows)
+		}
+		var listed []models.Book
+		srv.Client(t).Get("/api/v1/books").Expect(http.StatusOK).Data(&listed)
+		if len(listed) != 2 {
+			t.Fatalf("got %d books, want nothing more imported", len(listed))
+		}
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		admin.Upload("/api/v1/books/import", "other", "books.csv", []byte("title,author_id\n"), nil).ExpectProblem(http.StatusBadRequest)
+		admin.Upload("/api/v1/books/import", "file", "books.txt", []byte("title,author_id\n"), nil).ExpectProblem(http.StatusBadRequest)
+		admin.Upload("/api/v1/books/import", "file", "books.csv", []byte("name,year\nSomething,2000\n"), nil).ExpectProblem(http.StatusBadRequest)
+		admin.Upload("/api/v1/books/import", "file", "books.csv", []byte("title,author_id\n"), map[string]string{"mapping": "nope"}).ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		admin.Upload("/api/v1/books/import", "file", "books.csv", make([]byte, controllers.MaxImportSize+1), nil).ExpectProblem(http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		srv.Reader(t).Upload("/api/v1/books/import", "file", "books.csv", []byte("title,author_id\nMine,"+id+"\n"), nil).ExpectProblem(http.StatusForbidden)
+		srv.Client(t).Upload("/api/v1/books/import", "file", "books.csv", []byte("title,author_id\nMine,"+id+"\n"), nil).ExpectProblem(http.StatusUnauthorized)
+	})
+}
+
+func TestExportBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	other := admin.CreateAuthor("Octavia E. Butler")
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Eye of the Heron", AuthorID: author.ID, Year: 1978, ISBN: isbnA})
+	admin.CreateBook(controllers.CreateBookInput{Title: "Kindred", AuthorID: other.ID})
+	client := srv.Client(t)
+
+	resp := client.Get("/api/v1/books/export?author_id=" + itoa(author.ID)).Expect(http.StatusOK)
+	if resp.Header.Get("Content-Disposition") != `attachment; filename="books.csv"` {
+		t.Errorf("got Content-Disposition %q", resp.Header.Get("Content-Disposition"))
+	}
+	rows, err := csv.NewReader(strings.NewReader(string(resp.Body))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || strings.Join(rows[0], ",") != strings.Join(controllers.BookExportColumns, ",") {
+		t.Fatalf("got %v, want the header and one book", rows)
+	}
+	if rows[1][0] != book.ID.String() || rows[1][2] != book.Title || rows[1][5] != author.Name || rows[1][7] != isbnA {
+		t.Fatalf("got row %v, want %s", rows[1], book.Title)
+	}
+
+	xlsx := client.Get("/api/v1/books/export?format=xlsx").Expect(http.StatusOK)
+	if !strings.HasPrefix(string(xlsx.Body), "PK") {
+		t.Errorf("got %d bytes not looking like an XLSX file", len(xlsx.Body))
+	}
+	client.Get("/api/v1/books/export?format=pdf").ExpectProblem(http.StatusBadRequest)
+	client.Get("/api/v1/books/export?year_gte=nope").ExpectProblem(http.StatusBadRequest)
+}
+
+func TestLookupBook(t *testing.T) {
+	catalogs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("bibkeys") {
+		case "ISBN:" + isbnA:
+			w.Write([]byte(`{"ISBN:` + isbnA + `":{"title":"The Compass Rose","authors":[{"name":"Ursula K. Le Guin"}],"publishers":[{"name":"Harper & Row"}],"publish_date":"1982"}}`))
+		case "ISBN:" + isbnB:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer catalogs.Close()
+	srv := testsupport.Start(t, func(cfg *config.Config) {
+		cfg.Lookup.OpenLibraryURL = catalogs.URL
+		cfg.Lookup.Retries = 0
+		cfg.Lookup.CacheTTL = 0
+	})
+	admin := srv.Admin(t)
+
+	var metadata lookup.Metadata
+	admin.Post("/api/v1/books/lookup", controllers.LookupInput{ISBN: isbnA}).Expect(http.StatusOK).Data(&metadata)
+	if metadata.Title != "The Compass Rose" || metadata.Year != 1982 || metadata.Publisher != "Harper & Row" || len(metadata.Authors) != 1 || metadata.Source != "openlibrary" {
+		t.Fatalf("got %+v", metadata)
+	}
+	admin.Post("/api/v1/books/lookup", controllers.LookupInput{ISBN: "9781861972712"}).ExpectProblem(http.StatusNotFound)
+	admin.Post("/api/v1/books/lookup", controllers.LookupInput{ISBN: isbnB}).ExpectProblem(http.StatusBadGateway)
+	admin.Post("/api/v1/books/lookup", controllers.LookupInput{ISBN: "123"}).ExpectProblem(http.StatusBadRequest)
+	srv.Reader(t).Post("/api/v1/books/lookup", controllers.LookupInput{ISBN: isbnA}).ExpectProblem(http.StatusForbidden)
+}
+
+func TestDuplicateBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Farthest Shore", AuthorID: author.ID, ISBN: isbnA, Quantity: 2})
+	duplicate := admin.CreateBook(controllers.CreateBookInput{Title: "The Farthest Shore", AuthorID: author.ID, ISBN: isbnA, Year: 1972})
+	admin.CreateBook(controllers.CreateBookInput{Title: "The Tombs of Atuan", AuthorID: author.ID})
+	reader := srv.Reader(t)
+
+	var groups []struct {
+		Books []models.Book `json:"books"`
+	}
+	admin.Get("/api/v1/books/duplicates").Expect(http.StatusOK).Data(&groups)
+	if len(groups) != 1 || len(groups[0].Books) != 2 || groups[0].Books[0].ID != book.ID {
+		t.Fatalf("got %+v, want the two copies of The Farthest Shore, oldest first", groups)
+	}
+	reader.Get("/api/v1/books/duplicates").ExpectProblem(http.StatusForbidden)
+
+	path := "/api/v1/books/" + book.ID.String() + "/merge"
+	admin.Post(path, controllers.MergeBookInput{DuplicateID: book.ID.String()}).ExpectProblem(http.StatusBadRequest)
+	admin.Post(path, controllers.MergeBookInput{DuplicateID: missingBook}).ExpectProblem(http.StatusBadRequest)
+	admin.Post(path, controllers.MergeBookInput{DuplicateID: "not-a-uuid"}).ExpectProblem(http.StatusBadRequest)
+	admin.Post("/api/v1/books/"+missingBook+"/merge", controllers.MergeBookInput{DuplicateID: duplicate.ID.String()}).ExpectProblem(http.StatusNotFound)
+	reader.Post(path, controllers.MergeBookInput{DuplicateID: duplicate.ID.String()}).ExpectProblem(http.StatusForbidden)
+
+	admin.Post("/api/v1/books/"+book.ID.String()+"/tags", controllers.TagBookInput{Tags: []string{"wizards"}}).Expect(http.StatusOK)
+	var tagged models.Book
+	admin.Post("/api/v1/books/"+duplicate.ID.String()+"/tags", controllers.TagBookInput{Tags: []string{"wizards", "dragons"}}).Expect(http.StatusOK).Data(&tagged)
+
+	var merged models.Book
+	admin.Post(path, controllers.MergeBookInput{DuplicateID: duplicate.ID.String()}).Expect(http.StatusOK).Data(&merged)
+	if merged.Quantity != 3 || merged.Year != 1972 {
+		t.Fatalf("got %d copies from %d, want 3 copies and the duplicate's year", merged.Quantity, merged.Year)
+	}
+	// The links moved by the merge are in the audit log, as any others.
+	var entries []models.AuditLog
+	if err := models.DB.Where("entity = ?", "book_tags").Order("id").Find(&entries).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{}
+	for _, tag := range tagged.Tags {
+		want[models.AuditDelete+" "+duplicate.ID.String()+","+itoa(tag.ID)] = ""
+		if tag.Name == "dragons" {
+			want[models.AuditCreate+" "+book.ID.String()+","+itoa(tag.ID)] = ""
+		}
+	}
+	for _, entry := range entries {
+		delete(want, entry.Action+" "+entry.EntityID)
+	}
+	if len(want) != 0 {
+		t.Fatalf("got no audit entries for %v", want)
+	}
+	admin.Get("/api/v1/books/" + duplicate.ID.String()).ExpectProblem(http.StatusNotFound)
+	admin.Get("/api/v1/books/duplicates").Expect(http.StatusOK).Data(&groups)
+	if len(groups) != 0 {
+		t.Fatalf("got %+v, want no duplicates left", groups)
+	}
+}
@@ -0,0 +1,551 @@
+package e2e
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/testsupport"
+	"github.com/google/uuid"
+)
+
+// Valid ISBN-13s.
+const (
+	isbnA = "9780306406157"
+	isbnB = "9780140449136"
+)
+
+// Unknown to every test database.
+var missingBook = uuid.NewString()
+
+// catalog starts the API with an admin and an author to create books with.
+func catalog(t *testing.T) (*testsupport.Server, *testsupport.Client, models.Author) {
+	t.Helper()
+	srv := testsupport.Start(t)
+	admin := srv.Admin(t)
+	return srv, admin, admin.CreateAuthor("Ursula K. Le Guin")
+}
+
+func TestListBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	other := admin.CreateAuthor("Italo Calvino")
+	admin.CreateBook(controllers.CreateBookInput{Title: "The Dispossessed", AuthorID: author.ID, Year: 1974})
+	admin.CreateBook(controllers.CreateBookInput{Title: "A Wizard of Earthsea", AuthorID: author.ID, Year: 1968})
+	admin.CreateBook(controllers.CreateBookInput{Title: "Invisible Cities", AuthorID: other.ID, Year: 1972})
+	anonymous := srv.Client(t)
+
+	t.Run("all", func(t *testing.T) {
+		var books []models.Book
+		resp := srv.Client(t).Get("/api/v1/books").Expect(http.StatusOK)
+		resp.Data(&books)
+		if len(books) != 3 {
+			t.Fatalf("got %d books, want 3", len(books))
+		}
+		var body struct {
+			Meta controllers.Pagination `json:"meta"`
+		}
+		resp.Decode(&body)
+		if body.Meta.Total != 3 {
+			t.Errorf("got total %d, want 3", body.Meta.Total)
+		}
+	})
+
+	t.Run("filtered and sorted", func(t *testing.T) {
+		var books []models.Book
+		srv.Client(t).Get("/api/v1/books?author_id=" + itoa(author.ID) + "&sort=year").Expect(http.StatusOK).Data(&books)
+		if len(books) != 2 || books[0].Title != "A Wizard of Earthsea" || books[1].Title != "The Dispossessed" {
+			t.Fatalf("got %v, want Le Guin's books oldest first", titles(books))
+		}
+	})
+
+	t.Run("paginated", func(t *testing.T) {
+		var books []models.Book
+		srv.Client(t).Get("/api/v1/books?page=2&page_size=2&sort=title").Expect(http.StatusOK).Data(&books)
+		if len(books) != 1 || books[0].Title != "The Dispossessed" {
+			t.Fatalf("got %v, want the last book by title", titles(books))
+		}
+	})
+
+	t.Run("by cursor", func(t *testing.T) {
+		var page struct {
+			Data []models.Book                `json:"data"`
+			Meta controllers.CursorPagination `json:"meta"`
+		}
+		srv.Client(t).Get("/api/v1/books?cursor=&page_size=2&sort=title").Expect(http.StatusOK).Decode(&page)
+		if len(page.Data) != 2 || page.Meta.NextCursor == nil {
+			t.Fatalf("got %d books and cursor %v, want 2 and a next cursor", len(page.Data), page.Meta.NextCursor)
+		}
+		var rest []models.Book
+		srv.Client(t).Get("/api/v1/books?page_size=2&cursor=" + *page.Meta.NextCursor).Expect(http.StatusOK).Data(&rest)
+		if len(rest) != 1 || rest[0].Title != "The Dispossessed" {
+			t.Fatalf("got %v, want the last book by title", titles(rest))
+		}
+	})
+
+	t.Run("selected fields", func(t *testing.T) {
+		var books []map[string]any
+		srv.Client(t).Get("/api/v1/books?fields=title&sort=title&page_size=1").Expect(http.StatusOK).Data(&books)
+		if len(books) != 1 || books[0]["title"] != "A Wizard of Earthsea" || books[0]["year"] != nil {
+			t.Fatalf("got %v, want the title only", books)
+		}
+	})
+
+	t.Run("invalid sort", func(t *testing.T) {
+		anonymous.Get("/api/v1/books?sort=nope").ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		anonymous.Get("/api/v1/books?cursor=nope").ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("deleted books need an admin", func(t *testing.T) {
+		anonymous.Get("/api/v1/books?include_deleted=true").ExpectProblem(http.StatusForbidden)
+		srv.Reader(t).Get("/api/v1/books?include_deleted=true").ExpectProblem(http.StatusForbidden)
+		admin.Get("/api/v1/books?include_deleted=true").Expect(http.StatusOK)
+	})
+}
+
+func TestFindBook(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Left Hand of Darkness", AuthorID: author.ID, ISBN: isbnA})
+	client := srv.Client(t)
+
+	t.Run("by id", func(t *testing.T) {
+		var found models.Book
+		resp := client.Get("/api/v1/books/" + book.ID.String() + "?preload=author").Expect(http.StatusOK)
+		resp.Data(&found)
+		if found.ID != book.ID || found.Author == nil || found.Author.Name != author.Name {
+			t.Fatalf("got %+v, want the book with its author", found)
+		}
+		if resp.Header.Get("ETag") != book.ETag() {
+			t.Errorf("got ETag %s, want %s", resp.Header.Get("ETag"), book.ETag())
+		}
+	})
+
+	t.Run("by slug", func(t *testing.T) {
+		var found models.Book
+		client.Get("/api/v1/books/" + book.Slug).Expect(http.StatusOK).Data(&found)
+		if found.ID != book.ID {
+			t.Fatalf("got book %s, want %s", found.ID, book.ID)
+		}
+	})
+
+	t.Run("not modified", func(t *testing.T) {
+		resp := client.WithHeader("If-None-Match", book.ETag()).Get("/api/v1/books/" + book.ID.String()).Expect(http.StatusNotModified)
+		if len(resp.Body) != 0 {
+			t.Errorf("got body %s, want none", resp.Body)
+		}
+	})
+
+	t.Run("unknown preload", func(t *testing.T) {
+		client.Get("/api/v1/books/" + book.ID.String() + "?preload=nope").ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		client.Get("/api/v1/books/" + missingBook).ExpectProblem(http.StatusNotFound)
+		client.Get("/api/v1/books/no-such-slug").ExpectProblem(http.StatusNotFound)
+	})
+}
+
+func TestCreateBook(t *testing.T) {
+	srv, admin, author := catalog(t)
+
+	t.Run("created", func(t *testing.T) {
+		var book models.Book
+		resp := admin.Post("/api/v1/books", map[string]any{"title": "The Lathe of Heaven", "author_id": author.ID, "year": 1971, "quantity": 3, "price": "12.50", "currency": "EUR"}).Expect(http.StatusOK)
+		resp.Data(&book)
+		if book.ID == uuid.Nil || book.Slug != "the-lathe-of-heaven" || book.Quantity != 3 || book.AvailableCopies != 3 || book.Version != 1 {
+			t.Fatalf("got %+v", book)
+		}
+		if book.Price == nil || book.Price.String() != "12.5" {
+			t.Errorf("got price %v, want 12.50", book.Price)
+		}
+		if resp.Header.Get("ETag") != book.ETag() {
+			t.Errorf("got ETag %s, want %s", resp.Header.Get("ETag"), book.ETag())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		problem := admin.Post("/api/v1/books", map[string]any{"author_id": author.ID, "isbn": "123"}).ExpectProblem(http.StatusBadRequest)
+		if !hasFieldError(problem, "title") || !hasFieldError(problem, "isbn") {
+			t.Fatalf("got errors %+v, want title and isbn", problem.Errors)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		admin.Do(http.MethodPost, "/api/v1/books", []byte(`{"title":`)).ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("unknown author", func(t *testing.T) {
+		admin.Post("/api/v1/books", controllers.CreateBookInput{Title: "Orphan", AuthorID: 999}).ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("price without currency", func(t *testing.T) {
+		admin.Post("/api/v1/books", map[string]any{"title": "Priced", "author_id": author.ID, "price": "10"}).ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("likely duplicate", func(t *testing.T) {
+		admin.CreateBook(controllers.CreateBookInput{Title: "Always Coming Home", AuthorID: author.ID, ISBN: isbnB})
+		problem := admin.Post("/api/v1/books", controllers.CreateBookInput{Title: "Other Title", AuthorID: author.ID, ISBN: isbnB}).ExpectProblem(http.StatusConflict)
+		if _, ok := problem.Extensions["duplicates"]; !ok {
+			t.Fatalf("got %+v, want the duplicates listed", problem)
+		}
+		admin.Post("/api/v1/books?force=true", controllers.CreateBookInput{Title: "Other Title", AuthorID: author.ID, ISBN: isbnB}).Expect(http.StatusOK)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		body := `{"title":"Big","author_id":1,"description":"` + strings.Repeat("a", 2<<20) + `"}`
+		admin.Do(http.MethodPost, "/api/v1/books", []byte(body)).ExpectProblem(http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		srv.Client(t).Post("/api/v1/books", controllers.CreateBookInput{Title: "Anonymous", AuthorID: author.ID}).ExpectProblem(http.StatusUnauthorized)
+		srv.Client(t).WithToken("not-a-token").Post("/api/v1/books", controllers.CreateBookInput{Title: "Anonymous", AuthorID: author.ID}).ExpectProblem(http.StatusUnauthorized)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		srv.Reader(t).Post("/api/v1/books", controllers.CreateBookInput{Title: "Reader's", AuthorID: author.ID}).ExpectProblem(http.StatusForbidden)
+	})
+}
+
+func TestUpdateBook(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Rocannon's World", AuthorID: author.ID, Year: 1966})
+	path := "/api/v1/books/" + book.ID.String()
+
+	t.Run("If-Match required", func(t *testing.T) {
+		admin.Put(path, controllers.UpdateBookInput{Title: "Renamed"}).ExpectProblem(http.StatusPreconditionRequired)
+		admin.Patch(path, map[string]any{"title": "Renamed"}).ExpectProblem(http.StatusPreconditionRequired)
+	})
+
+	t.Run("stale ETag", func(t *testing.T) {
+		admin.WithHeader("If-Match", `"stale"`).Put(path, controllers.UpdateBookInput{Title: "Renamed"}).ExpectProblem(http.StatusPreconditionFailed)
+	})
+
+	t.Run("put", func(t *testing.T) {
+		var updated models.Book
+		resp := admin.WithHeader("If-Match", book.ETag()).Put(path, controllers.UpdateBookInput{Title: "Rocannon's World (revised)", Year: 1977}).Expect(http.StatusOK)
+		resp.Data(&updated)
+		if updated.Title != "Rocannon's World (revised)" || updated.Year != 1977 || updated.Version != 2 {
+			t.Fatalf("got %+v", updated)
+		}
+		if updated.Slug != book.Slug {
+			t.Errorf("got slug %s, want it kept as %s", updated.Slug, book.Slug)
+		}
+		book = updated
+	})
+
+	t.Run("stale version", func(t *testing.T) {
+		admin.WithHeader("If-Match", "*").Put(path, controllers.UpdateBookInput{Version: 1, Title: "Lost update"}).ExpectProblem(http.StatusConflict)
+	})
+
+	t.Run("patch", func(t *testing.T) {
+		var patched models.Book
+		admin.WithHeader("If-Match", book.ETag()).WithHeader("Content-Type", "application/merge-patch+json").
+			Patch(path, map[string]any{"description": "The first Hainish novel."}).Expect(http.StatusOK).Data(&patched)
+		if patched.Description != "The first Hainish novel." || patched.Title != book.Title || patched.Year != book.Year {
+			t.Fatalf("got %+v, want only the description changed", patched)
+		}
+	})
+
+	t.Run("invalid patch", func(t *testing.T) {
+		admin.WithHeader("If-Match", "*").Patch(path, map[string]any{"title": ""}).ExpectProblem(http.StatusBadRequest)
+		admin.WithHeader("If-Match", "*").Patch(path, map[string]any{"author_id": 999}).ExpectProblem(http.StatusBadRequest)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		admin.WithHeader("If-Match", "*").Put("/api/v1/books/"+missingBook, controllers.UpdateBookInput{Title: "Ghost"}).ExpectProblem(http.StatusNotFound)
+		admin.WithHeader("If-Match", "*").Patch("/api/v1/books/not-a-uuid", map[string]any{"title": "Ghost"}).ExpectProblem(http.StatusNotFound)
+	})
+
+	t.Run("not an admin", func(t *testing.T) {
+		srv.Reader(t).WithHeader("If-Match", "*").Put(path, controllers.UpdateBookInput{Title: "Mine"}).ExpectProblem(http.StatusForbidden)
+		srv.Client(t).WithHeader("If-Match", "*").Patch(path, map[string]any{"title": "Mine"}).ExpectProblem(http.StatusUnauthorized)
+	})
+}
+
+func TestDeleteBook(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Planet of Exile", AuthorID: author.ID})
+	path := "/api/v1/books/" + book.ID.String()
+
+	admin.Delete(path).ExpectProblem(http.StatusPreconditionRequired)
+	admin.WithHeader("If-Match", `"stale"`).Delete(path).ExpectProblem(http.StatusPreconditionFailed)
+	srv.Reader(t).WithHeader("If-Match", "*").Delete(path).ExpectProblem(http.StatusForbidden)
+	admin.Post(path+"/restore", nil).ExpectProblem(http.StatusConflict)
+
+	var deleted bool
+	admin.WithHeader("If-Match", book.ETag()).Delete(path).Expect(http.StatusOK).Data(&deleted)
+	if !deleted {
+		t.Fatal("got data false, want true")
+	}
+	srv.Client(t).Get(path).ExpectProblem(http.StatusNotFound)
+	admin.WithHeader("If-Match", "*").Delete(path).ExpectProblem(http.StatusNotFound)
+
+	var listed []models.Book
+	admin.Get("/api/v1/books?include_deleted=true").Expect(http.StatusOK).Data(&listed)
+	if len(listed) != 1 || listed[0].ID != book.ID {
+		t.Fatalf("got %v, want the deleted book listed", titles(listed))
+	}
+
+	var restored models.Book
+	admin.Post(path+"/restore", nil).Expect(http.StatusOK).Data(&restored)
+	if restored.ID != book.ID {
+		t.Fatalf("got %s restored, want %s", restored.ID, book.ID)
+	}
+	srv.Client(t).Get(path).Expect(http.StatusOK)
+	admin.Post("/api/v1/books/"+missingBook+"/restore", nil).ExpectProblem(http.StatusNotFound)
+
+	srv.Reader(t).Delete(path + "/permanent").ExpectProblem(http.StatusForbidden)
+	admin.Delete(path + "/permanent").Expect(http.StatusOK)
+	admin.Get("/api/v1/books?include_deleted=true").Expect(http.StatusOK).Data(&listed)
+	if len(listed) != 0 {
+		t.Fatalf("got %v, want the book gone for good", titles(listed))
+	}
+	admin.Delete(path + "/permanent").ExpectProblem(http.StatusNotFound)
+	admin.Post(path+"/restore", nil).ExpectProblem(http.StatusNotFound)
+}
+
+func TestBookStockCategoriesAndTags(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Word for World Is Forest", AuthorID: author.ID, Quantity: 2})
+	path := "/api/v1/books/" + book.ID.String()
+	reader := srv.Reader(t)
+
+	t.Run("stock", func(t *testing.T) {
+		var adjusted models.Book
+		admin.Post(path+"/stock/adjust", controllers.AdjustStockInput{Delta: 3}).Expect(http.StatusOK).Data(&adjusted)
+		if adjusted.Quantity != 5 || adjusted.AvailableCopies != 5 {
+			t.Fatalf("got %d copies, %d available, want 5 and 5", adjusted.Quantity, adjusted.AvailableCopies)
+		}
+		admin.Post(path+"/stock/adjust", controllers.AdjustStockInput{Delta: -6}).ExpectProblem(http.StatusConflict)
+		admin.Post(path+"/stock/adjust", map[string]any{"delta": 0}).ExpectProblem(http.StatusBadRequest)
+		admin.Post("/api/v1/books/"+missingBook+"/stock/adjust", controllers.AdjustStockInput{Delta: 1}).ExpectProblem(http.StatusNotFound)
+		reader.Post(path+"/stock/adjust", controllers.AdjustStockInput{Delta: 1}).ExpectProblem(http.StatusForbidden)
+
+		var availability struct {
+			Quantity        int  `json:"quantity"`
+			AvailableCopies int  `json:"available_copies"`
+			OnLoan          int  `json:"on_loan"`
+			Available       bool `json:"available"`
+		}
+		srv.Client(t).Get(path + "/availability").Expect(http.StatusOK).Data(&availability)
+		if availability.Quantity != 5 || availability.AvailableCopies != 5 || availability.OnLoan != 0 || !availability.Available {
+			t.Fatalf("got %+v", availability)
+		}
+		srv.Client(t).Get("/api/v1/books/" + missingBook + "/availability").ExpectProblem(http.StatusNotFound)
+	})
+
+	t.Run("categories", func(t *testing.T) {
+		fiction := admin.CreateCategory("Fiction")
+		ecology := admin.CreateCategory("Ecology")
+
+		var attached models.Book
+		admin.Post(path+"/categories", controllers.AttachCategoriesInput{CategoryIDs: []uint{fiction.ID, ecology.ID}}).Expect(http.StatusOK).Data(&attached)
+		if len(attached.Categories) != 2 {
+			t.Fatalf("got categories %+v, want 2", attached.Categories)
+		}
+		var inCategory []models.Book
+		srv.Client(t).Get("/api/v1/books?category_id=" + itoa(ecology.ID)).Expect(http.StatusOK).Data(&inCategory)
+		if len(inCategory) != 1 || inCategory[0].ID != book.ID {
+			t.Fatalf("got %v, want the book in its category", titles(inCategory))
+		}
+
+		admin.Post(path+"/categories", controllers.AttachCategoriesInput{CategoryIDs: []uint{999}}).ExpectProblem(http.StatusBadRequest)
+		admin.Post(path+"/categories", map[string]any{"category_ids": []uint{}}).ExpectProblem(http.StatusBadRequest)
+		admin.Post("/api/v1/books/"+missingBook+"/categories", controllers.AttachCategoriesInput{CategoryIDs: []uint{fiction.ID}}).ExpectProblem(http.StatusNotFound)
+		reader.Post(path+"/categories", controllers.AttachCategoriesInput{CategoryIDs: []uint{fiction.ID}}).ExpectProblem(http.StatusForbidden)
+
+		var detached models.Book
+		admin.Delete(path + "/categories/" + itoa(ecology.ID)).Expect(http.StatusOK).Data(&detached)
+		if len(detached.Categories) != 1 || detached.Categories[0].ID != fiction.ID {
+			t.Fatalf("got categories %+v, want only fiction", detached.Categories)
+		}
+		admin.Delete(path + "/categories/nope").ExpectProblem(http.StatusNotFound)
+		reader.Delete(path + "/categories/" + itoa(fiction.ID)).ExpectProblem(http.StatusForbidden)
+	})
+
+	t.Run("tags", func(t *testing.T) {
+		var tagged models.Book
+		admin.Post(path+"/tags", controllers.TagBookInput{Tags: []string{"Science  Fiction", "novella"}}).Expect(http.StatusOK).Data(&tagged)
+		if len(tagged.Tags) != 2 || !hasTag(tagged, "science fiction") || !hasTag(tagged, "novella") {
+			t.Fatalf("got tags %+v, want them normalized", tagged.Tags)
+		}
+		var withTag []models.Book
+		srv.Client(t).Get("/api/v1/books?tag=novella").Expect(http.StatusOK).Data(&withTag)
+		if len(withTag) != 1 || withTag[0].ID != book.ID {
+			t.Fatalf("got %v, want the tagged book", titles(withTag))
+		}
+
+		admin.Post(path+"/tags", controllers.TagBookInput{Tags: []string{"   "}}).ExpectProblem(http.StatusBadRequest)
+		admin.Post(path+"/tags", map[string]any{}).ExpectProblem(http.StatusBadRequest)
+		admin.Post("/api/v1/books/"+missingBook+"/tags", controllers.TagBookInput{Tags: []string{"x"}}).ExpectProblem(http.StatusNotFound)
+		reader.Post(path+"/tags", controllers.TagBookInput{Tags: []string{"mine"}}).ExpectProblem(http.StatusForbidden)
+
+		var untagged models.Book
+		admin.Delete(path + "/tags/novella").Expect(http.StatusOK).Data(&untagged)
+		if len(untagged.Tags) != 1 || !hasTag(untagged, "science fiction") {
+			t.Fatalf("got tags %+v, want only science fiction", untagged.Tags)
+		}
+		admin.Delete("/api/v1/books/" + missingBook + "/tags/novella").ExpectProblem(http.StatusNotFound)
+		reader.Delete(path + "/tags/novella").ExpectProblem(http.StatusForbidden)
+	})
+}
+
+func TestBookReviews(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Telling", AuthorID: author.ID})
+	path := "/api/v1/books/" + book.ID.String() + "/reviews"
+	reader := srv.Reader(t)
+
+	var review models.Review
+	reader.Post(path, controllers.CreateReviewInput{Rating: 4, Comment: "Quiet and wise."}).Expect(http.StatusCreated).Data(&review)
+	if review.BookID != book.ID || review.Rating != 4 {
+		t.Fatalf("got %+v", review)
+	}
+	reader.Post(path, controllers.CreateReviewInput{Rating: 5}).ExpectProblem(http.StatusConflict)
+	admin.Post(path, controllers.CreateReviewInput{Rating: 6}).ExpectProblem(http.StatusBadRequest)
+	srv.Client(t).Post(path, controllers.CreateReviewInput{Rating: 3}).ExpectProblem(http.StatusUnauthorized)
+	reader.Post("/api/v1/books/"+missingBook+"/reviews", controllers.CreateReviewInput{Rating: 3}).ExpectProblem(http.StatusNotFound)
+
+	var reviews []models.Review
+	srv.Client(t).Get(path).Expect(http.StatusOK).Data(&reviews)
+	if len(reviews) != 1 || reviews[0].Comment != "Quiet and wise." {
+		t.Fatalf("got %+v, want the review", reviews)
+	}
+	srv.Client(t).Get("/api/v1/books/" + missingBook + "/reviews").ExpectProblem(http.StatusNotFound)
+
+	var reviewed models.Book
+	srv.Client(t).Get("/api/v1/books/" + book.ID.String()).Expect(http.StatusOK).Data(&reviewed)
+	if reviewed.ReviewCount != 1 || reviewed.RatingAverage != 4 {
+		t.Fatalf("got %d reviews averaging %v, want 1 averaging 4", reviewed.ReviewCount, reviewed.RatingAverage)
+	}
+}
+
+func TestSimilarBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Lavinia", AuthorID: author.ID})
+	client := srv.Client(t)
+
+	var similar []map[string]any
+	client.Get("/api/v1/books/" + book.ID.String() + "/similar").Expect(http.StatusOK).Data(&similar)
+	if len(similar) != 0 {
+		t.Fatalf("got %v, want none before recommendations are computed", similar)
+	}
+	client.Get("/api/v1/books/" + book.ID.String() + "/similar?limit=0").ExpectProblem(http.StatusBadRequest)
+	client.Get("/api/v1/books/" + missingBook + "/similar").ExpectProblem(http.StatusNotFound)
+}
+
+func TestSearchBooks(t *testing.T) {
+	srv, admin, author := catalog(t)
+	admin.CreateBook(controllers.CreateBookInput{Title: "The Dispossessed", AuthorID: author.ID, Description: "An ambiguous utopia."})
+	admin.CreateBook(controllers.CreateBookInput{Title: "The Lathe of Heaven", AuthorID: author.ID})
+	client := srv.Client(t)
+
+	var results []controllers.BookSearchResult
+	client.Get("/api/v1/books/search?q=utopia").Expect(http.StatusOK).Data(&results)
+	if len(results) != 1 || results[0].Book.Title != "The Dispossessed" {
+		t.Fatalf("got %+v, want The Dispossessed", results)
+	}
+	client.Get("/api/v1/books/search").ExpectProblem(http.StatusBadRequest)
+	client.Get("/api/v1/books/search?q=%20").ExpectProblem(http.StatusBadRequest)
+	// Without a search index, filters aren't supported.
+	client.Get("/api/v1/books/search?q=utopia&year_gte=1970").ExpectProblem(http.StatusBadRequest)
+}
+
+func itoa(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+func titles(books []models.Book) []string {
+	names := make([]string, len(books))
+	for i, book := range books {
+		names[i] = book.Title
+	}
+	return names
+}
+
+func hasTag(book models.Book, name string) bool {
+	for _, tag := range book.Tags {
+		if tag.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func hasFieldError(problem testsupport.Problem, field string) bool {
+	for _, fe := range problem.Errors {
+		if fe.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestBookBarcodes(t *testing.T) {
+	srv, admin, author := catalog(t)
+	withISBN := admin.CreateBook(controllers.CreateBookInput{Title: "The Lathe of Heaven", AuthorID: author.ID, ISBN: isbnA})
+	withoutISBN := admin.CreateBook(controllers.CreateBookInput{Title: "The Word for World Is Forest", AuthorID: author.ID})
+	client := srv.Client(t)
+	barcode := func(book, query string) *testsupport.Response {
+		return client.Get("/api/v1/books/" + book + "/barcode" + query)
+	}
+
+	t.Run("barcode", func(t *testing.T) {
+		for _, test := range []struct {
+			book, query, contentType string
+		}{
+			{withISBN.ID.String(), "", "image/png"},
+			{withISBN.ID.String(), "?format=svg", "image/svg+xml"},
+			{withISBN.ID.String(), "?encode=id", "image/png"},
+			// A QR code of the ID instead.
+			{withoutISBN.ID.String(), "", "image/png"},
+		} {
+			response := barcode(test.book, test.query).Expect(http.StatusOK)
+			if got := response.Header.Get("Content-Type"); got != test.contentType || len(response.Body) == 0 {
+				t.Fatalf("%s%s: got %q of %d bytes, want %s", test.book, test.query, got, len(response.Body), test.contentType)
+			}
+		}
+		barcode(withoutISBN.ID.String(), "?encode=isbn").ExpectProblem(http.StatusBadRequest)
+		barcode(withISBN.ID.String(), "?format=gif").ExpectProblem(http.StatusBadRequest)
+		barcode(missingBook, "").ExpectProblem(http.StatusNotFound)
+	})
+
+	t.Run("labels", func(t *testing.T) {
+		labels := func(input controllers.LabelsInput) *testsupport.Response {
+			return admin.Post("/api/v1/books/labels", input)
+		}
+		response := labels(controllers.LabelsInput{BookIDs: []string{withISBN.ID.String(), withoutISBN.ID.String()}}).Expect(http.StatusOK)
+		if !strings.HasPrefix(string(response.Body), "%PDF") {
+			t.Fatalf("got %.20q, want a PDF", response.Body)
+		}
+		srv.Reader(t).Post("/api/v1/books/labels", controllers.LabelsInput{BookIDs: []string{withISBN.ID.String()}}).ExpectProblem(http.StatusForbidden)
+
+		tests := []struct {
+			name   string
+			input  controllers.LabelsInput
+			detail string
+		}{
+			{"no ISBN", controllers.LabelsInput{BookIDs: []string{withoutISBN.ID.String()}, Encode: "isbn"}, "Book " + withoutISBN.ID.String() + " has no valid ISBN to encode!"},
+			{"missing book", controllers.LabelsInput{BookIDs: []string{missingBook}}, "Book " + missingBook + " does not exist!"},
+		}
+		for _, test := range tests {
+			problem := labels(test.input).ExpectProblem(http.StatusBadRequest)
+			if problem.Detail != test.detail {
+				t.Fatalf("%s: got %q, want %q", test.name, problem.Detail, test.detail)
+			}
+		}
+
+		// 600 copies fit, but not twice.
+		many := admin.CreateBook(controllers.CreateBookInput{Title: "Always Coming Home", AuthorID: author.ID, Quantity: 600})
+		labels(controllers.LabelsInput{BookIDs: []string{many.ID.String()}, PerCopy: true}).Expect(http.StatusOK)
+		problem := labels(controllers.LabelsInput{BookIDs: []string{many.ID.String(), many.ID.String()}, PerCopy: true}).ExpectProblem(http.StatusBadRequest)
+		if problem.Detail != "Labels are printed at most 1000 at a time." {
+			t.Fatalf("got %q, want the labels capped", problem.Detail)
+		}
+	})
+}
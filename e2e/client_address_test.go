@@ -0,0 +1,73 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/testsupport"
+)
+
+// forwardedFor returns a copy of client claiming to forward a request from
+// address, as a proxy would.
+func forwardedFor(client *testsupport.Client, address string) *testsupport.Client {
+	return client.WithHeader("X-Forwarded-For", address).WithHeader("X-Real-IP", address)
+}
+
+func TestAddressRateLimit(t *testing.T) {
+	start := func(t *testing.T, proxies ...string) *testsupport.Client {
+		srv := testsupport.Start(t, func(cfg *config.Config) {
+			cfg.RateLimit.AddressRate, cfg.RateLimit.AddressBurst = 0.01, 2
+			cfg.TrustedProxies = proxies
+		})
+		return srv.Client(t)
+	}
+	send := func(client *testsupport.Client, address string) int {
+		return forwardedFor(client, address).Get("/api/v1/books").Status
+	}
+
+	t.Run("spoofed addresses", func(t *testing.T) {
+		client := start(t)
+		for i, address := range []string{"203.0.113.1", "203.0.113.2"} {
+			if status := send(client, address); status == http.StatusTooManyRequests {
+				t.Fatalf("got request %d limited, want it within the burst", i+1)
+			}
+		}
+		// Without trusted proxies, the headers don't make another client.
+		forwardedFor(client, "203.0.113.3").Get("/api/v1/books").ExpectProblem(http.StatusTooManyRequests)
+	})
+
+	t.Run("trusted proxy", func(t *testing.T) {
+		client := start(t, "127.0.0.1")
+		for _, address := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3"} {
+			if status := send(client, address); status == http.StatusTooManyRequests {
+				t.Fatalf("got %s limited, want each forwarded client limited on its own", address)
+			}
+		}
+		send(client, "203.0.113.1")
+		forwardedFor(client, "203.0.113.1").Get("/api/v1/books").ExpectProblem(http.StatusTooManyRequests)
+	})
+}
+
+func TestAddressLoginLockout(t *testing.T) {
+	srv := testsupport.Start(t, func(cfg *config.Config) {
+		cfg.Auth.MaxFailedLogins = 0
+		cfg.Auth.MaxIPFailedLogins, cfg.Auth.IPFailureWindow = 2, time.Minute
+	})
+	client := srv.Client(t)
+	email := testsupport.NewEmail("reader")
+	client.Post("/api/v1/auth/register", controllers.RegisterInput{Email: email, Password: testsupport.Password}).Expect(http.StatusCreated)
+	login := func(address, password string) *testsupport.Response {
+		return forwardedFor(client, address).Post("/api/v1/auth/login", controllers.LoginInput{Email: email, Password: password})
+	}
+
+	login("203.0.113.1", "guessed").ExpectProblem(http.StatusUnauthorized)
+	login("203.0.113.2", "guessed").ExpectProblem(http.StatusUnauthorized)
+	// Claiming to be someone else doesn't lift the lockout of the address.
+	problem := login("203.0.113.3", testsupport.Password).ExpectProblem(http.StatusTooManyRequests)
+	if _, ok := problem.Extensions["locked_until"]; !ok {
+		t.Fatalf("got %q, want the address locked out", problem.Detail)
+	}
+}
@@ -0,0 +1,260 @@
+package e2e
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/testsupport"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// borrow checks the book out to the member.
+func borrow(admin *testsupport.Client, book models.Book, member models.Member) models.Loan {
+	var loan models.Loan
+	admin.Post("/api/v1/loans", controllers.CheckoutInput{BookID: book.ID.String(), MemberID: member.ID.String()}).Expect(http.StatusCreated).Data(&loan)
+	return loan
+}
+
+// giveBack returns the loan.
+func giveBack(admin *testsupport.Client, loan models.Loan) models.Loan {
+	admin.Post("/api/v1/loans/"+loan.ID.String()+"/return", nil).Expect(http.StatusOK).Data(&loan)
+	return loan
+}
+
+// dueAt moves the loan's due date, to make it late or on time.
+func dueAt(t *testing.T, loan models.Loan, at time.Time) {
+	t.Helper()
+	if err := models.DB.Model(&models.Loan{}).Where("id = ?", loan.ID).Update("due_at", at).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFines(t *testing.T) {
+	srv := testsupport.Start(t, func(cfg *config.Config) {
+		cfg.Lending.Fines = config.FinesConfig{DailyRate: "1.50", Cap: "4.00", Currency: "USD", BlockAbove: "5.00"}
+	})
+	admin := srv.Admin(t)
+	author := admin.CreateAuthor("Ursula K. Le Guin")
+	member := admin.CreateMember("Shevek")
+	books := make([]models.Book, 4)
+	for i := range books {
+		books[i] = admin.CreateBook(controllers.CreateBookInput{Title: "The Dispossessed " + itoa(uint(i+1)), AuthorID: author.ID})
+	}
+
+	onTime := giveBack(admin, borrow(admin, books[0], member))
+	if onTime.Fine != nil {
+		t.Fatalf("got fine %+v for a loan returned on time", onTime.Fine)
+	}
+
+	// Any part of a day late counts as a day.
+	loan := borrow(admin, books[1], member)
+	dueAt(t, loan, time.Now().Add(-time.Minute))
+	oneDay := giveBack(admin, loan).Fine
+	if oneDay == nil || oneDay.DaysLate != 1 || !oneDay.Amount.Equal(decimal.RequireFromString("1.50")) || oneDay.Currency != "USD" || oneDay.Status != models.FineUnpaid {
+		t.Fatalf("got fine %+v, want an unpaid 1.50 USD for a day", oneDay)
+	}
+
+	// 3 days at 1.50 is above the cap.
+	loan = borrow(admin, books[2], member)
+	dueAt(t, loan, time.Now().Add(-50*time.Hour))
+	capped := giveBack(admin, loan).Fine
+	if capped == nil || capped.DaysLate != 3 || !capped.Amount.Equal(decimal.RequireFromString("4.00")) {
+		t.Fatalf("got fine %+v, want 4.00, the cap, for 3 days", capped)
+	}
+
+	var fines []models.Fine
+	admin.Get("/api/v1/fines?status=unpaid&member_id=" + member.ID.String()).Expect(http.StatusOK).Data(&fines)
+	if len(fines) != 2 {
+		t.Fatalf("got %d unpaid fines, want 2", len(fines))
+	}
+
+	t.Run("checkout blocked", func(t *testing.T) {
+		// 5.50 owed is above 5.00.
+		problem := admin.Post("/api/v1/loans", controllers.CheckoutInput{BookID: books[3].ID.String(), MemberID: member.ID.String()}).ExpectProblem(http.StatusConflict)
+		if problem.Detail != "The member owes too much in unpaid fines to borrow until they are settled!" {
+			t.Fatalf("got %q, want the member blocked by their fines", problem.Detail)
+		}
+		// Other members aren't.
+		giveBack(admin, borrow(admin, books[3], admin.CreateMember("Takver")))
+	})
+
+	t.Run("settle", func(t *testing.T) {
+		path := "/api/v1/fines/" + oneDay.ID.String() + "/settle"
+		admin.Post(path, controllers.SettleFineInput{Status: "refunded"}).ExpectProblem(http.StatusBadRequest)
+		srv.Reader(t).Post(path, controllers.SettleFineInput{Status: models.FinePaid}).ExpectProblem(http.StatusForbidden)
+
+		var settled models.Fine
+		admin.Post(path, controllers.SettleFineInput{Status: models.FinePaid, Note: "receipt 42"}).Expect(http.StatusOK).Data(&settled)
+		if settled.Status != models.FinePaid || settled.SettledAt == nil || settled.Note != "receipt 42" {
+			t.Fatalf("got %+v, want the fine paid", settled)
+		}
+		admin.Post(path, controllers.SettleFineInput{Status: models.FineWaived}).ExpectProblem(http.StatusConflict)
+		admin.Post("/api/v1/fines/"+uuid.NewString()+"/settle", controllers.SettleFineInput{Status: models.FinePaid}).ExpectProblem(http.StatusNotFound)
+
+		// 4.00 owed is no longer above 5.00.
+		borrow(admin, books[3], member)
+	})
+}
+
+func TestHolds(t *testing.T) {
+	srv, admin, author := catalog(t)
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "The Left Hand of Darkness", AuthorID: author.ID})
+	path := "/api/v1/books/" + book.ID.String() + "/holds"
+	genly, estraven, argaven, tibe := admin.CreateMember("Genly Ai"), admin.CreateMember("Estraven"), admin.CreateMember("Argaven"), admin.CreateMember("Tibe")
+	place := func(member models.Member) models.Hold {
+		t.Helper()
+		var hold models.Hold
+		admin.Post(path, controllers.PlaceHoldInput{MemberID: member.ID.String()}).Expect(http.StatusCreated).Data(&hold)
+		return hold
+	}
+	find := func(hold models.Hold) models.Hold {
+		t.Helper()
+		admin.Get("/api/v1/holds/" + hold.ID.String()).Expect(http.StatusOK).Data(&hold)
+		return hold
+	}
+
+	admin.Post(path, controllers.PlaceHoldInput{MemberID: estraven.ID.String()}).ExpectProblem(http.StatusConflict)
+	admin.Post("/api/v1/books/"+missingBook+"/holds", controllers.PlaceHoldInput{MemberID: estraven.ID.String()}).ExpectProblem(http.StatusNotFound)
+	srv.Reader(t).Post(path, controllers.PlaceHoldInput{MemberID: estraven.ID.String()}).ExpectProblem(http.StatusForbidden)
+	loan := borrow(admin, book, genly)
+	admin.Post(path, controllers.PlaceHoldInput{MemberID: genly.ID.String()}).ExpectProblem(http.StatusConflict)
+
+	first, second, third := place(estraven), place(argaven), place(tibe)
+	admin.Post(path, controllers.PlaceHoldInput{MemberID: argaven.ID.String()}).ExpectProblem(http.StatusConflict)
+	for i, hold := range []models.Hold{first, second, third} {
+		if hold.Status != models.HoldWaiting || hold.Position != i+1 {
+			t.Fatalf("got hold %d %s at %d, want it waiting at %d", i+1, hold.Status, hold.Position, i+1)
+		}
+	}
+
+	// Leaving the line moves those behind up.
+	admin.Delete("/api/v1/holds/" + second.ID.String()).Expect(http.StatusOK)
+	admin.Delete("/api/v1/holds/" + second.ID.String()).ExpectProblem(http.StatusConflict)
+	if third = find(third); third.Position != 2 {
+		t.Fatalf("got the last hold at %d, want 2 once the one before left", third.Position)
+	}
+
+	// The returned copy is set aside for the first in line, whom no one
+	// else can take it from.
+	giveBack(admin, loan)
+	if first = find(first); first.Status != models.HoldReady || first.ReadyAt == nil || first.ExpiresAt == nil {
+		t.Fatalf("got the first hold %s, want it ready until a deadline", first.Status)
+	}
+	if third = find(third); third.Status != models.HoldWaiting || third.Position != 1 {
+		t.Fatalf("got the last hold %s at %d, want it next", third.Status, third.Position)
+	}
+	admin.Post("/api/v1/loans", controllers.CheckoutInput{BookID: book.ID.String(), MemberID: tibe.ID.String()}).ExpectProblem(http.StatusConflict)
+	admin.Post("/api/v1/loans", controllers.CheckoutInput{BookID: book.ID.String(), MemberID: genly.ID.String()}).ExpectProblem(http.StatusConflict)
+
+	loan = borrow(admin, book, estraven)
+	if first = find(first); first.Status != models.HoldFulfilled {
+		t.Fatalf("got the first hold %s once its member borrowed the book, want it fulfilled", first.Status)
+	}
+
+	// Cancelling a ready hold passes the copy on.
+	giveBack(admin, loan)
+	if third = find(third); third.Status != models.HoldReady {
+		t.Fatalf("got the last hold %s once the copy came back, want it ready", third.Status)
+	}
+	fourth := place(genly)
+	admin.Delete("/api/v1/holds/" + third.ID.String()).Expect(http.StatusOK)
+	if fourth = find(fourth); fourth.Status != models.HoldReady {
+		t.Fatalf("got the hold placed last %s once the ready one was cancelled, want it ready", fourth.Status)
+	}
+	borrow(admin, book, genly)
+
+	// Members in line for a book keep their place unless it goes away.
+	duplicate := admin.CreateBook(controllers.CreateBookInput{Title: "Left Hand of Darkness", AuthorID: author.ID})
+	borrow(admin, duplicate, admin.CreateMember("Faxe"))
+	var hold models.Hold
+	admin.Post("/api/v1/books/"+duplicate.ID.String()+"/holds", controllers.PlaceHoldInput{MemberID: admin.CreateMember("Ashe").ID.String()}).Expect(http.StatusCreated).Data(&hold)
+
+	merge := controllers.MergeBookInput{DuplicateID: duplicate.ID.String()}
+	problem := admin.Post("/api/v1/books/"+book.ID.String()+"/merge", merge).ExpectProblem(http.StatusConflict)
+	if problem.Detail != "Members are in line for the duplicate; cancel their holds before merging it!" {
+		t.Fatalf("got %q, want the merge refused for the holds", problem.Detail)
+	}
+
+	admin.Delete("/api/v1/books/" + duplicate.ID.String() + "/permanent").Expect(http.StatusOK)
+	if hold = find(hold); hold.Status != models.HoldCancelled {
+		t.Fatalf("got the hold %s once its book was deleted, want it cancelled", hold.Status)
+	}
+}
+
+func TestBranchStock(t *testing.T) {
+	srv, admin, author := catalog(t)
+	branch := func(code string) models.Branch {
+		t.Helper()
+		var branch models.Branch
+		admin.Post("/api/v1/branches", controllers.CreateBranchInput{Code: code, Name: "Branch " + code}).Expect(http.StatusCreated).Data(&branch)
+		return branch
+	}
+	availability := func(book models.Book, query string) services.Availability {
+		t.Helper()
+		var availability services.Availability
+		admin.Get("/api/v1/books/" + book.ID.String() + "/availability" + query).Expect(http.StatusOK).Data(&availability)
+		return availability
+	}
+	roke, havnor := branch("roke"), branch("havnor")
+	book := admin.CreateBook(controllers.CreateBookInput{Title: "Tales from Earthsea", AuthorID: author.ID, Quantity: 4})
+	path := "/api/v1/books/" + book.ID.String() + "/transfers"
+
+	var transfer models.StockTransfer
+	admin.Post(path, controllers.TransferStockInput{ToBranchID: &roke.ID, Copies: 3, Note: "new shelf"}).Expect(http.StatusCreated).Data(&transfer)
+	if transfer.FromBranchID != nil || transfer.ToBranchID == nil || *transfer.ToBranchID != roke.ID || transfer.Copies != 3 || transfer.UserID == nil {
+		t.Fatalf("got transfer %+v, want 3 unassigned copies moved to Roke by the admin", transfer)
+	}
+	if at := availability(book, "?branch="+itoa(roke.ID)); at.Quantity != 3 || at.AvailableCopies != 3 {
+		t.Fatalf("got %d copies at Roke, %d available, want 3 of 3", at.Quantity, at.AvailableCopies)
+	}
+	if at := availability(book, ""); at.Quantity != 4 || len(at.Branches) != 1 || at.Branches[0].BranchID != roke.ID {
+		t.Fatalf("got %+v, want the 4 copies, 3 of them at Roke", at)
+	}
+	admin.Post(path, controllers.TransferStockInput{FromBranchID: &roke.ID, ToBranchID: &havnor.ID, Copies: 1}).Expect(http.StatusCreated)
+
+	t.Run("refused", func(t *testing.T) {
+		problem := admin.Post(path, controllers.TransferStockInput{FromBranchID: &roke.ID, ToBranchID: &havnor.ID, Copies: 3}).ExpectProblem(http.StatusConflict)
+		if problem.Detail != "Not enough copies on the shelves of the branch to transfer!" {
+			t.Fatalf("got %q, want too few copies at Roke", problem.Detail)
+		}
+		unknown := havnor.ID + 100
+		admin.Post(path, controllers.TransferStockInput{FromBranchID: &roke.ID, ToBranchID: &unknown, Copies: 1}).ExpectProblem(http.StatusBadRequest)
+		admin.Post(path, controllers.TransferStockInput{FromBranchID: &roke.ID, ToBranchID: &roke.ID, Copies: 1}).ExpectProblem(http.StatusBadRequest)
+		admin.Post("/api/v1/books/"+missingBook+"/transfers", controllers.TransferStockInput{ToBranchID: &roke.ID, Copies: 1}).ExpectProblem(http.StatusNotFound)
+		srv.Reader(t).Post(path, controllers.TransferStockInput{ToBranchID: &roke.ID, Copies: 1}).ExpectProblem(http.StatusForbidden)
+
+		admin.Get("/api/v1/books/" + book.ID.String() + "/availability?branch=" + itoa(unknown)).ExpectProblem(http.StatusBadRequest)
+		problem = admin.Get("/api/v1/books/" + book.ID.String() + "/availability?branch=roke").ExpectProblem(http.StatusBadRequest)
+		if problem.Detail != `Invalid branch "roke"; it must be a branch ID.` {
+			t.Fatalf("got %q, want the branch refused", problem.Detail)
+		}
+	})
+
+	var transfers []models.StockTransfer
+	admin.Get(path).Expect(http.StatusOK).Data(&transfers)
+	if len(transfers) != 2 || transfers[0].Copies != 1 || transfers[1].ID != transfer.ID {
+		t.Fatalf("got %+v, want both transfers, newest first", transfers)
+	}
+	admin.Get("/api/v1/branches/" + itoa(havnor.ID) + "/transfers").Expect(http.StatusOK).Data(&transfers)
+	if len(transfers) != 1 || *transfers[0].ToBranchID != havnor.ID {
+		t.Fatalf("got %+v, want the transfer to Havnor", transfers)
+	}
+
+	// A duplicate's copies stay where they are when it is merged.
+	duplicate := admin.CreateBook(controllers.CreateBookInput{Title: "Tales From Earthsea", AuthorID: author.ID, Quantity: 2})
+	admin.Post("/api/v1/books/"+duplicate.ID.String()+"/transfers", controllers.TransferStockInput{ToBranchID: &havnor.ID, Copies: 2}).Expect(http.StatusCreated)
+	admin.Post("/api/v1/books/"+book.ID.String()+"/merge", controllers.MergeBookInput{DuplicateID: duplicate.ID.String()}).Expect(http.StatusOK)
+	if at := availability(book, "?branch="+itoa(havnor.ID)); at.Quantity != 3 || at.AvailableCopies != 3 {
+		t.Fatalf("got %d copies at Havnor once merged, %d available, want 3 of 3", at.Quantity, at.AvailableCopies)
+	}
+	admin.Get(path).Expect(http.StatusOK).Data(&transfers)
+	if len(transfers) != 3 {
+		t.Fatalf("got %d transfers once merged, want the duplicate's too", len(transfers))
+	}
+}
@@ -0,0 +1,127 @@
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/ratelimit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/testsupport"
+	"github.com/google/uuid"
+)
+
+// signature is what a signed request says of itself.
+type signature struct {
+	keyID   uint
+	secret  string
+	uri     string
+	at      time.Time
+	nonce   string
+	payload []byte
+}
+
+// signed returns a copy of client sending the signature headers of a
+// method request, as the auth package describes them.
+func signed(client *testsupport.Client, method string, s signature) *testsupport.Client {
+	timestamp := strconv.FormatInt(s.at.Unix(), 10)
+	digest := auth.Digest(s.payload)
+	return client.
+		WithHeader("Content-Type", "application/json").
+		WithHeader(auth.SignatureKeyHeader, strconv.FormatUint(uint64(s.keyID), 10)).
+		WithHeader(auth.SignatureTimestampHeader, timestamp).
+		WithHeader(auth.SignatureNonceHeader, s.nonce).
+		WithHeader(auth.ContentDigestHeader, digest).
+		WithHeader(auth.SignatureHeader, auth.Sign(s.secret, method, s.uri, timestamp, s.nonce, digest))
+}
+
+func TestSignedRequests(t *testing.T) {
+	srv, admin, _ := catalog(t)
+	var key controllers.CreatedAPIKey
+	admin.Post("/api/v1/api-keys", controllers.CreateAPIKeyInput{Name: "signer", Scopes: []string{"read", "write"}, Signed: true}).Expect(http.StatusCreated).Data(&key)
+	client := srv.Client(t)
+	body, err := json.Marshal(controllers.CreateAuthorInput{Name: "Octavia E. Butler"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const path = "/api/v1/authors"
+	valid := func() signature {
+		return signature{keyID: key.ID, secret: key.Key, uri: path, at: time.Now(), nonce: uuid.NewString(), payload: body}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		s := valid()
+		signed(client, http.MethodPost, s).Post(path, body).Expect(http.StatusOK)
+		// The same nonce is only accepted once, even signed anew.
+		problem := signed(client, http.MethodPost, s).Post(path, body).ExpectProblem(http.StatusUnauthorized)
+		if problem.Detail != "This request was already received; sign every request with a new nonce." {
+			t.Fatalf("got %q, want the replay refused", problem.Detail)
+		}
+		s.at = s.at.Add(time.Second)
+		signed(client, http.MethodPost, s).Post(path, body).ExpectProblem(http.StatusUnauthorized)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			change func(s *signature)
+			send   []byte
+			detail string
+		}{
+			{"wrong secret", func(s *signature) { s.secret += "x" }, body, "Invalid request signature!"},
+			{"other uri", func(s *signature) { s.uri = path + "?page=2" }, body, "Invalid request signature!"},
+			{"unknown key", func(s *signature) { s.keyID = key.ID + 100 }, body, "Invalid, expired or revoked API key!"},
+			{"tampered body", func(*signature) {}, []byte(`{"name":"Someone Else"}`), "The body doesn't match X-Content-SHA256."},
+			{"too old", func(s *signature) { s.at = s.at.Add(-time.Hour) }, body, "The request signature is too old or from the future; check the client's clock."},
+			{"from the future", func(s *signature) { s.at = s.at.Add(time.Hour) }, body, "The request signature is too old or from the future; check the client's clock."},
+			{"short nonce", func(s *signature) { s.nonce = "abc" }, body, "The signature nonce must be 8 to 128 characters long."},
+		}
+		for _, test := range tests {
+			t.Run(test.name, func(t *testing.T) {
+				s := valid()
+				test.change(&s)
+				problem := signed(client, http.MethodPost, s).Post(path, test.send).ExpectProblem(http.StatusUnauthorized)
+				if problem.Detail != test.detail {
+					t.Fatalf("got %q, want %q", problem.Detail, test.detail)
+				}
+			})
+		}
+	})
+
+	t.Run("nonce kept for valid signatures only", func(t *testing.T) {
+		s := valid()
+		forged := s
+		forged.secret = "guessed"
+		signed(client, http.MethodPost, forged).Post(path, body).ExpectProblem(http.StatusUnauthorized)
+		signed(client, http.MethodPost, s).Post(path, body).Expect(http.StatusOK)
+	})
+
+	t.Run("missing headers", func(t *testing.T) {
+		client.WithHeader(auth.SignatureKeyHeader, strconv.FormatUint(uint64(key.ID), 10)).Post(path, body).ExpectProblem(http.StatusUnauthorized)
+	})
+
+	t.Run("as API key", func(t *testing.T) {
+		problem := client.WithHeader(ratelimit.APIKeyHeader, key.Key).Get("/api/v1/api-keys").ExpectProblem(http.StatusUnauthorized)
+		if problem.Detail != "This API key signs its requests; send the signature headers instead of the key." {
+			t.Fatalf("got %q, want signing required", problem.Detail)
+		}
+	})
+
+	t.Run("read-only key", func(t *testing.T) {
+		var reader controllers.CreatedAPIKey
+		admin.Post("/api/v1/api-keys", controllers.CreateAPIKeyInput{Name: "reader", Scopes: []string{"read"}, Signed: true}).Expect(http.StatusCreated).Data(&reader)
+		s := valid()
+		s.keyID, s.secret = reader.ID, reader.Key
+		problem := signed(client, http.MethodPost, s).Post(path, body).ExpectProblem(http.StatusForbidden)
+		if !bytes.Contains(problem.Extensions["required_scopes"], []byte("write")) {
+			t.Fatalf("got %s, want the write scope required", problem.Extensions["required_scopes"])
+		}
+		s = valid()
+		s.keyID, s.secret, s.uri, s.payload = reader.ID, reader.Key, "/api/v1/authors", nil
+		signed(client, http.MethodGet, s).Get(s.uri).Expect(http.StatusOK)
+	})
+}
@@ -0,0 +1,69 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Broadcaster hands every event it is given to all current subscribers, for
+// clients that keep a connection open to follow the catalog.
+type Broadcaster struct {
+	mu     sync.Mutex
+	subs   map[chan Event]struct{}
+	closed bool
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the events published from now on,
+// and a function to cancel the subscription. A subscriber that lets buffer
+// events pile up is dropped: its channel is closed, as are all channels once
+// the broadcaster is closed.
+func (b *Broadcaster) Subscribe(buffer int) (<-chan Event, func()) {
+	ch := make(chan Event, buffer)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		close(ch)
+		return ch, func() {}
+	}
+	b.subs[ch] = struct{}{}
+	return ch, func() { b.drop(ch) }
+}
+
+// Publish never blocks. It is a Handler.
+func (b *Broadcaster) Publish(_ context.Context, event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends every subscription, so handlers streaming events return and the
+// server can shut down.
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
+
+func (b *Broadcaster) drop(ch chan Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[ch]; ok {
+		delete(b.subs, ch)
+		close(ch)
+	}
+}
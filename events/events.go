@@ -0,0 +1,120 @@
+// Package events carries catalog changes, as recorded by the outbox, to
+// whatever pushes them out of the process, e.g. webhooks.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+)
+
+// Event types.
+const (
+	BookCreated = "book.created"
+	BookUpdated = "book.updated"
+	BookDeleted = "book.deleted"
+)
+
+// Types lists every event type, for validating subscriptions.
+var Types = []string{BookCreated, BookUpdated, BookDeleted}
+
+type Event struct {
+	// ID is unique per event, so receivers can drop duplicates.
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	// Data is the book as written (a *models.Book), or a DeletedBook for
+	// book.deleted.
+	Data interface{} `json:"data"`
+}
+
+// UnmarshalJSON decodes Data into the type its event type calls for.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	type plain Event
+	var raw struct {
+		plain
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*e = Event(raw.plain)
+	switch e.Type {
+	case BookDeleted:
+		var data DeletedBook
+		err := json.Unmarshal(raw.Data, &data)
+		e.Data = data
+		return err
+	default:
+		var data *models.Book
+		err := json.Unmarshal(raw.Data, &data)
+		e.Data = data
+		return err
+	}
+}
+
+// InCategory reports whether the event is about a book in the category.
+func (e Event) InCategory(id uint) bool {
+	switch data := e.Data.(type) {
+	case *models.Book:
+		for _, category := range data.Categories {
+			if category.ID == id {
+				return true
+			}
+		}
+	case DeletedBook:
+		for _, categoryID := range data.CategoryIDs {
+			if categoryID == id {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DeletedBook is the data of a book.deleted event.
+type DeletedBook struct {
+	ID          uuid.UUID `json:"id"`
+	CategoryIDs []uint    `json:"category_ids"`
+}
+
+func New(eventType string, data interface{}) Event {
+	return Event{ID: uuid.NewString(), Type: eventType, OccurredAt: time.Now().UTC(), Data: data}
+}
+
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// Handler receives published events. It runs in the publisher's goroutine,
+// so it must hand slow work off rather than do it inline.
+type Handler func(ctx context.Context, event Event)
+
+// Bus hands every published event to all subscribed handlers, in order of
+// subscription.
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(ctx, event)
+	}
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisRelay carries events between instances over a Redis pub/sub channel,
+// so a client following the catalog on one instance also sees the changes
+// made through the others.
+type RedisRelay struct {
+	client  *redis.Client
+	channel string
+	pubsub  *redis.PubSub
+}
+
+func NewRedisRelay(client *redis.Client, channel string) *RedisRelay {
+	return &RedisRelay{client: client, channel: channel}
+}
+
+// Publish sends the event to every instance, this one included. It is a
+// Handler.
+func (r *RedisRelay) Publish(ctx context.Context, event Event) {
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = r.client.Publish(context.WithoutCancel(ctx), r.channel, payload).Err()
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "relaying event failed", "event", event.Type, "error", err)
+	}
+}
+
+// Forward starts handing the events any instance publishes to local, until
+// Close is called. Events published while Redis is unreachable are lost.
+func (r *RedisRelay) Forward(local Publisher) {
+	r.pubsub = r.client.Subscribe(context.Background(), r.channel)
+	go func() {
+		for msg := range r.pubsub.Channel() {
+			var event Event
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				slog.Error("decoding relayed event failed", "error", err)
+				continue
+			}
+			local.Publish(context.Background(), event)
+		}
+	}()
+}
+
+func (r *RedisRelay) Close(context.Context) error {
+	if r.pubsub == nil {
+		return nil
+	}
+	return r.pubsub.Close()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tabular"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/spf13/cobra"
+)
+
+// newExportCommand builds `export [--format csv|xlsx] [--output FILE]
+// [--tenant SLUG]`, which writes the same file as GET books/export.
+// Without a tenant it exports the books of every tenant.
+func newExportCommand() *cobra.Command {
+	var format, output, tenant string
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the catalog as CSV or XLSX",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := bootstrap()
+			if err != nil {
+				return err
+			}
+			if err := app.CheckMigrations(models.DB, cfg.GinMode); err != nil {
+				return err
+			}
+
+			ctx := cmd.Context()
+			if tenant != "" {
+				found, err := repositories.NewTenantRepository(models.DB).FindBySlug(ctx, tenant)
+				if err != nil {
+					return fmt.Errorf("tenant %q: %w", tenant, err)
+				}
+				ctx = tenancy.NewContext(ctx, found.ID)
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if output != "" {
+				file, err := os.Create(output)
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				out = file
+			}
+			return exportBooks(ctx, format, out)
+		},
+	}
+	cmd.Flags().StringVar(&format, "format", tabular.CSV, "csv or xlsx")
+	cmd.Flags().StringVarP(&output, "output", "o", "", "file to write; stdout if empty")
+	cmd.Flags().StringVar(&tenant, "tenant", "", "slug of the tenant whose books to export")
+	return cmd
+}
+
+func exportBooks(ctx context.Context, format string, out io.Writer) error {
+	books := services.NewBookService(repositories.NewBookRepository(models.DB), repositories.NewAuthorRepository(models.DB), repositories.NewPublisherRepository(models.DB), repositories.NewCategoryRepository(models.DB))
+	_, err := controllers.WriteBookExport(books)(ctx, format, repositories.BookFilter{}, out)
+	return err
+}
@@ -0,0 +1,199 @@
+// Package features turns parts of the API on and off per environment, and
+// at runtime, without a deploy.
+package features
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// The features there are flags for.
+const (
+	// Book reviews: reading, writing and their routes.
+	Reviews = "reviews"
+	// Background exports and catalog reports.
+	Exports = "exports"
+	// Similar books and personal recommendations.
+	Recommendations = "recommendations"
+	// Maintenance turns away every request but those of admins; unlike the
+	// others it is off unless turned on.
+	Maintenance = "maintenance"
+)
+
+// Whether each feature is on when neither the configuration nor an admin
+// says otherwise.
+var defaults = map[string]bool{
+	Reviews:         true,
+	Exports:         true,
+	Recommendations: true,
+	Maintenance:     false,
+}
+
+var ErrUnknownFlag = errors.New("unknown feature flag")
+
+// Store holds the flags admins set at runtime. MemoryStore keeps them per
+// process, until it restarts; RedisStore shares them between instances and
+// keeps them across restarts.
+type Store interface {
+	Overrides(ctx context.Context) (map[string]bool, error)
+	Set(ctx context.Context, name string, enabled bool) error
+	// Unset drops the override, putting the flag back to its configured
+	// value.
+	Unset(ctx context.Context, name string) error
+}
+
+// Flag is the state of a feature flag.
+type Flag struct {
+	Name    string `json:"name" example:"reviews"`
+	Enabled bool   `json:"enabled"`
+	// Whether it is on by configuration, which an override overrules.
+	Configured bool `json:"configured"`
+	Overridden bool `json:"overridden"`
+}
+
+// Flags answers whether features are on: as configured, unless an admin
+// overrode it at runtime. The overrides are read from the store at most
+// once per refresh, so the ones set on other instances take up to that long
+// to apply.
+type Flags struct {
+	configured map[string]bool
+	store      Store
+	refresh    time.Duration
+
+	mu        sync.Mutex
+	overrides map[string]bool
+	loadedAt  time.Time
+}
+
+// New returns the flags with the configured values replacing the defaults;
+// configuring a flag that doesn't exist is an error.
+func New(configured map[string]bool, store Store, refresh time.Duration) (*Flags, error) {
+	values, err := withDefaults(configured)
+	if err != nil {
+		return nil, err
+	}
+	return &Flags{configured: values, store: store, refresh: refresh}, nil
+}
+
+// Configure replaces the configured values, as New took them, leaving the
+// overrides alone. On error nothing changes.
+func (f *Flags) Configure(configured map[string]bool) error {
+	values, err := withDefaults(configured)
+	if err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.configured = values
+	return nil
+}
+
+func withDefaults(configured map[string]bool) (map[string]bool, error) {
+	values := make(map[string]bool, len(defaults))
+	for name, enabled := range defaults {
+		values[name] = enabled
+	}
+	for name, enabled := range configured {
+		if _, ok := defaults[name]; !ok {
+			return nil, fmt.Errorf("%w %q", ErrUnknownFlag, name)
+		}
+		values[name] = enabled
+	}
+	return values, nil
+}
+
+// Enabled tells whether the feature is on. Should the store fail, the last
+// overrides read stay in force.
+func (f *Flags) Enabled(ctx context.Context, name string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.load(ctx)
+	if enabled, ok := f.overrides[name]; ok {
+		return enabled
+	}
+	return f.configured[name]
+}
+
+// load rereads the overrides once they are older than refresh. It must be
+// called with mu held.
+func (f *Flags) load(ctx context.Context) {
+	now := time.Now()
+	if f.overrides != nil && now.Sub(f.loadedAt) < f.refresh {
+		return
+	}
+	// Failures are retried after refresh too, not on every request.
+	f.loadedAt = now
+	overrides, err := f.store.Overrides(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "feature flag overrides unavailable", "error", err)
+		if f.overrides == nil {
+			f.overrides = map[string]bool{}
+		}
+		return
+	}
+	f.overrides = overrides
+}
+
+// List returns every flag, by name.
+func (f *Flags) List(ctx context.Context) ([]Flag, error) {
+	overrides, err := f.store.Overrides(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	f.overrides, f.loadedAt = overrides, time.Now()
+	values := f.configured
+	f.mu.Unlock()
+
+	flags := make([]Flag, 0, len(values))
+	for name, configured := range values {
+		flag := Flag{Name: name, Enabled: configured, Configured: configured}
+		if enabled, ok := overrides[name]; ok {
+			flag.Enabled, flag.Overridden = enabled, true
+		}
+		flags = append(flags, flag)
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Name < flags[j].Name })
+	return flags, nil
+}
+
+// Get returns the flag; ErrUnknownFlag if there is none by that name.
+func (f *Flags) Get(ctx context.Context, name string) (Flag, error) {
+	flags, err := f.List(ctx)
+	if err != nil {
+		return Flag{}, err
+	}
+	for _, flag := range flags {
+		if flag.Name == name {
+			return flag, nil
+		}
+	}
+	return Flag{}, ErrUnknownFlag
+}
+
+// Set overrides the flag until Reset. It applies to this instance at once.
+func (f *Flags) Set(ctx context.Context, name string, enabled bool) (Flag, error) {
+	if _, ok := defaults[name]; !ok {
+		return Flag{}, ErrUnknownFlag
+	}
+	if err := f.store.Set(ctx, name, enabled); err != nil {
+		return Flag{}, err
+	}
+	return f.Get(ctx, name)
+}
+
+// Reset drops the override of the flag, back to its configured value.
+func (f *Flags) Reset(ctx context.Context, name string) (Flag, error) {
+	if _, ok := defaults[name]; !ok {
+		return Flag{}, ErrUnknownFlag
+	}
+	if err := f.store.Unset(ctx, name); err != nil {
+		return Flag{}, err
+	}
+	return f.Get(ctx, name)
+}
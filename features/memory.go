@@ -0,0 +1,39 @@
+package features
+
+import (
+	"context"
+	"sync"
+)
+
+type MemoryStore struct {
+	mu        sync.Mutex
+	overrides map[string]bool
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{overrides: map[string]bool{}}
+}
+
+func (s *MemoryStore) Overrides(_ context.Context) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	overrides := make(map[string]bool, len(s.overrides))
+	for name, enabled := range s.overrides {
+		overrides[name] = enabled
+	}
+	return overrides, nil
+}
+
+func (s *MemoryStore) Set(_ context.Context, name string, enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.overrides[name] = enabled
+	return nil
+}
+
+func (s *MemoryStore) Unset(_ context.Context, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.overrides, name)
+	return nil
+}
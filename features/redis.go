@@ -0,0 +1,41 @@
+package features
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The overrides live in one hash, of flag names to "true" or "false".
+const redisKey = "feature_flags"
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+func (s *RedisStore) Overrides(ctx context.Context) (map[string]bool, error) {
+	values, err := s.client.HGetAll(ctx, redisKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	overrides := make(map[string]bool, len(values))
+	for name, value := range values {
+		if enabled, err := strconv.ParseBool(value); err == nil {
+			overrides[name] = enabled
+		}
+	}
+	return overrides, nil
+}
+
+func (s *RedisStore) Set(ctx context.Context, name string, enabled bool) error {
+	return s.client.HSet(ctx, redisKey, name, strconv.FormatBool(enabled)).Err()
+}
+
+func (s *RedisStore) Unset(ctx context.Context, name string) error {
+	return s.client.HDel(ctx, redisKey, name).Err()
+}
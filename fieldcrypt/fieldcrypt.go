@@ -0,0 +1,210 @@
+// Package fieldcrypt encrypts designated model fields at rest with
+// AES-256-GCM. Fields tagged `gorm:"serializer:encrypted"` are encrypted
+// on the way into the database and decrypted on the way out, so the rest
+// of the code only ever sees plaintext.
+//
+// Values are stored as enc:v1:<key ID>:<base64 nonce and ciphertext>. The
+// key ID picks the key to decrypt with, so keys can be rotated: values
+// written with a previous key stay readable, and are rewritten with the
+// current one the next time they are saved. Values without the prefix,
+// written before encryption was enabled, are read as they are.
+//
+// Encrypted values can't be compared in queries, since each encryption of
+// a value differs. Fields that are looked up or unique get a blind index
+// column next to them, see Hash.
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"gorm.io/gorm/schema"
+)
+
+const prefix = "enc:v1:"
+
+var ErrUnknownKey = errors.New("value encrypted with an unknown key")
+
+type keyring struct {
+	current  string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+var (
+	mu   sync.RWMutex
+	keys = &keyring{aeads: map[string]cipher.AEAD{}}
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Setup loads the keys of cfg. Until it is called, or without a key,
+// encrypted fields are stored in plaintext.
+func Setup(cfg config.EncryptionConfig) error {
+	ring := &keyring{aeads: map[string]cipher.AEAD{}}
+	for i, encoded := range append([]string{cfg.Key}, cfg.PreviousKeys...) {
+		if encoded == "" {
+			continue
+		}
+		key, err := decodeKey(encoded)
+		if err != nil {
+			return err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return err
+		}
+		id := keyID(key)
+		ring.aeads[id] = aead
+		if i == 0 {
+			ring.current = id
+		}
+	}
+	if cfg.IndexKey != "" {
+		key, err := decodeKey(cfg.IndexKey)
+		if err != nil {
+			return err
+		}
+		ring.indexKey = key
+	}
+
+	mu.Lock()
+	keys = ring
+	mu.Unlock()
+	return nil
+}
+
+// Keys are 32 bytes, base64-encoded: `openssl rand -base64 32`.
+func decodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != 32 {
+		return nil, errors.New("encryption keys must be 32 bytes, base64-encoded")
+	}
+	return key, nil
+}
+
+// Enabled reports whether values are encrypted when written.
+func Enabled() bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return keys.current != ""
+}
+
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// Encrypt encrypts plaintext with the current key. Empty values, and all
+// values while encryption is disabled, are returned as they are.
+func Encrypt(plaintext string) (string, error) {
+	mu.RLock()
+	ring := keys
+	mu.RUnlock()
+	if plaintext == "" || ring.current == "" {
+		return plaintext, nil
+	}
+
+	aead := ring.aeads[ring.current]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return prefix + ring.current + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts a value written by Encrypt. Values that aren't
+// encrypted are returned as they are.
+func Decrypt(value string) (string, error) {
+	rest, ok := strings.CutPrefix(value, prefix)
+	if !ok {
+		return value, nil
+	}
+	id, encoded, ok := strings.Cut(rest, ":")
+	if !ok {
+		return "", errors.New("malformed encrypted value")
+	}
+
+	mu.RLock()
+	aead, ok := keys.aeads[id]
+	mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("%w %s", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted value")
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypting value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// Hash returns the blind index of value: an HMAC-SHA256 keyed with the
+// index key, hex-encoded, which a unique index or lookup can use in place
+// of the encrypted value. It doesn't depend on the encryption keys, so
+// rotating them leaves indexes valid; changing the index key doesn't.
+// Without an index key, which config.Validate only allows while fields
+// aren't encrypted, the HMAC is keyed with an empty key: it hides nothing
+// from whoever can compute it too.
+func Hash(value string) string {
+	mu.RLock()
+	key := keys.indexKey
+	mu.RUnlock()
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Serializer is the GORM serializer behind the "encrypted" tag, for string
+// fields.
+type Serializer struct{}
+
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("can't decrypt %T into %s", dbValue, field.Name)
+	}
+
+	plaintext, err := Decrypt(value)
+	if err != nil {
+		return fmt.Errorf("%s: %w", field.Name, err)
+	}
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("can't encrypt %T in %s, only strings", fieldValue, field.Name)
+	}
+	return Encrypt(plaintext)
+}
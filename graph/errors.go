@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// fail turns an error from the service layer into the problem the REST API
+// would answer with. Unexpected errors are logged here since the client only
+// sees a generic message.
+func fail(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUnknownAuthor), errors.Is(err, services.ErrUnknownCategory):
+		return apierrors.Validation(err.Error())
+	case errors.Is(err, services.ErrAuthorHasBooks):
+		return apierrors.Conflict("Author still has books; delete or reassign them first!")
+	}
+
+	problem := apierrors.From(err)
+	if problem.Status >= http.StatusInternalServerError {
+		slog.ErrorContext(ctx, "graphql resolver failed", "error", err)
+	}
+	return problem
+}
+
+// validate checks input against its binding tags.
+func validate(input interface{}) error {
+	if err := binding.Validator.ValidateStruct(input); err != nil {
+		var validationErrs validator.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			return apierrors.Binding(err)
+		}
+		return err
+	}
+	return nil
+}
+
+// presentError exposes problems the way the REST API does: the detail is
+// the message, and the status, type and extension members (e.g. the field
+// errors of a validation problem) go in the extensions.
+func presentError(ctx context.Context, err error) *gqlerror.Error {
+	presented := graphql.DefaultErrorPresenter(ctx, err)
+
+	var problem *apierrors.Problem
+	if !errors.As(err, &problem) {
+		return presented
+	}
+	presented.Message = problem.Error()
+	presented.Extensions = map[string]interface{}{"status": problem.Status, "type": problem.Type}
+	for key, value := range problem.Extensions {
+		presented.Extensions[key] = value
+	}
+	return presented
+}
@@ -0,0 +1,24 @@
+package graph
+
+import (
+	"github.com/geisonsn/rest-api-golang-gin-gorm/graph/model"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+func bookFilter(f model.BookFilter) repositories.BookFilter {
+	var filter repositories.BookFilter
+	if f.AuthorID != nil {
+		filter.AuthorID = *f.AuthorID
+	}
+	if f.CategoryID != nil {
+		filter.CategoryID = *f.CategoryID
+	}
+	if f.Author != nil {
+		filter.Author = *f.Author
+	}
+	if f.Title != nil {
+		filter.TitleContains = *f.Title
+	}
+	filter.YearGte, filter.YearLte = f.YearGte, f.YearLte
+	return filter
+}
@@ -0,0 +1,64 @@
+package graph
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/graph/model"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+)
+
+// Bounds how expensive a single query may be; every field costs one, and
+// list fields multiply their children by the page size.
+const complexityLimit = 5000
+
+// NewHandler serves GraphQL queries over GET and POST. The caller must
+// authenticate the request beforehand; mutations read the identity from
+// the request context.
+func NewHandler(books services.BookService, authors services.AuthorService, categories services.CategoryService) http.Handler {
+	cfg := Config{Resolvers: &Resolver{books: books, authors: authors, categories: categories}}
+	cfg.Directives.HasRole = hasRole
+	cfg.Complexity.Query.Books = listComplexity
+	cfg.Complexity.Query.Authors = func(child int, page, pageSize *int) int { return listComplexity(child, page, pageSize, nil) }
+	cfg.Complexity.Query.Categories = func(child int, page, pageSize *int) int { return listComplexity(child, page, pageSize, nil) }
+
+	srv := handler.New(NewExecutableSchema(cfg))
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.SetQueryCache(lru.New(1000))
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New(100)})
+	srv.Use(extension.FixedComplexityLimit(complexityLimit))
+	srv.SetErrorPresenter(presentError)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := withLoaders(r.Context(), authors, categories)
+		srv.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func listComplexity(child int, _, pageSize *int, _ *model.BookFilter) int {
+	_, limit := pageBounds(nil, pageSize)
+	return 1 + child*limit
+}
+
+// hasRole guards fields marked @hasRole with the same checks as the REST
+// middlewares.
+func hasRole(ctx context.Context, _ interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	identity, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, apierrors.Unauthorized("Missing bearer token!")
+	}
+	if role == model.RoleAdmin && identity.Role != models.RoleAdmin {
+		return nil, apierrors.Forbidden("You do not have permission to perform this action!")
+	}
+	return next(ctx)
+}
@@ -0,0 +1,63 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/google/uuid"
+	"github.com/vikstrous/dataloadgen"
+)
+
+// Loaders batch the lookups the fields of a page of books make, so listing
+// N books with their authors and categories costs three queries, not 2N+1.
+// They live for a single request and cache what they load.
+type loaders struct {
+	authors    *dataloadgen.Loader[uint, *models.Author]
+	categories *dataloadgen.Loader[uuid.UUID, []*models.Category]
+}
+
+type loadersKey struct{}
+
+// How long a loader waits for more keys before running its batch.
+const batchWait = time.Millisecond
+
+func withLoaders(ctx context.Context, authors services.AuthorService, categories services.CategoryService) context.Context {
+	l := &loaders{
+		authors: dataloadgen.NewLoader(func(ctx context.Context, ids []uint) ([]*models.Author, []error) {
+			found, err := authors.GetMany(ctx, ids)
+			if err != nil {
+				return nil, []error{err}
+			}
+			byID := make(map[uint]*models.Author, len(found))
+			for i := range found {
+				byID[found[i].ID] = &found[i]
+			}
+			result := make([]*models.Author, len(ids))
+			for i, id := range ids {
+				result[i] = byID[id]
+			}
+			return result, nil
+		}, dataloadgen.WithWait(batchWait)),
+		categories: dataloadgen.NewLoader(func(ctx context.Context, bookIDs []uuid.UUID) ([][]*models.Category, []error) {
+			byBook, err := categories.ForBooks(ctx, bookIDs)
+			if err != nil {
+				return nil, []error{err}
+			}
+			result := make([][]*models.Category, len(bookIDs))
+			for i, id := range bookIDs {
+				result[i] = make([]*models.Category, 0, len(byBook[id]))
+				for j := range byBook[id] {
+					result[i] = append(result[i], &byBook[id][j])
+				}
+			}
+			return result, nil
+		}, dataloadgen.WithWait(batchWait)),
+	}
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
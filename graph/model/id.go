@@ -0,0 +1,43 @@
+// Package model holds the GraphQL types that don't map directly onto the
+// GORM models: scalars, inputs and pages.
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// MarshalUintID writes the integer IDs of authors and categories as the
+// strings the ID scalar is specified to serialize to.
+func MarshalUintID(id uint) graphql.Marshaler {
+	return graphql.WriterFunc(func(w io.Writer) {
+		io.WriteString(w, strconv.Quote(strconv.FormatUint(uint64(id), 10)))
+	})
+}
+
+// UnmarshalUintID accepts the ID as a string or a number, as the spec
+// requires for input.
+func UnmarshalUintID(v interface{}) (uint, error) {
+	var raw string
+	switch v := v.(type) {
+	case string:
+		raw = v
+	case json.Number:
+		raw = v.String()
+	case int:
+		raw = strconv.Itoa(v)
+	case int64:
+		raw = strconv.FormatInt(v, 10)
+	default:
+		return 0, fmt.Errorf("%T is not a valid ID", v)
+	}
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%q is not a valid ID", raw)
+	}
+	return uint(id), nil
+}
@@ -0,0 +1,52 @@
+package model
+
+// The binding tags mirror the REST inputs', so both APIs accept the same
+// values. They are checked by the resolvers.
+
+type CreateBookInput struct {
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=10000"`
+	AuthorID    uint   `json:"authorId" binding:"required"`
+	Year        int    `json:"year" binding:"omitempty,publication_year"`
+	ISBN        string `json:"isbn" binding:"omitempty,isbn"`
+	Quantity    int    `json:"quantity" binding:"omitempty,min=1,max=10000"`
+}
+
+type UpdateBookInput struct {
+	Version     *uint   `json:"version"`
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=10000"`
+	AuthorID    *uint   `json:"authorId" binding:"omitempty,min=1"`
+	Year        *int    `json:"year" binding:"omitempty,publication_year"`
+	ISBN        *string `json:"isbn" binding:"omitempty,isbn"`
+}
+
+type CreateAuthorInput struct {
+	Name string `json:"name" binding:"required,min=2,max=255"`
+	Bio  string `json:"bio" binding:"max=2000"`
+}
+
+type UpdateAuthorInput struct {
+	Name string `json:"name" binding:"omitempty,min=2,max=255"`
+	Bio  string `json:"bio" binding:"max=2000"`
+}
+
+type CategoryInput struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type BookFilter struct {
+	AuthorID   *uint   `json:"authorId"`
+	CategoryID *uint   `json:"categoryId"`
+	Author     *string `json:"author"`
+	Title      *string `json:"title"`
+	YearGte    *int    `json:"yearGte"`
+	YearLte    *int    `json:"yearLte"`
+}
+
+type PageInfo struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"pageSize"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"totalPages"`
+}
@@ -0,0 +1,71 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package model
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+type AuthorPage struct {
+	Items    []*models.Author `json:"items"`
+	PageInfo *PageInfo        `json:"pageInfo"`
+}
+
+type BookPage struct {
+	Items    []*models.Book `json:"items"`
+	PageInfo *PageInfo      `json:"pageInfo"`
+}
+
+type CategoryPage struct {
+	Items    []*models.Category `json:"items"`
+	PageInfo *PageInfo          `json:"pageInfo"`
+}
+
+type Mutation struct {
+}
+
+type Query struct {
+}
+
+type Role string
+
+const (
+	RoleAdmin Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
@@ -0,0 +1,41 @@
+package graph
+
+import "github.com/geisonsn/rest-api-golang-gin-gorm/graph/model"
+
+// Same defaults and cap as the REST API's ?page= and ?page_size=.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func pageBounds(page, pageSize *int) (int, int) {
+	p, size := 1, defaultPageSize
+	if page != nil && *page > 1 {
+		p = *page
+	}
+	if pageSize != nil && *pageSize > 0 {
+		size = *pageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+	return p, size
+}
+
+func pageInfo(page, size int, total int64) *model.PageInfo {
+	return &model.PageInfo{
+		Page:       page,
+		PageSize:   size,
+		Total:      total,
+		TotalPages: int((total + int64(size) - 1) / int64(size)),
+	}
+}
+
+// pointers returns a pointer to each element of items.
+func pointers[T any](items []T) []*T {
+	result := make([]*T, len(items))
+	for i := range items {
+		result[i] = &items[i]
+	}
+	return result
+}
@@ -0,0 +1,15 @@
+// Package graph serves the catalog over GraphQL. The schema lives in
+// schema.graphqls; generated.go and models_gen.go are produced from it by
+// gqlgen (see gqlgen.yml) and must not be edited by hand.
+//
+// Resolvers only translate between GraphQL and the service layer the REST
+// controllers use, so both APIs enforce the same rules.
+package graph
+
+import "github.com/geisonsn/rest-api-golang-gin-gorm/services"
+
+type Resolver struct {
+	books      services.BookService
+	authors    services.AuthorService
+	categories services.CategoryService
+}
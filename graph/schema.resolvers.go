@@ -0,0 +1,237 @@
+package graph
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen version v0.17.45
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/graph/model"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/google/uuid"
+)
+
+// Author is the resolver for the author field.
+func (r *bookResolver) Author(ctx context.Context, obj *models.Book) (*models.Author, error) {
+	if obj.Author != nil {
+		return obj.Author, nil
+	}
+	author, err := loadersFrom(ctx).authors.Load(ctx, obj.AuthorID)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return author, nil
+}
+
+// Categories is the resolver for the categories field.
+func (r *bookResolver) Categories(ctx context.Context, obj *models.Book) ([]*models.Category, error) {
+	categories, err := loadersFrom(ctx).categories.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return categories, nil
+}
+
+// CreateBook is the resolver for the createBook field.
+func (r *mutationResolver) CreateBook(ctx context.Context, input model.CreateBookInput) (*models.Book, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	book := models.Book{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, Year: input.Year, ISBN: input.ISBN, Quantity: input.Quantity}
+	if err := r.books.Create(ctx, &book); err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &book, nil
+}
+
+// UpdateBook is the resolver for the updateBook field.
+func (r *mutationResolver) UpdateBook(ctx context.Context, id uuid.UUID, input model.UpdateBookInput) (*models.Book, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	patch := services.BookPatch{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, Year: input.Year, ISBN: input.ISBN}
+	if input.Version != nil {
+		patch.Version = *input.Version
+	}
+	book, err := r.books.Patch(ctx, id, patch, nil)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return book, nil
+}
+
+// DeleteBook is the resolver for the deleteBook field.
+func (r *mutationResolver) DeleteBook(ctx context.Context, id uuid.UUID) (bool, error) {
+	if err := r.books.Delete(ctx, id, nil); err != nil {
+		return false, fail(ctx, err)
+	}
+	return true, nil
+}
+
+// CreateAuthor is the resolver for the createAuthor field.
+func (r *mutationResolver) CreateAuthor(ctx context.Context, input model.CreateAuthorInput) (*models.Author, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	author := models.Author{Name: input.Name, Bio: input.Bio}
+	if err := r.authors.Create(ctx, &author); err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &author, nil
+}
+
+// UpdateAuthor is the resolver for the updateAuthor field.
+func (r *mutationResolver) UpdateAuthor(ctx context.Context, id uint, input model.UpdateAuthorInput) (*models.Author, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	author, err := r.authors.Update(ctx, id, models.Author{Name: input.Name, Bio: input.Bio})
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return author, nil
+}
+
+// DeleteAuthor is the resolver for the deleteAuthor field.
+func (r *mutationResolver) DeleteAuthor(ctx context.Context, id uint) (bool, error) {
+	if err := r.authors.Delete(ctx, id); err != nil {
+		return false, fail(ctx, err)
+	}
+	return true, nil
+}
+
+// CreateCategory is the resolver for the createCategory field.
+func (r *mutationResolver) CreateCategory(ctx context.Context, input model.CategoryInput) (*models.Category, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	category := models.Category{Name: input.Name}
+	if err := r.categories.Create(ctx, &category); err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &category, nil
+}
+
+// UpdateCategory is the resolver for the updateCategory field.
+func (r *mutationResolver) UpdateCategory(ctx context.Context, id uint, input model.CategoryInput) (*models.Category, error) {
+	if err := validate(&input); err != nil {
+		return nil, err
+	}
+
+	category, err := r.categories.Update(ctx, id, models.Category{Name: input.Name})
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return category, nil
+}
+
+// DeleteCategory is the resolver for the deleteCategory field.
+func (r *mutationResolver) DeleteCategory(ctx context.Context, id uint) (bool, error) {
+	if err := r.categories.Delete(ctx, id); err != nil {
+		return false, fail(ctx, err)
+	}
+	return true, nil
+}
+
+// Books is the resolver for the books field.
+func (r *queryResolver) Books(ctx context.Context, page *int, pageSize *int, filter *model.BookFilter) (*model.BookPage, error) {
+	p, size := pageBounds(page, pageSize)
+	opts := repositories.BookListOptions{Offset: (p - 1) * size, Limit: size}
+	if filter != nil {
+		opts.Filter = bookFilter(*filter)
+	}
+
+	books, total, err := r.books.List(ctx, opts)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &model.BookPage{Items: pointers(books), PageInfo: pageInfo(p, size, total)}, nil
+}
+
+// Book is the resolver for the book field.
+func (r *queryResolver) Book(ctx context.Context, id *uuid.UUID, slug *string) (*models.Book, error) {
+	var book *models.Book
+	var err error
+	switch {
+	case id != nil && slug == nil:
+		book, err = r.books.Get(ctx, *id)
+	case slug != nil && id == nil:
+		book, err = r.books.GetBySlug(ctx, *slug)
+	default:
+		return nil, apierrors.Validation("Exactly one of id and slug must be given.")
+	}
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return book, nil
+}
+
+// Authors is the resolver for the authors field.
+func (r *queryResolver) Authors(ctx context.Context, page *int, pageSize *int) (*model.AuthorPage, error) {
+	p, size := pageBounds(page, pageSize)
+	authors, total, err := r.authors.List(ctx, (p-1)*size, size)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &model.AuthorPage{Items: pointers(authors), PageInfo: pageInfo(p, size, total)}, nil
+}
+
+// Author is the resolver for the author field.
+func (r *queryResolver) Author(ctx context.Context, id uint) (*models.Author, error) {
+	author, err := r.authors.Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return author, nil
+}
+
+// Categories is the resolver for the categories field.
+func (r *queryResolver) Categories(ctx context.Context, page *int, pageSize *int) (*model.CategoryPage, error) {
+	p, size := pageBounds(page, pageSize)
+	categories, total, err := r.categories.List(ctx, (p-1)*size, size)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &model.CategoryPage{Items: pointers(categories), PageInfo: pageInfo(p, size, total)}, nil
+}
+
+// Category is the resolver for the category field.
+func (r *queryResolver) Category(ctx context.Context, id uint) (*models.Category, error) {
+	category, err := r.categories.Get(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return category, nil
+}
+
+// Book returns BookResolver implementation.
+func (r *Resolver) Book() BookResolver { return &bookResolver{r} }
+
+// Mutation returns MutationResolver implementation.
+func (r *Resolver) Mutation() MutationResolver { return &mutationResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type bookResolver struct{ *Resolver }
+type mutationResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
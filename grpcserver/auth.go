@@ -0,0 +1,61 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	bookstorev1 "github.com/geisonsn/rest-api-golang-gin-gorm/proto/bookstore/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Methods anyone may call, as their REST counterparts are. All others take
+// an admin's token, so that a method added later isn't public by mistake.
+var publicMethods = map[string]bool{
+	bookstorev1.BookService_GetBook_FullMethodName:   true,
+	bookstorev1.BookService_ListBooks_FullMethodName: true,
+	healthpb.Health_Check_FullMethodName:             true,
+}
+
+// authenticate reads a bearer token from the "authorization" metadata and
+// stores the caller's identity in the context, like middlewares.OptionalAuth.
+// Calls to methods other than the public ones are rejected without an
+// admin's token.
+func authenticate(cfg config.AuthConfig, revoked auth.RevocationList) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		var header string
+		if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+			header = values[0]
+		}
+
+		identity, authenticated := auth.Identity{}, false
+		if header != "" {
+			token, ok := strings.CutPrefix(header, "Bearer ")
+			if !ok || token == "" {
+				return nil, status.Error(codes.Unauthenticated, "Missing bearer token!")
+			}
+			var err error
+			if identity, err = auth.Authenticate(ctx, cfg, revoked, token); err != nil {
+				return nil, status.Error(codes.Unauthenticated, "Invalid or expired token!")
+			}
+			authenticated = true
+			ctx = auth.NewContext(ctx, identity)
+		}
+
+		if !publicMethods[info.FullMethod] {
+			if !authenticated {
+				return nil, status.Error(codes.Unauthenticated, "Missing bearer token!")
+			}
+			if identity.Role != models.RoleAdmin {
+				return nil, status.Error(codes.PermissionDenied, "You do not have permission to perform this action!")
+			}
+		}
+		return handler(ctx, req)
+	}
+}
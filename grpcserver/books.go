@@ -0,0 +1,199 @@
+package grpcserver
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	bookstorev1 "github.com/geisonsn/rest-api-golang-gin-gorm/proto/bookstore/v1"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Same defaults and cap as the REST API's ?page= and ?page_size=.
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// The binding tags mirror the REST inputs', so every API accepts the same
+// values.
+
+type createBookInput struct {
+	Title       string `json:"title" binding:"required,max=255"`
+	Description string `json:"description" binding:"max=10000"`
+	AuthorID    uint   `json:"author_id" binding:"required"`
+	Year        int    `json:"year" binding:"omitempty,publication_year"`
+	ISBN        string `json:"isbn" binding:"omitempty,isbn"`
+	Quantity    int    `json:"quantity" binding:"omitempty,min=1,max=10000"`
+}
+
+type updateBookInput struct {
+	Title       *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string `json:"description" binding:"omitempty,max=10000"`
+	AuthorID    *uint   `json:"author_id" binding:"omitempty,min=1"`
+	Year        *int    `json:"year" binding:"omitempty,publication_year"`
+	ISBN        *string `json:"isbn" binding:"omitempty,isbn"`
+}
+
+type bookServer struct {
+	bookstorev1.UnimplementedBookServiceServer
+	books services.BookService
+}
+
+func (s *bookServer) GetBook(ctx context.Context, req *bookstorev1.GetBookRequest) (*bookstorev1.Book, error) {
+	var book *models.Book
+	var err error
+	switch key := req.Key.(type) {
+	case *bookstorev1.GetBookRequest_Id:
+		id, parseErr := parseID(key.Id)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		book, err = s.books.Get(ctx, id)
+	case *bookstorev1.GetBookRequest_Slug:
+		book, err = s.books.GetBySlug(ctx, key.Slug)
+	default:
+		return nil, status.Error(codes.InvalidArgument, "Exactly one of id and slug must be given.")
+	}
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return toProto(book), nil
+}
+
+func (s *bookServer) ListBooks(ctx context.Context, req *bookstorev1.ListBooksRequest) (*bookstorev1.ListBooksResponse, error) {
+	page, size := int(req.Page), int(req.PageSize)
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 {
+		size = defaultPageSize
+	}
+	if size > maxPageSize {
+		size = maxPageSize
+	}
+
+	opts := repositories.BookListOptions{
+		Offset: (page - 1) * size,
+		Limit:  size,
+		Filter: repositories.BookFilter{
+			AuthorID:      uint(req.AuthorId),
+			CategoryID:    uint(req.CategoryId),
+			Author:        req.Author,
+			TitleContains: req.Title,
+			YearGte:       intPtr(req.YearGte),
+			YearLte:       intPtr(req.YearLte),
+		},
+	}
+	books, total, err := s.books.List(ctx, opts)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	resp := &bookstorev1.ListBooksResponse{Books: make([]*bookstorev1.Book, 0, len(books)), Total: total}
+	for i := range books {
+		resp.Books = append(resp.Books, toProto(&books[i]))
+	}
+	return resp, nil
+}
+
+func (s *bookServer) CreateBook(ctx context.Context, req *bookstorev1.CreateBookRequest) (*bookstorev1.Book, error) {
+	input := createBookInput{
+		Title:       req.Title,
+		Description: req.Description,
+		AuthorID:    uint(req.AuthorId),
+		Year:        int(req.Year),
+		ISBN:        req.Isbn,
+		Quantity:    int(req.Quantity),
+	}
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	book := models.Book{Title: input.Title, Description: input.Description, AuthorID: input.AuthorID, Year: input.Year, ISBN: input.ISBN, Quantity: input.Quantity}
+	if err := s.books.Create(ctx, &book); err != nil {
+		return nil, fail(ctx, err)
+	}
+	return toProto(&book), nil
+}
+
+func (s *bookServer) UpdateBook(ctx context.Context, req *bookstorev1.UpdateBookRequest) (*bookstorev1.Book, error) {
+	id, err := parseID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	input := updateBookInput{Title: req.Title, Description: req.Description, Year: intPtr(req.Year), ISBN: req.Isbn}
+	if req.AuthorId != nil {
+		authorID := uint(*req.AuthorId)
+		input.AuthorID = &authorID
+	}
+	if err := binding.Validator.ValidateStruct(&input); err != nil {
+		return nil, fail(ctx, err)
+	}
+
+	patch := services.BookPatch{
+		Version:     uint(req.Version),
+		Title:       input.Title,
+		Description: input.Description,
+		AuthorID:    input.AuthorID,
+		Year:        input.Year,
+		ISBN:        input.ISBN,
+	}
+	book, err := s.books.Patch(ctx, id, patch, nil)
+	if err != nil {
+		return nil, fail(ctx, err)
+	}
+	return toProto(book), nil
+}
+
+func (s *bookServer) DeleteBook(ctx context.Context, req *bookstorev1.DeleteBookRequest) (*emptypb.Empty, error) {
+	id, err := parseID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.books.Delete(ctx, id, nil); err != nil {
+		return nil, fail(ctx, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func parseID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, status.Error(codes.InvalidArgument, "id must be a valid UUID")
+	}
+	return id, nil
+}
+
+func intPtr(v *int32) *int {
+	if v == nil {
+		return nil
+	}
+	i := int(*v)
+	return &i
+}
+
+func toProto(book *models.Book) *bookstorev1.Book {
+	return &bookstorev1.Book{
+		Id:              book.ID.String(),
+		Slug:            book.Slug,
+		Title:           book.Title,
+		Description:     book.Description,
+		AuthorId:        uint32(book.AuthorID),
+		Year:            int32(book.Year),
+		Isbn:            book.ISBN,
+		Quantity:        int32(book.Quantity),
+		AvailableCopies: int32(book.AvailableCopies),
+		RatingAverage:   book.RatingAverage,
+		ReviewCount:     int32(book.ReviewCount),
+		Version:         uint32(book.Version),
+		CreatedAt:       timestamppb.New(book.CreatedAt),
+		UpdatedAt:       timestamppb.New(book.UpdatedAt),
+	}
+}
@@ -0,0 +1,68 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// fail turns an error from the service layer into a status carrying the
+// message the REST API would answer with. Field errors are attached as a
+// BadRequest detail. Unexpected errors are logged here since the client
+// only sees a generic message.
+func fail(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, services.ErrUnknownAuthor), errors.Is(err, services.ErrUnknownCategory):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, repositories.ErrDuplicate):
+		return status.Error(codes.AlreadyExists, apierrors.From(err).Error())
+	case errors.Is(err, repositories.ErrStaleVersion):
+		return status.Error(codes.Aborted, apierrors.From(err).Error())
+	}
+
+	problem := apierrors.From(err)
+	code := codeFor(problem.Status)
+	if code == codes.Internal {
+		slog.ErrorContext(ctx, "grpc handler failed", "error", err)
+	}
+
+	st := status.New(code, problem.Error())
+	if fields, ok := problem.Extensions["errors"].([]apierrors.FieldError); ok {
+		violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(fields))
+		for _, field := range fields {
+			violations = append(violations, &errdetails.BadRequest_FieldViolation{Field: field.Field, Description: field.Message})
+		}
+		if detailed, err := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); err == nil {
+			st = detailed
+		}
+	}
+	return st.Err()
+}
+
+func codeFor(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	}
+	return codes.Internal
+}
@@ -0,0 +1,59 @@
+// Package grpcserver serves the catalog over gRPC (see proto/bookstore/v1)
+// for internal consumers. It sits beside the HTTP controllers and calls the
+// same services.
+package grpcserver
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	bookstorev1 "github.com/geisonsn/rest-api-golang-gin-gorm/proto/bookstore/v1"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+)
+
+// New returns a server exposing the book service, the standard health
+// service and reflection, so tools like grpcurl work without the .proto
+// files.
+func New(authCfg config.AuthConfig, revoked auth.RevocationList, tenancyCfg config.TenancyConfig, tenants services.TenantService, books services.BookService) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(logRequests, recoverPanics, authenticate(authCfg, revoked), resolveTenant(tenancyCfg, tenants)))
+	bookstorev1.RegisterBookServiceServer(srv, &bookServer{books: books})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	reflection.Register(srv)
+	return srv
+}
+
+// logRequests logs each call like the HTTP access log does.
+func logRequests(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	code := status.Code(err)
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "grpc request", "method", info.FullMethod, "code", code.String(), "duration", time.Since(start))
+	return resp, err
+}
+
+// recoverPanics answers INTERNAL instead of crashing the process, as
+// gin.Recovery does for HTTP.
+func recoverPanics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.ErrorContext(ctx, "grpc handler panicked", "method", info.FullMethod, "panic", r, "stack", string(debug.Stack()))
+			err = status.Error(codes.Internal, "An unexpected error occurred.")
+		}
+	}()
+	return handler(ctx, req)
+}
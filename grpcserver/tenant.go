@@ -0,0 +1,47 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tenantFinder interface {
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+}
+
+// resolveTenant reads the tenant's slug from the metadata named like the
+// HTTP header, x-tenant by default, and acts for it as middlewares.Tenant
+// does; calls naming none are for the default tenant. Callers who aren't
+// members of the tenant are refused.
+func resolveTenant(cfg config.TenancyConfig, tenants tenantFinder) grpc.UnaryServerInterceptor {
+	key := strings.ToLower(cfg.Header)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := tenancy.DefaultID
+		if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 && values[0] != "" {
+			slug := strings.ToLower(values[0])
+			tenant, err := tenants.FindBySlug(ctx, slug)
+			if errors.Is(err, repositories.ErrNotFound) {
+				return nil, status.Errorf(codes.NotFound, "Unknown tenant %q!", slug)
+			}
+			if err != nil {
+				return nil, fail(ctx, err)
+			}
+			id = tenant.ID
+		}
+		if identity, ok := auth.FromContext(ctx); ok && !identity.MemberOf(id) {
+			return nil, status.Error(codes.PermissionDenied, "You are not a member of this tenant!")
+		}
+		return handler(tenancy.NewContext(ctx, id), req)
+	}
+}
@@ -0,0 +1,130 @@
+// Package httpcache sets the caching headers of the public read endpoints:
+// Cache-Control, with a max age for lists and one for single records,
+// Vary, and Last-Modified, answering If-Modified-Since with 304 when
+// nothing changed.
+//
+// What a response is made of can't be dated row by row: embedded records,
+// stock and review counts change without touching a book's updated_at. So
+// Last-Modified is the last time any table the route reads was written
+// to, as the GORM plugin records it; coarse, but never too old.
+package httpcache
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Policies hands out the caching middleware of each route. A nil *Policies
+// hands out middleware that does nothing.
+type Policies struct {
+	store        Store
+	list, detail time.Duration
+	vary         []string
+
+	mu sync.RWMutex
+	// The tables some route reads, which are those writes are recorded for.
+	tables map[string]bool
+}
+
+// New returns the policies cfg configures, recording writes in store.
+// Responses vary by the vary headers, those that authenticate the client
+// or pick the tenant.
+func New(store Store, cfg config.HTTPCacheConfig, vary ...string) *Policies {
+	return &Policies{store: store, list: cfg.ListMaxAge, detail: cfg.DetailMaxAge, vary: vary, tables: map[string]bool{}}
+}
+
+// List is the policy of a route listing records read from tables.
+func (p *Policies) List(tables ...string) gin.HandlerFunc {
+	if p == nil {
+		return next
+	}
+	return p.policy(p.list, tables)
+}
+
+// Detail is the policy of a route serving a single record read from tables.
+func (p *Policies) Detail(tables ...string) gin.HandlerFunc {
+	if p == nil {
+		return next
+	}
+	return p.policy(p.detail, tables)
+}
+
+func next(c *gin.Context) { c.Next() }
+
+func (p *Policies) policy(maxAge time.Duration, tables []string) gin.HandlerFunc {
+	p.mu.Lock()
+	for _, table := range tables {
+		p.tables[table] = true
+	}
+	p.mu.Unlock()
+
+	directive := "no-cache"
+	if maxAge > 0 {
+		directive = "max-age=" + strconv.Itoa(int(maxAge/time.Second))
+	}
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		// Shared caches must not hand what a client was shown to others.
+		scope := "public"
+		if c.GetHeader("Authorization") != "" || c.GetHeader("X-API-Key") != "" {
+			scope = "private"
+		}
+		header.Set("Cache-Control", scope+", "+directive)
+		for _, name := range p.vary {
+			header.Add("Vary", name)
+		}
+
+		modified, err := p.store.LastModified(c.Request.Context(), tables)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "reading last modification failed", "error", err)
+		}
+		// Dates have whole seconds: one in the current second could be
+		// followed by another write within it, which it wouldn't tell
+		// apart.
+		if err == nil && modified.Before(time.Now().Truncate(time.Second)) {
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+			// If-None-Match takes precedence, and the handler checks it.
+			if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && c.GetHeader("If-None-Match") == "" && !modified.Truncate(time.Second).After(since) {
+				c.AbortWithStatus(http.StatusNotModified)
+				return
+			}
+		}
+
+		// Kept once the handler returns: errors are written after, by
+		// apierrors.Middleware.
+		c.Writer = &policyWriter{ResponseWriter: c.Writer}
+		c.Next()
+	}
+}
+
+// policyWriter drops the caching headers from responses that aren't the
+// record or list asked for, such as errors.
+type policyWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *policyWriter) WriteHeader(status int) {
+	if status >= http.StatusMultipleChoices && status != http.StatusNotModified {
+		w.Header().Del("Cache-Control")
+		w.Header().Del("Last-Modified")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// read reports whether some route reads table.
+func (p *Policies) read(table string) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.tables[table]
+}
@@ -0,0 +1,56 @@
+package httpcache
+
+import (
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// settle is how much later than they happen writes are recorded. The
+// transaction a write is part of commits after it, and until it does,
+// readers see the data from before; a Last-Modified dating that data after
+// the write would make them keep it.
+const settle = 5 * time.Second
+
+// GormPlugin returns the plugin recording the writes to the tables routes
+// read, for their Last-Modified. Raw SQL counts as a write to every table.
+func (p *Policies) GormPlugin() gorm.Plugin {
+	return gormPlugin{p}
+}
+
+type gormPlugin struct {
+	policies *Policies
+}
+
+func (gormPlugin) Name() string { return "httpcache" }
+
+func (pl gormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("httpcache:create", pl.touch); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("httpcache:update", pl.touch); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("httpcache:delete", pl.touch); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("httpcache:raw", pl.touch)
+}
+
+func (pl gormPlugin) touch(db *gorm.DB) {
+	stmt := db.Statement
+	if db.Error != nil || db.RowsAffected == 0 {
+		return
+	}
+	table := stmt.Table
+	if table == "" {
+		table = AllTables
+	} else if !pl.policies.read(table) {
+		return
+	}
+	if err := pl.policies.store.Touch(stmt.Context, table, time.Now().Add(settle)); err != nil {
+		slog.WarnContext(stmt.Context, "recording modification failed", "table", table, "error", err)
+	}
+}
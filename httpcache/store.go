@@ -0,0 +1,116 @@
+package httpcache
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// AllTables is the table recorded for writes that may have changed any,
+// such as raw SQL.
+const AllTables = "*"
+
+// Store remembers when tables were last written to. MemoryStore remembers
+// the writes of this process only; RedisStore shares them between
+// instances.
+type Store interface {
+	Touch(ctx context.Context, table string, t time.Time) error
+	// LastModified returns the last time any of tables was written to, or
+	// AllTables was; tables never written to since the store started count
+	// as written to then.
+	LastModified(ctx context.Context, tables []string) (time.Time, error)
+}
+
+type MemoryStore struct {
+	mu       sync.Mutex
+	started  time.Time
+	modified map[string]time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{started: time.Now(), modified: map[string]time.Time{}}
+}
+
+func (s *MemoryStore) Touch(_ context.Context, table string, t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if t.After(s.modified[table]) {
+		s.modified[table] = t
+	}
+	return nil
+}
+
+func (s *MemoryStore) LastModified(_ context.Context, tables []string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	latest := s.started
+	// Capped, so that appending doesn't write to the caller's array.
+	for _, table := range append(tables[:len(tables):len(tables)], AllTables) {
+		if t := s.modified[table]; t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, nil
+}
+
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(client *redis.Client) *RedisStore {
+	return &RedisStore{client: client}
+}
+
+// The script keeps the latest of the times instances write.
+var touchScript = redis.NewScript(`
+local current = tonumber(redis.call("GET", KEYS[1]) or "0")
+if tonumber(ARGV[1]) > current then
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+func (s *RedisStore) Touch(ctx context.Context, table string, t time.Time) error {
+	return touchScript.Run(ctx, s.client, []string{modifiedKey(table)}, t.UnixMilli()).Err()
+}
+
+func (s *RedisStore) LastModified(ctx context.Context, tables []string) (time.Time, error) {
+	all := append(tables[:len(tables):len(tables)], AllTables)
+	keys := make([]string, len(all))
+	for i, table := range all {
+		keys[i] = modifiedKey(table)
+	}
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var latest int64
+	for i, v := range values {
+		if v == nil {
+			// Not written to since Redis lost its data, if ever: from now
+			// on it counts as written to now, for every instance.
+			now := time.Now().UnixMilli()
+			if err := s.client.SetNX(ctx, keys[i], now, 0).Err(); err != nil {
+				return time.Time{}, err
+			}
+			v, err = s.client.Get(ctx, keys[i]).Result()
+			if err != nil && !errors.Is(err, redis.Nil) {
+				return time.Time{}, err
+			}
+		}
+		s, _ := v.(string)
+		if ms, err := strconv.ParseInt(s, 10, 64); err == nil && ms > latest {
+			latest = ms
+		}
+	}
+	return time.UnixMilli(latest), nil
+}
+
+func modifiedKey(table string) string {
+	return "http_cache:modified:" + table
+}
@@ -0,0 +1,75 @@
+package httpclient
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// breaker opens after threshold consecutive requests to host fail. Once
+// cooldown has passed it lets a single request through to probe the host,
+// closing again if it succeeds. A nil *breaker is always closed.
+type breaker struct {
+	host      string
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	// Zero while closed.
+	openedAt time.Time
+	probing  bool
+}
+
+func (b *breaker) allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return true
+	case b.probing || time.Since(b.openedAt) < b.cooldown:
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *breaker) record(failed bool) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := !b.openedAt.IsZero()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		if wasOpen {
+			slog.Info("host reachable again, closed circuit breaker", "host", b.host)
+		}
+		return
+	}
+
+	b.failures++
+	if wasOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if !wasOpen {
+			slog.Warn("host failing, opened circuit breaker", "host", b.host, "failures", b.failures, "cooldown", b.cooldown.String())
+		}
+	}
+}
+
+// abandon is for a request allowed through that ended without telling
+// whether the host works, so another may probe it.
+func (b *breaker) abandon() {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
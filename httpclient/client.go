@@ -0,0 +1,159 @@
+// Package httpclient makes the requests to other services, such as the ISBN
+// catalogs, the exchange rates API and webhooks, so they all time out,
+// retry and fail fast the same way, with metrics by host.
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/metrics"
+)
+
+// ErrCircuitOpen is returned instead of making a request to a host whose
+// circuit breaker is open.
+var ErrCircuitOpen = errors.New("httpclient: circuit breaker open")
+
+type Options struct {
+	// Bounds each attempt, reading the headers of the response included.
+	Timeout time.Duration
+	// How many times a failed request is retried, waiting Backoff before
+	// the first retry and twice as long before each next one; see Do.
+	Retries int
+	Backoff time.Duration
+	// After BreakerThreshold failures in a row, requests to the host fail
+	// fast for BreakerCooldown. 0 disables the breakers.
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// As in http.Client; nil follows up to 10 redirects.
+	CheckRedirect func(req *http.Request, via []*http.Request) error
+	// As in http.Client; nil uses http.DefaultTransport.
+	Transport http.RoundTripper
+}
+
+// Client is an http.Client retrying failed requests with exponential
+// backoff, and keeping a circuit breaker per host.
+type Client struct {
+	http    *http.Client
+	retries int
+	backoff time.Duration
+	// Zero disables the breakers.
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	breakers map[string]*breaker
+}
+
+func New(opts Options) *Client {
+	return &Client{
+		http:      &http.Client{Timeout: opts.Timeout, CheckRedirect: opts.CheckRedirect, Transport: opts.Transport},
+		retries:   opts.Retries,
+		backoff:   opts.Backoff,
+		threshold: opts.BreakerThreshold,
+		cooldown:  opts.BreakerCooldown,
+		breakers:  map[string]*breaker{},
+	}
+}
+
+// Do sends req like http.Client.Do. Network errors, timeouts, 429s and 5xx
+// responses are retried, unless req has a body that can't be read again
+// (see http.Request.GetBody). The response of the last attempt is returned
+// as is, whatever its status.
+//
+// Network errors, timeouts and 5xx responses count as failures of the host;
+// after enough in a row, requests to it fail with ErrCircuitOpen until the
+// cooldown has passed.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	host := req.URL.Host
+	b := c.breaker(host)
+	retries := c.retries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+
+	if !b.allow() {
+		metrics.OutboundRejected(host)
+		return nil, ErrCircuitOpen
+	}
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(req, attempt)
+		switch {
+		case err != nil && ctx.Err() != nil:
+			// Cancelled by the caller, which says nothing about the host.
+			b.abandon()
+			return nil, err
+		case err != nil:
+			metrics.OutboundRequest(host, "error")
+			b.record(true)
+		default:
+			metrics.OutboundRequest(host, strconv.Itoa(resp.StatusCode))
+			b.record(resp.StatusCode >= 500)
+		}
+
+		if attempt >= retries || !retryable(resp, err) {
+			return resp, err
+		}
+		select {
+		case <-ctx.Done():
+			if resp != nil {
+				resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		case <-time.After(c.backoff << attempt):
+		}
+		// A retry the breaker refuses leaves the last attempt's answer.
+		if !b.allow() {
+			metrics.OutboundRejected(host)
+			return resp, err
+		}
+		if resp != nil {
+			// Read what was sent so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+	}
+}
+
+// send makes an attempt at req, with a fresh copy of its body after the
+// first.
+func (c *Client) send(req *http.Request, attempt int) (*http.Response, error) {
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		req = req.Clone(req.Context())
+		req.Body = body
+	}
+	start := time.Now()
+	defer func() { metrics.OutboundDuration(req.URL.Host, time.Since(start)) }()
+	return c.http.Do(req)
+}
+
+func retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
+
+func (c *Client) breaker(host string) *breaker {
+	if c.threshold == 0 {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, ok := c.breakers[host]
+	if !ok {
+		b = &breaker{host: host, threshold: c.threshold, cooldown: c.cooldown}
+		c.breakers[host] = b
+	}
+	return b
+}
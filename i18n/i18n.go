@@ -0,0 +1,100 @@
+// Package i18n translates the messages the API answers with into the
+// languages clients ask for with Accept-Language.
+//
+// Messages are written in English in the code, and the English text is
+// what the catalogs in locales are keyed by: each is a JSON object of
+// English messages to their translation, named after its language tag.
+// Messages with arguments are keyed by their format string, such as
+// "Rate limit exceeded, retry in %ds.", and translated before being
+// filled in. A message missing from a catalog falls back to the catalog of
+// the parent language, e.g. pt for pt-BR, and then to English.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// English is what messages are written in, and the language answered with
+// when none of those asked for is supported.
+var English = language.English
+
+//go:embed locales/*.json
+var locales embed.FS
+
+var (
+	catalogs = map[language.Tag]map[string]string{}
+	// English first, so the matcher falls back to it.
+	supported = []language.Tag{English}
+	matcher   language.Matcher
+)
+
+func init() {
+	files, err := locales.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		tag, err := language.Parse(strings.TrimSuffix(file.Name(), path.Ext(file.Name())))
+		if err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", file.Name(), err))
+		}
+		raw, err := locales.ReadFile("locales/" + file.Name())
+		if err != nil {
+			panic(err)
+		}
+		messages := map[string]string{}
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("i18n: %s: %v", file.Name(), err))
+		}
+		catalogs[tag] = messages
+		if tag != English {
+			supported = append(supported, tag)
+		}
+	}
+	matcher = language.NewMatcher(supported)
+}
+
+// Match returns the supported language that best suits an Accept-Language
+// header, English if none does or the header is empty or malformed.
+func Match(acceptLanguage string) language.Tag {
+	if acceptLanguage == "" {
+		return English
+	}
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return English
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return English
+	}
+	return supported[index]
+}
+
+// T translates format into lang, falling back through its parents to
+// English, then fills it in with args as fmt.Sprintf does. Without args,
+// the message is returned as translated, %s and all.
+func T(lang language.Tag, format string, args ...interface{}) string {
+	message := lookup(lang, format)
+	if len(args) == 0 {
+		return message
+	}
+	return fmt.Sprintf(message, args...)
+}
+
+func lookup(lang language.Tag, message string) string {
+	for tag := lang; ; tag = tag.Parent() {
+		if translated, ok := catalogs[tag][message]; ok {
+			return translated
+		}
+		if tag == language.Und {
+			return message
+		}
+	}
+}
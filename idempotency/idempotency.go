@@ -0,0 +1,59 @@
+// Package idempotency lets clients retry a create request safely: a request
+// sent with an Idempotency-Key header runs once, and retries with the same
+// key get the first response back instead of creating the resource again.
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+var (
+	// ErrInProgress means another request with the key hasn't finished.
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+	// ErrMismatch means the key was used for a different request.
+	ErrMismatch = errors.New("idempotency key was used for a different request")
+)
+
+// How long a request may hold its key before another request can claim it,
+// in case the instance running it dies before releasing it.
+const lockTTL = time.Minute
+
+// Response is what a request with a key answered, kept to replay it.
+type Response struct {
+	// Fingerprint identifies the request: its method, path and body.
+	Fingerprint string      `json:"fingerprint"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Store keeps the responses. MemoryStore keeps them per process;
+// RedisStore shares them between instances.
+type Store interface {
+	// Begin claims key for the request with fingerprint. It returns the
+	// response of an earlier request with the key instead when there is
+	// one, ErrInProgress while such a request is running and ErrMismatch if
+	// it had another fingerprint.
+	Begin(ctx context.Context, key, fingerprint string) (*Response, error)
+	// Complete keeps the response of the request holding key.
+	Complete(ctx context.Context, key string, response Response) error
+	// Release frees key without keeping a response, so the request can be
+	// retried.
+	Release(ctx context.Context, key string) error
+}
+
+// replay checks that existing, the claim or response found under a key,
+// was made by the request with fingerprint.
+func replay(existing *Response, fingerprint string) (*Response, error) {
+	if existing.Fingerprint != fingerprint {
+		return nil, ErrMismatch
+	}
+	if !existing.Done {
+		return nil, ErrInProgress
+	}
+	return existing, nil
+}
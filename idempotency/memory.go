@@ -0,0 +1,68 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	response Response
+	expires  time.Time
+}
+
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu        sync.Mutex
+	entries   map[string]*entry
+	lastSweep time.Time
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: map[string]*entry{}, lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Begin(_ context.Context, key, fingerprint string) (*Response, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		response := e.response
+		return replay(&response, fingerprint)
+	}
+	s.entries[key] = &entry{response: Response{Fingerprint: fingerprint}, expires: now.Add(lockTTL)}
+	return nil, nil
+}
+
+func (s *MemoryStore) Complete(_ context.Context, key string, response Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	response.Done = true
+	s.entries[key] = &entry{response: response, expires: time.Now().Add(s.ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
+
+// sweep drops expired entries. It runs at most once a minute.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < time.Minute {
+		return
+	}
+	s.lastSweep = now
+	for key, e := range s.entries {
+		if !now.Before(e.expires) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	Header = "Idempotency-Key"
+	// Set on replayed responses.
+	ReplayedHeader = "Idempotent-Replayed"
+)
+
+const maxKeyLength = 255
+
+// Response headers worth replaying; the rest describe the original
+// exchange (request ID, rate limit) rather than its outcome.
+var replayedHeaders = []string{"Content-Type", "Location", "ETag", "Link", "Vary"}
+
+// Middleware makes requests carrying an Idempotency-Key header run once per
+// key and caller. Retries get the first response back, flagged with
+// Idempotent-Replayed: true; a retry while the first request is still
+// running is rejected with 409, and reusing a key for a different request
+// (other method, path or body) with 422, as the IETF Idempotency-Key draft
+// has it.
+//
+// Only successful responses are kept: a request that fails doesn't hold on
+// to its key, so it can be retried once the problem is fixed. Requests
+// without the header run as usual, as do all of them if the store fails.
+func Middleware(store Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			apierrors.Abort(c, apierrors.Validation("%s must be at most %d characters.").WithArgs(Header, maxKeyLength))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			apierrors.Abort(c, apierrors.From(err))
+			return
+		case err != nil:
+			apierrors.Abort(c, apierrors.BadRequest("Could not read the request body."))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		ctx := c.Request.Context()
+		key = callerKey(c) + ":" + key
+		sum := fingerprint(c.Request, body)
+		existing, err := store.Begin(ctx, key, sum)
+		switch {
+		case errors.Is(err, ErrInProgress):
+			apierrors.Abort(c, apierrors.Conflict("A request with this %s is still in progress; retry later.").WithArgs(Header))
+			return
+		case errors.Is(err, ErrMismatch):
+			apierrors.Abort(c, apierrors.New(http.StatusUnprocessableEntity, "This %s was already used for a different request.").WithArgs(Header))
+			return
+		case err != nil:
+			slog.WarnContext(ctx, "idempotency store unavailable", "error", err)
+			c.Next()
+			return
+		case existing != nil:
+			for name, values := range existing.Header {
+				c.Writer.Header()[name] = values
+			}
+			c.Header(ReplayedHeader, "true")
+			c.Data(existing.Status, existing.Header.Get("Content-Type"), existing.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &recorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+		c.Writer = recorder.ResponseWriter
+
+		// Errors attached with c.Error are only rendered once the chain
+		// unwinds, so a handler that failed may not have written anything
+		// yet.
+		status := c.Writer.Status()
+		if len(c.Errors) > 0 || status < 200 || status >= 300 {
+			if err := store.Release(ctx, key); err != nil {
+				slog.WarnContext(ctx, "releasing idempotency key", "error", err)
+			}
+			return
+		}
+		response := Response{Fingerprint: sum, Status: status, Header: http.Header{}, Body: recorder.body.Bytes()}
+		for _, name := range replayedHeaders {
+			if values := c.Writer.Header().Values(name); len(values) > 0 {
+				response.Header[name] = values
+			}
+		}
+		if err := store.Complete(ctx, key, response); err != nil {
+			slog.WarnContext(ctx, "storing idempotent response", "error", err)
+		}
+	}
+}
+
+// Keys are chosen by clients, so each caller gets its own, in each tenant.
+func callerKey(c *gin.Context) string {
+	caller := "ip:" + c.ClientIP()
+	if identity, ok := auth.FromContext(c.Request.Context()); ok {
+		caller = "user:" + strconv.FormatUint(uint64(identity.UserID), 10)
+	}
+	if id, ok := tenancy.FromContext(c.Request.Context()); ok {
+		caller = "tenant:" + strconv.FormatUint(uint64(id), 10) + ":" + caller
+	}
+	return caller
+}
+
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// recorder keeps a copy of the response body.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
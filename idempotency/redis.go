@@ -0,0 +1,61 @@
+package idempotency
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisStore struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRedisStore(client *redis.Client, ttl time.Duration) *RedisStore {
+	return &RedisStore{client: client, ttl: ttl}
+}
+
+func (s *RedisStore) Begin(ctx context.Context, key, fingerprint string) (*Response, error) {
+	claim, err := json.Marshal(Response{Fingerprint: fingerprint})
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.client.SetNX(ctx, redisKey(key), claim, lockTTL).Result()
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	data, err := s.client.Get(ctx, redisKey(key)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		// Released or expired in between; the client can try again.
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	var existing Response
+	if err := json.Unmarshal(data, &existing); err != nil {
+		return nil, err
+	}
+	return replay(&existing, fingerprint)
+}
+
+func (s *RedisStore) Complete(ctx context.Context, key string, response Response) error {
+	response.Done = true
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisKey(key), data, s.ttl).Err()
+}
+
+func (s *RedisStore) Release(ctx context.Context, key string) error {
+	return s.client.Del(ctx, redisKey(key)).Err()
+}
+
+func redisKey(key string) string {
+	return "idempotency:" + key
+}
@@ -0,0 +1,82 @@
+// Package images validates uploaded images and renders thumbnails.
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupported   = errors.New("image must be a JPEG, PNG, GIF or WebP")
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// Extensions for the accepted content types, sniffed from the data rather
+// than trusted from the client.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// Detect returns the content type and file extension of data, or
+// ErrUnsupported.
+func Detect(data []byte) (contentType, ext string, err error) {
+	contentType = http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return "", "", ErrUnsupported
+	}
+	return contentType, ext, nil
+}
+
+// Thumbnail decodes data and scales it down to fit in size x size pixels,
+// keeping the aspect ratio; smaller images are not enlarged. JPEGs stay
+// JPEGs, everything else becomes PNG to keep transparency.
+//
+// Images of more than maxPixels pixels are ErrTooManyPixels. Their
+// dimensions are read from the header first: a small file can declare
+// enough of them for decoding it to exhaust memory.
+func Thumbnail(data []byte, contentType string, size, maxPixels int) ([]byte, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxPixels/config.Height {
+		return nil, "", ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", ErrUnsupported
+	}
+
+	bounds := src.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+		return buf.Bytes(), contentType, err
+	}
+	err = png.Encode(&buf, dst)
+	return buf.Bytes(), "image/png", err
+}
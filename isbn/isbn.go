@@ -0,0 +1,90 @@
+// Package isbn checks and normalizes International Standard Book Numbers.
+package isbn
+
+import "strings"
+
+// Normalize strips the hyphens and spaces ISBNs are usually printed with and
+// uppercases the ISBN-10 check character: "0-306-40615-x" becomes
+// "030640615X". It doesn't validate s.
+func Normalize(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '-' || r == ' ':
+		case r == 'x':
+			b.WriteRune('X')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Valid reports whether s, once normalized, is an ISBN-10 or ISBN-13 with a
+// correct check digit.
+func Valid(s string) bool {
+	s = Normalize(s)
+	switch len(s) {
+	case 10:
+		return valid10(s)
+	case 13:
+		return valid13(s)
+	}
+	return false
+}
+
+// To13 returns the ISBN-13 form of a valid ISBN (normalized), so both forms
+// of the same book compare equal. It returns "" for invalid input.
+func To13(s string) string {
+	s = Normalize(s)
+	switch {
+	case len(s) == 13 && valid13(s):
+		return s
+	case len(s) == 10 && valid10(s):
+		body := "978" + s[:9]
+		return body + string(rune('0'+check13(body)))
+	}
+	return ""
+}
+
+// The ISBN-10 check digit makes the sum of the digits weighted 10 down to 1
+// a multiple of 11; a check value of 10 is written X.
+func valid10(s string) bool {
+	sum := 0
+	for i := 0; i < 10; i++ {
+		var d int
+		switch c := s[i]; {
+		case c >= '0' && c <= '9':
+			d = int(c - '0')
+		case c == 'X' && i == 9:
+			d = 10
+		default:
+			return false
+		}
+		sum += d * (10 - i)
+	}
+	return sum%11 == 0
+}
+
+func valid13(s string) bool {
+	for i := 0; i < 13; i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return check13(s[:12]) == int(s[12]-'0')
+}
+
+// check13 computes the ISBN-13 check digit of the first twelve digits,
+// weighted alternately 1 and 3.
+func check13(body string) int {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		d := int(body[i] - '0')
+		if i%2 == 1 {
+			d *= 3
+		}
+		sum += d
+	}
+	return (10 - sum%10) % 10
+}
@@ -0,0 +1,274 @@
+// Package jobs runs maintenance tasks in the background: on a cron schedule,
+// or on demand through the admin API.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrUnknownJob means no job is registered under the name.
+	ErrUnknownJob = errors.New("no such job")
+	// ErrAlreadyQueued means the job is already waiting for, or running on,
+	// a worker.
+	ErrAlreadyQueued = errors.New("job is already queued or running")
+)
+
+// What started a run.
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+)
+
+// Job states.
+const (
+	StateIdle    = "idle"
+	StateQueued  = "queued"
+	StateRunning = "running"
+)
+
+// Job is a task the runner runs. Jobs must be safe to run again, and
+// concurrently on several instances: every instance runs its own schedule.
+type Job struct {
+	Name string
+	// A cron expression (minute hour day month weekday) or a descriptor
+	// such as @daily or @every 1h; empty runs the job only on demand.
+	Schedule string
+	Run      func(ctx context.Context) error
+}
+
+// Run is the outcome of one run of a job.
+type Run struct {
+	Trigger    string    `json:"trigger"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMS int64     `json:"duration_ms"`
+	Succeeded  bool      `json:"succeeded"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Status describes a job as this instance sees it; nothing is kept across
+// restarts.
+type Status struct {
+	Name     string `json:"name"`
+	Schedule string `json:"schedule"`
+	// idle, queued or running.
+	State     string     `json:"state"`
+	NextRunAt *time.Time `json:"next_run_at"`
+	LastRun   *Run       `json:"last_run"`
+	Runs      int        `json:"runs"`
+	Failures  int        `json:"failures"`
+}
+
+type job struct {
+	Job
+	entry    cron.EntryID
+	state    string
+	lastRun  *Run
+	runs     int
+	failures int
+}
+
+type request struct {
+	job     *job
+	trigger string
+}
+
+// Runner hands due jobs to a pool of workers. A job is never queued twice:
+// a run that comes due while the previous one is still queued or running is
+// skipped.
+type Runner struct {
+	cron    *cron.Cron
+	queue   chan request
+	workers sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+func NewRunner(workers int) *Runner {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &Runner{
+		cron:   cron.New(),
+		queue:  make(chan request, 16),
+		ctx:    ctx,
+		cancel: cancel,
+		jobs:   map[string]*job{},
+	}
+	for i := 0; i < workers; i++ {
+		r.workers.Add(1)
+		go r.work()
+	}
+	return r
+}
+
+// Register adds a job; call it before Start.
+func (r *Runner) Register(j Job) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.jobs[j.Name]; ok {
+		return fmt.Errorf("job %q registered twice", j.Name)
+	}
+
+	registered := &job{Job: j, state: StateIdle}
+	if j.Schedule != "" {
+		entry, err := r.cron.AddFunc(j.Schedule, func() {
+			if err := r.enqueue(registered, TriggerSchedule); err != nil {
+				slog.Warn("skipping scheduled job", "job", j.Name, "error", err)
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		registered.entry = entry
+	}
+	r.jobs[j.Name] = registered
+	return nil
+}
+
+// Start starts the schedule.
+func (r *Runner) Start() {
+	r.cron.Start()
+}
+
+// Stop stops the schedule and cancels the context of running jobs, then
+// waits until ctx is done for them to return.
+func (r *Runner) Stop(ctx context.Context) error {
+	<-r.cron.Stop().Done()
+	r.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		r.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Trigger queues the job to run now.
+func (r *Runner) Trigger(name string) error {
+	r.mu.Lock()
+	j, ok := r.jobs[name]
+	r.mu.Unlock()
+	if !ok {
+		return ErrUnknownJob
+	}
+	return r.enqueue(j, TriggerManual)
+}
+
+func (r *Runner) Statuses() []Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statuses := make([]Status, 0, len(r.jobs))
+	for _, j := range r.jobs {
+		statuses = append(statuses, r.status(j))
+	}
+	sort.Slice(statuses, func(i, k int) bool { return statuses[i].Name < statuses[k].Name })
+	return statuses
+}
+
+func (r *Runner) Status(name string) (Status, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j, ok := r.jobs[name]
+	if !ok {
+		return Status{}, ErrUnknownJob
+	}
+	return r.status(j), nil
+}
+
+// status must be called with r.mu held.
+func (r *Runner) status(j *job) Status {
+	status := Status{Name: j.Name, Schedule: j.Schedule, State: j.state, Runs: j.runs, Failures: j.failures}
+	if j.lastRun != nil {
+		run := *j.lastRun
+		status.LastRun = &run
+	}
+	if j.entry != 0 {
+		if next := r.cron.Entry(j.entry).Next; !next.IsZero() {
+			status.NextRunAt = &next
+		}
+	}
+	return status
+}
+
+func (r *Runner) enqueue(j *job, trigger string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if j.state != StateIdle {
+		return ErrAlreadyQueued
+	}
+	select {
+	case r.queue <- request{job: j, trigger: trigger}:
+		j.state = StateQueued
+		return nil
+	default:
+		return ErrAlreadyQueued
+	}
+}
+
+func (r *Runner) work() {
+	defer r.workers.Done()
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case req := <-r.queue:
+			r.run(req)
+		}
+	}
+}
+
+func (r *Runner) run(req request) {
+	j := req.job
+	r.mu.Lock()
+	j.state = StateRunning
+	r.mu.Unlock()
+
+	run := Run{Trigger: req.trigger, StartedAt: time.Now()}
+	err := safely(r.ctx, j.Run)
+	run.FinishedAt = time.Now()
+	run.DurationMS = run.FinishedAt.Sub(run.StartedAt).Milliseconds()
+	run.Succeeded = err == nil
+	if err != nil {
+		run.Error = err.Error()
+		slog.Error("job failed", "job", j.Name, "trigger", req.trigger, "error", err)
+	} else {
+		slog.Info("job finished", "job", j.Name, "trigger", req.trigger, "duration_ms", run.DurationMS)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	j.state = StateIdle
+	j.lastRun = &run
+	j.runs++
+	if err != nil {
+		j.failures++
+	}
+}
+
+// safely turns a panic in fn into an error, so a failing job doesn't take
+// the worker, or the process, down with it.
+func safely(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,180 @@
+// Package live feeds the admin channel, which pushes what happens in this
+// instance to the admins following it, for live dashboards, and runs the
+// commands they send back.
+//
+// Besides the catalog events every instance shares, the channel carries
+// loans, the errors reported and metrics snapshots of the instance it is
+// connected to only.
+package live
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/reporting"
+)
+
+// Event types, besides those of the catalog.
+const (
+	LoanCreated  = "loan.created"
+	LoanReturned = "loan.returned"
+	// Data is a ReportedError.
+	ErrorReported = "error.reported"
+	// Data is a metrics.Snapshot.
+	MetricsSnapshot = "metrics.snapshot"
+	// Data is the Result of a command.
+	CommandResult = "command.result"
+)
+
+// Commands admins can send.
+const (
+	// Turns maintenance mode on or off, as the maintenance feature flag.
+	CommandMaintenance = "maintenance"
+	// Drops the cached reads.
+	CommandInvalidateCache = "invalidate-cache"
+)
+
+var (
+	ErrUnknownCommand  = errors.New("unknown command")
+	ErrEnabledRequired = errors.New("enabled is required")
+	ErrUnknownCache    = errors.New("unknown cache")
+	ErrNoCache         = errors.New("no cache is configured")
+)
+
+// ReportedError is an error.reported event: a panic, or an error a 5xx
+// response was made of.
+type ReportedError struct {
+	Message   string `json:"message"`
+	Panic     bool   `json:"panic"`
+	Status    int    `json:"status"`
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	RequestID string `json:"request_id"`
+	UserID    uint   `json:"user_id,omitempty"`
+	TenantID  uint   `json:"tenant_id,omitempty"`
+}
+
+// Reporter publishes the errors reported to it as error.reported events.
+type Reporter struct {
+	feed events.Publisher
+}
+
+func NewReporter(feed events.Publisher) *Reporter {
+	return &Reporter{feed: feed}
+}
+
+func (r *Reporter) Report(ctx context.Context, event reporting.Event) {
+	reported := ReportedError{
+		Message:   event.Err.Error(),
+		Panic:     event.Stack != nil,
+		Status:    event.Status,
+		RequestID: event.RequestID,
+		UserID:    event.UserID,
+		TenantID:  event.TenantID,
+	}
+	if event.Request != nil {
+		reported.Method, reported.Path = event.Request.Method, event.Request.URL.Path
+	}
+	r.feed.Publish(ctx, events.New(ErrorReported, reported))
+}
+
+// Flush has nothing to wait for: events are published as they are reported.
+func (r *Reporter) Flush(context.Context) error {
+	return nil
+}
+
+// Command is what admins send over the channel, as JSON.
+type Command struct {
+	// ID is sent back with the result, to tell which command it answers.
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	// Whether maintenance mode is turned on or off.
+	Enabled *bool `json:"enabled"`
+	// The cache invalidate-cache drops, or every cache when empty.
+	Cache string `json:"cache"`
+}
+
+// Result is what a command did, or why it failed.
+type Result struct {
+	ID      string `json:"id"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Data    any    `json:"data,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Flags are the feature flags the maintenance command sets;
+// *features.Flags implements it.
+type Flags interface {
+	Set(ctx context.Context, name string, enabled bool) (features.Flag, error)
+}
+
+// Commands runs the commands of admins.
+type Commands struct {
+	flags  Flags
+	caches map[string]*cache.Cache
+}
+
+// NewCommands invalidates caches by name; there are none without Redis.
+func NewCommands(flags Flags, caches map[string]*cache.Cache) *Commands {
+	return &Commands{flags: flags, caches: caches}
+}
+
+// Run runs the command of the user, logging it.
+func (c *Commands) Run(ctx context.Context, userID uint, command Command) Result {
+	result := Result{ID: command.ID, Command: command.Command}
+	var err error
+	switch command.Command {
+	case CommandMaintenance:
+		result.Data, err = c.maintenance(ctx, command)
+	case CommandInvalidateCache:
+		result.Data, err = c.invalidateCache(ctx, command)
+	default:
+		err = ErrUnknownCommand
+	}
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	slog.InfoContext(ctx, "admin command run", "command", command.Command, "user_id", userID)
+	result.OK = true
+	return result
+}
+
+func (c *Commands) maintenance(ctx context.Context, command Command) (any, error) {
+	if command.Enabled == nil {
+		return nil, ErrEnabledRequired
+	}
+	return c.flags.Set(ctx, features.Maintenance, *command.Enabled)
+}
+
+// invalidated lists the caches invalidate-cache dropped.
+type invalidated struct {
+	Caches []string `json:"caches"`
+}
+
+func (c *Commands) invalidateCache(ctx context.Context, command Command) (any, error) {
+	if len(c.caches) == 0 {
+		return nil, ErrNoCache
+	}
+	var names []string
+	if command.Cache == "" {
+		for name := range c.caches {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	} else if _, ok := c.caches[command.Cache]; ok {
+		names = []string{command.Cache}
+	} else {
+		return nil, ErrUnknownCache
+	}
+	for _, name := range names {
+		c.caches[name].Invalidate(ctx)
+	}
+	return invalidated{Caches: names}, nil
+}
@@ -0,0 +1,69 @@
+// Package logging provides the application's structured JSON logger.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/requestid"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// New builds a JSON logger writing to stdout at the given level (debug,
+// info, warn or error) and installs it as the slog and log default.
+func New(level string) *slog.Logger {
+	return newLogger(os.Stdout, level)
+}
+
+// NewSink builds a JSON logger writing to w, for records kept apart from
+// the application log. It doesn't become the default.
+func NewSink(w io.Writer) *slog.Logger {
+	return slog.New(&requestIDHandler{slog.NewJSONHandler(w, nil)})
+}
+
+// level is the level of the default logger, which SetLevel changes.
+var level slog.LevelVar
+
+// SetLevel changes the level of the logger New built, as it logs.
+// Unknown levels are taken for info.
+func SetLevel(name string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(name)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	level.Set(lvl)
+}
+
+func newLogger(w io.Writer, name string) *slog.Logger {
+	SetLevel(name)
+	logger := slog.New(&requestIDHandler{slog.NewJSONHandler(w, &slog.HandlerOptions{Level: &level})})
+	slog.SetDefault(logger)
+	return logger
+}
+
+// requestIDHandler adds request_id (and trace_id/span_id when a span is
+// active) to every record logged with a context that carries one, so
+// handlers only need slog.InfoContext(ctx, ...).
+type requestIDHandler struct {
+	slog.Handler
+}
+
+func (h *requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := requestid.FromContext(ctx); id != "" {
+		r.AddAttrs(slog.String(requestid.Key, id))
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h *requestIDHandler) WithGroup(name string) slog.Handler {
+	return &requestIDHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,103 @@
+package lookup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+)
+
+// Store remembers lookups: Fetch returns the metadata stored under isbn, or
+// calls load and stores its result. Failed loads, including ErrNotFound,
+// are not stored.
+type Store interface {
+	Fetch(ctx context.Context, isbn string, load func() (*Metadata, error)) (*Metadata, error)
+}
+
+type cached struct {
+	provider Provider
+	store    Store
+}
+
+// Cached serves repeated lookups of the same ISBN from store; catalog
+// entries rarely change and the APIs are rate limited.
+func Cached(provider Provider, store Store) Provider {
+	return &cached{provider: provider, store: store}
+}
+
+func (c *cached) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	return c.store.Fetch(ctx, isbn, func() (*Metadata, error) {
+		return c.provider.Lookup(ctx, isbn)
+	})
+}
+
+type redisStore struct {
+	cache *cache.Cache
+}
+
+// NewRedisStore shares the lookups with every instance through c.
+func NewRedisStore(c *cache.Cache) Store {
+	return &redisStore{cache: c}
+}
+
+func (s *redisStore) Fetch(ctx context.Context, isbn string, load func() (*Metadata, error)) (*Metadata, error) {
+	return cache.Fetch(ctx, s.cache, isbn, load)
+}
+
+type memoryEntry struct {
+	metadata *Metadata
+	expires  time.Time
+}
+
+// MemoryStore keeps lookups in process for ttl, up to maxEntries of them.
+type MemoryStore struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore(ttl time.Duration, maxEntries int) *MemoryStore {
+	return &MemoryStore{ttl: ttl, maxEntries: maxEntries, entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryStore) Fetch(_ context.Context, isbn string, load func() (*Metadata, error)) (*Metadata, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.entries[isbn]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.metadata, nil
+	}
+
+	metadata, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.entries) >= s.maxEntries {
+		s.evict(now)
+	}
+	s.entries[isbn] = memoryEntry{metadata: metadata, expires: now.Add(s.ttl)}
+	return metadata, nil
+}
+
+// evict drops the expired entries, or an arbitrary one if none has expired.
+func (s *MemoryStore) evict(now time.Time) {
+	for key, entry := range s.entries {
+		if !now.Before(entry.expires) {
+			delete(s.entries, key)
+		}
+	}
+	for key := range s.entries {
+		if len(s.entries) < s.maxEntries {
+			break
+		}
+		delete(s.entries, key)
+	}
+}
@@ -0,0 +1,58 @@
+package lookup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpclient"
+)
+
+// Client makes the catalog requests, through an httpclient.Client timing
+// out, retrying and failing fast as configured for lookups.
+type Client struct {
+	http *httpclient.Client
+}
+
+func NewClient(http *httpclient.Client) *Client {
+	return &Client{http: http}
+}
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.status)
+}
+
+// getJSON decodes the JSON body of a GET to url into v.
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	if err := c.get(ctx, url, v); err != nil {
+		return fmt.Errorf("GET %s: %w", url, err)
+	}
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "bookstore-api")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return &statusError{status: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
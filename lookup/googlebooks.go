@@ -0,0 +1,67 @@
+package lookup
+
+import (
+	"context"
+	"net/url"
+	"strings"
+)
+
+// GoogleBooks queries the Google Books volumes API. The API key is optional
+// but raises the quota.
+type GoogleBooks struct {
+	client  *Client
+	baseURL string
+	apiKey  string
+}
+
+func NewGoogleBooks(client *Client, baseURL, apiKey string) *GoogleBooks {
+	return &GoogleBooks{client: client, baseURL: baseURL, apiKey: apiKey}
+}
+
+type googleBooksVolumes struct {
+	Items []struct {
+		VolumeInfo struct {
+			Title         string   `json:"title"`
+			Subtitle      string   `json:"subtitle"`
+			Authors       []string `json:"authors"`
+			Publisher     string   `json:"publisher"`
+			PublishedDate string   `json:"publishedDate"`
+			Description   string   `json:"description"`
+			ImageLinks    struct {
+				Thumbnail string `json:"thumbnail"`
+			} `json:"imageLinks"`
+		} `json:"volumeInfo"`
+	} `json:"items"`
+}
+
+func (p *GoogleBooks) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	query := url.Values{"q": {"isbn:" + isbn}}
+	if p.apiKey != "" {
+		query.Set("key", p.apiKey)
+	}
+
+	var volumes googleBooksVolumes
+	if err := p.client.getJSON(ctx, p.baseURL+"/volumes?"+query.Encode(), &volumes); err != nil {
+		return nil, err
+	}
+	if len(volumes.Items) == 0 {
+		return nil, ErrNotFound
+	}
+
+	info := volumes.Items[0].VolumeInfo
+	metadata := &Metadata{
+		ISBN:        isbn,
+		Title:       info.Title,
+		Authors:     info.Authors,
+		Publisher:   info.Publisher,
+		Year:        year(info.PublishedDate),
+		Description: info.Description,
+		// Image links come as http URLs; the https ones serve the same image.
+		CoverURL: strings.Replace(info.ImageLinks.Thumbnail, "http://", "https://", 1),
+		Source:   "googlebooks",
+	}
+	if info.Subtitle != "" {
+		metadata.Title += ": " + info.Subtitle
+	}
+	return metadata, nil
+}
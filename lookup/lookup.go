@@ -0,0 +1,76 @@
+// Package lookup fetches book metadata by ISBN from public catalogs
+// (OpenLibrary, Google Books).
+package lookup
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNotFound means no catalog knows the ISBN.
+var ErrNotFound = errors.New("no book found for this ISBN")
+
+// Metadata is what a catalog knows about an edition. Fields a catalog
+// doesn't provide are left empty.
+type Metadata struct {
+	ISBN        string   `json:"isbn"`
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors"`
+	Publisher   string   `json:"publisher,omitempty"`
+	Year        int      `json:"year,omitempty"`
+	Description string   `json:"description,omitempty"`
+	CoverURL    string   `json:"cover_url,omitempty"`
+	// Source names the catalog the metadata came from.
+	Source string `json:"source"`
+}
+
+// Provider looks up a normalized ISBN-13, failing with ErrNotFound when the
+// book is unknown.
+type Provider interface {
+	Lookup(ctx context.Context, isbn string) (*Metadata, error)
+}
+
+type chain []Provider
+
+// Chain asks each provider in turn and returns the first match. If none
+// matches, it fails with ErrNotFound when they all answered, or with the
+// first error otherwise, so an outage isn't reported as an unknown ISBN.
+func Chain(providers ...Provider) Provider {
+	return chain(providers)
+}
+
+func (c chain) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	var firstErr error
+	for _, provider := range c {
+		metadata, err := provider.Lookup(ctx, isbn)
+		if err == nil {
+			return metadata, nil
+		}
+		if !errors.Is(err, ErrNotFound) && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return nil, ErrNotFound
+}
+
+// year extracts the year from the loosely formatted dates catalogs return,
+// e.g. "1988", "2004-05-01" or "May 2004".
+func year(date string) int {
+	for i := 0; i+4 <= len(date); i++ {
+		y := 0
+		for _, c := range date[i : i+4] {
+			if c < '0' || c > '9' {
+				y = -1
+				break
+			}
+			y = y*10 + int(c-'0')
+		}
+		if y > 0 {
+			return y
+		}
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package lookup
+
+import (
+	"context"
+	"net/url"
+)
+
+// OpenLibrary queries the Open Library Books API.
+type OpenLibrary struct {
+	client  *Client
+	baseURL string
+}
+
+func NewOpenLibrary(client *Client, baseURL string) *OpenLibrary {
+	return &OpenLibrary{client: client, baseURL: baseURL}
+}
+
+type openLibraryBook struct {
+	Title    string `json:"title"`
+	Subtitle string `json:"subtitle"`
+	Authors  []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Publishers []struct {
+		Name string `json:"name"`
+	} `json:"publishers"`
+	PublishDate string `json:"publish_date"`
+	Cover       struct {
+		Large  string `json:"large"`
+		Medium string `json:"medium"`
+	} `json:"cover"`
+}
+
+func (p *OpenLibrary) Lookup(ctx context.Context, isbn string) (*Metadata, error) {
+	key := "ISBN:" + isbn
+	query := url.Values{"bibkeys": {key}, "format": {"json"}, "jscmd": {"data"}}
+
+	// Unknown ISBNs get an empty object rather than a 404.
+	var books map[string]openLibraryBook
+	if err := p.client.getJSON(ctx, p.baseURL+"/api/books?"+query.Encode(), &books); err != nil {
+		return nil, err
+	}
+	book, ok := books[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	metadata := &Metadata{ISBN: isbn, Title: book.Title, Year: year(book.PublishDate), Source: "openlibrary"}
+	if book.Subtitle != "" {
+		metadata.Title += ": " + book.Subtitle
+	}
+	for _, author := range book.Authors {
+		metadata.Authors = append(metadata.Authors, author.Name)
+	}
+	if len(book.Publishers) > 0 {
+		metadata.Publisher = book.Publishers[0].Name
+	}
+	metadata.CoverURL = book.Cover.Large
+	if metadata.CoverURL == "" {
+		metadata.CoverURL = book.Cover.Medium
+	}
+	return metadata, nil
+}
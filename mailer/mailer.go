@@ -0,0 +1,38 @@
+// Package mailer sends the emails of the account flows: address
+// verification and password resets.
+package mailer
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+)
+
+// Message is a plain-text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns an SMTP mailer when cfg has an SMTP host, and one that only
+// logs the messages otherwise, for development.
+func New(cfg config.MailConfig) Mailer {
+	if cfg.SMTP.Host == "" {
+		return Log{}
+	}
+	return NewSMTP(cfg.SMTP, cfg.From)
+}
+
+// Log writes messages to the log instead of sending them.
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	slog.InfoContext(ctx, "email not sent, no SMTP server configured", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+	return nil
+}
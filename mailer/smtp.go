@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+)
+
+// SMTP sends messages through an SMTP server, authenticating with PLAIN
+// when it has a username. The connection is upgraded with STARTTLS when the
+// server offers it.
+type SMTP struct {
+	cfg  config.SMTPConfig
+	from string
+}
+
+func NewSMTP(cfg config.SMTPConfig, from string) *SMTP {
+	return &SMTP{cfg: cfg, from: from}
+}
+
+func (m *SMTP) Send(_ context.Context, msg Message) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	for _, header := range [][2]string{
+		{"From", from.String()},
+		{"To", msg.To},
+		{"Subject", mime.QEncoding.Encode("utf-8", msg.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "text/plain; charset=utf-8"},
+	} {
+		body.WriteString(header[0] + ": " + header[1] + "\r\n")
+	}
+	body.WriteString("\r\n" + msg.Body)
+
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	return smtp.SendMail(addr, auth, from.Address, []string{msg.To}, body.Bytes())
+}
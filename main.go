@@ -1,21 +1,94 @@
 package main
 
 import (
-	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
-	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
-	"github.com/gin-gonic/gin"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
 )
 
+// @title Bookstore API
+// @version 1.0
+// @description A bookstore REST API built with Gin and GORM. Responses wrap their payload in data, with meta for paginated lists and links to the response itself, neighbouring pages and related resources. Clients asking for application/vnd.api+json get JSON:API documents instead, with related resources named in ?include= and errors as error objects.
+// @BasePath /
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Type "Bearer" followed by a space and the JWT.
+// @securityDefinitions.apikey APIKeyAuth
+// @in header
+// @name X-API-Key
+// @description An API key issued at /api/v1/api-keys, for machine clients.
 func main() {
-	r := gin.Default()
+	if err := newRootCommand().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
 
-	models.ConnectDatabase()
+// runServe runs the HTTP and gRPC servers, and the background jobs, until
+// SIGINT or SIGTERM or until any fails, then shuts them down within the
+// shutdown timeout. SIGHUP rereads the configuration, and so does the
+// secrets refresh, see reload.
+func runServe() error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	a, err := app.Bootstrap(cfg)
+	if err != nil {
+		return err
+	}
 
-	r.GET("/books", controllers.FindBooks)
-	r.GET("/books/:id", controllers.FindBook)
-	r.POST("/books", controllers.CreateBook)
-	r.PUT("/books/:id", controllers.UpdateBook)
-	r.DELETE("/books/:id", controllers.DeleteBook)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	hangups := make(chan os.Signal, 1)
+	signal.Notify(hangups, syscall.SIGHUP)
+	defer signal.Stop(hangups)
+	go reload(ctx, a, hangups, cfg.Secrets.Refresh)
+	err = a.Run(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	return errors.Join(err, a.Shutdown(shutdownCtx))
+}
 
-	r.Run()
+// reload rereads the configuration on every signal from hangups, and every
+// refresh if positive so rotated secrets are fetched again, until ctx is
+// done, applying what can be while the server runs. A configuration that
+// doesn't load or validate is logged and ignored, keeping the current one.
+func reload(ctx context.Context, a *app.App, hangups <-chan os.Signal, refresh time.Duration) {
+	var refreshes <-chan time.Time
+	if refresh > 0 {
+		ticker := time.NewTicker(refresh)
+		defer ticker.Stop()
+		refreshes = ticker.C
+	}
+	for {
+		hangup := false
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangups:
+			hangup = true
+		case <-refreshes:
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			slog.Error("invalid configuration, keeping the current one", "error", err)
+			continue
+		}
+		if err := a.Reload(cfg); err != nil {
+			slog.Error("reloading the configuration failed, keeping the current one", "error", err)
+			continue
+		}
+		if hangup {
+			slog.Info("reloaded the configuration")
+		}
+	}
 }
@@ -0,0 +1,36 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	outboundRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "http_client_requests_total",
+		Help: "Requests made to other services, by host and result (the status code, error, or circuit_open when refused by the circuit breaker).",
+	}, []string{"host", "result"})
+
+	outboundDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_client_request_duration_seconds",
+		Help:    "Latency of the requests made to other services, until the response headers, by host.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"host"})
+)
+
+// OutboundRequest counts an attempt at a request to host, by its status
+// code or "error".
+func OutboundRequest(host, result string) {
+	outboundRequests.WithLabelValues(host, result).Inc()
+}
+
+// OutboundRejected counts a request to host the circuit breaker refused.
+func OutboundRejected(host string) {
+	outboundRequests.WithLabelValues(host, "circuit_open").Inc()
+}
+
+func OutboundDuration(host string, d time.Duration) {
+	outboundDuration.WithLabelValues(host).Observe(d.Seconds())
+}
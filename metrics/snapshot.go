@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// Snapshot sums up the metrics of the instance, for dashboards that are
+// pushed them rather than scraping /metrics.
+type Snapshot struct {
+	TakenAt time.Time `json:"taken_at"`
+	// Requests served since the instance started, and those of them
+	// answered with a 5xx status.
+	Requests     int64 `json:"requests"`
+	ServerErrors int64 `json:"server_errors"`
+	InFlight     int64 `json:"in_flight"`
+	Goroutines   int64 `json:"goroutines"`
+	HeapBytes    int64 `json:"heap_bytes"`
+	// Connections of the database pool, open and in use.
+	DBOpenConnections  int64 `json:"db_open_connections"`
+	DBInUseConnections int64 `json:"db_in_use_connections"`
+}
+
+// TakeSnapshot reads the snapshot off the default registry.
+func TakeSnapshot() (Snapshot, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snapshot := Snapshot{TakenAt: time.Now().UTC()}
+	gauges := map[string]*int64{
+		"http_requests_in_flight":      &snapshot.InFlight,
+		"go_goroutines":                &snapshot.Goroutines,
+		"go_memstats_heap_alloc_bytes": &snapshot.HeapBytes,
+		"go_sql_open_connections":      &snapshot.DBOpenConnections,
+		"go_sql_in_use_connections":    &snapshot.DBInUseConnections,
+	}
+	for _, family := range families {
+		if family.GetName() == "http_requests_total" {
+			for _, metric := range family.GetMetric() {
+				value := int64(metric.GetCounter().GetValue())
+				snapshot.Requests += value
+				if strings.HasPrefix(label(metric, "status"), "5") {
+					snapshot.ServerErrors += value
+				}
+			}
+		} else if total, ok := gauges[family.GetName()]; ok {
+			for _, metric := range family.GetMetric() {
+				*total += int64(metric.GetGauge().GetValue())
+			}
+		}
+	}
+	return snapshot, nil
+}
+
+func label(metric *dto.Metric, name string) string {
+	for _, pair := range metric.GetLabel() {
+		if pair.GetName() == name {
+			return pair.GetValue()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,83 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/quota"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/ratelimit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyKey is the context key under which the API key a request was made
+// with is stored.
+const APIKeyKey = "api_key"
+
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+}
+
+// APIKeyAuth authenticates requests carrying an X-API-Key header as the
+// user the key was issued to, so RequireAuth lets them through without a
+// bearer token. Keys without the write scope may only make safe requests
+// (GET, HEAD, OPTIONS). Each key is rate limited on its own, with its own
+// limit if it has one, so it must run before the rate limiter; the same
+// goes for its quota, and quota.Middleware.
+func APIKeyAuth(keys APIKeyAuthenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(ratelimit.APIKeyHeader)
+		if secret == "" {
+			c.Next()
+			return
+		}
+
+		key, err := keys.Authenticate(c.Request.Context(), secret)
+		if err != nil {
+			apierrors.Abort(c, apiKeyError(err))
+			return
+		}
+		if actAs(c, key) {
+			c.Next()
+		}
+	}
+}
+
+// actAs authenticates the request as made with key, unless the key may not
+// make it, in which case the request is aborted and false returned.
+func actAs(c *gin.Context, key *models.APIKey) bool {
+	if !key.HasScope(models.ScopeWrite) && !safeMethod(c.Request.Method) {
+		problem := apierrors.Forbidden("This API key can only read!")
+		apierrors.Abort(c, problem.With("required_scopes", []string{models.ScopeWrite}))
+		return false
+	}
+
+	identity := auth.Identity{UserID: key.UserID, Role: key.User.Role, TenantID: key.User.TenantID}
+	c.Set(APIKeyKey, key)
+	c.Set(UserIDKey, identity.UserID)
+	c.Set(UserRoleKey, identity.Role)
+	c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), identity))
+	ratelimit.ForClient(c, "apikey:"+strconv.FormatUint(uint64(key.ID), 10), ratelimit.Limit{Rate: key.RateLimit, Burst: key.RateBurst})
+	return quota.ForClient(c, quota.Subject{UserID: key.UserID, APIKeyID: key.ID}, key.MonthlyQuota)
+}
+
+func apiKeyError(err error) *apierrors.Problem {
+	switch {
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		return apierrors.Unauthorized("Invalid, expired or revoked API key!")
+	case errors.Is(err, services.ErrAPIKeyMustSign):
+		return apierrors.Unauthorized("This API key signs its requests; send the signature headers instead of the key.")
+	case errors.Is(err, services.ErrAPIKeyMustNotSign):
+		return apierrors.Unauthorized("This API key doesn't sign requests; send it in X-API-Key.")
+	}
+	return apierrors.From(err)
+}
+
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
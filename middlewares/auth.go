@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/quota"
+	"github.com/gin-gonic/gin"
+)
+
+// Context keys under which the authenticated user is stored.
+const (
+	UserIDKey   = "user_id"
+	UserRoleKey = "user_role"
+)
+
+// RequireAuth rejects requests without a valid "Authorization: Bearer <token>"
+// header and stores the token's user ID in the gin and request contexts.
+// Requests already authenticated by APIKeyAuth, and without a token, are
+// let through as they are. Tokens on revoked, if not nil, are rejected,
+// and so are users who aren't members of the tenant the request is for,
+// see Tenant, with 403. The request then counts against the user's quota, see quota.ForClient.
+func RequireAuth(cfg config.AuthConfig, revoked auth.RevocationList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		if _, ok := c.Get(APIKeyKey); ok && header == "" {
+			c.Next()
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok || token == "" {
+			apierrors.Abort(c, apierrors.Unauthorized("Missing bearer token!"))
+			return
+		}
+
+		identity, err := auth.Authenticate(c.Request.Context(), cfg, revoked, token)
+		if err != nil {
+			apierrors.Abort(c, apierrors.Unauthorized("Invalid or expired token!"))
+			return
+		}
+
+		c.Set(UserIDKey, identity.UserID)
+		c.Set(UserRoleKey, identity.Role)
+		c.Request = c.Request.WithContext(auth.NewContext(c.Request.Context(), identity))
+		if memberOfTenant(c, identity) && quota.ForClient(c, quota.Subject{UserID: identity.UserID}, 0) {
+			c.Next()
+		}
+	}
+}
+
+// TokenFromQuery lets clients that can't set headers, such as browsers
+// opening WebSockets, pass their bearer token as the param query parameter.
+// It must run before RequireAuth, and only on the routes such clients use:
+// URLs end up in logs and browser histories.
+func TokenFromQuery(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query(param); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+	}
+}
+
+// OptionalAuth identifies the caller when a valid bearer token is present
+// but lets anonymous requests through. An invalid token is still rejected.
+func OptionalAuth(cfg config.AuthConfig, revoked auth.RevocationList) gin.HandlerFunc {
+	require := RequireAuth(cfg, revoked)
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
+			return
+		}
+		require(c)
+	}
+}
+
+// RequireRole must run after RequireAuth. It rejects callers whose role is
+// not one of roles with 403.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString(UserRoleKey)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		problem := apierrors.Forbidden("You do not have permission to perform this action!")
+		apierrors.Abort(c, problem.With("required_roles", roles))
+	}
+}
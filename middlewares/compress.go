@@ -0,0 +1,172 @@
+package middlewares
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Compress gzips or deflates responses for clients that accept it, as
+// their Accept-Encoding header prefers. Only bodies of at least
+// cfg.MinSize bytes whose Content-Type is one of cfg.Types are compressed:
+// small bodies barely shrink, and images are compressed already.
+//
+// The start of the body is held back until it reaches MinSize, or the
+// handler flushes, to tell which it will be.
+func Compress(cfg config.CompressionConfig) gin.HandlerFunc {
+	types := make(map[string]bool, len(cfg.Types))
+	for _, t := range cfg.Types {
+		types[strings.ToLower(t)] = true
+	}
+	return func(c *gin.Context) {
+		encoding := acceptedEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, cfg: cfg, types: types, encoding: encoding}
+		c.Writer = w
+		defer func() {
+			w.Close()
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptedEncoding returns gzip, deflate or "" for neither, going by the
+// q-values of header.
+func acceptedEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		q := 1.0
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					q = parsed
+				}
+			}
+		}
+		if coding == "*" {
+			coding = "gzip"
+		}
+		// gzip wins ties: it's what everyone implements the same way.
+		if (coding == "gzip" || coding == "deflate") && q > 0 && (q > bestQ || q == bestQ && coding == "gzip") {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+type compressWriter struct {
+	gin.ResponseWriter
+	cfg      config.CompressionConfig
+	types    map[string]bool
+	encoding string
+
+	buf     bytes.Buffer
+	decided bool
+	// Set once the body is known to be compressed.
+	encoder io.WriteCloser
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.cfg.MinSize {
+			return len(data), nil
+		}
+		if err := w.decide(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Written and Size count what the handler wrote, held back or not, so
+// handlers and middlewares checking whether a response was written see
+// it.
+func (w *compressWriter) Written() bool {
+	return w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if w.buf.Len() > 0 {
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.decided && w.buf.Len() > 0 {
+		w.decide()
+	}
+	if f, ok := w.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide picks between compressing the body or not and writes what was
+// held back.
+func (w *compressWriter) decide() error {
+	w.decided = true
+	header := w.Header()
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	compressible := w.types[mediaType] && header.Get("Content-Encoding") == ""
+	if compressible {
+		header.Add("Vary", "Accept-Encoding")
+	}
+	if compressible && w.buf.Len() >= w.cfg.MinSize {
+		header.Set("Content-Encoding", w.encoding)
+		header.Del("Content-Length")
+		var err error
+		if w.encoding == "gzip" {
+			w.encoder, err = gzip.NewWriterLevel(w.ResponseWriter, w.cfg.Level)
+		} else {
+			w.encoder, err = flate.NewWriter(w.ResponseWriter, w.cfg.Level)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.encoder.Write(w.buf.Bytes())
+		w.buf.Reset()
+		return err
+	}
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	w.buf.Reset()
+	return err
+}
+
+// Close writes what's left once the handler is done.
+func (w *compressWriter) Close() {
+	if !w.decided {
+		if w.buf.Len() == 0 {
+			return
+		}
+		w.decide()
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
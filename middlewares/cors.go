@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// CORS lets browser apps served from cfg.AllowedOrigins call the API. Used
+// on the whole router it also answers preflight OPTIONS requests with 204,
+// before the routes' own OPTIONS handlers; requests from other origins get
+// 403.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	c := cors.Config{
+		AllowMethods:     cfg.AllowedMethods,
+		AllowHeaders:     cfg.AllowedHeaders,
+		ExposeHeaders:    cfg.ExposedHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           cfg.MaxAge,
+		AllowWildcard:    true,
+	}
+	if len(cfg.AllowedOrigins) == 1 && cfg.AllowedOrigins[0] == "*" {
+		c.AllowAllOrigins = true
+	} else {
+		c.AllowOrigins = cfg.AllowedOrigins
+	}
+	return cors.New(c)
+}
@@ -0,0 +1,68 @@
+package middlewares
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// FeatureFlags tells whether features are on; *features.Flags implements
+// it.
+type FeatureFlags interface {
+	Enabled(ctx context.Context, name string) bool
+}
+
+// Feature answers the routes of a feature that is off with 404, as if they
+// didn't exist.
+func Feature(flags FeatureFlags, name string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.Enabled(c.Request.Context(), name) {
+			apierrors.Abort(c, apierrors.NotFound("No route matches %s").WithArgs(c.Request.URL.Path))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Maintenance answers every request with 503 and Retry-After while the
+// maintenance flag is on, except those of admins, by API key or bearer
+// token, and those to the skipped routes, such as the probes and login. It
+// must run after APIKeyAuth.
+func Maintenance(flags FeatureFlags, cfg config.AuthConfig, revoked auth.RevocationList, retryAfter time.Duration, skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+	seconds := strconv.Itoa(int(math.Ceil(retryAfter.Seconds())))
+	return func(c *gin.Context) {
+		if skipped[c.FullPath()] || !flags.Enabled(c.Request.Context(), features.Maintenance) || isAdmin(c, cfg, revoked) {
+			c.Next()
+			return
+		}
+		c.Header("Retry-After", seconds)
+		apierrors.Abort(c, apierrors.ServiceUnavailable("The service is down for maintenance; try again later."))
+	}
+}
+
+// isAdmin tells whether the request is made by an admin. The token is only
+// looked at here; RequireAuth still checks it on the routes that need it.
+func isAdmin(c *gin.Context, cfg config.AuthConfig, revoked auth.RevocationList) bool {
+	if _, ok := c.Get(APIKeyKey); ok {
+		return c.GetString(UserRoleKey) == models.RoleAdmin
+	}
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		return false
+	}
+	identity, err := auth.Authenticate(c.Request.Context(), cfg, revoked, token)
+	return err == nil && identity.Role == models.RoleAdmin
+}
@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-gonic/gin"
+)
+
+// HSTS tells browsers to keep to HTTPS for the host for cfg.HSTSMaxAge,
+// with a Strict-Transport-Security header on the responses sent over TLS;
+// browsers ignore the header over plain HTTP.
+func HSTS(cfg config.TLSConfig) gin.HandlerFunc {
+	value := "max-age=" + strconv.FormatInt(int64(cfg.HSTSMaxAge.Seconds()), 10)
+	if cfg.HSTSIncludeSubdomains {
+		value += "; includeSubDomains"
+	}
+	return func(c *gin.Context) {
+		if c.Request.TLS != nil {
+			c.Header("Strict-Transport-Security", value)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+// Holds the request body as the server read it, before any BodyLimit.
+const rawBodyKey = "raw_body"
+
+// Timeout gives every request limit to complete. Its context is cancelled
+// then, which stops the database queries made with it; a handler that
+// fails because of it is answered with 408. Routes in skip, full paths such
+// as /api/v1/books/events, are never timed out: event streams stay open on
+// purpose.
+func Timeout(limit time.Duration, skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+	return func(c *gin.Context) {
+		if limit <= 0 || skipped[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), limit)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && !c.Writer.Written() {
+			apierrors.Abort(c, apierrors.RequestTimeout("The request took longer than %s to process.").WithArgs(limit.String()))
+		}
+	}
+}
+
+// BodyLimit rejects bodies larger than limit bytes with 413 as soon as the
+// handler reads past it. On a route it replaces the limit a BodyLimit on
+// the whole router set, so routes taking uploads can allow more than the
+// default.
+func BodyLimit(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw, ok := c.Get(rawBodyKey)
+		if !ok {
+			raw = c.Request.Body
+			c.Set(rawBodyKey, raw)
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, raw.(io.ReadCloser), limit)
+		c.Next()
+	}
+}
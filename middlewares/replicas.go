@@ -0,0 +1,15 @@
+package middlewares
+
+import (
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// ReadYourWrites sends the reads of a request to the primary database once
+// the request has written, see models.WithReadYourWrites.
+func ReadYourWrites() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(models.WithReadYourWrites(c.Request.Context()))
+		c.Next()
+	}
+}
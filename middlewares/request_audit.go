@@ -0,0 +1,152 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// Written in place of the values of redacted members.
+const redacted = "[redacted]"
+
+// RequestAudit logs every request that may change something, that is with
+// a method other than GET, HEAD and OPTIONS, along with its response, to
+// logger: who made it, for which tenant, and both bodies. It must run
+// after BodyLimit and Compress, so that it sees both bodies as the handler
+// reads and writes them.
+//
+// The values of the members of JSON bodies named in cfg.RedactFields are
+// replaced by [redacted], at any depth. Bodies that can't be redacted,
+// because they aren't JSON or were cut off at cfg.MaxBodySize, are only
+// described by their type and size.
+func RequestAudit(cfg config.RequestAuditConfig, logger *slog.Logger) gin.HandlerFunc {
+	redact := make(map[string]bool, len(cfg.RedactFields))
+	for _, field := range cfg.RedactFields {
+		redact[strings.ToLower(field)] = true
+	}
+	return func(c *gin.Context) {
+		if safeMethod(c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		request := &capture{limit: cfg.MaxBodySize}
+		tee := func(body io.ReadCloser) io.ReadCloser {
+			return struct {
+				io.Reader
+				io.Closer
+			}{io.TeeReader(body, request), body}
+		}
+		c.Request.Body = tee(c.Request.Body)
+		// Routes with a BodyLimit of their own read the raw body instead.
+		if raw, ok := c.Get(rawBodyKey); ok {
+			c.Set(rawBodyKey, tee(raw.(io.ReadCloser)))
+		}
+		response := &captureWriter{ResponseWriter: c.Writer, body: capture{limit: cfg.MaxBodySize}}
+		c.Writer = response
+
+		c.Next()
+		// Rendered here rather than by apierrors.Middleware, on the way
+		// out, so that the problem is recorded too.
+		if len(c.Errors) > 0 && !c.Writer.Written() {
+			apierrors.Abort(c, apierrors.From(c.Errors.Last().Err))
+		}
+		c.Writer = response.ResponseWriter
+
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.RequestURI()),
+			slog.String("route", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+			slog.Uint64("tenant_id", uint64(tenancy.ID(c.Request.Context()))),
+		}
+		if userID, ok := c.Get(UserIDKey); ok {
+			attrs = append(attrs, slog.Any("user_id", userID))
+		}
+		if _, ok := c.Get(APIKeyKey); ok {
+			attrs = append(attrs, slog.Bool("api_key", true))
+		}
+		attrs = append(attrs,
+			slog.Any("request", request.describe(c.Request.Header.Get("Content-Type"), redact)),
+			slog.Any("response", response.body.describe(c.Writer.Header().Get("Content-Type"), redact)),
+		)
+		logger.LogAttrs(c.Request.Context(), slog.LevelInfo, "request audit", attrs...)
+	}
+}
+
+// capture keeps the first limit bytes written to it, counting the rest.
+type capture struct {
+	limit int
+	buf   bytes.Buffer
+	size  int
+}
+
+func (c *capture) Write(data []byte) (int, error) {
+	c.size += len(data)
+	if room := c.limit - c.buf.Len(); room > 0 {
+		c.buf.Write(data[:min(room, len(data))])
+	}
+	return len(data), nil
+}
+
+// describe returns the body, redacted, if it can be logged, or else its
+// type and size.
+func (c *capture) describe(contentType string, redact map[string]bool) any {
+	if c.size == 0 {
+		return nil
+	}
+	// Whatever the content type says, as handlers bind JSON regardless.
+	if c.size <= c.limit {
+		var body any
+		decoder := json.NewDecoder(bytes.NewReader(c.buf.Bytes()))
+		decoder.UseNumber()
+		if decoder.Decode(&body) == nil && !decoder.More() {
+			return redactValue(body, redact)
+		}
+	}
+	return map[string]any{"content_type": contentType, "bytes": c.size}
+}
+
+func redactValue(v any, redact map[string]bool) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, member := range v {
+			if redact[strings.ToLower(key)] {
+				v[key] = redacted
+			} else {
+				v[key] = redactValue(member, redact)
+			}
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactValue(item, redact)
+		}
+	}
+	return v
+}
+
+type captureWriter struct {
+	gin.ResponseWriter
+	body capture
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	w.body.Write([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
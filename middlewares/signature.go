@@ -0,0 +1,143 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// Nonces must be about this long, enough to be random, not so long they
+// fill the cache.
+const (
+	minNonceLength = 8
+	maxNonceLength = 128
+)
+
+type SignedAPIKeyAuthenticator interface {
+	AuthenticateSigned(ctx context.Context, id uint) (*models.APIKey, error)
+}
+
+type signedKeyKey struct{}
+
+// signedKeyFrom returns the API key the request ctx belongs to was signed
+// with, if it was.
+func signedKeyFrom(ctx context.Context) (*models.APIKey, bool) {
+	key, ok := ctx.Value(signedKeyKey{}).(*models.APIKey)
+	return key, ok
+}
+
+// SignatureAuth authenticates requests signed with an API key, see the
+// auth package, as APIKeyAuth does those sending one. The timestamp must be
+// within skew of the server clock, and each nonce of a key is accepted
+// once. To check the body digest the body is read, up to maxBody bytes, the
+// most any route accepts, and then limited to bodyLimit again for the
+// routes that don't set their own. It must run after BodyLimit and before
+// the rate limiter.
+//
+// Requests made on behalf of a signed one with its context, such as the
+// operations of a batch, can't be signed themselves: they are made with its
+// key unless they bring an API key of their own.
+func SignatureAuth(keys SignedAPIKeyAuthenticator, nonces auth.NonceCache, skew time.Duration, bodyLimit, maxBody int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		keyID := c.GetHeader(auth.SignatureKeyHeader)
+		if keyID == "" {
+			key, signed := signedKeyFrom(ctx)
+			if _, ok := c.Get(APIKeyKey); signed && !ok {
+				if actAs(c, key) {
+					c.Next()
+				}
+				return
+			}
+			c.Next()
+			return
+		}
+
+		timestamp := c.GetHeader(auth.SignatureTimestampHeader)
+		nonce := c.GetHeader(auth.SignatureNonceHeader)
+		digest := c.GetHeader(auth.ContentDigestHeader)
+		signature := c.GetHeader(auth.SignatureHeader)
+		if timestamp == "" || nonce == "" || digest == "" || signature == "" {
+			apierrors.Abort(c, apierrors.Unauthorized("A signed request needs the X-Signature-Timestamp, X-Signature-Nonce, X-Content-SHA256 and X-Signature headers."))
+			return
+		}
+		id, err := strconv.ParseUint(keyID, 10, 64)
+		if err != nil {
+			apierrors.Abort(c, apierrors.Unauthorized("Invalid, expired or revoked API key!"))
+			return
+		}
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > skew {
+			apierrors.Abort(c, apierrors.Unauthorized("The request signature is too old or from the future; check the client's clock."))
+			return
+		}
+		if len(nonce) < minNonceLength || len(nonce) > maxNonceLength {
+			apierrors.Abort(c, apierrors.Unauthorized("The signature nonce must be %d to %d characters long.").WithArgs(minNonceLength, maxNonceLength))
+			return
+		}
+
+		body, err := bufferBody(c, bodyLimit, maxBody)
+		if err != nil {
+			apierrors.Abort(c, apierrors.From(err))
+			return
+		}
+		if auth.Digest(body) != digest {
+			apierrors.Abort(c, apierrors.Unauthorized("The body doesn't match X-Content-SHA256."))
+			return
+		}
+
+		key, err := keys.AuthenticateSigned(ctx, uint(id))
+		if err != nil {
+			apierrors.Abort(c, apiKeyError(err))
+			return
+		}
+		if !auth.VerifySignature(key.Secret, signature, c.Request.Method, c.Request.RequestURI, timestamp, nonce, digest) {
+			apierrors.Abort(c, apierrors.Unauthorized("Invalid request signature!"))
+			return
+		}
+
+		// Only once the signature is valid, so no one else can use up the
+		// key's nonces.
+		fresh, err := nonces.Use(ctx, keyID+":"+nonce, 2*skew)
+		if err != nil {
+			slog.WarnContext(ctx, "signature nonce cache unavailable", "error", err)
+			apierrors.Abort(c, apierrors.ServiceUnavailable("Request signatures can't be checked right now; try again later."))
+			return
+		}
+		if !fresh {
+			apierrors.Abort(c, apierrors.Unauthorized("This request was already received; sign every request with a new nonce."))
+			return
+		}
+
+		c.Request = c.Request.WithContext(context.WithValue(ctx, signedKeyKey{}, key))
+		if actAs(c, key) {
+			c.Next()
+		}
+	}
+}
+
+// bufferBody reads the whole body and puts it back, for BodyLimit on the
+// route, if any, to limit again.
+func bufferBody(c *gin.Context, bodyLimit, maxBody int64) ([]byte, error) {
+	raw, ok := c.Get(rawBodyKey)
+	if !ok {
+		raw = c.Request.Body
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, raw.(io.ReadCloser), maxBody))
+	if err != nil {
+		return nil, err
+	}
+	buffered := io.NopCloser(bytes.NewReader(body))
+	c.Set(rawBodyKey, buffered)
+	c.Request.Body = http.MaxBytesReader(c.Writer, buffered, bodyLimit)
+	return body, nil
+}
@@ -0,0 +1,98 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// TenantKey is the context key under which the ID of the tenant a request
+// is made for is stored.
+const TenantKey = "tenant_id"
+
+type TenantFinder interface {
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+}
+
+// Tenant picks the tenant a request is made for and passes it down in the
+// request context, see the tenancy package. The tenant is named by its
+// slug in the cfg.Header header or, if cfg.BaseDomain is set, as the
+// subdomain of the host, e.g. westside.library.example; requests naming
+// none are for the default tenant. Naming an unknown tenant is a 404, and
+// one the caller authenticated by APIKeyAuth or SignatureAuth isn't a
+// member of a 403; RequireAuth checks the callers it authenticates.
+func Tenant(cfg config.TenancyConfig, tenants TenantFinder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := tenancy.DefaultID
+		if slug := tenantSlug(c, cfg); slug != "" {
+			tenant, err := tenants.FindBySlug(c.Request.Context(), slug)
+			if errors.Is(err, repositories.ErrNotFound) {
+				apierrors.Abort(c, apierrors.NotFound(`Unknown tenant "%s"!`).WithArgs(slug))
+				return
+			}
+			if err != nil {
+				apierrors.Abort(c, apierrors.From(err))
+				return
+			}
+			id = tenant.ID
+		}
+
+		c.Set(TenantKey, id)
+		c.Request = c.Request.WithContext(tenancy.NewContext(c.Request.Context(), id))
+		if identity, ok := auth.FromContext(c.Request.Context()); ok && !memberOfTenant(c, identity) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// memberOfTenant aborts the request with 403 unless the caller is a member
+// of the tenant it is made for, if any, and reports whether they are.
+func memberOfTenant(c *gin.Context, identity auth.Identity) bool {
+	id, ok := tenancy.FromContext(c.Request.Context())
+	if !ok || identity.MemberOf(id) {
+		return true
+	}
+	apierrors.Abort(c, apierrors.Forbidden("You are not a member of this tenant!"))
+	return false
+}
+
+// RequireAllTenants must run after RequireAuth. It rejects callers who are
+// members of a single tenant with 403, on routes acting for the whole
+// deployment, such as managing users and tenants.
+func RequireAllTenants() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if identity, ok := auth.FromContext(c.Request.Context()); ok && identity.TenantID != nil {
+			apierrors.Abort(c, apierrors.Forbidden("Only members of every tenant can perform this action!"))
+			return
+		}
+		c.Next()
+	}
+}
+
+func tenantSlug(c *gin.Context, cfg config.TenancyConfig) string {
+	if slug := c.GetHeader(cfg.Header); slug != "" {
+		return strings.ToLower(slug)
+	}
+	if cfg.BaseDomain == "" {
+		return ""
+	}
+	host := c.Request.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	subdomain, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(cfg.BaseDomain))
+	if !ok {
+		return ""
+	}
+	return subdomain
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import "github.com/gin-gonic/gin"
+
+// APIVersionKey holds the API version ("v1", ...) the matched route belongs
+// to, so handlers shared between versions can branch on it.
+const APIVersionKey = "api_version"
+
+// VersionHeader is echoed on every versioned response and may be sent by
+// clients of the legacy unversioned paths to pick the version they are
+// redirected to.
+const VersionHeader = "API-Version"
+
+// APIVersion tags the request with version.
+func APIVersion(version string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(APIVersionKey, version)
+		c.Header(VersionHeader, version)
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/migrations"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// newMigrateCommand builds `migrate up|down|status`.
+func newMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back or list the schema migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("usage: migrate up|down|status")
+		},
+	}
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply the pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withDatabase(migrations.Up)
+			},
+		},
+		&cobra.Command{
+			Use:   "down",
+			Short: "Roll back the last applied migration",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withDatabase(migrations.Down)
+			},
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List the migrations and whether each is applied, as JSON",
+			Args:  cobra.NoArgs,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return withDatabase(func(db *gorm.DB) error {
+					statuses, err := migrations.List(db)
+					if err != nil {
+						return err
+					}
+					encoder := json.NewEncoder(os.Stdout)
+					encoder.SetIndent("", "  ")
+					return encoder.Encode(statuses)
+				})
+			},
+		},
+	)
+	return cmd
+}
+
+// withDatabase runs fn against the database, with none of the plugins the
+// other commands install.
+func withDatabase(fn func(db *gorm.DB) error) error {
+	if _, err := bootstrap(); err != nil {
+		return err
+	}
+	return fn(models.DB)
+}
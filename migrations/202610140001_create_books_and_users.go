@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createBooksAndUsers = &gormigrate.Migration{
+	ID: "202610140001_create_books_and_users",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			ID        uint `gorm:"primary_key"`
+			Title     string
+			Author    string
+			Year      int
+			CreatedAt time.Time
+			UpdatedAt time.Time
+		}
+		type User struct {
+			ID           uint   `gorm:"primary_key"`
+			Email        string `gorm:"uniqueIndex;not null"`
+			PasswordHash string `gorm:"not null"`
+			Role         string `gorm:"not null;default:reader"`
+			CreatedAt    time.Time
+			UpdatedAt    time.Time
+		}
+		return tx.AutoMigrate(&Book{}, &User{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("users", "books")
+	},
+}
@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createAuthorsAuthor struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createAuthorsAuthor) TableName() string { return "authors" }
+
+type createAuthorsBook struct {
+	ID       uint `gorm:"primary_key"`
+	AuthorID *uint
+	Author   *createAuthorsAuthor `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (createAuthorsBook) TableName() string { return "books" }
+
+// Moves the free-text books.author column into an authors table referenced
+// by books.author_id, creating one author per distinct name.
+var createAuthors = &gormigrate.Migration{
+	ID: "202610140002_create_authors",
+	Migrate: func(tx *gorm.DB) error {
+		type Author struct {
+			ID        uint `gorm:"primary_key"`
+			Name      string
+			Bio       string
+			CreatedAt time.Time
+			UpdatedAt time.Time
+		}
+		type Book struct {
+			ID       uint `gorm:"primary_key"`
+			Author   string
+			AuthorID *uint `gorm:"index"`
+		}
+
+		if err := tx.AutoMigrate(&Author{}, &Book{}); err != nil {
+			return err
+		}
+
+		var names []string
+		if err := tx.Model(&Book{}).Distinct().Where("author <> ''").Pluck("author", &names).Error; err != nil {
+			return err
+		}
+		for _, name := range names {
+			author := Author{Name: name}
+			if err := tx.Create(&author).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Book{}).Where("author = ?", name).Update("author_id", author.ID).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Migrator().DropColumn(&Book{}, "author"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateConstraint(&createAuthorsBook{}, "Author"); err != nil {
+			return err
+		}
+		// SQLite rebuilds the table for the two steps above, dropping indexes.
+		if !tx.Migrator().HasIndex(&Book{}, "AuthorID") {
+			return tx.Migrator().CreateIndex(&Book{}, "AuthorID")
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			ID       uint `gorm:"primary_key"`
+			Author   string
+			AuthorID *uint
+		}
+
+		if err := tx.Migrator().DropConstraint(&createAuthorsBook{}, "Author"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().AddColumn(&Book{}, "Author"); err != nil {
+			return err
+		}
+		err := tx.Exec("UPDATE books SET author = (SELECT name FROM authors WHERE authors.id = books.author_id)").Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&Book{}, "author_id"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("authors")
+	},
+}
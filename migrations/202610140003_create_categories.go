@@ -0,0 +1,31 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createCategories = &gormigrate.Migration{
+	ID: "202610140003_create_categories",
+	Migrate: func(tx *gorm.DB) error {
+		type Category struct {
+			ID        uint   `gorm:"primary_key"`
+			Name      string `gorm:"uniqueIndex;not null"`
+			CreatedAt time.Time
+			UpdatedAt time.Time
+		}
+		type Book struct {
+			ID         uint       `gorm:"primary_key"`
+			Categories []Category `gorm:"many2many:book_categories;constraint:OnDelete:CASCADE"`
+		}
+
+		// Migrating the book snapshot only creates the book_categories join
+		// table; the existing books columns are left alone.
+		return tx.AutoMigrate(&Category{}, &Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("book_categories", "categories")
+	},
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addISBNToBooks = &gormigrate.Migration{
+	ID: "202610140004_add_isbn_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			ISBN string `gorm:"index"`
+		}
+		return tx.AutoMigrate(&Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			ISBN string `gorm:"index"`
+		}
+		if err := tx.Migrator().DropIndex(&Book{}, "ISBN"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&Book{}, "ISBN")
+	},
+}
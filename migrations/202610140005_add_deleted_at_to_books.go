@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addDeletedAtToBooks = &gormigrate.Migration{
+	ID: "202610140005_add_deleted_at_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.AutoMigrate(&Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		if err := tx.Migrator().DropIndex(&Book{}, "DeletedAt"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropColumn(&Book{}, "DeletedAt")
+	},
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addDescriptionToBooks = &gormigrate.Migration{
+	ID: "202610140006_add_description_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			Description string `gorm:"type:text"`
+		}
+		return tx.AutoMigrate(&Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			Description string `gorm:"type:text"`
+		}
+		if err := tx.Migrator().DropColumn(&Book{}, "Description"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.Table("books").AutoMigrate(&indexedBook{})
+	},
+}
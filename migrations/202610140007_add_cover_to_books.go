@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var addCoverToBooks = &gormigrate.Migration{
+	ID: "202610140007_add_cover_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			CoverKey          string
+			CoverThumbnailKey string
+		}
+		return tx.AutoMigrate(&Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			CoverKey          string
+			CoverThumbnailKey string
+		}
+		for _, column := range []string{"CoverKey", "CoverThumbnailKey"} {
+			if err := tx.Migrator().DropColumn(&Book{}, column); err != nil {
+				return err
+			}
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.Table("books").AutoMigrate(&indexedBook{})
+	},
+}
@@ -0,0 +1,198 @@
+package migrations
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/slug"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Primary keys can't be changed in place on every driver, so books and
+// book_categories are rebuilt: the new tables are created under a temporary
+// name, filled from the old ones and renamed over them.
+
+type uuidBooksAuthor struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (uuidBooksAuthor) TableName() string { return "authors" }
+
+type uuidBooksCategory struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (uuidBooksCategory) TableName() string { return "categories" }
+
+// uuidBooksColumns are the books columns other than the key, as of this
+// migration.
+type uuidBooksColumns struct {
+	Title             string
+	Description       string `gorm:"type:text"`
+	AuthorID          *uint
+	Year              int
+	ISBN              string
+	Version           uint `gorm:"not null;default:1"`
+	CoverKey          string
+	CoverThumbnailKey string
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	DeletedAt         gorm.DeletedAt
+}
+
+type uuidBooksBook struct {
+	ID      string           `gorm:"type:char(36);primaryKey"`
+	Slug    string           `gorm:"type:varchar(255);not null"`
+	Columns uuidBooksColumns `gorm:"embedded"`
+	Author  *uuidBooksAuthor `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (uuidBooksBook) TableName() string { return "books_uuid" }
+
+type uuidBooksLink struct {
+	BookID     string             `gorm:"type:char(36);primaryKey"`
+	Book       *uuidBooksBook     `gorm:"constraint:OnDelete:CASCADE"`
+	CategoryID uint               `gorm:"primaryKey"`
+	Category   *uuidBooksCategory `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (uuidBooksLink) TableName() string { return "book_categories_uuid" }
+
+type serialBooksBook struct {
+	ID      uint             `gorm:"primary_key"`
+	Columns uuidBooksColumns `gorm:"embedded"`
+	Author  *uuidBooksAuthor `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT"`
+}
+
+func (serialBooksBook) TableName() string { return "books_serial" }
+
+type serialBooksLink struct {
+	BookID     uint               `gorm:"primaryKey"`
+	Book       *serialBooksBook   `gorm:"constraint:OnDelete:CASCADE"`
+	CategoryID uint               `gorm:"primaryKey"`
+	Category   *uuidBooksCategory `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (serialBooksLink) TableName() string { return "book_categories_serial" }
+
+// Gives every book a random UUID and a slug made from its title, and
+// re-points the category links at the new IDs.
+var useUUIDsForBooks = &gormigrate.Migration{
+	ID: "202610140009_use_uuids_for_books",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&uuidBooksBook{}, &uuidBooksLink{}); err != nil {
+				return err
+			}
+
+			var books []serialBooksBook
+			if err := tx.Table("books").Unscoped().Order("id").Find(&books).Error; err != nil {
+				return err
+			}
+			ids := make(map[uint]string, len(books))
+			taken := map[string]bool{}
+			for _, old := range books {
+				book := uuidBooksBook{ID: uuid.NewString(), Slug: uniqueSlug(old.Columns.Title, taken), Columns: old.Columns}
+				if err := tx.Create(&book).Error; err != nil {
+					return err
+				}
+				ids[old.ID] = book.ID
+			}
+
+			var links []struct{ BookID, CategoryID uint }
+			if err := tx.Table("book_categories").Find(&links).Error; err != nil {
+				return err
+			}
+			for _, link := range links {
+				if err := tx.Create(&uuidBooksLink{BookID: ids[link.BookID], CategoryID: link.CategoryID}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := replaceBookTables(tx, "books_uuid", "book_categories_uuid"); err != nil {
+				return err
+			}
+			type indexedBook struct {
+				Slug      string         `gorm:"type:varchar(255);not null;uniqueIndex"`
+				AuthorID  uint           `gorm:"index"`
+				ISBN      string         `gorm:"index"`
+				DeletedAt gorm.DeletedAt `gorm:"index"`
+			}
+			return tx.Table("books").AutoMigrate(&indexedBook{})
+		})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&serialBooksBook{}, &serialBooksLink{}); err != nil {
+				return err
+			}
+
+			var books []uuidBooksBook
+			if err := tx.Table("books").Unscoped().Order("created_at, id").Find(&books).Error; err != nil {
+				return err
+			}
+			ids := make(map[string]uint, len(books))
+			for _, old := range books {
+				book := serialBooksBook{Columns: old.Columns}
+				if err := tx.Create(&book).Error; err != nil {
+					return err
+				}
+				ids[old.ID] = book.ID
+			}
+
+			var links []struct {
+				BookID     string
+				CategoryID uint
+			}
+			if err := tx.Table("book_categories").Find(&links).Error; err != nil {
+				return err
+			}
+			for _, link := range links {
+				if err := tx.Create(&serialBooksLink{BookID: ids[link.BookID], CategoryID: link.CategoryID}).Error; err != nil {
+					return err
+				}
+			}
+
+			if err := replaceBookTables(tx, "books_serial", "book_categories_serial"); err != nil {
+				return err
+			}
+			type indexedBook struct {
+				AuthorID  uint           `gorm:"index"`
+				ISBN      string         `gorm:"index"`
+				DeletedAt gorm.DeletedAt `gorm:"index"`
+			}
+			return tx.Table("books").AutoMigrate(&indexedBook{})
+		})
+	},
+}
+
+// replaceBookTables drops books and book_categories and renames the given
+// tables to take their place. The link table goes first since it references
+// books.
+func replaceBookTables(tx *gorm.DB, books, links string) error {
+	if err := tx.Migrator().DropTable("book_categories", "books"); err != nil {
+		return err
+	}
+	if err := tx.Migrator().RenameTable(books, "books"); err != nil {
+		return err
+	}
+	return tx.Migrator().RenameTable(links, "book_categories")
+}
+
+// uniqueSlug mirrors how the application slugs new books at the time of this
+// migration.
+func uniqueSlug(title string, taken map[string]bool) string {
+	base := slug.Make(title)
+	if _, err := uuid.Parse(base); base == "" || err == nil {
+		base = strings.Trim("book-"+base, "-")
+	}
+	candidate := base
+	for n := 2; taken[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d", base, n)
+	}
+	taken[candidate] = true
+	return candidate
+}
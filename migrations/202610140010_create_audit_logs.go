@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+var createAuditLogs = &gormigrate.Migration{
+	ID: "202610140010_create_audit_logs",
+	Migrate: func(tx *gorm.DB) error {
+		type AuditLog struct {
+			ID        uint  `gorm:"primary_key"`
+			UserID    *uint `gorm:"index"`
+			Action    string
+			Entity    string `gorm:"index:idx_audit_logs_entity"`
+			EntityID  string `gorm:"index:idx_audit_logs_entity"`
+			Changes   string `gorm:"type:text"`
+			RequestID string
+			CreatedAt time.Time `gorm:"index"`
+		}
+		return tx.AutoMigrate(&AuditLog{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("audit_logs")
+	},
+}
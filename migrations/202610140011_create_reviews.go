@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createReviewsBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createReviewsBook) TableName() string { return "books" }
+
+type createReviewsUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createReviewsUser) TableName() string { return "users" }
+
+type createReviewsReview struct {
+	ID        string             `gorm:"type:char(36);primaryKey"`
+	BookID    string             `gorm:"type:char(36);not null;uniqueIndex:idx_reviews_book_user"`
+	Book      *createReviewsBook `gorm:"constraint:OnDelete:CASCADE"`
+	UserID    uint               `gorm:"not null;uniqueIndex:idx_reviews_book_user"`
+	User      *createReviewsUser `gorm:"constraint:OnDelete:CASCADE"`
+	Rating    int                `gorm:"not null"`
+	Comment   string             `gorm:"type:text"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (createReviewsReview) TableName() string { return "reviews" }
+
+// Adds the reviews table and the rating stats denormalized onto books.
+var createReviews = &gormigrate.Migration{
+	ID: "202610140011_create_reviews",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			RatingAverage float64 `gorm:"not null;default:0"`
+			ReviewCount   int     `gorm:"not null;default:0"`
+		}
+		return tx.AutoMigrate(&createReviewsReview{}, &Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			RatingAverage float64
+			ReviewCount   int
+		}
+		if err := tx.Migrator().DropTable("reviews"); err != nil {
+			return err
+		}
+		for _, column := range []string{"RatingAverage", "ReviewCount"} {
+			if err := tx.Migrator().DropColumn(&Book{}, column); err != nil {
+				return err
+			}
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			Slug      string         `gorm:"type:varchar(255);not null;uniqueIndex"`
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.Table("books").AutoMigrate(&indexedBook{})
+	},
+}
@@ -0,0 +1,72 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createLoansBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createLoansBook) TableName() string { return "books" }
+
+type createLoansMember struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	Name      string `gorm:"not null"`
+	Email     string `gorm:"type:varchar(255);uniqueIndex;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (createLoansMember) TableName() string { return "members" }
+
+type createLoansLoan struct {
+	ID         string             `gorm:"type:char(36);primaryKey"`
+	BookID     string             `gorm:"type:char(36);not null;index"`
+	Book       *createLoansBook   `gorm:"constraint:OnDelete:CASCADE"`
+	MemberID   string             `gorm:"type:char(36);not null;index"`
+	Member     *createLoansMember `gorm:"constraint:OnDelete:RESTRICT"`
+	LoanedAt   time.Time          `gorm:"not null"`
+	DueAt      time.Time          `gorm:"not null;index"`
+	ReturnedAt *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (createLoansLoan) TableName() string { return "loans" }
+
+// Adds library members, their loans and the number of copies owned of each
+// book (one for existing books).
+var createMembersAndLoans = &gormigrate.Migration{
+	ID: "202610140012_create_members_and_loans",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			Quantity int `gorm:"not null;default:1"`
+		}
+		return tx.AutoMigrate(&createLoansMember{}, &createLoansLoan{}, &Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			Quantity int
+		}
+		if err := tx.Migrator().DropTable("loans", "members"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&Book{}, "Quantity"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			Slug      string         `gorm:"type:varchar(255);not null;uniqueIndex"`
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.Table("books").AutoMigrate(&indexedBook{})
+	},
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Stores how many copies of each book are on the shelf, starting from the
+// copies owned minus those on open loans.
+var addAvailableCopiesToBooks = &gormigrate.Migration{
+	ID: "202610140013_add_available_copies_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			AvailableCopies int `gorm:"not null;default:1"`
+		}
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&Book{}); err != nil {
+				return err
+			}
+			return tx.Exec(`UPDATE books SET available_copies = quantity -
+				(SELECT COUNT(*) FROM loans WHERE loans.book_id = books.id AND loans.returned_at IS NULL)`).Error
+		})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			AvailableCopies int
+		}
+		if err := tx.Migrator().DropColumn(&Book{}, "AvailableCopies"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			Slug      string         `gorm:"type:varchar(255);not null;uniqueIndex"`
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		return tx.Table("books").AutoMigrate(&indexedBook{})
+	},
+}
@@ -0,0 +1,47 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createWebhooksWebhook struct {
+	ID        uint   `gorm:"primary_key"`
+	URL       string `gorm:"not null"`
+	Events    string `gorm:"type:text"`
+	Secret    string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (createWebhooksWebhook) TableName() string { return "webhooks" }
+
+type createWebhooksDelivery struct {
+	ID         uint                   `gorm:"primary_key"`
+	WebhookID  uint                   `gorm:"not null;index"`
+	Webhook    *createWebhooksWebhook `gorm:"constraint:OnDelete:CASCADE"`
+	EventID    string                 `gorm:"index"`
+	Event      string
+	Payload    string `gorm:"type:text"`
+	Attempt    int
+	StatusCode int
+	Error      string
+	Succeeded  bool
+	DurationMS int64
+	CreatedAt  time.Time `gorm:"index"`
+}
+
+func (createWebhooksDelivery) TableName() string { return "webhook_deliveries" }
+
+// Adds webhooks and the log of their deliveries.
+var createWebhooks = &gormigrate.Migration{
+	ID: "202610140014_create_webhooks",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createWebhooksWebhook{}, &createWebhooksDelivery{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("webhook_deliveries", "webhooks")
+	},
+}
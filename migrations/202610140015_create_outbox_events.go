@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createOutboxEvent struct {
+	ID          uint   `gorm:"primary_key"`
+	EventID     string `gorm:"type:varchar(36);not null;uniqueIndex"`
+	Type        string `gorm:"not null"`
+	Payload     string `gorm:"type:text;not null"`
+	Attempts    int
+	LastError   string
+	LockedUntil *time.Time
+	PublishedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"index"`
+}
+
+func (createOutboxEvent) TableName() string { return "outbox_events" }
+
+// Adds the outbox book events are written to before they are published.
+var createOutboxEvents = &gormigrate.Migration{
+	ID: "202610140015_create_outbox_events",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createOutboxEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("outbox_events")
+	},
+}
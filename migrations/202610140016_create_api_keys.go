@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createAPIKeysUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createAPIKeysUser) TableName() string { return "users" }
+
+type createAPIKey struct {
+	ID         uint               `gorm:"primary_key"`
+	Name       string             `gorm:"not null"`
+	Prefix     string             `gorm:"not null"`
+	KeyHash    string             `gorm:"not null;uniqueIndex"`
+	Scopes     string             `gorm:"type:text"`
+	UserID     uint               `gorm:"not null;index"`
+	User       *createAPIKeysUser `gorm:"constraint:OnDelete:CASCADE"`
+	RateLimit  float64
+	RateBurst  int
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (createAPIKey) TableName() string { return "api_keys" }
+
+// Adds the API keys machine clients authenticate with.
+var createAPIKeys = &gormigrate.Migration{
+	ID: "202610140016_create_api_keys",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createAPIKey{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("api_keys")
+	},
+}
@@ -0,0 +1,38 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createRefreshTokensUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createRefreshTokensUser) TableName() string { return "users" }
+
+type createRefreshToken struct {
+	ID        uint                     `gorm:"primary_key"`
+	TokenHash string                   `gorm:"not null;uniqueIndex"`
+	Family    string                   `gorm:"not null;index"`
+	UserID    uint                     `gorm:"not null;index"`
+	User      *createRefreshTokensUser `gorm:"constraint:OnDelete:CASCADE"`
+	ExpiresAt time.Time                `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (createRefreshToken) TableName() string { return "refresh_tokens" }
+
+// Adds the refresh tokens issued at login.
+var createRefreshTokens = &gormigrate.Migration{
+	ID: "202610140017_create_refresh_tokens",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createRefreshToken{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("refresh_tokens")
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createUserIdentitiesUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createUserIdentitiesUser) TableName() string { return "users" }
+
+type createUserIdentity struct {
+	ID        uint                      `gorm:"primary_key"`
+	UserID    uint                      `gorm:"not null;index"`
+	User      *createUserIdentitiesUser `gorm:"constraint:OnDelete:CASCADE"`
+	Provider  string                    `gorm:"not null;uniqueIndex:idx_user_identities_subject"`
+	Subject   string                    `gorm:"not null;uniqueIndex:idx_user_identities_subject"`
+	Email     string
+	CreatedAt time.Time
+}
+
+func (createUserIdentity) TableName() string { return "user_identities" }
+
+// Adds the OAuth provider accounts users sign in with.
+var createUserIdentities = &gormigrate.Migration{
+	ID: "202610140018_create_user_identities",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createUserIdentity{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("user_identities")
+	},
+}
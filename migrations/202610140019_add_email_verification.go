@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addEmailVerificationUser struct {
+	ID              uint `gorm:"primary_key"`
+	EmailVerifiedAt *time.Time
+}
+
+func (addEmailVerificationUser) TableName() string { return "users" }
+
+type addEmailVerificationToken struct {
+	ID        uint                      `gorm:"primary_key"`
+	UserID    uint                      `gorm:"not null;index"`
+	User      *addEmailVerificationUser `gorm:"constraint:OnDelete:CASCADE"`
+	Purpose   string                    `gorm:"not null"`
+	TokenHash string                    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time                 `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (addEmailVerificationToken) TableName() string { return "user_tokens" }
+
+// Adds users' email verification time and the tokens emailed to verify
+// addresses and reset passwords.
+var addEmailVerification = &gormigrate.Migration{
+	ID: "202610140019_add_email_verification",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addEmailVerificationUser{}, &addEmailVerificationToken{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("user_tokens"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&addEmailVerificationUser{}, "EmailVerifiedAt"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedUser struct {
+			Email string `gorm:"uniqueIndex;not null"`
+		}
+		return tx.Table("users").AutoMigrate(&indexedUser{})
+	},
+}
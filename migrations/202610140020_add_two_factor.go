@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addTwoFactorUser struct {
+	ID            uint `gorm:"primary_key"`
+	TOTPSecret    string
+	TOTPEnabledAt *time.Time
+	TOTPLastStep  int64
+}
+
+func (addTwoFactorUser) TableName() string { return "users" }
+
+type addTwoFactorRecoveryCode struct {
+	ID        uint              `gorm:"primary_key"`
+	UserID    uint              `gorm:"not null;index"`
+	User      *addTwoFactorUser `gorm:"constraint:OnDelete:CASCADE"`
+	CodeHash  string            `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (addTwoFactorRecoveryCode) TableName() string { return "recovery_codes" }
+
+// Adds users' authenticator secrets and their recovery codes.
+var addTwoFactor = &gormigrate.Migration{
+	ID: "202610140020_add_two_factor",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addTwoFactorUser{}, &addTwoFactorRecoveryCode{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropTable("recovery_codes"); err != nil {
+			return err
+		}
+		for _, column := range []string{"TOTPSecret", "TOTPEnabledAt", "TOTPLastStep"} {
+			if err := tx.Migrator().DropColumn(&addTwoFactorUser{}, column); err != nil {
+				return err
+			}
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedUser struct {
+			Email string `gorm:"uniqueIndex;not null"`
+		}
+		return tx.Table("users").AutoMigrate(&indexedUser{})
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addLockoutUser struct {
+	FailedLogins int `gorm:"not null;default:0"`
+	LockedUntil  *time.Time
+}
+
+func (addLockoutUser) TableName() string { return "users" }
+
+// Adds the failed login count and lock of accounts.
+var addLockoutToUsers = &gormigrate.Migration{
+	ID: "202610140021_add_lockout_to_users",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addLockoutUser{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"FailedLogins", "LockedUntil"} {
+			if err := tx.Migrator().DropColumn(&addLockoutUser{}, column); err != nil {
+				return err
+			}
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedUser struct {
+			Email string `gorm:"uniqueIndex;not null"`
+		}
+		return tx.Table("users").AutoMigrate(&indexedUser{})
+	},
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createTenant struct {
+	ID        uint   `gorm:"primary_key"`
+	Slug      string `gorm:"type:varchar(63);uniqueIndex;not null"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (createTenant) TableName() string { return "tenants" }
+
+// The tables holding a tenant's data.
+var tenantTables = []string{"books", "authors", "loans"}
+
+type tenantScopedRow struct {
+	TenantID uint `gorm:"not null;default:1;index"`
+}
+
+// Adds tenants and gives books, authors and loans one. Everything already
+// there goes to the default tenant, the first one, created here.
+var createTenants = &gormigrate.Migration{
+	ID: "202610140022_create_tenants",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			if err := tx.AutoMigrate(&createTenant{}); err != nil {
+				return err
+			}
+			// The table is new, so this gets ID 1, tenancy.DefaultID,
+			// without upsetting sequences by inserting it explicitly.
+			if err := tx.Create(&createTenant{Slug: "default", Name: "Default"}).Error; err != nil {
+				return err
+			}
+			for _, table := range tenantTables {
+				if err := tx.Table(table).AutoMigrate(&tenantScopedRow{}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, table := range tenantTables {
+			if err := tx.Table(table).Migrator().DropIndex(&tenantScopedRow{}, "TenantID"); err != nil {
+				return err
+			}
+			if err := tx.Table(table).Migrator().DropColumn(&tenantScopedRow{}, "TenantID"); err != nil {
+				return err
+			}
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedBook struct {
+			Slug      string         `gorm:"type:varchar(255);not null;uniqueIndex"`
+			AuthorID  uint           `gorm:"index"`
+			ISBN      string         `gorm:"index"`
+			DeletedAt gorm.DeletedAt `gorm:"index"`
+		}
+		if err := tx.Table("books").AutoMigrate(&indexedBook{}); err != nil {
+			return err
+		}
+		type indexedLoan struct {
+			BookID   string    `gorm:"type:char(36);not null;index"`
+			MemberID string    `gorm:"type:char(36);not null;index"`
+			DueAt    time.Time `gorm:"not null;index"`
+		}
+		if err := tx.Table("loans").AutoMigrate(&indexedLoan{}); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("tenants")
+	},
+}
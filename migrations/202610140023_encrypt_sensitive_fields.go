@@ -0,0 +1,136 @@
+package migrations
+
+import (
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/fieldcrypt"
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type encryptFieldsMember struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	Email     string `gorm:"type:text;not null"`
+	EmailHash string `gorm:"type:char(64);not null;default:'';uniqueIndex"`
+	Phone     string `gorm:"type:text"`
+}
+
+func (encryptFieldsMember) TableName() string { return "members" }
+
+type encryptFieldsUser struct {
+	ID         uint
+	TOTPSecret string
+}
+
+func (encryptFieldsUser) TableName() string { return "users" }
+
+// Encrypts member emails and TOTP secrets at rest, with the keys
+// fieldcrypt is set up with, and adds member phone numbers, encrypted too.
+// Member emails are no longer comparable in the database, so their
+// uniqueness moves to a blind index.
+var encryptSensitiveFields = &gormigrate.Migration{
+	ID: "202610140023_encrypt_sensitive_fields",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.Transaction(func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, column := range []string{"EmailHash", "Phone"} {
+				if err := migrator.AddColumn(&encryptFieldsMember{}, column); err != nil {
+					return err
+				}
+			}
+			if err := migrator.DropIndex(&encryptFieldsMember{}, "idx_members_email"); err != nil {
+				return err
+			}
+			// SQLite doesn't enforce lengths; elsewhere encrypted emails
+			// outgrow varchar(255).
+			if tx.Dialector.Name() != "sqlite" {
+				if err := migrator.AlterColumn(&encryptFieldsMember{}, "Email"); err != nil {
+					return err
+				}
+			}
+
+			var members []encryptFieldsMember
+			if err := tx.Select("id", "email").Find(&members).Error; err != nil {
+				return err
+			}
+			for _, member := range members {
+				email, err := fieldcrypt.Encrypt(member.Email)
+				if err != nil {
+					return err
+				}
+				err = tx.Model(&member).Updates(map[string]interface{}{
+					"email":      email,
+					"email_hash": fieldcrypt.Hash(strings.ToLower(member.Email)),
+				}).Error
+				if err != nil {
+					return err
+				}
+			}
+			if err := migrator.CreateIndex(&encryptFieldsMember{}, "EmailHash"); err != nil {
+				return err
+			}
+
+			var users []encryptFieldsUser
+			if err := tx.Where("totp_secret <> ''").Find(&users).Error; err != nil {
+				return err
+			}
+			for _, user := range users {
+				secret, err := fieldcrypt.Encrypt(user.TOTPSecret)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(&user).Update("totp_secret", secret).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		var members []encryptFieldsMember
+		if err := tx.Select("id", "email").Find(&members).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			email, err := fieldcrypt.Decrypt(member.Email)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&member).Update("email", email).Error; err != nil {
+				return err
+			}
+		}
+		var users []encryptFieldsUser
+		if err := tx.Where("totp_secret <> ''").Find(&users).Error; err != nil {
+			return err
+		}
+		for _, user := range users {
+			secret, err := fieldcrypt.Decrypt(user.TOTPSecret)
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&user).Update("totp_secret", secret).Error; err != nil {
+				return err
+			}
+		}
+
+		migrator := tx.Migrator()
+		if err := migrator.DropIndex(&encryptFieldsMember{}, "EmailHash"); err != nil {
+			return err
+		}
+		for _, column := range []string{"EmailHash", "Phone"} {
+			if err := migrator.DropColumn(&encryptFieldsMember{}, column); err != nil {
+				return err
+			}
+		}
+		type indexedMember struct {
+			Email string `gorm:"type:varchar(255);uniqueIndex;not null"`
+		}
+		if tx.Dialector.Name() != "sqlite" {
+			if err := tx.Table("members").Migrator().AlterColumn(&indexedMember{}, "Email"); err != nil {
+				return err
+			}
+		}
+		return tx.Table("members").AutoMigrate(&indexedMember{})
+	},
+}
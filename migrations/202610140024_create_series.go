@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createSeriesSeries struct {
+	ID          uint   `gorm:"primary_key"`
+	TenantID    uint   `gorm:"not null;default:1;index"`
+	Name        string `gorm:"not null"`
+	Description string `gorm:"type:text"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (createSeriesSeries) TableName() string { return "series" }
+
+type createSeriesBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createSeriesBook) TableName() string { return "books" }
+
+// A book is in a series once, and a series has one book per volume number.
+type createSeriesVolume struct {
+	SeriesID  uint                `gorm:"primaryKey;autoIncrement:false;uniqueIndex:idx_series_volumes_volume"`
+	Series    *createSeriesSeries `gorm:"constraint:OnDelete:CASCADE"`
+	BookID    string              `gorm:"type:char(36);primaryKey;index"`
+	Book      *createSeriesBook   `gorm:"constraint:OnDelete:CASCADE"`
+	Volume    int                 `gorm:"not null;uniqueIndex:idx_series_volumes_volume"`
+	CreatedAt time.Time
+}
+
+func (createSeriesVolume) TableName() string { return "series_volumes" }
+
+// Adds series of books and their volumes.
+var createSeries = &gormigrate.Migration{
+	ID: "202610140024_create_series",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createSeriesSeries{}, &createSeriesVolume{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("series_volumes", "series")
+	},
+}
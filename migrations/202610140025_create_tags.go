@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Adds free-form tags and the book_tags join table.
+var createTags = &gormigrate.Migration{
+	ID: "202610140025_create_tags",
+	Migrate: func(tx *gorm.DB) error {
+		type Tag struct {
+			ID        uint   `gorm:"primary_key"`
+			TenantID  uint   `gorm:"not null;default:1;uniqueIndex:idx_tags_tenant_name"`
+			Name      string `gorm:"type:varchar(50);not null;uniqueIndex:idx_tags_tenant_name"`
+			CreatedAt time.Time
+			UpdatedAt time.Time
+		}
+		type Book struct {
+			ID   string `gorm:"type:char(36);primaryKey"`
+			Tags []Tag  `gorm:"many2many:book_tags;constraint:OnDelete:CASCADE"`
+		}
+
+		// Migrating the book snapshot only creates the book_tags join table;
+		// the existing books columns are left alone.
+		return tx.AutoMigrate(&Tag{}, &Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("book_tags", "tags")
+	},
+}
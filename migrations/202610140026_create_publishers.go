@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Adds publishers and the optional books.publisher_id referencing them.
+var createPublishers = &gormigrate.Migration{
+	ID: "202610140026_create_publishers",
+	Migrate: func(tx *gorm.DB) error {
+		type Publisher struct {
+			ID        uint `gorm:"primary_key"`
+			TenantID  uint `gorm:"not null;default:1;index"`
+			Name      string
+			Website   string
+			CreatedAt time.Time
+			UpdatedAt time.Time
+		}
+		type Book struct {
+			PublisherID *uint `gorm:"index"`
+		}
+		return tx.AutoMigrate(&Publisher{}, &Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		type Book struct {
+			PublisherID *uint `gorm:"index"`
+		}
+		if err := tx.Migrator().DropIndex(&Book{}, "PublisherID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&Book{}, "PublisherID"); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("publishers")
+	},
+}
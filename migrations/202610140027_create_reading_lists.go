@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createReadingListsUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createReadingListsUser) TableName() string { return "users" }
+
+type createReadingListsBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createReadingListsBook) TableName() string { return "books" }
+
+type createReadingListsList struct {
+	ID          uint                    `gorm:"primary_key"`
+	UserID      uint                    `gorm:"not null;index"`
+	User        *createReadingListsUser `gorm:"constraint:OnDelete:CASCADE"`
+	Name        string                  `gorm:"not null"`
+	Description string                  `gorm:"type:text"`
+	ShareToken  *string                 `gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (createReadingListsList) TableName() string { return "reading_lists" }
+
+type createReadingListsEntry struct {
+	ReadingListID uint                    `gorm:"primaryKey;autoIncrement:false"`
+	ReadingList   *createReadingListsList `gorm:"constraint:OnDelete:CASCADE"`
+	BookID        string                  `gorm:"type:char(36);primaryKey;index"`
+	Book          *createReadingListsBook `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt     time.Time
+}
+
+func (createReadingListsEntry) TableName() string { return "reading_list_books" }
+
+type createReadingListsFavorite struct {
+	UserID    uint                    `gorm:"primaryKey;autoIncrement:false"`
+	User      *createReadingListsUser `gorm:"constraint:OnDelete:CASCADE"`
+	BookID    string                  `gorm:"type:char(36);primaryKey;index"`
+	Book      *createReadingListsBook `gorm:"constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+func (createReadingListsFavorite) TableName() string { return "favorites" }
+
+// Adds the users' reading lists and favorite books.
+var createReadingLists = &gormigrate.Migration{
+	ID: "202610140027_create_reading_lists",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createReadingListsList{}, &createReadingListsEntry{}, &createReadingListsFavorite{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("favorites", "reading_list_books", "reading_lists")
+	},
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createBookSimilaritiesBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createBookSimilaritiesBook) TableName() string { return "books" }
+
+type createBookSimilarity struct {
+	BookID        string                      `gorm:"type:char(36);primaryKey"`
+	Book          *createBookSimilaritiesBook `gorm:"constraint:OnDelete:CASCADE"`
+	SimilarBookID string                      `gorm:"type:char(36);primaryKey;index"`
+	SimilarBook   *createBookSimilaritiesBook `gorm:"constraint:OnDelete:CASCADE"`
+	Score         int                         `gorm:"not null"`
+	ComputedAt    time.Time                   `gorm:"not null"`
+}
+
+func (createBookSimilarity) TableName() string { return "book_similarities" }
+
+// Adds the precomputed similar books recommendations are made from.
+var createBookSimilarities = &gormigrate.Migration{
+	ID: "202610140028_create_book_similarities",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createBookSimilarity{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("book_similarities")
+	},
+}
@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createBookVersionsBook struct {
+	ID string `gorm:"type:char(36);primaryKey"`
+}
+
+func (createBookVersionsBook) TableName() string { return "books" }
+
+type createBookVersion struct {
+	ID        uint                    `gorm:"primary_key"`
+	BookID    string                  `gorm:"type:char(36);uniqueIndex:idx_book_versions_book_version"`
+	Book      *createBookVersionsBook `gorm:"constraint:OnDelete:CASCADE"`
+	Version   uint                    `gorm:"uniqueIndex:idx_book_versions_book_version"`
+	Snapshot  string                  `gorm:"type:text"`
+	UserID    *uint
+	CreatedAt time.Time
+}
+
+func (createBookVersion) TableName() string { return "book_versions" }
+
+// Adds the snapshots books can be diffed against and reverted to.
+var createBookVersions = &gormigrate.Migration{
+	ID: "202610140029_create_book_versions",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createBookVersion{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("book_versions")
+	},
+}
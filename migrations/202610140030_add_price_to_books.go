@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+// Adds an optional price and its currency to books.
+var addPriceToBooks = &gormigrate.Migration{
+	ID: "202610140030_add_price_to_books",
+	Migrate: func(tx *gorm.DB) error {
+		type Book struct {
+			Price    *string `gorm:"type:decimal(15,3)"`
+			Currency string  `gorm:"type:char(3)"`
+		}
+		return tx.AutoMigrate(&Book{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		// Rather than Migrator().DropColumn: on SQLite it rebuilds the table
+		// from its DDL, which it can't parse once a column is decimal(15,3).
+		// Every supported database drops columns with this statement.
+		for _, column := range []string{"price", "currency"} {
+			if err := tx.Exec("ALTER TABLE books DROP COLUMN " + column).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
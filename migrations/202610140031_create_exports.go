@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createExportsUser struct {
+	ID uint `gorm:"primary_key"`
+}
+
+func (createExportsUser) TableName() string { return "users" }
+
+type createExport struct {
+	ID         string             `gorm:"type:char(36);primaryKey"`
+	TenantID   uint               `gorm:"not null;default:1;index"`
+	UserID     uint               `gorm:"not null;index"`
+	User       *createExportsUser `gorm:"constraint:OnDelete:CASCADE"`
+	Format     string             `gorm:"size:16;not null"`
+	Filter     string             `gorm:"type:text"`
+	Status     string             `gorm:"size:16;not null;index"`
+	Error      string             `gorm:"type:text"`
+	Rows       int
+	Size       int64
+	Filename   string
+	StorageKey string
+	CreatedAt  time.Time
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	ExpiresAt  *time.Time `gorm:"index"`
+}
+
+func (createExport) TableName() string { return "exports" }
+
+// Adds the exports built in the background.
+var createExports = &gormigrate.Migration{
+	ID: "202610140031_create_exports",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createExport{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("exports")
+	},
+}
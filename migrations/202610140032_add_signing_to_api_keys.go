@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type signingAPIKey struct {
+	Signed bool   `gorm:"not null;default:false"`
+	Secret string `gorm:"type:text"`
+}
+
+func (signingAPIKey) TableName() string { return "api_keys" }
+
+// Adds API keys that sign requests instead of being sent with them, and
+// the encrypted copy of the key they are checked with.
+var addSigningToAPIKeys = &gormigrate.Migration{
+	ID: "202610140032_add_signing_to_api_keys",
+	Migrate: func(tx *gorm.DB) error {
+		for _, column := range []string{"Signed", "Secret"} {
+			if err := tx.Migrator().AddColumn(&signingAPIKey{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	Rollback: func(tx *gorm.DB) error {
+		for _, column := range []string{"Signed", "Secret"} {
+			if err := tx.Migrator().DropColumn(&signingAPIKey{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addDisabledUser struct {
+	DisabledAt *time.Time
+}
+
+func (addDisabledUser) TableName() string { return "users" }
+
+// Adds accounts disabled by admins.
+var addDisabledToUsers = &gormigrate.Migration{
+	ID: "202610140033_add_disabled_to_users",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addDisabledUser{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&addDisabledUser{}, "DisabledAt"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedUser struct {
+			Email string `gorm:"uniqueIndex;not null"`
+		}
+		return tx.Table("users").AutoMigrate(&indexedUser{})
+	},
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addTenantAuditLog struct {
+	TenantID *uint `gorm:"index"`
+}
+
+func (addTenantAuditLog) TableName() string { return "audit_logs" }
+
+// Records the tenant changes were made for, so the changes feed shows each
+// tenant its own. Earlier entries have none.
+var addTenantToAuditLogs = &gormigrate.Migration{
+	ID: "202610140034_add_tenant_to_audit_logs",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addTenantAuditLog{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&addTenantAuditLog{}, "TenantID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&addTenantAuditLog{}, "TenantID"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedAuditLog struct {
+			UserID    *uint     `gorm:"index"`
+			Entity    string    `gorm:"index:idx_audit_logs_entity"`
+			EntityID  string    `gorm:"index:idx_audit_logs_entity"`
+			CreatedAt time.Time `gorm:"index"`
+		}
+		return tx.Table("audit_logs").AutoMigrate(&indexedAuditLog{})
+	},
+}
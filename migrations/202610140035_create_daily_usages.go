@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createDailyUsage struct {
+	ID       uint   `gorm:"primary_key"`
+	UserID   uint   `gorm:"not null;uniqueIndex:idx_daily_usages_subject_day"`
+	APIKeyID uint   `gorm:"not null;default:0;uniqueIndex:idx_daily_usages_subject_day"`
+	Day      string `gorm:"type:varchar(10);not null;uniqueIndex:idx_daily_usages_subject_day;index"`
+	Requests int64  `gorm:"not null;default:0"`
+}
+
+func (createDailyUsage) TableName() string { return "daily_usages" }
+
+// Adds the request counts quotas are enforced with.
+var createDailyUsages = &gormigrate.Migration{
+	ID: "202610140035_create_daily_usages",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createDailyUsage{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("daily_usages")
+	},
+}
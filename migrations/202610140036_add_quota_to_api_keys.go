@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addQuotaAPIKey struct {
+	MonthlyQuota int `gorm:"not null;default:0"`
+}
+
+func (addQuotaAPIKey) TableName() string { return "api_keys" }
+
+// Adds the monthly quotas API keys may be issued with.
+var addQuotaToAPIKeys = &gormigrate.Migration{
+	ID: "202610140036_add_quota_to_api_keys",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addQuotaAPIKey{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&addQuotaAPIKey{}, "MonthlyQuota"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedAPIKey struct {
+			KeyHash string `gorm:"not null;uniqueIndex"`
+			UserID  uint   `gorm:"not null;index"`
+		}
+		return tx.Table("api_keys").AutoMigrate(&indexedAPIKey{})
+	},
+}
@@ -0,0 +1,44 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createNotificationsPreference struct {
+	MemberID   string `gorm:"type:char(36);primaryKey"`
+	Channels   string `gorm:"type:text"`
+	WebhookURL string
+	UpdatedAt  time.Time
+}
+
+func (createNotificationsPreference) TableName() string { return "notification_preferences" }
+
+type createNotificationsSent struct {
+	ID        uint    `gorm:"primary_key"`
+	TenantID  uint    `gorm:"not null;default:1;index"`
+	MemberID  string  `gorm:"type:char(36);not null;index"`
+	LoanID    *string `gorm:"type:char(36);index"`
+	Kind      string  `gorm:"not null"`
+	Channel   string  `gorm:"not null"`
+	Subject   string
+	Sent      bool
+	Error     string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (createNotificationsSent) TableName() string { return "sent_notifications" }
+
+// Adds the members' notification preferences and the log of the
+// notifications sent to them.
+var createNotifications = &gormigrate.Migration{
+	ID: "202610140037_create_notifications",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createNotificationsPreference{}, &createNotificationsSent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("sent_notifications", "notification_preferences")
+	},
+}
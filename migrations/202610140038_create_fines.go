@@ -0,0 +1,37 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createFinesFine struct {
+	ID          string `gorm:"type:char(36);primaryKey"`
+	TenantID    uint   `gorm:"not null;default:1;index"`
+	LoanID      string `gorm:"type:char(36);not null;uniqueIndex"`
+	MemberID    string `gorm:"type:char(36);not null;index"`
+	DaysLate    int    `gorm:"not null"`
+	Amount      string `gorm:"type:decimal(15,3);not null"`
+	Currency    string `gorm:"type:char(3);not null"`
+	Status      string `gorm:"type:varchar(10);not null;default:unpaid;index"`
+	SettledAt   *time.Time
+	SettledByID *uint
+	Note        string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (createFinesFine) TableName() string { return "fines" }
+
+// Adds the fines charged for loans returned late.
+var createFines = &gormigrate.Migration{
+	ID: "202610140038_create_fines",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createFinesFine{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		return tx.Migrator().DropTable("fines")
+	},
+}
@@ -0,0 +1,56 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type createHoldsHold struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	TenantID  uint   `gorm:"not null;default:1;index"`
+	BookID    string `gorm:"type:char(36);not null;index:idx_holds_book_status"`
+	MemberID  string `gorm:"type:char(36);not null;index"`
+	Status    string `gorm:"type:varchar(10);not null;default:waiting;index:idx_holds_book_status"`
+	BranchID  *uint
+	ReadyAt   *time.Time
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (createHoldsHold) TableName() string { return "holds" }
+
+type createHoldsSent struct {
+	HoldID *string `gorm:"type:char(36);index"`
+}
+
+func (createHoldsSent) TableName() string { return "sent_notifications" }
+
+// Adds the members' holds on books, and the hold each notification is
+// about.
+var createHolds = &gormigrate.Migration{
+	ID: "202610140040_create_holds",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&createHoldsHold{}, &createHoldsSent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&createHoldsSent{}, "HoldID"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedSent struct {
+			TenantID  uint      `gorm:"not null;default:1;index"`
+			MemberID  string    `gorm:"type:char(36);not null;index"`
+			LoanID    *string   `gorm:"type:char(36);index"`
+			CreatedAt time.Time `gorm:"index"`
+		}
+		if err := tx.Table("sent_notifications").AutoMigrate(&indexedSent{}); err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("holds")
+	},
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addTenantUser struct {
+	TenantID *uint `gorm:"index"`
+}
+
+func (addTenantUser) TableName() string { return "users" }
+
+// Adds the tenant users are members of. Existing users are members of
+// none, which is every tenant, as they were.
+var addTenantToUsers = &gormigrate.Migration{
+	ID: "202610140041_add_tenant_to_users",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addTenantUser{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropIndex(&addTenantUser{}, "TenantID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().DropColumn(&addTenantUser{}, "TenantID"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedUser struct {
+			Email string `gorm:"uniqueIndex;not null"`
+		}
+		return tx.Table("users").AutoMigrate(&indexedUser{})
+	},
+}
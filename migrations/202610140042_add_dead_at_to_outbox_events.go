@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"time"
+
+	"github.com/go-gormigrate/gormigrate/v2"
+	"gorm.io/gorm"
+)
+
+type addDeadAtOutboxEvent struct {
+	DeadAt *time.Time
+}
+
+func (addDeadAtOutboxEvent) TableName() string { return "outbox_events" }
+
+// Adds when events that can never be published were set aside.
+var addDeadAtToOutboxEvents = &gormigrate.Migration{
+	ID: "202610140042_add_dead_at_to_outbox_events",
+	Migrate: func(tx *gorm.DB) error {
+		return tx.AutoMigrate(&addDeadAtOutboxEvent{})
+	},
+	Rollback: func(tx *gorm.DB) error {
+		if err := tx.Migrator().DropColumn(&addDeadAtOutboxEvent{}, "DeadAt"); err != nil {
+			return err
+		}
+
+		// SQLite drops a column by rebuilding the table, which loses the
+		// indexes on the remaining columns.
+		type indexedOutboxEvent struct {
+			EventID     string     `gorm:"type:varchar(36);not null;uniqueIndex"`
+			PublishedAt *time.Time `gorm:"index"`
+			CreatedAt   time.Time  `gorm:"index"`
+		}
+		return tx.Table("outbox_events").AutoMigrate(&indexedOutboxEvent{})
+	},
+}
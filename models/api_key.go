@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// API key scopes: read allows safe requests (GET, HEAD), write everything
+// else as well.
+const (
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
+
+// APIKey lets a machine client call the API in the name of the user it was
+// issued to, with that user's role, limited to its scopes.
+type APIKey struct {
+	ID   uint   `json:"id" gorm:"primary_key"`
+	Name string `json:"name" gorm:"not null"`
+	// Prefix is the start of the key, shown so keys can be told apart; the
+	// key itself is only shown when it is issued, and only its SHA-256 hash
+	// is stored.
+	Prefix  string     `json:"prefix" gorm:"not null"`
+	KeyHash string     `json:"-" gorm:"not null;uniqueIndex" audit:"-"`
+	Scopes  StringList `json:"scopes" gorm:"type:text"`
+	UserID  uint       `json:"user_id" gorm:"not null;index"`
+	User    *User      `json:"-"`
+	// Requests per second and burst allowed to the key; 0 uses the default
+	// rate limit.
+	RateLimit float64 `json:"rate_limit"`
+	RateBurst int     `json:"rate_burst"`
+	// Requests allowed to the key per calendar month; 0 uses the default
+	// quota.
+	MonthlyQuota int        `json:"monthly_quota" gorm:"not null;default:0"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Signed keys never travel with requests: they sign them instead, as
+	// described in the auth package, and are also kept encrypted to check
+	// the signatures with. Requests sending them in X-API-Key are refused.
+	Signed bool   `json:"signed" gorm:"not null;default:false"`
+	Secret string `json:"-" gorm:"serializer:encrypted" audit:"-"`
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key can be used at t.
+func (k *APIKey) Active(t time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || t.Before(*k.ExpiresAt))
+}
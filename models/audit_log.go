@@ -0,0 +1,59 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditLog records one create, update or delete of a row: who made it, on
+// which table and row, and the column values written.
+type AuditLog struct {
+	ID     uint   `json:"id" gorm:"primary_key"`
+	UserID *uint  `json:"user_id" gorm:"index"`
+	Action string `json:"action"`
+	// Entity is the table name, e.g. "books"; EntityID the row's primary key
+	// (comma separated when it has several columns).
+	Entity    string     `json:"entity" gorm:"index:idx_audit_logs_entity"`
+	EntityID  string     `json:"entity_id" gorm:"index:idx_audit_logs_entity"`
+	Changes   JSONObject `json:"changes,omitempty" gorm:"type:text" swaggertype:"object"`
+	RequestID string     `json:"request_id,omitempty"`
+	// The tenant the change was made for; unset for changes made outside
+	// of any, such as by the maintenance jobs.
+	TenantID  *uint     `json:"-" gorm:"index"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// JSONObject is stored as a JSON text column.
+type JSONObject map[string]interface{}
+
+func (o JSONObject) Value() (driver.Value, error) {
+	if o == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(o)
+	return string(b), err
+}
+
+func (o *JSONObject) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*o = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return errors.New("JSONObject: unsupported column type")
+	}
+	return json.Unmarshal(raw, o)
+}
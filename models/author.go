@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Author struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1;index"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetTenant makes the author the tenant's.
+func (a *Author) SetTenant(id uint) {
+	a.TenantID = id
+}
@@ -1,7 +1,80 @@
 package models
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
 type Book struct {
-	ID     uint   `json:"id" gorm:"primary_key"`
-	Title  string `json:"title"`
-	Author string `json:"author"`
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	// Slug is derived from the title when the book is created and then kept,
+	// so links to it stay valid when the title is edited.
+	Slug        string  `json:"slug" gorm:"type:varchar(255);uniqueIndex;not null"`
+	Title       string  `json:"title"`
+	Description string  `json:"description" gorm:"type:text"`
+	AuthorID    uint    `json:"author_id" gorm:"index"`
+	Author      *Author `json:"author,omitempty"`
+	// Books may have no publisher.
+	PublisherID *uint      `json:"publisher_id" gorm:"index"`
+	Publisher   *Publisher `json:"publisher,omitempty"`
+	Categories  []Category `json:"categories,omitempty" gorm:"many2many:book_categories"`
+	Tags        []Tag      `json:"tags,omitempty" gorm:"many2many:book_tags"`
+	Year        int        `json:"year"`
+	ISBN        string     `json:"isbn" gorm:"index"`
+	// Quantity is how many copies the library owns; AvailableCopies is how
+	// many of them are not on loan. Both change only through stock
+	// adjustments, checkouts and returns, which lock the row.
+	Quantity        int `json:"quantity" gorm:"not null;default:1"`
+	AvailableCopies int `json:"available_copies" gorm:"not null;default:1"`
+	// Denormalized from the book's reviews, refreshed whenever one is added.
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0"`
+	ReviewCount   int     `json:"review_count" gorm:"not null;default:0"`
+	// Price is in Currency, an ISO 4217 code; books may have neither. JSON
+	// carries it as a string so it never goes through a float.
+	Price    *decimal.Decimal `json:"price" gorm:"type:decimal(15,3)" swaggertype:"string" example:"19.90"`
+	Currency string           `json:"currency" gorm:"type:char(3)" example:"EUR"`
+	// ConvertedPrice is the price in the currency asked for with
+	// ?convert_to=; it isn't stored.
+	ConvertedPrice *Money `json:"converted_price,omitempty" gorm:"-"`
+	// Version is incremented by every update; writes carrying an older one
+	// are rejected.
+	Version uint `json:"version" gorm:"not null;default:1"`
+	// Storage keys of the uploaded cover and its thumbnail; served through
+	// GET books/:id/cover rather than exposed.
+	CoverKey          string         `json:"-"`
+	CoverThumbnailKey string         `json:"-"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index" swaggertype:"string" format:"date-time"`
+}
+
+// Money is an amount in a currency, written with as many decimals as the
+// currency has, e.g. "21.50".
+type Money struct {
+	Amount   string `json:"amount" example:"21.50"`
+	Currency string `json:"currency" example:"USD"`
+}
+
+func (b *Book) BeforeCreate(tx *gorm.DB) error {
+	assignID(&b.ID)
+	if b.Version == 0 {
+		b.Version = 1
+	}
+	if b.Quantity == 0 {
+		b.Quantity = 1
+	}
+	b.AvailableCopies = b.Quantity
+	return nil
+}
+
+// ETag identifies this version of the book; it changes on every update, and
+// when a review is added or a copy is lent or returned, since those don't
+// bump the version.
+func (b *Book) ETag() string {
+	return fmt.Sprintf(`"%s-%d-%d-%d"`, b.ID, b.Version, b.ReviewCount, b.AvailableCopies)
 }
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BookSimilarity scores how alike two books are, going by what they share
+// and who borrowed both. They are recomputed in bulk by the
+// refresh-recommendations job rather than on every change.
+type BookSimilarity struct {
+	BookID        uuid.UUID `gorm:"type:char(36);primaryKey"`
+	SimilarBookID uuid.UUID `gorm:"type:char(36);primaryKey"`
+	Score         int       `gorm:"not null"`
+	ComputedAt    time.Time `gorm:"not null"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// BookVersion is a book as one of its versions left it. A version is
+// recorded whenever a book is created or its version bumped, by whom when
+// known; versions written before versions were recorded are kept on the
+// book's next update, with no user.
+type BookVersion struct {
+	ID        uint         `json:"-" gorm:"primary_key"`
+	BookID    uuid.UUID    `json:"book_id" gorm:"type:char(36);uniqueIndex:idx_book_versions_book_version" swaggertype:"string" format:"uuid"`
+	Version   uint         `json:"version" gorm:"uniqueIndex:idx_book_versions_book_version"`
+	Snapshot  BookSnapshot `json:"snapshot" gorm:"type:text"`
+	UserID    *uint        `json:"user_id"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// BookSnapshot holds the fields of a book that are edited and versioned,
+// stored as a JSON text column.
+type BookSnapshot struct {
+	Title       string           `json:"title"`
+	Description string           `json:"description"`
+	AuthorID    uint             `json:"author_id"`
+	PublisherID *uint            `json:"publisher_id"`
+	Year        int              `json:"year"`
+	ISBN        string           `json:"isbn"`
+	Quantity    int              `json:"quantity"`
+	Price       *decimal.Decimal `json:"price" swaggertype:"string"`
+	Currency    string           `json:"currency"`
+}
+
+func (b *Book) Snapshot() BookSnapshot {
+	return BookSnapshot{
+		Title:       b.Title,
+		Description: b.Description,
+		AuthorID:    b.AuthorID,
+		PublisherID: b.PublisherID,
+		Year:        b.Year,
+		ISBN:        b.ISBN,
+		Quantity:    b.Quantity,
+		Price:       b.Price,
+		Currency:    b.Currency,
+	}
+}
+
+func (s BookSnapshot) Value() (driver.Value, error) {
+	b, err := json.Marshal(s)
+	return string(b), err
+}
+
+func (s *BookSnapshot) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return errors.New("BookSnapshot: unsupported column type")
+	}
+	return json.Unmarshal(raw, s)
+}
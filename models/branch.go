@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Branch is one of the places a library keeps its books. A book's copies
+// are spread across its tenant's branches; those at none are unassigned.
+type Branch struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1;uniqueIndex:idx_branches_tenant_code"`
+	Code      string    `json:"code" gorm:"type:varchar(32);not null;uniqueIndex:idx_branches_tenant_code" example:"downtown"`
+	Name      string    `json:"name" gorm:"not null"`
+	Address   string    `json:"address"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetTenant makes the branch the tenant's.
+func (b *Branch) SetTenant(id uint) {
+	b.TenantID = id
+}
+
+// BranchStock is how many copies of a book a branch holds, and how many of
+// them are on its shelves; the others are on loan. The book's Quantity and
+// AvailableCopies count those of every branch, and the unassigned ones.
+type BranchStock struct {
+	BookID          uuid.UUID `json:"book_id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	BranchID        uint      `json:"branch_id" gorm:"primaryKey;index"`
+	Quantity        int       `json:"quantity" gorm:"not null;default:0"`
+	AvailableCopies int       `json:"available_copies" gorm:"not null;default:0"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// StockTransfer records copies of a book moved from one branch to another.
+// A nil branch stands for the unassigned copies.
+type StockTransfer struct {
+	ID           uint      `json:"id" gorm:"primary_key"`
+	TenantID     uint      `json:"-" gorm:"not null;default:1;index"`
+	BookID       uuid.UUID `json:"book_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	FromBranchID *uint     `json:"from_branch_id" gorm:"index"`
+	ToBranchID   *uint     `json:"to_branch_id" gorm:"index"`
+	Copies       int       `json:"copies" gorm:"not null"`
+	// The user who moved them, and why.
+	UserID    *uint     `json:"user_id"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
@@ -0,0 +1,12 @@
+package models
+
+// DailyUsage counts the requests a user made on a day (UTC): with one of
+// their API keys, or with access tokens when APIKeyID is 0.
+type DailyUsage struct {
+	ID       uint `json:"-" gorm:"primary_key"`
+	UserID   uint `json:"user_id" gorm:"not null;uniqueIndex:idx_daily_usages_subject_day"`
+	APIKeyID uint `json:"api_key_id" gorm:"not null;default:0;uniqueIndex:idx_daily_usages_subject_day"`
+	// The day as YYYY-MM-DD, which sorts as the days do.
+	Day      string `json:"day" gorm:"type:varchar(10);not null;uniqueIndex:idx_daily_usages_subject_day;index"`
+	Requests int64  `json:"requests" gorm:"not null;default:0"`
+}
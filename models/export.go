@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The states of an export.
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportSucceeded = "succeeded"
+	ExportFailed    = "failed"
+)
+
+// Export is a file of the catalog, or of the books matching a filter, built
+// in the background for the user who asked for it and kept until
+// ExpiresAt.
+type Export struct {
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	UserID   uint      `json:"-" gorm:"not null;index"`
+	Format   string    `json:"format" gorm:"size:16;not null"`
+	// The books exported, a repositories.BookFilter as JSON.
+	Filter string `json:"-" gorm:"type:text"`
+	// pending, running, succeeded or failed.
+	Status string `json:"status" gorm:"size:16;not null;index"`
+	// Why the export failed.
+	Error string `json:"error,omitempty" gorm:"type:text"`
+	// How many books were exported, and the size of the file in bytes.
+	Rows       int        `json:"rows"`
+	Size       int64      `json:"size"`
+	Filename   string     `json:"filename,omitempty"`
+	StorageKey string     `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	ExpiresAt  *time.Time `json:"expires_at" gorm:"index"`
+}
+
+func (e *Export) BeforeCreate(tx *gorm.DB) error {
+	assignID(&e.ID)
+	return nil
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// The states of a fine: owed until paid or waived.
+const (
+	FineUnpaid = "unpaid"
+	FinePaid   = "paid"
+	FineWaived = "waived"
+)
+
+// Fine is what a member is charged for returning a loan late; there is at
+// most one per loan.
+type Fine struct {
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	LoanID   uuid.UUID `json:"loan_id" gorm:"type:char(36);not null;uniqueIndex" swaggertype:"string" format:"uuid"`
+	MemberID uuid.UUID `json:"member_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	DaysLate int       `json:"days_late" gorm:"not null"`
+	// JSON carries the amount as a string so it never goes through a float.
+	Amount   decimal.Decimal `json:"amount" gorm:"type:decimal(15,3);not null" swaggertype:"string" example:"2.50"`
+	Currency string          `json:"currency" gorm:"type:char(3);not null" example:"USD"`
+	Status   string          `json:"status" gorm:"type:varchar(10);not null;default:unpaid;index" enums:"unpaid,paid,waived"`
+	// When, by which user and why the fine was paid or waived.
+	SettledAt   *time.Time `json:"settled_at"`
+	SettledByID *uint      `json:"settled_by_id"`
+	Note        string     `json:"note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (f *Fine) BeforeCreate(tx *gorm.DB) error {
+	assignID(&f.ID)
+	return nil
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// The states of a hold: waiting in line until a returned copy is set aside
+// for it, then ready until the member borrows the copy, or the pickup
+// window ends and the hold expires. Members may cancel holds before that.
+const (
+	HoldWaiting   = "waiting"
+	HoldReady     = "ready"
+	HoldFulfilled = "fulfilled"
+	HoldCancelled = "cancelled"
+	HoldExpired   = "expired"
+)
+
+// Hold is a member's place in the line for a book with no copies
+// available. The book's waiting holds get copies in the order they were
+// placed.
+type Hold struct {
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	BookID   uuid.UUID `json:"book_id" gorm:"type:char(36);not null;index:idx_holds_book_status" swaggertype:"string" format:"uuid"`
+	Book     *Book     `json:"book,omitempty"`
+	MemberID uuid.UUID `json:"member_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	Member   *Member   `json:"member,omitempty"`
+	Status   string    `json:"status" gorm:"type:varchar(10);not null;default:waiting;index:idx_holds_book_status" enums:"waiting,ready,fulfilled,cancelled,expired"`
+	// Position is the hold's place in the line while it waits, 1 being
+	// next.
+	Position int `json:"position,omitempty" gorm:"-"`
+	// BranchID is where the copy set aside for the hold is; nil for an
+	// unassigned copy.
+	BranchID *uint `json:"branch_id"`
+	// When the copy was set aside, and until when it is kept.
+	ReadyAt   *time.Time `json:"ready_at"`
+	ExpiresAt *time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+func (h *Hold) BeforeCreate(tx *gorm.DB) error {
+	assignID(&h.ID)
+	return nil
+}
@@ -0,0 +1,11 @@
+package models
+
+import "github.com/google/uuid"
+
+// assignID gives a new record a random UUID primary key unless the caller
+// chose one. Models keyed by UUID call it from their BeforeCreate hook.
+func assignID(id *uuid.UUID) {
+	if *id == uuid.Nil {
+		*id = uuid.New()
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Loan is one copy of a book checked out by a member. It stays active until
+// ReturnedAt is set.
+type Loan struct {
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	BookID   uuid.UUID `json:"book_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	Book     *Book     `json:"book,omitempty"`
+	MemberID uuid.UUID `json:"member_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	Member   *Member   `json:"member,omitempty"`
+	// BranchID is where the copy was lent from and goes back to; nil for an
+	// unassigned copy.
+	BranchID   *uint      `json:"branch_id"`
+	LoanedAt   time.Time  `json:"loaned_at" gorm:"not null"`
+	DueAt      time.Time  `json:"due_at" gorm:"not null;index"`
+	ReturnedAt *time.Time `json:"returned_at"`
+	// Fine is what returning the loan late cost, if it did.
+	Fine      *Fine     `json:"fine,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (l *Loan) BeforeCreate(tx *gorm.DB) error {
+	assignID(&l.ID)
+	return nil
+}
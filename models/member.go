@@ -0,0 +1,40 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/fieldcrypt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Member is a library patron who can borrow books. Members are managed by
+// staff and are not tied to a user account.
+type Member struct {
+	ID   uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	Name string    `json:"name" gorm:"not null"`
+	// Email and Phone are encrypted at rest, see the fieldcrypt package.
+	// EmailHash, the blind index of the email, keeps emails unique.
+	Email     string    `json:"email" gorm:"type:text;not null;serializer:encrypted" audit:"-"`
+	EmailHash string    `json:"-" gorm:"type:char(64);uniqueIndex;not null" audit:"-"`
+	Phone     string    `json:"phone" gorm:"type:text;serializer:encrypted" audit:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (m *Member) BeforeCreate(tx *gorm.DB) error {
+	assignID(&m.ID)
+	return nil
+}
+
+func (m *Member) BeforeSave(tx *gorm.DB) error {
+	m.EmailHash = MemberEmailHash(m.Email)
+	return nil
+}
+
+// MemberEmailHash is the blind index of a member email; emails differing
+// only in case are the same.
+func MemberEmailHash(email string) string {
+	return fieldcrypt.Hash(strings.ToLower(email))
+}
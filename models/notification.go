@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// The kinds of notifications: reminders of overdue loans, and news that
+// the copy a member held was set aside for them.
+const (
+	NotificationKindLoanOverdue = "loan.overdue"
+	NotificationKindHoldReady   = "hold.ready"
+)
+
+// NotificationPreference is how a member wants to be notified. Members
+// without one get the configured default channels.
+type NotificationPreference struct {
+	MemberID uuid.UUID `json:"member_id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	// Channels are email, webhook and console; empty sends nothing.
+	Channels StringList `json:"channels" gorm:"type:text"`
+	// Where the webhook channel POSTs to.
+	WebhookURL string    `json:"webhook_url"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SentNotification records one notification sent, or attempted, to a
+// member through one channel.
+type SentNotification struct {
+	ID       uint      `json:"id" gorm:"primary_key"`
+	TenantID uint      `json:"-" gorm:"not null;default:1;index"`
+	MemberID uuid.UUID `json:"member_id" gorm:"type:char(36);not null;index" swaggertype:"string" format:"uuid"`
+	// The loan or hold it is about, if any.
+	LoanID  *uuid.UUID `json:"loan_id" gorm:"type:char(36);index" swaggertype:"string" format:"uuid"`
+	HoldID  *uuid.UUID `json:"hold_id" gorm:"type:char(36);index" swaggertype:"string" format:"uuid"`
+	Kind    string     `json:"kind" gorm:"not null"`
+	Channel string     `json:"channel" gorm:"not null"`
+	Subject string     `json:"subject"`
+	// Error says why, when it wasn't Sent.
+	Sent      bool      `json:"sent"`
+	Error     string    `json:"error,omitempty"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
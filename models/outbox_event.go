@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// OutboxEvent is an event waiting to be published, written in the same
+// transaction as the change it describes.
+type OutboxEvent struct {
+	ID      uint   `gorm:"primary_key"`
+	EventID string `gorm:"type:varchar(36);not null;uniqueIndex"`
+	Type    string `gorm:"not null"`
+	// Payload is the event's JSON.
+	Payload string `gorm:"type:text;not null"`
+	// Attempts counts the failed publishing attempts; LastError holds the
+	// reason of the latest one.
+	Attempts  int
+	LastError string
+	// An instance publishing the event holds it until LockedUntil, so
+	// others skip it.
+	LockedUntil *time.Time
+	PublishedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time  `gorm:"index"`
+	// DeadAt is set once the event can never be published, as its payload
+	// doesn't decode; it is kept, with the reason, for inspection.
+	DeadAt *time.Time
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type Publisher struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1;index"`
+	Name      string    `json:"name"`
+	Website   string    `json:"website"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SetTenant makes the publisher the tenant's.
+func (p *Publisher) SetTenant(id uint) {
+	p.TenantID = id
+}
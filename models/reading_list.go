@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ReadingList is a named list of books kept by a user. It is private to
+// them until they share it, which gives it a ShareToken anyone holding can
+// read it with.
+type ReadingList struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	UserID      uint      `json:"-" gorm:"not null;index"`
+	Name        string    `json:"name"`
+	Description string    `json:"description" gorm:"type:text"`
+	ShareToken  *string   `json:"share_token,omitempty" gorm:"type:varchar(64);uniqueIndex"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ReadingListBook is a book on a reading list, which holds it once.
+type ReadingListBook struct {
+	ReadingListID uint      `json:"reading_list_id" gorm:"primaryKey;autoIncrement:false"`
+	BookID        uuid.UUID `json:"book_id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	Book          *Book     `json:"book,omitempty"`
+	CreatedAt     time.Time `json:"added_at"`
+}
+
+// Favorite marks a book as one of a user's favorites.
+type Favorite struct {
+	UserID    uint      `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	BookID    uuid.UUID `json:"book_id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	Book      *Book     `json:"book,omitempty"`
+	CreatedAt time.Time `json:"added_at"`
+}
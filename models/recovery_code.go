@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// RecoveryCode stands in for an authenticator code once, for users who lost
+// their authenticator. Only its SHA-256 hash is stored.
+type RecoveryCode struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	CodeHash  string     `json:"-" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
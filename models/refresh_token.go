@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RefreshToken lets a client get a new access token without logging in
+// again. Each is used once: refreshing revokes it and issues the next one
+// of its family, the tokens descending from the same login. Only the
+// SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	Family    string     `json:"family" gorm:"not null;index"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Active reports whether the token can be used at t.
+func (t *RefreshToken) Active(at time.Time) bool {
+	return t.RevokedAt == nil && at.Before(t.ExpiresAt)
+}
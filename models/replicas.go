@@ -0,0 +1,79 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+)
+
+// The tables served by the replicas: the catalog, which is read far more
+// than it is written and can stand a little lag. Everything else, such as
+// accounts and loans, is always read from the primary.
+var replicatedTables = []interface{}{&Book{}, &Author{}, &Publisher{}, &Category{}, &Review{}, &Series{}, &SeriesVolume{}, &Tag{}, "book_categories", "book_tags"}
+
+func useReplicas(db *gorm.DB, cfg config.DatabaseConfig) error {
+	replicas := make([]gorm.Dialector, len(cfg.Replicas))
+	for i, dsn := range cfg.Replicas {
+		replicas[i] = dialector(cfg.Driver, dsn)
+	}
+	resolver := dbresolver.Register(dbresolver.Config{Replicas: replicas}, replicatedTables...).
+		SetMaxOpenConns(cfg.MaxOpenConns).
+		SetMaxIdleConns(cfg.MaxIdleConns).
+		SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	if err := db.Use(resolver); err != nil {
+		return err
+	}
+	// Statements in a transaction from InTransaction must all go to it.
+	callbacks := db.Callback()
+	err := errors.Join(
+		callbacks.Query().After("gorm:db_resolver").Before("gorm:query").Register("replicas:in_transaction", inTransaction),
+		callbacks.Row().After("gorm:db_resolver").Before("gorm:row").Register("replicas:in_transaction", inTransaction),
+		callbacks.Raw().After("gorm:db_resolver").Before("gorm:raw").Register("replicas:in_transaction", inTransaction),
+	)
+	if err != nil || !cfg.ReadYourWrites {
+		return err
+	}
+
+	// Mark the context once it has written, and read from the primary
+	// through marked contexts.
+	return errors.Join(
+		callbacks.Create().After("*").Register("replicas:mark_write", markWrite),
+		callbacks.Update().After("*").Register("replicas:mark_write", markWrite),
+		callbacks.Delete().After("*").Register("replicas:mark_write", markWrite),
+		callbacks.Raw().After("*").Register("replicas:mark_write", markWrite),
+		callbacks.Query().After("gorm:db_resolver").Before("gorm:query").Register("replicas:read_your_writes", readYourWrites),
+		callbacks.Row().After("gorm:db_resolver").Before("gorm:row").Register("replicas:read_your_writes", readYourWrites),
+	)
+}
+
+type writesKey struct{}
+
+// WithReadYourWrites returns a context reading from the primary once a
+// write has been made through it, so a request sees its own writes
+// despite replica lag. It has no effect without replicas or when
+// read_your_writes is off.
+func WithReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, writesKey{}, new(atomic.Bool))
+}
+
+func markWrite(db *gorm.DB) {
+	if wrote, ok := db.Statement.Context.Value(writesKey{}).(*atomic.Bool); ok && db.Error == nil {
+		wrote.Store(true)
+	}
+}
+
+func readYourWrites(db *gorm.DB) {
+	if wrote, ok := db.Statement.Context.Value(writesKey{}).(*atomic.Bool); ok && wrote.Load() {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
+
+func inTransaction(db *gorm.DB) {
+	if _, ok := transactionFrom(db.Statement.Context); ok {
+		dbresolver.Write.ModifyStatement(db.Statement)
+	}
+}
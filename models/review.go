@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Review is a user's rating of a book; each user reviews a book at most once.
+type Review struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	BookID    uuid.UUID `json:"book_id" gorm:"type:char(36);not null;uniqueIndex:idx_reviews_book_user" swaggertype:"string" format:"uuid"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_reviews_book_user"`
+	Rating    int       `json:"rating" gorm:"not null"`
+	Comment   string    `json:"comment" gorm:"type:text"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (r *Review) BeforeCreate(tx *gorm.DB) error {
+	assignID(&r.ID)
+	return nil
+}
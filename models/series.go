@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Series groups books meant to be read in order, each as a numbered volume.
+type Series struct {
+	ID          uint      `json:"id" gorm:"primary_key"`
+	TenantID    uint      `json:"-" gorm:"not null;default:1;index"`
+	Name        string    `json:"name"`
+	Description string    `json:"description" gorm:"type:text"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (Series) TableName() string { return "series" }
+
+// SeriesVolume places a book in a series. A book is in a series at most
+// once, and no two books of a series share a volume number.
+type SeriesVolume struct {
+	SeriesID  uint      `json:"series_id" gorm:"primaryKey;autoIncrement:false"`
+	BookID    uuid.UUID `json:"book_id" gorm:"type:char(36);primaryKey" swaggertype:"string" format:"uuid"`
+	Book      *Book     `json:"book,omitempty"`
+	Volume    int       `json:"volume" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
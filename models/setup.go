@@ -3,25 +3,101 @@
 package models
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/querystats"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
-	_ "gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
 var DB *gorm.DB
 
-func ConnectDatabase() {
+// ConnectDatabase opens the connection pool. A database that can't be
+// reached yet, such as one starting alongside the app, is retried with
+// exponential backoff for up to cfg.ConnectTimeout.
+func ConnectDatabase(cfg config.DatabaseConfig) error {
+	deadline := time.Now().Add(cfg.ConnectTimeout)
+	backoff := 250 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		database, err := open(cfg)
+		if err == nil {
+			DB = database
+			return nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("connect to database (%d attempts): %w", attempt, err)
+		}
+		slog.Warn("database unreachable, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		time.Sleep(backoff)
+		backoff = min(2*backoff, 5*time.Second)
+	}
+}
 
-	database, err := gorm.Open(sqlite.Open("test.db"), &gorm.Config{})
+func open(cfg config.DatabaseConfig) (*gorm.DB, error) {
+	// gorm.Open pings the database.
+	database, err := gorm.Open(dialector(cfg.Driver, cfg.DSN), &gorm.Config{
+		TranslateError: true,
+		Logger:         querystats.NewLogger(),
+	})
+	if err != nil {
+		if database != nil {
+			if sqlDB, dbErr := database.DB(); dbErr == nil {
+				sqlDB.Close()
+			}
+		}
+		return nil, err
+	}
 
+	sqlDB, err := database.DB()
 	if err != nil {
-		panic("Failed to connect to database!")
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	database.ConnPool = ambientPool{database.ConnPool}
+	database.Statement.ConnPool = database.ConnPool
+
+	if len(cfg.Replicas) > 0 {
+		if err := useReplicas(database, cfg); err != nil {
+			sqlDB.Close()
+			return nil, fmt.Errorf("replicas: %w", err)
+		}
 	}
+	return database, nil
+}
+
+func dialector(driver, dsn string) gorm.Dialector {
+	switch driver {
+	case "postgres":
+		return postgres.Open(dsn)
+	case "mysql":
+		return mysql.Open(dsn)
+	default:
+		return sqlite.Open(dsn)
+	}
+}
 
-	err = database.AutoMigrate(&Book{})
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context) error {
+	sqlDB, err := DB.DB()
 	if err != nil {
-		return
+		return err
 	}
+	return sqlDB.PingContext(ctx)
+}
 
-	DB = database
+// Close releases the connection pool.
+func Close() error {
+	sqlDB, err := DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
 }
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Tag is a free-form label on books. Names are kept lowercase, and are
+// unique within a tenant.
+type Tag struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	TenantID  uint      `json:"-" gorm:"not null;default:1"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Tenant is one of the libraries a deployment serves. Its books, authors
+// and loans are only visible to requests made for it, which name it by
+// slug in a header or as the subdomain of the host.
+type Tenant struct {
+	ID        uint      `json:"id" gorm:"primary_key"`
+	Slug      string    `json:"slug" gorm:"type:varchar(63);uniqueIndex;not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
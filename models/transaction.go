@@ -0,0 +1,150 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// ambientTx is the transaction of an InTransaction call, and what to do
+// once it is committed.
+type ambientTx struct {
+	tx          *sql.Tx
+	mu          sync.Mutex
+	afterCommit []func(ctx context.Context)
+}
+
+// InTransaction runs fn with a context every statement made through DB
+// with it joins one transaction, committed if fn returns nil and rolled
+// back otherwise. Repositories don't need to know: the transactions they
+// start themselves through the context become savepoints of it. Called
+// with a context in a transaction already, it runs fn in that one.
+func InTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := transactionFrom(ctx); ok {
+		return fn(ctx)
+	}
+	ambient := &ambientTx{}
+	err := DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ambient.tx = tx.Statement.ConnPool.(*sql.Tx)
+		return fn(context.WithValue(ctx, txKey{}, ambient))
+	})
+	if err != nil {
+		return err
+	}
+	for _, hook := range ambient.afterCommit {
+		hook(ctx)
+	}
+	return nil
+}
+
+// InAmbientTransaction reports whether ctx comes from InTransaction, so
+// that what is read with it may not be committed yet.
+func InAmbientTransaction(ctx context.Context) bool {
+	_, ok := transactionFrom(ctx)
+	return ok
+}
+
+// AfterCommit calls fn once the transaction ctx is in is committed, and
+// never if it is rolled back; outside of one, it calls fn right away. fn is
+// given a context out of the transaction.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	ambient, ok := ctx.Value(txKey{}).(*ambientTx)
+	if !ok {
+		fn(ctx)
+		return
+	}
+	ambient.mu.Lock()
+	defer ambient.mu.Unlock()
+	ambient.afterCommit = append(ambient.afterCommit, fn)
+}
+
+func transactionFrom(ctx context.Context) (*sql.Tx, bool) {
+	ambient, ok := ctx.Value(txKey{}).(*ambientTx)
+	if !ok {
+		return nil, false
+	}
+	return ambient.tx, true
+}
+
+// ambientPool sends the statements made with a context from InTransaction
+// to its transaction, and the others to the pool.
+type ambientPool struct {
+	gorm.ConnPool
+}
+
+func (p ambientPool) conn(ctx context.Context) gorm.ConnPool {
+	if tx, ok := transactionFrom(ctx); ok {
+		return tx
+	}
+	return p.ConnPool
+}
+
+func (p ambientPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.conn(ctx).PrepareContext(ctx, query)
+}
+
+func (p ambientPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.conn(ctx).ExecContext(ctx, query, args...)
+}
+
+func (p ambientPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.conn(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p ambientPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.conn(ctx).QueryRowContext(ctx, query, args...)
+}
+
+func (p ambientPool) GetDBConn() (*sql.DB, error) {
+	if db, ok := p.ConnPool.(*sql.DB); ok {
+		return db, nil
+	}
+	if connector, ok := p.ConnPool.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	return nil, gorm.ErrInvalidDB
+}
+
+var savepoints atomic.Uint64
+
+// BeginTx starts a transaction, or a savepoint of the one ctx is in.
+func (p ambientPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	if tx, ok := transactionFrom(ctx); ok {
+		name := fmt.Sprintf("ambient_%d", savepoints.Add(1))
+		if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+			return nil, err
+		}
+		return &savepoint{Tx: tx, ctx: ctx, name: name}, nil
+	}
+	switch beginner := p.ConnPool.(type) {
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	}
+	return nil, gorm.ErrInvalidTransaction
+}
+
+// savepoint stands for a transaction begun inside another: committing it
+// releases the savepoint, rolling it back undoes what was done since.
+type savepoint struct {
+	*sql.Tx
+	ctx  context.Context
+	name string
+}
+
+func (s *savepoint) Commit() error {
+	_, err := s.Tx.ExecContext(s.ctx, "RELEASE SAVEPOINT "+s.name)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	_, err := s.Tx.ExecContext(s.ctx, "ROLLBACK TO SAVEPOINT "+s.name)
+	return err
+}
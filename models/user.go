@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	RoleAdmin  = "admin"
+	RoleReader = "reader"
+)
+
+type User struct {
+	ID           uint   `json:"id" gorm:"primary_key"`
+	Email        string `json:"email" gorm:"uniqueIndex;not null"`
+	PasswordHash string `json:"-" gorm:"not null" audit:"-"`
+	Role         string `json:"role" gorm:"not null;default:reader"`
+	// The tenant the user is a member of, whose data is the only data they
+	// can see or change; users of none are members of every tenant.
+	TenantID *uint `json:"tenant_id" gorm:"index"`
+	// Set once the user proves they own the address.
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	// The secret of the user's authenticator app; logging in with a
+	// password takes one of its codes too once TOTPEnabledAt is set.
+	TOTPSecret    string     `json:"-" gorm:"serializer:encrypted" audit:"-"`
+	TOTPEnabledAt *time.Time `json:"totp_enabled_at"`
+	// The time step of the last code used, which can't be used again.
+	TOTPLastStep int64 `json:"-" audit:"-"`
+	// Failed logins since the last successful one; too many lock the
+	// account until LockedUntil.
+	FailedLogins int        `json:"failed_logins"`
+	LockedUntil  *time.Time `json:"locked_until"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Disabled accounts can't log in, refresh their tokens or use their API
+	// keys until an admin enables them again.
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+func (u *User) SetPassword(password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+func (u *User) TOTPEnabled() bool {
+	return u.TOTPEnabledAt != nil
+}
+
+// Locked reports whether logins to the account are refused at t.
+func (u *User) Locked(t time.Time) bool {
+	return u.LockedUntil != nil && t.Before(*u.LockedUntil)
+}
+
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+func (u *User) CheckPassword(password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
+}
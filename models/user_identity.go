@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to their account at an OAuth provider, so
+// signing in there signs them in here.
+type UserIdentity struct {
+	ID       uint   `json:"id" gorm:"primary_key"`
+	UserID   uint   `json:"user_id" gorm:"not null;index"`
+	User     *User  `json:"-"`
+	Provider string `json:"provider" gorm:"not null;uniqueIndex:idx_user_identities_subject"`
+	// The provider's ID for the account.
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_user_identities_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+}
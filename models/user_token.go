@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// What a UserToken is for.
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken is a one-time token emailed to a user to prove they own their
+// address, when verifying it or resetting their password. Only its SHA-256
+// hash is stored.
+type UserToken struct {
+	ID        uint       `json:"id" gorm:"primary_key"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+	User      *User      `json:"-"`
+	Purpose   string     `json:"purpose" gorm:"not null"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Usable reports whether the token can be used at t.
+func (t *UserToken) Usable(at time.Time) bool {
+	return t.UsedAt == nil && at.Before(t.ExpiresAt)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Webhook is a URL that catalog events are POSTed to.
+type Webhook struct {
+	ID  uint   `json:"id" gorm:"primary_key"`
+	URL string `json:"url" gorm:"not null"`
+	// Events are the event types delivered to the hook; empty means all.
+	Events StringList `json:"events" gorm:"type:text"`
+	// Secret is the HMAC key deliveries are signed with. It is only shown
+	// when the hook is registered.
+	Secret    string    `json:"-" gorm:"not null" audit:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Subscribed reports whether events of the given type are delivered to w.
+func (w *Webhook) Subscribed(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records one attempt at delivering an event to a webhook.
+type WebhookDelivery struct {
+	ID        uint   `json:"id" gorm:"primary_key"`
+	WebhookID uint   `json:"webhook_id" gorm:"index"`
+	EventID   string `json:"event_id" gorm:"index"`
+	Event     string `json:"event"`
+	Payload   string `json:"payload" gorm:"type:text"`
+	Attempt   int    `json:"attempt"`
+	// StatusCode is 0 when no response was received; Error then says why.
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error,omitempty"`
+	Succeeded  bool      `json:"succeeded"`
+	DurationMS int64     `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// StringList is stored as a JSON array in a text column.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(l)
+	return string(b), err
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return errors.New("StringList: unsupported column type")
+	}
+	return json.Unmarshal(raw, l)
+}
@@ -0,0 +1,85 @@
+package money
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+)
+
+// Store remembers rates: Fetch returns the rates stored for base, or calls
+// load and stores its result. Failed loads are not stored.
+type Store interface {
+	Fetch(ctx context.Context, base string, load func() (Rates, error)) (Rates, error)
+}
+
+type cached struct {
+	provider Provider
+	store    Store
+}
+
+// Cached serves the rates of each base from store; reference rates change
+// once a day, and converting a page of books would otherwise ask for them
+// over and over.
+func Cached(provider Provider, store Store) Provider {
+	return &cached{provider: provider, store: store}
+}
+
+func (c *cached) Rates(ctx context.Context, base string) (Rates, error) {
+	return c.store.Fetch(ctx, base, func() (Rates, error) {
+		return c.provider.Rates(ctx, base)
+	})
+}
+
+type redisStore struct {
+	cache *cache.Cache
+}
+
+// NewRedisStore shares the rates with every instance through c.
+func NewRedisStore(c *cache.Cache) Store {
+	return &redisStore{cache: c}
+}
+
+func (s *redisStore) Fetch(ctx context.Context, base string, load func() (Rates, error)) (Rates, error) {
+	return cache.Fetch(ctx, s.cache, base, load)
+}
+
+type memoryEntry struct {
+	rates   Rates
+	expires time.Time
+}
+
+// MemoryStore keeps rates in process for ttl. There are only so many
+// currencies, so entries are never evicted, only replaced.
+type MemoryStore struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{ttl: ttl, entries: map[string]memoryEntry{}}
+}
+
+func (s *MemoryStore) Fetch(_ context.Context, base string, load func() (Rates, error)) (Rates, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.entries[base]
+	s.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.rates, nil
+	}
+
+	rates, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[base] = memoryEntry{rates: rates, expires: now.Add(s.ttl)}
+	return rates, nil
+}
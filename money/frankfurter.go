@@ -0,0 +1,62 @@
+package money
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpclient"
+)
+
+// Frankfurter fetches the reference rates of the European Central Bank from
+// the Frankfurter API, or a server compatible with it.
+type Frankfurter struct {
+	http    *httpclient.Client
+	baseURL string
+}
+
+func NewFrankfurter(http *httpclient.Client, baseURL string) *Frankfurter {
+	return &Frankfurter{http: http, baseURL: baseURL}
+}
+
+type frankfurterRates struct {
+	Base  string `json:"base"`
+	Rates Rates  `json:"rates"`
+}
+
+func (p *Frankfurter) Rates(ctx context.Context, base string) (Rates, error) {
+	endpoint := p.baseURL + "/latest?" + url.Values{"from": {base}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "bookstore-api")
+
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	// Currencies the central bank doesn't publish a rate for.
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusUnprocessableEntity:
+		io.Copy(io.Discard, resp.Body)
+		return nil, ErrNoRate
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, resp.Body)
+		return nil, fmt.Errorf("GET %s: unexpected status %d", endpoint, resp.StatusCode)
+	}
+
+	// Decoded into decimals directly, so the rates never go through a
+	// float.
+	var body frankfurterRates
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("GET %s: %w", endpoint, err)
+	}
+	return body.Rates, nil
+}
@@ -0,0 +1,77 @@
+package money
+
+import (
+	"context"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+// ErrNoRate means the provider has no rate between the two currencies.
+var ErrNoRate = errors.New("no exchange rate between these currencies")
+
+// Rates maps currency codes to how much of each one unit of a base
+// currency buys.
+type Rates map[string]decimal.Decimal
+
+// Provider returns the rates from base to the currencies it knows.
+type Provider interface {
+	Rates(ctx context.Context, base string) (Rates, error)
+}
+
+// Converter converts amounts at the rates of a provider.
+type Converter struct {
+	provider Provider
+}
+
+func NewConverter(provider Provider) *Converter {
+	return &Converter{provider: provider}
+}
+
+// Convert returns amount, in from, in to, rounded half away from zero to
+// the minor units of to.
+func (c *Converter) Convert(ctx context.Context, amount decimal.Decimal, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return amount.Round(MinorUnits(to)), nil
+	}
+	rates, err := c.provider.Rates(ctx, from)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	rate, ok := rates[to]
+	if !ok {
+		return decimal.Decimal{}, ErrNoRate
+	}
+	return amount.Mul(rate).Round(MinorUnits(to)), nil
+}
+
+// Fixed rates are carried to this many decimal places when derived for
+// another base.
+const ratePrecision = 10
+
+type static struct {
+	base  string
+	rates Rates
+}
+
+// Static serves fixed rates, each how much of the currency one unit of base
+// buys. Rates from other currencies are derived through base.
+func Static(base string, rates Rates) Provider {
+	all := Rates{base: decimal.NewFromInt(1)}
+	for code, rate := range rates {
+		all[code] = rate
+	}
+	return &static{base: base, rates: all}
+}
+
+func (s *static) Rates(_ context.Context, base string) (Rates, error) {
+	via, ok := s.rates[base]
+	if !ok {
+		return nil, ErrNoRate
+	}
+	rates := make(Rates, len(s.rates))
+	for code, rate := range s.rates {
+		rates[code] = rate.DivRound(via, ratePrecision)
+	}
+	return rates, nil
+}
@@ -0,0 +1,65 @@
+// Package notify sends members the notifications of the lending desk, such
+// as overdue loan reminders, through the channels they chose.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/mailer"
+	"github.com/google/uuid"
+)
+
+// The channels notifications are sent through.
+const (
+	ChannelEmail   = "email"
+	ChannelWebhook = "webhook"
+	ChannelConsole = "console"
+)
+
+// ErrNoAddress means the member can't be reached through the channel, e.g.
+// has no webhook URL.
+var ErrNoAddress = errors.New("member has no address for the channel")
+
+// Notification is a message for a member.
+type Notification struct {
+	// What it is about, e.g. loan.overdue.
+	Kind string
+	// Plain text, for people.
+	Subject string
+	Body    string
+	// The details, for machines: sent as JSON by webhooks.
+	Data any
+	// Who it is for and where to reach them.
+	MemberID   uuid.UUID
+	Name       string
+	Email      string
+	WebhookURL string
+}
+
+// Notifier sends notifications through one channel.
+type Notifier interface {
+	Send(ctx context.Context, n Notification) error
+}
+
+// Email sends notifications as emails.
+type Email struct {
+	Mailer mailer.Mailer
+}
+
+func (e Email) Send(ctx context.Context, n Notification) error {
+	if n.Email == "" {
+		return ErrNoAddress
+	}
+	return e.Mailer.Send(ctx, mailer.Message{To: n.Email, Subject: n.Subject, Body: n.Body})
+}
+
+// Console writes notifications to the log, for development or for staff to
+// follow up by hand.
+type Console struct{}
+
+func (Console) Send(ctx context.Context, n Notification) error {
+	slog.InfoContext(ctx, "member notification", "kind", n.Kind, "member_id", n.MemberID, "member", n.Name, "subject", n.Subject, "body", n.Body)
+	return nil
+}
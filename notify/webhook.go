@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpclient"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/webhooks"
+	"github.com/google/uuid"
+)
+
+// Webhook POSTs notifications as JSON to the member's webhook URL, signed
+// like webhook deliveries when it has a secret. A notification is sent once
+// the hook answers 2xx.
+type Webhook struct {
+	client *httpclient.Client
+	secret string
+}
+
+func NewWebhook(client *httpclient.Client, secret string) *Webhook {
+	return &Webhook{client: client, secret: secret}
+}
+
+// webhookPayload is the body of webhook notifications.
+type webhookPayload struct {
+	Kind     string    `json:"kind"`
+	MemberID uuid.UUID `json:"member_id"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+	Data     any       `json:"data,omitempty"`
+	SentAt   time.Time `json:"sent_at"`
+}
+
+func (w *Webhook) Send(ctx context.Context, n Notification) error {
+	if n.WebhookURL == "" {
+		return ErrNoAddress
+	}
+	now := time.Now()
+	payload, err := json.Marshal(webhookPayload{Kind: n.Kind, MemberID: n.MemberID, Subject: n.Subject, Body: n.Body, Data: n.Data, SentAt: now.UTC()})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.WebhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bookstore-notifications/1.0")
+	req.Header.Set("X-Notification-Kind", n.Kind)
+	if w.secret != "" {
+		req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(w.secret, now, payload))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Read what the hook sent so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package oauth
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/oauth2"
+)
+
+// GitHub signs users in with their GitHub account, whose primary email
+// address must be verified.
+type GitHub struct {
+	config *oauth2.Config
+	apiURL string
+}
+
+func NewGitHub(config *oauth2.Config, apiURL string) *GitHub {
+	return &GitHub{config: config, apiURL: apiURL}
+}
+
+func (p *GitHub) AuthCodeURL(state string) string {
+	return p.config.AuthCodeURL(state)
+}
+
+func (p *GitHub) Profile(ctx context.Context, code string) (*Profile, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	client := p.config.Client(ctx, token)
+
+	var user struct {
+		ID int64 `json:"id"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user", &user); err != nil {
+		return nil, err
+	}
+	// The email on the profile may be hidden or unverified; the list of
+	// addresses says which is which.
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := getJSON(ctx, client, p.apiURL+"/user/emails", &emails); err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if email.Primary && email.Verified {
+			return &Profile{Subject: strconv.FormatInt(user.ID, 10), Email: email.Email}, nil
+		}
+	}
+	return nil, ErrNoVerifiedEmail
+}
@@ -0,0 +1,42 @@
+package oauth
+
+import (
+	"context"
+
+	"golang.org/x/oauth2"
+)
+
+// Google signs users in with their Google account, going by the OpenID
+// Connect userinfo endpoint.
+type Google struct {
+	config      *oauth2.Config
+	userInfoURL string
+}
+
+func NewGoogle(config *oauth2.Config, userInfoURL string) *Google {
+	return &Google{config: config, userInfoURL: userInfoURL}
+}
+
+func (p *Google) AuthCodeURL(state string) string {
+	return p.config.AuthCodeURL(state)
+}
+
+func (p *Google) Profile(ctx context.Context, code string) (*Profile, error) {
+	token, err := p.config.Exchange(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var info struct {
+		Sub           string `json:"sub"`
+		Email         string `json:"email"`
+		EmailVerified bool   `json:"email_verified"`
+	}
+	if err := getJSON(ctx, p.config.Client(ctx, token), p.userInfoURL, &info); err != nil {
+		return nil, err
+	}
+	if info.Email == "" || !info.EmailVerified {
+		return nil, ErrNoVerifiedEmail
+	}
+	return &Profile{Subject: info.Sub, Email: info.Email}, nil
+}
@@ -0,0 +1,82 @@
+// Package oauth signs users in with their account at an OAuth2 provider
+// (Google, GitHub).
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"golang.org/x/oauth2"
+)
+
+// ErrNoVerifiedEmail means the provider vouches for no email address of the
+// account, so it can't be matched to a local account.
+var ErrNoVerifiedEmail = errors.New("no verified email address for the account")
+
+// Profile is who the provider says the user is.
+type Profile struct {
+	// Subject is the provider's ID for the account, which, unlike the
+	// email address, doesn't change.
+	Subject string
+	Email   string
+}
+
+type Provider interface {
+	// AuthCodeURL is where to send the user to sign in; they come back to
+	// the callback with state and a code.
+	AuthCodeURL(state string) string
+	// Profile exchanges the code for the user's profile.
+	Profile(ctx context.Context, code string) (*Profile, error)
+}
+
+// New returns the providers cfg has credentials for, by name. Each
+// redirects back to <redirect base URL>/api/v1/auth/<name>/callback, which
+// must be registered with the provider.
+func New(cfg config.OAuthConfig) map[string]Provider {
+	callback := func(name string) string {
+		return strings.TrimSuffix(cfg.RedirectBaseURL, "/") + "/api/v1/auth/" + name + "/callback"
+	}
+	providers := map[string]Provider{}
+	if cfg.Google.ClientID != "" && cfg.Google.ClientSecret != "" {
+		providers["google"] = NewGoogle(&oauth2.Config{
+			ClientID:     cfg.Google.ClientID,
+			ClientSecret: cfg.Google.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: "https://accounts.google.com/o/oauth2/v2/auth", TokenURL: "https://oauth2.googleapis.com/token"},
+			RedirectURL:  callback("google"),
+			Scopes:       []string{"openid", "email"},
+		}, "https://openidconnect.googleapis.com/v1/userinfo")
+	}
+	if cfg.GitHub.ClientID != "" && cfg.GitHub.ClientSecret != "" {
+		providers["github"] = NewGitHub(&oauth2.Config{
+			ClientID:     cfg.GitHub.ClientID,
+			ClientSecret: cfg.GitHub.ClientSecret,
+			Endpoint:     oauth2.Endpoint{AuthURL: "https://github.com/login/oauth/authorize", TokenURL: "https://github.com/login/oauth/access_token"},
+			RedirectURL:  callback("github"),
+			Scopes:       []string{"read:user", "user:email"},
+		}, "https://api.github.com")
+	}
+	return providers
+}
+
+// getJSON decodes the response to an authenticated GET of url into v.
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
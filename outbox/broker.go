@@ -0,0 +1,30 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+)
+
+// Broker is a message broker events are published to. Publish must return
+// only once the broker has durably accepted the message; it returns an
+// error, and the event is retried, otherwise.
+type Broker interface {
+	// Publish sends payload on the subject; id is the event's ID, which
+	// brokers that can use it to drop duplicates.
+	Publish(ctx context.Context, subject, id string, payload []byte) error
+	Close() error
+}
+
+// NewBroker connects to the configured broker; it returns nil when none is
+// configured, in which case events are only published within the process.
+func NewBroker(cfg config.OutboxConfig) (Broker, error) {
+	switch cfg.Broker {
+	case "":
+		return nil, nil
+	case "nats":
+		return NewNATS(cfg.NATS)
+	}
+	return nil, fmt.Errorf("unknown outbox broker %q", cfg.Broker)
+}
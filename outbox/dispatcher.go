@@ -0,0 +1,160 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+// How long an instance holds the events it is publishing; if it dies
+// meanwhile, others pick them up after that.
+const lockDuration = 30 * time.Second
+
+// How often published events past the retention period are deleted.
+const cleanupInterval = time.Hour
+
+// Store is the part of repositories.OutboxRepository the dispatcher uses.
+type Store interface {
+	Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error)
+	Claim(ctx context.Context, event *models.OutboxEvent, now, until time.Time) (bool, error)
+	MarkPublished(ctx context.Context, event *models.OutboxEvent, at time.Time) error
+	MarkFailed(ctx context.Context, event *models.OutboxEvent, reason string) error
+	MarkDead(ctx context.Context, event *models.OutboxEvent, reason string, at time.Time) error
+	DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// Dispatcher polls the outbox and publishes each event to the broker, under
+// "<subject prefix>.<event type>", then to local (webhooks, event streams),
+// then marks it published. While the broker fails, events wait in the
+// outbox, in order, and are neither marked nor published locally. An event
+// whose payload doesn't decode is set aside for good instead.
+type Dispatcher struct {
+	store  Store
+	broker Broker
+	local  events.Publisher
+	cfg    config.OutboxConfig
+	done   chan struct{}
+	exited chan struct{}
+}
+
+// NewDispatcher starts polling; Stop it on shutdown. broker may be nil.
+func NewDispatcher(store Store, broker Broker, local events.Publisher, cfg config.OutboxConfig) *Dispatcher {
+	d := &Dispatcher{store: store, broker: broker, local: local, cfg: cfg, done: make(chan struct{}), exited: make(chan struct{})}
+	go d.run()
+	return d
+}
+
+// Stop waits, until ctx is done, for the batch being published, then closes
+// the broker connection.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.done)
+	var err error
+	select {
+	case <-d.exited:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if d.broker != nil {
+		if closeErr := d.broker.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) run() {
+	defer close(d.exited)
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	var cleaned time.Time
+	for {
+		select {
+		case <-d.done:
+			return
+		case <-ticker.C:
+		}
+
+		ctx := context.Background()
+		d.publishPending(ctx)
+		if d.cfg.Retention > 0 && time.Since(cleaned) >= cleanupInterval {
+			cleaned = time.Now()
+			if _, err := d.store.DeletePublishedBefore(ctx, cleaned.Add(-d.cfg.Retention)); err != nil {
+				slog.Error("deleting published outbox events failed", "error", err)
+			}
+		}
+	}
+}
+
+func (d *Dispatcher) publishPending(ctx context.Context) {
+	now := time.Now()
+	pending, err := d.store.Pending(ctx, now, d.cfg.BatchSize)
+	if err != nil {
+		slog.Error("loading outbox events failed", "error", err)
+		return
+	}
+
+	for i := range pending {
+		entry := &pending[i]
+		claimed, err := d.store.Claim(ctx, entry, now, now.Add(lockDuration))
+		if err != nil {
+			slog.Error("claiming outbox event failed", "event_id", entry.EventID, "error", err)
+			return
+		}
+		if !claimed {
+			continue
+		}
+		err = d.publish(ctx, entry)
+		var invalid *invalidPayloadError
+		if errors.As(err, &invalid) {
+			// No retry would decode it; the events after it go on.
+			slog.Error("outbox event can't be published", "event_id", entry.EventID, "error", err)
+			if err := d.store.MarkDead(ctx, entry, err.Error(), time.Now()); err != nil {
+				slog.Error("setting outbox event aside failed", "event_id", entry.EventID, "error", err)
+			}
+			continue
+		}
+		if err != nil {
+			slog.Warn("publishing outbox event failed", "event_id", entry.EventID, "attempts", entry.Attempts+1, "error", err)
+			if err := d.store.MarkFailed(ctx, entry, err.Error()); err != nil {
+				slog.Error("recording outbox failure failed", "event_id", entry.EventID, "error", err)
+			}
+			// The broker is likely down; later events would fail too.
+			return
+		}
+		if err := d.store.MarkPublished(ctx, entry, time.Now()); err != nil {
+			slog.Error("marking outbox event published failed", "event_id", entry.EventID, "error", err)
+		}
+	}
+}
+
+func (d *Dispatcher) publish(ctx context.Context, entry *models.OutboxEvent) error {
+	var event events.Event
+	if err := json.Unmarshal([]byte(entry.Payload), &event); err != nil {
+		return &invalidPayloadError{err: err}
+	}
+	if d.broker != nil {
+		subject := d.cfg.NATS.SubjectPrefix + "." + event.Type
+		if err := d.broker.Publish(ctx, subject, event.ID, []byte(entry.Payload)); err != nil {
+			return err
+		}
+	}
+	d.local.Publish(ctx, event)
+	return nil
+}
+
+// invalidPayloadError means the payload of an event isn't one, so that it
+// can never be published.
+type invalidPayloadError struct {
+	err error
+}
+
+func (e *invalidPayloadError) Error() string { return "invalid payload: " + e.err.Error() }
+
+func (e *invalidPayloadError) Unwrap() error { return e.err }
@@ -0,0 +1,52 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/nats-io/nats.go"
+)
+
+// NATS publishes to a JetStream stream, which acknowledges each message once
+// stored and drops those whose ID it has seen within its duplicate window.
+type NATS struct {
+	conn *nats.Conn
+	js   nats.JetStreamContext
+	cfg  config.NATSConfig
+}
+
+// NewNATS doesn't wait for the server to be up: the connection is retried in
+// the background, and events stay in the outbox meanwhile.
+func NewNATS(cfg config.NATSConfig) (*NATS, error) {
+	conn, err := nats.Connect(cfg.URL, nats.Name("bookstore-api"), nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	js, err := conn.JetStream()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATS{conn: conn, js: js, cfg: cfg}, nil
+}
+
+// Publish creates the stream, capturing every subject under the prefix, the
+// first time no stream takes the message.
+func (n *NATS) Publish(ctx context.Context, subject, id string, payload []byte) error {
+	_, err := n.js.Publish(subject, payload, nats.Context(ctx), nats.MsgId(id))
+	if !errors.Is(err, nats.ErrNoStreamResponse) {
+		return err
+	}
+	stream := &nats.StreamConfig{Name: n.cfg.Stream, Subjects: []string{n.cfg.SubjectPrefix + ".>"}}
+	if _, err := n.js.AddStream(stream, nats.Context(ctx)); err != nil && !errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+		return err
+	}
+	_, err = n.js.Publish(subject, payload, nats.Context(ctx), nats.MsgId(id))
+	return err
+}
+
+func (n *NATS) Close() error {
+	n.conn.Close()
+	return nil
+}
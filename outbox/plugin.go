@@ -0,0 +1,161 @@
+// Package outbox makes book events as durable as the changes they describe:
+// they are written to the outbox_events table in the same transaction as
+// the change, then published from there by a background dispatcher, which
+// retries until the broker has them. Delivery is at least once; receivers
+// drop duplicates by event ID.
+package outbox
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GORM drops the SET clause once an UPDATE has run, so the builder keeps a
+// copy under this key for the callback.
+const setKey = "outbox:set"
+
+// GormPlugin records an event for every book GORM creates, updates or
+// deletes, right before the statement's transaction commits. Books must be
+// written by value, not by condition, for the plugin to know which ones
+// changed.
+//
+// Every write to a book's row counts, so lending a copy or adding a review
+// publishes book.updated too; restoring a soft-deleted book publishes
+// book.created, and writes to books that stay soft-deleted publish nothing.
+type GormPlugin struct{}
+
+func (GormPlugin) Name() string { return "outbox" }
+
+func (GormPlugin) Initialize(db *gorm.DB) error {
+	build := db.ClauseBuilders["SET"]
+	db.ClauseBuilders["SET"] = func(c clause.Clause, builder clause.Builder) {
+		if stmt, ok := builder.(*gorm.Statement); ok {
+			stmt.DB.InstanceSet(setKey, c.Expression)
+		}
+		if build != nil {
+			build(c, builder)
+		} else {
+			c.Build(builder)
+		}
+	}
+
+	// After the associations are saved, so the events see the book's
+	// categories as committed.
+	const before = "gorm:commit_or_rollback_transaction"
+	cb := db.Callback()
+	if err := cb.Create().Before(before).Register("outbox:create", record(events.BookCreated)); err != nil {
+		return err
+	}
+	if err := cb.Update().Before(before).Register("outbox:update", record(events.BookUpdated)); err != nil {
+		return err
+	}
+	return cb.Delete().Before(before).Register("outbox:delete", record(events.BookDeleted))
+}
+
+func record(eventType string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		stmt := db.Statement
+		if db.Error != nil || db.RowsAffected == 0 || stmt.Schema == nil || stmt.Table != "books" {
+			return
+		}
+		ids := bookIDs(db)
+		if len(ids) == 0 {
+			return
+		}
+		if eventType == events.BookUpdated && restored(db) {
+			eventType = events.BookCreated
+		}
+
+		tx := db.Session(&gorm.Session{NewDB: true})
+		var books []models.Book
+		if err := tx.Unscoped().Preload("Categories").Where("id IN ?", ids).Find(&books).Error; err != nil {
+			db.AddError(err)
+			return
+		}
+		byID := make(map[uuid.UUID]*models.Book, len(books))
+		for i := range books {
+			byID[books[i].ID] = &books[i]
+		}
+
+		var entries []models.OutboxEvent
+		for _, id := range ids {
+			var event events.Event
+			book, found := byID[id]
+			switch {
+			case eventType == events.BookDeleted:
+				deleted := events.DeletedBook{ID: id}
+				if found {
+					for _, category := range book.Categories {
+						deleted.CategoryIDs = append(deleted.CategoryIDs, category.ID)
+					}
+				}
+				event = events.New(eventType, deleted)
+			case !found || book.DeletedAt.Valid:
+				continue
+			default:
+				event = events.New(eventType, book)
+			}
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				db.AddError(err)
+				return
+			}
+			entries = append(entries, models.OutboxEvent{EventID: event.ID, Type: event.Type, Payload: string(payload)})
+		}
+		if len(entries) > 0 {
+			if err := tx.Create(&entries).Error; err != nil {
+				db.AddError(err)
+			}
+		}
+	}
+}
+
+// bookIDs returns the primary keys of the books the statement wrote.
+func bookIDs(db *gorm.DB) []uuid.UUID {
+	stmt := db.Statement
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return nil
+	}
+
+	var ids []uuid.UUID
+	add := func(row reflect.Value) {
+		if value, zero := field.ValueOf(stmt.Context, row); !zero {
+			if id, ok := value.(uuid.UUID); ok {
+				ids = append(ids, id)
+			}
+		}
+	}
+	value := reflect.Indirect(stmt.ReflectValue)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			add(reflect.Indirect(value.Index(i)))
+		}
+	case reflect.Struct:
+		add(value)
+	}
+	return ids
+}
+
+// restored reports whether an UPDATE cleared deleted_at.
+func restored(db *gorm.DB) bool {
+	expr, _ := db.InstanceGet(setKey)
+	set, ok := expr.(clause.Set)
+	if !ok {
+		return false
+	}
+	for _, assignment := range set {
+		if assignment.Column.Name == "deleted_at" && assignment.Value == nil {
+			return true
+		}
+	}
+	return false
+}
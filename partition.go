@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/spf13/cobra"
+)
+
+// newPartitionCommand builds `partition`, which converts the audit log and
+// loans of a Postgres database into monthly partitions, creates those of
+// the coming months and prints every partition as JSON. Converting locks
+// each table while its rows are checked against the first partition's
+// bounds, so it is best run when the API is quiet; run again, it only
+// creates the partitions missing.
+func newPartitionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "partition",
+		Short: "Keep the audit log and loans in monthly partitions (Postgres)",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := bootstrap()
+			if err != nil {
+				return err
+			}
+			if cfg.Database.Driver != "postgres" {
+				return errors.New("tables can only be partitioned on postgres (DB_DRIVER)")
+			}
+			if err := app.CheckMigrations(models.DB, cfg.GinMode); err != nil {
+				return err
+			}
+
+			partitions := services.NewPartitionService(repositories.NewPartitionRepository(models.DB), cfg.Database)
+			if err := partitions.Partition(cmd.Context()); err != nil {
+				return err
+			}
+			all, err := partitions.List(cmd.Context())
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(cmd.OutOrStdout())
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(all)
+		},
+	}
+}
@@ -0,0 +1,65 @@
+package querystats
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Logger is a GORM logger recording every statement in the summary and
+// logging, through slog, failed statements and those at least as slow as
+// the threshold. Record-not-found errors are expected and not logged.
+type Logger struct {
+	level logger.LogLevel
+}
+
+func NewLogger() *Logger {
+	return &Logger{level: logger.Warn}
+}
+
+func (l *Logger) LogMode(level logger.LogLevel) logger.Interface {
+	return &Logger{level: level}
+}
+
+func (l *Logger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Info {
+		slog.InfoContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *Logger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Warn {
+		slog.WarnContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *Logger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= logger.Error {
+		slog.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+	}
+}
+
+func (l *Logger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	sql, rows := fc()
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	r := current()
+	slow := r.record(sql, elapsed, rows, failed)
+
+	switch {
+	case failed && l.level >= logger.Error:
+		slog.WarnContext(ctx, "query failed", "error", err, "query", r.fingerprint(sql), "duration_ms", elapsed.Milliseconds())
+	case slow && l.level >= logger.Warn:
+		slog.WarnContext(ctx, "slow query", "query", r.fingerprint(sql), "duration_ms", elapsed.Milliseconds(), "rows", rows, "threshold_ms", r.threshold.Milliseconds())
+	case l.level >= logger.Info:
+		slog.DebugContext(ctx, "query", "query", r.fingerprint(sql), "duration_ms", elapsed.Milliseconds(), "rows", rows)
+	}
+}
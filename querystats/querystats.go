@@ -0,0 +1,250 @@
+// Package querystats times the statements GORM runs, logs those slower
+// than a threshold and keeps a rolling summary of the last window, grouped
+// by query shape, for spotting the queries worth an index.
+//
+// Queries are grouped by fingerprint: their SQL with literals replaced by ?
+// and IN lists collapsed, so the same query with different arguments is
+// counted once. Fingerprints carry no argument values, which keeps emails
+// and the like out of the summary and the logs.
+package querystats
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+)
+
+// The window is split into this many buckets, the oldest dropped as a new
+// one starts, so the summary covers between window and window plus a
+// bucket.
+const buckets = 12
+
+// Caps on what is kept, so no stream of distinct queries can grow memory
+// without bound.
+const (
+	maxFingerprints = 1000
+	maxSlowQueries  = 50
+)
+
+// QueryStats aggregates the runs of one fingerprint.
+type QueryStats struct {
+	Query string `json:"query"`
+	Count int    `json:"count"`
+	// Runs that took at least the slow query threshold.
+	SlowCount int     `json:"slow_count"`
+	Errors    int     `json:"errors"`
+	TotalMS   float64 `json:"total_ms"`
+	AverageMS float64 `json:"average_ms"`
+	MaxMS     float64 `json:"max_ms"`
+	// Rows returned or affected, over every run.
+	Rows int64 `json:"rows"`
+}
+
+type SlowQuery struct {
+	Query      string    `json:"query"`
+	DurationMS float64   `json:"duration_ms"`
+	Rows       int64     `json:"rows"`
+	At         time.Time `json:"at"`
+}
+
+type Summary struct {
+	Window      string    `json:"window"`
+	ThresholdMS int64     `json:"threshold_ms"`
+	Since       time.Time `json:"since"`
+	// By total time spent, the costliest first.
+	Queries []QueryStats `json:"queries"`
+	// The latest slow queries, newest first.
+	Slow []SlowQuery `json:"slow"`
+}
+
+type bucket struct {
+	start   time.Time
+	queries map[string]*QueryStats
+}
+
+type recorder struct {
+	threshold time.Duration
+	window    time.Duration
+	// Matches the string literals GORM interpolates into logged SQL, which
+	// it quotes as the dialect does.
+	strings *regexp.Regexp
+
+	mu      sync.Mutex
+	buckets []bucket
+	slow    []SlowQuery
+}
+
+var (
+	mu  sync.RWMutex
+	std = newRecorder("sqlite", 200*time.Millisecond, 15*time.Minute)
+)
+
+func newRecorder(driver string, threshold, window time.Duration) *recorder {
+	literals := singleQuoted
+	if driver == "sqlite" {
+		literals = doubleQuoted
+	}
+	return &recorder{threshold: threshold, window: window, strings: literals}
+}
+
+// Setup applies the slow query threshold and summary window of cfg,
+// starting a new summary.
+func Setup(cfg config.DatabaseConfig) {
+	mu.Lock()
+	defer mu.Unlock()
+	std = newRecorder(cfg.Driver, cfg.SlowQueryThreshold, cfg.QueryStatsWindow)
+}
+
+func current() *recorder {
+	mu.RLock()
+	defer mu.RUnlock()
+	return std
+}
+
+// Snapshot returns the summary of the last window, with up to limit
+// queries.
+func Snapshot(limit int) Summary {
+	return current().snapshot(time.Now(), limit)
+}
+
+// Fingerprint returns sql, as GORM logs it, with its literals replaced, IN
+// lists and multi-row VALUES collapsed and whitespace normalized.
+func Fingerprint(sql string) string {
+	return current().fingerprint(sql)
+}
+
+// record counts a run of sql and reports whether it was slow.
+func (r *recorder) record(sql string, elapsed time.Duration, rows int64, failed bool) bool {
+	now := time.Now()
+	fingerprint := r.fingerprint(sql)
+	slow := r.threshold > 0 && elapsed >= r.threshold
+	ms := float64(elapsed.Microseconds()) / 1000
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(now)
+	stats, ok := b.queries[fingerprint]
+	if !ok {
+		if len(b.queries) >= maxFingerprints {
+			return slow
+		}
+		stats = &QueryStats{Query: fingerprint}
+		b.queries[fingerprint] = stats
+	}
+	stats.Count++
+	stats.TotalMS += ms
+	stats.MaxMS = max(stats.MaxMS, ms)
+	stats.Rows += max(rows, 0)
+	if failed {
+		stats.Errors++
+	}
+	if slow {
+		stats.SlowCount++
+		r.slow = append(r.slow, SlowQuery{Query: fingerprint, DurationMS: ms, Rows: rows, At: now})
+		if len(r.slow) > maxSlowQueries {
+			r.slow = r.slow[len(r.slow)-maxSlowQueries:]
+		}
+	}
+	return slow
+}
+
+// bucket returns the bucket now falls in, starting a new one and dropping
+// those out of the window as needed. r.mu must be held.
+func (r *recorder) bucket(now time.Time) *bucket {
+	span := r.window / buckets
+	if n := len(r.buckets); n > 0 && now.Sub(r.buckets[n-1].start) < span {
+		return &r.buckets[n-1]
+	}
+	r.buckets = append(r.expired(now), bucket{start: now, queries: map[string]*QueryStats{}})
+	return &r.buckets[len(r.buckets)-1]
+}
+
+// expired returns r.buckets without those that ended before the window.
+// r.mu must be held.
+func (r *recorder) expired(now time.Time) []bucket {
+	span := r.window / buckets
+	kept := r.buckets[:0]
+	for _, b := range r.buckets {
+		if now.Sub(b.start) < r.window+span {
+			kept = append(kept, b)
+		}
+	}
+	return kept
+}
+
+func (r *recorder) snapshot(now time.Time, limit int) Summary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buckets = r.expired(now)
+
+	summary := Summary{
+		Window:      r.window.String(),
+		ThresholdMS: r.threshold.Milliseconds(),
+		Since:       now.Add(-r.window),
+		Queries:     []QueryStats{},
+		Slow:        []SlowQuery{},
+	}
+	if len(r.buckets) > 0 {
+		summary.Since = r.buckets[0].start
+	}
+
+	merged := map[string]*QueryStats{}
+	for _, b := range r.buckets {
+		for fingerprint, stats := range b.queries {
+			total, ok := merged[fingerprint]
+			if !ok {
+				copied := *stats
+				merged[fingerprint] = &copied
+				continue
+			}
+			total.Count += stats.Count
+			total.SlowCount += stats.SlowCount
+			total.Errors += stats.Errors
+			total.TotalMS += stats.TotalMS
+			total.MaxMS = max(total.MaxMS, stats.MaxMS)
+			total.Rows += stats.Rows
+		}
+	}
+	for _, stats := range merged {
+		stats.AverageMS = stats.TotalMS / float64(stats.Count)
+		summary.Queries = append(summary.Queries, *stats)
+	}
+	sort.Slice(summary.Queries, func(i, j int) bool {
+		a, b := summary.Queries[i], summary.Queries[j]
+		if a.TotalMS != b.TotalMS {
+			return a.TotalMS > b.TotalMS
+		}
+		return a.Query < b.Query
+	})
+	if len(summary.Queries) > limit {
+		summary.Queries = summary.Queries[:limit]
+	}
+
+	for i := len(r.slow) - 1; i >= 0; i-- {
+		if r.slow[i].At.After(summary.Since) {
+			summary.Slow = append(summary.Slow, r.slow[i])
+		}
+	}
+	return summary
+}
+
+var (
+	singleQuoted  = regexp.MustCompile(`'(?:[^']|'')*'`)
+	doubleQuoted  = regexp.MustCompile(`"(?:[^"]|"")*"`)
+	numberLiteral = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	inList        = regexp.MustCompile(`(?i)\bIN \((?:\?, ?)*\?\)`)
+	valuesList    = regexp.MustCompile(`(?i)\bVALUES (\([^()]*\))(?:,\s*\([^()]*\))+`)
+	spaces        = regexp.MustCompile(`\s+`)
+)
+
+func (r *recorder) fingerprint(sql string) string {
+	sql = r.strings.ReplaceAllString(sql, "?")
+	sql = numberLiteral.ReplaceAllString(sql, "?")
+	sql = inList.ReplaceAllString(sql, "IN (...)")
+	sql = valuesList.ReplaceAllString(sql, "VALUES $1, ...")
+	return strings.TrimSpace(spaces.ReplaceAllString(sql, " "))
+}
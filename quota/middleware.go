@@ -0,0 +1,71 @@
+package quota
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Set by Middleware and ForClient.
+const (
+	meterKey   = "quota_meter"
+	countedKey = "quota_counted"
+)
+
+type metering struct {
+	meter *Meter
+	cfg   config.QuotaConfig
+}
+
+// Middleware lets the middlewares that authenticate clients count their
+// requests with ForClient; it must run before them. A nil meter counts
+// nothing.
+func Middleware(meter *Meter, cfg config.QuotaConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if meter != nil {
+			c.Set(meterKey, metering{meter: meter, cfg: cfg})
+		}
+		c.Next()
+	}
+}
+
+// ForClient counts the request against the monthly quota of subject, limit,
+// or the default quota of API keys or users if zero. Past the quota the
+// request is aborted with 429 and false returned. Only the first call of a
+// request counts it, so a request authenticated twice, with an API key and
+// a token, counts once.
+func ForClient(c *gin.Context, subject Subject, limit int) bool {
+	value, ok := c.Get(meterKey)
+	if !ok || c.GetBool(countedKey) {
+		return true
+	}
+	c.Set(countedKey, true)
+	metering := value.(metering)
+	if limit == 0 {
+		limit = metering.cfg.User
+		if subject.APIKeyID != 0 {
+			limit = metering.cfg.APIKey
+		}
+	}
+
+	result, err := metering.meter.Take(c.Request.Context(), subject, limit)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "reading request counts failed", "error", err)
+	}
+	if result.Limit > 0 {
+		c.Header("X-Quota-Limit", strconv.Itoa(result.Limit))
+		c.Header("X-Quota-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("X-Quota-Reset", strconv.FormatInt(result.Reset.Unix(), 10))
+	}
+	if !result.Allowed {
+		retryAfter := int(time.Until(result.Reset).Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		apierrors.Abort(c, apierrors.TooManyRequests("Monthly quota of %d requests used up; it resets on %s.").WithArgs(result.Limit, result.Reset.Format(time.DateOnly)))
+		return false
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+// Package quota counts the requests of authenticated clients per day and
+// caps those they make in a calendar month (UTC). Counts are kept in memory
+// and written to the database every flush interval, so enforcing a quota
+// doesn't take a write per request.
+package quota
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+// Subject is whom requests are counted for: a user's API key, or the user
+// when APIKeyID is 0.
+type Subject struct {
+	UserID   uint
+	APIKeyID uint
+}
+
+// Result is the outcome of counting a request.
+type Result struct {
+	Allowed bool
+	// The quota, 0 if uncapped, and what is left of it.
+	Limit     int
+	Remaining int
+	// When the month ends and the count starts over.
+	Reset time.Time
+}
+
+// Store is the part of repositories.UsageRepository the meter uses.
+type Store interface {
+	// Add adds the requests of each row to the counts of its subject and
+	// day.
+	Add(ctx context.Context, usage []models.DailyUsage) error
+	// Total returns the requests counted for the user or API key from day
+	// from to day to, exclusive.
+	Total(ctx context.Context, userID, apiKeyID uint, from, to string) (int64, error)
+}
+
+type pendingKey struct {
+	Subject
+	Day string
+}
+
+// Meter counts requests, flushing the counts to its store in the
+// background; Stop it on shutdown.
+type Meter struct {
+	store Store
+	cfg   config.QuotaConfig
+
+	mu    sync.Mutex
+	month string
+	// What the store had for this month, read once per subject between
+	// flushes, and what was counted since.
+	stored   map[Subject]int64
+	unstored map[Subject]int64
+	pending  map[pendingKey]int64
+
+	done   chan struct{}
+	exited chan struct{}
+}
+
+func NewMeter(store Store, cfg config.QuotaConfig) *Meter {
+	m := &Meter{
+		store:    store,
+		cfg:      cfg,
+		stored:   map[Subject]int64{},
+		unstored: map[Subject]int64{},
+		pending:  map[pendingKey]int64{},
+		done:     make(chan struct{}),
+		exited:   make(chan struct{}),
+	}
+	go m.run()
+	return m
+}
+
+// Take counts a request of subject against limit, a monthly quota, or
+// returns a Result that isn't Allowed, without counting it, if the quota
+// is used up. A limit of 0 counts the request without capping it. If the
+// store fails, the request is let through rather than taking the API down
+// with it.
+func (m *Meter) Take(ctx context.Context, subject Subject, limit int) (Result, error) {
+	now := time.Now().UTC()
+	month := now.Format("2006-01")
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	result := Result{Limit: limit, Reset: start.AddDate(0, 1, 0)}
+
+	m.mu.Lock()
+	if month != m.month {
+		m.month = month
+		m.stored, m.unstored = map[Subject]int64{}, map[Subject]int64{}
+	}
+	m.mu.Unlock()
+
+	var err error
+	if limit > 0 {
+		var used int64
+		used, err = m.used(ctx, subject, month, start)
+		if used >= int64(limit) {
+			return result, nil
+		}
+		result.Remaining = limit - int(used) - 1
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if month == m.month {
+		m.unstored[subject]++
+	}
+	m.pending[pendingKey{Subject: subject, Day: now.Format(time.DateOnly)}]++
+	result.Allowed = true
+	return result, err
+}
+
+// used returns how many requests subject made in the month starting at
+// start, as far as this instance knows.
+func (m *Meter) used(ctx context.Context, subject Subject, month string, start time.Time) (int64, error) {
+	m.mu.Lock()
+	stored, ok := m.stored[subject]
+	unstored := m.unstored[subject]
+	m.mu.Unlock()
+	if ok {
+		return stored + unstored, nil
+	}
+
+	stored, err := m.store.Total(ctx, subject.UserID, subject.APIKeyID, start.Format(time.DateOnly), start.AddDate(0, 1, 0).Format(time.DateOnly))
+	if err != nil {
+		return unstored, err
+	}
+	m.mu.Lock()
+	if month == m.month {
+		m.stored[subject] = stored
+	}
+	m.mu.Unlock()
+	return stored + unstored, nil
+}
+
+// Flush writes the counts taken since the last flush to the store, and
+// has the stored ones read again.
+func (m *Meter) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	pending := m.pending
+	m.pending = map[pendingKey]int64{}
+	m.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	usage := make([]models.DailyUsage, 0, len(pending))
+	for key, requests := range pending {
+		usage = append(usage, models.DailyUsage{UserID: key.UserID, APIKeyID: key.APIKeyID, Day: key.Day, Requests: requests})
+	}
+	err := m.store.Add(ctx, usage)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		// Kept for the next flush.
+		for key, requests := range pending {
+			m.pending[key] += requests
+		}
+		return err
+	}
+	for key, requests := range pending {
+		if key.Day[:len("2006-01")] == m.month {
+			m.unstored[key.Subject] -= requests
+		}
+	}
+	m.stored = map[Subject]int64{}
+	return nil
+}
+
+// Stop flushes the counts a last time, giving up once ctx is done.
+func (m *Meter) Stop(ctx context.Context) error {
+	close(m.done)
+	select {
+	case <-m.exited:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return m.Flush(ctx)
+}
+
+func (m *Meter) run() {
+	defer close(m.exited)
+	ticker := time.NewTicker(m.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case <-ticker.C:
+		}
+		if err := m.Flush(context.Background()); err != nil {
+			slog.Error("saving request counts failed", "error", err)
+		}
+	}
+}
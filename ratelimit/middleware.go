@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHeader identifies a client independently of its address; requests
+// without it are limited per IP.
+const APIKeyHeader = "X-API-Key"
+
+// Set by ForClient.
+const (
+	clientKeyKey   = "ratelimit_client"
+	clientLimitKey = "ratelimit_limit"
+)
+
+// ForClient makes the request count against the bucket under key, with
+// limit, rather than the caller's default one; a zero limit keeps the
+// default. Middlewares that identify clients, such as API key
+// authentication, call it so each client gets its own bucket and limit.
+// It must run before Middleware.
+func ForClient(c *gin.Context, key string, limit Limit) {
+	c.Set(clientKeyKey, key)
+	if limit.Rate > 0 && limit.Burst > 0 {
+		c.Set(clientLimitKey, limit)
+	}
+}
+
+// Middleware takes one token per request from the caller's bucket and
+// rejects the request with 429 and Retry-After when the bucket is empty.
+// If the store fails the request is let through rather than taking the API
+// down with it. While the default limit has a zero Rate, no request is
+// limited, whatever ForClient said.
+func Middleware(store Store, defaultLimit *DefaultLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := defaultLimit.Get()
+		if limit.Rate <= 0 {
+			c.Next()
+			return
+		}
+		if override, ok := c.Get(clientLimitKey); ok {
+			limit = override.(Limit)
+		}
+		if take(c, store, clientKey(c), limit) {
+			c.Next()
+		}
+	}
+}
+
+// PerAddress is Middleware for the caller's IP address, whoever the caller
+// turns out to be, with its own limit. It runs before the middlewares that
+// authenticate callers, so that requests failing authentication, such as
+// those guessing API keys, are limited too.
+func PerAddress(store Store, limit *DefaultLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit := limit.Get()
+		if limit.Rate <= 0 || take(c, store, "address:"+c.ClientIP(), limit) {
+			c.Next()
+		}
+	}
+}
+
+// take takes a token from the bucket under key and sets the rate limit
+// headers. If there was none it rejects the request and returns false.
+func take(c *gin.Context, store Store, key string, limit Limit) bool {
+	result, err := store.Take(c.Request.Context(), key, limit)
+	if err != nil {
+		slog.WarnContext(c.Request.Context(), "rate limiter unavailable", "error", err)
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(limit.Burst))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	if !result.Allowed {
+		retryAfter := int(math.Ceil(result.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		apierrors.Abort(c, apierrors.TooManyRequests("Rate limit exceeded, retry in %ds.").WithArgs(retryAfter))
+		return false
+	}
+	return true
+}
+
+func clientKey(c *gin.Context) string {
+	if key := c.GetString(clientKeyKey); key != "" {
+		return key
+	}
+	if key := c.GetHeader(APIKeyHeader); key != "" {
+		// Hashed so the secret itself never ends up in Redis.
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:16])
+	}
+	return "ip:" + c.ClientIP()
+}
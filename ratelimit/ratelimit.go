@@ -0,0 +1,71 @@
+// Package ratelimit throttles clients with a token bucket per client key.
+package ratelimit
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Limit describes a bucket: it refills at Rate tokens per second and holds
+// at most Burst tokens. Every request takes one.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// DefaultLimit is the limit of clients that don't have one of their own,
+// which may change while requests are served.
+type DefaultLimit struct {
+	limit atomic.Pointer[Limit]
+}
+
+func NewDefaultLimit(limit Limit) *DefaultLimit {
+	d := &DefaultLimit{}
+	d.Set(limit)
+	return d
+}
+
+func (d *DefaultLimit) Get() Limit {
+	return *d.limit.Load()
+}
+
+// Set changes the limit; a zero Rate turns rate limiting off.
+func (d *DefaultLimit) Set(limit Limit) {
+	d.limit.Store(&limit)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is how long until a token is available; zero when Allowed.
+	RetryAfter time.Duration
+}
+
+// Store holds the buckets. MemoryStore keeps them per process; RedisStore
+// shares them between instances.
+type Store interface {
+	// Take takes a token from the bucket under key, which has limit.
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// refill returns the bucket level after elapsed time, capped at Burst.
+func (l Limit) refill(tokens float64, elapsed time.Duration) float64 {
+	tokens += elapsed.Seconds() * l.Rate
+	if tokens > float64(l.Burst) {
+		tokens = float64(l.Burst)
+	}
+	return tokens
+}
+
+// take removes a token from a bucket holding tokens and returns the new
+// level together with the result.
+func (l Limit) take(tokens float64) (float64, Result) {
+	if tokens >= 1 {
+		tokens--
+		return tokens, Result{Allowed: true, Remaining: int(tokens)}
+	}
+	wait := time.Duration((1 - tokens) / l.Rate * float64(time.Second))
+	return tokens, Result{RetryAfter: wait}
+}
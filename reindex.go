@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/search"
+	"github.com/spf13/cobra"
+)
+
+// newReindexCommand builds `reindex`, which rebuilds the search index from
+// the books of every tenant and swaps it in for the current one, which
+// serves searches until then.
+func newReindexCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reindex",
+		Short: "Rebuild the search index from the database",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := bootstrap()
+			if err != nil {
+				return err
+			}
+			if cfg.Search.URL == "" {
+				return errors.New("no search index is configured (SEARCH_URL)")
+			}
+			if err := app.CheckMigrations(models.DB, cfg.GinMode); err != nil {
+				return err
+			}
+			return search.Reindex(cmd.Context(), search.NewClient(cfg.Search), repositories.NewBookRepository(models.DB))
+		},
+	}
+}
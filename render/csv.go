@@ -0,0 +1,118 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tabular"
+	"github.com/gin-gonic/gin"
+)
+
+type csvRender struct {
+	body   gin.H
+	header http.Header
+}
+
+func (r csvRender) Render(w http.ResponseWriter) error {
+	data, err := tree(r.body["data"])
+	if err != nil {
+		return err
+	}
+	if meta, ok := r.body["meta"]; ok {
+		meta, err := tree(meta)
+		if err != nil {
+			return err
+		}
+		metaHeaders(r.header, meta)
+	}
+	r.WriteContentType(w)
+
+	var rows []object
+	switch data := data.(type) {
+	case []value:
+		for _, item := range data {
+			rows = append(rows, flatten("", item, nil))
+		}
+	default:
+		rows = append(rows, flatten("", data, nil))
+	}
+
+	// Items may leave out different members, so the columns are all those
+	// any row has, in the order they are first seen.
+	var columns []string
+	seen := map[string]bool{}
+	for _, row := range rows {
+		for _, m := range row {
+			if !seen[m.key] {
+				seen[m.key] = true
+				columns = append(columns, m.key)
+			}
+		}
+	}
+
+	out := csv.NewWriter(w)
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = tabular.EscapeFormula(column)
+	}
+	if err := out.Write(header); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = cell(row.get(column))
+		}
+		if err := out.Write(record); err != nil {
+			return err
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
+
+func (r csvRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+}
+
+// flatten appends the scalar members of v to row, naming those of nested
+// objects after their path. A scalar v is a single column named "data".
+func flatten(prefix string, v value, row object) object {
+	obj, ok := v.(object)
+	if !ok {
+		if prefix == "" {
+			prefix = "data"
+		}
+		return append(row, member{key: prefix, value: v})
+	}
+	for _, m := range obj {
+		key := m.key
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		row = flatten(key, m.value, row)
+	}
+	return row
+}
+
+// cell formats a scalar as text; arrays and objects as JSON. Strings that
+// would be run as formulas are escaped; numbers are left as they are.
+func cell(v value) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return tabular.EscapeFormula(v)
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
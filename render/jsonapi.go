@@ -0,0 +1,292 @@
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IncludeError is returned for an ?include= naming something the response
+// has no relationship to.
+type IncludeError struct {
+	Path string
+}
+
+func (e *IncludeError) Error() string {
+	return fmt.Sprintf("cannot include %q", e.Path)
+}
+
+// JSONAPIVersion is the version of the specification documents follow.
+const JSONAPIVersion = "1.1"
+
+// document turns body into a JSON:API document. Objects with an id become
+// resource objects, typed with the name they were registered under with
+// Link, or else after their Go type, e.g. book_version. Their embedded
+// resources, lists of them and name_id members become relationships; the
+// embedded ones asked for with ?include=author,categories go in included
+// too. The rest are attributes.
+//
+// Bodies whose data aren't resources, such as the true deletes answer, have
+// it as the result member of meta instead. Meta and links are the
+// document's own.
+func document(c *gin.Context, body gin.H) (object, error) {
+	enveloped, err := envelope(c, body)
+	if err != nil {
+		return nil, err
+	}
+	primary := dataType(body["data"])
+	included, err := includes(c.Query("include"), primary)
+	if err != nil {
+		return nil, err
+	}
+
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+	typ := resourceType(primary)
+	doc := &jsonAPIDocument{include: included, seen: map[string]bool{}}
+	data := enveloped["data"].(value)
+
+	var meta object
+	if body["meta"] != nil {
+		tree, err := tree(body["meta"])
+		if err != nil {
+			return nil, err
+		}
+		meta, _ = tree.(object)
+	}
+
+	out := object{{key: "jsonapi", value: object{{key: "version", value: JSONAPIVersion}}}}
+	switch {
+	case typ != "" && isResourceList(data):
+		items := data.([]value)
+		resources := make([]value, len(items))
+		for _, item := range items {
+			doc.seen[typ+"/"+cell(item.(object).get("id"))] = true
+		}
+		for i, item := range items {
+			resources[i] = doc.resource(item.(object), typ, true)
+		}
+		out = append(out, member{key: "data", value: resources})
+	case typ != "" && isResourceObject(data):
+		doc.seen[typ+"/"+cell(data.(object).get("id"))] = true
+		out = append(out, member{key: "data", value: doc.resource(data.(object), typ, true)})
+	default:
+		meta = append(object{{key: "result", value: data}}, meta...)
+	}
+	if included != nil {
+		out = append(out, member{key: "included", value: doc.included})
+	}
+	if len(meta) > 0 {
+		out = append(out, member{key: "meta", value: meta})
+	}
+	return append(out, member{key: "links", value: enveloped["links"]}), nil
+}
+
+type jsonAPIDocument struct {
+	include  map[string]bool
+	included []value
+	// The type/id of every resource in the document already.
+	seen map[string]bool
+}
+
+// resource returns obj as a resource object of type typ. For primary data,
+// the related resources asked for are added to the included ones.
+// resourcesMu must be held.
+func (d *jsonAPIDocument) resource(obj object, typ string, primary bool) object {
+	var attributes, relationships object
+	var links object
+	relate := func(name string, linkage value) {
+		if relationships.get(name) == nil {
+			relationships = append(relationships, member{key: name, value: object{{key: "data", value: linkage}}})
+		}
+	}
+	embedded := func(name string, related resource, obj object) value {
+		id := cell(obj.get("id"))
+		if primary && d.include[name] && !d.seen[related.name+"/"+id] {
+			d.seen[related.name+"/"+id] = true
+			self := member{key: "links", value: object{{key: "self", value: related.path + "/" + id}}}
+			d.included = append(d.included, d.resource(append(obj, self), related.name, false))
+		}
+		return identifier(related.name, obj.get("id"))
+	}
+
+	for _, m := range obj {
+		if m.key == "id" {
+			continue
+		}
+		if m.key == "links" {
+			if self := m.value.(object).get("self"); self != nil {
+				links = object{{key: "self", value: self}}
+			}
+			continue
+		}
+
+		related, ok := resourcesByMember[m.key]
+		if !ok {
+			attributes = append(attributes, m)
+			continue
+		}
+		name := strings.TrimSuffix(m.key, "_id")
+		switch v := m.value.(type) {
+		case object:
+			if v.get("id") == nil {
+				attributes = append(attributes, m)
+				continue
+			}
+			relate(name, embedded(name, related, v))
+		case []value:
+			if !isResourceList(v) {
+				attributes = append(attributes, m)
+				continue
+			}
+			linkage := make([]value, len(v))
+			for i, item := range v {
+				linkage[i] = embedded(name, related, item.(object))
+			}
+			relate(name, linkage)
+		case nil:
+			relate(name, nil)
+		default:
+			if !strings.HasSuffix(m.key, "_id") {
+				attributes = append(attributes, m)
+				continue
+			}
+			if cell(v) == "0" {
+				relate(name, nil)
+				continue
+			}
+			relate(name, identifier(related.name, v))
+		}
+	}
+
+	res := object{{key: "type", value: typ}, {key: "id", value: cell(obj.get("id"))}}
+	if attributes == nil {
+		attributes = object{}
+	}
+	res = append(res, member{key: "attributes", value: attributes})
+	if len(relationships) > 0 {
+		res = append(res, member{key: "relationships", value: relationships})
+	}
+	if len(links) > 0 {
+		res = append(res, member{key: "links", value: links})
+	}
+	return res
+}
+
+func identifier(typ string, id value) object {
+	return object{{key: "type", value: typ}, {key: "id", value: cell(id)}}
+}
+
+func isResourceObject(v value) bool {
+	obj, ok := v.(object)
+	return ok && obj.get("id") != nil
+}
+
+// isResourceList reports whether v is a list of resource objects; an empty
+// list is one.
+func isResourceList(v value) bool {
+	items, ok := v.([]value)
+	if !ok {
+		return false
+	}
+	for _, item := range items {
+		if !isResourceObject(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// resourceType is the JSON:API type of the objects of Go type t. Only
+// structs make resources. resourcesMu must be held.
+func resourceType(t reflect.Type) string {
+	if r, ok := resourcesByType[t]; ok {
+		return r.name
+	}
+	if t == nil || t.Kind() != reflect.Struct || t.Name() == "" {
+		return ""
+	}
+	var name []rune
+	for i, r := range t.Name() {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				name = append(name, '_')
+			}
+			r = unicode.ToLower(r)
+		}
+		name = append(name, r)
+	}
+	return string(name)
+}
+
+// includes parses raw, a comma-separated ?include=, checking that objects
+// of type t have each relationship it names. It returns nil when nothing
+// is asked for.
+func includes(raw string, t reflect.Type) (map[string]bool, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	relationships := relationshipNames(t)
+	included := map[string]bool{}
+	for _, path := range strings.Split(raw, ",") {
+		path = strings.TrimSpace(path)
+		if !relationships[path] {
+			return nil, &IncludeError{Path: path}
+		}
+		included[path] = true
+	}
+	return included, nil
+}
+
+// relationshipNames returns the relationships objects of type t have, going
+// by the names of their JSON members.
+func relationshipNames(t reflect.Type) map[string]bool {
+	names := map[string]bool{}
+	if t == nil || t.Kind() != reflect.Struct {
+		return names
+	}
+	resourcesMu.RLock()
+	defer resourcesMu.RUnlock()
+	var walk func(t reflect.Type)
+	walk = func(t reflect.Type) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if field.Anonymous && name == "" && indirectType(field.Type).Kind() == reflect.Struct {
+				walk(indirectType(field.Type))
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			if _, ok := resourcesByMember[name]; ok && field.IsExported() {
+				names[strings.TrimSuffix(name, "_id")] = true
+			}
+		}
+	}
+	walk(t)
+	return names
+}
+
+type jsonAPIRender struct {
+	body interface{}
+}
+
+func (r jsonAPIRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	body, err := json.Marshal(r.body)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
+
+func (r jsonAPIRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", JSONAPI)
+}
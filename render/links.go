@@ -0,0 +1,206 @@
+package render
+
+import (
+	"encoding/json"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// A resource is a kind of object the API serves at path/<id>.
+type resource struct {
+	name string
+	path string
+}
+
+var (
+	resourcesMu sync.RWMutex
+	// By Go type, for the self link of the objects a response is made of.
+	resourcesByType = map[reflect.Type]resource{}
+	// By member name, for the links to related objects: an embedded object
+	// (author), a list of them (categories) or a reference by ID (author_id).
+	resourcesByMember = map[string]resource{}
+)
+
+// Link registers model, e.g. models.Book{}, as a resource served at path/<id>
+// whose embedded objects are named name. Responses made of such objects
+// then link each to itself, and any object with an embedded resource, a
+// list of them named after the last segment of path, or a name_id member
+// links to those too:
+//
+//	Link(models.Author{}, "author", "/api/v1/authors")
+//	// {"id": "…", "author_id": 7, "links": {"self": "…", "author": "/api/v1/authors/7"}}
+func Link(model interface{}, name, path string) {
+	r := resource{name: name, path: strings.TrimSuffix(path, "/")}
+	resourcesMu.Lock()
+	defer resourcesMu.Unlock()
+	resourcesByType[indirectType(reflect.TypeOf(model))] = r
+	resourcesByMember[name] = r
+	resourcesByMember[name+"_id"] = r
+	resourcesByMember[r.path[strings.LastIndex(r.path, "/")+1:]] = r
+}
+
+// envelope returns body with links added: the links member of the body,
+// with the URL of the response itself and, for paginated lists, of the
+// first, previous, next and last pages; and a links member in each object
+// of data. The page links also go in a Link header, for clients that don't
+// read the body, such as CSV ones.
+func envelope(c *gin.Context, body gin.H) (gin.H, error) {
+	data, err := tree(body["data"])
+	if err != nil {
+		return nil, err
+	}
+	resourcesMu.RLock()
+	self, isResource := resourcesByType[dataType(body["data"])]
+	switch items := data.(type) {
+	case []value:
+		for i, item := range items {
+			items[i] = withLinks(item, self, isResource)
+		}
+	default:
+		data = withLinks(data, self, isResource)
+	}
+	resourcesMu.RUnlock()
+
+	links := object{{key: "self", value: c.Request.URL.RequestURI()}}
+	if meta, ok := body["meta"]; ok {
+		meta, err := tree(meta)
+		if err != nil {
+			return nil, err
+		}
+		var header []string
+		for _, m := range pageLinks(c.Request.URL, meta) {
+			links = append(links, m)
+			header = append(header, "<"+m.value.(string)+`>; rel="`+m.key+`"`)
+		}
+		if len(header) > 0 {
+			c.Header("Link", strings.Join(header, ", "))
+		}
+	}
+
+	enveloped := gin.H{}
+	for key, v := range body {
+		enveloped[key] = v
+	}
+	enveloped["data"] = data
+	enveloped["links"] = links
+	return enveloped, nil
+}
+
+// withLinks adds a links member to v if it's an object with anything to
+// link to. resourcesMu must be held.
+func withLinks(v value, self resource, isResource bool) value {
+	obj, ok := v.(object)
+	if !ok || obj.get("links") != nil {
+		return v
+	}
+
+	var links object
+	if id := obj.get("id"); isResource && id != nil {
+		links = append(links, member{key: "self", value: self.path + "/" + cell(id)})
+	}
+	for _, m := range obj {
+		related, ok := resourcesByMember[m.key]
+		if !ok || links.get(related.name) != nil {
+			continue
+		}
+		switch v := m.value.(type) {
+		case object:
+			if id := v.get("id"); id != nil {
+				links = append(links, member{key: related.name, value: related.path + "/" + cell(id)})
+			}
+		case []value:
+			urls := make([]value, 0, len(v))
+			for _, item := range v {
+				if item, ok := item.(object); ok && item.get("id") != nil {
+					urls = append(urls, related.path+"/"+cell(item.get("id")))
+				}
+			}
+			if len(urls) > 0 {
+				links = append(links, member{key: m.key, value: urls})
+			}
+		case nil:
+		default:
+			// An ID of 0 stands for none, e.g. the api_key_id of usage
+			// counted for access tokens.
+			if strings.HasSuffix(m.key, "_id") && cell(v) != "0" {
+				links = append(links, member{key: related.name, value: related.path + "/" + cell(v)})
+			}
+		}
+	}
+	if len(links) == 0 {
+		return v
+	}
+	return append(obj, member{key: "links", value: links})
+}
+
+// pageLinks links to the neighbouring pages of a list, going by its meta:
+// page and total_pages for page-numbered lists, next_cursor for
+// cursor-paginated ones, and next_since for the changes feed.
+func pageLinks(u *url.URL, meta value) object {
+	obj, ok := meta.(object)
+	if !ok {
+		return nil
+	}
+	withParam := func(key, value string) string {
+		query := u.Query()
+		query.Set(key, value)
+		return u.Path + "?" + query.Encode()
+	}
+
+	if next, ok := obj.get("next_cursor").(string); ok {
+		return object{{key: "next", value: withParam("cursor", next)}}
+	}
+	if next, ok := obj.get("next_since").(string); ok {
+		return object{{key: "next", value: withParam("since", next)}}
+	}
+	page, pageOK := number(obj.get("page"))
+	pages, pagesOK := number(obj.get("total_pages"))
+	if !pageOK || !pagesOK {
+		return nil
+	}
+	links := object{{key: "first", value: withParam("page", "1")}}
+	if page > 1 {
+		links = append(links, member{key: "prev", value: withParam("page", strconv.Itoa(page-1))})
+	}
+	if page < pages {
+		links = append(links, member{key: "next", value: withParam("page", strconv.Itoa(page+1))})
+	}
+	if pages > 0 {
+		links = append(links, member{key: "last", value: withParam("page", strconv.Itoa(pages))})
+	}
+	return links
+}
+
+func number(v value) (int, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	i, err := strconv.Atoi(n.String())
+	return i, err == nil
+}
+
+// dataType is the type of the objects data is made of: its own, or that of
+// its elements for a slice.
+func dataType(data interface{}) reflect.Type {
+	if p, ok := data.(picked); ok {
+		return p.from
+	}
+	t := indirectType(reflect.TypeOf(data))
+	if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+		t = indirectType(t.Elem())
+	}
+	return t
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
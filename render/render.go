@@ -0,0 +1,156 @@
+// Package render writes response bodies in the format the client asks for
+// in its Accept header: JSON (the default), XML, CSV or JSON:API.
+// Controllers build the same body for every format; the others are derived
+// from its JSON encoding, so they carry the same member names and leave out
+// the same fields.
+package render
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	JSON = "application/json"
+	XML  = "application/xml"
+	CSV  = "text/csv"
+	// JSON:API documents, see document.
+	JSONAPI = "application/vnd.api+json"
+)
+
+// Offered, in order of preference when the client accepts several equally.
+var offered = []string{JSON, XML, CSV, JSONAPI}
+
+// Respond writes body, a gin.H with a "data" member and optionally a
+// "meta" one, with the status in the negotiated format. Clients accepting
+// none of the formats get JSON.
+//
+// Every body gets links on the way, see Link.
+//
+// XML wraps the body in a <response> element, array items in <item>
+// elements. CSV has one row per item of data (a single row for an object),
+// nested objects flattened into dotted columns such as author.name, and
+// arrays as JSON text; the members of meta become headers, e.g. page_size
+// becomes X-Page-Size.
+//
+// JSON:API documents are described at document.
+func Respond(c *gin.Context, status int, body gin.H) {
+	c.Writer.Header().Add("Vary", "Accept")
+	format := Negotiate(c.GetHeader("Accept"))
+	if format == JSONAPI {
+		doc, err := document(c, body)
+		if err != nil {
+			c.Error(err)
+			return
+		}
+		c.Render(status, jsonAPIRender{doc})
+		return
+	}
+
+	body, err := envelope(c, body)
+	if err != nil {
+		c.Error(err)
+		return
+	}
+	switch format {
+	case XML:
+		c.Render(status, xmlRender{body})
+	case CSV:
+		c.Render(status, csvRender{body: body, header: c.Writer.Header()})
+	default:
+		c.JSON(status, body)
+	}
+}
+
+// Negotiate picks the offered format the Accept header rates highest.
+func Negotiate(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return JSON
+	}
+	ranges := parseAccept(accept)
+	best, bestQ := JSON, 0.0
+	for _, offer := range offered {
+		if q := quality(ranges, offer); q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+type mediaRange struct {
+	typ, subtype string
+	q            float64
+}
+
+func parseAccept(header string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		typ, subtype, ok := strings.Cut(strings.ToLower(strings.TrimSpace(params[0])), "/")
+		if !ok {
+			continue
+		}
+		r := mediaRange{typ: typ, subtype: subtype, q: 1}
+		for _, param := range params[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+			if strings.EqualFold(key, "q") {
+				if q, err := strconv.ParseFloat(value, 64); err == nil {
+					r.q = q
+				}
+			}
+		}
+		ranges = append(ranges, r)
+	}
+	// The most specific range matching an offer sets its quality.
+	sort.SliceStable(ranges, func(i, j int) bool { return specificity(ranges[i]) > specificity(ranges[j]) })
+	return ranges
+}
+
+func specificity(r mediaRange) int {
+	switch {
+	case r.typ == "*":
+		return 0
+	case r.subtype == "*":
+		return 1
+	}
+	return 2
+}
+
+// quality returns how much the client wants offer, 0 meaning not at all.
+// text/xml counts as application/xml.
+func quality(ranges []mediaRange, offer string) float64 {
+	typ, subtype, _ := strings.Cut(offer, "/")
+	for _, r := range ranges {
+		matches := r.typ == "*" ||
+			(r.typ == typ && (r.subtype == "*" || r.subtype == subtype)) ||
+			(offer == XML && r.typ == "text" && r.subtype == "xml")
+		if matches {
+			return r.q
+		}
+	}
+	return 0
+}
+
+// The body of a CSV response has no room for these, so they go in headers.
+func metaHeaders(header http.Header, meta value) {
+	obj, ok := meta.(object)
+	if !ok {
+		return
+	}
+	for _, m := range obj {
+		if _, nested := m.value.(object); nested {
+			continue
+		}
+		words := strings.Split(m.key, "_")
+		for i, word := range words {
+			if word != "" {
+				words[i] = strings.ToUpper(word[:1]) + word[1:]
+			}
+		}
+		header.Set("X-"+strings.Join(words, "-"), cell(m.value))
+	}
+}
@@ -0,0 +1,151 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// A value is a decoded JSON value: an object, []value, string, json.Number,
+// bool or nil. Objects keep their members in order, so XML elements and CSV
+// columns come out in the order the JSON has them.
+type value interface{}
+
+type member struct {
+	key   string
+	value value
+}
+
+type object []member
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		val, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (o object) get(key string) value {
+	for _, m := range o {
+		if m.key == key {
+			return m.value
+		}
+	}
+	return nil
+}
+
+// tree returns v as its JSON encoding would decode.
+func tree(v interface{}) (value, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return readValue(dec)
+}
+
+func readValue(dec *json.Decoder) (value, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := object{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			val, err := readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			obj = append(obj, member{key: key.(string), value: val})
+		}
+		_, err = dec.Token()
+		return obj, err
+	case '[':
+		arr := []value{}
+		for dec.More() {
+			val, err := readValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err = dec.Token()
+		return arr, err
+	}
+	return nil, fmt.Errorf("unexpected %v", delim)
+}
+
+// Pick returns v, an object or an array of objects once encoded, with only
+// the named members, in their original order. Responses render it like any
+// other body, links included.
+func Pick(v interface{}, keys []string) (interface{}, error) {
+	root, err := tree(v)
+	if err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		wanted[key] = true
+	}
+	pick := func(v value) value {
+		obj, ok := v.(object)
+		if !ok {
+			return v
+		}
+		picked := object{}
+		for _, m := range obj {
+			if wanted[m.key] {
+				picked = append(picked, m)
+			}
+		}
+		return picked
+	}
+
+	from := dataType(v)
+	if items, ok := root.([]value); ok {
+		for i, item := range items {
+			items[i] = pick(item)
+		}
+		return picked{from: from, value: items}, nil
+	}
+	return picked{from: from, value: pick(root)}, nil
+}
+
+// picked is what Pick returns: what's left of a value, and the type it
+// came from.
+type picked struct {
+	from  reflect.Type
+	value value
+}
+
+func (p picked) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.value)
+}
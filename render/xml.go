@@ -0,0 +1,85 @@
+package render
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+type xmlRender struct {
+	body interface{}
+}
+
+func (r xmlRender) Render(w http.ResponseWriter) error {
+	r.WriteContentType(w)
+	root, err := tree(r.body)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write([]byte(xml.Header)); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	if err := writeElement(enc, "response", root); err != nil {
+		return err
+	}
+	return enc.Flush()
+}
+
+func (r xmlRender) WriteContentType(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+}
+
+// writeElement writes v as the element name: objects as child elements
+// named after their members, arrays as <item> children, null as an empty
+// element. Member names that aren't valid XML names are written as <item>
+// elements with a key attribute.
+func writeElement(enc *xml.Encoder, name string, v value) error {
+	start := xml.StartElement{Name: xml.Name{Local: name}}
+	if !validName(name) {
+		start = xml.StartElement{
+			Name: xml.Name{Local: "item"},
+			Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+		}
+	}
+	if err := enc.EncodeToken(start); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case object:
+		for _, m := range v {
+			if err := writeElement(enc, m.key, m.value); err != nil {
+				return err
+			}
+		}
+	case []value:
+		for _, item := range v {
+			if err := writeElement(enc, "item", item); err != nil {
+				return err
+			}
+		}
+	case nil:
+	default:
+		if err := enc.EncodeToken(xml.CharData(cell(v))); err != nil {
+			return err
+		}
+	}
+	return enc.EncodeToken(start.End())
+}
+
+// validName accepts the names JSON members usually have: letters, digits,
+// '_', '-' and '.', not starting with a digit, '-' or '.', nor with "xml".
+func validName(name string) bool {
+	if name == "" || len(name) >= 3 && (name[:3] == "xml" || name[:3] == "XML") {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package reporting
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/requestid"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/gin-gonic/gin"
+)
+
+// Recovery takes the place of gin.Recovery: it answers requests whose
+// handler panicked with a 500 problem, logging the panic with its stack.
+// Those panics, and the last error of the other 5xx responses, are sent
+// to reporter, if not nil. It must run before apierrors.Middleware, so the
+// response is known by the time it reports.
+func Recovery(reporter Reporter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+			// http.Server aborts the response quietly.
+			if recovered == http.ErrAbortHandler {
+				panic(recovered)
+			}
+
+			err, ok := recovered.(error)
+			if !ok {
+				err = fmt.Errorf("%v", recovered)
+			}
+			err = fmt.Errorf("panic: %w", err)
+			slog.ErrorContext(c.Request.Context(), "handler panicked", "error", err, "stack", string(debug.Stack()))
+			c.Error(err)
+			if !c.Writer.Written() {
+				apierrors.Abort(c, apierrors.Internal())
+			} else {
+				c.Abort()
+			}
+
+			if reporter != nil {
+				// The stack below this deferred call: the runtime's panic
+				// frames, then those that panicked.
+				pcs := make([]uintptr, 64)
+				event := newEvent(c, err)
+				event.Stack = pcs[:runtime.Callers(2, pcs)]
+				reporter.Report(c.Request.Context(), event)
+			}
+		}()
+
+		c.Next()
+
+		if reporter == nil || c.Writer.Status() < http.StatusInternalServerError || len(c.Errors) == 0 {
+			return
+		}
+		reporter.Report(c.Request.Context(), newEvent(c, c.Errors.Last().Err))
+	}
+}
+
+func newEvent(c *gin.Context, err error) Event {
+	ctx := c.Request.Context()
+	event := Event{Err: err, Request: c.Request, RequestID: requestid.FromContext(ctx), Status: c.Writer.Status()}
+	if identity, ok := auth.FromContext(ctx); ok {
+		event.UserID = identity.UserID
+	}
+	if id, ok := tenancy.FromContext(ctx); ok {
+		event.TenantID = id
+	}
+	return event
+}
@@ -0,0 +1,104 @@
+// Package reporting sends panics, and the errors 5xx responses are made of,
+// to an error tracker, with the request and user they happened for.
+package reporting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/ratelimit"
+)
+
+// Event is an error to report.
+type Event struct {
+	Err error
+	// The program counters of the goroutine that panicked, as
+	// runtime.Callers returns them, for panics; errors carry no stack of
+	// their own.
+	Stack []uintptr
+	// The request, without the headers that authenticate it, and what is
+	// known of who made it.
+	Request   *http.Request
+	RequestID string
+	UserID    uint
+	TenantID  uint
+	Status    int
+}
+
+// Reporter sends events to an error tracker. Reports are made while
+// requests are answered, so they must not wait for the tracker.
+type Reporter interface {
+	Report(ctx context.Context, event Event)
+	// Flush waits, until ctx is done, for the events reported to be sent.
+	Flush(ctx context.Context) error
+}
+
+// New returns the reporter cfg configures, or nil if none is.
+func New(cfg config.ErrorReportingConfig) (Reporter, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case "sentry":
+		sentry, err := NewSentry(cfg.Sentry)
+		if err != nil {
+			return nil, err
+		}
+		return sentry, nil
+	}
+	return nil, fmt.Errorf("unknown error reporting provider %q", cfg.Provider)
+}
+
+// The headers left out of reports, as they authenticate the client.
+var sensitiveHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"Proxy-Authorization",
+	ratelimit.APIKeyHeader,
+	auth.SignatureHeader,
+}
+
+// scrub returns a copy of r fit to be reported: its body was read already,
+// and the headers that authenticate it are left out.
+func scrub(r *http.Request) *http.Request {
+	scrubbed := r.Clone(context.Background())
+	scrubbed.Body = http.NoBody
+	for _, name := range sensitiveHeaders {
+		scrubbed.Header.Del(name)
+	}
+	return scrubbed
+}
+
+type tee []Reporter
+
+// Tee reports events to every one of reporters that isn't nil, or returns
+// nil if they all are.
+func Tee(reporters ...Reporter) Reporter {
+	var t tee
+	for _, reporter := range reporters {
+		if reporter != nil {
+			t = append(t, reporter)
+		}
+	}
+	if len(t) == 0 {
+		return nil
+	}
+	return t
+}
+
+func (t tee) Report(ctx context.Context, event Event) {
+	for _, reporter := range t {
+		reporter.Report(ctx, event)
+	}
+}
+
+func (t tee) Flush(ctx context.Context) error {
+	var errs []error
+	for _, reporter := range t {
+		errs = append(errs, reporter.Flush(ctx))
+	}
+	return errors.Join(errs...)
+}
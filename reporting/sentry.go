@@ -0,0 +1,94 @@
+package reporting
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/getsentry/sentry-go"
+)
+
+// Sentry reports events to Sentry, sending them in the background.
+type Sentry struct {
+	client *sentry.Client
+}
+
+func NewSentry(cfg config.SentryConfig) (*Sentry, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:         cfg.DSN,
+		Environment: cfg.Environment,
+		Release:     cfg.Release,
+		SampleRate:  cfg.SampleRate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Sentry{client: client}, nil
+}
+
+func (s *Sentry) Report(ctx context.Context, event Event) {
+	scope := sentry.NewScope()
+	if event.Request != nil {
+		scope.SetRequest(scrub(event.Request))
+	}
+	if event.UserID != 0 {
+		scope.SetUser(sentry.User{ID: strconv.FormatUint(uint64(event.UserID), 10)})
+	}
+	if event.RequestID != "" {
+		scope.SetTag("request_id", event.RequestID)
+	}
+	if event.TenantID != 0 {
+		scope.SetTag("tenant_id", strconv.FormatUint(uint64(event.TenantID), 10))
+	}
+	if event.Status != 0 {
+		scope.SetTag("status", strconv.Itoa(event.Status))
+	}
+
+	report := sentry.NewEvent()
+	report.Level = sentry.LevelError
+	if event.Stack != nil {
+		report.Level = sentry.LevelFatal
+	}
+	// The chain of wrapped errors, innermost first as Sentry expects, the
+	// outermost one with the stack.
+	for err := event.Err; err != nil; err = errors.Unwrap(err) {
+		report.Exception = append(report.Exception, sentry.Exception{Type: reflect.TypeOf(err).String(), Value: err.Error()})
+	}
+	slices.Reverse(report.Exception)
+	if len(report.Exception) > 0 && event.Stack != nil {
+		report.Exception[len(report.Exception)-1].Stacktrace = stacktrace(event.Stack)
+	}
+	sentry.NewHub(s.client, scope).CaptureEvent(report)
+}
+
+func (s *Sentry) Flush(ctx context.Context) error {
+	timeout := time.Second
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	if !s.client.Flush(timeout) {
+		return errors.New("sentry: events left unsent")
+	}
+	return nil
+}
+
+// stacktrace turns program counters into a Sentry stack trace, which lists
+// the outermost call first.
+func stacktrace(pcs []uintptr) *sentry.Stacktrace {
+	var frames []sentry.Frame
+	callers := runtime.CallersFrames(pcs)
+	for {
+		frame, more := callers.Next()
+		frames = append(frames, sentry.NewFrame(frame))
+		if !more {
+			break
+		}
+	}
+	slices.Reverse(frames)
+	return &sentry.Stacktrace{Frames: frames}
+}
@@ -0,0 +1,370 @@
+// Package reports lays out printable reports of the catalog as PDF.
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/go-pdf/fpdf"
+)
+
+const PDF = "pdf"
+
+const ContentType = "application/pdf"
+
+// Page layout, in millimetres.
+const (
+	margin      = 15.0
+	coverWidth  = 24.0
+	coverHeight = 36.0
+	// Space between the cover and the text, and between entries.
+	gutter = 5.0
+)
+
+// Descriptions are cut to about this many characters.
+const summaryLength = 320
+
+// The most rows each table of the summary page lists.
+const summaryRows = 12
+
+// Cover is a book's cover image, JPEG or PNG.
+type Cover struct {
+	Data        []byte
+	ContentType string
+}
+
+// Catalog is what a catalog report lists.
+type Catalog struct {
+	// Heads every page, e.g. "Catalog" or the name of a category.
+	Title       string
+	GeneratedAt time.Time
+	// With their Author, Publisher and Categories loaded, in the order
+	// they are listed.
+	Books []models.Book
+	// Cover returns the image printed next to the book, or nil for none.
+	Cover func(book *models.Book) (*Cover, error)
+}
+
+// WriteCatalog writes the catalog as an A4 document: a first page counting
+// the books by category, decade and author, then every book with its cover,
+// details and the start of its description.
+func WriteCatalog(w io.Writer, catalog Catalog) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(margin, margin+5, margin)
+	pdf.SetAutoPageBreak(true, margin+5)
+	pdf.SetTitle(catalog.Title, true)
+	pdf.SetCreationDate(catalog.GeneratedAt)
+	pdf.AliasNbPages("")
+
+	r := &renderer{pdf: pdf, tr: pdf.UnicodeTranslatorFromDescriptor(""), catalog: catalog}
+	pdf.SetHeaderFunc(r.header)
+	pdf.SetFooterFunc(r.footer)
+
+	r.summary()
+	if err := r.books(); err != nil {
+		return err
+	}
+	return pdf.Output(w)
+}
+
+type renderer struct {
+	pdf     *fpdf.Fpdf
+	tr      func(string) string
+	catalog Catalog
+}
+
+// text writes s, which the core fonts can only print in their code page.
+func (r *renderer) text(w, h float64, s, align string) {
+	r.pdf.CellFormat(w, h, r.tr(s), "", 0, align, false, 0, "")
+}
+
+func (r *renderer) header() {
+	r.pdf.SetY(margin - 3)
+	r.pdf.SetFont("Helvetica", "", 8)
+	r.pdf.SetTextColor(120, 120, 120)
+	r.text(0, 5, r.catalog.Title, "L")
+	r.pdf.SetX(margin)
+	r.text(0, 5, r.catalog.GeneratedAt.Format("2 January 2006"), "R")
+	r.pdf.Ln(8)
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+func (r *renderer) footer() {
+	r.pdf.SetY(-margin)
+	r.pdf.SetFont("Helvetica", "", 8)
+	r.pdf.SetTextColor(120, 120, 120)
+	r.text(0, 5, fmt.Sprintf("Page %d of {nb}", r.pdf.PageNo()), "C")
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+type count struct {
+	name  string
+	count int
+}
+
+// tally returns the counts most common first, ties by name.
+func tally(counts map[string]int) []count {
+	out := make([]count, 0, len(counts))
+	for name, n := range counts {
+		out = append(out, count{name, n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].name < out[j].name
+	})
+	return out
+}
+
+func (r *renderer) summary() {
+	pdf := r.pdf
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 22)
+	pdf.MultiCell(0, 10, r.tr(r.catalog.Title), "", "L", false)
+	pdf.SetFont("Helvetica", "", 10)
+	r.text(0, 6, "Generated on "+r.catalog.GeneratedAt.Format("2 January 2006 at 15:04 MST"), "L")
+	pdf.Ln(12)
+
+	authors, publishers, categories, decades := map[string]int{}, map[string]int{}, map[string]int{}, map[string]int{}
+	minYear, maxYear, covers := 0, 0, 0
+	for i := range r.catalog.Books {
+		book := &r.catalog.Books[i]
+		if book.Author != nil {
+			authors[book.Author.Name]++
+		}
+		if book.Publisher != nil {
+			publishers[book.Publisher.Name]++
+		}
+		for _, category := range book.Categories {
+			categories[category.Name]++
+		}
+		if book.Year != 0 {
+			decades[strconv.Itoa(book.Year/10*10)+"s"]++
+			if minYear == 0 || book.Year < minYear {
+				minYear = book.Year
+			}
+			maxYear = max(maxYear, book.Year)
+		}
+		if book.CoverThumbnailKey != "" {
+			covers++
+		}
+	}
+
+	years := "-"
+	if minYear != 0 {
+		years = fmt.Sprintf("%d to %d", minYear, maxYear)
+	}
+	figures := []count{
+		{"Books", len(r.catalog.Books)},
+		{"Authors", len(authors)},
+		{"Publishers", len(publishers)},
+		{"Categories", len(categories)},
+		{"Books with a cover", covers},
+	}
+	pdf.SetFont("Helvetica", "B", 12)
+	r.text(0, 7, "At a glance", "L")
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "", 10)
+	for _, figure := range figures {
+		r.text(60, 6, figure.name, "L")
+		r.text(30, 6, strconv.Itoa(figure.count), "R")
+		pdf.Ln(6)
+	}
+	r.text(60, 6, "Published", "L")
+	r.text(30, 6, years, "R")
+	pdf.Ln(12)
+
+	r.table("By category", tally(categories))
+	decadeRows := tally(decades)
+	sort.Slice(decadeRows, func(i, j int) bool { return decadeRows[i].name < decadeRows[j].name })
+	r.table("By decade", decadeRows)
+	r.table("Most listed authors", tally(authors))
+}
+
+// table lists the first summaryRows counts under a heading, and how many
+// more there are.
+func (r *renderer) table(heading string, rows []count) {
+	if len(rows) == 0 {
+		return
+	}
+	pdf := r.pdf
+	pdf.SetFont("Helvetica", "B", 12)
+	r.text(0, 7, heading, "L")
+	pdf.Ln(8)
+	pdf.SetFont("Helvetica", "", 10)
+	for i, row := range rows {
+		if i == summaryRows {
+			pdf.SetTextColor(120, 120, 120)
+			r.text(90, 6, fmt.Sprintf("and %d more", len(rows)-summaryRows), "L")
+			pdf.SetTextColor(0, 0, 0)
+			pdf.Ln(6)
+			break
+		}
+		r.text(90, 6, truncate(row.name, 50), "L")
+		r.text(20, 6, strconv.Itoa(row.count), "R")
+		pdf.Ln(6)
+	}
+	pdf.Ln(6)
+}
+
+func (r *renderer) books() error {
+	pdf := r.pdf
+	pdf.AddPage()
+	pageWidth, pageHeight := pdf.GetPageSize()
+	textX := margin + coverWidth + gutter
+	textWidth := pageWidth - margin - textX
+	bottom := pageHeight - margin - 5
+
+	if len(r.catalog.Books) == 0 {
+		pdf.SetFont("Helvetica", "I", 10)
+		r.text(0, 6, "No books match this report.", "L")
+		return nil
+	}
+
+	for i := range r.catalog.Books {
+		book := &r.catalog.Books[i]
+		lines := r.entryLines(book, textWidth)
+		height := max(coverHeight, lines.height())
+		if pdf.GetY()+height > bottom {
+			pdf.AddPage()
+		}
+		top := pdf.GetY()
+
+		if err := r.cover(book, margin, top); err != nil {
+			return err
+		}
+		pdf.SetXY(textX, top)
+		for _, line := range lines {
+			pdf.SetFont("Helvetica", line.style, line.size)
+			pdf.SetX(textX)
+			pdf.MultiCell(textWidth, line.lineHeight, line.text, "", "L", false)
+		}
+
+		pdf.SetY(top + height + gutter/2)
+		if i < len(r.catalog.Books)-1 {
+			pdf.SetDrawColor(220, 220, 220)
+			pdf.Line(margin, pdf.GetY(), pageWidth-margin, pdf.GetY())
+			pdf.SetY(pdf.GetY() + gutter/2)
+		}
+	}
+	return nil
+}
+
+type entryLine struct {
+	text       string
+	style      string
+	size       float64
+	lineHeight float64
+	// How many lines it wraps to.
+	wrapped int
+}
+
+type entry []entryLine
+
+func (e entry) height() float64 {
+	h := 0.0
+	for _, line := range e {
+		h += line.lineHeight * float64(line.wrapped)
+	}
+	return h
+}
+
+// entryLines lays out the text printed next to the cover: the title, who
+// and when, the categories and the summary.
+func (r *renderer) entryLines(book *models.Book, width float64) entry {
+	var byline []string
+	if book.Author != nil {
+		byline = append(byline, book.Author.Name)
+	}
+	if book.Publisher != nil {
+		byline = append(byline, book.Publisher.Name)
+	}
+	if book.Year != 0 {
+		byline = append(byline, strconv.Itoa(book.Year))
+	}
+	var details []string
+	if book.ISBN != "" {
+		details = append(details, "ISBN "+book.ISBN)
+	}
+	if len(book.Categories) > 0 {
+		names := make([]string, len(book.Categories))
+		for i, category := range book.Categories {
+			names[i] = category.Name
+		}
+		details = append(details, strings.Join(names, ", "))
+	}
+
+	var lines entry
+	add := func(s, style string, size, lineHeight float64) {
+		if s == "" {
+			return
+		}
+		s = r.tr(s)
+		r.pdf.SetFont("Helvetica", style, size)
+		lines = append(lines, entryLine{text: s, style: style, size: size, lineHeight: lineHeight, wrapped: len(r.pdf.SplitLines([]byte(s), width))})
+	}
+	add(book.Title, "B", 12, 6)
+	add(strings.Join(byline, " · "), "", 10, 5)
+	add(strings.Join(details, " · "), "I", 9, 5)
+	add(truncate(book.Description, summaryLength), "", 9, 4.5)
+	return lines
+}
+
+// cover prints the book's cover scaled into the cover box, or an empty box
+// when there is none or it can't be read.
+func (r *renderer) cover(book *models.Book, x, y float64) error {
+	pdf := r.pdf
+	var cover *Cover
+	if r.catalog.Cover != nil {
+		var err error
+		if cover, err = r.catalog.Cover(book); err != nil {
+			return err
+		}
+	}
+
+	if cover != nil {
+		imageType := "PNG"
+		if cover.ContentType == "image/jpeg" {
+			imageType = "JPG"
+		}
+		options := fpdf.ImageOptions{ImageType: imageType}
+		info := pdf.RegisterImageOptionsReader(book.ID.String(), options, bytes.NewReader(cover.Data))
+		if pdf.Ok() && info != nil {
+			w, h := info.Extent()
+			scale := min(coverWidth/w, coverHeight/h)
+			w, h = w*scale, h*scale
+			pdf.ImageOptions(book.ID.String(), x+(coverWidth-w)/2, y+(coverHeight-h)/2, w, h, false, options, 0, "")
+			return nil
+		}
+		// An image the PDF can't embed is left out, not the report.
+		pdf.ClearError()
+	}
+
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.SetFillColor(245, 245, 245)
+	pdf.Rect(x, y, coverWidth, coverHeight, "FD")
+	return nil
+}
+
+// truncate cuts s to at most n characters, at a word if there is one near,
+// marking the cut with an ellipsis.
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	runes := []rune(s)[:n]
+	cut := string(runes)
+	if i := strings.LastIndexByte(cut, ' '); i > len(cut)*3/4 {
+		cut = cut[:i]
+	}
+	return strings.TrimRight(cut, " ,.;:") + "…"
+}
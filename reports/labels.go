@@ -0,0 +1,120 @@
+package reports
+
+import (
+	"io"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/barcodes"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/go-pdf/fpdf"
+)
+
+// Sheet layout of the common A4 sheets of 24 labels, 3 by 8, in
+// millimetres.
+const (
+	labelColumns = 3
+	labelRows    = 8
+	labelWidth   = 63.5
+	labelHeight  = 33.9
+	// From one label to the next.
+	labelPitch = 66.0
+	sheetLeft  = 7.2
+	sheetTop   = 12.9
+	// Blank inside the edges of a label.
+	labelPadding = 2.5
+	// Where the code is drawn, under the title and author.
+	codeTop    = 9.5
+	codeHeight = 17.5
+	// Linear codes aren't printed wider than this per module, about the
+	// nominal size of an EAN-13.
+	maxBarWidth = 0.33
+)
+
+// LabelsPerSheet is how many labels a sheet holds.
+const LabelsPerSheet = labelColumns * labelRows
+
+// Label is a label for one copy of a book.
+type Label struct {
+	// With its Author loaded.
+	Book *models.Book
+	Code *barcodes.Code
+}
+
+// LabelSheet is what a sheet of labels prints.
+type LabelSheet struct {
+	GeneratedAt time.Time
+	// Skip leaves as many labels blank at the start of the first sheet, to
+	// print on a partly used one.
+	Skip   int
+	Labels []Label
+}
+
+// WriteLabels writes the labels on A4 sheets of 3 by 8, each with the book's
+// title and author, its code and the text the code encodes.
+func WriteLabels(w io.Writer, sheet LabelSheet) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(0, 0, 0)
+	pdf.SetAutoPageBreak(false, 0)
+	pdf.SetTitle("Labels", true)
+	pdf.SetCreationDate(sheet.GeneratedAt)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	if len(sheet.Labels) == 0 {
+		pdf.AddPage()
+	}
+	for i, label := range sheet.Labels {
+		slot := (sheet.Skip + i) % LabelsPerSheet
+		if i == 0 || slot == 0 {
+			pdf.AddPage()
+		}
+		x := sheetLeft + float64(slot%labelColumns)*labelPitch
+		y := sheetTop + float64(slot/labelColumns)*labelHeight
+		writeLabel(pdf, tr, label, x, y)
+	}
+	return pdf.Output(w)
+}
+
+func writeLabel(pdf *fpdf.Fpdf, tr func(string) string, label Label, x, y float64) {
+	width := labelWidth - 2*labelPadding
+	pdf.SetXY(x+labelPadding, y+labelPadding)
+	pdf.SetFont("Helvetica", "B", 8)
+	pdf.CellFormat(width, 3.5, fit(pdf, tr(label.Book.Title), width), "", 2, "L", false, 0, "")
+	if label.Book.Author != nil {
+		pdf.SetFont("Helvetica", "", 7)
+		pdf.CellFormat(width, 3, fit(pdf, tr(label.Book.Author.Name), width), "", 2, "L", false, 0, "")
+	}
+
+	code := label.Code
+	modulesWide, modulesTall := code.Size()
+	quiet := float64(code.QuietZone())
+	// Modules are square in matrices; linear codes stretch to codeHeight.
+	module := codeHeight / float64(modulesTall)
+	unitHeight := module
+	if code.Linear() {
+		module = min(maxBarWidth, width/(float64(modulesWide)+2*quiet))
+	}
+	left := x + (labelWidth-module*float64(modulesWide))/2
+	top := y + codeTop
+	pdf.SetFillColor(0, 0, 0)
+	for _, run := range code.Runs() {
+		pdf.Rect(left+module*float64(run.X), top+unitHeight*float64(run.Y), module*float64(run.Width), unitHeight*float64(run.Height), "F")
+	}
+
+	pdf.SetXY(x+labelPadding, top+codeHeight+0.5)
+	pdf.SetFont("Courier", "", 6)
+	pdf.CellFormat(width, 3, fit(pdf, code.Text, width), "", 0, "C", false, 0, "")
+}
+
+// fit cuts s to what fits in width with the current font, marking the cut
+// with an ellipsis.
+func fit(pdf *fpdf.Fpdf, s string, width float64) string {
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	// The ellipsis of the core fonts' code page.
+	const ellipsis = "\x85"
+	for len(s) > 0 && pdf.GetStringWidth(s+ellipsis) > width {
+		s = s[:len(s)-1]
+	}
+	return s + ellipsis
+}
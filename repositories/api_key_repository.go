@@ -0,0 +1,71 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.APIKey, int64, error)
+	FindByID(ctx context.Context, id uint, preloads ...string) (*models.APIKey, error)
+	// FindByHash returns the key with the given hash, with its user.
+	FindByHash(ctx context.Context, hash string) (*models.APIKey, error)
+	Create(ctx context.Context, key *models.APIKey) error
+	Revoke(ctx context.Context, key *models.APIKey, at time.Time) error
+	// Touch records that the key was used at t.
+	Touch(ctx context.Context, key *models.APIKey, t time.Time) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) List(ctx context.Context, offset, limit int) ([]models.APIKey, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.APIKey{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var keys []models.APIKey
+	if err := r.db.WithContext(ctx).Order("id").Offset(offset).Limit(limit).Find(&keys).Error; err != nil {
+		return nil, 0, err
+	}
+	return keys, total, nil
+}
+
+func (r *apiKeyRepository) FindByID(ctx context.Context, id uint, preloads ...string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Scopes(preloadScope(preloads)).First(&key, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindByHash(ctx context.Context, hash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.WithContext(ctx).Preload("User").Where("key_hash = ?", hash).First(&key).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return translate(r.db.WithContext(ctx).Create(key).Error)
+}
+
+func (r *apiKeyRepository) Revoke(ctx context.Context, key *models.APIKey, at time.Time) error {
+	return r.db.WithContext(ctx).Model(key).Update("revoked_at", at).Error
+}
+
+// Using a key isn't a change to it worth a new updated_at, hence
+// UpdateColumn.
+func (r *apiKeyRepository) Touch(ctx context.Context, key *models.APIKey, t time.Time) error {
+	return r.db.WithContext(ctx).Model(key).UpdateColumn("last_used_at", t).Error
+}
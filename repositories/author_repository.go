@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type AuthorRepository interface {
+	CRUDRepository[models.Author]
+	CountBooks(ctx context.Context, id uint) (int64, error)
+}
+
+type authorRepository struct {
+	CRUDRepository[models.Author]
+	db *gorm.DB
+}
+
+// NewAuthorRepository stores the authors of each tenant.
+func NewAuthorRepository(db *gorm.DB) AuthorRepository {
+	return &authorRepository{CRUDRepository: NewCRUDRepository[models.Author](db, "id"), db: db}
+}
+
+func (r *authorRepository) CountBooks(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	// Soft-deleted books still hold the foreign key, so they count too.
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).Where("author_id = ?", id).Count(&count).Error
+	return count, err
+}
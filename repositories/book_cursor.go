@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor means a cursor wasn't issued by ListAfter, or was issued
+// for another sort.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// BookCursor marks a position in a sorted list of books: the sort, and the
+// values of its columns, created_at and id included, in the last book of a
+// page. The values are kept rather than the book's ID so that the position
+// holds when that book is changed or deleted.
+type BookCursor struct {
+	Sort   []Sort   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// How the columns books can be ordered by are read from a book into a
+// cursor and back into query parameters.
+var bookKeysetColumns = map[string]struct {
+	value func(*models.Book) string
+	parse func(string) (interface{}, error)
+}{
+	"id": {
+		value: func(b *models.Book) string { return b.ID.String() },
+		parse: func(s string) (interface{}, error) { return uuid.Parse(s) },
+	},
+	"title": {
+		value: func(b *models.Book) string { return b.Title },
+		parse: func(s string) (interface{}, error) { return s, nil },
+	},
+	"author_id": {
+		value: func(b *models.Book) string { return strconv.FormatUint(uint64(b.AuthorID), 10) },
+		parse: func(s string) (interface{}, error) { return strconv.ParseUint(s, 10, 64) },
+	},
+	"year": {
+		value: func(b *models.Book) string { return strconv.Itoa(b.Year) },
+		parse: func(s string) (interface{}, error) { return strconv.Atoi(s) },
+	},
+	"created_at": {
+		value: func(b *models.Book) string { return b.CreatedAt.Format(time.RFC3339Nano) },
+		parse: func(s string) (interface{}, error) { return time.Parse(time.RFC3339Nano, s) },
+	},
+	"updated_at": {
+		value: func(b *models.Book) string { return b.UpdatedAt.Format(time.RFC3339Nano) },
+		parse: func(s string) (interface{}, error) { return time.Parse(time.RFC3339Nano, s) },
+	},
+}
+
+func (r *bookRepository) ListAfter(ctx context.Context, opts BookListOptions, after *BookCursor) ([]models.Book, *BookCursor, error) {
+	db := r.db.WithContext(ctx)
+	if opts.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	order := bookOrder(opts.Sort)
+	if after != nil {
+		condition, err := keysetCondition(order, after.Values)
+		if err != nil {
+			return nil, nil, err
+		}
+		db = db.Where(condition)
+	}
+
+	columns := opts.Columns
+	if len(columns) > 0 {
+		// The next cursor is made of the last book's order columns.
+		columns = append([]string{}, columns...)
+		for _, column := range order {
+			columns = append(columns, column.Column.Name)
+		}
+	}
+
+	var books []models.Book
+	err := db.Scopes(tenantScope(ctx), bookFilterScope(opts.Filter), columnsScope(columns, opts.Preloads), preloadScope(opts.Preloads)).
+		Clauses(clause.OrderBy{Columns: order}).
+		Limit(opts.Limit + 1).
+		Find(&books).Error
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(books) <= opts.Limit {
+		return books, nil, nil
+	}
+
+	books = books[:opts.Limit]
+	last := &books[len(books)-1]
+	next := &BookCursor{Sort: opts.Sort, Values: make([]string, len(order))}
+	for i, column := range order {
+		next.Values[i] = bookKeysetColumns[column.Column.Name].value(last)
+	}
+	return books, next, nil
+}
+
+// keysetCondition selects the rows after the given values of the order
+// columns: those greater (or less, when descending) in the first column,
+// or equal in it and after them in the next, and so on.
+func keysetCondition(order []clause.OrderByColumn, values []string) (clause.Expression, error) {
+	if len(values) != len(order) {
+		return nil, ErrInvalidCursor
+	}
+	parsed := make([]interface{}, len(values))
+	for i, column := range order {
+		keyset, ok := bookKeysetColumns[column.Column.Name]
+		if !ok {
+			return nil, ErrInvalidCursor
+		}
+		value, err := keyset.parse(values[i])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		parsed[i] = value
+	}
+
+	branches := make([]clause.Expression, len(order))
+	for i, column := range order {
+		terms := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, clause.Eq{Column: order[j].Column, Value: parsed[j]})
+		}
+		if column.Desc {
+			terms = append(terms, clause.Lt{Column: column.Column, Value: parsed[i]})
+		} else {
+			terms = append(terms, clause.Gt{Column: column.Column, Value: parsed[i]})
+		}
+		branches[i] = clause.And(terms...)
+	}
+	return clause.Or(branches...), nil
+}
@@ -0,0 +1,229 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/audit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *bookRepository) DuplicateCandidates(ctx context.Context, isbns []string, authorID uint) ([]models.Book, error) {
+	db := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Preload("Author")
+	if len(isbns) > 0 {
+		db = db.Where("isbn IN ? OR author_id = ?", isbns, authorID)
+	} else {
+		db = db.Where("author_id = ?", authorID)
+	}
+	var books []models.Book
+	err := db.Order("created_at, id").Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) ListIdentities(ctx context.Context) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Select("id", "title", "author_id", "isbn").
+		Order("created_at, id").
+		Find(&books).Error
+	return books, err
+}
+
+func (r *bookRepository) FindByIDs(ctx context.Context, ids []uuid.UUID, preloads ...string) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.WithContext(ctx).Scopes(tenantScope(ctx), preloadScope(preloads)).Where("id IN ?", ids).Find(&books).Error
+	return books, err
+}
+
+// The tables holding one row per book and something else, keyed by that
+// something else. Rows of the duplicate move to the target unless the
+// target has one with the same key already, in which case the target's
+// wins and the duplicate's is dropped. primaryKey names the columns of
+// their primary key, in order, for the audit entries of the moves.
+var bookLinks = []struct {
+	table, key string
+	primaryKey []string
+}{
+	{"book_categories", "category_id", []string{"book_id", "category_id"}},
+	{"book_tags", "tag_id", []string{"book_id", "tag_id"}},
+	{"series_volumes", "series_id", []string{"series_id", "book_id"}},
+	{"reviews", "user_id", []string{"id"}},
+	{"favorites", "user_id", []string{"user_id", "book_id"}},
+	{"reading_list_books", "reading_list_id", []string{"reading_list_id", "book_id"}},
+}
+
+// linkIDs returns the audit entity IDs of the rows linking each of books
+// to id, as GORM's plugin writes those of book_categories and book_tags.
+func linkIDs(books []string, id uint) []string {
+	ids := make([]string, len(books))
+	for i, book := range books {
+		ids[i] = book + "," + strconv.FormatUint(uint64(id), 10)
+	}
+	return ids
+}
+
+// recordLinkMerge writes the audit entries of merging the row of the
+// duplicate, which raw SQL moved to target, or dropped unless moved. A row
+// keyed by its book moves as a delete and a create.
+func recordLinkMerge(tx *gorm.DB, table string, primaryKey []string, row map[string]interface{}, target uuid.UUID, moved bool) error {
+	from, to := make([]string, len(primaryKey)), make([]string, len(primaryKey))
+	for i, column := range primaryKey {
+		from[i] = columnText(row[column])
+		to[i] = from[i]
+		if column == "book_id" {
+			to[i] = target.String()
+		}
+	}
+	id := strings.Join(from, ",")
+	switch {
+	case !moved:
+		return audit.Record(tx, models.AuditDelete, table, []string{id}, nil)
+	case !slices.Contains(primaryKey, "book_id"):
+		return audit.Record(tx, models.AuditUpdate, table, []string{id}, models.JSONObject{"book_id": target})
+	}
+	values := models.JSONObject{}
+	for column, value := range row {
+		if b, ok := value.([]byte); ok {
+			value = string(b)
+		}
+		if !slices.Contains(primaryKey, column) {
+			values[column] = value
+		}
+	}
+	if err := audit.Record(tx, models.AuditDelete, table, []string{id}, nil); err != nil {
+		return err
+	}
+	return audit.Record(tx, models.AuditCreate, table, []string{strings.Join(to, ",")}, values)
+}
+
+// columnText returns a column value scanned into a map as text.
+func columnText(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+func (r *bookRepository) Merge(ctx context.Context, target, duplicate *models.Book) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// In ID order, so two merges of the same books can't deadlock.
+		first, second := target, duplicate
+		if second.ID.String() < first.ID.String() {
+			first, second = second, first
+		}
+		for _, book := range []*models.Book{first, second} {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(book, "id = ?", book.ID).Error; err != nil {
+				return translate(err)
+			}
+		}
+		before := *target
+
+		// The holds of a line can't be merged into another's fairly, nor the
+		// copies set aside for them.
+		var held int64
+		if err := tx.Model(&models.Hold{}).Where("book_id = ? AND status IN ?", duplicate.ID, activeHolds).Count(&held).Error; err != nil {
+			return err
+		}
+		if held > 0 {
+			return ErrBookHeld
+		}
+
+		for _, link := range bookLinks {
+			var rows []map[string]interface{}
+			if err := tx.Table(link.table).Where("book_id = ?", duplicate.ID).Find(&rows).Error; err != nil {
+				return err
+			}
+			var kept []string
+			if err := tx.Table(link.table).Where("book_id = ?", target.ID).Pluck(link.key, &kept).Error; err != nil {
+				return err
+			}
+
+			// The derived table keeps MySQL from refusing a subquery on
+			// the table being updated.
+			update := fmt.Sprintf(
+				"UPDATE %[1]s SET book_id = ? WHERE book_id = ? AND %[2]s NOT IN (SELECT %[2]s FROM (SELECT %[2]s FROM %[1]s WHERE book_id = ?) existing)",
+				link.table, link.key)
+			if err := tx.Exec(update, target.ID, duplicate.ID, target.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE book_id = ?", link.table), duplicate.ID).Error; err != nil {
+				return err
+			}
+
+			for _, row := range rows {
+				if err := recordLinkMerge(tx, link.table, link.primaryKey, row, target.ID, !slices.Contains(kept, columnText(row[link.key]))); err != nil {
+					return err
+				}
+			}
+		}
+		if err := tx.Model(&models.Loan{}).Where("book_id = ?", duplicate.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+		// The duplicate's copies stay on the shelves of their branches, and
+		// its transfers tell how they got there.
+		var stocks []models.BranchStock
+		if err := tx.Where("book_id = ?", duplicate.ID).Find(&stocks).Error; err != nil {
+			return err
+		}
+		for _, stock := range stocks {
+			if err := addBranchStock(tx, target.ID, stock.BranchID, stock.Quantity, stock.AvailableCopies); err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("book_id = ?", duplicate.ID).Delete(&models.BranchStock{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.StockTransfer{}).Where("book_id = ?", duplicate.ID).Update("book_id", target.ID).Error; err != nil {
+			return err
+		}
+		// Recomputed by the next refresh-recommendations run.
+		err := tx.Where("book_id = ? OR similar_book_id = ?", duplicate.ID, duplicate.ID).Delete(&models.BookSimilarity{}).Error
+		if err != nil {
+			return err
+		}
+
+		// The copies of both are the library's, and the target keeps what
+		// it lacks of the duplicate's details.
+		changes := map[string]interface{}{
+			"quantity":         target.Quantity + duplicate.Quantity,
+			"available_copies": target.AvailableCopies + duplicate.AvailableCopies,
+			"version":          target.Version + 1,
+		}
+		if target.Description == "" && duplicate.Description != "" {
+			changes["description"] = duplicate.Description
+		}
+		if target.ISBN == "" && duplicate.ISBN != "" {
+			changes["isbn"] = duplicate.ISBN
+		}
+		if target.Year == 0 && duplicate.Year != 0 {
+			changes["year"] = duplicate.Year
+		}
+		if target.PublisherID == nil && duplicate.PublisherID != nil {
+			changes["publisher_id"] = duplicate.PublisherID
+		}
+		if target.CoverKey == "" && duplicate.CoverKey != "" {
+			changes["cover_key"] = duplicate.CoverKey
+			changes["cover_thumbnail_key"] = duplicate.CoverThumbnailKey
+		}
+		if err := tx.Model(target).Updates(changes).Error; err != nil {
+			return err
+		}
+		if err := refreshBookStats(tx, target); err != nil {
+			return err
+		}
+		if err := tx.First(target, "id = ?", target.ID).Error; err != nil {
+			return err
+		}
+		if err := recordUpdate(tx, &before, target); err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Select("Categories", "Tags").Delete(duplicate).Error
+	})
+}
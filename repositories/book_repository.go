@@ -0,0 +1,401 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookFilter struct {
+	AuthorID    uint
+	PublisherID uint
+	CategoryID  uint
+	// The name of a tag the books have.
+	Tag           string
+	Author        string
+	TitleContains string
+	YearGte       *int
+	YearLte       *int
+}
+
+// Sort is an ORDER BY entry. Column must already be validated against a
+// whitelist by the caller.
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+type BookListOptions struct {
+	Filter   BookFilter
+	Sort     []Sort
+	Offset   int
+	Limit    int
+	Preloads []string
+	// Columns limits the columns loaded, validated by the caller; all of
+	// them when empty.
+	Columns []string
+	// IncludeDeleted lists soft-deleted books alongside live ones.
+	IncludeDeleted bool
+}
+
+type BookRepository interface {
+	List(ctx context.Context, opts BookListOptions) ([]models.Book, int64, error)
+	// ListAfter lists up to opts.Limit books following after in the sort
+	// order, or from the start when after is nil, ignoring opts.Offset.
+	// Books inserted meanwhile don't shift the pages as they do with
+	// offsets. It returns the cursor of the next page, nil on the last one;
+	// nothing is counted.
+	ListAfter(ctx context.Context, opts BookListOptions, after *BookCursor) ([]models.Book, *BookCursor, error)
+	FindByID(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error)
+	FindBySlug(ctx context.Context, slug string, preloads ...string) (*models.Book, error)
+	// FindByIDColumns and FindBySlugColumns load only the given columns
+	// (validated by the caller), all of them when there are none.
+	FindByIDColumns(ctx context.Context, id uuid.UUID, columns []string, preloads ...string) (*models.Book, error)
+	FindBySlugColumns(ctx context.Context, slug string, columns []string, preloads ...string) (*models.Book, error)
+	Each(ctx context.Context, filter BookFilter, batchSize int, fn func([]models.Book) error, preloads ...string) error
+	FindByIDWithDeleted(ctx context.Context, id uuid.UUID) (*models.Book, error)
+	// DeletedBefore returns up to limit books soft-deleted before t, oldest
+	// first, leaving out those with copies still on loan.
+	DeletedBefore(ctx context.Context, t time.Time, limit int) ([]models.Book, error)
+	SlugsLike(ctx context.Context, base string) ([]string, error)
+	Create(ctx context.Context, book *models.Book) error
+	CreateMany(ctx context.Context, books []*models.Book) error
+	Update(ctx context.Context, book *models.Book, changes models.Book) error
+	UpdateFields(ctx context.Context, book *models.Book, fields map[string]interface{}) error
+	Delete(ctx context.Context, book *models.Book) error
+	DeleteMany(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error)
+	Restore(ctx context.Context, book *models.Book) error
+	// DeletePermanently deletes the book and its branch stock, cancelling
+	// the holds on it.
+	DeletePermanently(ctx context.Context, book *models.Book) error
+	AddCategories(ctx context.Context, book *models.Book, categories []models.Category) error
+	RemoveCategory(ctx context.Context, book *models.Book, category *models.Category) error
+	Search(ctx context.Context, query string, offset, limit int) ([]BookSearchHit, int64, error)
+	// DuplicateCandidates returns the live books with one of the given
+	// ISBNs or by the given author, with their authors.
+	DuplicateCandidates(ctx context.Context, isbns []string, authorID uint) ([]models.Book, error)
+	// ListIdentities returns every live book with only the columns that
+	// identify it loaded: ID, title, author and ISBN.
+	ListIdentities(ctx context.Context) ([]models.Book, error)
+	FindByIDs(ctx context.Context, ids []uuid.UUID, preloads ...string) ([]models.Book, error)
+	// Merge moves everything attached to duplicate, such as its loans,
+	// reviews, categories, copies and their branch stock and transfers, to
+	// target, then deletes duplicate permanently, in one transaction. It
+	// fails with ErrBookHeld while members are in line for duplicate.
+	Merge(ctx context.Context, target, duplicate *models.Book) error
+}
+
+type bookRepository struct {
+	db *gorm.DB
+}
+
+func NewBookRepository(db *gorm.DB) BookRepository {
+	return &bookRepository{db: db}
+}
+
+func (r *bookRepository) List(ctx context.Context, opts BookListOptions) ([]models.Book, int64, error) {
+	db := r.db.WithContext(ctx)
+	if opts.IncludeDeleted {
+		db = db.Unscoped()
+	}
+
+	filtered := db.Model(&models.Book{}).Scopes(tenantScope(ctx), bookFilterScope(opts.Filter))
+
+	var total int64
+	if err := filtered.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var books []models.Book
+	err := db.Scopes(tenantScope(ctx), bookFilterScope(opts.Filter), columnsScope(opts.Columns, opts.Preloads), preloadScope(opts.Preloads)).
+		Clauses(clause.OrderBy{Columns: bookOrder(opts.Sort)}).
+		Offset(opts.Offset).
+		Limit(opts.Limit).
+		Find(&books).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return books, total, nil
+}
+
+func bookOrder(sort []Sort) []clause.OrderByColumn {
+	order := make([]clause.OrderByColumn, 0, len(sort)+2)
+	for _, s := range sort {
+		order = append(order, clause.OrderByColumn{Column: clause.Column{Name: s.Column}, Desc: s.Desc})
+	}
+	// Unsorted lists come in creation order; the ID keeps paging stable when
+	// the requested columns contain duplicates.
+	return append(order,
+		clause.OrderByColumn{Column: clause.Column{Name: "created_at"}},
+		clause.OrderByColumn{Column: clause.Column{Name: "id"}})
+}
+
+func bookFilterScope(f BookFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.AuthorID != 0 {
+			db = db.Where("author_id = ?", f.AuthorID)
+		}
+		if f.PublisherID != 0 {
+			db = db.Where("publisher_id = ?", f.PublisherID)
+		}
+		if f.CategoryID != 0 {
+			db = db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+				Table("book_categories").Select("book_id").Where("category_id = ?", f.CategoryID))
+		}
+		if f.Tag != "" {
+			db = db.Where("id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+				Table("book_tags").Select("book_id").
+				Joins("JOIN tags ON tags.id = book_tags.tag_id").Where("tags.name = ?", f.Tag))
+		}
+		if f.Author != "" {
+			db = db.Where("author_id IN (?)", db.Session(&gorm.Session{NewDB: true}).
+				Model(&models.Author{}).Select("id").Where("name = ?", f.Author))
+		}
+		if f.TitleContains != "" {
+			db = db.Where("title LIKE ?", "%"+f.TitleContains+"%")
+		}
+		if f.YearGte != nil {
+			db = db.Where("year >= ?", *f.YearGte)
+		}
+		if f.YearLte != nil {
+			db = db.Where("year <= ?", *f.YearLte)
+		}
+		return db
+	}
+}
+
+// Preloads are association names (e.g. "Author") already validated by the caller.
+func preloadScope(preloads []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		for _, preload := range preloads {
+			db = db.Preload(preload)
+		}
+		return db
+	}
+}
+
+// columnsScope selects the columns, adding those the preloads need to find
+// the associations: the book's ID, and its author's and publisher's for
+// Author and Publisher.
+func columnsScope(columns, preloads []string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(columns) == 0 {
+			return db
+		}
+		selected := append([]string{"id"}, columns...)
+		for _, preload := range preloads {
+			switch preload {
+			case "Author":
+				selected = append(selected, "author_id")
+			case "Publisher":
+				selected = append(selected, "publisher_id")
+			}
+		}
+		return db.Select(selected)
+	}
+}
+
+func (r *bookRepository) FindByID(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error) {
+	return r.FindByIDColumns(ctx, id, nil, preloads...)
+}
+
+func (r *bookRepository) FindBySlug(ctx context.Context, slug string, preloads ...string) (*models.Book, error) {
+	return r.FindBySlugColumns(ctx, slug, nil, preloads...)
+}
+
+func (r *bookRepository) FindByIDColumns(ctx context.Context, id uuid.UUID, columns []string, preloads ...string) (*models.Book, error) {
+	var book models.Book
+	err := r.db.WithContext(ctx).Scopes(tenantScope(ctx), columnsScope(columns, preloads), preloadScope(preloads)).First(&book, "id = ?", id).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &book, nil
+}
+
+func (r *bookRepository) FindBySlugColumns(ctx context.Context, slug string, columns []string, preloads ...string) (*models.Book, error) {
+	var book models.Book
+	err := r.db.WithContext(ctx).Scopes(tenantScope(ctx), columnsScope(columns, preloads), preloadScope(preloads)).First(&book, "slug = ?", slug).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &book, nil
+}
+
+func (r *bookRepository) DeletedBefore(ctx context.Context, t time.Time, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.WithContext(ctx).Unscoped().Scopes(tenantScope(ctx)).
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", t).
+		Where("NOT EXISTS (SELECT 1 FROM loans WHERE loans.book_id = books.id AND loans.returned_at IS NULL)").
+		Order("deleted_at, id").
+		Limit(limit).
+		Find(&books).Error
+	return books, err
+}
+
+// SlugsLike returns the slugs, including those of soft-deleted books, that
+// are base itself or base followed by a "-suffix". Slugs are unique across
+// tenants, so this looks at every tenant's books.
+func (r *bookRepository) SlugsLike(ctx context.Context, base string) ([]string, error) {
+	var slugs []string
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).
+		Where("slug = ? OR slug LIKE ?", base, base+"-%").
+		Pluck("slug", &slugs).Error
+	return slugs, err
+}
+
+// Each calls fn with successive batches of the live books matching filter,
+// in ID order and with the given associations loaded, the author when none
+// are given, so the whole catalog never has to be in memory at once.
+func (r *bookRepository) Each(ctx context.Context, filter BookFilter, batchSize int, fn func([]models.Book) error, preloads ...string) error {
+	if len(preloads) == 0 {
+		preloads = []string{"Author"}
+	}
+	var batch []models.Book
+	return r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), bookFilterScope(filter), preloadScope(preloads)).
+		FindInBatches(&batch, batchSize, func(*gorm.DB, int) error { return fn(batch) }).
+		Error
+}
+
+func (r *bookRepository) FindByIDWithDeleted(ctx context.Context, id uuid.UUID) (*models.Book, error) {
+	var book models.Book
+	if err := r.db.WithContext(ctx).Unscoped().Scopes(tenantScope(ctx)).First(&book, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &book, nil
+}
+
+// Create and CreateMany add the books to the tenant ctx acts for.
+func (r *bookRepository) Create(ctx context.Context, book *models.Book) error {
+	book.TenantID = tenancy.ID(ctx)
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(book).Error; err != nil {
+			return err
+		}
+		return recordVersion(tx, book)
+	})
+}
+
+// CreateMany inserts all books in one transaction: either every row is
+// written or none is.
+func (r *bookRepository) CreateMany(ctx context.Context, books []*models.Book) error {
+	for _, book := range books {
+		book.TenantID = tenancy.ID(ctx)
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(books).Error; err != nil {
+			return err
+		}
+		for _, book := range books {
+			if err := recordVersion(tx, book); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Update applies the non-zero fields of changes to book. Update and
+// UpdateFields only apply while the book is still at the version it was
+// loaded with, bumping it, and return ErrStaleVersion otherwise. Both
+// reload book and record its new version.
+func (r *bookRepository) Update(ctx context.Context, book *models.Book, changes models.Book) error {
+	changes.Version = book.Version + 1
+	return r.updateVersioned(ctx, book, changes)
+}
+
+// UpdateFields writes exactly the given columns, including zero values.
+func (r *bookRepository) UpdateFields(ctx context.Context, book *models.Book, fields map[string]interface{}) error {
+	fields["version"] = gorm.Expr("version + 1")
+	return r.updateVersioned(ctx, book, fields)
+}
+
+func (r *bookRepository) updateVersioned(ctx context.Context, book *models.Book, changes interface{}) error {
+	before := *book
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkVersioned(tx.Model(book).Where("version = ?", book.Version).Updates(changes)); err != nil {
+			return err
+		}
+		if err := tx.First(book, "id = ?", book.ID).Error; err != nil {
+			return err
+		}
+		return recordUpdate(tx, &before, book)
+	})
+}
+
+func checkVersioned(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrStaleVersion
+	}
+	return nil
+}
+
+// Delete soft-deletes the book; its category links are kept so a restore
+// brings them back.
+func (r *bookRepository) Delete(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Delete(book).Error
+}
+
+// DeleteMany soft-deletes the live books among ids in one transaction and
+// returns the IDs it deleted.
+func (r *bookRepository) DeleteMany(ctx context.Context, ids []uuid.UUID) ([]uuid.UUID, error) {
+	var deleted []uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Book{}).Scopes(tenantScope(ctx)).Where("id IN ?", ids).Pluck("id", &deleted).Error; err != nil {
+			return err
+		}
+		if len(deleted) == 0 {
+			return nil
+		}
+		// Deleting the rows by value, rather than by condition, lets the
+		// audit log see which books went.
+		books := make([]models.Book, len(deleted))
+		for i, id := range deleted {
+			books[i].ID = id
+		}
+		return tx.Delete(&books).Error
+	})
+	return deleted, err
+}
+
+func (r *bookRepository) Restore(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Unscoped().Model(book).Update("deleted_at", nil).Error
+}
+
+// DeletePermanently removes the row, its category links and the copies
+// branches held.
+func (r *bookRepository) DeletePermanently(ctx context.Context, book *models.Book) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ?", book.ID).Delete(&models.BranchStock{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.Hold{}).Where("book_id = ? AND status IN ?", book.ID, activeHolds).Update("status", models.HoldCancelled).Error
+		if err != nil {
+			return err
+		}
+		return tx.Unscoped().Select("Categories", "Tags").Delete(book).Error
+	})
+}
+
+func (r *bookRepository) AddCategories(ctx context.Context, book *models.Book, categories []models.Category) error {
+	return r.db.WithContext(ctx).Model(book).Association("Categories").Append(categories)
+}
+
+// RemoveCategory also touches the book, as appending does, so that unlinking
+// a category is recorded as a change to it.
+func (r *bookRepository) RemoveCategory(ctx context.Context, book *models.Book, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(book).Association("Categories").Delete(category); err != nil {
+			return err
+		}
+		return tx.Model(book).Update("updated_at", time.Now()).Error
+	})
+}
@@ -0,0 +1,246 @@
+package repositories
+
+import (
+	"context"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Highlights wrap matched terms in these markers, and are otherwise
+// HTML-escaped, so that clients can render them as HTML whatever the book
+// text holds.
+const (
+	HighlightStart = "<mark>"
+	HighlightStop  = "</mark>"
+)
+
+// The matches are marked with these private use characters first, while
+// the text is escaped, then replaced by the markers.
+const (
+	matchStart = "\uE000"
+	matchStop  = "\uE001"
+)
+
+var matchMarkers = strings.NewReplacer(matchStart, HighlightStart, matchStop, HighlightStop)
+
+// markHighlights escapes text, whose matches are wrapped in matchStart and
+// matchStop, and marks the matches with the markers.
+func markHighlights(text string) string {
+	return matchMarkers.Replace(html.EscapeString(text))
+}
+
+// The LIKE fallback ANDs one condition per term, so cap how many it builds.
+const maxSearchTerms = 10
+
+// BookSearchHit is a book matching a search, with its relevance and the
+// searched fields with matched terms highlighted.
+type BookSearchHit struct {
+	Book       models.Book
+	Rank       float64
+	Highlights map[string]string
+}
+
+type searchRow struct {
+	ID                   uuid.UUID
+	Rank                 float64
+	TitleHighlight       string
+	DescriptionHighlight string
+	AuthorHighlight      string
+}
+
+// Search matches query against the title, description and author name of
+// live books, best matches first. Postgres uses full-text search (stemming,
+// "quoted phrases", OR, -exclusions); other drivers require every word to
+// appear in one of the fields and rank title and author hits above
+// description hits.
+func (r *bookRepository) Search(ctx context.Context, query string, offset, limit int) ([]BookSearchHit, int64, error) {
+	db := r.db.WithContext(ctx)
+
+	var (
+		rows  []searchRow
+		total int64
+		err   error
+	)
+	if db.Dialector.Name() == "postgres" {
+		rows, total, err = searchFullText(db, query, offset, limit)
+	} else {
+		rows, total, err = searchLike(db, query, offset, limit)
+	}
+	if err != nil || len(rows) == 0 {
+		return nil, total, err
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	var books []models.Book
+	if err := db.Preload("Author").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[uuid.UUID]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	hits := make([]BookSearchHit, 0, len(rows))
+	for _, row := range rows {
+		book, ok := byID[row.ID]
+		if !ok {
+			continue
+		}
+		hits = append(hits, BookSearchHit{
+			Book: book,
+			Rank: row.Rank,
+			Highlights: map[string]string{
+				"title":       row.TitleHighlight,
+				"description": row.DescriptionHighlight,
+				"author":      row.AuthorHighlight,
+			},
+		})
+	}
+	return hits, total, nil
+}
+
+const fullTextFrom = `
+FROM books
+LEFT JOIN authors ON authors.id = books.author_id
+CROSS JOIN websearch_to_tsquery('english', @query) AS query
+CROSS JOIN LATERAL (SELECT
+	setweight(to_tsvector('english', books.title), 'A') ||
+	setweight(to_tsvector('english', coalesce(authors.name, '')), 'A') ||
+	setweight(to_tsvector('english', coalesce(books.description, '')), 'B') AS document
+) AS d
+WHERE books.deleted_at IS NULL AND d.document @@ query
+AND (@all_tenants OR books.tenant_id = @tenant)`
+
+func searchFullText(db *gorm.DB, query string, offset, limit int) ([]searchRow, int64, error) {
+	args := map[string]interface{}{
+		"query":     query,
+		"offset":    offset,
+		"limit":     limit,
+		"headline":  "StartSel=" + matchStart + ", StopSel=" + matchStop + ", HighlightAll=true",
+		"fragments": "StartSel=" + matchStart + ", StopSel=" + matchStop + ", MaxFragments=2",
+	}
+	// The query is written out, so tenantScope can't apply to it.
+	tenantID, ok := tenancy.FromContext(db.Statement.Context)
+	args["tenant"], args["all_tenants"] = tenantID, !ok
+
+	var total int64
+	if err := db.Raw("SELECT count(*)"+fullTextFrom, args).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []searchRow
+	err := db.Raw(`SELECT books.id,
+	ts_rank(d.document, query) AS rank,
+	ts_headline('english', books.title, query, @headline) AS title_highlight,
+	ts_headline('english', coalesce(books.description, ''), query, @fragments) AS description_highlight,
+	ts_headline('english', coalesce(authors.name, ''), query, @headline) AS author_highlight`+
+		fullTextFrom+`
+ORDER BY rank DESC, books.id
+LIMIT @limit OFFSET @offset`, args).Scan(&rows).Error
+	for i := range rows {
+		rows[i].TitleHighlight = markHighlights(rows[i].TitleHighlight)
+		rows[i].DescriptionHighlight = markHighlights(rows[i].DescriptionHighlight)
+		rows[i].AuthorHighlight = markHighlights(rows[i].AuthorHighlight)
+	}
+	return rows, total, err
+}
+
+func searchLike(db *gorm.DB, query string, offset, limit int) ([]searchRow, int64, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	filtered := db.Table("books").
+		Joins("LEFT JOIN authors ON authors.id = books.author_id").
+		Scopes(tenantScope(db.Statement.Context)).
+		Where("books.deleted_at IS NULL")
+
+	var rank []string
+	var rankArgs []interface{}
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		filtered = filtered.Where(
+			`(LOWER(books.title) LIKE ? ESCAPE '\' OR LOWER(COALESCE(authors.name, '')) LIKE ? ESCAPE '\' OR LOWER(COALESCE(books.description, '')) LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern)
+		rank = append(rank, `(CASE WHEN LOWER(books.title) LIKE ? ESCAPE '\' THEN 3 ELSE 0 END)`,
+			`(CASE WHEN LOWER(COALESCE(authors.name, '')) LIKE ? ESCAPE '\' THEN 2 ELSE 0 END)`,
+			`(CASE WHEN LOWER(COALESCE(books.description, '')) LIKE ? ESCAPE '\' THEN 1 ELSE 0 END)`)
+		rankArgs = append(rankArgs, pattern, pattern, pattern)
+	}
+
+	var total int64
+	if err := filtered.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	type likeRow struct {
+		ID          uuid.UUID
+		Rank        float64
+		Title       string
+		Description string
+		Author      string
+	}
+	var matches []likeRow
+	err := filtered.
+		Select("books.id, ("+strings.Join(rank, " + ")+") AS rank, books.title, books.description, authors.name AS author", rankArgs...).
+		Order("rank DESC, books.id").
+		Offset(offset).
+		Limit(limit).
+		Scan(&matches).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	highlight := highlighter(terms)
+	rows := make([]searchRow, len(matches))
+	for i, m := range matches {
+		rows[i] = searchRow{
+			ID:                   m.ID,
+			Rank:                 m.Rank,
+			TitleHighlight:       highlight(m.Title),
+			DescriptionHighlight: highlight(m.Description),
+			AuthorHighlight:      highlight(m.Author),
+		}
+	}
+	return rows, total, nil
+}
+
+func searchTerms(query string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, term := range strings.Fields(strings.ToLower(query)) {
+		if seen[term] || len(terms) == maxSearchTerms {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func escapeLike(term string) string {
+	return likeEscaper.Replace(term)
+}
+
+func highlighter(terms []string) func(string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	re := regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+	return func(text string) string {
+		return markHighlights(re.ReplaceAllString(text, matchStart+"$0"+matchStop))
+	}
+}
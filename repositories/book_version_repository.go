@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Versions are written by the repositories changing books, in the same
+// transaction as the change, rather than through this repository.
+type BookVersionRepository interface {
+	// List returns the versions of the book, newest first.
+	List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.BookVersion, int64, error)
+	Find(ctx context.Context, bookID uuid.UUID, version uint) (*models.BookVersion, error)
+}
+
+type bookVersionRepository struct {
+	db *gorm.DB
+}
+
+func NewBookVersionRepository(db *gorm.DB) BookVersionRepository {
+	return &bookVersionRepository{db: db}
+}
+
+func (r *bookVersionRepository) List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.BookVersion, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.BookVersion{}).Where("book_id = ?", bookID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var versions []models.BookVersion
+	err := r.db.WithContext(ctx).Where("book_id = ?", bookID).Order("version DESC").Offset(offset).Limit(limit).Find(&versions).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return versions, total, nil
+}
+
+func (r *bookVersionRepository) Find(ctx context.Context, bookID uuid.UUID, version uint) (*models.BookVersion, error) {
+	var v models.BookVersion
+	if err := r.db.WithContext(ctx).First(&v, "book_id = ? AND version = ?", bookID, version).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &v, nil
+}
+
+// recordVersion snapshots book at its current version, as written by the
+// user tx acts for.
+func recordVersion(tx *gorm.DB, book *models.Book) error {
+	version := models.BookVersion{BookID: book.ID, Version: book.Version, Snapshot: book.Snapshot(), CreatedAt: book.UpdatedAt}
+	if identity, ok := auth.FromContext(tx.Statement.Context); ok {
+		version.UserID = &identity.UserID
+	}
+	return tx.Create(&version).Error
+}
+
+// recordUpdate snapshots book as an update left it, and as it was before,
+// in case that version predates versions being recorded.
+func recordUpdate(tx *gorm.DB, before, book *models.Book) error {
+	previous := models.BookVersion{BookID: before.ID, Version: before.Version, Snapshot: before.Snapshot(), CreatedAt: before.UpdatedAt}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&previous).Error; err != nil {
+		return err
+	}
+	return recordVersion(tx, book)
+}
@@ -0,0 +1,45 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type BranchRepository interface {
+	CRUDRepository[models.Branch]
+	// CountCopies returns how many copies of books the branch holds,
+	// including those it lent out.
+	CountCopies(ctx context.Context, id uint) (int64, error)
+}
+
+type branchRepository struct {
+	CRUDRepository[models.Branch]
+	db *gorm.DB
+}
+
+// NewBranchRepository stores the branches of each tenant.
+func NewBranchRepository(db *gorm.DB) BranchRepository {
+	return &branchRepository{CRUDRepository: NewCRUDRepository[models.Branch](db, "code"), db: db}
+}
+
+func (r *branchRepository) CountCopies(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.BranchStock{}).
+		Where("branch_id = ?", id).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&count).Error
+	return count, err
+}
+
+// Delete also removes the branch's rows of books it holds no copies of
+// anymore, which the foreign key would otherwise refuse to orphan.
+func (r *branchRepository) Delete(ctx context.Context, branch *models.Branch) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("branch_id = ? AND quantity = 0", branch.ID).Delete(&models.BranchStock{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(branch).Error
+	})
+}
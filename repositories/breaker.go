@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CircuitBreaker is a GORM plugin failing statements fast with
+// ErrUnavailable while the database is down, instead of making every
+// request wait for its own connection attempt to time out.
+//
+// It opens after threshold consecutive statements fail to reach the
+// database; errors the database itself returns, such as constraint
+// violations, don't count. Once cooldown has passed it lets a single
+// statement through to probe the database, closing again if it succeeds.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	// Zero while closed.
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+func (b *CircuitBreaker) Name() string { return "circuit_breaker" }
+
+func (b *CircuitBreaker) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("*").Register("breaker:before_create", b.before),
+		cb.Query().Before("*").Register("breaker:before_query", b.before),
+		cb.Update().Before("*").Register("breaker:before_update", b.before),
+		cb.Delete().Before("*").Register("breaker:before_delete", b.before),
+		cb.Row().Before("*").Register("breaker:before_row", b.before),
+		cb.Raw().Before("*").Register("breaker:before_raw", b.before),
+		cb.Create().After("*").Register("breaker:after_create", b.after),
+		cb.Query().After("*").Register("breaker:after_query", b.after),
+		cb.Update().After("*").Register("breaker:after_update", b.after),
+		cb.Delete().After("*").Register("breaker:after_delete", b.after),
+		cb.Row().After("*").Register("breaker:after_row", b.after),
+		cb.Raw().After("*").Register("breaker:after_raw", b.after),
+	)
+}
+
+const passedKey = "breaker:passed"
+
+// before refuses the statement while the circuit is open; GORM skips
+// statements that already carry an error.
+func (b *CircuitBreaker) before(db *gorm.DB) {
+	if !b.allow() {
+		db.AddError(ErrUnavailable)
+		return
+	}
+	db.InstanceSet(passedKey, true)
+}
+
+func (b *CircuitBreaker) after(db *gorm.DB) {
+	if _, ok := db.InstanceGet(passedKey); !ok {
+		return
+	}
+	b.record(unreachable(db.Error))
+}
+
+func (b *CircuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.openedAt.IsZero():
+		return true
+	case b.probing || time.Since(b.openedAt) < b.cooldown:
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+func (b *CircuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := !b.openedAt.IsZero()
+	b.probing = false
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		if wasOpen {
+			slog.Info("database reachable again, closed circuit breaker")
+		}
+		return
+	}
+
+	b.failures++
+	if wasOpen || b.failures >= b.threshold {
+		b.openedAt = time.Now()
+		if !wasOpen {
+			slog.Warn("database unreachable, opened circuit breaker", "failures", b.failures, "cooldown", b.cooldown.String())
+		}
+	}
+}
+
+// unreachable reports whether err means the database couldn't be reached,
+// rather than that it refused the statement. Cancelled requests say
+// nothing about the database either way.
+func unreachable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr)
+}
@@ -0,0 +1,67 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/audit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	CRUDRepository[models.Category]
+	// ListByBooks returns the categories of each book, sorted by name. Books
+	// without categories are absent from the map.
+	ListByBooks(ctx context.Context, bookIDs []uuid.UUID) (map[uuid.UUID][]models.Category, error)
+}
+
+type categoryRepository struct {
+	CRUDRepository[models.Category]
+	db *gorm.DB
+}
+
+// NewCategoryRepository stores the categories every tenant shares.
+func NewCategoryRepository(db *gorm.DB) CategoryRepository {
+	return &categoryRepository{CRUDRepository: NewCRUDRepository[models.Category](db, "name"), db: db}
+}
+
+func (r *categoryRepository) ListByBooks(ctx context.Context, bookIDs []uuid.UUID) (map[uuid.UUID][]models.Category, error) {
+	var rows []struct {
+		BookID uuid.UUID
+		models.Category
+	}
+	err := r.db.WithContext(ctx).
+		Table("categories").
+		Select("book_categories.book_id, categories.*").
+		Joins("JOIN book_categories ON book_categories.category_id = categories.id").
+		Where("book_categories.book_id IN ?", bookIDs).
+		Order("categories.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byBook := make(map[uuid.UUID][]models.Category, len(bookIDs))
+	for _, row := range rows {
+		byBook[row.BookID] = append(byBook[row.BookID], row.Category)
+	}
+	return byBook, nil
+}
+
+// Delete also removes the category from every book it was attached to.
+func (r *categoryRepository) Delete(ctx context.Context, category *models.Category) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var books []string
+		if err := tx.Table("book_categories").Where("category_id = ?", category.ID).Pluck("book_id", &books).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM book_categories WHERE category_id = ?", category.ID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, models.AuditDelete, "book_categories", linkIDs(books, category.ID), nil); err != nil {
+			return err
+		}
+		return tx.Delete(category).Error
+	})
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"gorm.io/gorm"
+)
+
+// CRUDRepository stores the records of a resource with integer IDs. The
+// repositories of such resources embed it, overriding what differs.
+type CRUDRepository[T any] interface {
+	List(ctx context.Context, offset, limit int) ([]T, int64, error)
+	FindByID(ctx context.Context, id uint) (*T, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]T, error)
+	Create(ctx context.Context, record *T) error
+	// Update applies the non-zero fields of changes to record.
+	Update(ctx context.Context, record *T, changes T) error
+	Delete(ctx context.Context, record *T) error
+}
+
+// tenantOwned is implemented by the models that belong to a tenant.
+type tenantOwned interface {
+	SetTenant(id uint)
+}
+
+type crudRepository[T any] struct {
+	db    *gorm.DB
+	order string
+	// Whether records belong to a tenant, and are only seen by it.
+	tenanted bool
+}
+
+// NewCRUDRepository stores T in the table gorm maps it to, listing records
+// in order. If T belongs to a tenant, records are created for the tenant
+// ctx acts for, and only its own are found.
+func NewCRUDRepository[T any](db *gorm.DB, order string) CRUDRepository[T] {
+	_, tenanted := any(new(T)).(tenantOwned)
+	return &crudRepository[T]{db: db, order: order, tenanted: tenanted}
+}
+
+func (r *crudRepository[T]) scoped(ctx context.Context) *gorm.DB {
+	db := r.db.WithContext(ctx)
+	if r.tenanted {
+		db = db.Scopes(tenantScope(ctx))
+	}
+	return db
+}
+
+func (r *crudRepository[T]) List(ctx context.Context, offset, limit int) ([]T, int64, error) {
+	var total int64
+	if err := r.scoped(ctx).Model(new(T)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var records []T
+	if err := r.scoped(ctx).Order(r.order).Offset(offset).Limit(limit).Find(&records).Error; err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
+
+func (r *crudRepository[T]) FindByID(ctx context.Context, id uint) (*T, error) {
+	var record T
+	if err := r.scoped(ctx).First(&record, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &record, nil
+}
+
+func (r *crudRepository[T]) FindByIDs(ctx context.Context, ids []uint) ([]T, error) {
+	var records []T
+	err := r.scoped(ctx).Where("id IN ?", ids).Find(&records).Error
+	return records, err
+}
+
+func (r *crudRepository[T]) Create(ctx context.Context, record *T) error {
+	if owned, ok := any(record).(tenantOwned); ok {
+		owned.SetTenant(tenancy.ID(ctx))
+	}
+	return translate(r.db.WithContext(ctx).Create(record).Error)
+}
+
+func (r *crudRepository[T]) Update(ctx context.Context, record *T, changes T) error {
+	return translate(r.db.WithContext(ctx).Model(record).Updates(changes).Error)
+}
+
+func (r *crudRepository[T]) Delete(ctx context.Context, record *T) error {
+	return r.db.WithContext(ctx).Delete(record).Error
+}
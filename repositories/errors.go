@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNotFound  = errors.New("record not found")
+	ErrDuplicate = errors.New("record already exists")
+	// ErrStaleVersion means the record was updated by someone else after the
+	// caller loaded it.
+	ErrStaleVersion = errors.New("record has been modified")
+	// ErrUnavailable means the database is down, see CircuitBreaker.
+	ErrUnavailable = errors.New("database unavailable")
+)
+
+// Translates GORM's sentinels into the errors above so callers don't have to
+// depend on gorm. Duplicate keys are only reported when the connection was
+// opened with TranslateError.
+func translate(err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ExportRepository stores the exports. They are looked up by their owner and
+// ID, so one user never finds another's.
+type ExportRepository interface {
+	FindByID(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, error)
+	Create(ctx context.Context, export *models.Export) error
+	// Claim marks the oldest pending export running and returns it, or
+	// fails with ErrNotFound when none is pending. Exports running since
+	// before staleBefore, left so by an instance that stopped, are pending
+	// again. Instances claiming at the same time never get the same one.
+	Claim(ctx context.Context, staleBefore time.Time) (*models.Export, error)
+	// Finish saves the outcome of a running export.
+	Finish(ctx context.Context, export *models.Export) error
+	// Expired returns up to limit exports that expired before t.
+	Expired(ctx context.Context, t time.Time, limit int) ([]models.Export, error)
+	Delete(ctx context.Context, export *models.Export) error
+}
+
+type exportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) ExportRepository {
+	return &exportRepository{db: db}
+}
+
+func (r *exportRepository) FindByID(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, error) {
+	var export models.Export
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&export, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &export, nil
+}
+
+func (r *exportRepository) Create(ctx context.Context, export *models.Export) error {
+	return r.db.WithContext(ctx).Create(export).Error
+}
+
+func (r *exportRepository) Claim(ctx context.Context, staleBefore time.Time) (*models.Export, error) {
+	db := r.db.WithContext(ctx)
+	for {
+		var export models.Export
+		if err := db.Scopes(claimable(staleBefore)).Order("created_at, id").First(&export).Error; err != nil {
+			return nil, translate(err)
+		}
+
+		// Only the instance whose update still finds the export claimable
+		// gets it; the others look for the next one.
+		now := time.Now()
+		result := db.Model(&models.Export{}).Where("id = ?", export.ID).Scopes(claimable(staleBefore)).
+			Updates(map[string]interface{}{"status": models.ExportRunning, "started_at": now})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			export.Status = models.ExportRunning
+			export.StartedAt = &now
+			return &export, nil
+		}
+	}
+}
+
+func claimable(staleBefore time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("status = ? OR (status = ? AND started_at < ?)", models.ExportPending, models.ExportRunning, staleBefore)
+	}
+}
+
+func (r *exportRepository) Finish(ctx context.Context, export *models.Export) error {
+	return r.db.WithContext(ctx).Model(export).Select("status", "error", "rows", "size", "filename", "storage_key", "finished_at", "expires_at").Updates(export).Error
+}
+
+func (r *exportRepository) Expired(ctx context.Context, t time.Time, limit int) ([]models.Export, error) {
+	var exports []models.Export
+	err := r.db.WithContext(ctx).Where("expires_at < ?", t).Order("expires_at").Limit(limit).Find(&exports).Error
+	return exports, err
+}
+
+func (r *exportRepository) Delete(ctx context.Context, export *models.Export) error {
+	return r.db.WithContext(ctx).Delete(export).Error
+}
@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ErrFineSettled means the fine was paid or waived before.
+var ErrFineSettled = errors.New("fine has already been settled")
+
+// FineFilter narrows the fines listed; zero fields match all.
+type FineFilter struct {
+	MemberID uuid.UUID
+	Status   string
+}
+
+type FineRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Fine, error)
+	// List returns the matching fines, newest first.
+	List(ctx context.Context, filter FineFilter, offset, limit int) ([]models.Fine, int64, error)
+	// Owed adds up the member's unpaid fines in the currency, whichever
+	// tenant charged them.
+	Owed(ctx context.Context, memberID uuid.UUID, currency string) (decimal.Decimal, error)
+	// Settle marks the fine paid or waived, failing with ErrFineSettled if
+	// it was already, including by a concurrent request.
+	Settle(ctx context.Context, fine *models.Fine, status string, by uint, note string, at time.Time) error
+}
+
+type fineRepository struct {
+	db *gorm.DB
+}
+
+func NewFineRepository(db *gorm.DB) FineRepository {
+	return &fineRepository{db: db}
+}
+
+func (r *fineRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Fine, error) {
+	var fine models.Fine
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).First(&fine, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &fine, nil
+}
+
+func (r *fineRepository) List(ctx context.Context, filter FineFilter, offset, limit int) ([]models.Fine, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if filter.MemberID != uuid.Nil {
+			db = db.Where("member_id = ?", filter.MemberID)
+		}
+		if filter.Status != "" {
+			db = db.Where("status = ?", filter.Status)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Fine{}).Scopes(tenantScope(ctx), scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var fines []models.Fine
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), scope).
+		Order("created_at DESC, id").
+		Offset(offset).
+		Limit(limit).
+		Find(&fines).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return fines, total, nil
+}
+
+func (r *fineRepository) Owed(ctx context.Context, memberID uuid.UUID, currency string) (decimal.Decimal, error) {
+	// Added up here rather than with SUM, which SQLite computes in floats.
+	var amounts []decimal.Decimal
+	err := r.db.WithContext(ctx).Model(&models.Fine{}).
+		Where("member_id = ? AND currency = ? AND status = ?", memberID, currency, models.FineUnpaid).
+		Pluck("amount", &amounts).Error
+	return decimal.Sum(decimal.Zero, amounts...), err
+}
+
+func (r *fineRepository) Settle(ctx context.Context, fine *models.Fine, status string, by uint, note string, at time.Time) error {
+	result := r.db.WithContext(ctx).Model(fine).
+		Where("status = ?", models.FineUnpaid).
+		Updates(map[string]any{"status": status, "settled_at": at, "settled_by_id": by, "note": note})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrFineSettled
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCopiesAvailable means the book can be borrowed right away, so
+	// there is nothing to hold.
+	ErrCopiesAvailable = errors.New("copies of the book are available")
+	// ErrAlreadyHeld means the member already holds the book.
+	ErrAlreadyHeld = errors.New("member already holds this book")
+	// ErrTooManyHolds means the member holds as many books as they may.
+	ErrTooManyHolds = errors.New("member holds too many books")
+	// ErrHoldClosed means the hold was fulfilled, cancelled or expired
+	// before.
+	ErrHoldClosed = errors.New("hold is no longer active")
+	// ErrBookHeld means members are in line for the book.
+	ErrBookHeld = errors.New("book has holds in line")
+)
+
+// The statuses of the holds still in line.
+var activeHolds = []string{models.HoldWaiting, models.HoldReady}
+
+// HoldFilter narrows the active holds listed; zero fields match all.
+type HoldFilter struct {
+	BookID   uuid.UUID
+	MemberID uuid.UUID
+}
+
+type HoldRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Hold, error)
+	// List returns the matching active holds in line order: the ready ones
+	// first, then the waiting ones in the order they were placed, with
+	// their positions.
+	List(ctx context.Context, filter HoldFilter, offset, limit int) ([]models.Hold, int64, error)
+	// ListReady returns the holds a copy was set aside for, with their book
+	// and member, earliest first.
+	ListReady(ctx context.Context, offset, limit int) ([]models.Hold, error)
+	// Position returns where the waiting hold is in its book's line, 1 being
+	// next, or 0 if it isn't waiting.
+	Position(ctx context.Context, hold *models.Hold) (int, error)
+	// Place puts the hold at the end of its book's line. It fails with
+	// ErrNotFound when the book doesn't exist, ErrCopiesAvailable,
+	// ErrAlreadyBorrowed, ErrAlreadyHeld, or ErrTooManyHolds when the member
+	// already holds max books (0 for no limit). The hold belongs to the
+	// book's tenant.
+	Place(ctx context.Context, hold *models.Hold, max int) error
+	// Cancel closes the hold, setting the copy set aside for it, if any,
+	// aside for the next in line until pickupBy. Fails with ErrHoldClosed.
+	Cancel(ctx context.Context, hold *models.Hold, at, pickupBy time.Time) error
+	// Expire closes the ready holds whose copy wasn't borrowed before now,
+	// passing the copies on like Cancel, and returns how many it closed.
+	Expire(ctx context.Context, now, pickupBy time.Time) (int, error)
+}
+
+type holdRepository struct {
+	db *gorm.DB
+}
+
+func NewHoldRepository(db *gorm.DB) HoldRepository {
+	return &holdRepository{db: db}
+}
+
+func (r *holdRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	var hold models.Hold
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Preload("Book").Preload("Member").First(&hold, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &hold, nil
+}
+
+func (r *holdRepository) List(ctx context.Context, filter HoldFilter, offset, limit int) ([]models.Hold, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = db.Where("status IN ?", activeHolds)
+		if filter.BookID != uuid.Nil {
+			db = db.Where("book_id = ?", filter.BookID)
+		}
+		if filter.MemberID != uuid.Nil {
+			db = db.Where("member_id = ?", filter.MemberID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Hold{}).Scopes(tenantScope(ctx), scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var holds []models.Hold
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), scope).
+		Preload("Book").
+		Preload("Member").
+		Order(gorm.Expr("CASE WHEN status = ? THEN 0 ELSE 1 END, created_at, id", models.HoldReady)).
+		Offset(offset).
+		Limit(limit).
+		Find(&holds).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	for i := range holds {
+		if holds[i].Position, err = r.Position(ctx, &holds[i]); err != nil {
+			return nil, 0, err
+		}
+	}
+	return holds, total, nil
+}
+
+func (r *holdRepository) ListReady(ctx context.Context, offset, limit int) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx)).
+		Preload("Book").
+		Preload("Member").
+		Where("status = ?", models.HoldReady).
+		Order("ready_at, id").
+		Offset(offset).
+		Limit(limit).
+		Find(&holds).Error
+	return holds, err
+}
+
+func (r *holdRepository) Position(ctx context.Context, hold *models.Hold) (int, error) {
+	if hold.Status != models.HoldWaiting {
+		return 0, nil
+	}
+	var ahead int64
+	err := r.db.WithContext(ctx).Model(&models.Hold{}).
+		Where("book_id = ? AND status = ?", hold.BookID, models.HoldWaiting).
+		Where("created_at < ? OR (created_at = ? AND id < ?)", hold.CreatedAt, hold.CreatedAt, hold.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// Place locks the book like a checkout, so a copy can't become available
+// between the check and the hold being queued.
+func (r *holdRepository) Place(ctx context.Context, hold *models.Hold, max int) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		book, err := lockBook(tx, hold.BookID)
+		if err != nil {
+			return err
+		}
+		if book.AvailableCopies > 0 {
+			return ErrCopiesAvailable
+		}
+
+		var borrowed int64
+		err = tx.Model(&models.Loan{}).
+			Where("book_id = ? AND member_id = ? AND returned_at IS NULL", hold.BookID, hold.MemberID).
+			Count(&borrowed).Error
+		if err != nil {
+			return err
+		}
+		if borrowed > 0 {
+			return ErrAlreadyBorrowed
+		}
+
+		// Members belong to no tenant, so their holds are counted across
+		// all of them.
+		var held, holding int64
+		err = tx.Model(&models.Hold{}).
+			Where("member_id = ? AND status IN ?", hold.MemberID, activeHolds).
+			Select("COUNT(*), COALESCE(SUM(CASE WHEN book_id = ? THEN 1 ELSE 0 END), 0)", hold.BookID).
+			Row().Scan(&holding, &held)
+		if err != nil {
+			return err
+		}
+		if held > 0 {
+			return ErrAlreadyHeld
+		}
+		if max > 0 && holding >= int64(max) {
+			return ErrTooManyHolds
+		}
+
+		hold.TenantID = book.TenantID
+		hold.Status = models.HoldWaiting
+		return tx.Create(hold).Error
+	})
+}
+
+func (r *holdRepository) Cancel(ctx context.Context, hold *models.Hold, at, pickupBy time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return closeHold(tx, hold, models.HoldCancelled, at, pickupBy)
+	})
+}
+
+func (r *holdRepository) Expire(ctx context.Context, now, pickupBy time.Time) (int, error) {
+	expired := 0
+	for {
+		var holds []models.Hold
+		err := r.db.WithContext(ctx).
+			Scopes(tenantScope(ctx)).
+			Where("status = ? AND expires_at < ?", models.HoldReady, now).
+			Order("expires_at, id").
+			Limit(100).
+			Find(&holds).Error
+		if err != nil {
+			return expired, err
+		}
+		for i := range holds {
+			err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return closeHold(tx, &holds[i], models.HoldExpired, now, pickupBy)
+			})
+			// Borrowed or cancelled since it was listed.
+			if errors.Is(err, ErrHoldClosed) {
+				continue
+			}
+			if err != nil {
+				return expired, err
+			}
+			expired++
+		}
+		if len(holds) < 100 {
+			return expired, nil
+		}
+	}
+}
+
+// closeHold moves the active hold to status, and sets the copy set aside
+// for it, if any, aside for the next in line. Fails with ErrHoldClosed if
+// the hold was closed already, including by a concurrent request.
+func closeHold(tx *gorm.DB, hold *models.Hold, status string, at, pickupBy time.Time) error {
+	was := hold.Status
+	if was != models.HoldWaiting && was != models.HoldReady {
+		return ErrHoldClosed
+	}
+	result := tx.Model(hold).Where("status = ?", was).Update("status", status)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrHoldClosed
+	}
+	if was != models.HoldReady {
+		return nil
+	}
+	_, err := reserve(tx, hold.BookID, hold.BranchID, at, pickupBy)
+	return err
+}
+
+// reserve sets a copy of the book, coming back to the shelves of the branch
+// (nil for the unassigned ones), aside for the first hold in line until
+// pickupBy, and returns that hold. When nobody waits, the copy goes back on
+// the shelves and nil is returned.
+func reserve(tx *gorm.DB, bookID uuid.UUID, branchID *uint, at, pickupBy time.Time) (*models.Hold, error) {
+	for {
+		var hold models.Hold
+		err := tx.Where("book_id = ? AND status = ?", bookID, models.HoldWaiting).
+			Order("created_at, id").
+			Limit(1).
+			Find(&hold).Error
+		if err != nil {
+			return nil, err
+		}
+		if hold.ID == uuid.Nil {
+			break
+		}
+		// Conditional, should the hold have been cancelled meanwhile.
+		result := tx.Model(&hold).Where("status = ?", models.HoldWaiting).Updates(map[string]interface{}{
+			"status":     models.HoldReady,
+			"branch_id":  branchID,
+			"ready_at":   at,
+			"expires_at": pickupBy,
+		})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected > 0 {
+			hold.Status, hold.BranchID, hold.ReadyAt, hold.ExpiresAt = models.HoldReady, branchID, &at, &pickupBy
+			return &hold, nil
+		}
+	}
+
+	if branchID != nil {
+		if err := addBranchStock(tx, bookID, *branchID, 0, 1); err != nil {
+			return nil, err
+		}
+	}
+	// Unscoped: a copy of a soft-deleted book still comes back to the
+	// shelf, and a restore should find the count right. By value, so the
+	// outbox sees which book changed.
+	return nil, tx.Unscoped().Model(&models.Book{ID: bookID}).
+		UpdateColumn("available_copies", gorm.Expr("available_copies + 1")).Error
+}
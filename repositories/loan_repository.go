@@ -0,0 +1,181 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNoCopiesAvailable means every copy of the book is already on loan.
+	ErrNoCopiesAvailable = errors.New("no copies of the book are available")
+	// ErrAlreadyBorrowed means the member already has the book on loan.
+	ErrAlreadyBorrowed = errors.New("member already has this book on loan")
+	// ErrAlreadyReturned means the loan was closed before.
+	ErrAlreadyReturned = errors.New("loan has already been returned")
+)
+
+type LoanRepository interface {
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Loan, error)
+	// ListActiveByMember returns the member's open loans, soonest due first.
+	ListActiveByMember(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Loan, int64, error)
+	// ListOverdue returns the open loans due before now, most overdue first.
+	ListOverdue(ctx context.Context, now time.Time, offset, limit int) ([]models.Loan, int64, error)
+	Checkout(ctx context.Context, loan *models.Loan) error
+	// Return also charges fine, if not nil. The copy is set aside for the
+	// first hold on the book until pickupBy, and that hold returned, if
+	// anyone waits for it.
+	Return(ctx context.Context, loan *models.Loan, at time.Time, fine *models.Fine, pickupBy time.Time) (*models.Hold, error)
+}
+
+type loanRepository struct {
+	db *gorm.DB
+}
+
+func NewLoanRepository(db *gorm.DB) LoanRepository {
+	return &loanRepository{db: db}
+}
+
+func (r *loanRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	var loan models.Loan
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Preload("Book").Preload("Member").Preload("Fine").First(&loan, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &loan, nil
+}
+
+func (r *loanRepository) ListActiveByMember(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Loan, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Where("member_id = ? AND returned_at IS NULL", memberID)
+	}
+	return r.list(ctx, scope, "due_at, id", offset, limit)
+}
+
+func (r *loanRepository) ListOverdue(ctx context.Context, now time.Time, offset, limit int) ([]models.Loan, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		return db.Where("due_at < ? AND returned_at IS NULL", now)
+	}
+	return r.list(ctx, scope, "due_at, id", offset, limit)
+}
+
+func (r *loanRepository) list(ctx context.Context, scope func(*gorm.DB) *gorm.DB, order string, offset, limit int) ([]models.Loan, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Loan{}).Scopes(tenantScope(ctx), scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var loans []models.Loan
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), scope).
+		Preload("Book").
+		Preload("Member").
+		Order(order).
+		Offset(offset).
+		Limit(limit).
+		Find(&loans).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return loans, total, nil
+}
+
+// Checkout inserts the loan and takes one of the book's available copies,
+// from the shelves of the loan's branch or the unassigned ones, unless a
+// copy was set aside for the member's hold: that one is lent, and the hold
+// fulfilled. The book row is locked first so concurrent checkouts of the
+// last copy are serialized and only one of them gets it. Fails with ErrNotFound when the
+// book doesn't exist, ErrAlreadyBorrowed when the member already has it, or
+// ErrNoCopiesAvailable. The loan belongs to the book's tenant.
+func (r *loanRepository) Checkout(ctx context.Context, loan *models.Loan) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		book, err := lockBook(tx, loan.BookID)
+		if err != nil {
+			return err
+		}
+
+		var borrowed int64
+		err = tx.Model(&models.Loan{}).
+			Where("book_id = ? AND member_id = ? AND returned_at IS NULL", loan.BookID, loan.MemberID).
+			Count(&borrowed).Error
+		if err != nil {
+			return err
+		}
+		if borrowed > 0 {
+			return ErrAlreadyBorrowed
+		}
+
+		var hold models.Hold
+		err = tx.Where("book_id = ? AND member_id = ? AND status IN ?", loan.BookID, loan.MemberID, activeHolds).
+			Limit(1).
+			Find(&hold).Error
+		if err != nil {
+			return err
+		}
+		reserved := false
+		if hold.ID != uuid.Nil {
+			// Conditional, should the hold have expired meanwhile and its
+			// copy gone to the next in line.
+			was := hold.Status
+			result := tx.Model(&hold).Where("status = ?", was).Update("status", models.HoldFulfilled)
+			if result.Error != nil {
+				return result.Error
+			}
+			reserved = result.RowsAffected > 0 && was == models.HoldReady
+		}
+
+		loan.TenantID = book.TenantID
+		if reserved {
+			loan.BranchID = hold.BranchID
+			return tx.Create(loan).Error
+		}
+
+		shelf, err := shelved(tx, book, loan.BranchID)
+		if err != nil {
+			return err
+		}
+		if shelf < 1 {
+			return ErrNoCopiesAvailable
+		}
+		if err := tx.Create(loan).Error; err != nil {
+			return err
+		}
+		if loan.BranchID != nil {
+			if err := addBranchStock(tx, book.ID, *loan.BranchID, 0, -1); err != nil {
+				return err
+			}
+		}
+		return tx.Model(book).UpdateColumn("available_copies", gorm.Expr("available_copies - 1")).Error
+	})
+}
+
+// Return closes the loan at the given time and brings the copy back to the
+// branch it was lent from, failing with ErrAlreadyReturned if it was closed
+// already, including by a concurrent request. The fine belongs to the
+// loan's tenant.
+func (r *loanRepository) Return(ctx context.Context, loan *models.Loan, at time.Time, fine *models.Fine, pickupBy time.Time) (*models.Hold, error) {
+	var hold *models.Hold
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(loan).Where("returned_at IS NULL").Update("returned_at", at)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyReturned
+		}
+		if fine != nil {
+			fine.TenantID = loan.TenantID
+			if err := tx.Create(fine).Error; err != nil {
+				return err
+			}
+		}
+
+		var err error
+		hold, err = reserve(tx, loan.BookID, loan.BranchID, at, pickupBy)
+		return err
+	})
+	return hold, err
+}
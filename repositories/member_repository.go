@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type MemberRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.Member, int64, error)
+	FindByID(ctx context.Context, id uuid.UUID) (*models.Member, error)
+	Create(ctx context.Context, member *models.Member) error
+}
+
+type memberRepository struct {
+	db *gorm.DB
+}
+
+func NewMemberRepository(db *gorm.DB) MemberRepository {
+	return &memberRepository{db: db}
+}
+
+func (r *memberRepository) List(ctx context.Context, offset, limit int) ([]models.Member, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Member{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var members []models.Member
+	if err := r.db.WithContext(ctx).Order("name, id").Offset(offset).Limit(limit).Find(&members).Error; err != nil {
+		return nil, 0, err
+	}
+	return members, total, nil
+}
+
+func (r *memberRepository) FindByID(ctx context.Context, id uuid.UUID) (*models.Member, error) {
+	var member models.Member
+	if err := r.db.WithContext(ctx).First(&member, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &member, nil
+}
+
+// Create fails with ErrDuplicate when the email is already registered.
+func (r *memberRepository) Create(ctx context.Context, member *models.Member) error {
+	return translate(r.db.WithContext(ctx).Create(member).Error)
+}
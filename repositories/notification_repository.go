@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationFilter narrows the sent notifications listed; zero fields
+// match all.
+type NotificationFilter struct {
+	MemberID uuid.UUID
+	LoanID   uuid.UUID
+	HoldID   uuid.UUID
+	Kind     string
+}
+
+type NotificationRepository interface {
+	// FindPreference fails with ErrNotFound when the member hasn't chosen.
+	FindPreference(ctx context.Context, memberID uuid.UUID) (*models.NotificationPreference, error)
+	SavePreference(ctx context.Context, preference *models.NotificationPreference) error
+	// Record adds a notification to the log.
+	Record(ctx context.Context, notification *models.SentNotification) error
+	// SentSince returns the channels a notification of the kind was sent
+	// through about the loan since the given time.
+	SentSince(ctx context.Context, loanID uuid.UUID, kind string, since time.Time) ([]string, error)
+	// SentForHold returns the channels a notification was sent through
+	// about the hold.
+	SentForHold(ctx context.Context, holdID uuid.UUID) ([]string, error)
+	// List returns the matching notifications, newest first.
+	List(ctx context.Context, filter NotificationFilter, offset, limit int) ([]models.SentNotification, int64, error)
+}
+
+type notificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) NotificationRepository {
+	return &notificationRepository{db: db}
+}
+
+func (r *notificationRepository) FindPreference(ctx context.Context, memberID uuid.UUID) (*models.NotificationPreference, error) {
+	var preference models.NotificationPreference
+	if err := r.db.WithContext(ctx).First(&preference, "member_id = ?", memberID).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &preference, nil
+}
+
+func (r *notificationRepository) SavePreference(ctx context.Context, preference *models.NotificationPreference) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "member_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"channels", "webhook_url", "updated_at"}),
+	}).Create(preference).Error
+}
+
+func (r *notificationRepository) Record(ctx context.Context, notification *models.SentNotification) error {
+	return r.db.WithContext(ctx).Create(notification).Error
+}
+
+func (r *notificationRepository) SentSince(ctx context.Context, loanID uuid.UUID, kind string, since time.Time) ([]string, error) {
+	var channels []string
+	err := r.db.WithContext(ctx).Model(&models.SentNotification{}).
+		Where("loan_id = ? AND kind = ? AND sent = ? AND created_at >= ?", loanID, kind, true, since).
+		Distinct().
+		Pluck("channel", &channels).Error
+	return channels, err
+}
+
+func (r *notificationRepository) SentForHold(ctx context.Context, holdID uuid.UUID) ([]string, error) {
+	var channels []string
+	err := r.db.WithContext(ctx).Model(&models.SentNotification{}).
+		Where("hold_id = ? AND sent = ?", holdID, true).
+		Distinct().
+		Pluck("channel", &channels).Error
+	return channels, err
+}
+
+func (r *notificationRepository) List(ctx context.Context, filter NotificationFilter, offset, limit int) ([]models.SentNotification, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if filter.MemberID != uuid.Nil {
+			db = db.Where("member_id = ?", filter.MemberID)
+		}
+		if filter.LoanID != uuid.Nil {
+			db = db.Where("loan_id = ?", filter.LoanID)
+		}
+		if filter.HoldID != uuid.Nil {
+			db = db.Where("hold_id = ?", filter.HoldID)
+		}
+		if filter.Kind != "" {
+			db = db.Where("kind = ?", filter.Kind)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.SentNotification{}).Scopes(tenantScope(ctx), scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var notifications []models.SentNotification
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), scope).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&notifications).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return notifications, total, nil
+}
@@ -0,0 +1,80 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type OutboxRepository interface {
+	// Pending returns up to limit unpublished events that no instance holds,
+	// oldest first.
+	Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error)
+	// Claim holds the event until the given time. It reports false when
+	// another instance claimed or published it first.
+	Claim(ctx context.Context, event *models.OutboxEvent, now, until time.Time) (bool, error)
+	MarkPublished(ctx context.Context, event *models.OutboxEvent, at time.Time) error
+	// MarkFailed records the reason and releases the event for a retry.
+	MarkFailed(ctx context.Context, event *models.OutboxEvent, reason string) error
+	// MarkDead records the reason and sets the event aside for good, so
+	// that it is never pending again.
+	MarkDead(ctx context.Context, event *models.OutboxEvent, reason string, at time.Time) error
+	// DeletePublishedBefore removes events published before t.
+	DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	var pending []models.OutboxEvent
+	err := r.db.WithContext(ctx).
+		Where("published_at IS NULL AND dead_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", now).
+		Order("id").
+		Limit(limit).
+		Find(&pending).Error
+	return pending, err
+}
+
+func (r *outboxRepository) Claim(ctx context.Context, event *models.OutboxEvent, now, until time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(event).
+		Where("published_at IS NULL AND (locked_until IS NULL OR locked_until < ?)", now).
+		UpdateColumn("locked_until", until)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *outboxRepository) MarkPublished(ctx context.Context, event *models.OutboxEvent, at time.Time) error {
+	return r.db.WithContext(ctx).Model(event).UpdateColumns(map[string]interface{}{
+		"published_at": at,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *outboxRepository) MarkFailed(ctx context.Context, event *models.OutboxEvent, reason string) error {
+	return r.db.WithContext(ctx).Model(event).UpdateColumns(map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   reason,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *outboxRepository) MarkDead(ctx context.Context, event *models.OutboxEvent, reason string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(event).UpdateColumns(map[string]interface{}{
+		"attempts":     gorm.Expr("attempts + 1"),
+		"last_error":   reason,
+		"dead_at":      at,
+		"locked_until": nil,
+	}).Error
+}
+
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("published_at < ?", t).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
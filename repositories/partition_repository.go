@@ -0,0 +1,344 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrPartitionsUnsupported means the database isn't Postgres, the only one
+// tables are partitioned on.
+var ErrPartitionsUnsupported = errors.New("tables can only be partitioned on postgres")
+
+// PartitionedTable is a table kept in monthly partitions, by the time in
+// Column.
+type PartitionedTable struct {
+	Name   string
+	Column string
+}
+
+// The tables that grow without bound: every change writes to the audit
+// log, and loans are kept once returned.
+var (
+	AuditLogPartitions = PartitionedTable{Name: "audit_logs", Column: "created_at"}
+	LoanPartitions     = PartitionedTable{Name: "loans", Column: "loaned_at"}
+	PartitionedTables  = []PartitionedTable{AuditLogPartitions, LoanPartitions}
+)
+
+// Partition is one of the partitions of a table, holding its rows from
+// From, inclusive, to To. The first one holds the rows from before the
+// table was partitioned and has no From; the default one holds those no
+// other takes, should a month's partition be missing, and has neither.
+type Partition struct {
+	Table   string     `json:"table"`
+	Name    string     `json:"name"`
+	From    *time.Time `json:"from"`
+	To      *time.Time `json:"to"`
+	Default bool       `json:"default"`
+	// As estimated by Postgres when it last analyzed the partition.
+	Rows int64 `json:"rows"`
+}
+
+type PartitionRepository interface {
+	// Partition turns table into a partitioned table. The table as it is
+	// becomes its first partition, holding the rows from before until,
+	// and a default partition is added. It does nothing and returns false
+	// when the table is partitioned already. Nothing may reference the
+	// table, and its unique indexes other than the primary key can't be
+	// kept; the partition column is added to the primary key.
+	Partition(ctx context.Context, table PartitionedTable, until time.Time) (bool, error)
+	// List returns the partitions of the table, oldest first and the
+	// default one last, or none when it isn't partitioned.
+	List(ctx context.Context, table PartitionedTable) ([]Partition, error)
+	// CreateMonth adds the partition of the table for the month starting
+	// at month, returning false when it exists. It fails if the default
+	// partition holds rows of that month.
+	CreateMonth(ctx context.Context, table PartitionedTable, month time.Time) (bool, error)
+	// Drop drops the partition and its rows.
+	Drop(ctx context.Context, partition Partition) error
+}
+
+// Held while partitions are changed, should instances run the
+// maintain-partitions job at the same time.
+const partitionLock = 7236105
+
+// The suffixes of partition names: YYYY_MM for a month's, until_YYYY_MM
+// for the rows from before.
+const (
+	partitionMonth   = "2006_01"
+	partitionUntil   = "until_"
+	partitionDefault = "default"
+)
+
+type partitionRepository struct {
+	db *gorm.DB
+}
+
+func NewPartitionRepository(db *gorm.DB) PartitionRepository {
+	return &partitionRepository{db: db}
+}
+
+type partitionIndex struct {
+	Name       string
+	Definition string
+	IsPrimary  bool
+	IsUnique   bool
+}
+
+func (r *partitionRepository) Partition(ctx context.Context, table PartitionedTable, until time.Time) (bool, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return false, ErrPartitionsUnsupported
+	}
+	converted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", partitionLock).Error; err != nil {
+			return err
+		}
+		var state struct {
+			Found       bool
+			Partitioned bool
+		}
+		err := tx.Raw(`SELECT to_regclass(?) IS NOT NULL AS found,
+			EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = to_regclass(?)) AS partitioned`, table.Name, table.Name).
+			Scan(&state).Error
+		if err != nil {
+			return err
+		}
+		if !state.Found {
+			return fmt.Errorf("table %s does not exist", table.Name)
+		}
+		if state.Partitioned {
+			return nil
+		}
+
+		var referencing []string
+		err = tx.Raw("SELECT conname FROM pg_constraint WHERE confrelid = ?::regclass AND contype = 'f'", table.Name).
+			Scan(&referencing).Error
+		if err != nil {
+			return err
+		}
+		if len(referencing) > 0 {
+			return fmt.Errorf("%s can't be partitioned while foreign keys reference it: %s", table.Name, strings.Join(referencing, ", "))
+		}
+
+		// Read before the table is renamed, so the definitions name it as
+		// the partitioned table will be.
+		var indexes []partitionIndex
+		err = tx.Raw(`SELECT c.relname AS name, pg_get_indexdef(i.indexrelid) AS definition,
+			i.indisprimary AS is_primary, i.indisunique AS is_unique
+			FROM pg_index i JOIN pg_class c ON c.oid = i.indexrelid
+			WHERE i.indrelid = ?::regclass`, table.Name).
+			Scan(&indexes).Error
+		if err != nil {
+			return err
+		}
+		var primaryKey []string
+		err = tx.Raw(`SELECT a.attname FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+			WHERE i.indrelid = ?::regclass AND i.indisprimary
+			ORDER BY array_position(i.indkey::int2[], a.attnum)`, table.Name).
+			Scan(&primaryKey).Error
+		if err != nil {
+			return err
+		}
+		var foreignKeys []struct {
+			Name       string
+			Definition string
+		}
+		err = tx.Raw("SELECT conname AS name, pg_get_constraintdef(oid) AS definition FROM pg_constraint WHERE conrelid = ?::regclass AND contype = 'f'", table.Name).
+			Scan(&foreignKeys).Error
+		if err != nil {
+			return err
+		}
+		for _, index := range indexes {
+			if index.IsUnique && !index.IsPrimary {
+				return fmt.Errorf("%s can't be partitioned with the unique index %s", table.Name, index.Name)
+			}
+		}
+
+		first := table.Name + "_" + partitionUntil + until.UTC().Format(partitionMonth)
+		statements := []string{
+			fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quote(table.Name), quote(first)),
+		}
+		// Index names are unique in a schema, so the first partition's
+		// make way for the partitioned table's.
+		for _, index := range indexes {
+			renamed := strings.Replace(index.Name, table.Name, first, 1)
+			if renamed == index.Name {
+				renamed = first + "_" + index.Name
+			}
+			statements = append(statements, fmt.Sprintf("ALTER INDEX %s RENAME TO %s", quote(index.Name), quote(renamed)))
+		}
+		statements = append(statements,
+			fmt.Sprintf("CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS INCLUDING STORAGE INCLUDING COMMENTS) PARTITION BY RANGE (%s)",
+				quote(table.Name), quote(first), quote(table.Column)))
+		if len(primaryKey) > 0 {
+			// Unique constraints of partitioned tables must include the
+			// partition column.
+			if !slices.Contains(primaryKey, table.Column) {
+				primaryKey = append(primaryKey, table.Column)
+			}
+			quoted := make([]string, len(primaryKey))
+			for i, column := range primaryKey {
+				quoted[i] = quote(column)
+			}
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD PRIMARY KEY (%s)", quote(table.Name), strings.Join(quoted, ", ")))
+		}
+		for _, index := range indexes {
+			if !index.IsUnique {
+				statements = append(statements, index.Definition)
+			}
+		}
+		for _, key := range foreignKeys {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s", quote(table.Name), quote(key.Name), key.Definition))
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+
+		// The sequences of serial columns go with the partitioned table,
+		// so dropping the first partition keeps them.
+		var sequences []struct {
+			Name     string
+			Sequence string
+		}
+		err = tx.Raw(`SELECT attname AS name, pg_get_serial_sequence(?, attname) AS sequence
+			FROM pg_attribute
+			WHERE attrelid = ?::regclass AND attnum > 0 AND NOT attisdropped AND pg_get_serial_sequence(?, attname) IS NOT NULL`, first, first, first).
+			Scan(&sequences).Error
+		if err != nil {
+			return err
+		}
+		statements = statements[:0]
+		for _, sequence := range sequences {
+			statements = append(statements, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s.%s", sequence.Sequence, quote(table.Name), quote(sequence.Name)))
+		}
+		// Attaching checks every row, under lock, and builds the indexes
+		// the first partition lacks.
+		statements = append(statements,
+			fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (MINVALUE) TO (%s)", quote(table.Name), quote(first), partitionBound(until)),
+			fmt.Sprintf("CREATE TABLE %s PARTITION OF %s DEFAULT", quote(table.Name+"_"+partitionDefault), quote(table.Name)),
+		)
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		converted = true
+		return nil
+	})
+	return converted, err
+}
+
+func (r *partitionRepository) List(ctx context.Context, table PartitionedTable) ([]Partition, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return nil, ErrPartitionsUnsupported
+	}
+	var rows []struct {
+		Name string
+		Rows int64
+	}
+	err := r.db.WithContext(ctx).Raw(`SELECT c.relname AS name, GREATEST(c.reltuples, 0)::bigint AS rows
+		FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass(?)`, table.Name).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	partitions := make([]Partition, 0, len(rows))
+	for _, row := range rows {
+		partition, ok := parsePartition(table, row.Name)
+		if !ok {
+			// Attached by hand; its bounds are unknown.
+			continue
+		}
+		partition.Rows = row.Rows
+		partitions = append(partitions, partition)
+	}
+	sort.Slice(partitions, func(i, j int) bool {
+		a, b := partitions[i], partitions[j]
+		if a.Default || b.Default {
+			return b.Default && !a.Default
+		}
+		return a.To.Before(*b.To)
+	})
+	return partitions, nil
+}
+
+func parsePartition(table PartitionedTable, name string) (Partition, bool) {
+	partition := Partition{Table: table.Name, Name: name}
+	suffix, ok := strings.CutPrefix(name, table.Name+"_")
+	if !ok {
+		return partition, false
+	}
+	if suffix == partitionDefault {
+		partition.Default = true
+		return partition, true
+	}
+	until, first := strings.CutPrefix(suffix, partitionUntil)
+	month, err := time.Parse(partitionMonth, until)
+	if err != nil {
+		return partition, false
+	}
+	if first {
+		partition.To = &month
+		return partition, true
+	}
+	end := month.AddDate(0, 1, 0)
+	partition.From, partition.To = &month, &end
+	return partition, true
+}
+
+func (r *partitionRepository) CreateMonth(ctx context.Context, table PartitionedTable, month time.Time) (bool, error) {
+	if r.db.Dialector.Name() != "postgres" {
+		return false, ErrPartitionsUnsupported
+	}
+	name := table.Name + "_" + month.UTC().Format(partitionMonth)
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", partitionLock).Error; err != nil {
+			return err
+		}
+		var exists bool
+		if err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", name).Scan(&exists).Error; err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		statement := fmt.Sprintf("CREATE TABLE %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)",
+			quote(name), quote(table.Name), partitionBound(month), partitionBound(month.AddDate(0, 1, 0)))
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+func (r *partitionRepository) Drop(ctx context.Context, partition Partition) error {
+	if r.db.Dialector.Name() != "postgres" {
+		return ErrPartitionsUnsupported
+	}
+	return r.db.WithContext(ctx).Exec("DROP TABLE IF EXISTS " + quote(partition.Name)).Error
+}
+
+// partitionBound is the literal of a partition bound; DDL takes no
+// parameters.
+func partitionBound(t time.Time) string {
+	return "'" + t.UTC().Format("2006-01-02 15:04:05") + "+00'"
+}
+
+func quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
@@ -0,0 +1,30 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type PublisherRepository interface {
+	CRUDRepository[models.Publisher]
+	CountBooks(ctx context.Context, id uint) (int64, error)
+}
+
+type publisherRepository struct {
+	CRUDRepository[models.Publisher]
+	db *gorm.DB
+}
+
+// NewPublisherRepository stores the publishers of each tenant.
+func NewPublisherRepository(db *gorm.DB) PublisherRepository {
+	return &publisherRepository{CRUDRepository: NewCRUDRepository[models.Publisher](db, "id"), db: db}
+}
+
+func (r *publisherRepository) CountBooks(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	// Soft-deleted books still reference the publisher, so they count too.
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).Where("publisher_id = ?", id).Count(&count).Error
+	return count, err
+}
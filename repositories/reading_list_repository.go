@@ -0,0 +1,165 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReadingListRepository stores the users' reading lists and favorites. Lists
+// are looked up by their owner and ID, so one user never finds another's,
+// except through FindByShareToken.
+type ReadingListRepository interface {
+	List(ctx context.Context, userID uint, offset, limit int) ([]models.ReadingList, int64, error)
+	FindByID(ctx context.Context, userID, id uint) (*models.ReadingList, error)
+	FindByShareToken(ctx context.Context, token string) (*models.ReadingList, error)
+	Create(ctx context.Context, list *models.ReadingList) error
+	Update(ctx context.Context, list *models.ReadingList, changes models.ReadingList) error
+	// SetShareToken sets the token of the list, or clears it when nil.
+	SetShareToken(ctx context.Context, list *models.ReadingList, token *string) error
+	// Delete removes the list along with its entries; the books stay.
+	Delete(ctx context.Context, list *models.ReadingList) error
+	// ListBooks returns the books on the list, oldest addition first.
+	// Deleted books are left out.
+	ListBooks(ctx context.Context, listID uint) ([]models.ReadingListBook, error)
+	// AddBook fails with ErrDuplicate if the book is on the list already.
+	AddBook(ctx context.Context, entry *models.ReadingListBook) error
+	// RemoveBook fails with ErrNotFound if the book isn't on the list.
+	RemoveBook(ctx context.Context, listID uint, bookID uuid.UUID) error
+
+	// ListFavorites returns the user's favorites, latest first, leaving out
+	// deleted books.
+	ListFavorites(ctx context.Context, userID uint, offset, limit int) ([]models.Favorite, int64, error)
+	// AddFavorite does nothing if the book is a favorite already.
+	AddFavorite(ctx context.Context, favorite *models.Favorite) error
+	// RemoveFavorite fails with ErrNotFound if the book isn't a favorite.
+	RemoveFavorite(ctx context.Context, userID uint, bookID uuid.UUID) error
+}
+
+type readingListRepository struct {
+	db *gorm.DB
+}
+
+func NewReadingListRepository(db *gorm.DB) ReadingListRepository {
+	return &readingListRepository{db: db}
+}
+
+func (r *readingListRepository) List(ctx context.Context, userID uint, offset, limit int) ([]models.ReadingList, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.ReadingList{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var lists []models.ReadingList
+	if err := db.Order("name, id").Offset(offset).Limit(limit).Find(&lists).Error; err != nil {
+		return nil, 0, err
+	}
+	return lists, total, nil
+}
+
+func (r *readingListRepository) FindByID(ctx context.Context, userID, id uint) (*models.ReadingList, error) {
+	var list models.ReadingList
+	if err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&list, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &list, nil
+}
+
+func (r *readingListRepository) FindByShareToken(ctx context.Context, token string) (*models.ReadingList, error) {
+	var list models.ReadingList
+	if err := r.db.WithContext(ctx).Where("share_token = ?", token).First(&list).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &list, nil
+}
+
+func (r *readingListRepository) Create(ctx context.Context, list *models.ReadingList) error {
+	return r.db.WithContext(ctx).Create(list).Error
+}
+
+// Update applies the non-zero fields of changes to list.
+func (r *readingListRepository) Update(ctx context.Context, list *models.ReadingList, changes models.ReadingList) error {
+	return r.db.WithContext(ctx).Model(list).Updates(changes).Error
+}
+
+func (r *readingListRepository) SetShareToken(ctx context.Context, list *models.ReadingList, token *string) error {
+	return r.db.WithContext(ctx).Model(list).Update("share_token", token).Error
+}
+
+func (r *readingListRepository) Delete(ctx context.Context, list *models.ReadingList) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SQLite only cascades with foreign keys enabled, so this doesn't
+		// count on it.
+		if err := tx.Where("reading_list_id = ?", list.ID).Delete(&models.ReadingListBook{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(list).Error
+	})
+}
+
+func (r *readingListRepository) ListBooks(ctx context.Context, listID uint) ([]models.ReadingListBook, error) {
+	var entries []models.ReadingListBook
+	err := r.db.WithContext(ctx).
+		Joins("JOIN books ON books.id = reading_list_books.book_id AND books.deleted_at IS NULL").
+		Preload("Book.Author").
+		Where("reading_list_books.reading_list_id = ?", listID).
+		Order("reading_list_books.created_at, reading_list_books.book_id").
+		Find(&entries).Error
+	return entries, err
+}
+
+func (r *readingListRepository) AddBook(ctx context.Context, entry *models.ReadingListBook) error {
+	return translate(r.db.WithContext(ctx).Omit("Book").Create(entry).Error)
+}
+
+func (r *readingListRepository) RemoveBook(ctx context.Context, listID uint, bookID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("reading_list_id = ? AND book_id = ?", listID, bookID).Delete(&models.ReadingListBook{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *readingListRepository) ListFavorites(ctx context.Context, userID uint, offset, limit int) ([]models.Favorite, int64, error) {
+	db := r.db.WithContext(ctx).Model(&models.Favorite{}).
+		Joins("JOIN books ON books.id = favorites.book_id AND books.deleted_at IS NULL").
+		Where("favorites.user_id = ?", userID)
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var favorites []models.Favorite
+	err := db.Preload("Book.Author").
+		Order("favorites.created_at DESC, favorites.book_id").
+		Offset(offset).Limit(limit).
+		Find(&favorites).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return favorites, total, nil
+}
+
+func (r *readingListRepository) AddFavorite(ctx context.Context, favorite *models.Favorite) error {
+	return r.db.WithContext(ctx).Omit("Book").Clauses(clause.OnConflict{DoNothing: true}).Create(favorite).Error
+}
+
+func (r *readingListRepository) RemoveFavorite(ctx context.Context, userID uint, bookID uuid.UUID) error {
+	result := r.db.WithContext(ctx).Where("user_id = ? AND book_id = ?", userID, bookID).Delete(&models.Favorite{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// How much each thing two books have in common adds to their similarity:
+// per shared category, per shared tag, for having the same author, and per
+// member who borrowed both.
+const (
+	sharedCategoryScore = 2
+	sharedTagScore      = 1
+	sameAuthorScore     = 3
+	coBorrowerScore     = 1
+)
+
+// ScoredBook is a recommended book and how strongly it is recommended.
+type ScoredBook struct {
+	Book  *models.Book `json:"book"`
+	Score int          `json:"score"`
+}
+
+type RecommendationRepository interface {
+	// ComputeSimilar scores the books most like the given one from the
+	// current categories, tags, authors and loans.
+	ComputeSimilar(ctx context.Context, bookID uuid.UUID, limit int) ([]models.BookSimilarity, error)
+	// ReplaceSimilar stores similar as the books like bookID, in place of
+	// those stored before.
+	ReplaceSimilar(ctx context.Context, bookID uuid.UUID, similar []models.BookSimilarity) error
+	// DeleteComputedBefore removes the similarities of books that weren't
+	// recomputed since cutoff, because they were deleted.
+	DeleteComputedBefore(ctx context.Context, cutoff time.Time) (int64, error)
+	// FindSimilar returns the stored books most like the given one, best
+	// first, leaving out deleted ones.
+	FindSimilar(ctx context.Context, bookID uuid.UUID, limit int) ([]ScoredBook, error)
+	// RecommendFor sums the stored similarities of the books the user likes,
+	// that is their favorites, the books on their reading lists and those
+	// they rated 4 or more, to the books they haven't come across yet.
+	RecommendFor(ctx context.Context, userID uint, limit int) ([]ScoredBook, error)
+}
+
+type recommendationRepository struct {
+	db *gorm.DB
+}
+
+func NewRecommendationRepository(db *gorm.DB) RecommendationRepository {
+	return &recommendationRepository{db: db}
+}
+
+// Each branch lists one candidate per thing it shares with @book; the sum
+// per candidate is its score. Candidates are kept to the tenant of the
+// book, and to books not deleted.
+const computeSimilarSQL = `
+SELECT candidates.book_id AS similar_book_id, SUM(candidates.score) AS score
+FROM (
+	SELECT b.book_id, @category AS score
+	FROM book_categories a JOIN book_categories b ON b.category_id = a.category_id
+	WHERE a.book_id = @book AND b.book_id <> @book
+	UNION ALL
+	SELECT b.book_id, @tag
+	FROM book_tags a JOIN book_tags b ON b.tag_id = a.tag_id
+	WHERE a.book_id = @book AND b.book_id <> @book
+	UNION ALL
+	SELECT b.id, @author
+	FROM books a JOIN books b ON b.author_id = a.author_id
+	WHERE a.id = @book AND b.id <> @book
+	UNION ALL
+	SELECT b.book_id, @borrower
+	FROM (SELECT DISTINCT member_id FROM loans WHERE book_id = @book) a
+	JOIN (SELECT DISTINCT member_id, book_id FROM loans) b ON b.member_id = a.member_id
+	WHERE b.book_id <> @book
+) candidates
+JOIN books ON books.id = candidates.book_id AND books.deleted_at IS NULL
+	AND books.tenant_id = (SELECT tenant_id FROM books WHERE id = @book)
+GROUP BY candidates.book_id
+ORDER BY score DESC, candidates.book_id
+LIMIT @limit`
+
+func (r *recommendationRepository) ComputeSimilar(ctx context.Context, bookID uuid.UUID, limit int) ([]models.BookSimilarity, error) {
+	var similar []models.BookSimilarity
+	err := r.db.WithContext(ctx).Raw(computeSimilarSQL, map[string]interface{}{
+		"book":     bookID,
+		"category": sharedCategoryScore,
+		"tag":      sharedTagScore,
+		"author":   sameAuthorScore,
+		"borrower": coBorrowerScore,
+		"limit":    limit,
+	}).Scan(&similar).Error
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range similar {
+		similar[i].BookID, similar[i].ComputedAt = bookID, now
+	}
+	return similar, nil
+}
+
+func (r *recommendationRepository) ReplaceSimilar(ctx context.Context, bookID uuid.UUID, similar []models.BookSimilarity) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("book_id = ?", bookID).Delete(&models.BookSimilarity{}).Error; err != nil {
+			return err
+		}
+		if len(similar) == 0 {
+			return nil
+		}
+		return tx.Create(&similar).Error
+	})
+}
+
+func (r *recommendationRepository) DeleteComputedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Where("computed_at < ?", cutoff).Delete(&models.BookSimilarity{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *recommendationRepository) FindSimilar(ctx context.Context, bookID uuid.UUID, limit int) ([]ScoredBook, error) {
+	var similar []models.BookSimilarity
+	err := r.db.WithContext(ctx).
+		Joins("JOIN books ON books.id = book_similarities.similar_book_id AND books.deleted_at IS NULL").
+		Where("book_similarities.book_id = ?", bookID).
+		Order("book_similarities.score DESC, book_similarities.similar_book_id").
+		Limit(limit).
+		Find(&similar).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.withBooks(ctx, similar)
+}
+
+// The books the user came across, and among them those they like.
+const (
+	userBooksSQL = `
+SELECT book_id FROM favorites WHERE user_id = @user
+UNION SELECT reading_list_books.book_id FROM reading_list_books
+	JOIN reading_lists ON reading_lists.id = reading_list_books.reading_list_id
+	WHERE reading_lists.user_id = @user
+UNION SELECT book_id FROM reviews WHERE user_id = @user`
+	likedBooksSQL = `
+SELECT book_id FROM favorites WHERE user_id = @user
+UNION SELECT reading_list_books.book_id FROM reading_list_books
+	JOIN reading_lists ON reading_lists.id = reading_list_books.reading_list_id
+	WHERE reading_lists.user_id = @user
+UNION SELECT book_id FROM reviews WHERE user_id = @user AND rating >= 4`
+)
+
+const recommendForSQL = `
+SELECT book_similarities.similar_book_id, SUM(book_similarities.score) AS score
+FROM book_similarities
+JOIN books ON books.id = book_similarities.similar_book_id AND books.deleted_at IS NULL AND books.tenant_id = @tenant
+WHERE book_similarities.book_id IN (` + likedBooksSQL + `)
+	AND book_similarities.similar_book_id NOT IN (` + userBooksSQL + `)
+GROUP BY book_similarities.similar_book_id
+ORDER BY score DESC, book_similarities.similar_book_id
+LIMIT @limit`
+
+func (r *recommendationRepository) RecommendFor(ctx context.Context, userID uint, limit int) ([]ScoredBook, error) {
+	var similar []models.BookSimilarity
+	err := r.db.WithContext(ctx).Raw(recommendForSQL, map[string]interface{}{
+		"user":   userID,
+		"tenant": tenancy.ID(ctx),
+		"limit":  limit,
+	}).Scan(&similar).Error
+	if err != nil {
+		return nil, err
+	}
+	return r.withBooks(ctx, similar)
+}
+
+// withBooks loads the similar books, with their authors, keeping the order
+// of similar.
+func (r *recommendationRepository) withBooks(ctx context.Context, similar []models.BookSimilarity) ([]ScoredBook, error) {
+	ids := make([]uuid.UUID, len(similar))
+	for i, s := range similar {
+		ids[i] = s.SimilarBookID
+	}
+	var books []models.Book
+	if len(ids) > 0 {
+		if err := r.db.WithContext(ctx).Preload("Author").Where("id IN ?", ids).Find(&books).Error; err != nil {
+			return nil, err
+		}
+	}
+	byID := make(map[uuid.UUID]*models.Book, len(books))
+	for i := range books {
+		byID[books[i].ID] = &books[i]
+	}
+
+	scored := make([]ScoredBook, 0, len(similar))
+	for _, s := range similar {
+		// Gone if deleted in between.
+		if book, ok := byID[s.SimilarBookID]; ok {
+			scored = append(scored, ScoredBook{Book: book, Score: s.Score})
+		}
+	}
+	return scored, nil
+}
@@ -0,0 +1,59 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type RefreshTokenRepository interface {
+	// FindByHash returns the token with the given hash, with its user.
+	FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	Create(ctx context.Context, token *models.RefreshToken) error
+	// Revoke revokes token unless it already is, reporting whether it did,
+	// so of two requests using the same token only one gets to.
+	Revoke(ctx context.Context, token *models.RefreshToken, at time.Time) (bool, error)
+	RevokeFamily(ctx context.Context, family string, at time.Time) error
+	RevokeUser(ctx context.Context, userID uint, at time.Time) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) RefreshTokenRepository {
+	return &refreshTokenRepository{db: db}
+}
+
+func (r *refreshTokenRepository) FindByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).Preload("User").Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return translate(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *refreshTokenRepository) Revoke(ctx context.Context, token *models.RefreshToken, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND revoked_at IS NULL", token.ID).
+		Update("revoked_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, family string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family = ? AND revoked_at IS NULL", family).
+		Update("revoked_at", at).Error
+}
+
+func (r *refreshTokenRepository) RevokeUser(ctx context.Context, userID uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReviewRepository interface {
+	ListByBook(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Review, int64, error)
+	Create(ctx context.Context, review *models.Review) error
+	// RefreshBookStats recomputes the rating average and review count of
+	// every book whose stored ones disagree with its reviews, and returns how
+	// many books it corrected.
+	RefreshBookStats(ctx context.Context) (int, error)
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) ReviewRepository {
+	return &reviewRepository{db: db}
+}
+
+// ListByBook returns the book's reviews, newest first.
+func (r *reviewRepository) ListByBook(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Review, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Review{}).Where("book_id = ?", bookID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var reviews []models.Review
+	err := r.db.WithContext(ctx).
+		Where("book_id = ?", bookID).
+		Order("created_at DESC, id").
+		Offset(offset).
+		Limit(limit).
+		Find(&reviews).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return reviews, total, nil
+}
+
+// Create inserts the review and refreshes the book's rating average and
+// review count in the same transaction. The book row is locked first so
+// concurrent reviews can't compute the stats from each other's snapshots.
+// A second review of the same book by the same user fails with
+// ErrDuplicate.
+func (r *reviewRepository) Create(ctx context.Context, review *models.Review) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var book models.Book
+		err := tx.Scopes(tenantScope(ctx)).Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&book, "id = ?", review.BookID).Error
+		if err != nil {
+			return translate(err)
+		}
+		if err := tx.Create(review).Error; err != nil {
+			return translate(err)
+		}
+		return refreshBookStats(tx, &book)
+	})
+}
+
+func (r *reviewRepository) RefreshBookStats(ctx context.Context) (int, error) {
+	var drifted []uuid.UUID
+	err := r.db.WithContext(ctx).Unscoped().Model(&models.Book{}).
+		Joins("LEFT JOIN (SELECT book_id, COUNT(*) AS count, AVG(rating) AS average FROM reviews GROUP BY book_id) stats ON stats.book_id = books.id").
+		Where("books.review_count <> COALESCE(stats.count, 0) OR ABS(books.rating_average - COALESCE(stats.average, 0)) >= 0.005").
+		Pluck("books.id", &drifted).Error
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range drifted {
+		err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var book models.Book
+			err := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&book, "id = ?", id).Error
+			if err != nil {
+				return translate(err)
+			}
+			return refreshBookStats(tx, &book)
+		})
+		if err != nil && !errors.Is(err, ErrNotFound) {
+			return 0, err
+		}
+	}
+	return len(drifted), nil
+}
+
+// refreshBookStats stores the book's rating average and review count as
+// computed from its reviews. The caller holds the book's row lock.
+func refreshBookStats(tx *gorm.DB, book *models.Book) error {
+	var stats struct {
+		Count   int
+		Average float64
+	}
+	err := tx.Model(&models.Review{}).
+		Select("COUNT(*) AS count, COALESCE(AVG(rating), 0) AS average").
+		Where("book_id = ?", book.ID).
+		Scan(&stats).Error
+	if err != nil {
+		return err
+	}
+
+	// UpdateColumns leaves updated_at and the version alone: a review isn't
+	// an edit of the book. Unscoped, so soft-deleted books are right if
+	// restored.
+	return tx.Unscoped().Model(book).UpdateColumns(map[string]interface{}{
+		"review_count":   stats.Count,
+		"rating_average": math.Round(stats.Average*100) / 100,
+	}).Error
+}
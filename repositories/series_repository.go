@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SeriesRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.Series, int64, error)
+	FindByID(ctx context.Context, id uint) (*models.Series, error)
+	Create(ctx context.Context, series *models.Series) error
+	Update(ctx context.Context, series *models.Series, changes models.Series) error
+	// Delete removes the series along with its volumes; the books stay.
+	Delete(ctx context.Context, series *models.Series) error
+	// ListVolumes returns the volumes of the series in order, with their
+	// books. Volumes whose book was deleted are left out.
+	ListVolumes(ctx context.Context, seriesID uint) ([]models.SeriesVolume, error)
+	// FindVolume returns the volume of the series with the given number.
+	FindVolume(ctx context.Context, seriesID uint, volume int) (*models.SeriesVolume, error)
+	// FindBookVolume returns the volume the book is in the series.
+	FindBookVolume(ctx context.Context, seriesID uint, bookID uuid.UUID) (*models.SeriesVolume, error)
+	// AddVolume fails with ErrDuplicate if the book is in the series
+	// already or the volume number is taken.
+	AddVolume(ctx context.Context, volume *models.SeriesVolume) error
+	RemoveVolume(ctx context.Context, volume *models.SeriesVolume) error
+}
+
+type seriesRepository struct {
+	db *gorm.DB
+}
+
+func NewSeriesRepository(db *gorm.DB) SeriesRepository {
+	return &seriesRepository{db: db}
+}
+
+func (r *seriesRepository) List(ctx context.Context, offset, limit int) ([]models.Series, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Series{}).Scopes(tenantScope(ctx)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var series []models.Series
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Order("name, id").Offset(offset).Limit(limit).Find(&series).Error; err != nil {
+		return nil, 0, err
+	}
+	return series, total, nil
+}
+
+func (r *seriesRepository) FindByID(ctx context.Context, id uint) (*models.Series, error) {
+	var series models.Series
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).First(&series, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &series, nil
+}
+
+// Create adds the series to the tenant ctx acts for.
+func (r *seriesRepository) Create(ctx context.Context, series *models.Series) error {
+	series.TenantID = tenancy.ID(ctx)
+	return r.db.WithContext(ctx).Create(series).Error
+}
+
+// Update applies the non-zero fields of changes to series.
+func (r *seriesRepository) Update(ctx context.Context, series *models.Series, changes models.Series) error {
+	return r.db.WithContext(ctx).Model(series).Updates(changes).Error
+}
+
+func (r *seriesRepository) Delete(ctx context.Context, series *models.Series) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// SQLite only cascades with foreign keys enabled, so this doesn't
+		// count on it.
+		if err := tx.Where("series_id = ?", series.ID).Delete(&models.SeriesVolume{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(series).Error
+	})
+}
+
+func (r *seriesRepository) ListVolumes(ctx context.Context, seriesID uint) ([]models.SeriesVolume, error) {
+	var volumes []models.SeriesVolume
+	err := r.db.WithContext(ctx).
+		Joins("JOIN books ON books.id = series_volumes.book_id AND books.deleted_at IS NULL").
+		Preload("Book.Author").
+		Where("series_volumes.series_id = ?", seriesID).
+		Order("series_volumes.volume").
+		Find(&volumes).Error
+	return volumes, err
+}
+
+func (r *seriesRepository) FindVolume(ctx context.Context, seriesID uint, volume int) (*models.SeriesVolume, error) {
+	var found models.SeriesVolume
+	if err := r.db.WithContext(ctx).Where("series_id = ? AND volume = ?", seriesID, volume).First(&found).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &found, nil
+}
+
+func (r *seriesRepository) FindBookVolume(ctx context.Context, seriesID uint, bookID uuid.UUID) (*models.SeriesVolume, error) {
+	var found models.SeriesVolume
+	if err := r.db.WithContext(ctx).Where("series_id = ? AND book_id = ?", seriesID, bookID).First(&found).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &found, nil
+}
+
+func (r *seriesRepository) AddVolume(ctx context.Context, volume *models.SeriesVolume) error {
+	return translate(r.db.WithContext(ctx).Omit("Book").Create(volume).Error)
+}
+
+func (r *seriesRepository) RemoveVolume(ctx context.Context, volume *models.SeriesVolume) error {
+	return r.db.WithContext(ctx).Where("series_id = ? AND book_id = ?", volume.SeriesID, volume.BookID).Delete(&models.SeriesVolume{}).Error
+}
@@ -0,0 +1,173 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StatsTotals struct {
+	Books        int64 `json:"books"`
+	Authors      int64 `json:"authors"`
+	ActiveLoans  int64 `json:"active_loans"`
+	OverdueLoans int64 `json:"overdue_loans"`
+}
+
+type CategoryCount struct {
+	CategoryID uint   `json:"category_id"`
+	Name       string `json:"name"`
+	Books      int64  `json:"books"`
+}
+
+type MonthCount struct {
+	// As YYYY-MM.
+	Month string `json:"month"`
+	Books int64  `json:"books"`
+}
+
+type BorrowCount struct {
+	Book  models.Book `json:"book"`
+	Loans int64       `json:"loans"`
+}
+
+// StatsRepository computes the catalog and lending figures of the admin
+// statistics, each in a single aggregate query over the tenant's data.
+// Soft-deleted books are left out, except from loan history.
+type StatsRepository interface {
+	Totals(ctx context.Context, now time.Time) (StatsTotals, error)
+	// BooksByCategory counts the books in each category, categories with
+	// none included, most books first.
+	BooksByCategory(ctx context.Context) ([]CategoryCount, error)
+	// BooksAddedPerMonth counts the books created in each month since
+	// since, in UTC, oldest first. Months without any are left out.
+	BooksAddedPerMonth(ctx context.Context, since time.Time) ([]MonthCount, error)
+	// TopRated returns the best-rated reviewed books, the most reviewed
+	// first among equal ratings.
+	TopRated(ctx context.Context, limit int) ([]models.Book, error)
+	// MostBorrowed returns the books lent the most times, returned loans
+	// included.
+	MostBorrowed(ctx context.Context, limit int) ([]BorrowCount, error)
+}
+
+type statsRepository struct {
+	db *gorm.DB
+}
+
+func NewStatsRepository(db *gorm.DB) StatsRepository {
+	return &statsRepository{db: db}
+}
+
+func (r *statsRepository) Totals(ctx context.Context, now time.Time) (StatsTotals, error) {
+	var totals StatsTotals
+	db := r.db.WithContext(ctx)
+	if err := db.Model(&models.Book{}).Scopes(tenantScope(ctx)).Count(&totals.Books).Error; err != nil {
+		return totals, err
+	}
+	if err := db.Model(&models.Author{}).Scopes(tenantScope(ctx)).Count(&totals.Authors).Error; err != nil {
+		return totals, err
+	}
+	var loans struct {
+		Active  int64
+		Overdue int64
+	}
+	err := db.Model(&models.Loan{}).Scopes(tenantScope(ctx)).
+		Select("COUNT(*) AS active, COALESCE(SUM(CASE WHEN due_at < ? THEN 1 ELSE 0 END), 0) AS overdue", now).
+		Where("returned_at IS NULL").
+		Scan(&loans).Error
+	totals.ActiveLoans, totals.OverdueLoans = loans.Active, loans.Overdue
+	return totals, err
+}
+
+func (r *statsRepository) BooksByCategory(ctx context.Context) ([]CategoryCount, error) {
+	// Categories are shared by every tenant, so the tenant's books are
+	// picked in the join rather than by tenantScope.
+	books := "LEFT JOIN books ON books.id = book_categories.book_id AND books.deleted_at IS NULL"
+	var args []interface{}
+	if id, ok := tenancy.FromContext(ctx); ok {
+		books += " AND books.tenant_id = ?"
+		args = append(args, id)
+	}
+
+	var counts []CategoryCount
+	err := r.db.WithContext(ctx).Table("categories").
+		Select("categories.id AS category_id, categories.name, COUNT(books.id) AS books").
+		Joins("LEFT JOIN book_categories ON book_categories.category_id = categories.id").
+		Joins(books, args...).
+		Group("categories.id, categories.name").
+		Order("books DESC, categories.name").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *statsRepository) BooksAddedPerMonth(ctx context.Context, since time.Time) ([]MonthCount, error) {
+	db := r.db.WithContext(ctx)
+	month := "strftime('%Y-%m', created_at)"
+	switch db.Dialector.Name() {
+	case "postgres":
+		month = "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM')"
+	case "mysql":
+		month = "DATE_FORMAT(created_at, '%Y-%m')"
+	}
+
+	var counts []MonthCount
+	err := db.Model(&models.Book{}).Scopes(tenantScope(ctx)).
+		Select(month+" AS month, COUNT(*) AS books").
+		Where("created_at >= ?", since).
+		Group("month").
+		Order("month").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *statsRepository) TopRated(ctx context.Context, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).
+		Preload("Author").
+		Where("review_count > 0").
+		Order("rating_average DESC, review_count DESC, id").
+		Limit(limit).
+		Find(&books).Error
+	return books, err
+}
+
+func (r *statsRepository) MostBorrowed(ctx context.Context, limit int) ([]BorrowCount, error) {
+	var rows []struct {
+		BookID uuid.UUID
+		Loans  int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Loan{}).Scopes(tenantScope(ctx)).
+		Select("book_id, COUNT(*) AS loans").
+		Group("book_id").
+		Order("loans DESC, book_id").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(rows))
+	for i, row := range rows {
+		ids[i] = row.BookID
+	}
+	var books []models.Book
+	// Unscoped: a book's loans count even once it is deleted.
+	if err := r.db.WithContext(ctx).Unscoped().Preload("Author").Where("id IN ?", ids).Find(&books).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	counts := make([]BorrowCount, 0, len(rows))
+	for _, row := range rows {
+		if book, ok := byID[row.BookID]; ok {
+			counts = append(counts, BorrowCount{Book: book, Loans: row.Loans})
+		}
+	}
+	return counts, nil
+}
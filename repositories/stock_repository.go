@@ -0,0 +1,206 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCopiesOnLoan means a stock adjustment would leave the library owning
+	// fewer copies than are currently lent out.
+	ErrCopiesOnLoan = errors.New("adjustment would remove copies that are on loan")
+	// ErrNotEnoughCopies means a transfer would move more copies than are on
+	// the shelves it takes them from.
+	ErrNotEnoughCopies = errors.New("not enough copies on the shelves to transfer")
+)
+
+// TransferFilter narrows the transfers listed; zero fields match all.
+type TransferFilter struct {
+	BookID uuid.UUID
+	// Transfers from or to the branch.
+	BranchID uint
+}
+
+type StockRepository interface {
+	// Adjust adds delta (which may be negative) to the book's quantity and
+	// available copies, and to the branch's when branchID isn't nil; nil
+	// adjusts the unassigned copies.
+	Adjust(ctx context.Context, id uuid.UUID, branchID *uint, delta int) (*models.Book, error)
+	// BranchStock returns the copies of the book each branch holds.
+	BranchStock(ctx context.Context, id uuid.UUID) ([]models.BranchStock, error)
+	// Transfer moves copies of a book on the shelves of one branch to
+	// another and logs it, failing with ErrNotEnoughCopies if there aren't
+	// as many. The transfer belongs to the book's tenant.
+	Transfer(ctx context.Context, transfer *models.StockTransfer) error
+	// ListTransfers returns the matching transfers, newest first.
+	ListTransfers(ctx context.Context, filter TransferFilter, offset, limit int) ([]models.StockTransfer, int64, error)
+}
+
+type stockRepository struct {
+	db *gorm.DB
+}
+
+func NewStockRepository(db *gorm.DB) StockRepository {
+	return &stockRepository{db: db}
+}
+
+// Adjust locks the book row for the duration of the transaction so it
+// can't interleave with a checkout, return or transfer of the same book.
+// Only copies on the shelf can be removed: ErrCopiesOnLoan is returned
+// otherwise. Changing the stock bumps the book's version like any other
+// edit.
+func (r *stockRepository) Adjust(ctx context.Context, id uuid.UUID, branchID *uint, delta int) (*models.Book, error) {
+	var book *models.Book
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if book, err = lockBook(tx, id); err != nil {
+			return err
+		}
+		shelf, err := shelved(tx, book, branchID)
+		if err != nil {
+			return err
+		}
+		if shelf+delta < 0 {
+			return ErrCopiesOnLoan
+		}
+		before := *book
+
+		if branchID != nil {
+			if err := addBranchStock(tx, id, *branchID, delta, delta); err != nil {
+				return err
+			}
+		}
+		err = tx.Model(book).Updates(map[string]interface{}{
+			"quantity":         gorm.Expr("quantity + ?", delta),
+			"available_copies": gorm.Expr("available_copies + ?", delta),
+			"version":          gorm.Expr("version + 1"),
+		}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.First(book, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return recordUpdate(tx, &before, book)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (r *stockRepository) BranchStock(ctx context.Context, id uuid.UUID) ([]models.BranchStock, error) {
+	var stock []models.BranchStock
+	err := r.db.WithContext(ctx).Where("book_id = ?", id).Order("branch_id").Find(&stock).Error
+	return stock, err
+}
+
+// Transfer holds the book's lock like Adjust, so the copies counted on the
+// shelves are still there when they are moved.
+func (r *stockRepository) Transfer(ctx context.Context, transfer *models.StockTransfer) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		book, err := lockBook(tx, transfer.BookID)
+		if err != nil {
+			return err
+		}
+		shelf, err := shelved(tx, book, transfer.FromBranchID)
+		if err != nil {
+			return err
+		}
+		if shelf < transfer.Copies {
+			return ErrNotEnoughCopies
+		}
+
+		if transfer.FromBranchID != nil {
+			if err := addBranchStock(tx, book.ID, *transfer.FromBranchID, -transfer.Copies, -transfer.Copies); err != nil {
+				return err
+			}
+		}
+		if transfer.ToBranchID != nil {
+			if err := addBranchStock(tx, book.ID, *transfer.ToBranchID, transfer.Copies, transfer.Copies); err != nil {
+				return err
+			}
+		}
+		transfer.TenantID = book.TenantID
+		return tx.Create(transfer).Error
+	})
+}
+
+func (r *stockRepository) ListTransfers(ctx context.Context, filter TransferFilter, offset, limit int) ([]models.StockTransfer, int64, error) {
+	scope := func(db *gorm.DB) *gorm.DB {
+		if filter.BookID != uuid.Nil {
+			db = db.Where("book_id = ?", filter.BookID)
+		}
+		if filter.BranchID != 0 {
+			db = db.Where("from_branch_id = ? OR to_branch_id = ?", filter.BranchID, filter.BranchID)
+		}
+		return db
+	}
+
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.StockTransfer{}).Scopes(tenantScope(ctx), scope).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var transfers []models.StockTransfer
+	err := r.db.WithContext(ctx).
+		Scopes(tenantScope(ctx), scope).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&transfers).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return transfers, total, nil
+}
+
+// lockBook loads the book with FOR UPDATE so the caller has exclusive use of
+// its stock columns until the transaction ends. Fails with ErrNotFound,
+// including for books of other tenants than the one tx's context acts for.
+func lockBook(tx *gorm.DB, id uuid.UUID) (*models.Book, error) {
+	var book models.Book
+	if err := tx.Scopes(tenantScope(tx.Statement.Context)).Clauses(clause.Locking{Strength: "UPDATE"}).First(&book, "id = ?", id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &book, nil
+}
+
+// shelved counts the copies of the book, locked by the caller, on the
+// shelves of the branch; nil counts the unassigned ones, those on the
+// book's shelves but no branch's.
+func shelved(tx *gorm.DB, book *models.Book, branchID *uint) (int, error) {
+	if branchID != nil {
+		var stock models.BranchStock
+		err := tx.Where("book_id = ? AND branch_id = ?", book.ID, *branchID).Limit(1).Find(&stock).Error
+		return stock.AvailableCopies, err
+	}
+	var atBranches int
+	err := tx.Model(&models.BranchStock{}).
+		Where("book_id = ?", book.ID).
+		Select("COALESCE(SUM(available_copies), 0)").
+		Scan(&atBranches).Error
+	return book.AvailableCopies - atBranches, err
+}
+
+// addBranchStock adds to the copies of the book the branch holds, and to
+// those on its shelves.
+func addBranchStock(tx *gorm.DB, bookID uuid.UUID, branchID uint, quantity, available int) error {
+	err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.BranchStock{BookID: bookID, BranchID: branchID}).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.BranchStock{}).
+		Where("book_id = ? AND branch_id = ?", bookID, branchID).
+		Updates(map[string]interface{}{
+			"quantity":         gorm.Expr("quantity + ?", quantity),
+			"available_copies": gorm.Expr("available_copies + ?", available),
+			"updated_at":       time.Now(),
+		}).Error
+}
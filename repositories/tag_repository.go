@@ -0,0 +1,139 @@
+package repositories
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/audit"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TagCount is a tag with the number of live books carrying it.
+type TagCount struct {
+	models.Tag
+	Books int64 `json:"books"`
+}
+
+type TagRepository interface {
+	// Search returns up to limit tags whose names start with prefix, the
+	// most used first.
+	Search(ctx context.Context, prefix string, limit int) ([]TagCount, error)
+	FindByName(ctx context.Context, name string) (*models.Tag, error)
+	// FindOrCreate returns the tags with the given names, creating those
+	// that don't exist yet.
+	FindOrCreate(ctx context.Context, names []string) ([]models.Tag, error)
+	Rename(ctx context.Context, tag *models.Tag, name string) error
+	// Merge moves the books of from to into and deletes from, all at once.
+	Merge(ctx context.Context, from, into *models.Tag) error
+	AddToBook(ctx context.Context, book *models.Book, tags []models.Tag) error
+	RemoveFromBook(ctx context.Context, book *models.Book, tag *models.Tag) error
+}
+
+type tagRepository struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepository{db: db}
+}
+
+func (r *tagRepository) Search(ctx context.Context, prefix string, limit int) ([]TagCount, error) {
+	var tags []TagCount
+	query := r.db.WithContext(ctx).
+		Model(&models.Tag{}).
+		Select("tags.*, COUNT(books.id) AS books").
+		Joins("LEFT JOIN book_tags ON book_tags.tag_id = tags.id").
+		Joins("LEFT JOIN books ON books.id = book_tags.book_id AND books.deleted_at IS NULL").
+		Scopes(tenantScope(ctx)).
+		Group("tags.id").
+		Order("books DESC, tags.name").
+		Limit(limit)
+	if prefix != "" {
+		query = query.Where(`tags.name LIKE ? ESCAPE '\'`, escapeLike(prefix)+"%")
+	}
+	err := query.Scan(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) FindByName(ctx context.Context, name string) (*models.Tag, error) {
+	var tag models.Tag
+	if err := r.db.WithContext(ctx).Scopes(tenantScope(ctx)).Where("name = ?", name).First(&tag).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &tag, nil
+}
+
+func (r *tagRepository) FindOrCreate(ctx context.Context, names []string) ([]models.Tag, error) {
+	tenantID := tenancy.ID(ctx)
+	rows := make([]models.Tag, len(names))
+	for i, name := range names {
+		rows[i] = models.Tag{TenantID: tenantID, Name: name}
+	}
+	db := r.db.WithContext(ctx)
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	var tags []models.Tag
+	err := db.Scopes(tenantScope(ctx)).Where("name IN ?", names).Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (r *tagRepository) Rename(ctx context.Context, tag *models.Tag, name string) error {
+	return translate(r.db.WithContext(ctx).Model(tag).Update("name", name).Error)
+}
+
+func (r *tagRepository) Merge(ctx context.Context, from, into *models.Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tagged, both []string
+		if err := tx.Table("book_tags").Where("tag_id = ?", from.ID).Pluck("book_id", &tagged).Error; err != nil {
+			return err
+		}
+		if err := tx.Table("book_tags").Where("tag_id = ? AND book_id IN ?", into.ID, tagged).Pluck("book_id", &both).Error; err != nil {
+			return err
+		}
+
+		// Books carrying both keep a single link, to into.
+		err := tx.Exec(`INSERT INTO book_tags (book_id, tag_id)
+			SELECT book_id, ? FROM book_tags WHERE tag_id = ?
+			AND book_id NOT IN (SELECT book_id FROM book_tags WHERE tag_id = ?)`, into.ID, from.ID, into.ID).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM book_tags WHERE tag_id = ?", from.ID).Error; err != nil {
+			return err
+		}
+		retagged := slices.DeleteFunc(slices.Clone(tagged), func(book string) bool { return slices.Contains(both, book) })
+		if err := audit.Record(tx, models.AuditCreate, "book_tags", linkIDs(retagged, into.ID), models.JSONObject{}); err != nil {
+			return err
+		}
+		if err := audit.Record(tx, models.AuditDelete, "book_tags", linkIDs(tagged, from.ID), nil); err != nil {
+			return err
+		}
+		return tx.Delete(from).Error
+	})
+}
+
+// AddToBook and RemoveFromBook also touch the book, so that retagging it is
+// recorded as a change to it.
+func (r *tagRepository) AddToBook(ctx context.Context, book *models.Book, tags []models.Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(book).Omit("Tags.*").Association("Tags").Append(tags); err != nil {
+			return err
+		}
+		return tx.Model(book).Update("updated_at", time.Now()).Error
+	})
+}
+
+func (r *tagRepository) RemoveFromBook(ctx context.Context, book *models.Book, tag *models.Tag) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(book).Association("Tags").Delete(tag); err != nil {
+			return err
+		}
+		return tx.Model(book).Update("updated_at", time.Now()).Error
+	})
+}
@@ -0,0 +1,74 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TenantRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.Tenant, int64, error)
+	FindByID(ctx context.Context, id uint) (*models.Tenant, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+	Create(ctx context.Context, tenant *models.Tenant) error
+}
+
+type tenantRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantRepository(db *gorm.DB) TenantRepository {
+	return &tenantRepository{db: db}
+}
+
+func (r *tenantRepository) List(ctx context.Context, offset, limit int) ([]models.Tenant, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Tenant{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var tenants []models.Tenant
+	if err := r.db.WithContext(ctx).Order("id").Offset(offset).Limit(limit).Find(&tenants).Error; err != nil {
+		return nil, 0, err
+	}
+	return tenants, total, nil
+}
+
+func (r *tenantRepository) FindByID(ctx context.Context, id uint) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.WithContext(ctx).First(&tenant, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &tenant, nil
+}
+
+func (r *tenantRepository) FindBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	var tenant models.Tenant
+	if err := r.db.WithContext(ctx).First(&tenant, "slug = ?", slug).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &tenant, nil
+}
+
+func (r *tenantRepository) Create(ctx context.Context, tenant *models.Tenant) error {
+	return translate(r.db.WithContext(ctx).Create(tenant).Error)
+}
+
+// tenantScope limits a query on books, authors or loans to the rows of the
+// tenant ctx acts for. Without one, as in the maintenance jobs, it sees
+// every tenant's.
+//
+// Rows are only ever looked up through a scoped query before being changed
+// by value, so updates and deletes need no scope of their own.
+func tenantScope(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		id, ok := tenancy.FromContext(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "tenant_id"}, Value: id})
+	}
+}
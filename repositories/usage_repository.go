@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UsageFilter narrows usage reads to the days from From to To, inclusive,
+// as YYYY-MM-DD; a zero UserID matches every user.
+type UsageFilter struct {
+	UserID uint
+	From   string
+	To     string
+}
+
+// UsageTotal is the requests a user made with an API key, or with access
+// tokens when APIKeyID is 0, over the days of a report.
+type UsageTotal struct {
+	UserID   uint  `json:"user_id"`
+	APIKeyID uint  `json:"api_key_id"`
+	Requests int64 `json:"requests"`
+}
+
+type UsageRepository interface {
+	// Add adds the requests of each row to the counts of its user, API key
+	// and day.
+	Add(ctx context.Context, usage []models.DailyUsage) error
+	// Total returns the requests counted for the user or API key from day
+	// from to day to, exclusive.
+	Total(ctx context.Context, userID, apiKeyID uint, from, to string) (int64, error)
+	// List returns the matching counts, by day then API key.
+	List(ctx context.Context, filter UsageFilter) ([]models.DailyUsage, error)
+	// Report sums the matching counts per user and API key, those with the
+	// most requests first.
+	Report(ctx context.Context, filter UsageFilter, offset, limit int) ([]UsageTotal, int64, error)
+}
+
+type usageRepository struct {
+	db *gorm.DB
+}
+
+func NewUsageRepository(db *gorm.DB) UsageRepository {
+	return &usageRepository{db: db}
+}
+
+func (r *usageRepository) Add(ctx context.Context, usage []models.DailyUsage) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, row := range usage {
+			// Creating the row first and then incrementing works the same
+			// on every database, and whoever else adds to it meanwhile.
+			requests := row.Requests
+			row.Requests = 0
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&row).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&models.DailyUsage{}).
+				Where("user_id = ? AND api_key_id = ? AND day = ?", row.UserID, row.APIKeyID, row.Day).
+				UpdateColumn("requests", gorm.Expr("requests + ?", requests)).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *usageRepository) Total(ctx context.Context, userID, apiKeyID uint, from, to string) (int64, error) {
+	var total int64
+	err := r.db.WithContext(ctx).Model(&models.DailyUsage{}).
+		Where("user_id = ? AND api_key_id = ? AND day >= ? AND day < ?", userID, apiKeyID, from, to).
+		Select("COALESCE(SUM(requests), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+func (r *usageRepository) List(ctx context.Context, filter UsageFilter) ([]models.DailyUsage, error) {
+	var usage []models.DailyUsage
+	err := r.db.WithContext(ctx).Scopes(usageFilterScope(filter)).Order("day, api_key_id").Find(&usage).Error
+	return usage, err
+}
+
+func (r *usageRepository) Report(ctx context.Context, filter UsageFilter, offset, limit int) ([]UsageTotal, int64, error) {
+	grouped := r.db.WithContext(ctx).Model(&models.DailyUsage{}).
+		Scopes(usageFilterScope(filter)).
+		Select("user_id, api_key_id, SUM(requests) AS requests").
+		Group("user_id, api_key_id")
+
+	var total int64
+	if err := r.db.WithContext(ctx).Table("(?) AS totals", grouped).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var totals []UsageTotal
+	err := grouped.Order("requests DESC, user_id, api_key_id").Offset(offset).Limit(limit).Scan(&totals).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return totals, total, nil
+}
+
+func usageFilterScope(f UsageFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.UserID != 0 {
+			db = db.Where("user_id = ?", f.UserID)
+		}
+		if f.From != "" {
+			db = db.Where("day >= ?", f.From)
+		}
+		if f.To != "" {
+			db = db.Where("day <= ?", f.To)
+		}
+		return db
+	}
+}
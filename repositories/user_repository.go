@@ -0,0 +1,210 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserFilter narrows user listings; zero fields match everything.
+type UserFilter struct {
+	// Part of the email address, in any case.
+	Query    string
+	Role     string
+	Disabled *bool
+}
+
+type UserRepository interface {
+	List(ctx context.Context, filter UserFilter, offset, limit int) ([]models.User, int64, error)
+	FindByID(ctx context.Context, id uint) (*models.User, error)
+	FindByEmail(ctx context.Context, email string) (*models.User, error)
+	Count(ctx context.Context) (int64, error)
+	// LockAdmins returns the IDs of the admins of every tenant whose
+	// accounts aren't disabled, locking their rows until the end of the transaction ctx is
+	// in, so that concurrent changes to them wait and see this one's.
+	LockAdmins(ctx context.Context) ([]uint, error)
+	Create(ctx context.Context, user *models.User) error
+	Update(ctx context.Context, user *models.User, changes models.User) error
+	// SetTenant makes the user a member of the tenant, or of every tenant
+	// for nil.
+	SetTenant(ctx context.Context, user *models.User, tenantID *uint) error
+	// SetDisabled disables the account at at, or enables it for nil.
+	SetDisabled(ctx context.Context, user *models.User, at *time.Time) error
+	// Delete removes the account with its tokens, API keys, sign-in
+	// identities, reviews, reading lists and favorites.
+	Delete(ctx context.Context, user *models.User) error
+	// UseTOTPStep records that the code of step was used, unless a code of
+	// it or a later step already was, reporting whether it did.
+	UseTOTPStep(ctx context.Context, user *models.User, step int64) (bool, error)
+	// ClearTOTP removes the user's authenticator.
+	ClearTOTP(ctx context.Context, user *models.User) error
+	// AddFailedLogin counts a failed login to the account and returns the
+	// failures since the last successful one.
+	AddFailedLogin(ctx context.Context, user *models.User) (int, error)
+	Lock(ctx context.Context, user *models.User, until time.Time) error
+	// Unlock clears the account's lock and failed logins.
+	Unlock(ctx context.Context, user *models.User) error
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func NewUserRepository(db *gorm.DB) UserRepository {
+	return &userRepository{db: db}
+}
+
+// List returns matching users by email.
+func (r *userRepository) List(ctx context.Context, filter UserFilter, offset, limit int) ([]models.User, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.User{}).Scopes(userFilterScope(filter)).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := r.db.WithContext(ctx).Scopes(userFilterScope(filter)).
+		Order("email, id").
+		Offset(offset).
+		Limit(limit).
+		Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+func userFilterScope(f UserFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.Query != "" {
+			// Emails are stored lowercased.
+			db = db.Where(`email LIKE ? ESCAPE '\'`, "%"+escapeLike(strings.ToLower(f.Query))+"%")
+		}
+		if f.Role != "" {
+			db = db.Where("role = ?", f.Role)
+		}
+		if f.Disabled != nil {
+			if *f.Disabled {
+				db = db.Where("disabled_at IS NOT NULL")
+			} else {
+				db = db.Where("disabled_at IS NULL")
+			}
+		}
+		return db
+	}
+}
+
+func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error
+	return count, err
+}
+
+func (r *userRepository) LockAdmins(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.User{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("role = ? AND tenant_id IS NULL AND disabled_at IS NULL", models.RoleAdmin).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	return translate(r.db.WithContext(ctx).Create(user).Error)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User, changes models.User) error {
+	return r.db.WithContext(ctx).Model(user).Updates(changes).Error
+}
+
+func (r *userRepository) SetTenant(ctx context.Context, user *models.User, tenantID *uint) error {
+	return r.db.WithContext(ctx).Model(user).Update("tenant_id", tenantID).Error
+}
+
+func (r *userRepository) SetDisabled(ctx context.Context, user *models.User, at *time.Time) error {
+	return r.db.WithContext(ctx).Model(user).Update("disabled_at", at).Error
+}
+
+// Delete removes the rows referencing the user itself: the foreign keys
+// cascade, but SQLite only enforces them when told to.
+func (r *userRepository) Delete(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		lists := tx.Model(&models.ReadingList{}).Select("id").Where("user_id = ?", user.ID)
+		if err := tx.Where("reading_list_id IN (?)", lists).Delete(&models.ReadingListBook{}).Error; err != nil {
+			return err
+		}
+		owned := []interface{}{
+			&models.ReadingList{},
+			&models.Favorite{},
+			&models.Review{},
+			&models.APIKey{},
+			&models.UserIdentity{},
+			&models.RefreshToken{},
+			&models.UserToken{},
+			&models.RecoveryCode{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", user.ID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(user).Error
+	})
+}
+
+func (r *userRepository) UseTOTPStep(ctx context.Context, user *models.User, step int64) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", user.ID, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userRepository) ClearTOTP(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Model(user).Updates(map[string]interface{}{
+		"totp_secret":     "",
+		"totp_enabled_at": nil,
+		"totp_last_step":  0,
+	}).Error
+}
+
+// Login bookkeeping isn't a change to the account worth a new updated_at,
+// hence UpdateColumn.
+func (r *userRepository) AddFailedLogin(ctx context.Context, user *models.User) (int, error) {
+	db := r.db.WithContext(ctx)
+	err := db.Model(user).UpdateColumn("failed_logins", gorm.Expr("failed_logins + 1")).Error
+	if err != nil {
+		return 0, err
+	}
+	var failures int
+	err = db.Model(&models.User{}).Where("id = ?", user.ID).Pluck("failed_logins", &failures).Error
+	return failures, err
+}
+
+func (r *userRepository) Lock(ctx context.Context, user *models.User, until time.Time) error {
+	return r.db.WithContext(ctx).Model(user).UpdateColumn("locked_until", until).Error
+}
+
+func (r *userRepository) Unlock(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Model(user).UpdateColumns(map[string]interface{}{
+		"failed_logins": 0,
+		"locked_until":  nil,
+	}).Error
+}
@@ -0,0 +1,57 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type UserTokenRepository interface {
+	Create(ctx context.Context, token *models.UserToken) error
+	// FindByHash returns the token for purpose with the given hash, with
+	// its user.
+	FindByHash(ctx context.Context, purpose, hash string) (*models.UserToken, error)
+	// Use marks token used unless it already is, reporting whether it did,
+	// so of two requests using the same token only one gets to.
+	Use(ctx context.Context, token *models.UserToken, at time.Time) (bool, error)
+	// UseAll marks the user's unused tokens for purpose used.
+	UseAll(ctx context.Context, userID uint, purpose string, at time.Time) error
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) UserTokenRepository {
+	return &userTokenRepository{db: db}
+}
+
+func (r *userTokenRepository) Create(ctx context.Context, token *models.UserToken) error {
+	return translate(r.db.WithContext(ctx).Create(token).Error)
+}
+
+func (r *userTokenRepository) FindByHash(ctx context.Context, purpose, hash string) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("purpose = ? AND token_hash = ?", purpose, hash).
+		First(&token).Error
+	if err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *userTokenRepository) Use(ctx context.Context, token *models.UserToken, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.UserToken{}).
+		Where("id = ? AND used_at IS NULL", token.ID).
+		Update("used_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *userTokenRepository) UseAll(ctx context.Context, userID uint, purpose string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.UserToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", at).Error
+}
@@ -0,0 +1,103 @@
+package repositories
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	List(ctx context.Context, offset, limit int) ([]models.Webhook, int64, error)
+	FindByID(ctx context.Context, id uint) (*models.Webhook, error)
+	// Subscribed returns the webhooks events of the given type go to.
+	Subscribed(ctx context.Context, eventType string) ([]models.Webhook, error)
+	Create(ctx context.Context, webhook *models.Webhook) error
+	// Delete removes the webhook along with its delivery log.
+	Delete(ctx context.Context, webhook *models.Webhook) error
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ListDeliveries returns a webhook's delivery attempts, newest first.
+	ListDeliveries(ctx context.Context, webhookID uint, offset, limit int) ([]models.WebhookDelivery, int64, error)
+}
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) List(ctx context.Context, offset, limit int) ([]models.Webhook, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.Webhook{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var webhooks []models.Webhook
+	if err := r.db.WithContext(ctx).Order("id").Offset(offset).Limit(limit).Find(&webhooks).Error; err != nil {
+		return nil, 0, err
+	}
+	return webhooks, total, nil
+}
+
+func (r *webhookRepository) FindByID(ctx context.Context, id uint) (*models.Webhook, error) {
+	var webhook models.Webhook
+	if err := r.db.WithContext(ctx).First(&webhook, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &webhook, nil
+}
+
+// Subscriptions are stored as JSON, which the supported databases can't all
+// query the same way, so they are matched here; there are few webhooks.
+func (r *webhookRepository) Subscribed(ctx context.Context, eventType string) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	if err := r.db.WithContext(ctx).Order("id").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+
+	subscribed := webhooks[:0]
+	for _, webhook := range webhooks {
+		if webhook.Subscribed(eventType) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	return subscribed, nil
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, webhook *models.Webhook) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhook.ID).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(webhook).Error
+	})
+}
+
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Create(delivery).Error
+}
+
+func (r *webhookRepository) ListDeliveries(ctx context.Context, webhookID uint, offset, limit int) ([]models.WebhookDelivery, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.WebhookDelivery{}).Where("webhook_id = ?", webhookID).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Where("webhook_id = ?", webhookID).
+		Order("created_at DESC, id DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
@@ -0,0 +1,49 @@
+// Package requestid assigns every request a correlation ID, taken from the
+// incoming X-Request-ID header when the client (or a proxy) already set one.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Header = "X-Request-ID"
+
+// Gin context key holding the ID.
+const Key = "request_id"
+
+type contextKey struct{}
+
+// Accept client-supplied IDs only up to this length so they can't bloat logs.
+const maxLength = 128
+
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(Header)
+		if id == "" || len(id) > maxLength {
+			id = generate()
+		}
+
+		c.Set(Key, id)
+		c.Header(Header, id)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, id))
+		c.Next()
+	}
+}
+
+// FromContext returns the request ID stored by Middleware, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+func generate() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
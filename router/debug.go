@@ -0,0 +1,71 @@
+package router
+
+import (
+	"expvar"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/gin-gonic/gin"
+)
+
+// DebugProfilePath is the route of the profiles, which may take longer
+// than the request timeout to record: /debug/pprof/profile?seconds=30
+// records for 30 seconds.
+const DebugProfilePath = "/debug/pprof/*profile"
+
+// AdminChannelPath is the route of the admin WebSocket, which stays open.
+const AdminChannelPath = "/ws"
+
+var (
+	publishOnce sync.Once
+	started     = time.Now()
+)
+
+// registerDebug mounts net/http/pprof under /debug/pprof and the expvar
+// variables, runtime and database pool stats included, at /debug/vars.
+func registerDebug(debug *gin.RouterGroup) {
+	publishOnce.Do(publishRuntimeVars)
+
+	debug.GET("/vars", gin.WrapH(expvar.Handler()))
+	debug.GET("/pprof", func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, "/debug/pprof/") })
+	profile := func(c *gin.Context) {
+		switch c.Param("profile") {
+		case "/cmdline":
+			pprof.Cmdline(c.Writer, c.Request)
+		case "/profile":
+			pprof.Profile(c.Writer, c.Request)
+		case "/symbol":
+			pprof.Symbol(c.Writer, c.Request)
+		case "/trace":
+			pprof.Trace(c.Writer, c.Request)
+		default:
+			// The index, and the named profiles: heap, goroutine, allocs…
+			pprof.Index(c.Writer, c.Request)
+		}
+	}
+	debug.GET("/pprof/*profile", profile)
+	// go tool pprof looks symbols up by POST.
+	debug.POST("/pprof/*profile", profile)
+}
+
+// publishRuntimeVars adds to the memstats and cmdline expvar publishes by
+// itself.
+func publishRuntimeVars() {
+	expvar.Publish("goroutines", expvar.Func(func() any { return runtime.NumGoroutine() }))
+	expvar.Publish("uptime_seconds", expvar.Func(func() any { return int64(time.Since(started).Seconds()) }))
+	expvar.Publish("go_version", expvar.Func(func() any { return runtime.Version() }))
+	expvar.Publish("database", expvar.Func(func() any {
+		if models.DB == nil {
+			return nil
+		}
+		sqlDB, err := models.DB.DB()
+		if err != nil {
+			return nil
+		}
+		return sqlDB.Stats()
+	}))
+}
@@ -0,0 +1,51 @@
+package router
+
+import (
+	"net/http"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/gin-gonic/gin"
+)
+
+// Resources that used to be served at the root before /api/v1 existed.
+var legacyPrefixes = []string{"/auth", "/books", "/authors", "/categories"}
+
+// registerLegacy keeps the old unversioned URLs working: they answer with a
+// 308 (method and body are preserved) to the versioned URL, flagged as
+// deprecated so clients can find out they should migrate.
+func registerLegacy(r *gin.Engine) {
+	for _, prefix := range legacyPrefixes {
+		r.Any(prefix, legacyRedirect)
+		r.Any(prefix+"/*path", legacyRedirect)
+	}
+}
+
+func legacyRedirect(c *gin.Context) {
+	version := Versions[len(Versions)-1]
+	if requested := c.GetHeader(middlewares.VersionHeader); requested != "" {
+		if !supported(requested) {
+			apierrors.Abort(c, apierrors.BadRequest("Unsupported API version %s.").WithArgs(requested))
+			return
+		}
+		version = requested
+	}
+
+	target := "/api/" + version + c.Request.URL.Path
+	if c.Request.URL.RawQuery != "" {
+		target += "?" + c.Request.URL.RawQuery
+	}
+
+	c.Header("Deprecation", "true")
+	c.Header("Link", "<"+target+`>; rel="successor-version"`)
+	c.Redirect(http.StatusPermanentRedirect, target)
+}
+
+func supported(version string) bool {
+	for _, v := range Versions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+	"github.com/gin-gonic/gin"
+)
+
+// The methods routes are served with, in the order Allow headers list them.
+var methodOrder = []string{
+	http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace,
+}
+
+type originalRequestKey struct{}
+
+// Methods hands the handlers of a GET route serving a HEAD request the
+// request as it came, so they see a HEAD. It must be the router's first
+// middleware.
+func Methods() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if original, ok := c.Request.Context().Value(originalRequestKey{}).(*http.Request); ok {
+			c.Request = original
+		}
+	}
+}
+
+// allowList tells which methods the routes matching a URL are served with.
+type allowList []*allowedRoute
+
+type allowedRoute struct {
+	path     string
+	segments []string
+	methods  map[string]bool
+}
+
+// registerMethods answers, on every route, HEAD requests as the GET route
+// does but without the body, OPTIONS requests with an Allow header of the
+// methods the URL is served with, and requests with any other method with
+// 405 Method Not Allowed and the same header. It must be called once every
+// other route is registered.
+func registerMethods(r *gin.Engine) {
+	var routes allowList
+	byPath := map[string]*allowedRoute{}
+	for _, info := range r.Routes() {
+		route, ok := byPath[info.Path]
+		if !ok {
+			route = &allowedRoute{path: info.Path, segments: strings.Split(info.Path, "/"), methods: map[string]bool{}}
+			byPath[info.Path] = route
+			routes = append(routes, route)
+		}
+		route.methods[info.Method] = true
+	}
+
+	// HEAD requests go through the middlewares once, in the GET route.
+	detached := r.Group("/")
+	detached.Handlers = nil
+	for _, route := range routes {
+		if route.methods[http.MethodGet] && !route.methods[http.MethodHead] {
+			detached.HEAD(route.path, head(r))
+			route.methods[http.MethodHead] = true
+		}
+		if !route.methods[http.MethodOptions] {
+			// CORS preflight requests are answered by its middleware first.
+			r.OPTIONS(route.path, options(routes))
+			route.methods[http.MethodOptions] = true
+		}
+	}
+
+	r.HandleMethodNotAllowed = true
+	r.NoMethod(func(c *gin.Context) {
+		c.Header("Allow", routes.allow(c.Request.URL.Path))
+		apierrors.Abort(c, apierrors.MethodNotAllowed("%s is not allowed on %s").WithArgs(c.Request.Method, c.Request.URL.Path))
+	})
+}
+
+// head serves a HEAD request with the GET route of its URL. net/http leaves
+// the body its handlers write out of the response.
+func head(r *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		get := c.Request.WithContext(context.WithValue(c.Request.Context(), originalRequestKey{}, c.Request))
+		get.Method = http.MethodGet
+		c.Request = get
+		r.HandleContext(c)
+		// HandleContext leaves the context running the GET route's handlers
+		// from where this one was.
+		c.Abort()
+	}
+}
+
+func options(routes allowList) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Allow", routes.allow(c.Request.URL.Path))
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// allow returns the Allow header of path: the methods of every route
+// matching it, as the router would match it to each method's routes.
+func (l allowList) allow(path string) string {
+	segments := strings.Split(path, "/")
+	allowed := map[string]bool{}
+	for _, route := range l {
+		if route.matches(segments) {
+			for method := range route.methods {
+				allowed[method] = true
+			}
+		}
+	}
+	var methods []string
+	for _, method := range methodOrder {
+		if allowed[method] {
+			methods = append(methods, method)
+		}
+	}
+	return strings.Join(methods, ", ")
+}
+
+func (r *allowedRoute) matches(segments []string) bool {
+	for i, segment := range r.segments {
+		switch {
+		case strings.HasPrefix(segment, "*"):
+			return len(segments) > i
+		case i >= len(segments):
+			return false
+		case strings.HasPrefix(segment, ":"):
+			if segments[i] == "" {
+				return false
+			}
+		case segment != segments[i]:
+			return false
+		}
+	}
+	return len(segments) == len(r.segments)
+}
@@ -0,0 +1,323 @@
+// Package router maps URLs to controllers.
+//
+// Resource routes live under /api/<version>. Each version has its own
+// register function, so a future v2 can mount different handlers (or wrap
+// the v1 ones to reshape responses) while v1 keeps working unchanged.
+// Operational endpoints (probes, metrics, docs, debug) are not versioned.
+package router
+
+import (
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/adminui"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/docs"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/features"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpcache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/metrics"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/middlewares"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/render"
+	"github.com/gin-gonic/gin"
+)
+
+// Supported API versions, oldest first. The last one is what legacy
+// unversioned requests are redirected to by default.
+var Versions = []string{"v1"}
+
+type Controllers struct {
+	Batch           *controllers.BatchController
+	Books           *controllers.BookController
+	BookVersions    *controllers.BookVersionController
+	Authors         *controllers.AuthorController
+	Categories      *controllers.CategoryController
+	Covers          *controllers.CoverController
+	Barcodes        *controllers.BarcodeController
+	Authentication  *controllers.AuthController
+	Health          *controllers.HealthController
+	Audit           *controllers.AuditController
+	Changes         *controllers.ChangeController
+	Usage           *controllers.UsageController
+	Reviews         *controllers.ReviewController
+	Members         *controllers.MemberController
+	Loans           *controllers.LoanController
+	Notifications   *controllers.NotificationController
+	Holds           *controllers.HoldController
+	Fines           *controllers.FineController
+	Stock           *controllers.StockController
+	Lookup          *controllers.LookupController
+	Webhooks        *controllers.WebhookController
+	BookEvents      *controllers.BookEventController
+	AdminChannel    *controllers.AdminChannelController
+	Jobs            *controllers.JobController
+	APIKeys         *controllers.APIKeyController
+	TwoFactor       *controllers.TwoFactorController
+	Users           *controllers.UserController
+	Tenants         *controllers.TenantController
+	Stats           *controllers.StatsController
+	Publishers      *controllers.PublisherController
+	Branches        *controllers.BranchController
+	ReadingLists    *controllers.ReadingListController
+	Recommendations *controllers.RecommendationController
+	Series          *controllers.SeriesController
+	Tags            *controllers.TagController
+	Exports         *controllers.ExportController
+	Reports         *controllers.ReportController
+	QueryStats      *controllers.QueryStatsController
+	Features        *controllers.FeatureController
+	// GraphQL serves the catalog schema; see the graph package.
+	GraphQL http.Handler
+	// Revoked lists the access tokens logged out before they expired; nil
+	// revokes none.
+	Revoked auth.RevocationList
+	// Flags gates the routes of the features that can be turned off; nil
+	// leaves them all on.
+	Flags middlewares.FeatureFlags
+	// Idempotent guards the create endpoints, see the idempotency package;
+	// nil leaves them unguarded.
+	Idempotent gin.HandlerFunc
+	// HTTPCache sets the caching headers of the public reads, see the
+	// httpcache package; nil sets none.
+	HTTPCache *httpcache.Policies
+	// Debug mounts the profiling and runtime endpoints under /debug, for
+	// admins only.
+	Debug bool
+	// AdminUI serves the admin web UI, see the adminui package.
+	AdminUI bool
+}
+
+func Register(r *gin.Engine, authCfg config.AuthConfig, ctrl Controllers) {
+	r.GET("/healthz", ctrl.Health.Liveness)
+	r.GET("/readyz", ctrl.Health.Readiness)
+	r.GET("/metrics", metrics.Handler())
+	r.GET("/openapi.json", docs.Spec)
+	r.GET("/docs", docs.UI)
+	if ctrl.AdminUI {
+		r.GET(adminui.Path, func(c *gin.Context) { c.Redirect(http.StatusMovedPermanently, adminui.Path+"/") })
+		r.GET(adminui.Path+"/*filepath", adminui.Serve)
+	}
+
+	registerV1(r.Group("/api/v1", middlewares.APIVersion("v1")), authCfg, ctrl)
+	requireAuth := middlewares.RequireAuth(authCfg, ctrl.Revoked)
+	// Browsers can't set headers on WebSockets.
+	r.GET(AdminChannelPath, middlewares.TokenFromQuery("access_token"), requireAuth, middlewares.RequireRole(models.RoleAdmin), middlewares.RequireAllTenants(), ctrl.AdminChannel.Connect)
+	if ctrl.Debug {
+		registerDebug(r.Group("/debug", requireAuth, middlewares.RequireRole(models.RoleAdmin), middlewares.RequireAllTenants()))
+	}
+
+	// GraphQL evolves its schema in place instead of by version.
+	graphql := gin.WrapH(ctrl.GraphQL)
+	optionalAuth := middlewares.OptionalAuth(authCfg, ctrl.Revoked)
+	r.GET("/graphql", optionalAuth, graphql)
+	r.POST("/graphql", optionalAuth, graphql)
+	r.GET("/graphql/playground", gin.WrapH(playground.Handler("Bookstore API", "/graphql")))
+
+	registerLegacy(r)
+	registerMethods(r)
+}
+
+// crudEndpoints are those of a controllers.CRUDController.
+type crudEndpoints interface {
+	List(c *gin.Context)
+	Get(c *gin.Context)
+	Create(c *gin.Context)
+	Update(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+// registerCRUD serves a resource at path: anyone can read it, admins write
+// it. Reads are cached as reads of tables. Its other routes are registered
+// besides.
+func registerCRUD(public, admin *gin.RouterGroup, path string, endpoints crudEndpoints, idempotent gin.HandlerFunc, cache *httpcache.Policies, tables ...string) {
+	public.GET(path, cache.List(tables...), endpoints.List)
+	public.GET(path+"/:id", cache.Detail(tables...), endpoints.Get)
+	admin.POST(path, idempotent, endpoints.Create)
+	admin.PUT(path+"/:id", endpoints.Update)
+	admin.DELETE(path+"/:id", endpoints.Delete)
+}
+
+func registerV1(v1 *gin.RouterGroup, authCfg config.AuthConfig, ctrl Controllers) {
+	books, authors, categories := ctrl.Books, ctrl.Authors, ctrl.Categories
+	requireAuth := middlewares.RequireAuth(authCfg, ctrl.Revoked)
+	optionalAuth := middlewares.OptionalAuth(authCfg, ctrl.Revoked)
+	idempotent := ctrl.Idempotent
+	if idempotent == nil {
+		idempotent = func(c *gin.Context) { c.Next() }
+	}
+	feature := func(name string) gin.HandlerFunc {
+		if ctrl.Flags == nil {
+			return func(c *gin.Context) { c.Next() }
+		}
+		return middlewares.Feature(ctrl.Flags, name)
+	}
+	reviews, exports, recommendations := feature(features.Reviews), feature(features.Exports), feature(features.Recommendations)
+	cache := ctrl.HTTPCache
+	// Everything books are served with.
+	bookTables := []string{"books", "authors", "publishers", "categories", "book_categories", "tags", "book_tags"}
+
+	// Everything served at <collection>/:id, for responses to link to.
+	render.Link(models.Book{}, "book", v1.BasePath()+"/books")
+	render.Link(models.Author{}, "author", v1.BasePath()+"/authors")
+	render.Link(models.Category{}, "category", v1.BasePath()+"/categories")
+	render.Link(models.Member{}, "member", v1.BasePath()+"/members")
+	render.Link(models.Fine{}, "fine", v1.BasePath()+"/fines")
+	render.Link(models.Hold{}, "hold", v1.BasePath()+"/holds")
+	render.Link(models.Webhook{}, "webhook", v1.BasePath()+"/webhooks")
+	render.Link(models.APIKey{}, "api_key", v1.BasePath()+"/api-keys")
+	render.Link(models.Tenant{}, "tenant", v1.BasePath()+"/tenants")
+	render.Link(models.Publisher{}, "publisher", v1.BasePath()+"/publishers")
+	render.Link(models.Series{}, "series", v1.BasePath()+"/series")
+	render.Link(models.Branch{}, "branch", v1.BasePath()+"/branches")
+
+	// Operations authenticate themselves, with the headers of the batch.
+	v1.POST("/batch", ctrl.Batch.RunBatch)
+
+	v1.POST("/auth/register", ctrl.Authentication.Register)
+	v1.POST("/auth/login", ctrl.Authentication.Login)
+	v1.POST("/auth/refresh", ctrl.Authentication.Refresh)
+	v1.POST("/auth/logout", requireAuth, ctrl.Authentication.Logout)
+	v1.POST("/auth/verify", ctrl.Authentication.VerifyEmail)
+	v1.POST("/auth/verify/resend", requireAuth, ctrl.Authentication.ResendVerification)
+	v1.POST("/auth/forgot", ctrl.Authentication.ForgotPassword)
+	v1.POST("/auth/reset", ctrl.Authentication.ResetPassword)
+	v1.POST("/auth/2fa/enable", requireAuth, ctrl.TwoFactor.EnableTwoFactor)
+	v1.POST("/auth/2fa/verify", requireAuth, ctrl.TwoFactor.VerifyTwoFactor)
+	v1.POST("/auth/2fa/disable", requireAuth, ctrl.TwoFactor.DisableTwoFactor)
+	v1.GET("/auth/:provider/login", ctrl.Authentication.ProviderLogin)
+	v1.GET("/auth/:provider/callback", ctrl.Authentication.ProviderCallback)
+
+	v1.GET("/books", optionalAuth, cache.List(bookTables...), books.FindBooks)
+	v1.GET("/books/search", books.SearchBooks)
+	v1.GET("/books/export", books.ExportBooks)
+	v1.GET("/books/events", ctrl.BookEvents.StreamBookEvents)
+	v1.GET("/books/:id", cache.Detail(bookTables...), books.FindBook)
+	v1.GET("/books/:id/cover", ctrl.Covers.FindCover)
+	v1.GET("/books/:id/barcode", ctrl.Barcodes.FindBarcode)
+	v1.GET("/books/:id/reviews", reviews, cache.List("reviews"), ctrl.Reviews.FindReviews)
+	v1.GET("/books/:id/availability", ctrl.Stock.FindAvailability)
+	v1.GET("/books/:id/similar", recommendations, ctrl.Recommendations.FindSimilarBooks)
+	v1.POST("/books/:id/reviews", reviews, requireAuth, idempotent, ctrl.Reviews.CreateReview)
+	v1.GET("/categories/:id/books", cache.List(bookTables...), books.FindCategoryBooks)
+	v1.GET("/publishers/:id/books", cache.List(bookTables...), books.FindPublisherBooks)
+	v1.GET("/series", cache.List("series"), ctrl.Series.FindSeries)
+	v1.GET("/series/:id", cache.Detail("series"), ctrl.Series.FindOneSeries)
+	v1.GET("/series/:id/books", cache.List(append(bookTables, "series", "series_volumes")...), ctrl.Series.FindSeriesBooks)
+	v1.GET("/tags", cache.List("tags", "book_tags"), ctrl.Tags.FindTags)
+	v1.GET("/tags/:name/books", cache.List(bookTables...), books.FindTagBooks)
+	v1.GET("/shared/lists/:token", ctrl.ReadingLists.FindSharedReadingList)
+	v1.POST("/exports", exports, requireAuth, idempotent, ctrl.Exports.CreateExport)
+	v1.GET("/exports/:id", exports, requireAuth, ctrl.Exports.FindExport)
+	v1.GET("/exports/:id/download", exports, requireAuth, ctrl.Exports.DownloadExport)
+	v1.GET("/reports/catalog.pdf", exports, requireAuth, ctrl.Reports.CatalogReport)
+	v1.GET("/reports/categories/:id/catalog.pdf", exports, requireAuth, ctrl.Reports.CategoryReport)
+
+	me := v1.Group("/me", requireAuth)
+	me.GET("/lists", ctrl.ReadingLists.FindReadingLists)
+	me.POST("/lists", idempotent, ctrl.ReadingLists.CreateReadingList)
+	me.GET("/lists/:id", ctrl.ReadingLists.FindReadingList)
+	me.PUT("/lists/:id", ctrl.ReadingLists.UpdateReadingList)
+	me.DELETE("/lists/:id", ctrl.ReadingLists.DeleteReadingList)
+	me.GET("/lists/:id/books", ctrl.ReadingLists.FindReadingListBooks)
+	me.POST("/lists/:id/books/:book_id", ctrl.ReadingLists.AddReadingListBook)
+	me.DELETE("/lists/:id/books/:book_id", ctrl.ReadingLists.RemoveReadingListBook)
+	me.POST("/lists/:id/share", ctrl.ReadingLists.ShareReadingList)
+	me.DELETE("/lists/:id/share", ctrl.ReadingLists.UnshareReadingList)
+	me.GET("/favorites", ctrl.ReadingLists.FindFavorites)
+	me.PUT("/favorites/:book_id", ctrl.ReadingLists.AddFavorite)
+	me.DELETE("/favorites/:book_id", ctrl.ReadingLists.RemoveFavorite)
+	me.GET("/recommendations", recommendations, ctrl.Recommendations.FindRecommendations)
+	me.GET("/usage", ctrl.Usage.FindMyUsage)
+
+	admin := v1.Group("/", requireAuth, middlewares.RequireRole(models.RoleAdmin))
+	// What isn't any one tenant's.
+	deployment := admin.Group("/", middlewares.RequireAllTenants())
+	registerCRUD(v1, admin, "/authors", authors, idempotent, cache, "authors")
+	registerCRUD(v1, admin, "/categories", categories, idempotent, cache, "categories")
+	registerCRUD(v1, admin, "/publishers", ctrl.Publishers, idempotent, cache, "publishers")
+	registerCRUD(v1, admin, "/branches", ctrl.Branches, idempotent, cache, "branches")
+	admin.GET("/branches/:id/transfers", ctrl.Stock.FindBranchTransfers)
+	admin.POST("/books", idempotent, books.CreateBook)
+	admin.POST("/books/bulk", idempotent, books.CreateBooks)
+	admin.DELETE("/books/bulk", books.DeleteBooks)
+	admin.POST("/books/import", middlewares.BodyLimit(controllers.MaxImportSize), idempotent, books.ImportBooks)
+	admin.POST("/books/lookup", ctrl.Lookup.LookupBook)
+	admin.GET("/books/duplicates", books.FindDuplicateBooks)
+	admin.POST("/books/labels", ctrl.Barcodes.PrintLabels)
+	admin.PUT("/books/:id", books.UpdateBook)
+	admin.PATCH("/books/:id", books.PatchBook)
+	admin.DELETE("/books/:id", books.DeleteBook)
+	admin.POST("/books/:id/restore", books.RestoreBook)
+	admin.POST("/books/:id/merge", books.MergeBook)
+	admin.DELETE("/books/:id/permanent", books.DeleteBookPermanently)
+	admin.POST("/books/:id/cover", middlewares.BodyLimit(controllers.MaxCoverSize+1<<20), ctrl.Covers.UploadCover)
+	admin.POST("/books/:id/stock/adjust", ctrl.Stock.AdjustStock)
+	admin.POST("/books/:id/transfers", idempotent, ctrl.Stock.TransferStock)
+	admin.GET("/books/:id/transfers", ctrl.Stock.FindBookTransfers)
+	admin.POST("/books/:id/categories", books.AttachCategories)
+	admin.DELETE("/books/:id/categories/:category_id", books.DetachCategory)
+	admin.POST("/books/:id/tags", ctrl.Tags.TagBook)
+	admin.DELETE("/books/:id/tags/:name", ctrl.Tags.UntagBook)
+	admin.POST("/series", idempotent, ctrl.Series.CreateSeries)
+	admin.PUT("/series/:id", ctrl.Series.UpdateSeries)
+	admin.DELETE("/series/:id", ctrl.Series.DeleteSeries)
+	admin.POST("/series/:id/books", ctrl.Series.AddSeriesBook)
+	admin.DELETE("/series/:id/books/:book_id", ctrl.Series.RemoveSeriesBook)
+	admin.PUT("/tags/:name", ctrl.Tags.RenameTag)
+	admin.POST("/tags/:name/merge", ctrl.Tags.MergeTag)
+	deployment.GET("/audit", ctrl.Audit.FindAuditLogs)
+	admin.GET("/changes", ctrl.Changes.FindChanges)
+	deployment.GET("/usage", ctrl.Usage.FindUsageReport)
+	admin.GET("/books/:id/history", ctrl.Audit.FindBookHistory)
+	admin.GET("/books/:id/versions", ctrl.BookVersions.FindBookVersions)
+	admin.GET("/books/:id/versions/:v/diff", ctrl.BookVersions.FindBookVersionDiff)
+	admin.POST("/books/:id/revert/:v", ctrl.BookVersions.RevertBook)
+	admin.GET("/members", ctrl.Members.FindMembers)
+	admin.POST("/members", idempotent, ctrl.Members.CreateMember)
+	admin.GET("/members/:id", ctrl.Members.FindMember)
+	admin.GET("/members/:id/loans", ctrl.Loans.FindMemberLoans)
+	admin.GET("/members/:id/holds", ctrl.Holds.FindMemberHolds)
+	admin.GET("/members/:id/notification-preferences", ctrl.Notifications.FindPreference)
+	admin.PUT("/members/:id/notification-preferences", ctrl.Notifications.UpdatePreference)
+	admin.GET("/notifications", ctrl.Notifications.FindNotifications)
+	admin.GET("/fines", ctrl.Fines.FindFines)
+	admin.GET("/fines/:id", ctrl.Fines.FindFine)
+	admin.POST("/fines/:id/settle", ctrl.Fines.SettleFine)
+	admin.POST("/loans", idempotent, ctrl.Loans.CreateLoan)
+	admin.GET("/loans/overdue", ctrl.Loans.FindOverdueLoans)
+	admin.POST("/loans/:id/return", ctrl.Loans.ReturnLoan)
+	admin.POST("/books/:id/holds", idempotent, ctrl.Holds.PlaceHold)
+	admin.GET("/books/:id/holds", ctrl.Holds.FindBookHolds)
+	admin.GET("/holds/:id", ctrl.Holds.FindHold)
+	admin.DELETE("/holds/:id", ctrl.Holds.CancelHold)
+	deployment.GET("/webhooks", ctrl.Webhooks.FindWebhooks)
+	deployment.POST("/webhooks", idempotent, ctrl.Webhooks.CreateWebhook)
+	deployment.GET("/webhooks/:id", ctrl.Webhooks.FindWebhook)
+	deployment.DELETE("/webhooks/:id", ctrl.Webhooks.DeleteWebhook)
+	deployment.GET("/webhooks/:id/deliveries", ctrl.Webhooks.FindWebhookDeliveries)
+	deployment.GET("/api-keys", ctrl.APIKeys.FindAPIKeys)
+	deployment.POST("/api-keys", idempotent, ctrl.APIKeys.CreateAPIKey)
+	deployment.GET("/api-keys/:id", ctrl.APIKeys.FindAPIKey)
+	deployment.DELETE("/api-keys/:id", ctrl.APIKeys.RevokeAPIKey)
+	deployment.POST("/users/:id/unlock", ctrl.Users.UnlockUser)
+	deployment.GET("/admin/users", ctrl.Users.FindUsers)
+	deployment.GET("/admin/users/:id", ctrl.Users.FindUser)
+	deployment.PUT("/admin/users/:id/role", ctrl.Users.SetUserRole)
+	deployment.PUT("/admin/users/:id/tenant", ctrl.Users.SetUserTenant)
+	deployment.POST("/admin/users/:id/disable", ctrl.Users.DisableUser)
+	deployment.POST("/admin/users/:id/enable", ctrl.Users.EnableUser)
+	deployment.DELETE("/admin/users/:id", ctrl.Users.DeleteUser)
+	deployment.GET("/tenants", ctrl.Tenants.FindTenants)
+	deployment.POST("/tenants", idempotent, ctrl.Tenants.CreateTenant)
+	deployment.GET("/tenants/:id", ctrl.Tenants.FindTenant)
+	deployment.GET("/admin/stats", ctrl.Stats.GetStats)
+	deployment.GET("/admin/queries", ctrl.QueryStats.GetQueryStats)
+	deployment.GET("/admin/features", ctrl.Features.FindFeatures)
+	deployment.PUT("/admin/features/:name", ctrl.Features.SetFeature)
+	deployment.DELETE("/admin/features/:name", ctrl.Features.ResetFeature)
+	deployment.GET("/jobs", ctrl.Jobs.FindJobs)
+	deployment.GET("/jobs/:name", ctrl.Jobs.FindJob)
+	deployment.POST("/jobs/:name/run", ctrl.Jobs.RunJob)
+}
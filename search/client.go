@@ -0,0 +1,243 @@
+package search
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/google/uuid"
+)
+
+// Client talks to the REST API Elasticsearch and OpenSearch share. Books
+// are read and written through an alias, so Rebuild can fill a new index
+// and swap it in while the old one keeps serving.
+type Client struct {
+	http     *http.Client
+	baseURL  string
+	alias    string
+	username string
+	password string
+}
+
+func NewClient(cfg config.SearchConfig) *Client {
+	return &Client{
+		http:     &http.Client{Timeout: cfg.Timeout},
+		baseURL:  strings.TrimSuffix(cfg.URL, "/"),
+		alias:    cfg.Index,
+		username: cfg.Username,
+		password: cfg.Password,
+	}
+}
+
+type statusError struct {
+	status int
+	body   string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.status, e.body)
+}
+
+func isStatus(err error, status int) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.status == status
+}
+
+// do sends body, JSON unless it is NDJSON for _bulk, and decodes the JSON
+// response into out, when not nil. Statuses other than 2xx are errors.
+func (c *Client) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var reader io.Reader
+	contentType := "application/json"
+	switch body := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(body)
+		contentType = "application/x-ndjson"
+	case string:
+		reader = strings.NewReader(body)
+	default:
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s %s: %w", method, path, &statusError{status: resp.StatusCode, body: string(detail)})
+	}
+	if out == nil {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+// indexes returns the names of the indexes behind the alias, none if it
+// doesn't exist yet.
+func (c *Client) indexes(ctx context.Context) ([]string, error) {
+	var aliases map[string]json.RawMessage
+	err := c.do(ctx, http.MethodGet, "/_alias/"+url.PathEscape(c.alias), nil, &aliases)
+	if isStatus(err, http.StatusNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+// createIndex creates an empty index named after the alias and the time.
+func (c *Client) createIndex(ctx context.Context) (string, error) {
+	name := c.alias + "-" + time.Now().UTC().Format("20060102150405.000")
+	name = strings.ReplaceAll(name, ".", "")
+	return name, c.do(ctx, http.MethodPut, "/"+url.PathEscape(name), indexDefinition, nil)
+}
+
+// Ensure creates the index and its alias unless they exist.
+func (c *Client) Ensure(ctx context.Context) error {
+	existing, err := c.indexes(ctx)
+	if err != nil || len(existing) > 0 {
+		return err
+	}
+	name, err := c.createIndex(ctx)
+	if err != nil {
+		return err
+	}
+	return c.swap(ctx, nil, name)
+}
+
+// swap points the alias from the old indexes to the new one, atomically.
+func (c *Client) swap(ctx context.Context, old []string, name string) error {
+	var actions []map[string]interface{}
+	for _, index := range old {
+		actions = append(actions, map[string]interface{}{"remove": map[string]string{"index": index, "alias": c.alias}})
+	}
+	actions = append(actions, map[string]interface{}{"add": map[string]string{"index": name, "alias": c.alias}})
+	return c.do(ctx, http.MethodPost, "/_aliases", map[string]interface{}{"actions": actions}, nil)
+}
+
+// Index adds the document, or replaces it.
+func (c *Client) Index(ctx context.Context, doc Document) error {
+	return c.do(ctx, http.MethodPut, "/"+url.PathEscape(c.alias)+"/_doc/"+doc.ID.String(), doc, nil)
+}
+
+// Delete removes the book's document; documents that don't exist are
+// ignored.
+func (c *Client) Delete(ctx context.Context, id uuid.UUID) error {
+	err := c.do(ctx, http.MethodDelete, "/"+url.PathEscape(c.alias)+"/_doc/"+id.String(), nil, nil)
+	if isStatus(err, http.StatusNotFound) {
+		return nil
+	}
+	return err
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID    string          `json:"_id"`
+		Error json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk indexes the documents into the named index in one request.
+func (c *Client) bulk(ctx context.Context, index string, docs []Document) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, doc := range docs {
+		if err := encoder.Encode(map[string]interface{}{"index": map[string]string{"_id": doc.ID.String()}}); err != nil {
+			return err
+		}
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	var resp bulkResponse
+	if err := c.do(ctx, http.MethodPost, "/"+url.PathEscape(index)+"/_bulk", body.Bytes(), &resp); err != nil {
+		return err
+	}
+	if resp.Errors {
+		for _, item := range resp.Items {
+			for _, result := range item {
+				if len(result.Error) > 0 && string(result.Error) != "null" {
+					return fmt.Errorf("indexing book %s: %s", result.ID, result.Error)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Rebuild fills a new index with the documents fill passes to write, then
+// swaps it in for the current one, which is deleted. Searches are served by
+// the current index meanwhile; if fill fails the new index is dropped.
+// Books changed while fill reads them may be indexed as they were read.
+func (c *Client) Rebuild(ctx context.Context, fill func(write func([]Document) error) error) error {
+	old, err := c.indexes(ctx)
+	if err != nil {
+		return err
+	}
+	name, err := c.createIndex(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fill(func(docs []Document) error {
+		if len(docs) == 0 {
+			return nil
+		}
+		return c.bulk(ctx, name, docs)
+	})
+	if err == nil {
+		err = c.do(ctx, http.MethodPost, "/"+url.PathEscape(name)+"/_refresh", nil, nil)
+	}
+	if err == nil {
+		err = c.swap(ctx, old, name)
+	}
+	if err != nil {
+		c.do(context.WithoutCancel(ctx), http.MethodDelete, "/"+url.PathEscape(name), nil, nil)
+		return err
+	}
+
+	for _, index := range old {
+		if err := c.do(ctx, http.MethodDelete, "/"+url.PathEscape(index), nil, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package search keeps books in an Elasticsearch or OpenSearch index and
+// searches them there, with fuzzy matching and facets, for catalogs too
+// large for the database's own search.
+package search
+
+import (
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+)
+
+// Document is what the index holds of a book: the fields searched, filtered
+// and counted, not the book itself, which is loaded from the database.
+type Document struct {
+	ID          uuid.UUID  `json:"-"`
+	TenantID    uint       `json:"tenant_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description,omitempty"`
+	AuthorID    uint       `json:"author_id"`
+	Author      string     `json:"author,omitempty"`
+	PublisherID *uint      `json:"publisher_id,omitempty"`
+	Publisher   string     `json:"publisher,omitempty"`
+	Categories  []Category `json:"categories,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	// Omitted when unknown, so it isn't counted as year 0.
+	Year int    `json:"year,omitempty"`
+	ISBN string `json:"isbn,omitempty"`
+}
+
+type Category struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
+
+// NewDocument describes book, whose Author, Publisher, Categories and Tags
+// must be loaded.
+func NewDocument(book *models.Book) Document {
+	doc := Document{
+		ID:          book.ID,
+		TenantID:    book.TenantID,
+		Title:       book.Title,
+		Description: book.Description,
+		AuthorID:    book.AuthorID,
+		PublisherID: book.PublisherID,
+		Year:        book.Year,
+		ISBN:        book.ISBN,
+	}
+	if book.Author != nil {
+		doc.Author = book.Author.Name
+	}
+	if book.Publisher != nil {
+		doc.Publisher = book.Publisher.Name
+	}
+	for _, category := range book.Categories {
+		doc.Categories = append(doc.Categories, Category{ID: category.ID, Name: category.Name})
+	}
+	for _, tag := range book.Tags {
+		doc.Tags = append(doc.Tags, tag.Name)
+	}
+	return doc
+}
+
+// The associations NewDocument reads.
+var Preloads = []string{"Author", "Publisher", "Categories", "Tags"}
+
+// The index's settings and mappings. Text is matched without regard to case
+// or accents; the keyword subfields are what facets count.
+const indexDefinition = `{
+	"settings": {
+		"analysis": {
+			"analyzer": {
+				"folding": {"tokenizer": "standard", "filter": ["lowercase", "asciifolding"]}
+			}
+		}
+	},
+	"mappings": {
+		"dynamic": "strict",
+		"properties": {
+			"tenant_id": {"type": "integer"},
+			"title": {"type": "text", "analyzer": "folding"},
+			"description": {"type": "text", "analyzer": "folding"},
+			"author_id": {"type": "integer"},
+			"author": {"type": "text", "analyzer": "folding", "fields": {"keyword": {"type": "keyword"}}},
+			"publisher_id": {"type": "integer"},
+			"publisher": {"type": "text", "analyzer": "folding", "fields": {"keyword": {"type": "keyword"}}},
+			"categories": {
+				"type": "nested",
+				"properties": {"id": {"type": "integer"}, "name": {"type": "keyword"}}
+			},
+			"tags": {"type": "keyword"},
+			"year": {"type": "integer"},
+			"isbn": {"type": "keyword"}
+		}
+	}
+}`
@@ -0,0 +1,165 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+// How many changes may wait for the worker before new ones are dropped.
+const queueSize = 1000
+
+// How many books Reindex loads and indexes at a time.
+const batchSize = 500
+
+// Store is the part of repositories.BookRepository the indexer uses.
+type Store interface {
+	FindByID(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error)
+	Each(ctx context.Context, filter repositories.BookFilter, batchSize int, fn func([]models.Book) error, preloads ...string) error
+}
+
+type change struct {
+	id      uuid.UUID
+	deleted bool
+}
+
+// Indexer keeps the index in step with the catalog: it reindexes every book
+// a book event is about, from the database, in one background worker so the
+// changes to a book are applied in order. A change that fails to apply is
+// logged and dropped, as are changes still queued when the process stops;
+// Reindex repairs the drift, as it does for author, publisher and category
+// renames, which raise no book events.
+type Indexer struct {
+	client *Client
+	store  Store
+	queue  chan change
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewIndexer starts the worker; Stop it on shutdown.
+func NewIndexer(client *Client, store Store) *Indexer {
+	i := &Indexer{
+		client: client,
+		store:  store,
+		queue:  make(chan change, queueSize),
+		done:   make(chan struct{}),
+	}
+	i.wg.Add(1)
+	go i.work()
+	return i
+}
+
+// Publish queues the book the event is about. It is an events.Handler.
+func (i *Indexer) Publish(ctx context.Context, event events.Event) {
+	var next change
+	switch data := event.Data.(type) {
+	case *models.Book:
+		next = change{id: data.ID}
+	case events.DeletedBook:
+		next = change{id: data.ID, deleted: true}
+	default:
+		return
+	}
+
+	select {
+	case <-i.done:
+		return
+	default:
+	}
+	select {
+	case i.queue <- next:
+	default:
+		slog.WarnContext(ctx, "search index queue is full, dropping change", "book_id", next.id, "event_id", event.ID)
+	}
+}
+
+// Stop stops the worker, waiting until ctx is done for the change in
+// progress.
+func (i *Indexer) Stop(ctx context.Context) error {
+	close(i.done)
+	stopped := make(chan struct{})
+	go func() {
+		i.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (i *Indexer) work() {
+	defer i.wg.Done()
+	ensured := false
+	for {
+		select {
+		case <-i.done:
+			return
+		case next := <-i.queue:
+			ctx := context.Background()
+			if !ensured {
+				if err := i.client.Ensure(ctx); err != nil {
+					slog.Error("creating the search index failed", "error", err)
+					continue
+				}
+				ensured = true
+			}
+			if err := i.apply(ctx, next); err != nil {
+				slog.Error("updating the search index failed", "book_id", next.id, "error", err)
+			}
+		}
+	}
+}
+
+func (i *Indexer) apply(ctx context.Context, next change) error {
+	if next.deleted {
+		return i.client.Delete(ctx, next.id)
+	}
+	// The book is read as it is now, not as the event has it, so that
+	// changes processed late don't overwrite newer ones, and with the names
+	// the event doesn't carry.
+	book, err := i.store.FindByID(ctx, next.id, Preloads...)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return i.client.Delete(ctx, next.id)
+	}
+	if err != nil {
+		return err
+	}
+	return i.client.Index(ctx, NewDocument(book))
+}
+
+// Reindex rebuilds the index from every tenant's live books and swaps it in
+// for the current one. It runs as the reindex-search job and the reindex
+// command.
+func (i *Indexer) Reindex(ctx context.Context) error {
+	return Reindex(ctx, i.client, i.store)
+}
+
+// Reindex rebuilds the index behind client from the books in store.
+func Reindex(ctx context.Context, client *Client, store Store) error {
+	total := 0
+	err := client.Rebuild(ctx, func(write func([]Document) error) error {
+		return store.Each(ctx, repositories.BookFilter{}, batchSize, func(books []models.Book) error {
+			docs := make([]Document, len(books))
+			for j := range books {
+				docs[j] = NewDocument(&books[j])
+			}
+			total += len(docs)
+			return write(docs)
+		}, Preloads...)
+	})
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "search index rebuilt", "books", total)
+	return nil
+}
@@ -0,0 +1,244 @@
+package search
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+)
+
+// The most buckets a facet returns.
+const facetSize = 20
+
+type Query struct {
+	Text   string
+	Filter repositories.BookFilter
+	Offset int
+	Limit  int
+}
+
+type Hit struct {
+	ID    uuid.UUID
+	Score float64
+	// The title, author and description fragments that matched, with the
+	// terms wrapped in repositories.HighlightStart and HighlightStop and the
+	// rest HTML-escaped; fields that didn't match are missing.
+	Highlights map[string]string
+}
+
+type Results struct {
+	Hits   []Hit
+	Total  int64
+	Facets Facets
+}
+
+// Facets count the books matching a search, filters included, by author,
+// publisher, category, tag and decade, most common first except for
+// decades, which are in order.
+type Facets struct {
+	Authors    []Bucket `json:"authors"`
+	Publishers []Bucket `json:"publishers"`
+	Categories []Bucket `json:"categories"`
+	Tags       []Bucket `json:"tags"`
+	Decades    []Bucket `json:"decades"`
+}
+
+type Bucket struct {
+	// The author, publisher or category; unset for tags and decades.
+	ID uint `json:"id,omitempty"`
+	// Its name, the tag, or the decade's first year, e.g. "1990".
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Search matches q.Text against the title, author and description of the
+// books of the tenant ctx acts for, tolerating typos, and narrows the
+// results down to those matching q.Filter.
+func (c *Client) Search(ctx context.Context, q Query) (*Results, error) {
+	var resp searchResponse
+	path := "/" + url.PathEscape(c.alias) + "/_search"
+	if err := c.do(ctx, http.MethodPost, path, searchBody(ctx, q), &resp); err != nil {
+		return nil, err
+	}
+
+	results := &Results{Total: resp.Hits.Total.Value, Hits: make([]Hit, 0, len(resp.Hits.Hits))}
+	for _, hit := range resp.Hits.Hits {
+		id, err := uuid.Parse(hit.ID)
+		if err != nil {
+			continue
+		}
+		highlights := make(map[string]string, len(hit.Highlight))
+		for field, fragments := range hit.Highlight {
+			highlights[field] = strings.Join(fragments, " ... ")
+		}
+		results.Hits = append(results.Hits, Hit{ID: id, Score: hit.Score, Highlights: highlights})
+	}
+
+	aggs := resp.Aggregations
+	results.Facets = Facets{
+		Authors:    namedBuckets(aggs.Authors.Buckets),
+		Publishers: namedBuckets(aggs.Publishers.Buckets),
+		Categories: namedBuckets(aggs.Categories.IDs.Buckets),
+		Tags:       []Bucket{},
+		Decades:    []Bucket{},
+	}
+	for _, bucket := range aggs.Tags.Buckets {
+		results.Facets.Tags = append(results.Facets.Tags, Bucket{Value: bucket.Key, Count: bucket.Count})
+	}
+	for _, bucket := range aggs.Decades.Buckets {
+		results.Facets.Decades = append(results.Facets.Decades, Bucket{Value: strconv.Itoa(int(bucket.Key)), Count: bucket.Count})
+	}
+	return results, nil
+}
+
+func searchBody(ctx context.Context, q Query) map[string]interface{} {
+	must := []interface{}{
+		map[string]interface{}{"multi_match": map[string]interface{}{
+			"query":     q.Text,
+			"fields":    []string{"title^3", "author^2", "description"},
+			"fuzziness": "AUTO",
+			"operator":  "and",
+		}},
+	}
+	filter := []interface{}{}
+	term := func(field string, value interface{}) {
+		filter = append(filter, map[string]interface{}{"term": map[string]interface{}{field: value}})
+	}
+
+	if tenantID, ok := tenancy.FromContext(ctx); ok {
+		term("tenant_id", tenantID)
+	}
+	f := q.Filter
+	if f.AuthorID != 0 {
+		term("author_id", f.AuthorID)
+	}
+	if f.PublisherID != 0 {
+		term("publisher_id", f.PublisherID)
+	}
+	if f.CategoryID != 0 {
+		filter = append(filter, map[string]interface{}{"nested": map[string]interface{}{
+			"path":  "categories",
+			"query": map[string]interface{}{"term": map[string]interface{}{"categories.id": f.CategoryID}},
+		}})
+	}
+	if f.Tag != "" {
+		term("tags", f.Tag)
+	}
+	if f.Author != "" {
+		term("author.keyword", f.Author)
+	}
+	if f.TitleContains != "" {
+		must = append(must, map[string]interface{}{"match_phrase": map[string]interface{}{"title": f.TitleContains}})
+	}
+	if f.YearGte != nil || f.YearLte != nil {
+		years := map[string]interface{}{}
+		if f.YearGte != nil {
+			years["gte"] = *f.YearGte
+		}
+		if f.YearLte != nil {
+			years["lte"] = *f.YearLte
+		}
+		filter = append(filter, map[string]interface{}{"range": map[string]interface{}{"year": years}})
+	}
+
+	named := func(id, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"terms": map[string]interface{}{"field": id, "size": facetSize},
+			"aggs": map[string]interface{}{
+				"name": map[string]interface{}{"terms": map[string]interface{}{"field": name, "size": 1}},
+			},
+		}
+	}
+	fragment := map[string]interface{}{"number_of_fragments": 2}
+	return map[string]interface{}{
+		"query":            map[string]interface{}{"bool": map[string]interface{}{"must": must, "filter": filter}},
+		"from":             q.Offset,
+		"size":             q.Limit,
+		"_source":          false,
+		"track_total_hits": true,
+		"highlight": map[string]interface{}{
+			"pre_tags":  []string{repositories.HighlightStart},
+			"post_tags": []string{repositories.HighlightStop},
+			// Escapes the text around the tags, as the database search does.
+			"encoder": "html",
+			"fields": map[string]interface{}{
+				"title":       map[string]interface{}{"number_of_fragments": 0},
+				"author":      map[string]interface{}{"number_of_fragments": 0},
+				"description": fragment,
+			},
+		},
+		"aggs": map[string]interface{}{
+			"authors":    named("author_id", "author.keyword"),
+			"publishers": named("publisher_id", "publisher.keyword"),
+			"categories": map[string]interface{}{
+				"nested": map[string]interface{}{"path": "categories"},
+				"aggs":   map[string]interface{}{"ids": named("categories.id", "categories.name")},
+			},
+			"tags":    map[string]interface{}{"terms": map[string]interface{}{"field": "tags", "size": facetSize}},
+			"decades": map[string]interface{}{"histogram": map[string]interface{}{"field": "year", "interval": 10, "min_doc_count": 1}},
+		},
+	}
+}
+
+type searchResponse struct {
+	Hits struct {
+		Total struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []struct {
+			ID        string              `json:"_id"`
+			Score     float64             `json:"_score"`
+			Highlight map[string][]string `json:"highlight"`
+		} `json:"hits"`
+	} `json:"hits"`
+	Aggregations struct {
+		Authors    namedAggregation `json:"authors"`
+		Publishers namedAggregation `json:"publishers"`
+		Categories struct {
+			IDs namedAggregation `json:"ids"`
+		} `json:"categories"`
+		Tags struct {
+			Buckets []struct {
+				Key   string `json:"key"`
+				Count int64  `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"tags"`
+		Decades struct {
+			Buckets []struct {
+				Key   float64 `json:"key"`
+				Count int64   `json:"doc_count"`
+			} `json:"buckets"`
+		} `json:"decades"`
+	} `json:"aggregations"`
+}
+
+type namedAggregation struct {
+	Buckets []namedBucket `json:"buckets"`
+}
+
+type namedBucket struct {
+	Key   uint  `json:"key"`
+	Count int64 `json:"doc_count"`
+	Name  struct {
+		Buckets []struct {
+			Key string `json:"key"`
+		} `json:"buckets"`
+	} `json:"name"`
+}
+
+func namedBuckets(buckets []namedBucket) []Bucket {
+	out := make([]Bucket, 0, len(buckets))
+	for _, bucket := range buckets {
+		b := Bucket{ID: bucket.Key, Count: bucket.Count}
+		if len(bucket.Name.Buckets) > 0 {
+			b.Value = bucket.Name.Buckets[0].Key
+		}
+		out = append(out, b)
+	}
+	return out
+}
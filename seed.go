@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/seed"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/gin-gonic/gin"
+	"github.com/spf13/cobra"
+)
+
+// newSeedCommand builds `seed [--force]`, which prints how many records it
+// created. The sample accounts have well-known passwords, so it refuses to
+// run in release mode unless forced.
+func newSeedCommand() *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Load the sample catalog, accounts and members, skipping those already there",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := bootstrap()
+			if err != nil {
+				return err
+			}
+			if cfg.GinMode == gin.ReleaseMode && !force {
+				return errors.New("refusing to seed sample accounts in release mode; pass --force to seed anyway")
+			}
+			if err := app.Prepare(cfg); err != nil {
+				return err
+			}
+
+			books := services.NewBookService(repositories.NewBookRepository(models.DB), repositories.NewAuthorRepository(models.DB), repositories.NewPublisherRepository(models.DB), repositories.NewCategoryRepository(models.DB))
+			result, err := seed.Run(cmd.Context(), models.DB, books)
+			if err != nil {
+				return err
+			}
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(result)
+		},
+	}
+	cmd.Flags().BoolVar(&force, "force", false, "seed even in release mode")
+	return cmd
+}
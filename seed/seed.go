@@ -0,0 +1,221 @@
+// Package seed loads a sample catalog, staff accounts and members into the
+// database, for development and demos. The data is embedded from the YAML
+// files in fixtures.
+//
+// Seeding is idempotent: records are matched by their natural key
+// (category and author names, book ISBNs, user and member emails) and only
+// the missing ones are created, so running it again changes nothing, and
+// never overwrites edits made since. Catalog records go to the default
+// tenant.
+package seed
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+//go:embed fixtures/*.yaml
+var fixtures embed.FS
+
+type category struct {
+	Name string `yaml:"name"`
+}
+
+type author struct {
+	Name string `yaml:"name"`
+	Bio  string `yaml:"bio"`
+}
+
+type book struct {
+	Title       string   `yaml:"title"`
+	Author      string   `yaml:"author"`
+	Categories  []string `yaml:"categories"`
+	Year        int      `yaml:"year"`
+	ISBN        string   `yaml:"isbn"`
+	Quantity    int      `yaml:"quantity"`
+	Description string   `yaml:"description"`
+}
+
+type user struct {
+	Email    string `yaml:"email"`
+	Password string `yaml:"password"`
+	Role     string `yaml:"role"`
+}
+
+type member struct {
+	Name  string `yaml:"name"`
+	Email string `yaml:"email"`
+	Phone string `yaml:"phone"`
+}
+
+// Result counts the records a run created.
+type Result struct {
+	Categories int `json:"categories"`
+	Authors    int `json:"authors"`
+	Books      int `json:"books"`
+	Users      int `json:"users"`
+	Members    int `json:"members"`
+}
+
+// Run creates the fixtures missing from db. Books go through books, so
+// they get their slugs and normalized ISBNs as if created through the API.
+func Run(ctx context.Context, db *gorm.DB, books services.BookService) (Result, error) {
+	ctx = tenancy.NewContext(ctx, tenancy.DefaultID)
+	db = db.WithContext(ctx)
+	var result Result
+
+	var categories []category
+	if err := load("categories.yaml", &categories); err != nil {
+		return result, err
+	}
+	categoryIDs := make(map[string]uint, len(categories))
+	for _, fixture := range categories {
+		record := models.Category{Name: fixture.Name}
+		created, err := firstOrCreate(db, &record, "name = ?", fixture.Name)
+		if err != nil {
+			return result, fmt.Errorf("seed category %q: %w", fixture.Name, err)
+		}
+		categoryIDs[fixture.Name] = record.ID
+		result.Categories += created
+	}
+
+	var authors []author
+	if err := load("authors.yaml", &authors); err != nil {
+		return result, err
+	}
+	authorIDs := make(map[string]uint, len(authors))
+	for _, fixture := range authors {
+		record := models.Author{TenantID: tenancy.DefaultID, Name: fixture.Name, Bio: fixture.Bio}
+		created, err := firstOrCreate(db, &record, "tenant_id = ? AND name = ?", tenancy.DefaultID, fixture.Name)
+		if err != nil {
+			return result, fmt.Errorf("seed author %q: %w", fixture.Name, err)
+		}
+		authorIDs[fixture.Name] = record.ID
+		result.Authors += created
+	}
+
+	var bookFixtures []book
+	if err := load("books.yaml", &bookFixtures); err != nil {
+		return result, err
+	}
+	for _, fixture := range bookFixtures {
+		created, err := seedBook(ctx, db, books, fixture, authorIDs, categoryIDs)
+		if err != nil {
+			return result, fmt.Errorf("seed book %q: %w", fixture.Title, err)
+		}
+		result.Books += created
+	}
+
+	var users []user
+	if err := load("users.yaml", &users); err != nil {
+		return result, err
+	}
+	now := time.Now()
+	for _, fixture := range users {
+		record := models.User{Email: strings.ToLower(fixture.Email), Role: fixture.Role, EmailVerifiedAt: &now}
+		if err := record.SetPassword(fixture.Password); err != nil {
+			return result, err
+		}
+		created, err := firstOrCreate(db, &record, "email = ?", record.Email)
+		if err != nil {
+			return result, fmt.Errorf("seed user %q: %w", fixture.Email, err)
+		}
+		result.Users += created
+	}
+
+	var members []member
+	if err := load("members.yaml", &members); err != nil {
+		return result, err
+	}
+	for _, fixture := range members {
+		record := models.Member{Name: fixture.Name, Email: fixture.Email, Phone: fixture.Phone}
+		created, err := firstOrCreate(db, &record, "email_hash = ?", models.MemberEmailHash(fixture.Email))
+		if err != nil {
+			return result, fmt.Errorf("seed member %q: %w", fixture.Email, err)
+		}
+		result.Members += created
+	}
+	return result, nil
+}
+
+func load(name string, into interface{}) error {
+	data, err := fixtures.ReadFile("fixtures/" + name)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(data, into); err != nil {
+		return fmt.Errorf("fixtures/%s: %w", name, err)
+	}
+	return nil
+}
+
+// firstOrCreate loads the first record matching the query into record, or
+// creates record if there is none, returning 1 if it did.
+func firstOrCreate[T any](db *gorm.DB, record *T, query string, args ...interface{}) (int, error) {
+	var existing T
+	err := db.Where(query, args...).First(&existing).Error
+	if err == nil {
+		*record = existing
+		return 0, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+	if err := db.Create(record).Error; err != nil {
+		return 0, err
+	}
+	return 1, nil
+}
+
+func seedBook(ctx context.Context, db *gorm.DB, books services.BookService, fixture book, authorIDs, categoryIDs map[string]uint) (int, error) {
+	authorID, ok := authorIDs[fixture.Author]
+	if !ok {
+		return 0, fmt.Errorf("unknown author %q", fixture.Author)
+	}
+	ids := make([]uint, 0, len(fixture.Categories))
+	for _, name := range fixture.Categories {
+		id, ok := categoryIDs[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown category %q", name)
+		}
+		ids = append(ids, id)
+	}
+
+	// Deleted books count: a book deleted since the last run stays deleted.
+	var count int64
+	err := db.Unscoped().Model(&models.Book{}).
+		Where("tenant_id = ? AND isbn = ?", tenancy.DefaultID, isbn.Normalize(fixture.ISBN)).
+		Count(&count).Error
+	if err != nil || count > 0 {
+		return 0, err
+	}
+
+	record := models.Book{
+		Title:       fixture.Title,
+		Description: fixture.Description,
+		AuthorID:    authorID,
+		Year:        fixture.Year,
+		ISBN:        fixture.ISBN,
+		Quantity:    fixture.Quantity,
+	}
+	if err := books.Create(ctx, &record); err != nil {
+		return 0, err
+	}
+	if len(ids) > 0 {
+		if _, err := books.AttachCategories(ctx, record.ID, ids); err != nil {
+			return 0, err
+		}
+	}
+	return 1, nil
+}
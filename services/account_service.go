@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/mailer"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var (
+	ErrInvalidUserToken = errors.New("invalid, expired or used token")
+	ErrEmailVerified    = errors.New("email address already verified")
+)
+
+// AccountService runs the flows in which users prove they own their email
+// address with a one-time token mailed to it.
+type AccountService interface {
+	// SendVerification emails the user a link to verify their address,
+	// failing with ErrEmailVerified if it already is.
+	SendVerification(ctx context.Context, userID uint) error
+	VerifyEmail(ctx context.Context, token string) (*models.User, error)
+	// ForgotPassword emails a password reset link to the user with the
+	// address, if there is one. The result doesn't tell whether there is.
+	ForgotPassword(ctx context.Context, email string) error
+	// ResetPassword sets the password of the token's user, which verifies
+	// their address too, and revokes their refresh tokens.
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type accountService struct {
+	users         repositories.UserRepository
+	tokens        repositories.UserTokenRepository
+	refreshTokens repositories.RefreshTokenRepository
+	mail          mailer.Mailer
+	authCfg       config.AuthConfig
+	mailCfg       config.MailConfig
+}
+
+func NewAccountService(users repositories.UserRepository, tokens repositories.UserTokenRepository, refreshTokens repositories.RefreshTokenRepository, mail mailer.Mailer, authCfg config.AuthConfig, mailCfg config.MailConfig) AccountService {
+	return &accountService{users: users, tokens: tokens, refreshTokens: refreshTokens, mail: mail, authCfg: authCfg, mailCfg: mailCfg}
+}
+
+func (s *accountService) SendVerification(ctx context.Context, userID uint) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		return ErrEmailVerified
+	}
+
+	token, expires, err := s.issue(ctx, user, models.TokenPurposeVerifyEmail, s.authCfg.VerificationTokenTTL)
+	if err != nil {
+		return err
+	}
+	return s.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: "Confirm that this is your email address by opening this link:\n\n" +
+			s.link("/verify-email", token) + "\n\n" +
+			"It works until " + expires.UTC().Format(time.RFC1123) + ". If you didn't create an account, ignore this email.\n",
+	})
+}
+
+func (s *accountService) VerifyEmail(ctx context.Context, token string) (*models.User, error) {
+	stored, err := s.use(ctx, models.TokenPurposeVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	user := stored.User
+	if user.EmailVerifiedAt == nil {
+		now := time.Now()
+		if err := s.users.Update(ctx, user, models.User{EmailVerifiedAt: &now}); err != nil {
+			return nil, err
+		}
+		user.EmailVerifiedAt = &now
+	}
+	return user, nil
+}
+
+func (s *accountService) ForgotPassword(ctx context.Context, email string) error {
+	user, err := s.users.FindByEmail(ctx, strings.ToLower(email))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	token, expires, err := s.issue(ctx, user, models.TokenPurposeResetPassword, s.authCfg.ResetTokenTTL)
+	if err != nil {
+		return err
+	}
+	err = s.mail.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: "Choose a new password by opening this link:\n\n" +
+			s.link("/reset-password", token) + "\n\n" +
+			"It works once, until " + expires.UTC().Format(time.RFC1123) + ". If you didn't ask for a new password, ignore this email; your password hasn't changed.\n",
+	})
+	if err != nil {
+		// Failing the request would tell that the address has an account.
+		slog.ErrorContext(ctx, "sending password reset email", "user_id", user.ID, "error", err)
+	}
+	return nil
+}
+
+func (s *accountService) ResetPassword(ctx context.Context, token, password string) error {
+	stored, err := s.use(ctx, models.TokenPurposeResetPassword, token)
+	if err != nil {
+		return err
+	}
+	user := stored.User
+
+	now := time.Now()
+	changes := models.User{EmailVerifiedAt: user.EmailVerifiedAt}
+	if changes.EmailVerifiedAt == nil {
+		changes.EmailVerifiedAt = &now
+	}
+	if err := changes.SetPassword(password); err != nil {
+		return err
+	}
+	if err := s.users.Update(ctx, user, changes); err != nil {
+		return err
+	}
+	// Other reset links are no longer needed, and whoever knew the old
+	// password is logged out.
+	if err := s.tokens.UseAll(ctx, user.ID, models.TokenPurposeResetPassword, now); err != nil {
+		return err
+	}
+	return s.refreshTokens.RevokeUser(ctx, user.ID, now)
+}
+
+// issue stores a new token for user and returns it with its expiry.
+func (s *accountService) issue(ctx context.Context, user *models.User, purpose string, ttl time.Duration) (string, time.Time, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", time.Time{}, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	stored := models.UserToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: hashSecret(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := s.tokens.Create(ctx, &stored); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, stored.ExpiresAt, nil
+}
+
+// use marks the token for purpose used and returns it, with its user.
+func (s *accountService) use(ctx context.Context, purpose, token string) (*models.UserToken, error) {
+	stored, err := s.tokens.FindByHash(ctx, purpose, hashSecret(token))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidUserToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !stored.Usable(time.Now()) || stored.User == nil {
+		return nil, ErrInvalidUserToken
+	}
+	used, err := s.tokens.Use(ctx, stored, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !used {
+		return nil, ErrInvalidUserToken
+	}
+	return stored, nil
+}
+
+func (s *accountService) link(path, token string) string {
+	return fmt.Sprintf("%s%s?%s", strings.TrimSuffix(s.mailCfg.LinkBaseURL, "/"), path, url.Values{"token": {token}}.Encode())
+}
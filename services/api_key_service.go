@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var (
+	ErrInvalidAPIKey     = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyMustSign    = errors.New("API key must sign requests")
+	ErrAPIKeyMustNotSign = errors.New("API key is sent with requests")
+)
+
+// Every key starts with this, so leaked keys are easy to scan for.
+const apiKeyPrefix = "bk_"
+
+// Last-used times are only written when they are older than this, so busy
+// keys don't cost a write per request.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyService interface {
+	List(ctx context.Context, offset, limit int) ([]models.APIKey, int64, error)
+	Get(ctx context.Context, id uint) (*models.APIKey, error)
+	// Issue generates a key for key, a filled-in APIKey without one, and
+	// returns it. It is the only time the key is known; only its hash is
+	// kept, and an encrypted copy for signed keys.
+	Issue(ctx context.Context, key *models.APIKey) (string, error)
+	Revoke(ctx context.Context, id uint) (*models.APIKey, error)
+	// Authenticate returns the active key matching secret, with its user,
+	// and records that it was used. It returns ErrInvalidAPIKey for unknown,
+	// expired and revoked keys and those of disabled accounts, and
+	// ErrAPIKeyMustSign for signed ones.
+	Authenticate(ctx context.Context, secret string) (*models.APIKey, error)
+	// AuthenticateSigned is Authenticate for a request signed by the key
+	// with the given ID; the caller checks the signature with its Secret.
+	// It returns ErrAPIKeyMustNotSign for keys that aren't signed.
+	AuthenticateSigned(ctx context.Context, id uint) (*models.APIKey, error)
+}
+
+type apiKeyService struct {
+	keys repositories.APIKeyRepository
+}
+
+func NewAPIKeyService(keys repositories.APIKeyRepository) APIKeyService {
+	return &apiKeyService{keys: keys}
+}
+
+func (s *apiKeyService) List(ctx context.Context, offset, limit int) ([]models.APIKey, int64, error) {
+	return s.keys.List(ctx, offset, limit)
+}
+
+func (s *apiKeyService) Get(ctx context.Context, id uint) (*models.APIKey, error) {
+	return s.keys.FindByID(ctx, id)
+}
+
+func (s *apiKeyService) Issue(ctx context.Context, key *models.APIKey) (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+	key.Prefix = secret[:len(apiKeyPrefix)+8]
+	key.KeyHash = hashSecret(secret)
+	if key.Signed {
+		key.Secret = secret
+	}
+	if err := s.keys.Create(ctx, key); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, err := s.keys.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, nil
+	}
+	now := time.Now()
+	if err := s.keys.Revoke(ctx, key, now); err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &now
+	return key, nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, secret string) (*models.APIKey, error) {
+	key, err := s.keys.FindByHash(ctx, hashSecret(secret))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if key.Signed {
+		return nil, ErrAPIKeyMustSign
+	}
+	return s.use(ctx, key)
+}
+
+func (s *apiKeyService) AuthenticateSigned(ctx context.Context, id uint) (*models.APIKey, error) {
+	key, err := s.keys.FindByID(ctx, id, "User")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !key.Signed || key.Secret == "" {
+		return nil, ErrAPIKeyMustNotSign
+	}
+	return s.use(ctx, key)
+}
+
+// use checks the key can be used now and records that it was.
+func (s *apiKeyService) use(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	now := time.Now()
+	if !key.Active(now) || key.User == nil || key.User.Disabled() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.keys.Touch(ctx, key, now); err != nil {
+			slog.WarnContext(ctx, "recording api key use", "api_key_id", key.ID, "error", err)
+		}
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// hashSecret hashes the API keys and tokens stored. They are random
+// enough that a fast hash is as good as a password hash, and it can be
+// looked up.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+type AuditService interface {
+	List(ctx context.Context, filter repositories.AuditFilter, offset, limit int) ([]models.AuditLog, int64, error)
+	ListAfter(ctx context.Context, filter repositories.AuditFilter, after *repositories.AuditCursor, limit int) ([]models.AuditLog, *repositories.AuditCursor, error)
+	// BookHistory lists the changes to one book, newest first, failing with
+	// repositories.ErrNotFound if the book never existed.
+	BookHistory(ctx context.Context, id uuid.UUID, offset, limit int) ([]models.AuditLog, int64, error)
+}
+
+type auditService struct {
+	audit repositories.AuditRepository
+	books repositories.BookRepository
+}
+
+func NewAuditService(audit repositories.AuditRepository, books repositories.BookRepository) AuditService {
+	return &auditService{audit: audit, books: books}
+}
+
+func (s *auditService) List(ctx context.Context, filter repositories.AuditFilter, offset, limit int) ([]models.AuditLog, int64, error) {
+	return s.audit.List(ctx, filter, offset, limit)
+}
+
+func (s *auditService) ListAfter(ctx context.Context, filter repositories.AuditFilter, after *repositories.AuditCursor, limit int) ([]models.AuditLog, *repositories.AuditCursor, error) {
+	return s.audit.ListAfter(ctx, filter, after, limit)
+}
+
+func (s *auditService) BookHistory(ctx context.Context, id uuid.UUID, offset, limit int) ([]models.AuditLog, int64, error) {
+	entries, total, err := s.audit.List(ctx, repositories.AuditFilter{Entity: "books", EntityID: id.String()}, offset, limit)
+	if err != nil || total > 0 {
+		return entries, total, err
+	}
+	// Books deleted permanently keep their history; only unknown IDs 404.
+	if _, err := s.books.FindByIDWithDeleted(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,389 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/oauth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var (
+	ErrEmailTaken          = errors.New("email already registered")
+	ErrInvalidCredentials  = errors.New("invalid email or password")
+	ErrInvalidRefreshToken = errors.New("invalid, expired or revoked refresh token")
+	ErrUnknownProvider     = errors.New("unknown or disabled login provider")
+	ErrProviderLogin       = errors.New("signing in with the provider failed")
+	ErrAccountDisabled     = errors.New("account disabled")
+)
+
+// LockedOutError is returned for logins refused after too many failed
+// ones, to the account or from the client's address, until Until.
+type LockedOutError struct {
+	Until time.Time
+}
+
+func (e *LockedOutError) Error() string {
+	return "too many failed logins, try again at " + e.Until.UTC().Format(time.RFC3339)
+}
+
+// LoginAttempt is what a client logs in with.
+type LoginAttempt struct {
+	Email    string
+	Password string
+	// An authenticator or recovery code, for users with two-factor
+	// authentication enabled.
+	Code string
+	// The client's address, to count failed logins from it.
+	IP string
+}
+
+type Token struct {
+	Token     string `json:"token"`
+	ExpiresIn int    `json:"expires_in"`
+	// Exchanged for the next token, and the next refresh token, at
+	// /auth/refresh. Each is good for one use.
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+type AuthService interface {
+	Register(ctx context.Context, email, password string) (*models.User, error)
+	// Login fails with ErrSecondFactorRequired for users with two-factor
+	// authentication enabled if the attempt has no code. Too many failed
+	// logins to an account, or from an address, fail with a
+	// *LockedOutError for a while. Disabled accounts fail with
+	// ErrAccountDisabled, here and in Refresh and ProviderLogin.
+	Login(ctx context.Context, attempt LoginAttempt) (*Token, error)
+	// Refresh exchanges a refresh token for a new access token and refresh
+	// token. A refresh token that was already used revokes every token
+	// descending from the same login; whoever used it first may have stolen
+	// it.
+	Refresh(ctx context.Context, refreshToken string) (*Token, error)
+	// Logout revokes the caller's access token and, if given, the refresh
+	// token issued with it; everywhere revokes all the caller's refresh
+	// tokens instead, logging out their other sessions once their access
+	// tokens expire.
+	Logout(ctx context.Context, identity auth.Identity, refreshToken string, everywhere bool) error
+	// ProviderLoginURL is where to send users signing in with provider, who
+	// come back to its callback with state and a code to pass to
+	// ProviderLogin.
+	ProviderLoginURL(provider, state string) (string, error)
+	// ProviderLogin signs in the user the provider's code identifies, like
+	// Login does. Users are matched by their account at the provider, then
+	// by its verified email address; those matching no one get an account
+	// without a password. The provider's sign-in stands in for a second
+	// factor.
+	ProviderLogin(ctx context.Context, provider, code string) (*Token, error)
+}
+
+type authService struct {
+	users         repositories.UserRepository
+	identities    repositories.UserIdentityRepository
+	refreshTokens repositories.RefreshTokenRepository
+	revoked       auth.RevocationList
+	providers     map[string]oauth.Provider
+	twoFactor     TwoFactorService
+	failures      auth.FailureCounter
+	cfg           config.AuthConfig
+}
+
+func NewAuthService(users repositories.UserRepository, identities repositories.UserIdentityRepository, refreshTokens repositories.RefreshTokenRepository, revoked auth.RevocationList, providers map[string]oauth.Provider, twoFactor TwoFactorService, failures auth.FailureCounter, cfg config.AuthConfig) AuthService {
+	return &authService{users: users, identities: identities, refreshTokens: refreshTokens, revoked: revoked, providers: providers, twoFactor: twoFactor, failures: failures, cfg: cfg}
+}
+
+// Register creates a reader account. The first account ever registered
+// becomes an admin so a fresh install can be bootstrapped.
+func (s *authService) Register(ctx context.Context, email, password string) (*models.User, error) {
+	email = strings.ToLower(email)
+
+	if _, err := s.users.FindByEmail(ctx, email); err == nil {
+		return nil, ErrEmailTaken
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	user := models.User{Email: email}
+	if err := user.SetPassword(password); err != nil {
+		return nil, err
+	}
+	if err := s.create(ctx, &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// create creates a reader account, or an admin one for the first account.
+func (s *authService) create(ctx context.Context, user *models.User) error {
+	count, err := s.users.Count(ctx)
+	if err != nil {
+		return err
+	}
+	user.Role = models.RoleReader
+	if count == 0 {
+		user.Role = models.RoleAdmin
+	}
+	return s.users.Create(ctx, user)
+}
+
+// Login checks the credentials and returns a signed access token, with the
+// first refresh token of a new family.
+func (s *authService) Login(ctx context.Context, attempt LoginAttempt) (*Token, error) {
+	if err := s.checkIP(ctx, attempt.IP); err != nil {
+		return nil, err
+	}
+	user, err := s.users.FindByEmail(ctx, strings.ToLower(attempt.Email))
+	if errors.Is(err, repositories.ErrNotFound) {
+		s.addIPFailure(ctx, attempt.IP)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Checked before the password so that guessing it goes nowhere while
+	// the account is locked.
+	if user.Locked(time.Now()) {
+		return nil, &LockedOutError{Until: *user.LockedUntil}
+	}
+	if !user.CheckPassword(attempt.Password) {
+		return nil, s.failed(ctx, user, attempt.IP, ErrInvalidCredentials)
+	}
+	if user.TOTPEnabled() {
+		err := s.twoFactor.Check(ctx, user, attempt.Code)
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			return nil, s.failed(ctx, user, attempt.IP, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if user.FailedLogins > 0 || user.LockedUntil != nil {
+		if err := s.users.Unlock(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return s.login(ctx, user)
+}
+
+// checkIP refuses logins from ip once too many failed within the window.
+// The count is a safeguard, not worth refusing every login over, so
+// failing to read it lets the login go ahead.
+func (s *authService) checkIP(ctx context.Context, ip string) error {
+	if s.cfg.MaxIPFailedLogins == 0 || ip == "" {
+		return nil
+	}
+	count, ends, err := s.failures.Count(ctx, "ip:"+ip)
+	if err != nil {
+		slog.WarnContext(ctx, "counting failed logins failed, allowing the login", "ip", ip, "error", err)
+		return nil
+	}
+	if count >= s.cfg.MaxIPFailedLogins {
+		return &LockedOutError{Until: ends}
+	}
+	return nil
+}
+
+func (s *authService) addIPFailure(ctx context.Context, ip string) {
+	if s.cfg.MaxIPFailedLogins == 0 || ip == "" {
+		return
+	}
+	if err := s.failures.Add(ctx, "ip:"+ip, s.cfg.IPFailureWindow); err != nil {
+		slog.WarnContext(ctx, "counting failed login failed", "ip", ip, "error", err)
+	}
+}
+
+// failed counts a failed login to user from ip and returns err, locking
+// the account once MaxFailedLogins failed in a row: for LockoutDuration,
+// doubled with each failure past that, up to MaxLockoutDuration.
+func (s *authService) failed(ctx context.Context, user *models.User, ip string, err error) error {
+	s.addIPFailure(ctx, ip)
+	if s.cfg.MaxFailedLogins == 0 {
+		return err
+	}
+	count, addErr := s.users.AddFailedLogin(ctx, user)
+	if addErr != nil {
+		return addErr
+	}
+	if count < s.cfg.MaxFailedLogins {
+		return err
+	}
+
+	lockout := s.cfg.LockoutDuration
+	for i := s.cfg.MaxFailedLogins; i < count && lockout < s.cfg.MaxLockoutDuration; i++ {
+		lockout *= 2
+	}
+	lockout = min(lockout, s.cfg.MaxLockoutDuration)
+	until := time.Now().Add(lockout)
+	if err := s.users.Lock(ctx, user, until); err != nil {
+		return err
+	}
+	slog.WarnContext(ctx, "locked account after failed logins", "user_id", user.ID, "failed_logins", count, "locked_until", until)
+	return &LockedOutError{Until: until}
+}
+
+func (s *authService) ProviderLoginURL(provider, state string) (string, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+	return p.AuthCodeURL(state), nil
+}
+
+func (s *authService) ProviderLogin(ctx context.Context, provider, code string) (*Token, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	profile, err := p.Profile(ctx, code)
+	if err != nil {
+		slog.WarnContext(ctx, "provider login failed", "provider", provider, "error", err)
+		return nil, fmt.Errorf("%w (%s): %w", ErrProviderLogin, provider, err)
+	}
+
+	identity, err := s.identities.FindBySubject(ctx, provider, profile.Subject)
+	if err == nil && identity.User != nil {
+		return s.login(ctx, identity.User)
+	}
+	if err != nil && !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	// The provider vouches for the address, so an account with it is the
+	// same person's.
+	email := strings.ToLower(profile.Email)
+	now := time.Now()
+	user, err := s.users.FindByEmail(ctx, email)
+	switch {
+	case errors.Is(err, repositories.ErrNotFound):
+		user = &models.User{Email: email, EmailVerifiedAt: &now}
+		err = s.create(ctx, user)
+	case err == nil && user.EmailVerifiedAt == nil:
+		err = s.users.Update(ctx, user, models.User{EmailVerifiedAt: &now})
+	}
+	if err != nil {
+		return nil, err
+	}
+	link := models.UserIdentity{UserID: user.ID, Provider: provider, Subject: profile.Subject, Email: profile.Email}
+	if err := s.identities.Create(ctx, &link); err != nil {
+		return nil, err
+	}
+	return s.login(ctx, user)
+}
+
+// login issues a token for user with the first refresh token of a new
+// family.
+func (s *authService) login(ctx context.Context, user *models.User) (*Token, error) {
+	family := make([]byte, 16)
+	if _, err := rand.Read(family); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, base64.RawURLEncoding.EncodeToString(family))
+}
+
+func (s *authService) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	stored, err := s.refreshTokens.FindByHash(ctx, hashSecret(refreshToken))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if stored.RevokedAt != nil {
+		return nil, s.revokeFamily(ctx, stored, now)
+	}
+	if !stored.Active(now) || stored.User == nil {
+		return nil, ErrInvalidRefreshToken
+	}
+	revoked, err := s.refreshTokens.Revoke(ctx, stored, now)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		// Used by another request in the meantime.
+		return nil, s.revokeFamily(ctx, stored, now)
+	}
+	return s.issue(ctx, stored.User, stored.Family)
+}
+
+func (s *authService) Logout(ctx context.Context, identity auth.Identity, refreshToken string, everywhere bool) error {
+	now := time.Now()
+	if identity.TokenID != "" {
+		if err := s.revoked.Revoke(ctx, identity.TokenID, identity.ExpiresAt); err != nil {
+			return err
+		}
+	}
+	if everywhere {
+		return s.refreshTokens.RevokeUser(ctx, identity.UserID, now)
+	}
+	if refreshToken == "" {
+		return nil
+	}
+
+	stored, err := s.refreshTokens.FindByHash(ctx, hashSecret(refreshToken))
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if stored.UserID != identity.UserID {
+		return nil
+	}
+	return s.refreshTokens.RevokeFamily(ctx, stored.Family, now)
+}
+
+// issue returns an access token for user with the next refresh token of
+// family, unless the account is disabled.
+func (s *authService) issue(ctx context.Context, user *models.User, family string) (*Token, error) {
+	if user.Disabled() {
+		return nil, ErrAccountDisabled
+	}
+	token, err := auth.GenerateToken(s.cfg, user.ID, user.Role, user.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(random)
+	stored := models.RefreshToken{
+		TokenHash: hashSecret(refreshToken),
+		Family:    family,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(s.cfg.RefreshTokenTTL),
+	}
+	if err := s.refreshTokens.Create(ctx, &stored); err != nil {
+		return nil, err
+	}
+
+	return &Token{
+		Token:            token,
+		ExpiresIn:        int(s.cfg.TokenTTL.Seconds()),
+		RefreshToken:     refreshToken,
+		RefreshExpiresIn: int(s.cfg.RefreshTokenTTL.Seconds()),
+	}, nil
+}
+
+// revokeFamily revokes the family of stored, one of whose tokens was used
+// twice, and returns ErrInvalidRefreshToken for the request reusing it.
+func (s *authService) revokeFamily(ctx context.Context, stored *models.RefreshToken, now time.Time) error {
+	slog.WarnContext(ctx, "revoked refresh token used, revoking its family", "user_id", stored.UserID, "refresh_token_id", stored.ID)
+	if err := s.refreshTokens.RevokeFamily(ctx, stored.Family, now); err != nil {
+		return err
+	}
+	return ErrInvalidRefreshToken
+}
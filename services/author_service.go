@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var ErrAuthorHasBooks = errors.New("author still has books")
+
+type AuthorService interface {
+	CRUDService[models.Author]
+}
+
+type authorService struct {
+	CRUDService[models.Author]
+	authors repositories.AuthorRepository
+}
+
+func NewAuthorService(authors repositories.AuthorRepository) AuthorService {
+	return &authorService{CRUDService: NewCRUDService[models.Author](authors), authors: authors}
+}
+
+// Delete refuses to remove an author that books still reference.
+func (s *authorService) Delete(ctx context.Context, id uint) error {
+	author, err := s.authors.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	books, err := s.authors.CountBooks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if books > 0 {
+		return ErrAuthorHasBooks
+	}
+
+	return s.authors.Delete(ctx, author)
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var ErrSameBook = errors.New("a book can't be merged into itself")
+
+// How alike two normalized titles must be, from 0 to 1, for books by the
+// same author to count as duplicates: "The Hobbit" and "Hobbit, The" are
+// equal once normalized, and "The Hobbit" and "The Hobit" are 0.83 alike.
+const titleSimilarity = 0.8
+
+// DuplicateGroup is a book and its likely duplicates, oldest first.
+type DuplicateGroup struct {
+	Books []models.Book `json:"books"`
+}
+
+func (s *bookService) FindDuplicates(ctx context.Context, book *models.Book) ([]models.Book, error) {
+	number := isbn.Normalize(book.ISBN)
+	var isbns []string
+	if number != "" {
+		isbns = append(isbns, number)
+		if long := isbn.To13(number); long != "" && long != number {
+			isbns = append(isbns, long)
+		}
+	}
+	candidates, err := s.books.DuplicateCandidates(ctx, isbns, book.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []models.Book
+	for _, candidate := range candidates {
+		if candidate.ID != book.ID && likelySame(*book, candidate) {
+			duplicates = append(duplicates, candidate)
+		}
+	}
+	return duplicates, nil
+}
+
+func (s *bookService) DuplicateReport(ctx context.Context) ([]DuplicateGroup, error) {
+	books, err := s.books.ListIdentities(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Books are grouped with everything they duplicate, directly or
+	// through another book, so that each appears in one group.
+	parent := make([]int, len(books))
+	for i := range parent {
+		parent[i] = i
+	}
+	var root func(int) int
+	root = func(i int) int {
+		if parent[i] != i {
+			parent[i] = root(parent[i])
+		}
+		return parent[i]
+	}
+
+	// Only books sharing an ISBN or an author can be duplicates.
+	buckets := map[string][]int{}
+	byAuthor := map[uint][]int{}
+	for i, book := range books {
+		if key := isbnKey(book.ISBN); key != "" {
+			buckets[key] = append(buckets[key], i)
+		}
+		byAuthor[book.AuthorID] = append(byAuthor[book.AuthorID], i)
+	}
+	for _, same := range buckets {
+		for _, i := range same[1:] {
+			parent[root(i)] = root(same[0])
+		}
+	}
+	for _, same := range byAuthor {
+		for a, i := range same {
+			for _, j := range same[a+1:] {
+				if root(i) != root(j) && likelySame(books[i], books[j]) {
+					parent[root(j)] = root(i)
+				}
+			}
+		}
+	}
+
+	members := map[int][]uuid.UUID{}
+	var order []int
+	for i := range books {
+		r := root(i)
+		if _, ok := members[r]; !ok {
+			order = append(order, r)
+		}
+		members[r] = append(members[r], books[i].ID)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, r := range order {
+		ids := members[r]
+		if len(ids) < 2 {
+			continue
+		}
+		found, err := s.books.FindByIDs(ctx, ids, "Author")
+		if err != nil {
+			return nil, err
+		}
+		// In the order of ids, which is the oldest first.
+		byID := make(map[uuid.UUID]models.Book, len(found))
+		for _, book := range found {
+			byID[book.ID] = book
+		}
+		group := DuplicateGroup{Books: make([]models.Book, 0, len(ids))}
+		for _, id := range ids {
+			if book, ok := byID[id]; ok {
+				group.Books = append(group.Books, book)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+func (s *bookService) Merge(ctx context.Context, targetID, duplicateID uuid.UUID) (*models.Book, error) {
+	if targetID == duplicateID {
+		return nil, ErrSameBook
+	}
+	target, err := s.books.FindByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	duplicate, err := s.books.FindByID(ctx, duplicateID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownBook
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.books.Merge(ctx, target, duplicate); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, targetID)
+}
+
+// likelySame reports whether a and b are likely the same book.
+func likelySame(a, b models.Book) bool {
+	if key := isbnKey(a.ISBN); key != "" && key == isbnKey(b.ISBN) {
+		return true
+	}
+	return a.AuthorID == b.AuthorID && similarity(normalizeTitle(a.Title), normalizeTitle(b.Title)) >= titleSimilarity
+}
+
+// isbnKey returns the ISBN-13 form of s, so that both forms of a book's
+// ISBN compare equal, or s normalized if it isn't valid.
+func isbnKey(s string) string {
+	if long := isbn.To13(s); long != "" {
+		return long
+	}
+	return isbn.Normalize(s)
+}
+
+// normalizeTitle lowercases title, keeps only its letters and digits and
+// drops a leading or trailing article: "The Lord of the Rings!" and "Lord
+// of the Rings, The" both become "lord of the rings".
+func normalizeTitle(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	article := func(word string) bool { return word == "the" || word == "a" || word == "an" }
+	if len(words) > 1 && article(words[0]) {
+		words = words[1:]
+	} else if len(words) > 1 && article(words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return strings.Join(words, " ")
+}
+
+// similarity returns 1 minus the edit distance between a and b over the
+// length of the longer, so 1 for equal strings and 0 for unrelated ones.
+func similarity(a, b string) float64 {
+	x, y := []rune(a), []rune(b)
+	longest := max(len(x), len(y))
+	if longest == 0 {
+		return 1
+	}
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(y)])/float64(longest)
+}
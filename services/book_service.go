@@ -0,0 +1,523 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/slug"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrNotDeleted       = errors.New("book is not deleted")
+	ErrUnknownAuthor    = errors.New("author_id does not reference an existing author")
+	ErrUnknownPublisher = errors.New("publisher_id does not reference an existing publisher")
+	ErrUnknownCategory  = errors.New("category_ids references a category that does not exist")
+	// ErrPreconditionFailed means the book changed since the caller read it.
+	ErrPreconditionFailed = errors.New("book has been modified")
+	ErrPriceCurrency      = errors.New("price needs a currency")
+	ErrInvalidPrice       = errors.New("price must not be negative nor have more decimals than its currency allows")
+)
+
+// BookPatch describes a partial update: nil fields are left untouched,
+// non-nil fields are written even when they hold the zero value.
+type BookPatch struct {
+	// Version, when set, must match the stored one.
+	Version     uint
+	Title       *string
+	Description *string
+	AuthorID    *uint
+	PublisherID *uint
+	// ClearPublisher removes the book's publisher, overriding PublisherID.
+	ClearPublisher bool
+	Year           *int
+	ISBN           *string
+	Price          *decimal.Decimal
+	Currency       *string
+	// ClearPrice removes the book's price, overriding Price.
+	ClearPrice bool
+}
+
+func (p BookPatch) fields() map[string]interface{} {
+	fields := map[string]interface{}{}
+	if p.Title != nil {
+		fields["title"] = *p.Title
+	}
+	if p.Description != nil {
+		fields["description"] = *p.Description
+	}
+	if p.AuthorID != nil {
+		fields["author_id"] = *p.AuthorID
+	}
+	if p.ClearPublisher {
+		fields["publisher_id"] = nil
+	} else if p.PublisherID != nil {
+		fields["publisher_id"] = *p.PublisherID
+	}
+	if p.Year != nil {
+		fields["year"] = *p.Year
+	}
+	if p.ISBN != nil {
+		fields["isbn"] = isbn.Normalize(*p.ISBN)
+	}
+	if p.ClearPrice {
+		fields["price"] = nil
+	} else if p.Price != nil {
+		fields["price"] = *p.Price
+	}
+	if p.Currency != nil {
+		fields["currency"] = *p.Currency
+	}
+	return fields
+}
+
+type BookService interface {
+	List(ctx context.Context, opts repositories.BookListOptions) ([]models.Book, int64, error)
+	// ListAfter pages through books by cursor rather than offset; see
+	// repositories.BookRepository.
+	ListAfter(ctx context.Context, opts repositories.BookListOptions, after *repositories.BookCursor) ([]models.Book, *repositories.BookCursor, error)
+	Get(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error)
+	GetBySlug(ctx context.Context, slug string, preloads ...string) (*models.Book, error)
+	// GetColumns and GetBySlugColumns load only the given columns.
+	GetColumns(ctx context.Context, id uuid.UUID, columns []string, preloads ...string) (*models.Book, error)
+	GetBySlugColumns(ctx context.Context, slug string, columns []string, preloads ...string) (*models.Book, error)
+	Create(ctx context.Context, book *models.Book) error
+	CreateMany(ctx context.Context, books []*models.Book) ([]error, error)
+	Import(ctx context.Context, books []*models.Book, dryRun bool) ([]error, error)
+	// Export passes the matching books to fn in batches, with their Author
+	// unless other preloads are given.
+	Export(ctx context.Context, filter repositories.BookFilter, fn func([]models.Book) error, preloads ...string) error
+	// Update, Patch and Delete take the ETags the caller holds and fail with
+	// ErrPreconditionFailed unless the current one is among them; nil skips
+	// the check. Update and Patch fail with repositories.ErrStaleVersion when
+	// a version is given and is not the current one.
+	Update(ctx context.Context, id uuid.UUID, changes models.Book, ifMatch []string) (*models.Book, error)
+	Patch(ctx context.Context, id uuid.UUID, patch BookPatch, ifMatch []string) (*models.Book, error)
+	Delete(ctx context.Context, id uuid.UUID, ifMatch []string) error
+	DeleteMany(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error)
+	Restore(ctx context.Context, id uuid.UUID) (*models.Book, error)
+	DeletePermanently(ctx context.Context, id uuid.UUID) error
+	AttachCategories(ctx context.Context, id uuid.UUID, categoryIDs []uint) (*models.Book, error)
+	DetachCategory(ctx context.Context, id uuid.UUID, categoryID uint) (*models.Book, error)
+	Search(ctx context.Context, query string, offset, limit int) ([]repositories.BookSearchHit, int64, error)
+	// FindDuplicates returns the live books book would likely duplicate:
+	// those with the same ISBN, or by the same author with a similar title.
+	FindDuplicates(ctx context.Context, book *models.Book) ([]models.Book, error)
+	// DuplicateReport groups every live book with its likely duplicates.
+	DuplicateReport(ctx context.Context) ([]DuplicateGroup, error)
+	// Merge folds the duplicate into the target book and deletes it, failing
+	// with ErrSameBook if both are the same, ErrUnknownBook if the
+	// duplicate doesn't exist and repositories.ErrBookHeld while members
+	// are in line for it.
+	Merge(ctx context.Context, targetID, duplicateID uuid.UUID) (*models.Book, error)
+}
+
+type bookService struct {
+	books      repositories.BookRepository
+	authors    repositories.AuthorRepository
+	publishers repositories.PublisherRepository
+	categories repositories.CategoryRepository
+}
+
+func NewBookService(books repositories.BookRepository, authors repositories.AuthorRepository, publishers repositories.PublisherRepository, categories repositories.CategoryRepository) BookService {
+	return &bookService{books: books, authors: authors, publishers: publishers, categories: categories}
+}
+
+func (s *bookService) List(ctx context.Context, opts repositories.BookListOptions) ([]models.Book, int64, error) {
+	return s.books.List(ctx, opts)
+}
+
+func (s *bookService) ListAfter(ctx context.Context, opts repositories.BookListOptions, after *repositories.BookCursor) ([]models.Book, *repositories.BookCursor, error) {
+	return s.books.ListAfter(ctx, opts, after)
+}
+
+func (s *bookService) Get(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error) {
+	return s.books.FindByID(ctx, id, preloads...)
+}
+
+func (s *bookService) GetBySlug(ctx context.Context, slug string, preloads ...string) (*models.Book, error) {
+	return s.books.FindBySlug(ctx, slug, preloads...)
+}
+
+func (s *bookService) GetColumns(ctx context.Context, id uuid.UUID, columns []string, preloads ...string) (*models.Book, error) {
+	return s.books.FindByIDColumns(ctx, id, columns, preloads...)
+}
+
+func (s *bookService) GetBySlugColumns(ctx context.Context, slug string, columns []string, preloads ...string) (*models.Book, error) {
+	return s.books.FindBySlugColumns(ctx, slug, columns, preloads...)
+}
+
+func (s *bookService) Create(ctx context.Context, book *models.Book) error {
+	normalizeISBNs([]*models.Book{book})
+	if err := checkPrice(book.Price, book.Currency); err != nil {
+		return err
+	}
+	if err := s.checkAuthor(ctx, book.AuthorID); err != nil {
+		return err
+	}
+	if err := s.checkPublisher(ctx, book.PublisherID); err != nil {
+		return err
+	}
+	if err := s.assignSlugs(ctx, []*models.Book{book}); err != nil {
+		return err
+	}
+	return s.books.Create(ctx, book)
+}
+
+// CreateMany inserts the books whose author exists in a single transaction.
+// The returned slice holds, per book, the reason it was rejected (nil for
+// inserted books); the error is set only if the insert itself failed, in
+// which case nothing was written.
+func (s *bookService) CreateMany(ctx context.Context, books []*models.Book) ([]error, error) {
+	normalizeISBNs(books)
+	rejected, err := s.checkAuthors(ctx, books)
+	if err != nil {
+		return nil, err
+	}
+
+	valid := make([]*models.Book, 0, len(books))
+	for i, book := range books {
+		if rejected[i] == nil {
+			valid = append(valid, book)
+		}
+	}
+	if len(valid) > 0 {
+		if err := s.assignSlugs(ctx, valid); err != nil {
+			return nil, err
+		}
+		if err := s.books.CreateMany(ctx, valid); err != nil {
+			return nil, err
+		}
+	}
+	return rejected, nil
+}
+
+// Import is all or nothing: the books are inserted in one transaction only
+// if every one of them is valid, and never in a dry run. The returned slice
+// has the same meaning as for CreateMany.
+func (s *bookService) Import(ctx context.Context, books []*models.Book, dryRun bool) ([]error, error) {
+	normalizeISBNs(books)
+	rejected, err := s.checkAuthors(ctx, books)
+	if err != nil {
+		return nil, err
+	}
+	for _, reason := range rejected {
+		if reason != nil {
+			return rejected, nil
+		}
+	}
+	if dryRun || len(books) == 0 {
+		return rejected, nil
+	}
+	if err := s.assignSlugs(ctx, books); err != nil {
+		return nil, err
+	}
+	return rejected, s.books.CreateMany(ctx, books)
+}
+
+func (s *bookService) Export(ctx context.Context, filter repositories.BookFilter, fn func([]models.Book) error, preloads ...string) error {
+	return s.books.Each(ctx, filter, 500, fn, preloads...)
+}
+
+func (s *bookService) Update(ctx context.Context, id uuid.UUID, changes models.Book, ifMatch []string) (*models.Book, error) {
+	book, err := s.findMatching(ctx, id, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	if changes.Version != 0 && changes.Version != book.Version {
+		return nil, repositories.ErrStaleVersion
+	}
+	if changes.AuthorID != 0 {
+		if err := s.checkAuthor(ctx, changes.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkPublisher(ctx, changes.PublisherID); err != nil {
+		return nil, err
+	}
+	price, currency := book.Price, book.Currency
+	if changes.Price != nil {
+		price = changes.Price
+	}
+	if changes.Currency != "" {
+		currency = changes.Currency
+	}
+	if err := checkPrice(price, currency); err != nil {
+		return nil, err
+	}
+	changes.ISBN = isbn.Normalize(changes.ISBN)
+	if err := s.books.Update(ctx, book, changes); err != nil {
+		return nil, err
+	}
+	return book, nil
+}
+
+func (s *bookService) Patch(ctx context.Context, id uuid.UUID, patch BookPatch, ifMatch []string) (*models.Book, error) {
+	book, err := s.findMatching(ctx, id, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+	if patch.Version != 0 && patch.Version != book.Version {
+		return nil, repositories.ErrStaleVersion
+	}
+	if patch.AuthorID != nil {
+		if err := s.checkAuthor(ctx, *patch.AuthorID); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkPublisher(ctx, patch.PublisherID); err != nil {
+		return nil, err
+	}
+	price, currency := book.Price, book.Currency
+	switch {
+	case patch.ClearPrice:
+		price = nil
+	case patch.Price != nil:
+		price = patch.Price
+	}
+	if patch.Currency != nil {
+		currency = *patch.Currency
+	}
+	if err := checkPrice(price, currency); err != nil {
+		return nil, err
+	}
+
+	fields := patch.fields()
+	if len(fields) == 0 {
+		return book, nil
+	}
+	if err := s.books.UpdateFields(ctx, book, fields); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, id)
+}
+
+func (s *bookService) Delete(ctx context.Context, id uuid.UUID, ifMatch []string) error {
+	book, err := s.findMatching(ctx, id, ifMatch)
+	if err != nil {
+		return err
+	}
+	return s.books.Delete(ctx, book)
+}
+
+// DeleteMany soft-deletes the given books and reports, per ID, whether it
+// existed and was deleted.
+func (s *bookService) DeleteMany(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	deleted, err := s.books.DeleteMany(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]bool, len(ids))
+	for _, id := range ids {
+		result[id] = false
+	}
+	for _, id := range deleted {
+		result[id] = true
+	}
+	return result, nil
+}
+
+func (s *bookService) Restore(ctx context.Context, id uuid.UUID) (*models.Book, error) {
+	book, err := s.books.FindByIDWithDeleted(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !book.DeletedAt.Valid {
+		return nil, ErrNotDeleted
+	}
+	if err := s.books.Restore(ctx, book); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, id)
+}
+
+func (s *bookService) DeletePermanently(ctx context.Context, id uuid.UUID) error {
+	book, err := s.books.FindByIDWithDeleted(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.books.DeletePermanently(ctx, book)
+}
+
+func (s *bookService) AttachCategories(ctx context.Context, id uuid.UUID, categoryIDs []uint) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	categories, err := s.categories.FindByIDs(ctx, categoryIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(categories) != len(uniqueIDs(categoryIDs)) {
+		return nil, ErrUnknownCategory
+	}
+
+	if err := s.books.AddCategories(ctx, book, categories); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, id, "Categories")
+}
+
+func (s *bookService) DetachCategory(ctx context.Context, id uuid.UUID, categoryID uint) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	category, err := s.categories.FindByID(ctx, categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.books.RemoveCategory(ctx, book, category); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, id, "Categories")
+}
+
+func (s *bookService) Search(ctx context.Context, query string, offset, limit int) ([]repositories.BookSearchHit, int64, error) {
+	return s.books.Search(ctx, query, offset, limit)
+}
+
+// findMatching loads the book and checks it against the caller's ETags.
+func (s *bookService) findMatching(ctx context.Context, id uuid.UUID, ifMatch []string) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ifMatch == nil {
+		return book, nil
+	}
+	for _, etag := range ifMatch {
+		if etag == book.ETag() {
+			return book, nil
+		}
+	}
+	return nil, ErrPreconditionFailed
+}
+
+// assignSlugs gives each book a slug made from its title that no other book,
+// soft-deleted ones included, uses: "clean-code", then "clean-code-2" and so
+// on. Two requests racing for the same slug make one insert fail with
+// repositories.ErrDuplicate.
+func (s *bookService) assignSlugs(ctx context.Context, books []*models.Book) error {
+	taken := map[string]bool{}
+	loaded := map[string]bool{}
+	for _, book := range books {
+		base := slug.Make(book.Title)
+		if _, err := uuid.Parse(base); base == "" || err == nil {
+			// Slugs must never be mistaken for IDs in GET books/:id.
+			base = strings.Trim("book-"+base, "-")
+		}
+		if !loaded[base] {
+			existing, err := s.books.SlugsLike(ctx, base)
+			if err != nil {
+				return err
+			}
+			for _, existingSlug := range existing {
+				taken[existingSlug] = true
+			}
+			loaded[base] = true
+		}
+
+		candidate := base
+		for n := 2; taken[candidate]; n++ {
+			candidate = fmt.Sprintf("%s-%d", base, n)
+		}
+		taken[candidate] = true
+		book.Slug = candidate
+	}
+	return nil
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	set := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return set
+}
+
+// checkAuthors runs checkAuthor once per distinct author, and
+// checkPublisher once per distinct publisher, and returns, per book,
+// ErrUnknownAuthor, ErrUnknownPublisher, the error of checkPrice or nil.
+func (s *bookService) checkAuthors(ctx context.Context, books []*models.Book) ([]error, error) {
+	rejected := make([]error, len(books))
+	checked := map[uint]error{}
+	checkedPublishers := map[uint]error{}
+	for i, book := range books {
+		if err := checkPrice(book.Price, book.Currency); err != nil {
+			rejected[i] = err
+			continue
+		}
+		err, ok := checked[book.AuthorID]
+		if !ok {
+			err = s.checkAuthor(ctx, book.AuthorID)
+			checked[book.AuthorID] = err
+		}
+		if err == nil && book.PublisherID != nil {
+			if err, ok = checkedPublishers[*book.PublisherID]; !ok {
+				err = s.checkPublisher(ctx, book.PublisherID)
+				checkedPublishers[*book.PublisherID] = err
+			}
+		}
+		if err != nil && !errors.Is(err, ErrUnknownAuthor) && !errors.Is(err, ErrUnknownPublisher) {
+			return nil, err
+		}
+		rejected[i] = err
+	}
+	return rejected, nil
+}
+
+// checkPrice accepts no price, or a price in a currency with no more
+// decimals than the currency's minor unit, e.g. cents. Currencies are
+// validated as input.
+func checkPrice(price *decimal.Decimal, currency string) error {
+	switch {
+	case price == nil:
+		return nil
+	case currency == "":
+		return ErrPriceCurrency
+	case price.IsNegative() || !price.Equal(price.Truncate(money.MinorUnits(currency))):
+		return ErrInvalidPrice
+	}
+	return nil
+}
+
+// SQLite doesn't enforce foreign keys by default, so the reference is
+// checked here for every driver.
+func (s *bookService) checkAuthor(ctx context.Context, id uint) error {
+	_, err := s.authors.FindByID(ctx, id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrUnknownAuthor
+	}
+	return err
+}
+
+// checkPublisher is checkAuthor for the optional publisher; nil passes.
+func (s *bookService) checkPublisher(ctx context.Context, id *uint) error {
+	if id == nil {
+		return nil
+	}
+	_, err := s.publishers.FindByID(ctx, *id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrUnknownPublisher
+	}
+	return err
+}
+
+// normalizeISBNs stores ISBNs without the hyphens and spaces they are often
+// typed with, so the same book is always written the same way.
+func normalizeISBNs(books []*models.Book) {
+	for _, book := range books {
+		book.ISBN = isbn.Normalize(book.ISBN)
+	}
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+// BookDiff lists the fields that differ between two versions of a book.
+type BookDiff struct {
+	From    uint                   `json:"from"`
+	To      uint                   `json:"to"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+type FieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type BookVersionService interface {
+	// List returns the recorded versions of the book, newest first; deleted
+	// books keep theirs.
+	List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.BookVersion, int64, error)
+	// Diff compares version from of the book with version to, or with the
+	// book as it is when to is 0.
+	Diff(ctx context.Context, bookID uuid.UUID, from, to uint) (*BookDiff, error)
+	// Revert writes the fields of the given version back to the book, as a
+	// new version. The quantity is left alone: copies are only added and
+	// removed through stock adjustments.
+	Revert(ctx context.Context, bookID uuid.UUID, version uint) (*models.Book, error)
+}
+
+type bookVersionService struct {
+	versions    repositories.BookVersionRepository
+	books       repositories.BookRepository
+	bookService BookService
+}
+
+// NewBookVersionService reverts books through bookService, so that
+// reverts are checked, recorded and invalidate caches like any other edit.
+func NewBookVersionService(versions repositories.BookVersionRepository, books repositories.BookRepository, bookService BookService) BookVersionService {
+	return &bookVersionService{versions: versions, books: books, bookService: bookService}
+}
+
+func (s *bookVersionService) List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.BookVersion, int64, error) {
+	// Versions aren't tenant-scoped themselves; their book is.
+	if _, err := s.books.FindByIDWithDeleted(ctx, bookID); err != nil {
+		return nil, 0, err
+	}
+	return s.versions.List(ctx, bookID, offset, limit)
+}
+
+func (s *bookVersionService) Diff(ctx context.Context, bookID uuid.UUID, from, to uint) (*BookDiff, error) {
+	book, err := s.books.FindByIDWithDeleted(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	older, err := s.versions.Find(ctx, bookID, from)
+	if err != nil {
+		return nil, err
+	}
+	newer := book.Snapshot()
+	if to == 0 {
+		to = book.Version
+	} else {
+		version, err := s.versions.Find(ctx, bookID, to)
+		if err != nil {
+			return nil, err
+		}
+		newer = version.Snapshot
+	}
+
+	a, b := snapshotFields(older.Snapshot), snapshotFields(newer)
+	diff := &BookDiff{From: from, To: to, Changes: map[string]FieldChange{}}
+	for field, value := range a {
+		if !reflect.DeepEqual(value, b[field]) {
+			diff.Changes[field] = FieldChange{From: value, To: b[field]}
+		}
+	}
+	return diff, nil
+}
+
+func (s *bookVersionService) Revert(ctx context.Context, bookID uuid.UUID, version uint) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.versions.Find(ctx, bookID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := target.Snapshot
+	patch := BookPatch{
+		Version:        book.Version,
+		Title:          &snapshot.Title,
+		Description:    &snapshot.Description,
+		AuthorID:       &snapshot.AuthorID,
+		PublisherID:    snapshot.PublisherID,
+		ClearPublisher: snapshot.PublisherID == nil,
+		Year:           &snapshot.Year,
+		ISBN:           &snapshot.ISBN,
+		Price:          snapshot.Price,
+		ClearPrice:     snapshot.Price == nil,
+		Currency:       &snapshot.Currency,
+	}
+	return s.bookService.Patch(ctx, bookID, patch, nil)
+}
+
+// snapshotFields returns the fields of s keyed by their JSON names.
+func snapshotFields(s models.BookSnapshot) map[string]interface{} {
+	raw, _ := json.Marshal(s)
+	var fields map[string]interface{}
+	json.Unmarshal(raw, &fields)
+	return fields
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var (
+	ErrBranchHasCopies = errors.New("branch still holds copies")
+	ErrUnknownBranch   = errors.New("branch_id does not reference an existing branch")
+)
+
+type BranchService interface {
+	CRUDService[models.Branch]
+}
+
+type branchService struct {
+	CRUDService[models.Branch]
+	branches repositories.BranchRepository
+}
+
+func NewBranchService(branches repositories.BranchRepository) BranchService {
+	return &branchService{CRUDService: NewCRUDService[models.Branch](branches), branches: branches}
+}
+
+// Delete refuses to remove a branch that still holds copies of books.
+func (s *branchService) Delete(ctx context.Context, id uint) error {
+	branch, err := s.branches.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	copies, err := s.branches.CountCopies(ctx, id)
+	if err != nil {
+		return err
+	}
+	if copies > 0 {
+		return ErrBranchHasCopies
+	}
+
+	return s.branches.Delete(ctx, branch)
+}
+
+// findBranch fails with ErrUnknownBranch unless id is nil, for the
+// unassigned copies, or a branch of the tenant ctx acts for.
+func findBranch(ctx context.Context, branches repositories.BranchRepository, id *uint) error {
+	if id == nil {
+		return nil
+	}
+	_, err := branches.FindByID(ctx, *id)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return ErrUnknownBranch
+	}
+	return err
+}
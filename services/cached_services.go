@@ -0,0 +1,419 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/cache"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+)
+
+// The decorators below serve book reads from the cache and invalidate it
+// after every write that can change what those reads return, including
+// writes to authors, publishers, categories and tags, which show up in preloaded books.
+//
+// Cached books are round-tripped through JSON, so fields hidden from JSON
+// (cover keys) are empty in them; Get and List results are only meant to be
+// rendered.
+
+type cachedBookService struct {
+	BookService
+	cache *cache.Cache
+}
+
+func NewCachedBookService(books BookService, c *cache.Cache) BookService {
+	return &cachedBookService{BookService: books, cache: c}
+}
+
+type bookPage struct {
+	Books []models.Book `json:"books"`
+	Total int64         `json:"total"`
+}
+
+func (s *cachedBookService) List(ctx context.Context, opts repositories.BookListOptions) ([]models.Book, int64, error) {
+	key, err := json.Marshal(opts)
+	if err != nil {
+		return s.BookService.List(ctx, opts)
+	}
+	sum := sha256.Sum256(key)
+
+	page, err := fetch(ctx, s.cache, tenantCacheKey(ctx, "list:"+hex.EncodeToString(sum[:])), func() (bookPage, error) {
+		books, total, err := s.BookService.List(ctx, opts)
+		return bookPage{Books: books, Total: total}, err
+	})
+	return page.Books, page.Total, err
+}
+
+func (s *cachedBookService) Get(ctx context.Context, id uuid.UUID, preloads ...string) (*models.Book, error) {
+	key := fmt.Sprintf("get:%s:%s", id, strings.Join(preloads, ","))
+	return fetch(ctx, s.cache, tenantCacheKey(ctx, key), func() (*models.Book, error) {
+		return s.BookService.Get(ctx, id, preloads...)
+	})
+}
+
+func (s *cachedBookService) GetBySlug(ctx context.Context, slug string, preloads ...string) (*models.Book, error) {
+	key := fmt.Sprintf("slug:%s:%s", slug, strings.Join(preloads, ","))
+	return fetch(ctx, s.cache, tenantCacheKey(ctx, key), func() (*models.Book, error) {
+		return s.BookService.GetBySlug(ctx, slug, preloads...)
+	})
+}
+
+func (s *cachedBookService) GetColumns(ctx context.Context, id uuid.UUID, columns []string, preloads ...string) (*models.Book, error) {
+	key := fmt.Sprintf("get:%s:%s:%s", id, strings.Join(preloads, ","), strings.Join(columns, ","))
+	return fetch(ctx, s.cache, tenantCacheKey(ctx, key), func() (*models.Book, error) {
+		return s.BookService.GetColumns(ctx, id, columns, preloads...)
+	})
+}
+
+func (s *cachedBookService) GetBySlugColumns(ctx context.Context, slug string, columns []string, preloads ...string) (*models.Book, error) {
+	key := fmt.Sprintf("slug:%s:%s:%s", slug, strings.Join(preloads, ","), strings.Join(columns, ","))
+	return fetch(ctx, s.cache, tenantCacheKey(ctx, key), func() (*models.Book, error) {
+		return s.BookService.GetBySlugColumns(ctx, slug, columns, preloads...)
+	})
+}
+
+// tenantCacheKey keeps each tenant's reads apart, since each sees only its
+// own books, and those of code acting for no tenant, which sees them all.
+func tenantCacheKey(ctx context.Context, key string) string {
+	if id, ok := tenancy.FromContext(ctx); ok {
+		return fmt.Sprintf("tenant:%d:%s", id, key)
+	}
+	return "all:" + key
+}
+
+// fetch reads through c, except in a transaction from models.InTransaction
+// (an atomic batch), whose reads may see writes that are rolled back later
+// and mustn't be cached.
+func fetch[T any](ctx context.Context, c *cache.Cache, key string, load func() (T, error)) (T, error) {
+	if models.InAmbientTransaction(ctx) {
+		return load()
+	}
+	return cache.Fetch(ctx, c, key, load)
+}
+
+// invalidate invalidates c once the write it follows is committed: right
+// away, or when the transaction it was made in is.
+func invalidate(ctx context.Context, c *cache.Cache) {
+	models.AfterCommit(ctx, c.Invalidate)
+}
+
+func (s *cachedBookService) Create(ctx context.Context, book *models.Book) error {
+	err := s.BookService.Create(ctx, book)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+func (s *cachedBookService) CreateMany(ctx context.Context, books []*models.Book) ([]error, error) {
+	rejected, err := s.BookService.CreateMany(ctx, books)
+	invalidate(ctx, s.cache)
+	return rejected, err
+}
+
+func (s *cachedBookService) Import(ctx context.Context, books []*models.Book, dryRun bool) ([]error, error) {
+	rejected, err := s.BookService.Import(ctx, books, dryRun)
+	if !dryRun {
+		invalidate(ctx, s.cache)
+	}
+	return rejected, err
+}
+
+func (s *cachedBookService) Update(ctx context.Context, id uuid.UUID, changes models.Book, ifMatch []string) (*models.Book, error) {
+	book, err := s.BookService.Update(ctx, id, changes, ifMatch)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cachedBookService) Patch(ctx context.Context, id uuid.UUID, patch BookPatch, ifMatch []string) (*models.Book, error) {
+	book, err := s.BookService.Patch(ctx, id, patch, ifMatch)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cachedBookService) Delete(ctx context.Context, id uuid.UUID, ifMatch []string) error {
+	err := s.BookService.Delete(ctx, id, ifMatch)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+func (s *cachedBookService) DeleteMany(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]bool, error) {
+	deleted, err := s.BookService.DeleteMany(ctx, ids)
+	invalidate(ctx, s.cache)
+	return deleted, err
+}
+
+func (s *cachedBookService) Restore(ctx context.Context, id uuid.UUID) (*models.Book, error) {
+	book, err := s.BookService.Restore(ctx, id)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cachedBookService) DeletePermanently(ctx context.Context, id uuid.UUID) error {
+	err := s.BookService.DeletePermanently(ctx, id)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+func (s *cachedBookService) AttachCategories(ctx context.Context, id uuid.UUID, categoryIDs []uint) (*models.Book, error) {
+	book, err := s.BookService.AttachCategories(ctx, id, categoryIDs)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cachedBookService) DetachCategory(ctx context.Context, id uuid.UUID, categoryID uint) (*models.Book, error) {
+	book, err := s.BookService.DetachCategory(ctx, id, categoryID)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cachedBookService) Merge(ctx context.Context, targetID, duplicateID uuid.UUID) (*models.Book, error) {
+	book, err := s.BookService.Merge(ctx, targetID, duplicateID)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+type cacheInvalidatingAuthorService struct {
+	AuthorService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingAuthorService invalidates c whenever an author changes.
+func NewCacheInvalidatingAuthorService(authors AuthorService, c *cache.Cache) AuthorService {
+	return &cacheInvalidatingAuthorService{AuthorService: authors, cache: c}
+}
+
+func (s *cacheInvalidatingAuthorService) Update(ctx context.Context, id uint, changes models.Author) (*models.Author, error) {
+	author, err := s.AuthorService.Update(ctx, id, changes)
+	invalidate(ctx, s.cache)
+	return author, err
+}
+
+func (s *cacheInvalidatingAuthorService) Delete(ctx context.Context, id uint) error {
+	err := s.AuthorService.Delete(ctx, id)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cacheInvalidatingPublisherService struct {
+	PublisherService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingPublisherService invalidates c whenever a publisher
+// changes.
+func NewCacheInvalidatingPublisherService(publishers PublisherService, c *cache.Cache) PublisherService {
+	return &cacheInvalidatingPublisherService{PublisherService: publishers, cache: c}
+}
+
+func (s *cacheInvalidatingPublisherService) Update(ctx context.Context, id uint, changes models.Publisher) (*models.Publisher, error) {
+	publisher, err := s.PublisherService.Update(ctx, id, changes)
+	invalidate(ctx, s.cache)
+	return publisher, err
+}
+
+func (s *cacheInvalidatingPublisherService) Delete(ctx context.Context, id uint) error {
+	err := s.PublisherService.Delete(ctx, id)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cacheInvalidatingCategoryService struct {
+	CategoryService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingCategoryService invalidates c whenever a category
+// changes.
+func NewCacheInvalidatingCategoryService(categories CategoryService, c *cache.Cache) CategoryService {
+	return &cacheInvalidatingCategoryService{CategoryService: categories, cache: c}
+}
+
+func (s *cacheInvalidatingCategoryService) Update(ctx context.Context, id uint, changes models.Category) (*models.Category, error) {
+	category, err := s.CategoryService.Update(ctx, id, changes)
+	invalidate(ctx, s.cache)
+	return category, err
+}
+
+func (s *cacheInvalidatingCategoryService) Delete(ctx context.Context, id uint) error {
+	err := s.CategoryService.Delete(ctx, id)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cacheInvalidatingCoverService struct {
+	CoverService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingCoverService invalidates c when a cover is uploaded,
+// since that bumps the book's updated_at.
+func NewCacheInvalidatingCoverService(covers CoverService, c *cache.Cache) CoverService {
+	return &cacheInvalidatingCoverService{CoverService: covers, cache: c}
+}
+
+func (s *cacheInvalidatingCoverService) Upload(ctx context.Context, bookID uuid.UUID, data []byte) (*models.Book, error) {
+	book, err := s.CoverService.Upload(ctx, bookID, data)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+type cacheInvalidatingReviewService struct {
+	ReviewService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingReviewService invalidates c when a review is added,
+// since books carry their rating average and review count.
+func NewCacheInvalidatingReviewService(reviews ReviewService, c *cache.Cache) ReviewService {
+	return &cacheInvalidatingReviewService{ReviewService: reviews, cache: c}
+}
+
+func (s *cacheInvalidatingReviewService) Create(ctx context.Context, review *models.Review) error {
+	err := s.ReviewService.Create(ctx, review)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cacheInvalidatingStockService struct {
+	StockService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingStockService invalidates c when a book's stock is
+// adjusted.
+func NewCacheInvalidatingStockService(stock StockService, c *cache.Cache) StockService {
+	return &cacheInvalidatingStockService{StockService: stock, cache: c}
+}
+
+func (s *cacheInvalidatingStockService) Adjust(ctx context.Context, id uuid.UUID, branchID *uint, delta int) (*models.Book, error) {
+	book, err := s.StockService.Adjust(ctx, id, branchID, delta)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+type cacheInvalidatingTagService struct {
+	TagService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingTagService invalidates c whenever a book is tagged or
+// untagged, or a tag renamed or merged.
+func NewCacheInvalidatingTagService(tags TagService, c *cache.Cache) TagService {
+	return &cacheInvalidatingTagService{TagService: tags, cache: c}
+}
+
+func (s *cacheInvalidatingTagService) TagBook(ctx context.Context, bookID uuid.UUID, names []string) (*models.Book, error) {
+	book, err := s.TagService.TagBook(ctx, bookID, names)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cacheInvalidatingTagService) UntagBook(ctx context.Context, bookID uuid.UUID, name string) (*models.Book, error) {
+	book, err := s.TagService.UntagBook(ctx, bookID, name)
+	invalidate(ctx, s.cache)
+	return book, err
+}
+
+func (s *cacheInvalidatingTagService) Rename(ctx context.Context, name, newName string) (*models.Tag, error) {
+	tag, err := s.TagService.Rename(ctx, name, newName)
+	invalidate(ctx, s.cache)
+	return tag, err
+}
+
+func (s *cacheInvalidatingTagService) Merge(ctx context.Context, from, into string) (*models.Tag, error) {
+	tag, err := s.TagService.Merge(ctx, from, into)
+	invalidate(ctx, s.cache)
+	return tag, err
+}
+
+type cacheInvalidatingLoanService struct {
+	LoanService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingLoanService invalidates c on checkouts and returns,
+// since books carry their number of available copies.
+func NewCacheInvalidatingLoanService(loans LoanService, c *cache.Cache) LoanService {
+	return &cacheInvalidatingLoanService{LoanService: loans, cache: c}
+}
+
+func (s *cacheInvalidatingLoanService) Checkout(ctx context.Context, bookID, memberID uuid.UUID, branchID *uint) (*models.Loan, error) {
+	loan, err := s.LoanService.Checkout(ctx, bookID, memberID, branchID)
+	invalidate(ctx, s.cache)
+	return loan, err
+}
+
+func (s *cacheInvalidatingLoanService) Return(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	loan, err := s.LoanService.Return(ctx, id)
+	invalidate(ctx, s.cache)
+	return loan, err
+}
+
+type cacheInvalidatingMaintenanceService struct {
+	MaintenanceService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingMaintenanceService invalidates c after purges and
+// rating refreshes, which write books behind the book service's back.
+func NewCacheInvalidatingMaintenanceService(maintenance MaintenanceService, c *cache.Cache) MaintenanceService {
+	return &cacheInvalidatingMaintenanceService{MaintenanceService: maintenance, cache: c}
+}
+
+func (s *cacheInvalidatingMaintenanceService) PurgeDeletedBooks(ctx context.Context) error {
+	err := s.MaintenanceService.PurgeDeletedBooks(ctx)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+func (s *cacheInvalidatingMaintenanceService) RefreshRatings(ctx context.Context) error {
+	err := s.MaintenanceService.RefreshRatings(ctx)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cacheInvalidatingHoldService struct {
+	HoldService
+	cache *cache.Cache
+}
+
+// NewCacheInvalidatingHoldService invalidates c when holds are cancelled or
+// expire, which can put the copies set aside for them back on the shelves.
+func NewCacheInvalidatingHoldService(holds HoldService, c *cache.Cache) HoldService {
+	return &cacheInvalidatingHoldService{HoldService: holds, cache: c}
+}
+
+func (s *cacheInvalidatingHoldService) Cancel(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	hold, err := s.HoldService.Cancel(ctx, id)
+	invalidate(ctx, s.cache)
+	return hold, err
+}
+
+func (s *cacheInvalidatingHoldService) Process(ctx context.Context) error {
+	err := s.HoldService.Process(ctx)
+	invalidate(ctx, s.cache)
+	return err
+}
+
+type cachedStatsService struct {
+	StatsService
+	cache *cache.Cache
+}
+
+// NewCachedStatsService serves the statistics from c, which isn't
+// invalidated by writes: they are up to its TTL old.
+func NewCachedStatsService(stats StatsService, c *cache.Cache) StatsService {
+	return &cachedStatsService{StatsService: stats, cache: c}
+}
+
+func (s *cachedStatsService) Get(ctx context.Context) (*Stats, error) {
+	return fetch(ctx, s.cache, tenantCacheKey(ctx, "stats"), func() (*Stats, error) {
+		return s.StatsService.Get(ctx)
+	})
+}
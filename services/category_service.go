@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+type CategoryService interface {
+	CRUDService[models.Category]
+	ForBooks(ctx context.Context, bookIDs []uuid.UUID) (map[uuid.UUID][]models.Category, error)
+}
+
+type categoryService struct {
+	CRUDService[models.Category]
+	categories repositories.CategoryRepository
+}
+
+func NewCategoryService(categories repositories.CategoryRepository) CategoryService {
+	return &categoryService{CRUDService: NewCRUDService[models.Category](categories), categories: categories}
+}
+
+func (s *categoryService) ForBooks(ctx context.Context, bookIDs []uuid.UUID) (map[uuid.UUID][]models.Category, error) {
+	return s.categories.ListByBooks(ctx, bookIDs)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+// ChangeTypes are the tables whose changes the feed reports: the catalog,
+// and the links between its records.
+var ChangeTypes = []string{
+	"authors",
+	"book_categories",
+	"book_tags",
+	"books",
+	"categories",
+	"publishers",
+	"series",
+	"series_volumes",
+	"tags",
+}
+
+// changeSettle is how old a change must be before the feed reports it.
+// Entries are numbered as they are written but become visible when their
+// transaction commits, so the newest ones may yet be preceded by others;
+// past this, any transaction writing them has long finished.
+const changeSettle = 5 * time.Second
+
+// Change is one create, update or delete in the feed. Sequence orders the
+// feed; ID is the primary key of the changed row, and Payload the column
+// values written, for creates and updates.
+type Change struct {
+	Sequence  uint              `json:"sequence"`
+	Type      string            `json:"type"`
+	ID        string            `json:"id"`
+	Operation string            `json:"operation"`
+	Timestamp time.Time         `json:"timestamp"`
+	Payload   models.JSONObject `json:"payload"`
+}
+
+type ChangeService interface {
+	// Feed returns up to limit changes to the tables of types, all of
+	// ChangeTypes if empty, following the one numbered since, and whether
+	// more already follow them.
+	Feed(ctx context.Context, since uint, types []string, limit int) ([]Change, bool, error)
+}
+
+type changeService struct {
+	audit repositories.AuditRepository
+}
+
+func NewChangeService(audit repositories.AuditRepository) ChangeService {
+	return &changeService{audit: audit}
+}
+
+func (s *changeService) Feed(ctx context.Context, since uint, types []string, limit int) ([]Change, bool, error) {
+	if len(types) == 0 {
+		types = ChangeTypes
+	}
+
+	filter := repositories.ChangeFilter{Entities: types, Before: time.Now().Add(-changeSettle)}
+	entries, err := s.audit.ListSince(ctx, filter, since, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	more := len(entries) > limit
+	if more {
+		entries = entries[:limit]
+	}
+
+	changes := make([]Change, len(entries))
+	for i, entry := range entries {
+		changes[i] = Change{
+			Sequence:  entry.ID,
+			Type:      entry.Entity,
+			ID:        entry.EntityID,
+			Operation: entry.Action,
+			Timestamp: entry.CreatedAt,
+			Payload:   entry.Changes,
+		}
+	}
+	return changes, more, nil
+}
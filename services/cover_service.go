@@ -0,0 +1,149 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/images"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/storage"
+	"github.com/google/uuid"
+)
+
+var ErrNoCover = errors.New("book has no cover")
+
+// Thumbnails fit in a square of this many pixels.
+const thumbnailSize = 256
+
+type CoverService interface {
+	Upload(ctx context.Context, bookID uuid.UUID, data []byte) (*models.Book, error)
+	Open(ctx context.Context, bookID uuid.UUID, thumbnail bool) (*storage.Object, error)
+	// DownloadURL returns a URL the cover can be downloaded from directly,
+	// or "" when the storage offers none and Open must serve it.
+	DownloadURL(ctx context.Context, bookID uuid.UUID, thumbnail bool) (string, error)
+}
+
+type coverService struct {
+	books     repositories.BookRepository
+	storage   storage.Storage
+	maxPixels int
+}
+
+func NewCoverService(books repositories.BookRepository, storage storage.Storage, maxPixels int) CoverService {
+	return &coverService{books: books, storage: storage, maxPixels: maxPixels}
+}
+
+// Upload stores the image and a thumbnail under fresh keys, then points the
+// book at them and removes the previous cover. images.ErrUnsupported is
+// returned for anything that isn't a decodable image, and
+// images.ErrTooManyPixels for images of more than maxPixels.
+func (s *coverService) Upload(ctx context.Context, bookID uuid.UUID, data []byte) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, ext, err := images.Detect(data)
+	if err != nil {
+		return nil, err
+	}
+	thumb, thumbType, err := images.Thumbnail(data, contentType, thumbnailSize, s.maxPixels)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("covers/%s/%s%s", book.ID, token, ext)
+	thumbKey := fmt.Sprintf("covers/%s/%s_thumb%s", book.ID, token, thumbExt(thumbType, ext))
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+		return nil, err
+	}
+	if err := s.storage.Put(ctx, thumbKey, bytes.NewReader(thumb), int64(len(thumb)), thumbType); err != nil {
+		s.remove(ctx, key)
+		return nil, err
+	}
+
+	previous := []string{book.CoverKey, book.CoverThumbnailKey}
+	err = s.books.UpdateFields(ctx, book, map[string]interface{}{"cover_key": key, "cover_thumbnail_key": thumbKey})
+	if err != nil {
+		s.remove(ctx, key, thumbKey)
+		return nil, err
+	}
+	s.remove(ctx, previous...)
+	return s.books.FindByID(ctx, book.ID)
+}
+
+func (s *coverService) Open(ctx context.Context, bookID uuid.UUID, thumbnail bool) (*storage.Object, error) {
+	key, err := s.key(ctx, bookID, thumbnail)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNoCover
+	}
+	return obj, err
+}
+
+func (s *coverService) DownloadURL(ctx context.Context, bookID uuid.UUID, thumbnail bool) (string, error) {
+	key, err := s.key(ctx, bookID, thumbnail)
+	if err != nil {
+		return "", err
+	}
+	// Shown inline, so no file name.
+	return storage.DownloadURL(ctx, s.storage, key, "")
+}
+
+// key returns the storage key of the book's cover or its thumbnail.
+func (s *coverService) key(ctx context.Context, bookID uuid.UUID, thumbnail bool) (string, error) {
+	book, err := s.books.FindByID(ctx, bookID)
+	if err != nil {
+		return "", err
+	}
+	key := book.CoverKey
+	if thumbnail {
+		key = book.CoverThumbnailKey
+	}
+	if key == "" {
+		return "", ErrNoCover
+	}
+	return key, nil
+}
+
+// remove deletes objects that are no longer referenced. Failures only leave
+// garbage behind, so they are logged rather than returned.
+func (s *coverService) remove(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if err := s.storage.Delete(ctx, key); err != nil {
+			slog.WarnContext(ctx, "deleting stored object", "key", key, "error", err)
+		}
+	}
+}
+
+func thumbExt(thumbType, ext string) string {
+	if thumbType == "image/png" {
+		return ".png"
+	}
+	return ext
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+// CRUDService reads and writes the records of a resource with integer IDs.
+// The services of such resources embed it, adding their own rules.
+type CRUDService[T any] interface {
+	List(ctx context.Context, offset, limit int) ([]T, int64, error)
+	Get(ctx context.Context, id uint) (*T, error)
+	// GetMany returns the records among ids that exist, in no particular
+	// order.
+	GetMany(ctx context.Context, ids []uint) ([]T, error)
+	Create(ctx context.Context, record *T) error
+	Update(ctx context.Context, id uint, changes T) (*T, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+type crudService[T any] struct {
+	records repositories.CRUDRepository[T]
+}
+
+func NewCRUDService[T any](records repositories.CRUDRepository[T]) CRUDService[T] {
+	return &crudService[T]{records: records}
+}
+
+func (s *crudService[T]) List(ctx context.Context, offset, limit int) ([]T, int64, error) {
+	return s.records.List(ctx, offset, limit)
+}
+
+func (s *crudService[T]) Get(ctx context.Context, id uint) (*T, error) {
+	return s.records.FindByID(ctx, id)
+}
+
+func (s *crudService[T]) GetMany(ctx context.Context, ids []uint) ([]T, error) {
+	return s.records.FindByIDs(ctx, ids)
+}
+
+func (s *crudService[T]) Create(ctx context.Context, record *T) error {
+	return s.records.Create(ctx, record)
+}
+
+func (s *crudService[T]) Update(ctx context.Context, id uint, changes T) (*T, error) {
+	record, err := s.records.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.records.Update(ctx, record, changes); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+func (s *crudService[T]) Delete(ctx context.Context, id uint) error {
+	record, err := s.records.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.records.Delete(ctx, record)
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+)
+
+type CurrencyService interface {
+	// ConvertPrices sets the ConvertedPrice of the books that have a price
+	// to it in currency. Books priced in a currency there is no rate from
+	// are left without one.
+	ConvertPrices(ctx context.Context, books []models.Book, currency string) error
+}
+
+type currencyService struct {
+	converter *money.Converter
+}
+
+func NewCurrencyService(provider money.Provider) CurrencyService {
+	return &currencyService{converter: money.NewConverter(provider)}
+}
+
+func (s *currencyService) ConvertPrices(ctx context.Context, books []models.Book, currency string) error {
+	// Missing rates aren't cached; they're asked for once per call.
+	noRate := map[string]bool{}
+	for i := range books {
+		book := &books[i]
+		if book.Price == nil || book.Currency == "" || noRate[book.Currency] {
+			continue
+		}
+		amount, err := s.converter.Convert(ctx, *book.Price, book.Currency, currency)
+		if errors.Is(err, money.ErrNoRate) {
+			noRate[book.Currency] = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		book.ConvertedPrice = &models.Money{Amount: amount.StringFixed(money.MinorUnits(currency)), Currency: currency}
+	}
+	return nil
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/reports"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/storage"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tabular"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/tenancy"
+	"github.com/google/uuid"
+)
+
+var ErrExportNotReady = errors.New("export has not succeeded")
+
+// An export running for longer is taken to have been abandoned by an
+// instance that stopped, and is run again.
+const exportStaleAfter = time.Hour
+
+// ExportWriter writes the books matching filter to w in format, returning
+// how many it wrote.
+type ExportWriter func(ctx context.Context, format string, filter repositories.BookFilter, w io.Writer) (int, error)
+
+// ExportContentType is the media type of export files in format.
+func ExportContentType(format string) string {
+	if format == reports.PDF {
+		return reports.ContentType
+	}
+	return tabular.ContentType(format)
+}
+
+// ExportService builds exports in the background for the user whose ID
+// every method but Process takes; exports of other users are not found.
+type ExportService interface {
+	// Create queues an export of the books of the tenant ctx acts for that
+	// match filter.
+	Create(ctx context.Context, userID uint, format string, filter repositories.BookFilter) (*models.Export, error)
+	Get(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, error)
+	// DownloadURL returns a URL the export's file can be downloaded from
+	// directly, or "" when the storage offers none and Open must serve it.
+	// Both fail with ErrExportNotReady until the export has succeeded.
+	DownloadURL(ctx context.Context, userID uint, id uuid.UUID) (string, error)
+	Open(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, *storage.Object, error)
+	// Process builds every queued export, one after the other, and deletes
+	// the expired ones. It runs as the process-exports job.
+	Process(ctx context.Context) error
+}
+
+type exportService struct {
+	exports repositories.ExportRepository
+	storage storage.Storage
+	write   ExportWriter
+	// How long finished exports are kept.
+	expireAfter time.Duration
+	// Called when an export is queued, to have it processed without
+	// waiting for the schedule.
+	wake func()
+}
+
+func NewExportService(exports repositories.ExportRepository, storage storage.Storage, write ExportWriter, expireAfter time.Duration, wake func()) ExportService {
+	return &exportService{exports: exports, storage: storage, write: write, expireAfter: expireAfter, wake: wake}
+}
+
+func (s *exportService) Create(ctx context.Context, userID uint, format string, filter repositories.BookFilter) (*models.Export, error) {
+	encoded, err := json.Marshal(filter)
+	if err != nil {
+		return nil, err
+	}
+	export := &models.Export{
+		TenantID: tenancy.ID(ctx),
+		UserID:   userID,
+		Format:   format,
+		Filter:   string(encoded),
+		Status:   models.ExportPending,
+	}
+	if err := s.exports.Create(ctx, export); err != nil {
+		return nil, err
+	}
+	if s.wake != nil {
+		s.wake()
+	}
+	return export, nil
+}
+
+func (s *exportService) Get(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, error) {
+	return s.exports.FindByID(ctx, userID, id)
+}
+
+func (s *exportService) DownloadURL(ctx context.Context, userID uint, id uuid.UUID) (string, error) {
+	export, err := s.succeeded(ctx, userID, id)
+	if err != nil {
+		return "", err
+	}
+	return storage.DownloadURL(ctx, s.storage, export.StorageKey, export.Filename)
+}
+
+func (s *exportService) Open(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, *storage.Object, error) {
+	export, err := s.succeeded(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	obj, err := s.storage.Get(ctx, export.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return export, obj, nil
+}
+
+func (s *exportService) succeeded(ctx context.Context, userID uint, id uuid.UUID) (*models.Export, error) {
+	export, err := s.exports.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != models.ExportSucceeded {
+		return nil, ErrExportNotReady
+	}
+	return export, nil
+}
+
+func (s *exportService) Process(ctx context.Context) error {
+	if err := s.purge(ctx); err != nil {
+		return err
+	}
+	for ctx.Err() == nil {
+		export, err := s.exports.Claim(ctx, time.Now().Add(-exportStaleAfter))
+		if errors.Is(err, repositories.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.run(ctx, export)
+	}
+	return ctx.Err()
+}
+
+// run builds the export and records the outcome. Failures are the export's,
+// not the job's: they are logged and reported to the user as the export
+// failing.
+func (s *exportService) run(ctx context.Context, export *models.Export) {
+	err := s.build(tenancy.NewContext(ctx, export.TenantID), export)
+
+	now := time.Now()
+	expires := now.Add(s.expireAfter)
+	export.FinishedAt = &now
+	export.ExpiresAt = &expires
+	if err != nil {
+		slog.ErrorContext(ctx, "export failed", "export_id", export.ID, "error", err)
+		export.Status = models.ExportFailed
+		export.Error = "The export could not be built."
+	} else {
+		export.Status = models.ExportSucceeded
+	}
+	// The context may be canceled by a shutdown while the export runs; the
+	// outcome is recorded all the same.
+	if err := s.exports.Finish(context.WithoutCancel(ctx), export); err != nil {
+		slog.ErrorContext(ctx, "recording export outcome failed", "export_id", export.ID, "error", err)
+	}
+}
+
+// build writes the file to a temporary file, as storages want to know its
+// size up front, then stores it.
+func (s *exportService) build(ctx context.Context, export *models.Export) error {
+	var filter repositories.BookFilter
+	if err := json.Unmarshal([]byte(export.Filter), &filter); err != nil {
+		return err
+	}
+
+	file, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	rows, err := s.write(ctx, export.Format, filter, file)
+	if err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%s.%s", export.ID, export.Format)
+	if err := s.storage.Put(ctx, key, file, size, ExportContentType(export.Format)); err != nil {
+		return err
+	}
+	export.StorageKey = key
+	export.Filename = fmt.Sprintf("books-%s.%s", export.CreatedAt.UTC().Format("20060102-150405"), export.Format)
+	export.Rows = rows
+	export.Size = size
+	return nil
+}
+
+// purge deletes the exports that expired, with their files.
+func (s *exportService) purge(ctx context.Context) error {
+	for {
+		expired, err := s.exports.Expired(ctx, time.Now(), maintenanceBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range expired {
+			export := &expired[i]
+			if export.StorageKey != "" {
+				if err := s.storage.Delete(ctx, export.StorageKey); err != nil {
+					return err
+				}
+			}
+			if err := s.exports.Delete(ctx, export); err != nil {
+				return err
+			}
+		}
+		if len(expired) < maintenanceBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type FineService interface {
+	List(ctx context.Context, filter repositories.FineFilter, offset, limit int) ([]models.Fine, int64, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Fine, error)
+	// Settle marks the fine paid or waived by the user, failing with
+	// repositories.ErrFineSettled if it was already.
+	Settle(ctx context.Context, id uuid.UUID, status string, userID uint, note string) (*models.Fine, error)
+}
+
+type fineService struct {
+	fines repositories.FineRepository
+}
+
+func NewFineService(fines repositories.FineRepository) FineService {
+	return &fineService{fines: fines}
+}
+
+func (s *fineService) List(ctx context.Context, filter repositories.FineFilter, offset, limit int) ([]models.Fine, int64, error) {
+	return s.fines.List(ctx, filter, offset, limit)
+}
+
+func (s *fineService) Get(ctx context.Context, id uuid.UUID) (*models.Fine, error) {
+	return s.fines.FindByID(ctx, id)
+}
+
+func (s *fineService) Settle(ctx context.Context, id uuid.UUID, status string, userID uint, note string) (*models.Fine, error) {
+	fine, err := s.fines.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if fine.Status != models.FineUnpaid {
+		return nil, repositories.ErrFineSettled
+	}
+
+	now := time.Now()
+	if err := s.fines.Settle(ctx, fine, status, userID, note, now); err != nil {
+		return nil, err
+	}
+	fine.Status, fine.SettledAt, fine.SettledByID, fine.Note = status, &now, &userID, note
+	return fine, nil
+}
+
+// fineRules charges late returns as config.FinesConfig says.
+type fineRules struct {
+	dailyRate decimal.Decimal
+	cap       decimal.Decimal
+	currency  string
+	// Nil never stops members from borrowing.
+	blockAbove *decimal.Decimal
+}
+
+// newFineRules reads cfg, which was validated with the configuration.
+func newFineRules(cfg config.FinesConfig) fineRules {
+	rules := fineRules{
+		dailyRate: decimal.RequireFromString(cfg.DailyRate),
+		cap:       decimal.RequireFromString(cfg.Cap),
+		currency:  cfg.Currency,
+	}
+	if cfg.BlockAbove != "" {
+		blockAbove := decimal.RequireFromString(cfg.BlockAbove)
+		rules.blockAbove = &blockAbove
+	}
+	return rules
+}
+
+// charge returns the fine for returning the loan at the given time, or nil
+// if it is on time or costs nothing.
+func (r fineRules) charge(loan *models.Loan, at time.Time) *models.Fine {
+	late := at.Sub(loan.DueAt)
+	if late <= 0 {
+		return nil
+	}
+	days := int((late + 24*time.Hour - 1) / (24 * time.Hour))
+	amount := r.dailyRate.Mul(decimal.NewFromInt(int64(days)))
+	if r.cap.IsPositive() && amount.GreaterThan(r.cap) {
+		amount = r.cap
+	}
+	amount = amount.Round(money.MinorUnits(r.currency))
+	if !amount.IsPositive() {
+		return nil
+	}
+	return &models.Fine{LoanID: loan.ID, MemberID: loan.MemberID, DaysLate: days, Amount: amount, Currency: r.currency, Status: models.FineUnpaid}
+}
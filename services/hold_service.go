@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+type HoldService interface {
+	// Place puts the member in line for the book. It fails with
+	// repositories.ErrNotFound when the book doesn't exist,
+	// ErrUnknownMember, repositories.ErrCopiesAvailable,
+	// repositories.ErrAlreadyBorrowed, repositories.ErrAlreadyHeld or
+	// repositories.ErrTooManyHolds.
+	Place(ctx context.Context, bookID, memberID uuid.UUID) (*models.Hold, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Hold, error)
+	// BookHolds returns the book's line. Fails with repositories.ErrNotFound
+	// unless the book exists.
+	BookHolds(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Hold, int64, error)
+	// MemberHolds returns the member's active holds. Fails with
+	// repositories.ErrNotFound unless the member exists.
+	MemberHolds(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Hold, int64, error)
+	// Cancel takes the member out of line, passing the copy set aside for
+	// them, if any, to the next. Fails with repositories.ErrHoldClosed.
+	Cancel(ctx context.Context, id uuid.UUID) (*models.Hold, error)
+	// Process passes on the copies not borrowed within the pickup window,
+	// then notifies the members whose holds are ready. It runs as the
+	// process-holds job.
+	Process(ctx context.Context) error
+}
+
+type holdService struct {
+	holds         repositories.HoldRepository
+	books         repositories.BookRepository
+	members       repositories.MemberRepository
+	notifications NotificationService
+	cfg           config.HoldsConfig
+	// Called when a copy is set aside for a hold, to have its member
+	// notified without waiting for the schedule.
+	wake func()
+}
+
+func NewHoldService(holds repositories.HoldRepository, books repositories.BookRepository, members repositories.MemberRepository, notifications NotificationService, cfg config.HoldsConfig, wake func()) HoldService {
+	return &holdService{holds: holds, books: books, members: members, notifications: notifications, cfg: cfg, wake: wake}
+}
+
+func (s *holdService) Place(ctx context.Context, bookID, memberID uuid.UUID) (*models.Hold, error) {
+	_, err := s.members.FindByID(ctx, memberID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownMember
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hold := models.Hold{BookID: bookID, MemberID: memberID}
+	if err := s.holds.Place(ctx, &hold, s.cfg.MaxPerMember); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, hold.ID)
+}
+
+func (s *holdService) Get(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	hold, err := s.holds.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Position, err = s.holds.Position(ctx, hold); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+func (s *holdService) BookHolds(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Hold, int64, error) {
+	if _, err := s.books.FindByID(ctx, bookID); err != nil {
+		return nil, 0, err
+	}
+	return s.holds.List(ctx, repositories.HoldFilter{BookID: bookID}, offset, limit)
+}
+
+func (s *holdService) MemberHolds(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Hold, int64, error) {
+	if _, err := s.members.FindByID(ctx, memberID); err != nil {
+		return nil, 0, err
+	}
+	return s.holds.List(ctx, repositories.HoldFilter{MemberID: memberID}, offset, limit)
+}
+
+func (s *holdService) Cancel(ctx context.Context, id uuid.UUID) (*models.Hold, error) {
+	hold, err := s.holds.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	wasReady := hold.Status == models.HoldReady
+
+	now := time.Now()
+	if err := s.holds.Cancel(ctx, hold, now, now.Add(s.cfg.PickupWindow)); err != nil {
+		return nil, err
+	}
+	if wasReady {
+		s.wake()
+	}
+	return hold, nil
+}
+
+func (s *holdService) Process(ctx context.Context) error {
+	now := time.Now()
+	expired, err := s.holds.Expire(ctx, now, now.Add(s.cfg.PickupWindow))
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		slog.InfoContext(ctx, "expired holds not picked up", "count", expired)
+	}
+	return s.notifications.NotifyReadyHolds(ctx)
+}
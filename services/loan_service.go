@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrUnknownBook   = errors.New("book_id does not reference an existing book")
+	ErrUnknownMember = errors.New("member_id does not reference an existing member")
+	ErrUnpaidFines   = errors.New("member owes too much in unpaid fines to borrow")
+)
+
+type LoanService interface {
+	// Checkout lends a copy of the book to the member for the configured
+	// loan duration, from the branch or, if nil, the unassigned copies. It
+	// fails with ErrUnpaidFines if they owe more than the configured limit.
+	Checkout(ctx context.Context, bookID, memberID uuid.UUID, branchID *uint) (*models.Loan, error)
+	// Return fines the member if the loan is late. The copy goes to the
+	// first member in line for the book, if any.
+	Return(ctx context.Context, id uuid.UUID) (*models.Loan, error)
+	// ActiveLoans fails with repositories.ErrNotFound unless the member
+	// exists.
+	ActiveLoans(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Loan, int64, error)
+	Overdue(ctx context.Context, offset, limit int) ([]models.Loan, int64, error)
+}
+
+type loanService struct {
+	loans    repositories.LoanRepository
+	members  repositories.MemberRepository
+	fines    repositories.FineRepository
+	branches repositories.BranchRepository
+	cfg      config.LendingConfig
+	rules    fineRules
+	// holdReady is called when a returned copy is set aside for a hold.
+	holdReady func()
+}
+
+// NewLoanService calls holdReady when a returned copy is set aside for a
+// hold, for its member to be notified.
+func NewLoanService(loans repositories.LoanRepository, members repositories.MemberRepository, fines repositories.FineRepository, branches repositories.BranchRepository, cfg config.LendingConfig, holdReady func()) LoanService {
+	return &loanService{loans: loans, members: members, fines: fines, branches: branches, cfg: cfg, rules: newFineRules(cfg.Fines), holdReady: holdReady}
+}
+
+func (s *loanService) Checkout(ctx context.Context, bookID, memberID uuid.UUID, branchID *uint) (*models.Loan, error) {
+	_, err := s.members.FindByID(ctx, memberID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownMember
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.rules.blockAbove != nil {
+		owed, err := s.fines.Owed(ctx, memberID, s.rules.currency)
+		if err != nil {
+			return nil, err
+		}
+		if owed.GreaterThan(*s.rules.blockAbove) {
+			return nil, ErrUnpaidFines
+		}
+	}
+	if err := findBranch(ctx, s.branches, branchID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	loan := models.Loan{BookID: bookID, MemberID: memberID, BranchID: branchID, LoanedAt: now, DueAt: now.Add(s.cfg.LoanDuration)}
+	err = s.loans.Checkout(ctx, &loan)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownBook
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.loans.FindByID(ctx, loan.ID)
+}
+
+func (s *loanService) Return(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	loan, err := s.loans.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if loan.ReturnedAt != nil {
+		return nil, repositories.ErrAlreadyReturned
+	}
+
+	now := time.Now()
+	fine := s.rules.charge(loan, now)
+	hold, err := s.loans.Return(ctx, loan, now, fine, now.Add(s.cfg.Holds.PickupWindow))
+	if err != nil {
+		return nil, err
+	}
+	if hold != nil {
+		s.holdReady()
+	}
+	loan.ReturnedAt, loan.Fine = &now, fine
+	return loan, nil
+}
+
+func (s *loanService) ActiveLoans(ctx context.Context, memberID uuid.UUID, offset, limit int) ([]models.Loan, int64, error) {
+	if _, err := s.members.FindByID(ctx, memberID); err != nil {
+		return nil, 0, err
+	}
+	return s.loans.ListActiveByMember(ctx, memberID, offset, limit)
+}
+
+func (s *loanService) Overdue(ctx context.Context, offset, limit int) ([]models.Loan, int64, error) {
+	return s.loans.ListOverdue(ctx, time.Now(), offset, limit)
+}
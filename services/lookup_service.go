@@ -0,0 +1,27 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/lookup"
+)
+
+type LookupService interface {
+	// Lookup fetches the metadata of a valid ISBN-10 or ISBN-13, failing
+	// with lookup.ErrNotFound when no catalog knows it.
+	Lookup(ctx context.Context, number string) (*lookup.Metadata, error)
+}
+
+type lookupService struct {
+	provider lookup.Provider
+}
+
+func NewLookupService(provider lookup.Provider) LookupService {
+	return &lookupService{provider: provider}
+}
+
+// Both forms of an ISBN are looked up (and cached) as ISBN-13.
+func (s *lookupService) Lookup(ctx context.Context, number string) (*lookup.Metadata, error) {
+	return s.provider.Lookup(ctx, isbn.To13(number))
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+// How many rows the maintenance tasks handle at a time.
+const maintenanceBatchSize = 100
+
+// MaintenanceService holds the tasks the job runner runs. Each logs what it
+// did.
+type MaintenanceService interface {
+	// PurgeDeletedBooks permanently deletes the books soft-deleted longer
+	// ago than the configured retention, except those with copies on loan.
+	PurgeDeletedBooks(ctx context.Context) error
+	// RefreshRatings corrects the books whose rating average or review
+	// count has drifted from their reviews.
+	RefreshRatings(ctx context.Context) error
+}
+
+type maintenanceService struct {
+	books   repositories.BookRepository
+	reviews repositories.ReviewRepository
+	cfg     config.JobsConfig
+}
+
+func NewMaintenanceService(books repositories.BookRepository, reviews repositories.ReviewRepository, cfg config.JobsConfig) MaintenanceService {
+	return &maintenanceService{books: books, reviews: reviews, cfg: cfg}
+}
+
+func (s *maintenanceService) PurgeDeletedBooks(ctx context.Context) error {
+	cutoff := time.Now().Add(-s.cfg.PurgeAfter)
+	purged := 0
+	for {
+		books, err := s.books.DeletedBefore(ctx, cutoff, maintenanceBatchSize)
+		if err != nil {
+			return err
+		}
+		for i := range books {
+			if err := s.books.DeletePermanently(ctx, &books[i]); err != nil {
+				return err
+			}
+			purged++
+		}
+		if len(books) < maintenanceBatchSize {
+			break
+		}
+	}
+	slog.InfoContext(ctx, "purged deleted books", "count", purged, "deleted_before", cutoff)
+	return nil
+}
+
+func (s *maintenanceService) RefreshRatings(ctx context.Context) error {
+	corrected, err := s.reviews.RefreshBookStats(ctx)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "refreshed book ratings", "corrected", corrected)
+	return nil
+}
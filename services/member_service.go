@@ -0,0 +1,35 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+type MemberService interface {
+	List(ctx context.Context, offset, limit int) ([]models.Member, int64, error)
+	Get(ctx context.Context, id uuid.UUID) (*models.Member, error)
+	Create(ctx context.Context, member *models.Member) error
+}
+
+type memberService struct {
+	members repositories.MemberRepository
+}
+
+func NewMemberService(members repositories.MemberRepository) MemberService {
+	return &memberService{members: members}
+}
+
+func (s *memberService) List(ctx context.Context, offset, limit int) ([]models.Member, int64, error) {
+	return s.members.List(ctx, offset, limit)
+}
+
+func (s *memberService) Get(ctx context.Context, id uuid.UUID) (*models.Member, error) {
+	return s.members.FindByID(ctx, id)
+}
+
+func (s *memberService) Create(ctx context.Context, member *models.Member) error {
+	return s.members.Create(ctx, member)
+}
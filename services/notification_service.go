@@ -0,0 +1,258 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/notify"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var ErrWebhookURLRequired = errors.New("the webhook channel needs a webhook_url")
+
+type NotificationService interface {
+	// Preference fails with repositories.ErrNotFound unless the member
+	// exists. Members who haven't chosen get the default channels.
+	Preference(ctx context.Context, memberID uuid.UUID) (*models.NotificationPreference, error)
+	// SetPreference fails with repositories.ErrNotFound unless the member
+	// exists, or with ErrWebhookURLRequired.
+	SetPreference(ctx context.Context, preference *models.NotificationPreference) error
+	List(ctx context.Context, filter repositories.NotificationFilter, offset, limit int) ([]models.SentNotification, int64, error)
+	// NotifyOverdueLoans reminds members of their overdue loans through
+	// each of their channels, and logs what was sent. A loan is notified
+	// through a channel at most once a day (UTC); failures are retried by
+	// the next run.
+	NotifyOverdueLoans(ctx context.Context) error
+	// NotifyReadyHolds tells members a copy of the book they hold was set
+	// aside for them, once through each of their channels; failures are
+	// retried by the next run while the hold is ready.
+	NotifyReadyHolds(ctx context.Context) error
+}
+
+type notificationService struct {
+	notifications repositories.NotificationRepository
+	members       repositories.MemberRepository
+	loans         repositories.LoanRepository
+	holds         repositories.HoldRepository
+	notifiers     map[string]notify.Notifier
+	cfg           config.NotificationsConfig
+}
+
+// NewNotificationService sends notifications through notifiers, keyed by
+// channel.
+func NewNotificationService(notifications repositories.NotificationRepository, members repositories.MemberRepository, loans repositories.LoanRepository, holds repositories.HoldRepository, notifiers map[string]notify.Notifier, cfg config.NotificationsConfig) NotificationService {
+	return &notificationService{notifications: notifications, members: members, loans: loans, holds: holds, notifiers: notifiers, cfg: cfg}
+}
+
+func (s *notificationService) Preference(ctx context.Context, memberID uuid.UUID) (*models.NotificationPreference, error) {
+	if _, err := s.members.FindByID(ctx, memberID); err != nil {
+		return nil, err
+	}
+	return s.preference(ctx, memberID)
+}
+
+func (s *notificationService) preference(ctx context.Context, memberID uuid.UUID) (*models.NotificationPreference, error) {
+	preference, err := s.notifications.FindPreference(ctx, memberID)
+	if errors.Is(err, repositories.ErrNotFound) {
+		return &models.NotificationPreference{MemberID: memberID, Channels: models.StringList(s.cfg.DefaultChannels)}, nil
+	}
+	return preference, err
+}
+
+func (s *notificationService) SetPreference(ctx context.Context, preference *models.NotificationPreference) error {
+	if _, err := s.members.FindByID(ctx, preference.MemberID); err != nil {
+		return err
+	}
+	if preference.WebhookURL == "" && slices.Contains(preference.Channels, notify.ChannelWebhook) {
+		return ErrWebhookURLRequired
+	}
+	return s.notifications.SavePreference(ctx, preference)
+}
+
+func (s *notificationService) List(ctx context.Context, filter repositories.NotificationFilter, offset, limit int) ([]models.SentNotification, int64, error) {
+	return s.notifications.List(ctx, filter, offset, limit)
+}
+
+func (s *notificationService) NotifyOverdueLoans(ctx context.Context) error {
+	now := time.Now()
+	today := now.UTC().Truncate(24 * time.Hour)
+	sent, failed := 0, 0
+	for offset := 0; ; offset += maintenanceBatchSize {
+		loans, _, err := s.loans.ListOverdue(ctx, now, offset, maintenanceBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, loan := range loans {
+			if loan.Member == nil {
+				continue
+			}
+			preference, err := s.preference(ctx, loan.MemberID)
+			if err != nil {
+				return err
+			}
+			done, err := s.notifications.SentSince(ctx, loan.ID, models.NotificationKindLoanOverdue, today)
+			if err != nil {
+				return err
+			}
+
+			notification := overdueNotification(loan, preference, now)
+			for _, channel := range preference.Channels {
+				notifier, ok := s.notifiers[channel]
+				if !ok || slices.Contains(done, channel) {
+					continue
+				}
+				loanID := loan.ID
+				record := models.SentNotification{
+					TenantID: loan.TenantID,
+					MemberID: loan.MemberID,
+					LoanID:   &loanID,
+					Kind:     notification.Kind,
+					Channel:  channel,
+					Subject:  notification.Subject,
+				}
+				if err := notifier.Send(ctx, notification); err != nil {
+					slog.WarnContext(ctx, "notifying overdue loan failed", "loan_id", loan.ID, "channel", channel, "error", err)
+					record.Error = err.Error()
+					failed++
+				} else {
+					record.Sent = true
+					sent++
+				}
+				if err := s.notifications.Record(ctx, &record); err != nil {
+					return err
+				}
+			}
+		}
+		if len(loans) < maintenanceBatchSize {
+			break
+		}
+	}
+	slog.InfoContext(ctx, "sent overdue loan notifications", "sent", sent, "failed", failed)
+	return nil
+}
+
+func (s *notificationService) NotifyReadyHolds(ctx context.Context) error {
+	sent, failed := 0, 0
+	for offset := 0; ; offset += maintenanceBatchSize {
+		holds, err := s.holds.ListReady(ctx, offset, maintenanceBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, hold := range holds {
+			if hold.Member == nil {
+				continue
+			}
+			preference, err := s.preference(ctx, hold.MemberID)
+			if err != nil {
+				return err
+			}
+			done, err := s.notifications.SentForHold(ctx, hold.ID)
+			if err != nil {
+				return err
+			}
+
+			notification := holdReadyNotification(hold, preference)
+			for _, channel := range preference.Channels {
+				notifier, ok := s.notifiers[channel]
+				if !ok || slices.Contains(done, channel) {
+					continue
+				}
+				holdID := hold.ID
+				record := models.SentNotification{
+					TenantID: hold.TenantID,
+					MemberID: hold.MemberID,
+					HoldID:   &holdID,
+					Kind:     notification.Kind,
+					Channel:  channel,
+					Subject:  notification.Subject,
+				}
+				if err := notifier.Send(ctx, notification); err != nil {
+					slog.WarnContext(ctx, "notifying ready hold failed", "hold_id", hold.ID, "channel", channel, "error", err)
+					record.Error = err.Error()
+					failed++
+				} else {
+					record.Sent = true
+					sent++
+				}
+				if err := s.notifications.Record(ctx, &record); err != nil {
+					return err
+				}
+			}
+		}
+		if len(holds) < maintenanceBatchSize {
+			break
+		}
+	}
+	if sent > 0 || failed > 0 {
+		slog.InfoContext(ctx, "sent ready hold notifications", "sent", sent, "failed", failed)
+	}
+	return nil
+}
+
+// overdueLoanData is what webhooks get about an overdue loan.
+type overdueLoanData struct {
+	LoanID      uuid.UUID `json:"loan_id"`
+	BookID      uuid.UUID `json:"book_id"`
+	BookTitle   string    `json:"book_title"`
+	DueAt       time.Time `json:"due_at"`
+	DaysOverdue int       `json:"days_overdue"`
+}
+
+func overdueNotification(loan models.Loan, preference *models.NotificationPreference, now time.Time) notify.Notification {
+	data := overdueLoanData{LoanID: loan.ID, BookID: loan.BookID, DueAt: loan.DueAt, DaysOverdue: int(now.Sub(loan.DueAt) / (24 * time.Hour))}
+	title := "a book"
+	if loan.Book != nil {
+		data.BookTitle = loan.Book.Title
+		title = `"` + loan.Book.Title + `"`
+	}
+	return notify.Notification{
+		Kind:    models.NotificationKindLoanOverdue,
+		Subject: "Your loan of " + title + " is overdue",
+		Body: fmt.Sprintf("Hello %s,\n\nYour loan of %s was due on %s. Please return it to the library as soon as you can.\n",
+			loan.Member.Name, title, loan.DueAt.UTC().Format(time.RFC1123)),
+		Data:       data,
+		MemberID:   loan.MemberID,
+		Name:       loan.Member.Name,
+		Email:      loan.Member.Email,
+		WebhookURL: preference.WebhookURL,
+	}
+}
+
+// holdReadyData is what webhooks get about a ready hold.
+type holdReadyData struct {
+	HoldID    uuid.UUID `json:"hold_id"`
+	BookID    uuid.UUID `json:"book_id"`
+	BookTitle string    `json:"book_title"`
+	BranchID  *uint     `json:"branch_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func holdReadyNotification(hold models.Hold, preference *models.NotificationPreference) notify.Notification {
+	data := holdReadyData{HoldID: hold.ID, BookID: hold.BookID, BranchID: hold.BranchID}
+	if hold.ExpiresAt != nil {
+		data.ExpiresAt = *hold.ExpiresAt
+	}
+	title := "the book you placed on hold"
+	if hold.Book != nil {
+		data.BookTitle = hold.Book.Title
+		title = `"` + hold.Book.Title + `"`
+	}
+	return notify.Notification{
+		Kind:    models.NotificationKindHoldReady,
+		Subject: "Your hold on " + title + " is ready",
+		Body: fmt.Sprintf("Hello %s,\n\nA copy of %s is waiting for you at the library. It is kept for you until %s, then goes to the next member in line.\n",
+			hold.Member.Name, title, data.ExpiresAt.UTC().Format(time.RFC1123)),
+		Data:       data,
+		MemberID:   hold.MemberID,
+		Name:       hold.Member.Name,
+		Email:      hold.Member.Email,
+		WebhookURL: preference.WebhookURL,
+	}
+}
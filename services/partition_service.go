@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+// PartitionService keeps the audit log and loans in monthly partitions on
+// Postgres, so that they can grow large without slowing down the listings
+// of recent entries, and old audit entries can be dropped a month at a time
+// instead of deleted row by row.
+type PartitionService interface {
+	// Partition converts the tables not partitioned yet, their rows so far
+	// making up the first partition, up to the end of this month, and then
+	// maintains them. It is safe to run again.
+	Partition(ctx context.Context) error
+	// Maintain creates the partitions of the configured number of months
+	// ahead, and drops the audit log partitions past retention.
+	Maintain(ctx context.Context) error
+	// List returns the partitions of every partitioned table.
+	List(ctx context.Context) ([]repositories.Partition, error)
+}
+
+type partitionService struct {
+	partitions repositories.PartitionRepository
+	cfg        config.DatabaseConfig
+}
+
+func NewPartitionService(partitions repositories.PartitionRepository, cfg config.DatabaseConfig) PartitionService {
+	return &partitionService{partitions: partitions, cfg: cfg}
+}
+
+func (s *partitionService) Partition(ctx context.Context) error {
+	until := monthOf(time.Now()).AddDate(0, 1, 0)
+	for _, table := range repositories.PartitionedTables {
+		converted, err := s.partitions.Partition(ctx, table, until)
+		if err != nil {
+			return err
+		}
+		if converted {
+			slog.InfoContext(ctx, "partitioned table", "table", table.Name, "first_partition_until", until)
+		}
+	}
+	return s.Maintain(ctx)
+}
+
+func (s *partitionService) Maintain(ctx context.Context) error {
+	now := time.Now()
+	through := monthOf(now).AddDate(0, s.cfg.PartitionsAhead, 0)
+	for _, table := range repositories.PartitionedTables {
+		partitions, err := s.partitions.List(ctx, table)
+		if err != nil {
+			return err
+		}
+		if len(partitions) == 0 {
+			slog.WarnContext(ctx, "table is not partitioned, run the partition command", "table", table.Name)
+			continue
+		}
+
+		// From the end of the last partition, so months can't be skipped.
+		var next time.Time
+		for _, partition := range partitions {
+			if partition.To != nil && partition.To.After(next) {
+				next = *partition.To
+			}
+		}
+		var created []string
+		for month := next; !month.After(through); month = month.AddDate(0, 1, 0) {
+			ok, err := s.partitions.CreateMonth(ctx, table, month)
+			if err != nil {
+				return err
+			}
+			if ok {
+				created = append(created, month.Format("2006-01"))
+			}
+		}
+		slog.InfoContext(ctx, "created partitions", "table", table.Name, "months", created)
+
+		if table != repositories.AuditLogPartitions || s.cfg.AuditRetention == 0 {
+			continue
+		}
+		cutoff := now.Add(-s.cfg.AuditRetention)
+		var dropped []string
+		for _, partition := range partitions {
+			if partition.To == nil || partition.To.After(cutoff) {
+				continue
+			}
+			if err := s.partitions.Drop(ctx, partition); err != nil {
+				return err
+			}
+			dropped = append(dropped, partition.Name)
+		}
+		slog.InfoContext(ctx, "dropped audit log partitions", "partitions", dropped, "ended_before", cutoff)
+	}
+	return nil
+}
+
+func (s *partitionService) List(ctx context.Context) ([]repositories.Partition, error) {
+	var all []repositories.Partition
+	for _, table := range repositories.PartitionedTables {
+		partitions, err := s.partitions.List(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, partitions...)
+	}
+	return all, nil
+}
+
+// monthOf returns the start of the month of t, in UTC.
+func monthOf(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
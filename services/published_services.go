@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/live"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/google/uuid"
+)
+
+// The decorators below publish what was done to the admin feed, for the
+// admins following the instance live; see the live package.
+
+type publishingLoanService struct {
+	LoanService
+	feed events.Publisher
+}
+
+// NewPublishingLoanService publishes loan.created and loan.returned events
+// with the loan to feed.
+func NewPublishingLoanService(loans LoanService, feed events.Publisher) LoanService {
+	return &publishingLoanService{LoanService: loans, feed: feed}
+}
+
+func (s *publishingLoanService) Checkout(ctx context.Context, bookID, memberID uuid.UUID, branchID *uint) (*models.Loan, error) {
+	loan, err := s.LoanService.Checkout(ctx, bookID, memberID, branchID)
+	if err == nil {
+		s.feed.Publish(ctx, events.New(live.LoanCreated, loan))
+	}
+	return loan, err
+}
+
+func (s *publishingLoanService) Return(ctx context.Context, id uuid.UUID) (*models.Loan, error) {
+	loan, err := s.LoanService.Return(ctx, id)
+	if err == nil {
+		s.feed.Publish(ctx, events.New(live.LoanReturned, loan))
+	}
+	return loan, err
+}
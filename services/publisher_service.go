@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var ErrPublisherHasBooks = errors.New("publisher still has books")
+
+type PublisherService interface {
+	CRUDService[models.Publisher]
+}
+
+type publisherService struct {
+	CRUDService[models.Publisher]
+	publishers repositories.PublisherRepository
+}
+
+func NewPublisherService(publishers repositories.PublisherRepository) PublisherService {
+	return &publisherService{CRUDService: NewCRUDService[models.Publisher](publishers), publishers: publishers}
+}
+
+// Delete refuses to remove a publisher that books still reference.
+func (s *publisherService) Delete(ctx context.Context, id uint) error {
+	publisher, err := s.publishers.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	books, err := s.publishers.CountBooks(ctx, id)
+	if err != nil {
+		return err
+	}
+	if books > 0 {
+		return ErrPublisherHasBooks
+	}
+
+	return s.publishers.Delete(ctx, publisher)
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var ErrBookInList = errors.New("book is already on the list")
+
+// ReadingListService manages the reading lists and favorites of the user
+// whose ID every method takes; lists of other users are not found.
+type ReadingListService interface {
+	List(ctx context.Context, userID uint, offset, limit int) ([]models.ReadingList, int64, error)
+	Get(ctx context.Context, userID, id uint) (*models.ReadingList, error)
+	Create(ctx context.Context, list *models.ReadingList) error
+	Update(ctx context.Context, userID, id uint, changes models.ReadingList) (*models.ReadingList, error)
+	Delete(ctx context.Context, userID, id uint) error
+	Books(ctx context.Context, userID, id uint) ([]models.ReadingListBook, error)
+	// AddBook fails with ErrUnknownBook or ErrBookInList.
+	AddBook(ctx context.Context, userID, id uint, bookID uuid.UUID) (*models.ReadingListBook, error)
+	RemoveBook(ctx context.Context, userID, id uint, bookID uuid.UUID) error
+	// Share gives the list a new share token, so links handed out before
+	// stop working; Unshare takes it away.
+	Share(ctx context.Context, userID, id uint) (*models.ReadingList, error)
+	Unshare(ctx context.Context, userID, id uint) (*models.ReadingList, error)
+	// Shared returns the list with the given share token and its books,
+	// whoever asks.
+	Shared(ctx context.Context, token string) (*models.ReadingList, []models.ReadingListBook, error)
+
+	Favorites(ctx context.Context, userID uint, offset, limit int) ([]models.Favorite, int64, error)
+	// AddFavorite fails with ErrUnknownBook; adding a favorite twice is no
+	// error.
+	AddFavorite(ctx context.Context, userID uint, bookID uuid.UUID) (*models.Favorite, error)
+	RemoveFavorite(ctx context.Context, userID uint, bookID uuid.UUID) error
+}
+
+type readingListService struct {
+	lists repositories.ReadingListRepository
+	books repositories.BookRepository
+}
+
+func NewReadingListService(lists repositories.ReadingListRepository, books repositories.BookRepository) ReadingListService {
+	return &readingListService{lists: lists, books: books}
+}
+
+func (s *readingListService) List(ctx context.Context, userID uint, offset, limit int) ([]models.ReadingList, int64, error) {
+	return s.lists.List(ctx, userID, offset, limit)
+}
+
+func (s *readingListService) Get(ctx context.Context, userID, id uint) (*models.ReadingList, error) {
+	return s.lists.FindByID(ctx, userID, id)
+}
+
+func (s *readingListService) Create(ctx context.Context, list *models.ReadingList) error {
+	return s.lists.Create(ctx, list)
+}
+
+func (s *readingListService) Update(ctx context.Context, userID, id uint, changes models.ReadingList) (*models.ReadingList, error) {
+	list, err := s.lists.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lists.Update(ctx, list, changes); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *readingListService) Delete(ctx context.Context, userID, id uint) error {
+	list, err := s.lists.FindByID(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+	return s.lists.Delete(ctx, list)
+}
+
+func (s *readingListService) Books(ctx context.Context, userID, id uint) ([]models.ReadingListBook, error) {
+	if _, err := s.lists.FindByID(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	return s.lists.ListBooks(ctx, id)
+}
+
+func (s *readingListService) AddBook(ctx context.Context, userID, id uint, bookID uuid.UUID) (*models.ReadingListBook, error) {
+	if _, err := s.lists.FindByID(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	book, err := s.findBook(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := models.ReadingListBook{ReadingListID: id, BookID: bookID}
+	err = s.lists.AddBook(ctx, &entry)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, ErrBookInList
+	}
+	if err != nil {
+		return nil, err
+	}
+	entry.Book = book
+	return &entry, nil
+}
+
+func (s *readingListService) RemoveBook(ctx context.Context, userID, id uint, bookID uuid.UUID) error {
+	if _, err := s.lists.FindByID(ctx, userID, id); err != nil {
+		return err
+	}
+	return s.lists.RemoveBook(ctx, id, bookID)
+}
+
+func (s *readingListService) Share(ctx context.Context, userID, id uint) (*models.ReadingList, error) {
+	list, err := s.lists.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return nil, err
+	}
+	token := base64.RawURLEncoding.EncodeToString(random)
+	if err := s.lists.SetShareToken(ctx, list, &token); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *readingListService) Unshare(ctx context.Context, userID, id uint) (*models.ReadingList, error) {
+	list, err := s.lists.FindByID(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lists.SetShareToken(ctx, list, nil); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *readingListService) Shared(ctx context.Context, token string) (*models.ReadingList, []models.ReadingListBook, error) {
+	list, err := s.lists.FindByShareToken(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	books, err := s.lists.ListBooks(ctx, list.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return list, books, nil
+}
+
+func (s *readingListService) Favorites(ctx context.Context, userID uint, offset, limit int) ([]models.Favorite, int64, error) {
+	return s.lists.ListFavorites(ctx, userID, offset, limit)
+}
+
+func (s *readingListService) AddFavorite(ctx context.Context, userID uint, bookID uuid.UUID) (*models.Favorite, error) {
+	book, err := s.findBook(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	favorite := models.Favorite{UserID: userID, BookID: bookID}
+	if err := s.lists.AddFavorite(ctx, &favorite); err != nil {
+		return nil, err
+	}
+	favorite.Book = book
+	return &favorite, nil
+}
+
+func (s *readingListService) RemoveFavorite(ctx context.Context, userID uint, bookID uuid.UUID) error {
+	return s.lists.RemoveFavorite(ctx, userID, bookID)
+}
+
+func (s *readingListService) findBook(ctx context.Context, id uuid.UUID) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, id, "Author")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownBook
+	}
+	return book, err
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+// How many similar books are kept per book; more can't be asked for.
+const MaxSimilarBooks = 50
+
+// RecommendationService suggests books from the similarities the
+// refresh-recommendations job computes, so reads never score the catalog
+// themselves. Books added since the last run have no suggestions yet.
+type RecommendationService interface {
+	Similar(ctx context.Context, bookID uuid.UUID, limit int) ([]repositories.ScoredBook, error)
+	ForUser(ctx context.Context, userID uint, limit int) ([]repositories.ScoredBook, error)
+	// Refresh recomputes the similar books of every book.
+	Refresh(ctx context.Context) error
+}
+
+type recommendationService struct {
+	recommendations repositories.RecommendationRepository
+	books           repositories.BookRepository
+}
+
+func NewRecommendationService(recommendations repositories.RecommendationRepository, books repositories.BookRepository) RecommendationService {
+	return &recommendationService{recommendations: recommendations, books: books}
+}
+
+func (s *recommendationService) Similar(ctx context.Context, bookID uuid.UUID, limit int) ([]repositories.ScoredBook, error) {
+	if _, err := s.books.FindByID(ctx, bookID); err != nil {
+		return nil, err
+	}
+	return s.recommendations.FindSimilar(ctx, bookID, limit)
+}
+
+func (s *recommendationService) ForUser(ctx context.Context, userID uint, limit int) ([]repositories.ScoredBook, error) {
+	return s.recommendations.RecommendFor(ctx, userID, limit)
+}
+
+func (s *recommendationService) Refresh(ctx context.Context) error {
+	start := time.Now()
+	refreshed := 0
+	err := s.books.Each(ctx, repositories.BookFilter{}, maintenanceBatchSize, func(books []models.Book) error {
+		for _, book := range books {
+			similar, err := s.recommendations.ComputeSimilar(ctx, book.ID, MaxSimilarBooks)
+			if err != nil {
+				return err
+			}
+			if err := s.recommendations.ReplaceSimilar(ctx, book.ID, similar); err != nil {
+				return err
+			}
+			refreshed++
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// What wasn't recomputed belongs to books deleted since.
+	stale, err := s.recommendations.DeleteComputedBefore(ctx, start)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "refreshed recommendations", "books", refreshed, "removed", stale)
+	return nil
+}
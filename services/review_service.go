@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var ErrAlreadyReviewed = errors.New("user has already reviewed this book")
+
+type ReviewService interface {
+	// List fails with repositories.ErrNotFound unless the book exists.
+	List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Review, int64, error)
+	Create(ctx context.Context, review *models.Review) error
+}
+
+type reviewService struct {
+	reviews repositories.ReviewRepository
+	books   repositories.BookRepository
+}
+
+func NewReviewService(reviews repositories.ReviewRepository, books repositories.BookRepository) ReviewService {
+	return &reviewService{reviews: reviews, books: books}
+}
+
+func (s *reviewService) List(ctx context.Context, bookID uuid.UUID, offset, limit int) ([]models.Review, int64, error) {
+	if _, err := s.books.FindByID(ctx, bookID); err != nil {
+		return nil, 0, err
+	}
+	return s.reviews.ListByBook(ctx, bookID, offset, limit)
+}
+
+func (s *reviewService) Create(ctx context.Context, review *models.Review) error {
+	err := s.reviews.Create(ctx, review)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return ErrAlreadyReviewed
+	}
+	return err
+}
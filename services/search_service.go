@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/search"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrSearchFilters = errors.New("filtering searches needs the search index, which is not configured")
+	// ErrSearchUnavailable means the search index could not be queried.
+	ErrSearchUnavailable = errors.New("search index unavailable")
+)
+
+type SearchResults struct {
+	Hits  []repositories.BookSearchHit
+	Total int64
+	// Only the search index counts facets; nil without one.
+	Facets *search.Facets
+}
+
+type SearchService interface {
+	// Search matches q.Text against the title, description and author name
+	// of the books, best matches first, with the author loaded.
+	Search(ctx context.Context, q search.Query) (*SearchResults, error)
+}
+
+type databaseSearchService struct {
+	books BookService
+}
+
+// NewDatabaseSearchService searches with the database's own search, which
+// supports no filters.
+func NewDatabaseSearchService(books BookService) SearchService {
+	return &databaseSearchService{books: books}
+}
+
+func (s *databaseSearchService) Search(ctx context.Context, q search.Query) (*SearchResults, error) {
+	if q.Filter != (repositories.BookFilter{}) {
+		return nil, ErrSearchFilters
+	}
+	hits, total, err := s.books.Search(ctx, q.Text, q.Offset, q.Limit)
+	if err != nil {
+		return nil, err
+	}
+	return &SearchResults{Hits: hits, Total: total}, nil
+}
+
+type indexSearchService struct {
+	client *search.Client
+	books  repositories.BookRepository
+}
+
+// NewIndexSearchService searches the index behind client and loads the
+// books found from the database.
+func NewIndexSearchService(client *search.Client, books repositories.BookRepository) SearchService {
+	return &indexSearchService{client: client, books: books}
+}
+
+func (s *indexSearchService) Search(ctx context.Context, q search.Query) (*SearchResults, error) {
+	found, err := s.client.Search(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSearchUnavailable, err)
+	}
+	results := &SearchResults{Hits: []repositories.BookSearchHit{}, Total: found.Total, Facets: &found.Facets}
+	if len(found.Hits) == 0 {
+		return results, nil
+	}
+
+	ids := make([]uuid.UUID, len(found.Hits))
+	for i, hit := range found.Hits {
+		ids[i] = hit.ID
+	}
+	books, err := s.books.FindByIDs(ctx, ids, "Author")
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]models.Book, len(books))
+	for _, book := range books {
+		byID[book.ID] = book
+	}
+
+	// Books deleted since they were indexed are left out.
+	for _, hit := range found.Hits {
+		book, ok := byID[hit.ID]
+		if !ok {
+			continue
+		}
+		highlights := map[string]string{"title": book.Title, "description": book.Description, "author": ""}
+		if book.Author != nil {
+			highlights["author"] = book.Author.Name
+		}
+		for field, fragment := range hit.Highlights {
+			highlights[field] = fragment
+		}
+		results.Hits = append(results.Hits, repositories.BookSearchHit{Book: book, Rank: hit.Score, Highlights: highlights})
+	}
+	return results, nil
+}
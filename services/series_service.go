@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrVolumeTaken  = errors.New("series already has a book with that volume number")
+	ErrBookInSeries = errors.New("book is already in the series")
+)
+
+type SeriesService interface {
+	List(ctx context.Context, offset, limit int) ([]models.Series, int64, error)
+	Get(ctx context.Context, id uint) (*models.Series, error)
+	Create(ctx context.Context, series *models.Series) error
+	Update(ctx context.Context, id uint, changes models.Series) (*models.Series, error)
+	Delete(ctx context.Context, id uint) error
+	// Volumes returns the books of the series in volume order.
+	Volumes(ctx context.Context, id uint) ([]models.SeriesVolume, error)
+	// AddBook makes the book the given volume of the series. It fails with
+	// ErrUnknownBook, ErrBookInSeries or ErrVolumeTaken.
+	AddBook(ctx context.Context, id uint, bookID uuid.UUID, volume int) (*models.SeriesVolume, error)
+	RemoveBook(ctx context.Context, id uint, bookID uuid.UUID) error
+}
+
+type seriesService struct {
+	series repositories.SeriesRepository
+	books  repositories.BookRepository
+}
+
+func NewSeriesService(series repositories.SeriesRepository, books repositories.BookRepository) SeriesService {
+	return &seriesService{series: series, books: books}
+}
+
+func (s *seriesService) List(ctx context.Context, offset, limit int) ([]models.Series, int64, error) {
+	return s.series.List(ctx, offset, limit)
+}
+
+func (s *seriesService) Get(ctx context.Context, id uint) (*models.Series, error) {
+	return s.series.FindByID(ctx, id)
+}
+
+func (s *seriesService) Create(ctx context.Context, series *models.Series) error {
+	return s.series.Create(ctx, series)
+}
+
+func (s *seriesService) Update(ctx context.Context, id uint, changes models.Series) (*models.Series, error) {
+	series, err := s.series.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.series.Update(ctx, series, changes); err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+func (s *seriesService) Delete(ctx context.Context, id uint) error {
+	series, err := s.series.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.series.Delete(ctx, series)
+}
+
+func (s *seriesService) Volumes(ctx context.Context, id uint) ([]models.SeriesVolume, error) {
+	if _, err := s.series.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.series.ListVolumes(ctx, id)
+}
+
+func (s *seriesService) AddBook(ctx context.Context, id uint, bookID uuid.UUID, volume int) (*models.SeriesVolume, error) {
+	if _, err := s.series.FindByID(ctx, id); err != nil {
+		return nil, err
+	}
+	book, err := s.books.FindByID(ctx, bookID, "Author")
+	if errors.Is(err, repositories.ErrNotFound) {
+		return nil, ErrUnknownBook
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Checked first for a precise error; the unique indexes settle races.
+	if _, err := s.series.FindBookVolume(ctx, id, bookID); err == nil {
+		return nil, ErrBookInSeries
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+	if _, err := s.series.FindVolume(ctx, id, volume); err == nil {
+		return nil, ErrVolumeTaken
+	} else if !errors.Is(err, repositories.ErrNotFound) {
+		return nil, err
+	}
+
+	entry := models.SeriesVolume{SeriesID: id, BookID: bookID, Volume: volume}
+	if err := s.series.AddVolume(ctx, &entry); err != nil {
+		return nil, err
+	}
+	entry.Book = book
+	return &entry, nil
+}
+
+func (s *seriesService) RemoveBook(ctx context.Context, id uint, bookID uuid.UUID) error {
+	if _, err := s.series.FindByID(ctx, id); err != nil {
+		return err
+	}
+	volume, err := s.series.FindBookVolume(ctx, id, bookID)
+	if err != nil {
+		return err
+	}
+	return s.series.RemoveVolume(ctx, volume)
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+const (
+	// How many months back BooksAddedPerMonth goes, the current one
+	// included.
+	statsMonths = 12
+	// How many books the top-rated and most-borrowed lists hold.
+	statsTopBooks = 10
+)
+
+type Stats struct {
+	Totals             repositories.StatsTotals     `json:"totals"`
+	BooksByCategory    []repositories.CategoryCount `json:"books_by_category"`
+	BooksAddedPerMonth []repositories.MonthCount    `json:"books_added_per_month"`
+	TopRated           []models.Book                `json:"top_rated"`
+	MostBorrowed       []repositories.BorrowCount   `json:"most_borrowed"`
+	GeneratedAt        time.Time                    `json:"generated_at"`
+}
+
+type StatsService interface {
+	// Get computes the statistics of the catalog and its loans. Every
+	// month of the last year is listed in BooksAddedPerMonth, those
+	// without books with a count of 0.
+	Get(ctx context.Context) (*Stats, error)
+}
+
+type statsService struct {
+	stats repositories.StatsRepository
+}
+
+func NewStatsService(stats repositories.StatsRepository) StatsService {
+	return &statsService{stats: stats}
+}
+
+func (s *statsService) Get(ctx context.Context) (*Stats, error) {
+	now := time.Now().UTC()
+	stats := &Stats{GeneratedAt: now}
+
+	var err error
+	if stats.Totals, err = s.stats.Totals(ctx, now); err != nil {
+		return nil, err
+	}
+	if stats.BooksByCategory, err = s.stats.BooksByCategory(ctx); err != nil {
+		return nil, err
+	}
+	since := time.Date(now.Year(), now.Month()-statsMonths+1, 1, 0, 0, 0, 0, time.UTC)
+	added, err := s.stats.BooksAddedPerMonth(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	stats.BooksAddedPerMonth = fillMonths(added, since, statsMonths)
+	if stats.TopRated, err = s.stats.TopRated(ctx, statsTopBooks); err != nil {
+		return nil, err
+	}
+	if stats.MostBorrowed, err = s.stats.MostBorrowed(ctx, statsTopBooks); err != nil {
+		return nil, err
+	}
+
+	// Empty lists rather than nulls for a fresh catalog.
+	if stats.BooksByCategory == nil {
+		stats.BooksByCategory = []repositories.CategoryCount{}
+	}
+	if stats.TopRated == nil {
+		stats.TopRated = []models.Book{}
+	}
+	if stats.MostBorrowed == nil {
+		stats.MostBorrowed = []repositories.BorrowCount{}
+	}
+	return stats, nil
+}
+
+// fillMonths returns the counts of the months months starting at since,
+// taking them from counts and 0 for the months missing from it.
+func fillMonths(counts []repositories.MonthCount, since time.Time, months int) []repositories.MonthCount {
+	byMonth := make(map[string]int64, len(counts))
+	for _, count := range counts {
+		byMonth[count.Month] = count.Books
+	}
+	filled := make([]repositories.MonthCount, months)
+	for i := range filled {
+		month := since.AddDate(0, i, 0).Format("2006-01")
+		filled[i] = repositories.MonthCount{Month: month, Books: byMonth[month]}
+	}
+	return filled
+}
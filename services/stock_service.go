@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+var ErrSameBranch = errors.New("from_branch_id and to_branch_id must differ")
+
+// Availability summarizes how many copies of a book can be lent right now.
+type Availability struct {
+	BookID uuid.UUID `json:"book_id" swaggertype:"string" format:"uuid"`
+	// BranchID is set when the numbers are those of one branch.
+	BranchID        *uint `json:"branch_id,omitempty"`
+	Quantity        int   `json:"quantity"`
+	AvailableCopies int   `json:"available_copies"`
+	OnLoan          int   `json:"on_loan"`
+	Available       bool  `json:"available"`
+	// Branches breaks the book's numbers down by branch; copies at none are
+	// unassigned.
+	Branches []BranchAvailability `json:"branches,omitempty"`
+}
+
+// BranchAvailability is how many copies of a book a branch can lend.
+type BranchAvailability struct {
+	BranchID        uint `json:"branch_id"`
+	Quantity        int  `json:"quantity"`
+	AvailableCopies int  `json:"available_copies"`
+	OnLoan          int  `json:"on_loan"`
+	Available       bool `json:"available"`
+}
+
+type StockService interface {
+	// Adjust changes the copies of the branch or, if nil, the unassigned
+	// ones. It fails with repositories.ErrCopiesOnLoan if it would remove
+	// copies that are lent out, or ErrUnknownBranch.
+	Adjust(ctx context.Context, id uuid.UUID, branchID *uint, delta int) (*models.Book, error)
+	// Availability returns the numbers of the branch, or the book's broken
+	// down by branch if nil. Fails with ErrUnknownBranch.
+	Availability(ctx context.Context, id uuid.UUID, branchID *uint) (*Availability, error)
+	// Transfer moves copies of a book between branches, failing with
+	// ErrUnknownBranch, ErrSameBranch or repositories.ErrNotEnoughCopies.
+	Transfer(ctx context.Context, transfer *models.StockTransfer) error
+	// BookTransfers fails with repositories.ErrNotFound unless the book
+	// exists.
+	BookTransfers(ctx context.Context, id uuid.UUID, offset, limit int) ([]models.StockTransfer, int64, error)
+	// BranchTransfers fails with repositories.ErrNotFound unless the branch
+	// exists.
+	BranchTransfers(ctx context.Context, id uint, offset, limit int) ([]models.StockTransfer, int64, error)
+}
+
+type stockService struct {
+	stock    repositories.StockRepository
+	books    repositories.BookRepository
+	branches repositories.BranchRepository
+}
+
+func NewStockService(stock repositories.StockRepository, books repositories.BookRepository, branches repositories.BranchRepository) StockService {
+	return &stockService{stock: stock, books: books, branches: branches}
+}
+
+func (s *stockService) Adjust(ctx context.Context, id uuid.UUID, branchID *uint, delta int) (*models.Book, error) {
+	if err := findBranch(ctx, s.branches, branchID); err != nil {
+		return nil, err
+	}
+	return s.stock.Adjust(ctx, id, branchID, delta)
+}
+
+func (s *stockService) Availability(ctx context.Context, id uuid.UUID, branchID *uint) (*Availability, error) {
+	book, err := s.books.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := findBranch(ctx, s.branches, branchID); err != nil {
+		return nil, err
+	}
+	stock, err := s.stock.BranchStock(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if branchID != nil {
+		availability := Availability{BookID: book.ID, BranchID: branchID}
+		for _, row := range stock {
+			if row.BranchID == *branchID {
+				availability.Quantity, availability.AvailableCopies = row.Quantity, row.AvailableCopies
+			}
+		}
+		availability.OnLoan = availability.Quantity - availability.AvailableCopies
+		availability.Available = availability.AvailableCopies > 0
+		return &availability, nil
+	}
+
+	availability := Availability{
+		BookID:          book.ID,
+		Quantity:        book.Quantity,
+		AvailableCopies: book.AvailableCopies,
+		OnLoan:          book.Quantity - book.AvailableCopies,
+		Available:       book.AvailableCopies > 0,
+	}
+	for _, row := range stock {
+		availability.Branches = append(availability.Branches, BranchAvailability{
+			BranchID:        row.BranchID,
+			Quantity:        row.Quantity,
+			AvailableCopies: row.AvailableCopies,
+			OnLoan:          row.Quantity - row.AvailableCopies,
+			Available:       row.AvailableCopies > 0,
+		})
+	}
+	return &availability, nil
+}
+
+func (s *stockService) Transfer(ctx context.Context, transfer *models.StockTransfer) error {
+	from, to := transfer.FromBranchID, transfer.ToBranchID
+	if (from == nil && to == nil) || (from != nil && to != nil && *from == *to) {
+		return ErrSameBranch
+	}
+	if err := findBranch(ctx, s.branches, from); err != nil {
+		return err
+	}
+	if err := findBranch(ctx, s.branches, to); err != nil {
+		return err
+	}
+	return s.stock.Transfer(ctx, transfer)
+}
+
+func (s *stockService) BookTransfers(ctx context.Context, id uuid.UUID, offset, limit int) ([]models.StockTransfer, int64, error) {
+	if _, err := s.books.FindByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.stock.ListTransfers(ctx, repositories.TransferFilter{BookID: id}, offset, limit)
+}
+
+func (s *stockService) BranchTransfers(ctx context.Context, id uint, offset, limit int) ([]models.StockTransfer, int64, error) {
+	if _, err := s.branches.FindByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.stock.ListTransfers(ctx, repositories.TransferFilter{BranchID: id}, offset, limit)
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/google/uuid"
+)
+
+// MaxTagLength is the longest a tag name may be, in characters.
+const MaxTagLength = 50
+
+var (
+	ErrInvalidTag = errors.New("tags must be 1 to 50 characters long")
+	ErrTagExists  = errors.New("a tag with that name already exists")
+	ErrSameTag    = errors.New("a tag can't be merged into itself")
+)
+
+type TagService interface {
+	// Autocomplete returns up to limit tags starting with prefix, the most
+	// used first.
+	Autocomplete(ctx context.Context, prefix string, limit int) ([]repositories.TagCount, error)
+	Get(ctx context.Context, name string) (*models.Tag, error)
+	// TagBook adds the tags to the book, creating those that don't exist
+	// yet, and returns the book with all its tags.
+	TagBook(ctx context.Context, bookID uuid.UUID, names []string) (*models.Book, error)
+	UntagBook(ctx context.Context, bookID uuid.UUID, name string) (*models.Book, error)
+	// Rename fails with ErrTagExists if the new name is taken; Merge them
+	// instead.
+	Rename(ctx context.Context, name, newName string) (*models.Tag, error)
+	// Merge moves every book tagged from to into and deletes from.
+	Merge(ctx context.Context, from, into string) (*models.Tag, error)
+}
+
+type tagService struct {
+	tags  repositories.TagRepository
+	books repositories.BookRepository
+}
+
+func NewTagService(tags repositories.TagRepository, books repositories.BookRepository) TagService {
+	return &tagService{tags: tags, books: books}
+}
+
+// NormalizeTag returns name as tags are stored: lowercase, trimmed, with
+// runs of whitespace collapsed to single spaces, so that "Sci  Fi" and
+// "sci fi" are the same tag.
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+func validTag(name string) bool {
+	return name != "" && utf8.RuneCountInString(name) <= MaxTagLength
+}
+
+func (s *tagService) Autocomplete(ctx context.Context, prefix string, limit int) ([]repositories.TagCount, error) {
+	return s.tags.Search(ctx, NormalizeTag(prefix), limit)
+}
+
+func (s *tagService) Get(ctx context.Context, name string) (*models.Tag, error) {
+	return s.tags.FindByName(ctx, NormalizeTag(name))
+}
+
+func (s *tagService) TagBook(ctx context.Context, bookID uuid.UUID, names []string) (*models.Book, error) {
+	normalized := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		name = NormalizeTag(name)
+		if !validTag(name) {
+			return nil, ErrInvalidTag
+		}
+		if !seen[name] {
+			seen[name] = true
+			normalized = append(normalized, name)
+		}
+	}
+
+	book, err := s.books.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	tags, err := s.tags.FindOrCreate(ctx, normalized)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tags.AddToBook(ctx, book, tags); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, bookID, "Tags")
+}
+
+func (s *tagService) UntagBook(ctx context.Context, bookID uuid.UUID, name string) (*models.Book, error) {
+	book, err := s.books.FindByID(ctx, bookID)
+	if err != nil {
+		return nil, err
+	}
+	tag, err := s.tags.FindByName(ctx, NormalizeTag(name))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.tags.RemoveFromBook(ctx, book, tag); err != nil {
+		return nil, err
+	}
+	return s.books.FindByID(ctx, bookID, "Tags")
+}
+
+func (s *tagService) Rename(ctx context.Context, name, newName string) (*models.Tag, error) {
+	newName = NormalizeTag(newName)
+	if !validTag(newName) {
+		return nil, ErrInvalidTag
+	}
+	tag, err := s.tags.FindByName(ctx, NormalizeTag(name))
+	if err != nil {
+		return nil, err
+	}
+	if tag.Name == newName {
+		return tag, nil
+	}
+
+	err = s.tags.Rename(ctx, tag, newName)
+	if errors.Is(err, repositories.ErrDuplicate) {
+		return nil, ErrTagExists
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tag, nil
+}
+
+func (s *tagService) Merge(ctx context.Context, from, into string) (*models.Tag, error) {
+	source, err := s.tags.FindByName(ctx, NormalizeTag(from))
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.tags.FindByName(ctx, NormalizeTag(into))
+	if err != nil {
+		return nil, err
+	}
+	if source.ID == target.ID {
+		return nil, ErrSameTag
+	}
+	if err := s.tags.Merge(ctx, source, target); err != nil {
+		return nil, err
+	}
+	return target, nil
+}
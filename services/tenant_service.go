@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+type TenantService interface {
+	List(ctx context.Context, offset, limit int) ([]models.Tenant, int64, error)
+	Get(ctx context.Context, id uint) (*models.Tenant, error)
+	FindBySlug(ctx context.Context, slug string) (*models.Tenant, error)
+	// Create provisions a tenant, failing with repositories.ErrDuplicate
+	// when its slug is taken. It starts out with no books, authors or
+	// loans.
+	Create(ctx context.Context, tenant *models.Tenant) error
+}
+
+type tenantService struct {
+	tenants repositories.TenantRepository
+}
+
+func NewTenantService(tenants repositories.TenantRepository) TenantService {
+	return &tenantService{tenants: tenants}
+}
+
+func (s *tenantService) List(ctx context.Context, offset, limit int) ([]models.Tenant, int64, error) {
+	return s.tenants.List(ctx, offset, limit)
+}
+
+func (s *tenantService) Get(ctx context.Context, id uint) (*models.Tenant, error) {
+	return s.tenants.FindByID(ctx, id)
+}
+
+func (s *tenantService) FindBySlug(ctx context.Context, slug string) (*models.Tenant, error) {
+	return s.tenants.FindBySlug(ctx, slug)
+}
+
+func (s *tenantService) Create(ctx context.Context, tenant *models.Tenant) error {
+	return s.tenants.Create(ctx, tenant)
+}
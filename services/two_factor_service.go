@@ -0,0 +1,183 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/skip2/go-qrcode"
+)
+
+var (
+	ErrTwoFactorEnabled     = errors.New("two-factor authentication already enabled")
+	ErrTwoFactorNotEnrolled = errors.New("no authenticator being enrolled")
+	ErrTwoFactorDisabled    = errors.New("two-factor authentication not enabled")
+	ErrSecondFactorRequired = errors.New("authenticator or recovery code required")
+	ErrInvalidSecondFactor  = errors.New("invalid authenticator or recovery code")
+)
+
+// How many recovery codes users get, each good for one login.
+const recoveryCodeCount = 10
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// TOTPEnrollment is what an authenticator app needs to be enrolled.
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"otpauth_url"`
+	// A data: URL of a PNG QR code of URL, for the app to scan.
+	QRCode string `json:"qr_code"`
+}
+
+// TwoFactorService manages users' authenticator apps, whose TOTP codes,
+// or a recovery code, they need besides their password to log in.
+type TwoFactorService interface {
+	// Enable starts enrolling a new authenticator, which takes effect once
+	// Verify is given one of its codes.
+	Enable(ctx context.Context, userID uint) (*TOTPEnrollment, error)
+	// Verify confirms the authenticator being enrolled and returns new
+	// recovery codes. They are only ever shown then.
+	Verify(ctx context.Context, userID uint, code string) ([]string, error)
+	// Disable removes the authenticator, given one of its codes or a
+	// recovery code.
+	Disable(ctx context.Context, userID uint, code string) error
+	// Check checks a code or recovery code of user, whose authenticator is
+	// enabled. Each can only be used once.
+	Check(ctx context.Context, user *models.User, code string) error
+}
+
+type twoFactorService struct {
+	users         repositories.UserRepository
+	recoveryCodes repositories.RecoveryCodeRepository
+	cfg           config.AuthConfig
+}
+
+func NewTwoFactorService(users repositories.UserRepository, recoveryCodes repositories.RecoveryCodeRepository, cfg config.AuthConfig) TwoFactorService {
+	return &twoFactorService{users: users, recoveryCodes: recoveryCodes, cfg: cfg}
+}
+
+func (s *twoFactorService) Enable(ctx context.Context, userID uint) (*TOTPEnrollment, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.Update(ctx, user, models.User{TOTPSecret: secret}); err != nil {
+		return nil, err
+	}
+	url := auth.TOTPURL(s.cfg.TOTPIssuer, user.Email, secret)
+	png, err := qrcode.Encode(url, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{
+		Secret: secret,
+		URL:    url,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+func (s *twoFactorService) Verify(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled() {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err := s.checkTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes := make([]string, recoveryCodeCount), make([]string, recoveryCodeCount)
+	for i := range codes {
+		random := make([]byte, 8)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		// Two groups of 5 base32 digits: readable, and plenty for a code
+		// that works once.
+		digits := strings.ToLower(recoveryCodeEncoding.EncodeToString(random))[:10]
+		codes[i] = digits[:5] + "-" + digits[5:]
+		hashes[i] = hashSecret(digits)
+	}
+	if err := s.recoveryCodes.Replace(ctx, user.ID, hashes); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if err := s.users.Update(ctx, user, models.User{TOTPEnabledAt: &now}); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *twoFactorService) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.users.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled() {
+		return ErrTwoFactorDisabled
+	}
+	if err := s.Check(ctx, user, code); err != nil {
+		return err
+	}
+	if err := s.recoveryCodes.DeleteAll(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.users.ClearTOTP(ctx, user)
+}
+
+func (s *twoFactorService) Check(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ErrSecondFactorRequired
+	}
+	if len(code) == 6 {
+		return s.checkTOTP(ctx, user, code)
+	}
+
+	digits := strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	used, err := s.recoveryCodes.Use(ctx, user.ID, hashSecret(digits), time.Now())
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidSecondFactor
+	}
+	return nil
+}
+
+func (s *twoFactorService) checkTOTP(ctx context.Context, user *models.User, code string) error {
+	step, ok := auth.ValidateTOTP(user.TOTPSecret, code, time.Now(), user.TOTPLastStep)
+	if !ok {
+		return ErrInvalidSecondFactor
+	}
+	// A request using the same code at the same time loses.
+	used, err := s.users.UseTOTPStep(ctx, user, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidSecondFactor
+	}
+	return nil
+}
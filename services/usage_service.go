@@ -0,0 +1,34 @@
+package services
+
+import (
+	"context"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+// UsageService reads the request counts the quota meter saves, which lag
+// behind by up to its flush interval.
+type UsageService interface {
+	// List returns the daily counts matching filter, by day then API key.
+	List(ctx context.Context, filter repositories.UsageFilter) ([]models.DailyUsage, error)
+	// Report sums the counts matching filter per user and API key, the
+	// busiest first.
+	Report(ctx context.Context, filter repositories.UsageFilter, offset, limit int) ([]repositories.UsageTotal, int64, error)
+}
+
+type usageService struct {
+	usage repositories.UsageRepository
+}
+
+func NewUsageService(usage repositories.UsageRepository) UsageService {
+	return &usageService{usage: usage}
+}
+
+func (s *usageService) List(ctx context.Context, filter repositories.UsageFilter) ([]models.DailyUsage, error) {
+	return s.usage.List(ctx, filter)
+}
+
+func (s *usageService) Report(ctx context.Context, filter repositories.UsageFilter, offset, limit int) ([]repositories.UsageTotal, int64, error) {
+	return s.usage.Report(ctx, filter, offset, limit)
+}
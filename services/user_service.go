@@ -0,0 +1,245 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/auth"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+)
+
+var (
+	ErrPasswordRequired = errors.New("a password is required to create an account")
+	ErrLastAdmin        = errors.New("the last admin can't be demoted, disabled or deleted")
+	ErrUnknownTenant    = errors.New("no tenant has this ID")
+)
+
+type UserService interface {
+	List(ctx context.Context, filter repositories.UserFilter, offset, limit int) ([]models.User, int64, error)
+	Get(ctx context.Context, id uint) (*models.User, error)
+	// SetRole changes the user's role. Demoting an admin revokes the access
+	// tokens already issued, which carry the old role; refreshing them
+	// picks up the new one.
+	SetRole(ctx context.Context, id uint, role string) (*models.User, error)
+	// SetTenant makes the user a member of the tenant only, or of every
+	// tenant for nil. It revokes the access tokens already issued, which
+	// carry the old tenant.
+	SetTenant(ctx context.Context, id uint, tenantID *uint) (*models.User, error)
+	// Disable disables the account and revokes its tokens, logging it out
+	// everywhere. Disabling a disabled account does nothing.
+	Disable(ctx context.Context, id uint) (*models.User, error)
+	Enable(ctx context.Context, id uint) (*models.User, error)
+	// Delete removes the account and everything that is only its own, and
+	// revokes its access tokens.
+	Delete(ctx context.Context, id uint) error
+	// Unlock lifts the lock failed logins put on the user's account and
+	// forgets the failures, so the next one doesn't lock it again.
+	Unlock(ctx context.Context, id uint) (*models.User, error)
+	// CreateAdmin makes the account of email an admin, creating it with
+	// password when there is none; a password given for an existing
+	// account replaces its own. Either way the address counts as verified.
+	// It reports whether the account was created.
+	CreateAdmin(ctx context.Context, email, password string) (*models.User, bool, error)
+}
+
+type userService struct {
+	users         repositories.UserRepository
+	refreshTokens repositories.RefreshTokenRepository
+	tenants       repositories.TenantRepository
+	revoked       auth.RevocationList
+	cfg           config.AuthConfig
+}
+
+// NewUserService revokes the access tokens of the users it demotes,
+// disables, deletes and moves to other tenants on revoked, which may be
+// nil for callers that don't.
+func NewUserService(users repositories.UserRepository, refreshTokens repositories.RefreshTokenRepository, tenants repositories.TenantRepository, revoked auth.RevocationList, cfg config.AuthConfig) UserService {
+	return &userService{users: users, refreshTokens: refreshTokens, tenants: tenants, revoked: revoked, cfg: cfg}
+}
+
+func (s *userService) List(ctx context.Context, filter repositories.UserFilter, offset, limit int) ([]models.User, int64, error) {
+	return s.users.List(ctx, filter, offset, limit)
+}
+
+func (s *userService) Get(ctx context.Context, id uint) (*models.User, error) {
+	return s.users.FindByID(ctx, id)
+}
+
+func (s *userService) SetRole(ctx context.Context, id uint, role string) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == role {
+		return user, nil
+	}
+	demoted := user.IsAdmin()
+	err = s.keepingAnAdmin(ctx, user, func(ctx context.Context) error {
+		return s.users.Update(ctx, user, models.User{Role: role})
+	})
+	if err != nil {
+		return nil, err
+	}
+	if demoted {
+		if err := s.revokeAccessTokens(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+func (s *userService) SetTenant(ctx context.Context, id uint, tenantID *uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tenantID == nil && user.TenantID == nil || tenantID != nil && user.TenantID != nil && *tenantID == *user.TenantID {
+		return user, nil
+	}
+	if tenantID != nil {
+		if _, err := s.tenants.FindByID(ctx, *tenantID); errors.Is(err, repositories.ErrNotFound) {
+			return nil, ErrUnknownTenant
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	err = s.keepingAnAdmin(ctx, user, func(ctx context.Context) error {
+		return s.users.SetTenant(ctx, user, tenantID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.revokeAccessTokens(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) Disable(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Disabled() {
+		return user, nil
+	}
+	now := time.Now()
+	err = s.keepingAnAdmin(ctx, user, func(ctx context.Context) error {
+		return s.users.SetDisabled(ctx, user, &now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.refreshTokens.RevokeUser(ctx, user.ID, now); err != nil {
+		return nil, err
+	}
+	if err := s.revokeAccessTokens(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) Enable(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Disabled() {
+		return user, nil
+	}
+	if err := s.users.SetDisabled(ctx, user, nil); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (s *userService) Delete(ctx context.Context, id uint) error {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	err = s.keepingAnAdmin(ctx, user, func(ctx context.Context) error {
+		return s.users.Delete(ctx, user)
+	})
+	if err != nil {
+		return err
+	}
+	return s.revokeAccessTokens(ctx, user)
+}
+
+// keepingAnAdmin makes change to user in a transaction, unless user is the
+// only admin of every tenant who can still log in, whom it takes to manage
+// everyone else: then it returns ErrLastAdmin. The admins' rows stay locked until the
+// change is committed, so two admins demoting each other at once can't
+// both succeed.
+func (s *userService) keepingAnAdmin(ctx context.Context, user *models.User, change func(ctx context.Context) error) error {
+	return models.InTransaction(ctx, func(ctx context.Context) error {
+		admins, err := s.users.LockAdmins(ctx)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(admins, user.ID) && len(admins) <= 1 {
+			return ErrLastAdmin
+		}
+		return change(ctx)
+	})
+}
+
+// revokeAccessTokens revokes the access tokens issued to user so far,
+// which are good for TokenTTL at most.
+func (s *userService) revokeAccessTokens(ctx context.Context, user *models.User) error {
+	if s.revoked == nil {
+		return nil
+	}
+	return s.revoked.RevokeUser(ctx, user.ID, time.Now().Add(s.cfg.TokenTTL))
+}
+
+func (s *userService) Unlock(ctx context.Context, id uint) (*models.User, error) {
+	user, err := s.users.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.users.Unlock(ctx, user); err != nil {
+		return nil, err
+	}
+	user.FailedLogins = 0
+	user.LockedUntil = nil
+	return user, nil
+}
+
+func (s *userService) CreateAdmin(ctx context.Context, email, password string) (*models.User, bool, error) {
+	email = strings.ToLower(email)
+	now := time.Now()
+	changes := models.User{Email: email, Role: models.RoleAdmin, EmailVerifiedAt: &now}
+	if password != "" {
+		if err := changes.SetPassword(password); err != nil {
+			return nil, false, err
+		}
+	}
+
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrNotFound) {
+		if password == "" {
+			return nil, false, ErrPasswordRequired
+		}
+		if err := s.users.Create(ctx, &changes); err != nil {
+			return nil, false, err
+		}
+		return &changes, true, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if user.EmailVerifiedAt != nil {
+		changes.EmailVerifiedAt = nil
+	}
+	if err := s.users.Update(ctx, user, changes); err != nil {
+		return nil, false, err
+	}
+	return user, false, nil
+}
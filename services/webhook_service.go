@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/webhooks"
+)
+
+type WebhookService interface {
+	List(ctx context.Context, offset, limit int) ([]models.Webhook, int64, error)
+	Get(ctx context.Context, id uint) (*models.Webhook, error)
+	// Create generates the secret the webhook's deliveries are signed with.
+	// webhooks.ErrPrivateTarget is returned for URLs pointing to private
+	// addresses, unless they are allowed.
+	Create(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id uint) error
+	Deliveries(ctx context.Context, id uint, offset, limit int) ([]models.WebhookDelivery, int64, error)
+}
+
+type webhookService struct {
+	webhooks repositories.WebhookRepository
+	cfg      config.WebhookConfig
+}
+
+func NewWebhookService(webhooks repositories.WebhookRepository, cfg config.WebhookConfig) WebhookService {
+	return &webhookService{webhooks: webhooks, cfg: cfg}
+}
+
+func (s *webhookService) List(ctx context.Context, offset, limit int) ([]models.Webhook, int64, error) {
+	return s.webhooks.List(ctx, offset, limit)
+}
+
+func (s *webhookService) Get(ctx context.Context, id uint) (*models.Webhook, error) {
+	return s.webhooks.FindByID(ctx, id)
+}
+
+func (s *webhookService) Create(ctx context.Context, webhook *models.Webhook) error {
+	if !s.cfg.AllowPrivateTargets {
+		if err := webhooks.CheckTarget(ctx, webhook.URL); err != nil {
+			return err
+		}
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return err
+	}
+	webhook.Secret = "whsec_" + hex.EncodeToString(secret)
+	return s.webhooks.Create(ctx, webhook)
+}
+
+func (s *webhookService) Delete(ctx context.Context, id uint) error {
+	webhook, err := s.webhooks.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.webhooks.Delete(ctx, webhook)
+}
+
+func (s *webhookService) Deliveries(ctx context.Context, id uint, offset, limit int) ([]models.WebhookDelivery, int64, error) {
+	if _, err := s.webhooks.FindByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return s.webhooks.ListDeliveries(ctx, id, offset, limit)
+}
@@ -0,0 +1,39 @@
+// Package slug turns titles into URL-friendly identifiers.
+package slug
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength bounds the length of the slugs Make returns, leaving room for a
+// "-N" suffix in a 255 character column.
+const MaxLength = 200
+
+// Make lowercases s, strips accents and joins the remaining runs of ASCII
+// letters and digits with hyphens: "Café Society!" becomes "cafe-society".
+// The result may be empty.
+func Make(s string) string {
+	var b strings.Builder
+	pendingHyphen := false
+	for _, r := range norm.NFD.String(s) {
+		if b.Len() >= MaxLength {
+			break
+		}
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// A combining accent split off by NFD.
+		case r <= unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			pendingHyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
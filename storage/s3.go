@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 stores objects in a bucket of any S3-compatible service (AWS, MinIO,
+// R2, ...).
+type S3 struct {
+	client *minio.Client
+	bucket string
+	// Signs the URLs of direct downloads, for the public endpoint.
+	presigner     *minio.Client
+	presignExpiry time.Duration
+}
+
+func NewS3(cfg config.S3Config) (*S3, error) {
+	client, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s := &S3{client: client, bucket: cfg.Bucket, presigner: client, presignExpiry: cfg.PresignExpiry}
+
+	if cfg.PublicEndpoint != "" {
+		// Signing needs the bucket's region, which the client would
+		// otherwise ask the public endpoint for.
+		region := cfg.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		s.presigner, err = minio.New(cfg.PublicEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+			Secure: cfg.UseSSL,
+			Region: region,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, key string) (*Object, error) {
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	// GetObject is lazy; Stat performs the request and surfaces a missing key.
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &Object{ReadCloser: obj, ContentType: info.ContentType, Size: info.Size}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) PresignGet(ctx context.Context, key, filename string) (string, error) {
+	if s.presignExpiry == 0 {
+		return "", nil
+	}
+	params := url.Values{}
+	if filename != "" {
+		params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	}
+	u, err := s.presigner.PresignedGetObject(ctx, s.bucket, key, s.presignExpiry, params)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
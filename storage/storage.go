@@ -0,0 +1,58 @@
+// Package storage stores binary objects (book covers, ...) by key, on local
+// disk or in an S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+)
+
+var ErrNotFound = errors.New("object not found")
+
+type Object struct {
+	io.ReadCloser
+	ContentType string
+	Size        int64
+}
+
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get returns ErrNotFound when key doesn't exist.
+	Get(ctx context.Context, key string) (*Object, error)
+	// Delete is a no-op when key doesn't exist.
+	Delete(ctx context.Context, key string) error
+}
+
+// Presigner is implemented by backends clients can download from directly,
+// sparing the API the transfer.
+type Presigner interface {
+	// PresignGet returns a URL anyone holding it can download key from for
+	// a limited time, saved as filename when that isn't empty, or "" when
+	// direct downloads are disabled.
+	PresignGet(ctx context.Context, key, filename string) (string, error)
+}
+
+// DownloadURL returns a URL clients can download key from without going
+// through the API, or "" when s offers none and the API has to serve it.
+func DownloadURL(ctx context.Context, s Storage, key, filename string) (string, error) {
+	presigner, ok := s.(Presigner)
+	if !ok {
+		return "", nil
+	}
+	return presigner.PresignGet(ctx, key, filename)
+}
+
+// New builds the backend selected by cfg.Driver.
+func New(cfg config.StorageConfig) (Storage, error) {
+	switch cfg.Driver {
+	case "local":
+		return NewLocal(cfg.LocalDir)
+	case "s3":
+		return NewS3(cfg.S3)
+	}
+	return nil, fmt.Errorf("unknown storage driver %q", cfg.Driver)
+}
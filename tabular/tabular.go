@@ -0,0 +1,162 @@
+// Package tabular reads and writes spreadsheets (CSV or XLSX) as rows of
+// strings, so import/export code doesn't care which format the client uses.
+package tabular
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	CSV  = "csv"
+	XLSX = "xlsx"
+)
+
+var ErrUnknownFormat = errors.New("format must be csv or xlsx")
+
+const sheetName = "Sheet1"
+
+func ContentType(format string) string {
+	if format == XLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Writer appends rows to a sheet, escaping the cells that would be run as
+// formulas. Close must be called to flush it.
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case CSV:
+		return &csvWriter{csv.NewWriter(w)}, nil
+	case XLSX:
+		f := excelize.NewFile()
+		sw, err := f.NewStreamWriter(sheetName)
+		if err != nil {
+			return nil, err
+		}
+		return &xlsxWriter{file: f, stream: sw, out: w}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// formulaStarts are the characters spreadsheet applications take a cell
+// starting with for the start of a formula.
+const formulaStarts = "=+-@\t\r"
+
+// EscapeFormula prefixes cell with an apostrophe if it would otherwise be
+// run as a formula by the spreadsheet application it is opened in, which
+// would let anyone who can write a book title have it run there. The
+// apostrophe makes it text, and isn't shown.
+func EscapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune(formulaStarts, rune(cell[0])) {
+		return "'" + cell
+	}
+	return cell
+}
+
+// unescapeFormula undoes EscapeFormula, so exported files import as they
+// were exported.
+func unescapeFormula(cell string) string {
+	if len(cell) > 1 && cell[0] == '\'' && strings.ContainsRune(formulaStarts, rune(cell[1])) {
+		return cell[1:]
+	}
+	return cell
+}
+
+// escapeRow returns row with EscapeFormula applied to every cell.
+func escapeRow(row []string) []string {
+	escaped := make([]string, len(row))
+	for i, cell := range row {
+		escaped[i] = EscapeFormula(cell)
+	}
+	return escaped
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func (w *csvWriter) Write(row []string) error {
+	return w.w.Write(escapeRow(row))
+}
+
+func (w *csvWriter) Close() error {
+	w.w.Flush()
+	return w.w.Error()
+}
+
+// xlsxWriter streams rows into the sheet's temporary storage; the workbook
+// itself can only be written out once complete, on Close.
+type xlsxWriter struct {
+	file   *excelize.File
+	stream *excelize.StreamWriter
+	out    io.Writer
+	rows   int
+}
+
+func (w *xlsxWriter) Write(row []string) error {
+	w.rows++
+	cell, err := excelize.CoordinatesToCellName(1, w.rows)
+	if err != nil {
+		return err
+	}
+	values := make([]interface{}, len(row))
+	for i, v := range escapeRow(row) {
+		values[i] = v
+	}
+	return w.stream.SetRow(cell, values)
+}
+
+func (w *xlsxWriter) Close() error {
+	defer w.file.Close()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
+
+// ReadAll returns every row of the first sheet, header included, with the
+// cells a Writer escaped unescaped. Rows may have fewer cells than the
+// header when trailing cells are empty.
+func ReadAll(format string, r io.Reader) ([][]string, error) {
+	rows, err := readAll(format, r)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			row[i] = unescapeFormula(cell)
+		}
+	}
+	return rows, nil
+}
+
+func readAll(format string, r io.Reader) ([][]string, error) {
+	switch format {
+	case CSV:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		return reader.ReadAll()
+	case XLSX:
+		f, err := excelize.OpenReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading xlsx: %w", err)
+		}
+		defer f.Close()
+		return f.GetRows(f.GetSheetName(0))
+	}
+	return nil, ErrUnknownFormat
+}
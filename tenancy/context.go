@@ -0,0 +1,38 @@
+// Package tenancy carries the tenant, the library, a request is made for
+// down to the repositories, which only show and change that tenant's
+// books, authors and loans. Users, members and categories are shared by
+// every tenant, and so are the book event stream and webhooks. Users may be
+// members of a single tenant, whose requests are the only ones they can
+// make; only members of every tenant manage what is shared.
+package tenancy
+
+import "context"
+
+// DefaultID is the tenant of requests naming none, and of everything
+// created before there were tenants.
+const DefaultID uint = 1
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the ID of the tenant it acts
+// for.
+func NewContext(ctx context.Context, tenantID uint) context.Context {
+	return context.WithValue(ctx, contextKey{}, tenantID)
+}
+
+// FromContext returns the tenant ID stored by NewContext, if any. Code
+// acting for no tenant in particular, such as the maintenance jobs, sees
+// every tenant's data.
+func FromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(contextKey{}).(uint)
+	return id, ok
+}
+
+// ID returns the tenant ID stored by NewContext, or DefaultID: what is
+// created outside of any tenant belongs to the default one.
+func ID(ctx context.Context) uint {
+	if id, ok := FromContext(ctx); ok {
+		return id
+	}
+	return DefaultID
+}
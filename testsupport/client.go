@@ -0,0 +1,143 @@
+package testsupport
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"testing"
+)
+
+// Client makes requests to the Server for one test, failing it when a
+// request can't be made at all. Responses are returned whatever their
+// status, for the test to check.
+type Client struct {
+	t      testing.TB
+	server *Server
+	header http.Header
+}
+
+// Client returns a client without credentials for t.
+func (s *Server) Client(t testing.TB) *Client {
+	return &Client{t: t, server: s, header: http.Header{}}
+}
+
+// WithHeader returns a copy of the client sending the header on every
+// request.
+func (c *Client) WithHeader(key, value string) *Client {
+	header := c.header.Clone()
+	header.Set(key, value)
+	return &Client{t: c.t, server: c.server, header: header}
+}
+
+// WithToken returns a copy of the client authenticating with the bearer
+// token.
+func (c *Client) WithToken(token string) *Client {
+	return c.WithHeader("Authorization", "Bearer "+token)
+}
+
+func (c *Client) Get(path string) *Response {
+	return c.Do(http.MethodGet, path, nil)
+}
+
+func (c *Client) Post(path string, body any) *Response {
+	return c.Do(http.MethodPost, path, body)
+}
+
+func (c *Client) Put(path string, body any) *Response {
+	return c.Do(http.MethodPut, path, body)
+}
+
+func (c *Client) Patch(path string, body any) *Response {
+	return c.Do(http.MethodPatch, path, body)
+}
+
+func (c *Client) Delete(path string) *Response {
+	return c.Do(http.MethodDelete, path, nil)
+}
+
+// Do sends a request to path, relative to the server's URL. A []byte body
+// is sent as is, anything else but nil as JSON.
+func (c *Client) Do(method, path string, body any) *Response {
+	c.t.Helper()
+	var reader io.Reader
+	contentType := ""
+	switch body := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(body)
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			c.t.Fatalf("encoding the body of %s %s: %v", method, path, err)
+		}
+		reader, contentType = bytes.NewReader(data), "application/json"
+	}
+
+	req := c.request(method, path, reader)
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return c.send(req)
+}
+
+// Upload posts a multipart form to path, with content as the file field
+// and fields as the other ones.
+func (c *Client) Upload(path, field, filename string, content []byte, fields map[string]string) *Response {
+	c.t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	part, err := form.CreateFormFile(field, filename)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if _, err := part.Write(content); err != nil {
+		c.t.Fatal(err)
+	}
+	if err := form.Close(); err != nil {
+		c.t.Fatal(err)
+	}
+
+	req := c.request(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return c.send(req)
+}
+
+// Request builds a request to path with the client's headers, for the
+// tests that send it themselves, such as to stream the response.
+func (c *Client) Request(method, path string, body io.Reader) *http.Request {
+	c.t.Helper()
+	return c.request(method, path, body)
+}
+
+func (c *Client) request(method, path string, body io.Reader) *http.Request {
+	c.t.Helper()
+	req, err := http.NewRequest(method, c.server.URL+path, body)
+	if err != nil {
+		c.t.Fatalf("building %s %s: %v", method, path, err)
+	}
+	for key, values := range c.header {
+		req.Header[key] = values
+	}
+	return req
+}
+
+func (c *Client) send(req *http.Request) *Response {
+	c.t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		c.t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		c.t.Fatalf("%s %s: reading the response: %v", req.Method, req.URL.Path, err)
+	}
+	return &Response{t: c.t, Request: req, Status: resp.StatusCode, Header: resp.Header, Body: body}
+}
@@ -0,0 +1,87 @@
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/controllers"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/repositories"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/services"
+)
+
+// Password is that of every account the fixtures create.
+const Password = "testsupport-password"
+
+var accounts atomic.Int64
+
+// NewEmail returns an address no other account of the process has.
+func NewEmail(prefix string) string {
+	return fmt.Sprintf("%s%d@example.com", prefix, accounts.Add(1))
+}
+
+// Admin creates an admin account, as the create-admin command does, and
+// returns a client signed in as it.
+func (s *Server) Admin(t testing.TB) *Client {
+	t.Helper()
+	email := NewEmail("admin")
+	users := services.NewUserService(repositories.NewUserRepository(models.DB), repositories.NewRefreshTokenRepository(models.DB), repositories.NewTenantRepository(models.DB), nil, config.AuthConfig{})
+	if _, _, err := users.CreateAdmin(context.Background(), email, Password); err != nil {
+		t.Fatalf("creating admin %s: %v", email, err)
+	}
+	return s.Login(t, email, Password)
+}
+
+// Reader registers a reader account and returns a client signed in as it.
+func (s *Server) Reader(t testing.TB) *Client {
+	t.Helper()
+	email := NewEmail("reader")
+	s.Client(t).Post("/api/v1/auth/register", controllers.RegisterInput{Email: email, Password: Password}).Expect(http.StatusCreated)
+	return s.Login(t, email, Password)
+}
+
+// Login returns a client signed in with the credentials.
+func (s *Server) Login(t testing.TB, email, password string) *Client {
+	t.Helper()
+	var token services.Token
+	s.Client(t).Post("/api/v1/auth/login", controllers.LoginInput{Email: email, Password: password}).Expect(http.StatusOK).Data(&token)
+	return s.Client(t).WithToken(token.Token)
+}
+
+// CreateAuthor creates an author; the client must be an admin's.
+func (c *Client) CreateAuthor(name string) models.Author {
+	c.t.Helper()
+	var author models.Author
+	c.Post("/api/v1/authors", controllers.CreateAuthorInput{Name: name}).Expect(http.StatusOK).Data(&author)
+	return author
+}
+
+// CreateCategory creates a category; the client must be an admin's.
+func (c *Client) CreateCategory(name string) models.Category {
+	c.t.Helper()
+	var category models.Category
+	c.Post("/api/v1/categories", controllers.CategoryInput{Name: name}).Expect(http.StatusOK).Data(&category)
+	return category
+}
+
+// CreateBook creates a book, even one that looks like a duplicate; the
+// client must be an admin's.
+func (c *Client) CreateBook(input controllers.CreateBookInput) models.Book {
+	c.t.Helper()
+	var book models.Book
+	c.Post("/api/v1/books?force=true", input).Expect(http.StatusOK).Data(&book)
+	return book
+}
+
+// CreateMember creates a library member with an address of their own; the
+// client must be an admin's.
+func (c *Client) CreateMember(name string) models.Member {
+	c.t.Helper()
+	var member models.Member
+	c.Post("/api/v1/members", controllers.CreateMemberInput{Name: name, Email: NewEmail("member")}).Expect(http.StatusCreated).Data(&member)
+	return member
+}
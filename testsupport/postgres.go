@@ -0,0 +1,83 @@
+//go:build postgres
+
+package testsupport
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	tcpostgres "github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func init() {
+	newDatabase = postgresDatabase
+}
+
+// One container serves every test of the process, each in a database of
+// its own; the testcontainers reaper removes it once the process exits.
+var (
+	containerDSN  string
+	containerErr  error
+	containerOnce sync.Once
+	databases     atomic.Int64
+)
+
+func postgresDatabase(t testing.TB, _ string) (driver, dsn string) {
+	t.Helper()
+	containerOnce.Do(startContainer)
+	if containerErr != nil {
+		t.Fatalf("starting PostgreSQL: %v", containerErr)
+	}
+
+	admin, err := gorm.Open(postgres.Open(containerDSN), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("test_%d", databases.Add(1))
+	if err := admin.Exec("CREATE DATABASE " + name).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		// Runs after the app has stopped and closed its connections.
+		if err := admin.Exec("DROP DATABASE IF EXISTS " + name + " WITH (FORCE)").Error; err != nil {
+			t.Errorf("dropping database %s: %v", name, err)
+		}
+		if db, err := admin.DB(); err == nil {
+			db.Close()
+		}
+	})
+
+	u, err := url.Parse(containerDSN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	return "postgres", u.String()
+}
+
+func startContainer() {
+	ctx := context.Background()
+	container, err := tcpostgres.RunContainer(ctx,
+		testcontainers.WithImage("postgres:16-alpine"),
+		tcpostgres.WithDatabase("bookstore"),
+		tcpostgres.WithUsername("bookstore"),
+		tcpostgres.WithPassword("bookstore"),
+		testcontainers.WithWaitStrategy(wait.ForLog("database system is ready to accept connections").
+			WithOccurrence(2).
+			WithStartupTimeout(time.Minute)),
+	)
+	if err != nil {
+		containerErr = err
+		return
+	}
+	containerDSN, containerErr = container.ConnectionString(ctx, "sslmode=disable")
+}
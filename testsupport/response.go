@@ -0,0 +1,79 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/apierrors"
+)
+
+// Response is a response read in full.
+type Response struct {
+	t       testing.TB
+	Request *http.Request
+	Status  int
+	Header  http.Header
+	Body    []byte
+}
+
+// Expect fails the test unless the response has the status, showing the
+// body to tell why. It returns the response for chaining.
+func (r *Response) Expect(status int) *Response {
+	r.t.Helper()
+	if r.Status != status {
+		r.t.Fatalf("%s %s: got status %d, want %d: %s", r.Request.Method, r.Request.URL.RequestURI(), r.Status, status, r.Body)
+	}
+	return r
+}
+
+// Decode reads the JSON body into v.
+func (r *Response) Decode(v any) {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s %s: decoding %s: %v", r.Request.Method, r.Request.URL.RequestURI(), r.Body, err)
+	}
+}
+
+// Data reads the data member of the JSON body into v.
+func (r *Response) Data(v any) {
+	r.t.Helper()
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	r.Decode(&envelope)
+	if err := json.Unmarshal(envelope.Data, v); err != nil {
+		r.t.Fatalf("%s %s: decoding the data of %s: %v", r.Request.Method, r.Request.URL.RequestURI(), r.Body, err)
+	}
+}
+
+// Problem is a problem details body, with its extension members.
+type Problem struct {
+	Type       string                     `json:"type"`
+	Title      string                     `json:"title"`
+	Status     int                        `json:"status"`
+	Detail     string                     `json:"detail"`
+	Errors     []apierrors.FieldError     `json:"errors"`
+	Extensions map[string]json.RawMessage `json:"-"`
+}
+
+// Problem reads the body as problem details.
+func (r *Response) Problem() Problem {
+	r.t.Helper()
+	var problem Problem
+	r.Decode(&problem)
+	r.Decode(&problem.Extensions)
+	return problem
+}
+
+// ExpectProblem fails the test unless the response is an error with the
+// status, and returns its problem details.
+func (r *Response) ExpectProblem(status int) Problem {
+	r.t.Helper()
+	r.Expect(status)
+	if contentType := r.Header.Get("Content-Type"); !strings.HasPrefix(contentType, apierrors.ContentType) {
+		r.t.Fatalf("%s %s: got Content-Type %q, want problem details", r.Request.Method, r.Request.URL.RequestURI(), contentType)
+	}
+	return r.Problem()
+}
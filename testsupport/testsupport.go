@@ -0,0 +1,106 @@
+// Package testsupport runs the whole API in-process for end-to-end tests.
+// Start boots it as the server does, against a database of its own, and
+// the Client it hands out makes requests and creates the records tests
+// need. Tests run against SQLite; built with the postgres tag, against a
+// PostgreSQL container instead, which needs Docker:
+//
+//	go test -tags postgres ./e2e/...
+//
+// The app keeps its database in models.DB, so tests using it must not run
+// in parallel.
+package testsupport
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/app"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/gin-gonic/gin"
+)
+
+// Server is the API running in-process, over HTTP on a local port.
+type Server struct {
+	App *app.App
+	// Where the API is served, without a trailing slash.
+	URL string
+}
+
+// Option changes the configuration the API starts with.
+type Option func(*config.Config)
+
+// Start boots the API for the test, with a new database and upload
+// directory, and stops it when the test ends. Nothing it starts reaches out
+// of the process: Redis, the search index, the message broker, tracing,
+// mail, the book catalogs, the exchange rates API and gRPC are off, as is
+// rate limiting, whatever the environment says; options can turn them back
+// on.
+func Start(t testing.TB, options ...Option) *Server {
+	t.Helper()
+	dir := t.TempDir()
+
+	// An empty file, so no config.yaml of the working directory is read.
+	configFile := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(configFile, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CONFIG_FILE", configFile)
+	t.Setenv("JWT_SECRET", "testsupport-secret")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading the configuration: %v", err)
+	}
+
+	cfg.Database.Driver, cfg.Database.DSN = newDatabase(t, dir)
+	cfg.Database.Replicas = nil
+	cfg.GinMode = gin.TestMode
+	cfg.LogLevel = "error"
+	cfg.GRPCPort = ""
+	cfg.Redis.URL = ""
+	cfg.Search.URL = ""
+	cfg.Tracing.Endpoint = ""
+	cfg.Outbox.Broker = ""
+	cfg.Lookup.OpenLibraryURL, cfg.Lookup.GoogleBooksURL = "", ""
+	cfg.Currency.URL = ""
+	cfg.Mail.SMTP.Host = ""
+	cfg.TLS = config.TLSConfig{}
+	cfg.RequestAudit.File = ""
+	cfg.RateLimit.Rate, cfg.RateLimit.AddressRate = 0, 0
+	cfg.Storage.Driver = "local"
+	cfg.Storage.LocalDir = filepath.Join(dir, "uploads")
+	for _, option := range options {
+		option(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("invalid configuration: %v", err)
+	}
+
+	a, err := app.Bootstrap(cfg)
+	if err != nil {
+		t.Fatalf("starting the API: %v", err)
+	}
+	server := httptest.NewServer(a.Handler)
+	t.Cleanup(func() {
+		server.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := a.Shutdown(ctx); err != nil {
+			t.Errorf("stopping the API: %v", err)
+		}
+	})
+
+	return &Server{App: a, URL: server.URL}
+}
+
+// sqliteDatabase is a database file in dir.
+func sqliteDatabase(_ testing.TB, dir string) (driver, dsn string) {
+	return "sqlite", filepath.Join(dir, "test.db")
+}
+
+// newDatabase gives the test a database of its own, returning its driver
+// and DSN. The postgres build tag swaps it for a PostgreSQL one.
+var newDatabase = sqliteDatabase
@@ -0,0 +1,71 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/isbn"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/money"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+const MinPublicationYear = 1450
+
+var subdomain = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// MaxPublicationYear allows books announced for next year.
+func MaxPublicationYear() int {
+	return time.Now().Year() + 1
+}
+
+// Setup configures gin's validator: field errors are reported under their
+// JSON names and the custom tags used by the input structs are registered.
+func Setup() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+
+	v.RegisterTagNameFunc(jsonFieldName)
+
+	if err := v.RegisterValidation("publication_year", func(fl validator.FieldLevel) bool {
+		year := int(fl.Field().Int())
+		return year >= MinPublicationYear && year <= MaxPublicationYear()
+	}); err != nil {
+		return err
+	}
+
+	// A DNS label, such as tenant slugs serve as.
+	if err := v.RegisterValidation("subdomain", func(fl validator.FieldLevel) bool {
+		return subdomain.MatchString(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+
+	// Active ISO 4217 codes, in upper case, such as prices are in.
+	if err := v.RegisterValidation("currency", func(fl validator.FieldLevel) bool {
+		return money.Valid(fl.Field().String())
+	}); err != nil {
+		return err
+	}
+
+	// Replaces the validator's own isbn tag, which only tolerates a few
+	// hyphens, with the checksum check books are stored with.
+	return v.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		return isbn.Valid(fl.Field().String())
+	})
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
@@ -0,0 +1,210 @@
+// Package webhooks delivers catalog events to the URLs admins registered.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/geisonsn/rest-api-golang-gin-gorm/config"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/events"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/httpclient"
+	"github.com/geisonsn/rest-api-golang-gin-gorm/models"
+)
+
+// How many deliveries may wait for a worker before new ones are dropped.
+const queueSize = 1000
+
+// Store is the part of repositories.WebhookRepository the dispatcher uses.
+type Store interface {
+	Subscribed(ctx context.Context, eventType string) ([]models.Webhook, error)
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+}
+
+type delivery struct {
+	webhook models.Webhook
+	event   events.Event
+	payload []byte
+	attempt int
+}
+
+// Dispatcher POSTs each event to every webhook subscribed to it, from a pool
+// of background workers, and logs every attempt. A delivery succeeds when
+// the hook answers 2xx; otherwise it is retried with exponential backoff
+// until cfg.MaxAttempts attempts have been made. Events may arrive out of
+// order, and deliveries still pending when the process stops are lost.
+type Dispatcher struct {
+	store  Store
+	cfg    config.WebhookConfig
+	client *httpclient.Client
+	queue  chan delivery
+	done   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts the workers; Stop them on shutdown. Hosts failing
+// deliveries get a circuit breaker as outbound configures it; deliveries it
+// refuses fail, to be retried like any other.
+func NewDispatcher(store Store, cfg config.WebhookConfig, outbound config.HTTPClientConfig) *Dispatcher {
+	d := &Dispatcher{
+		store: store,
+		cfg:   cfg,
+		client: httpclient.New(httpclient.Options{
+			Timeout: cfg.Timeout,
+			// Failed deliveries are retried later, by the dispatcher.
+			Retries:          0,
+			BreakerThreshold: outbound.BreakerThreshold,
+			BreakerCooldown:  outbound.BreakerCooldown,
+			// A redirect is reported as a failed delivery rather than
+			// followed, so hooks can't bounce requests elsewhere.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+			Transport:     transport(cfg),
+		}),
+		queue: make(chan delivery, queueSize),
+		done:  make(chan struct{}),
+	}
+	for i := 0; i < cfg.Workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// transport is the one deliveries are made through: Transport, unless
+// private targets are allowed.
+func transport(cfg config.WebhookConfig) http.RoundTripper {
+	if cfg.AllowPrivateTargets {
+		return nil
+	}
+	return Transport()
+}
+
+// Publish queues the event for every subscribed webhook. It is an
+// events.Handler.
+func (d *Dispatcher) Publish(ctx context.Context, event events.Event) {
+	// The request that caused the event may end before its deliveries do.
+	ctx = context.WithoutCancel(ctx)
+
+	webhooks, err := d.store.Subscribed(ctx, event.Type)
+	if err != nil {
+		slog.ErrorContext(ctx, "loading webhooks failed", "event", event.Type, "error", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		slog.ErrorContext(ctx, "encoding webhook payload failed", "event", event.Type, "error", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		d.enqueue(delivery{webhook: webhook, event: event, payload: payload, attempt: 1})
+	}
+}
+
+// Stop stops the workers, waiting until ctx is done for the deliveries in
+// progress.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	close(d.done)
+	stopped := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (d *Dispatcher) enqueue(job delivery) {
+	select {
+	case <-d.done:
+		return
+	default:
+	}
+	select {
+	case d.queue <- job:
+	default:
+		slog.Warn("webhook queue is full, dropping delivery", "webhook_id", job.webhook.ID, "event_id", job.event.ID)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.done:
+			return
+		case job := <-d.queue:
+			d.deliver(job)
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(job delivery) {
+	ctx := context.Background()
+	record := models.WebhookDelivery{
+		WebhookID: job.webhook.ID,
+		EventID:   job.event.ID,
+		Event:     job.event.Type,
+		Payload:   string(job.payload),
+		Attempt:   job.attempt,
+	}
+
+	start := time.Now()
+	status, err := d.post(ctx, job)
+	record.DurationMS = time.Since(start).Milliseconds()
+	record.StatusCode = status
+	switch {
+	case err != nil:
+		record.Error = err.Error()
+	case status < 200 || status > 299:
+		record.Error = fmt.Sprintf("unexpected status %d", status)
+	default:
+		record.Succeeded = true
+	}
+	if err := d.store.CreateDelivery(ctx, &record); err != nil {
+		slog.Error("recording webhook delivery failed", "webhook_id", job.webhook.ID, "error", err)
+	}
+
+	if record.Succeeded || job.attempt >= d.cfg.MaxAttempts {
+		if !record.Succeeded {
+			slog.Warn("webhook delivery failed for good", "webhook_id", job.webhook.ID, "event_id", job.event.ID, "attempts", job.attempt)
+		}
+		return
+	}
+	backoff := d.cfg.RetryBackoff << (job.attempt - 1)
+	job.attempt++
+	time.AfterFunc(backoff, func() { d.enqueue(job) })
+}
+
+func (d *Dispatcher) post(ctx context.Context, job delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.webhook.URL, bytes.NewReader(job.payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bookstore-webhooks/1.0")
+	req.Header.Set("X-Webhook-Event", job.event.Type)
+	req.Header.Set("X-Webhook-Delivery", job.event.ID)
+	req.Header.Set(SignatureHeader, Sign(job.webhook.Secret, time.Now(), job.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Read what the hook sent so the connection can be reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateTarget is returned for webhook URLs, and refused connections,
+// to addresses of the network the API runs in rather than the internet:
+// loopback, private, link-local (cloud metadata services among them),
+// shared and unspecified ones. Without this anyone who can register a
+// webhook could have the API make requests to internal services.
+var ErrPrivateTarget = errors.New("webhook URL must not point to a loopback, private or link-local address")
+
+// sharedAddresses is the carrier-grade NAT range, where some clouds serve
+// their metadata too.
+var sharedAddresses = netip.MustParsePrefix("100.64.0.0/10")
+
+func privateAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddresses.Contains(ip)
+}
+
+// CheckTarget returns ErrPrivateTarget if the host of rawURL is, or
+// resolves to, a private address. Hosts that don't resolve are let
+// through: they may by the time of the deliveries, which Transport checks
+// again.
+func CheckTarget(ctx context.Context, rawURL string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	host := target.Hostname()
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if privateAddress(ip) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, ip := range ips {
+		if privateAddress(ip) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// Transport returns a transport refusing to connect to private addresses.
+// The address is checked as it is dialed, whatever the host resolved to
+// when the webhook was registered. Requests aren't sent through a proxy
+// from the environment, whose address would be the one checked.
+func Transport() http.RoundTripper {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip, err := netip.ParseAddr(host)
+			if err != nil {
+				return err
+			}
+			if privateAddress(ip) {
+				return ErrPrivateTarget
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}